@@ -1,7 +1,6 @@
 package main
 
 import (
-	"afterzin/api/internal/logger"
 	"context"
 	"fmt"
 	"log"
@@ -9,14 +8,27 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
+	"afterzin/api/internal/admin"
+	"afterzin/api/internal/auth"
+	"afterzin/api/internal/checkin"
 	"afterzin/api/internal/config"
 	"afterzin/api/internal/db"
+	"afterzin/api/internal/eventtime"
 	"afterzin/api/internal/graphql"
+	"afterzin/api/internal/guest"
+	"afterzin/api/internal/jobs"
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/mailer"
 	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/outbox"
 	"afterzin/api/internal/pagarme"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/retention"
+	"afterzin/api/internal/tickets"
 
 	"github.com/joho/godotenv"
 )
@@ -26,6 +38,13 @@ func main() {
 	_ = godotenv.Load()
 
 	cfg := config.Load()
+	logger.Infof("configuração: %s", cfg.Summary())
+	if wait := time.Duration(cfg.FraudCheckRetries) * cfg.FraudCheckDelay; cfg.FraudCheckMaxWait > 0 && wait > cfg.FraudCheckMaxWait {
+		logger.Warnf("FRAUD_CHECK_RETRIES × FRAUD_CHECK_DELAY (%s) passa de FRAUD_CHECK_MAX_WAIT (%s): as consultas além do limite não serão feitas", wait, cfg.FraudCheckMaxWait)
+	}
+	if len(cfg.UnknownFeatures) > 0 {
+		logger.Warnf("FEATURES contém flags desconhecidas (ignoradas pelo código): %s", strings.Join(cfg.UnknownFeatures, ", "))
+	}
 
 	if err := os.MkdirAll(filepath.Dir(cfg.DBPath), 0755); err != nil {
 		logger.Fatalf("erro ao criar diretório de dados: %v", err)
@@ -40,15 +59,60 @@ func main() {
 	if err := db.Migrate(sqlite); err != nil {
 		logger.Fatalf("erro ao executar migrações: %v", err)
 	}
+	repository.OrderRefPrefix = cfg.OrderRefPrefix
+	if cfg.BcryptCost != 0 {
+		if err := auth.SetPasswordCost(cfg.BcryptCost); err != nil {
+			logger.Fatalf("BCRYPT_COST inválido: %v", err)
+		}
+	}
+	if _, err := eventtime.Load(cfg.DefaultTimezone); err != nil {
+		logger.Fatalf("DEFAULT_TIMEZONE inválido: %v", err)
+	}
 
-	graphqlHandler := graphql.NewHandler(sqlite, cfg)
+	// Background jobs are registered below and started once routes are ready
+	scheduler := jobs.New(cfg.JobsDisabled)
 
 	// Build HTTP mux with all routes
 	mux := http.NewServeMux()
-	mux.Handle("/graphql", graphqlHandler)
+	mail := mailer.New(cfg)
+
+	// Ticket, admin and guest account endpoints don't involve the payment
+	// gateway and are registered with or without Pagar.me
+	prefix := cfg.APIPrefix
+	ticketHandler := tickets.NewHandler(sqlite, cfg, mail)
+	mux.HandleFunc(prefix+"/order/resend-tickets", ticketHandler.ResendTickets)
+	mux.HandleFunc(prefix+"/ticket/pdf", ticketHandler.TicketPDF)
+	mux.HandleFunc(prefix+"/ticket/verify", ticketHandler.VerifyTicket)
+	mux.HandleFunc(prefix+"/checkin/batch", ticketHandler.CheckinBatch)
+	mux.HandleFunc(prefix+"/event/attendees", ticketHandler.ExportAttendees)
+	mux.HandleFunc(prefix+"/download/link", ticketHandler.CreateDownloadLink)
+	adminHandler := admin.NewHandler(sqlite, cfg)
+	mux.HandleFunc(prefix+"/admin/inventory/check", adminHandler.InventoryCheck)
+	mux.HandleFunc(prefix+"/admin/audit", adminHandler.AuditLog)
+	mux.HandleFunc(prefix+"/admin/outbox", adminHandler.Outbox)
+	mux.HandleFunc(prefix+"/admin/blocklist", adminHandler.Blocklist)
+	guestHandler := guest.NewHandler(sqlite, cfg, mail)
+	mux.HandleFunc(prefix+"/guest/claim", guestHandler.Claim)
+	mux.HandleFunc(prefix+"/guest/claim/confirm", guestHandler.ConfirmClaim)
 
 	// Pagar.me REST endpoints (only registered when PAGARME_API_KEY is set)
+	var pagarmeAPI pagarme.PagarmeAPI
+	var pagarmeHandler *pagarme.Handler
 	if cfg.PagarmeAPIKey != "" {
+		pagarmeEnv, err := pagarme.ValidateEnvironment(cfg.PagarmeEnv, cfg.PagarmeAPIKey)
+		if err != nil {
+			logger.Fatalf("configuração do Pagar.me inválida: %v", err)
+		}
+		logger.Infof("ambiente do Pagar.me: %s", pagarmeEnv)
+		region, err := pagarme.RegionFromConfig(cfg.PaymentRegion, cfg.PaymentCurrency)
+		if err != nil {
+			logger.Fatalf("configuração de pagamento inválida: %v", err)
+		}
+		logger.Infof("região de pagamento: %s (%s)", region.Code, region.Currency)
+		if err := pagarme.ValidateAccountType(cfg.DefaultAccountType); err != nil {
+			logger.Fatalf("DEFAULT_ACCOUNT_TYPE inválido: %v", err)
+		}
+
 		pagarmeClient := pagarme.NewClient(
 			cfg.PagarmeAPIKey,
 			cfg.PagarmeWebhookSecret,
@@ -56,25 +120,105 @@ func main() {
 			cfg.PagarmeAppFee,
 			cfg.BaseURL,
 		)
-		pagarmeHandler := pagarme.NewHandler(pagarmeClient, sqlite, cfg)
-		mux.HandleFunc("/v1/recipient/create", pagarmeHandler.CreateRecipient)
-		mux.HandleFunc("/v1/recipient/status", pagarmeHandler.GetRecipientStatus)
-		mux.HandleFunc("/v1/payment/create", pagarmeHandler.CreatePayment)
-		mux.HandleFunc("/v1/payment/status", pagarmeHandler.GetPaymentStatus)
-		mux.HandleFunc("/v1/webhook", pagarmeHandler.HandleWebhook)
-		logger.Infof("endpoints do Pagar.me registrados (Recipient + PIX + Webhook)")
+		pagarmeClient.APIPrefix = cfg.APIPrefix
+		if cfg.PagarmeEnsureWebhook {
+			if err := pagarmeClient.EnsureWebhook(pagarme.WebhookSubscriptions(cfg.WebhookEventTypes)); err != nil {
+				logger.Errorf("erro ao registrar webhook no Pagar.me: %v", err)
+			} else {
+				logger.Infof("webhook do Pagar.me registrado em %s", pagarmeClient.WebhookURL())
+			}
+		}
+		pagarmeAPI = pagarmeClient
+		pagarmeHandler = pagarme.NewHandler(pagarmeClient, sqlite, cfg, mail)
+		mux.HandleFunc(prefix+"/recipient/create", pagarmeHandler.CreateRecipient)
+		mux.HandleFunc(prefix+"/recipient/status", pagarmeHandler.GetRecipientStatus)
+		mux.HandleFunc(prefix+"/recipient/status/refresh", pagarmeHandler.RefreshRecipientStatus)
+		mux.HandleFunc(prefix+"/recipient/balance", pagarmeHandler.GetRecipientBalance)
+		mux.HandleFunc(prefix+"/recipient/payouts", pagarmeHandler.GetRecipientPayouts)
+		mux.HandleFunc(prefix+"/recipient", pagarmeHandler.DeleteRecipient)
+		mux.HandleFunc(prefix+"/payment/create", pagarmeHandler.CreatePayment)
+		mux.HandleFunc(prefix+"/payment/status", pagarmeHandler.GetPaymentStatus)
+		mux.HandleFunc(prefix+"/admin/pagarme/order", pagarmeHandler.AdminPagarmeOrder)
+		mux.HandleFunc(prefix+"/admin/webhook/stats", pagarmeHandler.AdminWebhookStats)
+		mux.HandleFunc(prefix+"/admin/webhook/latency", pagarmeHandler.AdminSlowConfirmations)
+		mux.HandleFunc(prefix+pagarme.WebhookPath, pagarmeHandler.HandleWebhook)
+		mux.HandleFunc(prefix+"/order/preview", pagarmeHandler.PreviewOrder)
+		mux.HandleFunc(prefix+"/order/items", pagarmeHandler.UpdateOrderItems)
+		logger.Infof("endpoints do Pagar.me registrados em %s/ (Recipient + PIX + Webhook)", prefix)
+
+		scheduler.Register("balance-cache-prune", 10*time.Minute, func(ctx context.Context) error {
+			if n := pagarme.PruneBalanceCache(); n > 0 {
+				logger.Debugf("%d saldo(s) expirado(s) removido(s) do cache", n)
+			}
+			return nil
+		})
+		scheduler.Register("recipient-refresh-prune", 10*time.Minute, func(ctx context.Context) error {
+			if n := pagarme.PruneRecipientRefreshes(time.Now()); n > 0 {
+				logger.Debugf("%d limite(s) de atualização de recebedor expirado(s) removido(s)", n)
+			}
+			return nil
+		})
 	} else {
 		logger.Warnf("PAGARME_API_KEY não definido — endpoints do Pagar.me desabilitados")
 	}
 
-	handler := middleware.CORS(cfg.CORSOrigins)(middleware.Auth(cfg.JWTSecret)(mux))
+	scheduler.Register("inventory-check", time.Hour, func(ctx context.Context) error {
+		return admin.CheckInventory(sqlite)
+	})
+	scheduler.Register("order-expiry", time.Minute, func(ctx context.Context) error {
+		return pagarme.ExpirePendingOrders(sqlite, cfg.AbandonedOrderAge, time.Now())
+	})
+	scheduler.Register("data-retention", 24*time.Hour, func(ctx context.Context) error {
+		return retention.Apply(sqlite, retention.Policy{
+			PendingOrders: cfg.RetentionOrders,
+			InactiveUsers: cfg.RetentionUsers,
+			DryRun:        cfg.RetentionDryRun,
+		}, time.Now())
+	})
+
+	scheduler.Register("outbox-dispatch", 30*time.Second, func(ctx context.Context) error {
+		return outbox.Dispatch(ctx, sqlite, mail, outbox.Policy{
+			MaxAttempts: cfg.OutboxMaxAttempts,
+			RetryBase:   cfg.OutboxRetryBase,
+		}, time.Now())
+	})
+
+	checkins := checkin.NewRecorder(sqlite)
+	defer checkins.Close()
+
+	graphqlHandler := graphql.NewHandler(sqlite, cfg, pagarmeAPI, checkins, mail)
+	mux.Handle("/graphql", graphqlHandler)
+
+	bodyLimit := middleware.MaxBody(cfg.MaxBodyBytes, map[string]int64{"/graphql": cfg.MaxUploadBytes})
+	// REST endpoints answer JSON; the ones with other representations
+	// negotiate them (or, for /graphql and webhooks, leave it to the caller)
+	produces := middleware.Produces([]string{"application/json"}, map[string][]string{
+		"/graphql":                          nil,
+		cfg.APIPrefix + pagarme.WebhookPath: nil,
+		cfg.APIPrefix + "/event/attendees":  nil,
+		cfg.APIPrefix + "/ticket/pdf":       {"application/pdf"},
+	})
+	handler := middleware.CORS(cfg.CORSOrigins)(middleware.Auth(cfg.JWTSecret)(middleware.Language(produces(bodyLimit(middleware.Recover(mux))))))
+	if cfg.Compression {
+		handler = middleware.Gzip(cfg.CompressionMinBytes)(handler)
+	}
+	handler = middleware.RequestID(handler)
 
 	addr := fmt.Sprintf("0.0.0.0:%d", cfg.Port)
 	httpServer := &http.Server{
-		Addr:         addr,
-		Handler:      handler,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		Addr:           addr,
+		Handler:        handler,
+		ReadTimeout:    cfg.ReadTimeout,
+		WriteTimeout:   cfg.WriteTimeout,
+		IdleTimeout:    cfg.IdleTimeout,
+		MaxHeaderBytes: cfg.MaxHeaderBytes,
+	}
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	if cfg.JobsEnabled {
+		scheduler.Start(jobsCtx)
+	} else {
+		logger.Infof("jobs em segundo plano desabilitados (JOBS_ENABLED=false)")
 	}
 
 	logger.Infof("servidor GraphQL escutando em %s", addr)
@@ -89,10 +233,20 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	logger.Infof("encerrando...")
+	// Payments are confirmed inside webhook requests, so Shutdown waits for
+	// them to commit (or roll back) before the DB closes
+	inFlight := pagarmeHandler.PaymentsInFlight()
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if err := httpServer.Shutdown(ctx); err != nil {
-		logger.Fatalf("erro ao encerrar servidor: %v", err)
+		logger.Errorf("erro ao encerrar servidor: %v", err)
+	}
+	if remaining := pagarmeHandler.PaymentsInFlight(); remaining > 0 {
+		logger.Errorf("%d pagamento(s) ainda em processamento ao encerrar", remaining)
+	} else if inFlight > 0 {
+		logger.Infof("%d pagamento(s) em processamento concluído(s) antes de encerrar", inFlight)
 	}
+	stopJobs()
+	scheduler.Wait()
 	logger.Infof("servidor parado")
 }
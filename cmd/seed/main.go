@@ -1,9 +1,14 @@
 package main
 
 import (
+	"bufio"
+	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"afterzin/api/internal/auth"
 	"afterzin/api/internal/config"
 	"afterzin/api/internal/db"
 	"afterzin/api/internal/db/seeds"
@@ -11,7 +16,16 @@ import (
 )
 
 func main() {
+	dryRun := flag.Bool("dry-run", false, "mostra o que seria apagado e inserido, sem alterar o banco")
+	yes := flag.Bool("yes", false, "não pede confirmação (uso em CI)")
+	flag.Parse()
+
 	cfg := config.Load()
+	if cfg.BcryptCost != 0 {
+		if err := auth.SetPasswordCost(cfg.BcryptCost); err != nil {
+			logger.Fatalf("BCRYPT_COST inválido: %v", err)
+		}
+	}
 
 	if err := os.MkdirAll(filepath.Dir(cfg.DBPath), 0755); err != nil {
 		logger.Fatalf("erro ao criar diretório de dados: %v", err)
@@ -27,9 +41,51 @@ func main() {
 		logger.Fatalf("erro ao executar migrações: %v", err)
 	}
 
+	opts := seeds.OptionsFromEnv()
+	report, err := seeds.DryRun(sqlite, opts)
+	if err != nil {
+		logger.Fatalf("erro ao planejar seeds: %v", err)
+	}
+	printReport(cfg.DBPath, report)
+	if opts.AdminEmail != "" {
+		logger.Infof("usuário ADMIN de seed: %s", opts.AdminEmail)
+	}
+	if *dryRun {
+		logger.Infof("dry run: nenhuma alteração feita")
+		return
+	}
+
+	if !*yes && !confirm(cfg.DBPath) {
+		logger.Infof("seeds cancelados")
+		os.Exit(1)
+	}
+
 	logger.Infof("executando seeds...")
-	if err := seeds.Run(sqlite); err != nil {
+	if err := seeds.Run(sqlite, opts); err != nil {
 		logger.Fatalf("erro ao executar seeds: %v", err)
 	}
 	logger.Infof("seeds finalizados com sucesso")
 }
+
+func printReport(dbPath string, r *seeds.Report) {
+	logger.Infof("banco: %s", dbPath)
+	for _, c := range r.Cleared {
+		logger.Infof("  apagar   %-14s %d linha(s)", c.Table, c.Rows)
+	}
+	for _, c := range r.Inserted {
+		logger.Infof("  inserir  %-14s %d linha(s)", c.Table, c.Rows)
+	}
+}
+
+// confirm asks before wiping the database. Without a terminal (CI, pipes)
+// there is nobody to ask, so --yes is required instead.
+func confirm(dbPath string) bool {
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		logger.Errorf("entrada não interativa: use --yes para confirmar ou --dry-run para simular")
+		return false
+	}
+	fmt.Printf("Os dados acima serão APAGADOS em %s. Continuar? [s/N] ", dbPath)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "s" || answer == "sim" || answer == "y" || answer == "yes"
+}
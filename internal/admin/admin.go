@@ -0,0 +1,146 @@
+// Package admin serves the /admin REST endpoints that don't involve the
+// payment gateway (audit log, inventory check, outbox, blocklist), plus the
+// inventory-check job.
+package admin
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+)
+
+// maxAuditPageSize caps the page size of AuditLog.
+const maxAuditPageSize = 200
+
+// Handler provides the HTTP handlers for admin endpoints.
+type Handler struct {
+	db  *sql.DB
+	cfg *config.Config
+}
+
+// NewHandler creates an admin HTTP handler.
+func NewHandler(db *sql.DB, cfg *config.Config) *Handler {
+	return &Handler{db: db, cfg: cfg}
+}
+
+// requireAdmin writes a 401 or 403 and returns false unless the request is
+// authenticated as an admin.
+func (h *Handler) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	userID := middleware.UserID(r.Context())
+	if userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return false
+	}
+	if !rest.IsAdmin(h.db, userID) {
+		rest.Error(w, http.StatusForbidden, "sem permissão")
+		return false
+	}
+	return true
+}
+
+// InventoryCheck handles GET /v1/admin/inventory/check
+// Runs repository.VerifyInventoryInvariants and lists the inconsistent lots
+// and ticket types.
+func (h *Handler) InventoryCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	violations, err := repository.VerifyInventoryInvariants(h.db)
+	if err != nil {
+		logger.Errorf("erro ao verificar inventário: %v", err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao verificar inventário")
+		return
+	}
+	if violations == nil {
+		violations = []repository.InventoryViolation{}
+	}
+	rest.JSON(w, http.StatusOK, map[string]interface{}{
+		"ok":         len(violations) == 0,
+		"violations": violations,
+	})
+}
+
+// AuditLog handles GET /v1/admin/audit
+// Lists the audit_log entries of admins (see audit.Admin), newest first.
+// Filters: actorId, action (prefix), entityType, entityId; all=true also
+// lists entries by other users. Paginated with limit (default 50) and
+// offset.
+func (h *Handler) AuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	q := r.URL.Query()
+	limit, offset := 50, 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			rest.Error(w, http.StatusBadRequest, "limit inválido")
+			return
+		}
+		limit = min(n, maxAuditPageSize)
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			rest.Error(w, http.StatusBadRequest, "offset inválido")
+			return
+		}
+		offset = n
+	}
+
+	filter := repository.AuditLogFilter{
+		ActorUserID:  q.Get("actorId"),
+		AdminsOnly:   q.Get("all") != "true",
+		ActionPrefix: q.Get("action"),
+		EntityType:   q.Get("entityType"),
+		EntityID:     q.Get("entityId"),
+	}
+
+	entries, total, err := repository.ListAuditLog(h.db, filter, limit, offset)
+	if err != nil {
+		logger.Errorf("erro ao listar auditoria: %v", err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao listar auditoria")
+		return
+	}
+	rest.JSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// CheckInventory is the body of the inventory-check job: it logs every
+// inventory violation so drift is noticed before it becomes an oversell.
+func CheckInventory(db *sql.DB) error {
+	violations, err := repository.VerifyInventoryInvariants(db)
+	if err != nil {
+		return err
+	}
+	for _, v := range violations {
+		logger.Errorf("inventário inconsistente: tipo=%s evento=%s lote=%s ingresso=%s: %s",
+			v.Kind, v.EventID, v.LotID, v.TicketTypeID, v.Detail)
+	}
+	if len(violations) > 0 {
+		logger.Warnf("verificação de inventário encontrou %d inconsistência(s)", len(violations))
+	}
+	return nil
+}
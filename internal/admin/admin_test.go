@@ -0,0 +1,144 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"afterzin/api/internal/audit"
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/qrcode"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+// withUser returns a copy of r authenticated as userID.
+func withUser(r *http.Request, userID string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, userID))
+}
+
+// promote makes userID an admin.
+func promote(t *testing.T, sqlite *sql.DB, userID string) {
+	t.Helper()
+	if _, err := sqlite.Exec(`UPDATE users SET role = 'ADMIN' WHERE id = ?`, userID); err != nil {
+		t.Fatalf("promote admin: %v", err)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	promote(t, sqlite, f.ProducerUserID)
+	// A non-admin entry that the default listing must leave out
+	if err := repository.InsertAuditLog(sqlite, f.BuyerID, "recipient.delete", "producer", "p1", ""); err != nil {
+		t.Fatalf("insert audit: %v", err)
+	}
+	for _, action := range []string{"admin.blocklist_add", "admin.blocklist_remove"} {
+		if err := audit.Admin(sqlite, f.ProducerUserID, action, "blocked_identity", "fraude@email.com", "chargeback", func(*sql.Tx) error { return nil }); err != nil {
+			t.Fatalf("audit %s: %v", action, err)
+		}
+	}
+	h := NewHandler(sqlite, &config.Config{})
+
+	list := func(userID, query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.AuditLog(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/admin/audit"+query, nil), userID))
+		return rec
+	}
+	if rec := list(f.BuyerID, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("buyer: status = %d, want 403", rec.Code)
+	}
+	if rec := list("", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous: status = %d, want 401", rec.Code)
+	}
+
+	var page struct {
+		Entries []repository.AuditLogRow `json:"entries"`
+		Total   int                      `json:"total"`
+	}
+	rec := list(f.ProducerUserID, "")
+	json.NewDecoder(rec.Body).Decode(&page)
+	if rec.Code != http.StatusOK || page.Total != 2 {
+		t.Fatalf("admin listing: status = %d total = %d, want 200/2 (body %s)", rec.Code, page.Total, rec.Body.String())
+	}
+	if page.Entries[0].ActorEmail != testutil.ProducerEmail || page.Entries[0].Action != "admin.blocklist_remove" {
+		t.Errorf("newest entry = %+v", page.Entries[0])
+	}
+
+	rec = list(f.ProducerUserID, "?action=admin.blocklist_add")
+	json.NewDecoder(rec.Body).Decode(&page)
+	if page.Total != 1 || page.Entries[0].EntityID != "fraude@email.com" || page.Entries[0].Details != "chargeback" {
+		t.Errorf("filtered listing = %+v", page)
+	}
+	rec = list(f.ProducerUserID, "?all=true")
+	json.NewDecoder(rec.Body).Decode(&page)
+	if page.Total != 3 {
+		t.Errorf("all entries total = %d, want 3", page.Total)
+	}
+	if rec := list(f.ProducerUserID, "?limit=0"); rec.Code != http.StatusBadRequest {
+		t.Errorf("limit=0: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestInventoryCheck(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	f.PaidOrder(t, sqlite, 3, qrcode.NewKeyRing("test-secret"))
+	promote(t, sqlite, f.ProducerUserID)
+	h := NewHandler(sqlite, &config.Config{})
+
+	check := func() (int, bool, int) {
+		rec := httptest.NewRecorder()
+		h.InventoryCheck(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/admin/inventory/check", nil), f.ProducerUserID))
+		var resp struct {
+			OK         bool                            `json:"ok"`
+			Violations []repository.InventoryViolation `json:"violations"`
+		}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return rec.Code, resp.OK, len(resp.Violations)
+	}
+	if code, ok, n := check(); code != http.StatusOK || !ok || n != 0 {
+		t.Fatalf("clean sale: status = %d ok = %v violations = %d", code, ok, n)
+	}
+
+	if _, err := sqlite.Exec(`UPDATE lots SET available_quantity = available_quantity + 1 WHERE id = ?`, f.LotID); err != nil {
+		t.Fatal(err)
+	}
+	if code, ok, n := check(); code != http.StatusOK || ok || n != 1 {
+		t.Errorf("drifted lot: status = %d ok = %v violations = %d, want one violation", code, ok, n)
+	}
+	if err := CheckInventory(sqlite); err != nil {
+		t.Errorf("CheckInventory: %v", err)
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	promote(t, sqlite, f.ProducerUserID)
+	if _, err := sqlite.Exec(`INSERT INTO outbox (id, kind, payload, status, attempts, last_error) VALUES ('m1', 'email', '{}', 'DEAD', 5, 'smtp fora do ar')`); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(sqlite, &config.Config{})
+
+	rec := httptest.NewRecorder()
+	h.Outbox(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/admin/outbox", nil), f.BuyerID))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("buyer: status = %d, want 403", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Outbox(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/admin/outbox", nil), f.ProducerUserID))
+	var resp struct {
+		ByStatus map[string]int              `json:"byStatus"`
+		Dead     []*repository.OutboxMessage `json:"dead"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || resp.ByStatus[repository.OutboxDead] != 1 || len(resp.Dead) != 1 || resp.Dead[0].LastError != "smtp fora do ar" {
+		t.Errorf("outbox: status = %d, resp = %+v", rec.Code, resp)
+	}
+}
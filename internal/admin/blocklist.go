@@ -0,0 +1,98 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"afterzin/api/internal/audit"
+	"afterzin/api/internal/blocklist"
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+)
+
+// errNotBlocked is returned when unblocking an entry that isn't listed.
+var errNotBlocked = errors.New("bloqueio não encontrado")
+
+// Blocklist handles /v1/admin/blocklist
+//
+//	GET                                   lists the denylist
+//	POST {"kind", "value", "reason"}      blocks a CPF or email
+//	DELETE ?kind=CPF|EMAIL&value=...      unblocks it
+//
+// Only admins can see or change the list.
+func (h *Handler) Blocklist(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) {
+		return
+	}
+	userID := middleware.UserID(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := repository.ListBlockedIdentities(h.db)
+		if err != nil {
+			logger.Errorf("erro ao listar lista de bloqueio: %v", err)
+			rest.Error(w, http.StatusInternalServerError, "erro ao listar bloqueios")
+			return
+		}
+		if list == nil {
+			list = []repository.BlockedIdentity{}
+		}
+		rest.JSON(w, http.StatusOK, map[string]interface{}{"blocked": list})
+
+	case http.MethodPost:
+		var req struct {
+			Kind   string `json:"kind"`
+			Value  string `json:"value"`
+			Reason string `json:"reason"`
+		}
+		if !rest.DecodeStrict(w, r, &req, h.cfg.StrictJSON) {
+			return
+		}
+		kind, value, err := blocklist.Normalize(req.Kind, req.Value)
+		if err != nil {
+			rest.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		reason := strings.TrimSpace(req.Reason)
+		err = audit.Admin(h.db, userID, "admin.blocklist_add", kind, value, reason, func(tx *sql.Tx) error {
+			return repository.BlockIdentity(tx, kind, value, reason, userID)
+		})
+		if err != nil {
+			logger.Errorf("erro ao bloquear %s %s: %v", kind, blocklist.Mask(kind, value), err)
+			rest.Error(w, http.StatusInternalServerError, "erro ao bloquear")
+			return
+		}
+		rest.JSON(w, http.StatusCreated, map[string]string{"kind": kind, "value": value})
+
+	case http.MethodDelete:
+		kind, value, err := blocklist.Normalize(r.URL.Query().Get("kind"), r.URL.Query().Get("value"))
+		if err != nil {
+			rest.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		err = audit.Admin(h.db, userID, "admin.blocklist_remove", kind, value, "", func(tx *sql.Tx) error {
+			removed, err := repository.UnblockIdentity(tx, kind, value)
+			if err == nil && !removed {
+				return errNotBlocked
+			}
+			return err
+		})
+		if errors.Is(err, errNotBlocked) {
+			rest.Error(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			logger.Errorf("erro ao desbloquear %s %s: %v", kind, blocklist.Mask(kind, value), err)
+			rest.Error(w, http.StatusInternalServerError, "erro ao desbloquear")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
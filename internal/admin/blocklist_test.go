@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/blocklist"
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/testutil"
+)
+
+func TestBlocklist(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	promote(t, sqlite, f.ProducerUserID)
+	h := NewHandler(sqlite, &config.Config{})
+
+	call := func(userID, method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.Blocklist(rec, withUser(httptest.NewRequest(method, target, strings.NewReader(body)), userID))
+		return rec
+	}
+
+	if rec := call(f.BuyerID, http.MethodGet, "/v1/admin/blocklist", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("buyer listing: status = %d, want 403", rec.Code)
+	}
+	if rec := call(f.ProducerUserID, http.MethodPost, "/v1/admin/blocklist", `{"kind":"cpf","value":"123"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid CPF: status = %d, want 400", rec.Code)
+	}
+	// Formatted input is stored digits only, and so matches the sanitized CPF.
+	if rec := call(f.ProducerUserID, http.MethodPost, "/v1/admin/blocklist", `{"kind":"cpf","value":"529.982.247-25","reason":"chargeback"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("block: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if err := blocklist.Check(sqlite, testutil.BuyerCPF, testutil.BuyerEmail, "teste"); err != blocklist.ErrIdentityBlocked {
+		t.Errorf("after block: Check = %v, want ErrIdentityBlocked", err)
+	}
+
+	list := call(f.ProducerUserID, http.MethodGet, "/v1/admin/blocklist", "")
+	if list.Code != http.StatusOK || !strings.Contains(list.Body.String(), `"value":"`+testutil.BuyerCPF+`"`) {
+		t.Errorf("list = %d %s", list.Code, list.Body.String())
+	}
+
+	// Unblocking applies to the next check, without a restart.
+	if rec := call(f.ProducerUserID, http.MethodDelete, "/v1/admin/blocklist?kind=CPF&value="+testutil.BuyerCPF, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("unblock: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := call(f.ProducerUserID, http.MethodDelete, "/v1/admin/blocklist?kind=CPF&value="+testutil.BuyerCPF, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unblock twice: status = %d, want 404", rec.Code)
+	}
+	if err := blocklist.Check(sqlite, testutil.BuyerCPF, testutil.BuyerEmail, "teste"); err != nil {
+		t.Errorf("after unblock: Check = %v, want nil", err)
+	}
+	// Only changes that took effect are audited, so the no-op unblock is not.
+	var audited int
+	if err := sqlite.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE action IN ('admin.blocklist_add', 'admin.blocklist_remove')`).Scan(&audited); err != nil {
+		t.Fatal(err)
+	}
+	if audited != 2 {
+		t.Errorf("audit entries = %d, want 2", audited)
+	}
+}
@@ -0,0 +1,49 @@
+package admin
+
+import (
+	"net/http"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/outbox"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+)
+
+// maxDeadOutboxMessages caps the dead-lettered messages Outbox lists.
+const maxDeadOutboxMessages = 50
+
+// Outbox handles GET /v1/admin/outbox
+// Reports email and notification delivery: counters since startup per kind
+// ("sinceStart": sent, failed attempts, dead), messages per status in the
+// outbox ("byStatus") and the most recent dead-lettered ones ("dead").
+func (h *Handler) Outbox(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	byStatus, err := repository.OutboxCounts(h.db)
+	if err != nil {
+		logger.Errorf("erro ao contar mensagens do outbox: %v", err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao consultar outbox")
+		return
+	}
+	dead, err := repository.DeadOutboxMessages(h.db, maxDeadOutboxMessages)
+	if err != nil {
+		logger.Errorf("erro ao listar mensagens descartadas do outbox: %v", err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao consultar outbox")
+		return
+	}
+	if dead == nil {
+		dead = []*repository.OutboxMessage{}
+	}
+	rest.JSON(w, http.StatusOK, map[string]interface{}{
+		"sinceStart": outbox.Counts(),
+		"byStatus":   byStatus,
+		"dead":       dead,
+	})
+}
@@ -0,0 +1,37 @@
+// Package audit records admin actions in audit_log.
+package audit
+
+import (
+	"database/sql"
+	"fmt"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/repository"
+)
+
+// Admin carries out an admin action and records it in audit_log in the same
+// transaction: do runs first and the entry is written only once it succeeds.
+// If the entry can't be written the action is rolled back, so an admin
+// action is never performed unrecorded nor recorded when it failed. Errors
+// returned by do are passed through unchanged.
+//
+// Only changes are audited; admin reads are not.
+func Admin(db *sql.DB, actorUserID, action, entityType, entityID, details string, do func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := do(tx); err != nil {
+		return err
+	}
+	if err := repository.InsertAuditLogTx(tx, actorUserID, action, entityType, entityID, details); err != nil {
+		logger.Errorf("erro ao registrar auditoria da ação admin %s em %s %s (admin %s): %v", action, entityType, entityID, actorUserID, err)
+		return fmt.Errorf("erro ao registrar auditoria: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	logger.Infof("admin %s: %s em %s %s", actorUserID, action, entityType, entityID)
+	return nil
+}
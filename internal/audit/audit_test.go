@@ -0,0 +1,58 @@
+package audit_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"afterzin/api/internal/audit"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestAdminRecordsOnlySuccessfulActions(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	count := func(query string, args ...interface{}) int {
+		t.Helper()
+		var n int
+		if err := sqlite.QueryRow(query, args...).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	err := audit.Admin(sqlite, f.ProducerUserID, "event.max_order_amount", "event", f.EventID, "max_order_centavos=100", func(tx *sql.Tx) error {
+		return repository.SetEventMaxOrderCentavos(tx, f.EventID, 100)
+	})
+	if err != nil {
+		t.Fatalf("Admin: %v", err)
+	}
+	if n := count(`SELECT COUNT(*) FROM audit_log WHERE action = 'event.max_order_amount' AND entity_id = ? AND actor_user_id = ?`, f.EventID, f.ProducerUserID); n != 1 {
+		t.Errorf("audit entries = %d, want 1", n)
+	}
+
+	// A failed action leaves no entry, and its error comes back unchanged.
+	failed := errors.New("falhou")
+	if err := audit.Admin(sqlite, f.ProducerUserID, "event.max_order_amount", "event", f.EventID, "", func(tx *sql.Tx) error {
+		return failed
+	}); err != failed {
+		t.Errorf("Admin = %v, want the action's error", err)
+	}
+	if n := count(`SELECT COUNT(*) FROM audit_log`); n != 1 {
+		t.Errorf("audit entries after a failed action = %d, want 1", n)
+	}
+
+	// An action that can't be audited is rolled back.
+	if _, err := sqlite.Exec(`DROP TABLE audit_log`); err != nil {
+		t.Fatal(err)
+	}
+	if err := audit.Admin(sqlite, f.ProducerUserID, "event.max_order_amount", "event", f.EventID, "", func(tx *sql.Tx) error {
+		return repository.SetEventMaxOrderCentavos(tx, f.EventID, 0)
+	}); err == nil {
+		t.Error("Admin succeeded without an audit entry")
+	}
+	if n := count(`SELECT COALESCE(max_order_centavos, 0) FROM events WHERE id = ?`, f.EventID); n != 100 {
+		t.Errorf("max_order_centavos = %d, want the unaudited change rolled back", n)
+	}
+}
@@ -1,20 +1,22 @@
 package auth
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(b), err
-}
+// RoleGuestClaim marks single-purpose tokens used to claim a guest account.
+// These tokens must not authenticate regular requests.
+const RoleGuestClaim = "GUEST_CLAIM"
 
-func CheckPassword(hash, password string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
-}
+// RoleGuestOrder marks tokens that let a guest buyer follow one order: the
+// subject is the order ID, not a user, so they never authenticate a session.
+const RoleGuestOrder = "GUEST_ORDER"
+
+// AccessTokenTTL is how long login/register tokens stay valid.
+const AccessTokenTTL = 24 * time.Hour
 
 func NewToken(userID, role, secret string, exp time.Duration) (string, error) {
 	claims := jwt.MapClaims{
@@ -26,3 +28,20 @@ func NewToken(userID, role, secret string, exp time.Duration) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
 }
+
+// ParseToken validates a token signed with secret and returns its subject and role.
+func ParseToken(tokenStr, secret string) (userID, role string, err error) {
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+	if err != nil || !token.Valid {
+		return "", "", errors.New("token inválido")
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", "", errors.New("token inválido")
+	}
+	userID, _ = claims["sub"].(string)
+	role, _ = claims["role"].(string)
+	return userID, role, nil
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password rules shared by registration, guest account claims and seeds.
+const (
+	MinPasswordLength = 6
+	// MaxPasswordBytes is bcrypt's input limit; longer passwords are rejected
+	// rather than silently truncated.
+	MaxPasswordBytes = 72
+)
+
+// passwordCost is the bcrypt cost of new hashes (BCRYPT_COST).
+var passwordCost = bcrypt.DefaultCost
+
+// SetPasswordCost changes the bcrypt cost used by HashPassword. Existing
+// hashes with a lower cost are upgraded by VerifyPassword on the next login.
+func SetPasswordCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("custo do bcrypt deve estar entre %d e %d (recebido %d)", bcrypt.MinCost, bcrypt.MaxCost, cost)
+	}
+	passwordCost = cost
+	return nil
+}
+
+func HashPassword(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	return string(b), err
+}
+
+// CheckPassword reports whether password matches hash. bcrypt compares the
+// derived keys in constant time.
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// VerifyPassword is CheckPassword for logins: when the password matches a
+// hash made with an outdated cost, upgraded is a fresh hash at the current
+// cost that the caller should store. upgraded is "" when no change is needed
+// or rehashing failed (the login still succeeds).
+func VerifyPassword(hash, password string) (ok bool, upgraded string) {
+	if !CheckPassword(hash, password) {
+		return false, ""
+	}
+	if cost, err := bcrypt.Cost([]byte(hash)); err == nil && cost >= passwordCost {
+		return true, ""
+	}
+	newHash, err := HashPassword(password)
+	if err != nil {
+		return true, ""
+	}
+	return true, newHash
+}
+
+// ValidatePassword checks a new password against the platform rules.
+func ValidatePassword(password string) error {
+	if len([]rune(password)) < MinPasswordLength {
+		return fmt.Errorf("senha deve ter ao menos %d caracteres", MinPasswordLength)
+	}
+	if len(password) > MaxPasswordBytes {
+		return errors.New("senha muito longa")
+	}
+	return nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// CheckPasswordUnknownUser burns the same bcrypt work as CheckPassword for a
+// login whose email doesn't exist, so response time doesn't reveal which
+// emails are registered. Always returns false.
+func CheckPasswordUnknownUser(password string) bool {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("afterzin-unknown-user"), passwordCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+	return false
+}
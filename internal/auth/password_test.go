@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerifyPasswordUpgradesOutdatedCost(t *testing.T) {
+	defer func(prev int) { passwordCost = prev }(passwordCost)
+
+	old, err := bcrypt.GenerateFromPassword([]byte("segredo123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetPasswordCost(bcrypt.MinCost + 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, upgraded := VerifyPassword(string(old), "errada"); ok || upgraded != "" {
+		t.Fatalf("wrong password: ok=%v upgraded=%q", ok, upgraded)
+	}
+
+	ok, upgraded := VerifyPassword(string(old), "segredo123")
+	if !ok {
+		t.Fatal("password hashed at the old cost should still verify")
+	}
+	if upgraded == "" {
+		t.Fatal("expected an upgraded hash")
+	}
+	if cost, _ := bcrypt.Cost([]byte(upgraded)); cost != bcrypt.MinCost+1 {
+		t.Errorf("upgraded cost = %d, want %d", cost, bcrypt.MinCost+1)
+	}
+	if !CheckPassword(upgraded, "segredo123") {
+		t.Error("upgraded hash does not verify")
+	}
+
+	// Already at the current cost: nothing to do
+	if ok, again := VerifyPassword(upgraded, "segredo123"); !ok || again != "" {
+		t.Errorf("current hash: ok=%v upgraded=%q", ok, again)
+	}
+}
+
+func TestSetPasswordCostBounds(t *testing.T) {
+	defer func(prev int) { passwordCost = prev }(passwordCost)
+	if err := SetPasswordCost(bcrypt.MinCost - 1); err == nil {
+		t.Error("cost below bcrypt.MinCost accepted")
+	}
+	if err := SetPasswordCost(bcrypt.MaxCost + 1); err == nil {
+		t.Error("cost above bcrypt.MaxCost accepted")
+	}
+}
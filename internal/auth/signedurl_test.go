@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerifyDownload(t *testing.T) {
+	now := time.Now()
+	q := SignDownload("segredo", "attendees", "evento-1", now.Add(10*time.Minute))
+
+	if err := VerifyDownload("segredo", "attendees", "evento-1", q, now); err != nil {
+		t.Errorf("valid link: %v", err)
+	}
+	if err := VerifyDownload("segredo", "attendees", "evento-2", q, now); err != ErrDownloadLinkInvalid {
+		t.Errorf("other resource: err = %v, want ErrDownloadLinkInvalid", err)
+	}
+	if err := VerifyDownload("segredo", "ticket_pdf", "evento-1", q, now); err != ErrDownloadLinkInvalid {
+		t.Errorf("other scope: err = %v, want ErrDownloadLinkInvalid", err)
+	}
+	if err := VerifyDownload("outro", "attendees", "evento-1", q, now); err != ErrDownloadLinkInvalid {
+		t.Errorf("other secret: err = %v, want ErrDownloadLinkInvalid", err)
+	}
+	if err := VerifyDownload("segredo", "attendees", "evento-1", q, now.Add(11*time.Minute)); err != ErrDownloadLinkExpired {
+		t.Errorf("expired: err = %v, want ErrDownloadLinkExpired", err)
+	}
+
+	tampered := SignDownload("segredo", "attendees", "evento-1", now.Add(10*time.Minute))
+	tampered.Set("exp", "9999999999")
+	if err := VerifyDownload("segredo", "attendees", "evento-1", tampered, now); err != ErrDownloadLinkInvalid {
+		t.Errorf("extended expiry: err = %v, want ErrDownloadLinkInvalid", err)
+	}
+}
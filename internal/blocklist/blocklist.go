@@ -0,0 +1,88 @@
+// Package blocklist refuses the CPFs and emails of known fraudsters
+// (blocked_identities) at registration, guest checkout and payment.
+package blocklist
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/validate"
+)
+
+// ErrIdentityBlocked is returned when the CPF or email is on the denylist
+// (blocked_identities). The message is deliberately generic, so a response
+// never tells which field matched or that a denylist exists.
+var ErrIdentityBlocked = errors.New("não foi possível concluir a operação; entre em contato com o suporte")
+
+// ErrCheckFailed is returned when the denylist can't be read; the cause is
+// only logged, for the same reason.
+var ErrCheckFailed = errors.New("erro ao validar cadastro")
+
+// Check returns ErrIdentityBlocked when cpf (any formatting) or email is on
+// the denylist, logging the attempt at WARN for review with both masked. The
+// table is read on every call, so entries added or removed by admins apply
+// immediately. attempt names what was refused in the log ("cadastro",
+// "pagamento do pedido ...").
+func Check(db *sql.DB, cpf, email, attempt string) error {
+	cpf, email = validate.SanitizeDocument(cpf), validate.NormalizeEmail(email)
+	match, err := repository.BlockedIdentityMatch(db, cpf, email)
+	if err != nil {
+		logger.Errorf("erro ao consultar lista de bloqueio (%s): %v", attempt, err)
+		return ErrCheckFailed
+	}
+	if match != nil {
+		logger.Warnf("tentativa bloqueada (%s): %s na lista de bloqueio (cpf=%s email=%s)", attempt, match.Kind, maskCPF(cpf), maskEmail(email))
+		return ErrIdentityBlocked
+	}
+	return nil
+}
+
+// Normalize validates an admin-supplied denylist entry and returns its kind
+// and value in the stored form.
+func Normalize(kind, value string) (string, string, error) {
+	switch kind = strings.ToUpper(strings.TrimSpace(kind)); kind {
+	case repository.BlockedCPF:
+		value = validate.SanitizeDocument(value)
+		if !validate.IsValidCPF(value) {
+			return "", "", errors.New("CPF inválido")
+		}
+	case repository.BlockedEmail:
+		value = validate.NormalizeEmail(value)
+		if err := validate.ValidateEmail(value); err != nil {
+			return "", "", errors.New("email inválido: " + err.Error())
+		}
+	default:
+		return "", "", errors.New("kind inválido: use CPF ou EMAIL")
+	}
+	return kind, value, nil
+}
+
+// Mask hides most of a denylist value for logging: a CPF keeps its first
+// three and last two digits, an email the first letter and the domain.
+func Mask(kind, value string) string {
+	if kind == repository.BlockedCPF {
+		return maskCPF(value)
+	}
+	return maskEmail(value)
+}
+
+// maskCPF keeps the first three and last two digits of a CPF, enough to
+// tell attempts apart in the log without recording the document.
+func maskCPF(cpf string) string {
+	if len(cpf) != 11 {
+		return strings.Repeat("*", len(cpf))
+	}
+	return cpf[:3] + ".***.***-" + cpf[9:]
+}
+
+// maskEmail keeps the first letter of the local part and the domain.
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return strings.Repeat("*", len(email))
+	}
+	return email[:1] + "***" + email[at:]
+}
@@ -0,0 +1,35 @@
+package blocklist
+
+import (
+	"testing"
+
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestCheckMatchesEmail(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	if err := repository.BlockIdentity(sqlite, repository.BlockedEmail, "fraude@email.com", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(sqlite, "", " Fraude@Email.com ", "teste"); err != ErrIdentityBlocked {
+		t.Errorf("blocked email = %v, want ErrIdentityBlocked", err)
+	}
+	if err := Check(sqlite, testutil.BuyerCPF, "outro@email.com", "teste"); err != nil {
+		t.Errorf("other identity = %v, want nil", err)
+	}
+}
+
+func TestMask(t *testing.T) {
+	for _, tc := range []struct{ kind, value, want string }{
+		{repository.BlockedCPF, "52998224725", "529.***.***-25"},
+		{repository.BlockedCPF, "123", "***"},
+		{repository.BlockedEmail, "fraude@email.com", "f***@email.com"},
+		{repository.BlockedEmail, "semarroba", "*********"},
+		{repository.BlockedEmail, "", ""},
+	} {
+		if got := Mask(tc.kind, tc.value); got != tc.want {
+			t.Errorf("Mask(%s, %q) = %q, want %q", tc.kind, tc.value, got, tc.want)
+		}
+	}
+}
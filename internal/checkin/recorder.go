@@ -0,0 +1,92 @@
+// Package checkin records gate scan attempts off the validation hot path.
+package checkin
+
+import (
+	"database/sql"
+	"time"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/repository"
+)
+
+const (
+	queueSize     = 1024
+	batchSize     = 100
+	flushInterval = 2 * time.Second
+)
+
+// Recorder buffers scan attempts and writes them in batches from a background goroutine.
+// A nil *Recorder is valid and records nothing.
+type Recorder struct {
+	db    *sql.DB
+	queue chan repository.CheckinAttempt
+	done  chan struct{}
+}
+
+// NewRecorder starts the background writer. Call Close on shutdown to flush pending attempts.
+func NewRecorder(db *sql.DB) *Recorder {
+	r := &Recorder{
+		db:    db,
+		queue: make(chan repository.CheckinAttempt, queueSize),
+		done:  make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Record enqueues an attempt without blocking; attempts are dropped if the queue is full.
+func (r *Recorder) Record(a repository.CheckinAttempt) {
+	if r == nil {
+		return
+	}
+	if a.ScannedAt.IsZero() {
+		a.ScannedAt = time.Now()
+	}
+	select {
+	case r.queue <- a:
+	default:
+		logger.Warnf("fila de tentativas de check-in cheia; tentativa descartada (evento=%s)", a.EventID)
+	}
+}
+
+// Close stops accepting attempts and waits for the queue to be flushed.
+func (r *Recorder) Close() {
+	if r == nil {
+		return
+	}
+	close(r.queue)
+	<-r.done
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]repository.CheckinAttempt, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := repository.InsertCheckinAttempts(r.db, batch); err != nil {
+			logger.Errorf("erro ao gravar %d tentativas de check-in: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case a, ok := <-r.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, a)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
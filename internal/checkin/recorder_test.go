@@ -0,0 +1,30 @@
+package checkin
+
+import (
+	"testing"
+
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestRecorderFlushesOnClose(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+
+	r := NewRecorder(sqlite)
+	for i := 0; i < batchSize+5; i++ {
+		r.Record(repository.CheckinAttempt{Result: repository.CheckinResultInvalid})
+	}
+	r.Close()
+
+	var n int
+	if err := sqlite.QueryRow(`SELECT COUNT(*) FROM checkin_attempts`).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != batchSize+5 {
+		t.Errorf("attempts = %d, want %d", n, batchSize+5)
+	}
+
+	var nilRecorder *Recorder
+	nilRecorder.Record(repository.CheckinAttempt{Result: repository.CheckinResultOK})
+	nilRecorder.Close()
+}
@@ -4,19 +4,79 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
+
+	"afterzin/api/internal/qrcode"
 )
 
 type Config struct {
 	Port                 int
 	DBPath               string
 	JWTSecret            string
+	QRSigningKeys        []string // ticket QR signing key ring, current key first (QR_SIGNING_KEYS; empty = JWTSecret); see QRKeys
 	Playground           bool
 	CORSOrigins          []string
 	PagarmeAPIKey        string
+	PagarmeEnv           string // "test" or "live"; must match the API key prefix
 	PagarmeWebhookSecret string
 	PagarmeRecipientID   string // Platform's own recipient ID for split
 	PagarmeAppFee        int64  // centavos per ticket (default 500 = R$5.00)
 	BaseURL              string // frontend URL for redirects
+	PagarmeEnsureWebhook bool   // register the webhook subscription with Pagar.me on startup
+	SMTPHost             string // empty disables email delivery (emails are only logged)
+	SMTPPort             int
+	SMTPUsername         string
+	SMTPPassword         string
+	MailFrom             string
+	PlatformName         string // shown to buyers in payment descriptions (default "Afterzin")
+	StatementDescriptor  string // name on the buyer's charge; producers may override (default: PlatformName)
+	DefaultAccountType   string // recipient bank account type when none is sent: checking or savings (default checking)
+	TicketPDFCoverImage  bool   // embed the event cover image in ticket PDFs (fetched over HTTP)
+	OrderRefPrefix       string // prefix of human-friendly order references (default "AFZ")
+	MaxOrderCentavos     int64  // per-order amount cap; events may override (default R$50.000,00)
+	MinOrderCentavos     int64  // smallest amount charged via PIX, the gateway's minimum; 0 disables (default R$1,00)
+	MaxOrderItems        int    // line items per order (default 20)
+	MaxItemQuantity      int    // tickets per line item (default 10)
+	MaxPendingOrders     int    // unpaid orders a user may hold at once; 0 disables (default 3)
+	MaxGuestOrdersPerIP  int    // unpaid guest checkout orders per client address; 0 disables (default 10)
+	MaxCompsPerEvent     int    // complimentary tickets a producer may issue per event; 0 disables the cap (default 50)
+	MaxTicketTransfers   int    // times a ticket may change holders; events may override; 0 disables the cap (default 0)
+	APIPrefix            string // path prefix of the REST routes, e.g. "/api/v1" (default "/v1"; "/" mounts at the root)
+	Compression          bool   // gzip responses for clients that accept it (default true)
+	CompressionMinBytes  int    // smallest response body worth compressing (default 1024)
+	// HTTP server limits. WriteTimeout bounds every response; export and PDF
+	// handlers extend their own deadline to StreamWriteTimeout (0 = no limit).
+	ReadTimeout        time.Duration   // default 15s
+	WriteTimeout       time.Duration   // default 15s
+	IdleTimeout        time.Duration   // default 60s
+	StreamWriteTimeout time.Duration   // default 10m
+	MaxHeaderBytes     int             // default 1 MiB
+	MaxBodyBytes       int64           // request body cap of the REST (JSON) routes; 0 disables (default 1 MiB)
+	MaxUploadBytes     int64           // request body cap of /graphql, which carries base64 image uploads (default 4 MiB)
+	StrictJSON         bool            // reject unknown fields in REST request bodies (default true; STRICT_JSON=false accepts them)
+	JobsEnabled        bool            // run background jobs (default true; disable on extra replicas)
+	JobsDisabled       []string        // names of individual jobs to skip (JOBS_DISABLED, comma-separated)
+	PaymentRegion      string          // region whose currency/document rules apply (default "BR")
+	PaymentCurrency    string          // ISO 4217 currency of orders (default: the region's, BRL)
+	BcryptCost         int             // bcrypt cost of new password hashes; 0 = library default (10)
+	EventSalesGrace    time.Duration   // how long after a date's start time tickets stay on sale (default 0)
+	DefaultTimezone    string          // IANA zone of event dates for events without their own (default America/Sao_Paulo)
+	AbandonedOrderAge  time.Duration   // cancel PENDING orders that never got a PIX after this long; 0 disables (default 30m)
+	FraudCheckRetries  int             // re-fetches of the paid amount before a mismatch is flagged as fraud (default 2)
+	FraudCheckDelay    time.Duration   // wait between those re-fetches (default 1s)
+	FraudCheckMaxWait  time.Duration   // cap on those waits, which delay the webhook response; 0 = no cap (default 3s)
+	WebhookEventTypes  []string        // webhook event types to process, "x.*" allowed (WEBHOOK_EVENT_TYPES; empty = built-in list)
+	Features           map[string]bool // feature flags set by FEATURES; see Feature and DefaultFeatures
+	UnknownFeatures    []string        // names in FEATURES that aren't known flags (reported at startup)
+	// Outbox of emails and notifications (see the outbox-dispatch job).
+	OutboxMaxAttempts int           // delivery attempts before a message is dead-lettered (default 5)
+	OutboxRetryBase   time.Duration // wait before the first retry, doubled after each failure (default 1m)
+	// Data retention (LGPD). 0 disables each window.
+	RetentionOrders time.Duration // delete abandoned PENDING orders older than this (default 0, disabled)
+	RetentionUsers  time.Duration // anonymize accounts inactive for this long (default 0, disabled)
+	RetentionDryRun bool          // log what the retention job would change without changing it
+
+	qrRing qrcode.KeyRing // built from QRKeys by Load; see QRRing
 }
 
 func Load() *Config {
@@ -60,6 +120,7 @@ func Load() *Config {
 	}
 	stripeSecretKey := os.Getenv("PAGARME_API_KEY")
 	stripeWebhookSecret := os.Getenv("PAGARME_WEBHOOK_SECRET")
+	pagarmeEnv := strings.ToLower(strings.TrimSpace(os.Getenv("PAGARME_ENV")))
 	pagarmeRecipientID := os.Getenv("PAGARME_PLATFORM_RECIPIENT_ID")
 	var stripeAppFee int64 = 500 // R$5.00 default
 	if f := os.Getenv("PAGARME_APP_FEE"); f != "" {
@@ -71,17 +132,262 @@ func Load() *Config {
 	if baseURL == "" {
 		baseURL = "http://localhost:4040"
 	}
+	ensureWebhook := os.Getenv("PAGARME_ENSURE_WEBHOOK") == "true" || os.Getenv("PAGARME_ENSURE_WEBHOOK") == "1"
+	smtpPort := 587
+	if p := os.Getenv("SMTP_PORT"); p != "" {
+		if v, err := strconv.Atoi(p); err == nil && v > 0 {
+			smtpPort = v
+		}
+	}
+	ticketPDFCover := os.Getenv("TICKET_PDF_COVER_IMAGE") != "false" && os.Getenv("TICKET_PDF_COVER_IMAGE") != "0"
+	var maxOrderCentavos int64 = 5_000_000 // R$50.000,00 default
+	if v := os.Getenv("MAX_ORDER_CENTAVOS"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			maxOrderCentavos = n
+		}
+	}
+	var minOrderCentavos int64 = 100 // R$1,00, Pagar.me's PIX minimum
+	if v := os.Getenv("MIN_ORDER_CENTAVOS"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			minOrderCentavos = n
+		}
+	}
+	maxOrderItems := 20
+	if v := os.Getenv("MAX_ORDER_ITEMS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			maxOrderItems = n
+		}
+	}
+	maxItemQuantity := 10
+	if v := os.Getenv("MAX_ITEM_QUANTITY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			maxItemQuantity = n
+		}
+	}
+	maxPendingOrders := 3
+	if v := os.Getenv("MAX_PENDING_ORDERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			maxPendingOrders = n
+		}
+	}
+	maxGuestOrdersPerIP := 10
+	if v := os.Getenv("MAX_GUEST_ORDERS_PER_IP"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			maxGuestOrdersPerIP = n
+		}
+	}
+	maxCompsPerEvent := 50
+	if v := os.Getenv("MAX_COMPS_PER_EVENT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			maxCompsPerEvent = n
+		}
+	}
+	maxTicketTransfers := 0
+	if v := os.Getenv("MAX_TICKET_TRANSFERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			maxTicketTransfers = n
+		}
+	}
+	fraudCheckRetries := 2
+	if v := os.Getenv("FRAUD_CHECK_RETRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			fraudCheckRetries = n
+		}
+	}
+	outboxMaxAttempts := 5
+	if v := os.Getenv("OUTBOX_MAX_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 1 {
+			outboxMaxAttempts = n
+		}
+	}
+	orderRefPrefix := strings.ToUpper(strings.TrimSpace(os.Getenv("ORDER_REF_PREFIX")))
+	if orderRefPrefix == "" {
+		orderRefPrefix = "AFZ"
+	}
+	apiPrefix := "/v1"
+	if p := strings.TrimSpace(os.Getenv("API_PREFIX")); p != "" {
+		// Normalize to a leading slash and no trailing slash ("/" becomes "")
+		apiPrefix = strings.TrimRight("/"+strings.Trim(p, "/"), "/")
+	}
+	strictJSON := os.Getenv("STRICT_JSON") != "false" && os.Getenv("STRICT_JSON") != "0"
+	compression := os.Getenv("COMPRESSION") != "false" && os.Getenv("COMPRESSION") != "0"
+	compressionMinBytes := 1024
+	if v := os.Getenv("COMPRESSION_MIN_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			compressionMinBytes = n
+		}
+	}
+	maxBodyBytes := int64(1 << 20)
+	if v := os.Getenv("MAX_BODY_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			maxBodyBytes = n
+		}
+	}
+	maxUploadBytes := int64(4 << 20)
+	if v := os.Getenv("MAX_UPLOAD_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			maxUploadBytes = n
+		}
+	}
+	maxHeaderBytes := 1 << 20
+	if v := os.Getenv("HTTP_MAX_HEADER_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			maxHeaderBytes = n
+		}
+	}
+	bcryptCost, _ := strconv.Atoi(os.Getenv("BCRYPT_COST"))
+	jobsEnabled := os.Getenv("JOBS_ENABLED") != "false" && os.Getenv("JOBS_ENABLED") != "0"
+	var jobsDisabled []string
+	for _, name := range strings.Split(os.Getenv("JOBS_DISABLED"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			jobsDisabled = append(jobsDisabled, name)
+		}
+	}
+	var webhookEventTypes []string
+	for _, t := range strings.Split(os.Getenv("WEBHOOK_EVENT_TYPES"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			webhookEventTypes = append(webhookEventTypes, t)
+		}
+	}
+	var qrSigningKeys []string
+	for _, k := range strings.Split(os.Getenv("QR_SIGNING_KEYS"), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			qrSigningKeys = append(qrSigningKeys, k)
+		}
+	}
+	features, unknownFeatures := ParseFeatures(os.Getenv("FEATURES"))
+	platformName := strings.TrimSpace(os.Getenv("PLATFORM_NAME"))
+	if platformName == "" {
+		platformName = "Afterzin"
+	}
+	defaultAccountType := strings.ToLower(strings.TrimSpace(os.Getenv("DEFAULT_ACCOUNT_TYPE")))
+	if defaultAccountType == "" {
+		defaultAccountType = "checking"
+	}
+	defaultTimezone := strings.TrimSpace(os.Getenv("DEFAULT_TIMEZONE"))
+	if defaultTimezone == "" {
+		defaultTimezone = fallbackTimezone
+	}
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "Afterzin <no-reply@afterzin.com>"
+	}
 
-	return &Config{
+	cfg := &Config{
 		Port:                 port,
 		DBPath:               dbPath,
 		JWTSecret:            jwtSecret,
+		QRSigningKeys:        qrSigningKeys,
 		Playground:           playground,
 		CORSOrigins:          corsOrigins,
 		PagarmeAPIKey:        stripeSecretKey,
+		PagarmeEnv:           pagarmeEnv,
 		PagarmeWebhookSecret: stripeWebhookSecret,
 		PagarmeRecipientID:   pagarmeRecipientID,
 		PagarmeAppFee:        stripeAppFee,
 		BaseURL:              baseURL,
+		PagarmeEnsureWebhook: ensureWebhook,
+		SMTPHost:             os.Getenv("SMTP_HOST"),
+		SMTPPort:             smtpPort,
+		SMTPUsername:         os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:         os.Getenv("SMTP_PASSWORD"),
+		MailFrom:             mailFrom,
+		PlatformName:         platformName,
+		StatementDescriptor:  os.Getenv("STATEMENT_DESCRIPTOR"),
+		DefaultAccountType:   defaultAccountType,
+		TicketPDFCoverImage:  ticketPDFCover,
+		OrderRefPrefix:       orderRefPrefix,
+		MaxOrderCentavos:     maxOrderCentavos,
+		MinOrderCentavos:     minOrderCentavos,
+		MaxOrderItems:        maxOrderItems,
+		MaxItemQuantity:      maxItemQuantity,
+		MaxPendingOrders:     maxPendingOrders,
+		MaxGuestOrdersPerIP:  maxGuestOrdersPerIP,
+		MaxCompsPerEvent:     maxCompsPerEvent,
+		MaxTicketTransfers:   maxTicketTransfers,
+		EventSalesGrace:      durationEnv("EVENT_SALES_GRACE", 0),
+		DefaultTimezone:      defaultTimezone,
+		AbandonedOrderAge:    durationEnv("ABANDONED_ORDER_AGE", 30*time.Minute),
+		FraudCheckRetries:    fraudCheckRetries,
+		FraudCheckDelay:      durationEnv("FRAUD_CHECK_DELAY", time.Second),
+		FraudCheckMaxWait:    durationEnv("FRAUD_CHECK_MAX_WAIT", 3*time.Second),
+		WebhookEventTypes:    webhookEventTypes,
+		Features:             features,
+		UnknownFeatures:      unknownFeatures,
+		OutboxMaxAttempts:    outboxMaxAttempts,
+		OutboxRetryBase:      durationEnv("OUTBOX_RETRY_BASE", time.Minute),
+		RetentionOrders:      durationEnv("RETENTION_PENDING_ORDERS", 0),
+		RetentionUsers:       durationEnv("RETENTION_INACTIVE_USERS", 0),
+		RetentionDryRun:      os.Getenv("RETENTION_DRY_RUN") == "true" || os.Getenv("RETENTION_DRY_RUN") == "1",
+		APIPrefix:            apiPrefix,
+		Compression:          compression,
+		StrictJSON:           strictJSON,
+		CompressionMinBytes:  compressionMinBytes,
+		ReadTimeout:          durationEnv("HTTP_READ_TIMEOUT", 15*time.Second),
+		WriteTimeout:         durationEnv("HTTP_WRITE_TIMEOUT", 15*time.Second),
+		IdleTimeout:          durationEnv("HTTP_IDLE_TIMEOUT", 60*time.Second),
+		StreamWriteTimeout:   durationEnv("HTTP_STREAM_WRITE_TIMEOUT", 10*time.Minute),
+		MaxHeaderBytes:       maxHeaderBytes,
+		MaxBodyBytes:         maxBodyBytes,
+		MaxUploadBytes:       maxUploadBytes,
+		JobsEnabled:          jobsEnabled,
+		PaymentRegion:        strings.ToUpper(strings.TrimSpace(os.Getenv("PAYMENT_REGION"))),
+		PaymentCurrency:      strings.ToUpper(strings.TrimSpace(os.Getenv("PAYMENT_CURRENCY"))),
+		BcryptCost:           bcryptCost,
+		JobsDisabled:         jobsDisabled,
+	}
+	cfg.qrRing = qrcode.NewKeyRing(cfg.QRKeys()...)
+	return cfg
+}
+
+// QRKeys returns the key ring that signs and verifies ticket QR payloads:
+// new payloads are signed with the first key, and a payload signed with any
+// of them is accepted. Without QR_SIGNING_KEYS the ring is just JWTSecret,
+// which is what every payload issued before the ring existed was signed with.
+func (c *Config) QRKeys() []string {
+	if len(c.QRSigningKeys) > 0 {
+		return c.QRSigningKeys
+	}
+	return []string{c.JWTSecret}
+}
+
+// QRRing returns the QR key ring of QRKeys. Load builds it once; a Config
+// built as a literal (as in tests) gets a new one on each call.
+func (c *Config) QRRing() qrcode.KeyRing {
+	if c.qrRing != nil {
+		return c.qrRing
+	}
+	return qrcode.NewKeyRing(c.QRKeys()...)
+}
+
+// fallbackTimezone is the zone of event dates when neither the event nor
+// DEFAULT_TIMEZONE sets one.
+const fallbackTimezone = "America/Sao_Paulo"
+
+// EventTimezone is the zone an event's dates are in: its own (eventTZ), else
+// DEFAULT_TIMEZONE, else America/Sao_Paulo.
+func (c *Config) EventTimezone(eventTZ string) string {
+	switch {
+	case eventTZ != "":
+		return eventTZ
+	case c.DefaultTimezone != "":
+		return c.DefaultTimezone
+	}
+	return fallbackTimezone
+}
+
+// durationEnv parses a Go duration ("30s", "5m") or plain seconds from an
+// environment variable. "0" is kept (no timeout); invalid values use def.
+func durationEnv(name string, def time.Duration) time.Duration {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return def
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return d
+	}
+	if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second
 	}
+	return def
 }
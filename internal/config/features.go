@@ -0,0 +1,100 @@
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// Feature flags. Each names a behavior that can be switched per environment
+// with FEATURES; new flags must be added to DefaultFeatures.
+//
+// Flags gate what clients can call. Internal machinery that other features
+// depend on has none: the outbox delivers every email and notification, so
+// turning it off would drop them; pause its worker with JOBS_DISABLED instead.
+const (
+	FeatureGuestCheckout     = "guest_checkout"        // PIX checkout without an account (POST /payment/create with "guest")
+	FeatureLivePaymentStatus = "live_payment_status"   // ?live=true on GET /payment/status
+	FeaturePayoutReconcile   = "payout_reconciliation" // GET /recipient/payouts
+	FeatureSalesAttribution  = "sales_attribution"     // record channel/UTM sent at checkout
+	FeatureComps             = "complimentary_tickets" // issueComplimentaryTickets
+	FeatureTicketTransfers   = "ticket_transfers"      // transferTicket (past transfers stay readable)
+	FeatureTicketVerify      = "ticket_verify"         // POST /ticket/verify
+	FeatureAddressBook       = "address_book"          // saved addresses and their use as the default payment address
+)
+
+// DefaultFeatures are the known flags and whether they are on when FEATURES
+// doesn't mention them.
+var DefaultFeatures = map[string]bool{
+	FeatureGuestCheckout:     true,
+	FeatureLivePaymentStatus: true,
+	FeaturePayoutReconcile:   true,
+	FeatureSalesAttribution:  true,
+	FeatureComps:             true,
+	FeatureTicketTransfers:   true,
+	FeatureTicketVerify:      true,
+	FeatureAddressBook:       true,
+}
+
+// ParseFeatures reads a FEATURES value: comma-separated flag names, each
+// optionally prefixed with "-" to turn it off or suffixed with "=true" /
+// "=false" ("guest_checkout,-payout_reconciliation"). Names are lowercased.
+// Unknown names are kept (so typos can be reported) and returned separately.
+func ParseFeatures(spec string) (flags map[string]bool, unknown []string) {
+	flags = map[string]bool{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		on := true
+		if strings.HasPrefix(item, "-") {
+			on, item = false, strings.TrimSpace(item[1:])
+		} else if name, value, ok := strings.Cut(item, "="); ok {
+			item = strings.TrimSpace(name)
+			switch strings.TrimSpace(value) {
+			case "false", "0", "off":
+				on = false
+			}
+		}
+		if item == "" {
+			continue
+		}
+		if _, known := DefaultFeatures[item]; !known {
+			unknown = append(unknown, item)
+		}
+		flags[item] = on
+	}
+	return flags, unknown
+}
+
+// Feature reports whether the named feature flag is on: the FEATURES value
+// if set, otherwise the flag's default. Flags neither set nor known are off.
+func (c *Config) Feature(name string) bool {
+	if c != nil {
+		if on, ok := c.Features[name]; ok {
+			return on
+		}
+	}
+	return DefaultFeatures[name]
+}
+
+// EnabledFeatures lists the flags that are on, sorted.
+func (c *Config) EnabledFeatures() []string {
+	var names []string
+	seen := map[string]bool{}
+	for name := range DefaultFeatures {
+		seen[name] = true
+		if c.Feature(name) {
+			names = append(names, name)
+		}
+	}
+	if c != nil {
+		for name, on := range c.Features {
+			if on && !seen[name] {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
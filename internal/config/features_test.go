@@ -0,0 +1,31 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFeatures(t *testing.T) {
+	flags, unknown := ParseFeatures(" Guest_Checkout=false, -sales_attribution ,payout_reconciliation=true,boleto,, =true")
+	want := map[string]bool{
+		FeatureGuestCheckout:    false,
+		FeatureSalesAttribution: false,
+		FeaturePayoutReconcile:  true,
+		"boleto":                true,
+	}
+	if !reflect.DeepEqual(flags, want) {
+		t.Errorf("flags = %v, want %v", flags, want)
+	}
+	if !reflect.DeepEqual(unknown, []string{"boleto"}) {
+		t.Errorf("unknown = %v, want [boleto]", unknown)
+	}
+
+	cfg := &Config{Features: flags}
+	if cfg.Feature(FeatureGuestCheckout) || !cfg.Feature(FeatureComps) || !cfg.Feature("boleto") {
+		t.Error("Feature should prefer FEATURES over defaults")
+	}
+	var nilCfg *Config
+	if !nilCfg.Feature(FeatureGuestCheckout) {
+		t.Error("nil config should use defaults")
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+// redacted replaces secret values in the summary.
+const redacted = "***"
+
+// Summary describes the effective configuration as one line of key=value
+// pairs, for the startup log. Secrets (JWT secret, Pagar.me API key and
+// webhook secret, SMTP password) are never included, not even in part: they
+// show as *** when set and are left empty otherwise. QR signing keys show
+// only as their count.
+func (c *Config) Summary() string {
+	pagarme := "disabled"
+	if c.PagarmeAPIKey != "" {
+		pagarme = "enabled"
+	}
+	fields := []struct{ key, value string }{
+		{"port", strconv.Itoa(c.Port)},
+		{"db_path", c.DBPath},
+		{"api_prefix", c.APIPrefix},
+		{"base_url", c.BaseURL},
+		{"cors_origins", strings.Join(c.CORSOrigins, ",")},
+		{"playground", strconv.FormatBool(c.Playground)},
+		{"pagarme", pagarme},
+		{"pagarme_env", c.PagarmeEnv},
+		{"pagarme_api_key", secret(c.PagarmeAPIKey)},
+		{"pagarme_webhook_secret", secret(c.PagarmeWebhookSecret)},
+		{"pagarme_recipient_id", c.PagarmeRecipientID},
+		{"payment_region", c.PaymentRegion},
+		{"jwt_secret", secret(c.JWTSecret)},
+		{"qr_signing_keys", qrKeysSummary(c.QRSigningKeys)},
+		{"smtp_host", c.SMTPHost},
+		{"smtp_password", secret(c.SMTPPassword)},
+		{"default_timezone", c.DefaultTimezone},
+		{"abandoned_order_age", c.AbandonedOrderAge.String()},
+		{"jobs_enabled", strconv.FormatBool(c.JobsEnabled)},
+		{"features", strings.Join(c.EnabledFeatures(), ",")},
+	}
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = f.key + "=" + quoteIfNeeded(f.value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// secret hides a secret value, keeping only whether it is set.
+func secret(v string) string {
+	if v == "" {
+		return ""
+	}
+	return redacted
+}
+
+// qrKeysSummary shows how many QR signing keys are configured, or
+// "jwt_secret" when the ring falls back to it; the keys themselves are never
+// shown.
+func qrKeysSummary(keys []string) string {
+	if len(keys) == 0 {
+		return "jwt_secret"
+	}
+	return strconv.Itoa(len(keys))
+}
+
+// quoteIfNeeded quotes empty values and values with spaces, quotes or "="
+// so the summary stays parseable as key=value pairs.
+func quoteIfNeeded(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t\"=") {
+		return strconv.Quote(v)
+	}
+	return v
+}
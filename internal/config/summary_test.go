@@ -0,0 +1,62 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummaryRedactsSecrets(t *testing.T) {
+	cfg := &Config{
+		Port:                 8080,
+		DBPath:               "./data/afterzin db.sqlite",
+		CORSOrigins:          []string{"http://a", "http://b"},
+		JWTSecret:            "jwt-super-secret",
+		PagarmeAPIKey:        "sk_test_abcdef123456",
+		PagarmeWebhookSecret: "whsec_987654",
+		SMTPPassword:         "smtp-pass",
+		QRSigningKeys:        []string{"qr-current-key", "qr-previous-key"},
+	}
+	got := cfg.Summary()
+	for _, secret := range []string{"jwt-super", "sk_test", "abcdef", "123456", "whsec", "987654", "smtp-pass", "qr-current", "qr-previous"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("summary leaks %q: %s", secret, got)
+		}
+	}
+	for _, want := range []string{
+		"port=8080", `db_path="./data/afterzin db.sqlite"`, "cors_origins=http://a,http://b",
+		"pagarme=enabled", "pagarme_api_key=***", "jwt_secret=***", "smtp_password=***", "qr_signing_keys=2", "features=" + FeatureAddressBook + "," + FeatureComps,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summary missing %q: %s", want, got)
+		}
+	}
+
+	if got := (&Config{}).Summary(); !strings.Contains(got, `pagarme=disabled`) || !strings.Contains(got, `jwt_secret=""`) || !strings.Contains(got, "qr_signing_keys=jwt_secret") {
+		t.Errorf("empty config summary = %s", got)
+	}
+}
+
+func TestQRKeysFallsBackToJWTSecret(t *testing.T) {
+	cfg := &Config{JWTSecret: "jwt"}
+	if got := cfg.QRKeys(); len(got) != 1 || got[0] != "jwt" {
+		t.Errorf("QRKeys() without a ring = %v, want [jwt]", got)
+	}
+	cfg.QRSigningKeys = []string{"new", "old"}
+	if got := cfg.QRKeys(); len(got) != 2 || got[0] != "new" || got[1] != "old" {
+		t.Errorf("QRKeys() = %v, want [new old]", got)
+	}
+}
+
+func TestEventTimezoneFallbacks(t *testing.T) {
+	cfg := &Config{}
+	if got := cfg.EventTimezone(""); got != "America/Sao_Paulo" {
+		t.Errorf("fallback = %s", got)
+	}
+	cfg.DefaultTimezone = "America/Manaus"
+	if got := cfg.EventTimezone(""); got != "America/Manaus" {
+		t.Errorf("default = %s", got)
+	}
+	if got := cfg.EventTimezone("America/Rio_Branco"); got != "America/Rio_Branco" {
+		t.Errorf("event = %s", got)
+	}
+}
@@ -0,0 +1,6 @@
+-- Pagar.me environment tagging
+-- Records whether orders/recipients were created with test or live keys,
+-- so cross-environment data can be detected
+
+ALTER TABLE orders ADD COLUMN pagarme_env TEXT;
+ALTER TABLE producers ADD COLUMN pagarme_env TEXT;
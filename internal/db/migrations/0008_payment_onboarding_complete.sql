@@ -0,0 +1,7 @@
+-- Gateway-neutral onboarding flag
+-- Replaces stripe_onboarding_complete (leftover from the Stripe integration).
+-- The old column is kept and still written during the transition window.
+
+ALTER TABLE producers ADD COLUMN payment_onboarding_complete INTEGER NOT NULL DEFAULT 0;
+
+UPDATE producers SET payment_onboarding_complete = stripe_onboarding_complete;
@@ -0,0 +1,11 @@
+-- Ticket resend log
+-- Each row is one self-service "resend tickets" email, used for rate limiting
+
+CREATE TABLE IF NOT EXISTS ticket_resends (
+  id TEXT PRIMARY KEY,
+  order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_ticket_resends_order ON ticket_resends(order_id, created_at);
@@ -0,0 +1,15 @@
+-- Check-in attempt log
+-- Every scan (accepted or rejected) for duplicate/invalid scan analytics.
+-- event_id/ticket_id are NULL when the scanned code could not be resolved.
+
+CREATE TABLE IF NOT EXISTS checkin_attempts (
+  id TEXT PRIMARY KEY,
+  event_id TEXT REFERENCES events(id) ON DELETE CASCADE,
+  ticket_id TEXT REFERENCES tickets(id) ON DELETE CASCADE,
+  producer_id TEXT REFERENCES producers(id) ON DELETE SET NULL,
+  result TEXT NOT NULL CHECK (result IN ('ok', 'already_used', 'invalid', 'wrong_event')),
+  scanned_at TEXT NOT NULL DEFAULT (datetime('now')),
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_checkin_attempts_event ON checkin_attempts(event_id, result);
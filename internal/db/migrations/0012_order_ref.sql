@@ -0,0 +1,26 @@
+-- Human-friendly order reference (e.g. AFZ-2025-000123)
+-- Numbers are sequential per year, drawn atomically from order_ref_counters.
+
+CREATE TABLE IF NOT EXISTS order_ref_counters (
+  year INTEGER PRIMARY KEY,
+  last_value INTEGER NOT NULL
+);
+
+ALTER TABLE orders ADD COLUMN order_ref TEXT;
+
+-- Backfill existing orders in creation order, per year. The prefix is the
+-- default 'AFZ': migrations can't read ORDER_REF_PREFIX, and references are
+-- never rewritten once issued, so a custom prefix applies to new orders only.
+UPDATE orders SET order_ref = (
+  SELECT printf('AFZ-%s-%06d', strftime('%Y', numbered.created_at), numbered.n)
+  FROM (
+    SELECT id, created_at, ROW_NUMBER() OVER (PARTITION BY strftime('%Y', created_at) ORDER BY created_at, id) AS n
+    FROM orders
+  ) AS numbered
+  WHERE numbered.id = orders.id
+);
+
+INSERT INTO order_ref_counters (year, last_value)
+SELECT CAST(strftime('%Y', created_at) AS INTEGER), COUNT(*) FROM orders GROUP BY strftime('%Y', created_at);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_order_ref ON orders(order_ref);
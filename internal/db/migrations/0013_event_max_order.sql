@@ -0,0 +1,4 @@
+-- Per-event override of the maximum order amount (MAX_ORDER_CENTAVOS).
+-- NULL means the platform default applies.
+
+ALTER TABLE events ADD COLUMN max_order_centavos INTEGER;
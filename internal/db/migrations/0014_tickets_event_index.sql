@@ -0,0 +1,2 @@
+-- Attendee export and check-in stats scan tickets by event
+CREATE INDEX IF NOT EXISTS idx_tickets_event ON tickets(event_id);
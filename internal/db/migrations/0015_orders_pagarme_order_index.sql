@@ -0,0 +1,2 @@
+-- Support lookups by Pagar.me order ID (dashboard → internal order)
+CREATE INDEX IF NOT EXISTS idx_orders_pagarme_order ON orders(pagarme_order_id);
@@ -0,0 +1,4 @@
+-- Optional venue capacity. The lots of each event date may not add up to more
+-- than this. NULL means unlimited.
+
+ALTER TABLE events ADD COLUMN capacity INTEGER;
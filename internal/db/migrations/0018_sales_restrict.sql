@@ -0,0 +1,53 @@
+-- Deletion policy for the event catalog:
+--   producers → events → event_dates → lots → ticket_types cascade, since
+--   they are purely structural; sales (order_items, tickets) reference the
+--   catalog with ON DELETE RESTRICT, so a producer, event, date or ticket type
+--   that has sold anything cannot be deleted. See repository/delete.go.
+--
+-- SQLite can't change a foreign key in place, so order_items and tickets are
+-- rebuilt. foreign_keys can only be toggled outside a transaction.
+
+PRAGMA foreign_keys = OFF;
+
+BEGIN;
+
+CREATE TABLE order_items_new (
+  id TEXT PRIMARY KEY,
+  order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  event_date_id TEXT NOT NULL REFERENCES event_dates(id) ON DELETE RESTRICT,
+  ticket_type_id TEXT NOT NULL REFERENCES ticket_types(id) ON DELETE RESTRICT,
+  quantity INTEGER NOT NULL,
+  unit_price REAL NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+INSERT INTO order_items_new (id, order_id, event_date_id, ticket_type_id, quantity, unit_price, created_at)
+  SELECT id, order_id, event_date_id, ticket_type_id, quantity, unit_price, created_at FROM order_items;
+DROP TABLE order_items;
+ALTER TABLE order_items_new RENAME TO order_items;
+
+CREATE TABLE tickets_new (
+  id TEXT PRIMARY KEY,
+  code TEXT NOT NULL UNIQUE,
+  qr_code TEXT NOT NULL UNIQUE,
+  order_id TEXT NOT NULL REFERENCES orders(id),
+  order_item_id TEXT NOT NULL REFERENCES order_items(id),
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  event_id TEXT NOT NULL REFERENCES events(id) ON DELETE RESTRICT,
+  event_date_id TEXT NOT NULL REFERENCES event_dates(id) ON DELETE RESTRICT,
+  ticket_type_id TEXT NOT NULL REFERENCES ticket_types(id) ON DELETE RESTRICT,
+  used INTEGER NOT NULL DEFAULT 0,
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),
+  used_at TEXT
+);
+INSERT INTO tickets_new (id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, used, created_at, used_at)
+  SELECT id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, used, created_at, used_at FROM tickets;
+DROP TABLE tickets;
+ALTER TABLE tickets_new RENAME TO tickets;
+
+CREATE INDEX IF NOT EXISTS idx_tickets_user ON tickets(user_id);
+CREATE INDEX IF NOT EXISTS idx_tickets_qr ON tickets(qr_code);
+CREATE INDEX IF NOT EXISTS idx_tickets_event ON tickets(event_id);
+
+COMMIT;
+
+PRAGMA foreign_keys = ON;
@@ -0,0 +1,7 @@
+-- Public profile of a producer, shown to buyers on event pages. logo_url is an
+-- http(s) URL or a data URI, like users.photo_url.
+
+ALTER TABLE producers ADD COLUMN display_name TEXT;
+ALTER TABLE producers ADD COLUMN description TEXT;
+ALTER TABLE producers ADD COLUMN logo_url TEXT;
+ALTER TABLE producers ADD COLUMN contact_email TEXT;
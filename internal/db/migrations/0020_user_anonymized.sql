@@ -0,0 +1,3 @@
+-- Set when the data-retention job anonymizes an inactive account, so it is
+-- skipped on later runs.
+ALTER TABLE users ADD COLUMN anonymized_at TEXT;
@@ -0,0 +1,5 @@
+-- Last Pagar.me recipient status received by webhook. status_at is the event's
+-- created_at, used to ignore deliveries that arrive out of order.
+
+ALTER TABLE producers ADD COLUMN pagarme_recipient_status TEXT;
+ALTER TABLE producers ADD COLUMN pagarme_recipient_status_at TEXT;
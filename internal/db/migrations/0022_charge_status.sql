@@ -0,0 +1,6 @@
+-- Lifecycle of the order's Pagar.me charge (pending, authorized, captured,
+-- paid, failed, canceled), tracked apart from orders.status so methods that
+-- authorize before capturing don't need new order states. NULL for orders
+-- that never reached the gateway.
+
+ALTER TABLE orders ADD COLUMN charge_status TEXT;
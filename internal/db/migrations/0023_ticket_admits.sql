@@ -0,0 +1,10 @@
+-- Ticket types that let several people in on one ticket (a table, a family
+-- pass). Each sold unit issues one ticket carrying ticket_types.admits entries;
+-- check-in counts tickets.admits_remaining down and sets used at zero.
+-- Lot capacity counts people, so a sold unit takes admits places from the lot.
+
+ALTER TABLE ticket_types ADD COLUMN admits INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE tickets ADD COLUMN admits INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE tickets ADD COLUMN admits_remaining INTEGER NOT NULL DEFAULT 1;
+
+UPDATE tickets SET admits_remaining = 0 WHERE used = 1;
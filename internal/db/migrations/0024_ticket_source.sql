@@ -0,0 +1,6 @@
+-- How a ticket was issued: PURCHASE (paid order) or COMP (complimentary,
+-- issued by the producer without payment). Comps still take inventory.
+
+ALTER TABLE tickets ADD COLUMN source TEXT NOT NULL DEFAULT 'PURCHASE';
+
+CREATE INDEX IF NOT EXISTS idx_tickets_event_source ON tickets(event_id, source);
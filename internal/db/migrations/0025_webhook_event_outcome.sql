@@ -0,0 +1,5 @@
+-- Whether a received webhook event was handled or ignored (type not in the
+-- allowlist, or no handler for it). NULL for events received before this.
+ALTER TABLE pagarme_webhook_events ADD COLUMN outcome TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_pagarme_wh_type_outcome ON pagarme_webhook_events(event_type, outcome);
@@ -0,0 +1,3 @@
+-- Per-producer override of the name buyers see for the charge (PIX
+-- additional information / card statement). NULL uses the platform default.
+ALTER TABLE producers ADD COLUMN statement_descriptor TEXT;
@@ -0,0 +1,4 @@
+-- Marketing attribution of an order: the sales channel (whitelisted, see
+-- pagarme.SalesChannels) and the free-form UTM parameters as JSON.
+ALTER TABLE orders ADD COLUMN channel TEXT;
+ALTER TABLE orders ADD COLUMN utm TEXT;
@@ -0,0 +1,150 @@
+-- Timestamps are stored as UTC RFC3339 ("2026-01-02T15:04:05Z"), see
+-- repository.FormatTime. Convert existing values written by datetime('now')
+-- ("2026-01-02 15:04:05", also UTC), and rewrite the column defaults, which
+-- still use datetime('now'), right after each insert.
+
+UPDATE audit_log SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE checkin_attempts SET scanned_at = strftime('%Y-%m-%dT%H:%M:%SZ', scanned_at) WHERE scanned_at LIKE '____-__-__ __:__:__';
+UPDATE checkin_attempts SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE event_dates SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE events SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE events SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', updated_at) WHERE updated_at LIKE '____-__-__ __:__:__';
+UPDATE lots SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE notifications SET read_at = strftime('%Y-%m-%dT%H:%M:%SZ', read_at) WHERE read_at LIKE '____-__-__ __:__:__';
+UPDATE notifications SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE order_items SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE order_status_history SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE orders SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE pagarme_webhook_events SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE pagarme_webhook_events SET processed_at = strftime('%Y-%m-%dT%H:%M:%SZ', processed_at) WHERE processed_at LIKE '____-__-__ __:__:__';
+UPDATE producers SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE producers SET pagarme_recipient_status_at = strftime('%Y-%m-%dT%H:%M:%SZ', pagarme_recipient_status_at) WHERE pagarme_recipient_status_at LIKE '____-__-__ __:__:__';
+UPDATE stripe_webhook_events SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE ticket_resends SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE ticket_types SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE ticket_validations SET validated_at = strftime('%Y-%m-%dT%H:%M:%SZ', validated_at) WHERE validated_at LIKE '____-__-__ __:__:__';
+UPDATE ticket_validations SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE tickets SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE tickets SET used_at = strftime('%Y-%m-%dT%H:%M:%SZ', used_at) WHERE used_at LIKE '____-__-__ __:__:__';
+UPDATE users SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at LIKE '____-__-__ __:__:__';
+UPDATE users SET anonymized_at = strftime('%Y-%m-%dT%H:%M:%SZ', anonymized_at) WHERE anonymized_at LIKE '____-__-__ __:__:__';
+
+CREATE TRIGGER IF NOT EXISTS trg_audit_log_created_at_utc AFTER INSERT ON audit_log
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE audit_log SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_checkin_attempts_scanned_at_utc AFTER INSERT ON checkin_attempts
+WHEN NEW.scanned_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE checkin_attempts SET scanned_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.scanned_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_checkin_attempts_created_at_utc AFTER INSERT ON checkin_attempts
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE checkin_attempts SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_event_dates_created_at_utc AFTER INSERT ON event_dates
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE event_dates SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_events_created_at_utc AFTER INSERT ON events
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE events SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_events_updated_at_utc AFTER INSERT ON events
+WHEN NEW.updated_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE events SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.updated_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_lots_created_at_utc AFTER INSERT ON lots
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE lots SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_notifications_created_at_utc AFTER INSERT ON notifications
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE notifications SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_order_items_created_at_utc AFTER INSERT ON order_items
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE order_items SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_order_status_history_created_at_utc AFTER INSERT ON order_status_history
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE order_status_history SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_orders_created_at_utc AFTER INSERT ON orders
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE orders SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_pagarme_webhook_events_created_at_utc AFTER INSERT ON pagarme_webhook_events
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE pagarme_webhook_events SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_producers_created_at_utc AFTER INSERT ON producers
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE producers SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_stripe_webhook_events_created_at_utc AFTER INSERT ON stripe_webhook_events
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE stripe_webhook_events SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_ticket_resends_created_at_utc AFTER INSERT ON ticket_resends
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE ticket_resends SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_ticket_types_created_at_utc AFTER INSERT ON ticket_types
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE ticket_types SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_ticket_validations_validated_at_utc AFTER INSERT ON ticket_validations
+WHEN NEW.validated_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE ticket_validations SET validated_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.validated_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_ticket_validations_created_at_utc AFTER INSERT ON ticket_validations
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE ticket_validations SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_tickets_created_at_utc AFTER INSERT ON tickets
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE tickets SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_users_created_at_utc AFTER INSERT ON users
+WHEN NEW.created_at LIKE '____-__-__ __:__:__'
+BEGIN
+  UPDATE users SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', NEW.created_at) WHERE rowid = NEW.rowid;
+END;
@@ -0,0 +1,3 @@
+-- IANA time zone of the event's dates and start times ("America/Manaus").
+-- NULL uses the DEFAULT_TIMEZONE setting (America/Sao_Paulo by default).
+ALTER TABLE events ADD COLUMN timezone TEXT;
@@ -0,0 +1,11 @@
+-- Denylist of CPFs (digits only) and emails (lowercased) refused at
+-- registration and payment creation. Read on every check, so changes apply
+-- without a restart.
+CREATE TABLE IF NOT EXISTS blocked_identities (
+  kind TEXT NOT NULL CHECK (kind IN ('CPF', 'EMAIL')),
+  value TEXT NOT NULL,
+  reason TEXT,
+  created_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+  PRIMARY KEY (kind, value)
+);
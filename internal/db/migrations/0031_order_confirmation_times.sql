@@ -0,0 +1,12 @@
+-- When the order's current PIX was created and when its payment was
+-- confirmed, to measure how long confirmations take (webhook delivery plus
+-- processing). Older paid orders get confirmed_at from their status history;
+-- their PIX creation time was never recorded.
+ALTER TABLE orders ADD COLUMN pix_created_at TEXT;
+ALTER TABLE orders ADD COLUMN confirmed_at TEXT;
+
+UPDATE orders SET confirmed_at = (
+  SELECT MIN(h.created_at) FROM order_status_history h WHERE h.order_id = orders.id AND h.new_status = 'PAID'
+) WHERE status <> 'PENDING';
+
+CREATE INDEX IF NOT EXISTS idx_orders_confirmed_at ON orders(confirmed_at);
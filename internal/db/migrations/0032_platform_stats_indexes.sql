@@ -0,0 +1,5 @@
+-- Indexes for the admin platformStats query: orders by creation time (orders
+-- by status over a period) and order items by order (tickets of each paid
+-- order); paid orders are found through idx_orders_confirmed_at.
+CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at);
+CREATE INDEX IF NOT EXISTS idx_order_items_order ON order_items(order_id);
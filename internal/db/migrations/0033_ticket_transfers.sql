@@ -0,0 +1,16 @@
+-- Whether an event's tickets may be passed on to another user, and how many
+-- times each at most (NULL uses MAX_TICKET_TRANSFERS). Existing events stay
+-- transferable.
+ALTER TABLE events ADD COLUMN transferable INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE events ADD COLUMN max_ticket_transfers INTEGER;
+
+-- Chain of custody of transferred tickets: one row per transfer, oldest first.
+CREATE TABLE IF NOT EXISTS ticket_transfers (
+  id TEXT PRIMARY KEY,
+  ticket_id TEXT NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+  from_user_id TEXT NOT NULL REFERENCES users(id),
+  to_user_id TEXT NOT NULL REFERENCES users(id),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_ticket_transfers_ticket ON ticket_transfers(ticket_id, created_at);
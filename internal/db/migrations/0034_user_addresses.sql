@@ -0,0 +1,22 @@
+-- Saved addresses of a user (address book), for payment methods that need the
+-- buyer's address. Fields mirror pagarme.Address; zip_code is digits only and
+-- state a UF. At most one address per user is the default.
+CREATE TABLE IF NOT EXISTS user_addresses (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  label TEXT,
+  street TEXT NOT NULL,
+  street_number TEXT NOT NULL,
+  complementary TEXT,
+  neighborhood TEXT NOT NULL,
+  city TEXT NOT NULL,
+  state TEXT NOT NULL,
+  zip_code TEXT NOT NULL,
+  reference_point TEXT,
+  is_default INTEGER NOT NULL DEFAULT 0,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+  updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_addresses_user ON user_addresses(user_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_addresses_default ON user_addresses(user_id) WHERE is_default = 1;
@@ -0,0 +1,18 @@
+-- Emails and in-app notifications waiting to be delivered. Rows are written
+-- (when possible in the transaction of the event that triggers them) as
+-- PENDING and retried with backoff by the outbox-dispatch job until SENT, or
+-- DEAD after OUTBOX_MAX_ATTEMPTS failed attempts. payload is JSON whose format
+-- depends on kind.
+CREATE TABLE IF NOT EXISTS outbox (
+  id TEXT PRIMARY KEY,
+  kind TEXT NOT NULL CHECK (kind IN ('email', 'notification')),
+  payload TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'SENT', 'DEAD')),
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT,
+  next_attempt_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+  sent_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_due ON outbox(status, next_attempt_at);
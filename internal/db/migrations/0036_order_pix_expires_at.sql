@@ -0,0 +1,9 @@
+-- When the order's current PIX expires, as returned by Pagar.me. A new PIX
+-- can be generated for an older PENDING order, so the order's own expires_at
+-- doesn't tell when its PIX stops being payable. Pending orders that already
+-- have a PIX get its creation time plus the 15-minute PIX lifetime, or the
+-- previous expires_at-based limit when the creation time wasn't recorded.
+ALTER TABLE orders ADD COLUMN pix_expires_at TEXT;
+
+UPDATE orders SET pix_expires_at = strftime('%Y-%m-%dT%H:%M:%SZ', COALESCE(pix_created_at, expires_at), '+900 seconds')
+WHERE status = 'PENDING' AND COALESCE(pagarme_order_id, '') <> '';
@@ -0,0 +1,7 @@
+-- Orders in the legacy CONFIRMED status count as paid, but 0031 only
+-- backfilled confirmed_at from PAID transitions. Take it from their first
+-- PAID/CONFIRMED transition, or their creation when none was recorded.
+UPDATE orders SET confirmed_at = COALESCE(
+  (SELECT MIN(h.created_at) FROM order_status_history h WHERE h.order_id = orders.id AND h.new_status IN ('PAID', 'CONFIRMED')),
+  created_at
+) WHERE status = 'CONFIRMED' AND confirmed_at IS NULL;
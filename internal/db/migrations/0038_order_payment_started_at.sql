@@ -0,0 +1,5 @@
+-- When CreatePayment claimed the order, right before calling Pagar.me.
+-- UpdateOrderItems refuses orders whose payment has started, so the PIX is
+-- always charged for the items stored. Cleared again when no PIX comes out
+-- of the call.
+ALTER TABLE orders ADD COLUMN payment_started_at TEXT;
@@ -0,0 +1,6 @@
+-- The PIX copy-and-paste code and QR image of the order's current PIX, saved
+-- when CreatePayment creates it. The order query reads them from here instead
+-- of asking Pagar.me on every view; orders paid with a PIX created before
+-- this migration have none stored and show it only after an explicit refresh.
+ALTER TABLE orders ADD COLUMN pix_qr_code TEXT;
+ALTER TABLE orders ADD COLUMN pix_qr_code_url TEXT;
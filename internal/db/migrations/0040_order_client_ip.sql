@@ -0,0 +1,7 @@
+-- The address a guest checkout came from. Guests get a new account for each
+-- email, so the per-user pending order limit doesn't stop one client from
+-- holding many unpaid orders; CreateOrderWithItems also caps them per
+-- address. Left NULL for orders of logged-in buyers.
+ALTER TABLE orders ADD COLUMN client_ip TEXT;
+
+CREATE INDEX idx_orders_client_ip ON orders(client_ip) WHERE client_ip IS NOT NULL;
@@ -0,0 +1,6 @@
+-- Whether the order took its lot places when it was created. Orders created
+-- with items reserve them in the same transaction, so a buyer holding a
+-- PENDING order can't find the lot sold out when paying; cancelling,
+-- expiring or purging the order gives them back. Orders that predate this
+-- column hold nothing and still take their places when paid.
+ALTER TABLE orders ADD COLUMN inventory_reserved INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,26 @@
+-- Inserts now set their timestamps explicitly in the stored format
+-- (repository.nowSQL), so the triggers added by 0028 to rewrite the
+-- datetime('now') column defaults are no longer needed. SQLite can't change
+-- a column default without rebuilding the table; the defaults stay, for rows
+-- inserted by hand, and ParseTime still reads their format.
+
+DROP TRIGGER IF EXISTS trg_audit_log_created_at_utc;
+DROP TRIGGER IF EXISTS trg_checkin_attempts_scanned_at_utc;
+DROP TRIGGER IF EXISTS trg_checkin_attempts_created_at_utc;
+DROP TRIGGER IF EXISTS trg_event_dates_created_at_utc;
+DROP TRIGGER IF EXISTS trg_events_created_at_utc;
+DROP TRIGGER IF EXISTS trg_events_updated_at_utc;
+DROP TRIGGER IF EXISTS trg_lots_created_at_utc;
+DROP TRIGGER IF EXISTS trg_notifications_created_at_utc;
+DROP TRIGGER IF EXISTS trg_order_items_created_at_utc;
+DROP TRIGGER IF EXISTS trg_order_status_history_created_at_utc;
+DROP TRIGGER IF EXISTS trg_orders_created_at_utc;
+DROP TRIGGER IF EXISTS trg_pagarme_webhook_events_created_at_utc;
+DROP TRIGGER IF EXISTS trg_producers_created_at_utc;
+DROP TRIGGER IF EXISTS trg_stripe_webhook_events_created_at_utc;
+DROP TRIGGER IF EXISTS trg_ticket_resends_created_at_utc;
+DROP TRIGGER IF EXISTS trg_ticket_types_created_at_utc;
+DROP TRIGGER IF EXISTS trg_ticket_validations_validated_at_utc;
+DROP TRIGGER IF EXISTS trg_ticket_validations_created_at_utc;
+DROP TRIGGER IF EXISTS trg_tickets_created_at_utc;
+DROP TRIGGER IF EXISTS trg_users_created_at_utc;
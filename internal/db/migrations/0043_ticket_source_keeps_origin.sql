@@ -0,0 +1,4 @@
+-- A transfer no longer rewrites tickets.source: the chain of custody lives in
+-- ticket_transfers, and source keeps how the ticket was first issued. Comps
+-- can't be transferred, so every ticket marked TRANSFER started as a purchase.
+UPDATE tickets SET source = 'PURCHASE' WHERE source = 'TRANSFER';
@@ -2,31 +2,154 @@ package seeds
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"afterzin/api/internal/auth"
 )
 
+// seedTables are cleared in this order (children before parents).
+var seedTables = []string{
+	"ticket_validations", "tickets", "order_items", "orders",
+	"ticket_types", "lots", "event_dates", "events",
+	"producers", "users",
+}
+
+// execer is the part of *sql.DB the seed steps use, so a dry run can record
+// the statements instead of executing them.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// TableCount is a number of rows in a table.
+type TableCount struct {
+	Table string
+	Rows  int
+}
+
+// Report describes what a seed run deletes and inserts, per table.
+type Report struct {
+	Cleared  []TableCount
+	Inserted []TableCount
+}
+
+// DefaultPassword is the password of seed users when SEED_PASSWORD is unset.
+const DefaultPassword = "123456"
+
+// seedAdminCPF is a valid CPF reserved for the seeded admin.
+const seedAdminCPF = "529.982.247-25"
+
+// Options configures the seeded accounts.
+type Options struct {
+	Password      string // password of every seed user
+	AdminEmail    string // when set, an ADMIN user is created with this email
+	AdminName     string
+	AdminPassword string // defaults to Password
+}
+
+// OptionsFromEnv reads SEED_PASSWORD, SEED_ADMIN_EMAIL, SEED_ADMIN_NAME and
+// SEED_ADMIN_PASSWORD.
+func OptionsFromEnv() Options {
+	return Options{
+		Password:      os.Getenv("SEED_PASSWORD"),
+		AdminEmail:    strings.ToLower(strings.TrimSpace(os.Getenv("SEED_ADMIN_EMAIL"))),
+		AdminName:     strings.TrimSpace(os.Getenv("SEED_ADMIN_NAME")),
+		AdminPassword: os.Getenv("SEED_ADMIN_PASSWORD"),
+	}
+}
+
+// withDefaults fills unset options and validates the passwords with the
+// same rules as registration.
+func (o Options) withDefaults() (Options, error) {
+	if o.Password == "" {
+		o.Password = DefaultPassword
+	}
+	if err := auth.ValidatePassword(o.Password); err != nil {
+		return o, fmt.Errorf("SEED_PASSWORD: %w", err)
+	}
+	if o.AdminEmail == "" {
+		return o, nil
+	}
+	if !strings.Contains(o.AdminEmail, "@") {
+		return o, fmt.Errorf("SEED_ADMIN_EMAIL inválido: %s", o.AdminEmail)
+	}
+	if o.AdminName == "" {
+		o.AdminName = "Administrador"
+	}
+	if o.AdminPassword == "" {
+		o.AdminPassword = o.Password
+	}
+	if err := auth.ValidatePassword(o.AdminPassword); err != nil {
+		return o, fmt.Errorf("SEED_ADMIN_PASSWORD: %w", err)
+	}
+	return o, nil
+}
+
 // Run clears seed-related data and inserts fresh seed data.
 // Safe to run multiple times (resets to seed state).
-func Run(db *sql.DB) error {
+func Run(db *sql.DB, opts Options) error {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return err
+	}
 	if err := clear(db); err != nil {
 		return fmt.Errorf("clear: %w", err)
 	}
-	if err := insert(db); err != nil {
+	if err := insert(db, opts); err != nil {
 		return fmt.Errorf("insert: %w", err)
 	}
 	return nil
 }
 
-func clear(db *sql.DB) error {
-	tables := []string{
-		"tickets", "order_items", "orders",
-		"ticket_types", "lots", "event_dates", "events",
-		"producers", "users",
+// DryRun reports what Run would delete (current row counts) and insert,
+// without writing anything.
+func DryRun(db *sql.DB, opts Options) (*Report, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
 	}
-	for _, t := range tables {
+	r := &Report{}
+	for _, t := range seedTables {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + t).Scan(&n); err != nil {
+			return nil, fmt.Errorf("count %s: %w", t, err)
+		}
+		r.Cleared = append(r.Cleared, TableCount{Table: t, Rows: n})
+	}
+	rec := &recordingExecer{}
+	if err := insert(rec, opts); err != nil {
+		return nil, fmt.Errorf("insert: %w", err)
+	}
+	r.Inserted = rec.counts
+	return r, nil
+}
+
+// recordingExecer counts INSERT statements per table without executing them.
+type recordingExecer struct {
+	counts []TableCount
+}
+
+func (e *recordingExecer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	fields := strings.Fields(query)
+	if len(fields) < 3 || !strings.EqualFold(fields[0], "INSERT") {
+		return nil, fmt.Errorf("dry run: unexpected statement %q", query)
+	}
+	table := fields[2]
+	for i := range e.counts {
+		if e.counts[i].Table == table {
+			e.counts[i].Rows++
+			return driver.RowsAffected(1), nil
+		}
+	}
+	e.counts = append(e.counts, TableCount{Table: table, Rows: 1})
+	return driver.RowsAffected(1), nil
+}
+
+func clear(db execer) error {
+	for _, t := range seedTables {
 		if _, err := db.Exec("DELETE FROM " + t); err != nil {
 			return fmt.Errorf("delete %s: %w", t, err)
 		}
@@ -34,9 +157,8 @@ func clear(db *sql.DB) error {
 	return nil
 }
 
-func insert(db *sql.DB) error {
-	// Password for all seed users: "123456"
-	passwordHash, err := auth.HashPassword("123456")
+func insert(db execer, opts Options) error {
+	passwordHash, err := auth.HashPassword(opts.Password)
 	if err != nil {
 		return fmt.Errorf("hash password: %w", err)
 	}
@@ -57,6 +179,26 @@ func insert(db *sql.DB) error {
 		{"seed-user-2", "Maria Santos", "maria@email.com", passwordHash, "987.654.321-00", "1988-11-20", "USER"},
 		{"seed-producer-user", "Produtor Eventos", "produtor@email.com", passwordHash, "111.222.333-44", "1985-03-10", "USER"},
 	}
+	if opts.AdminEmail != "" {
+		adminHash, err := auth.HashPassword(opts.AdminPassword)
+		if err != nil {
+			return fmt.Errorf("hash admin password: %w", err)
+		}
+		for _, u := range users {
+			if u.email == opts.AdminEmail {
+				return fmt.Errorf("SEED_ADMIN_EMAIL %s já é usado por outro usuário de seed", opts.AdminEmail)
+			}
+		}
+		users = append(users, struct {
+			id           string
+			name         string
+			email        string
+			passwordHash string
+			cpf          string
+			birthDate    string
+			role         string
+		}{"seed-admin", opts.AdminName, opts.AdminEmail, adminHash, seedAdminCPF, "1980-01-01", "ADMIN"})
+	}
 	for _, u := range users {
 		_, err := db.Exec(`INSERT INTO users (id, name, email, password_hash, cpf, birth_date, role, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
 			u.id, u.name, u.email, u.passwordHash, u.cpf, u.birthDate, u.role, now)
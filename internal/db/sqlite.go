@@ -7,6 +7,9 @@ import (
 	_ "modernc.org/sqlite"
 )
 
+// OpenSQLite opens the database with WAL and foreign key enforcement. The
+// pragmas are part of the DSN, so the driver applies them to every connection
+// it opens, not just the first one.
 func OpenSQLite(path string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=foreign_keys(ON)")
 	if err != nil {
@@ -17,5 +20,16 @@ func OpenSQLite(path string) (*sql.DB, error) {
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("ping: %w", err)
 	}
+	// SQLite silently ignores REFERENCES clauses when enforcement is off;
+	// refuse to run rather than let orphaned rows in.
+	var fk int
+	if err := db.QueryRow(`PRAGMA foreign_keys`).Scan(&fk); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("foreign_keys: %w", err)
+	}
+	if fk != 1 {
+		db.Close()
+		return nil, fmt.Errorf("foreign_keys desativado na conexão SQLite")
+	}
 	return db, nil
 }
@@ -0,0 +1,101 @@
+// Package eventtime turns event dates, stored as a wall clock date and time
+// in the event's IANA time zone, into absolute instants.
+package eventtime
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	_ "time/tzdata" // IANA zones even on hosts without /usr/share/zoneinfo
+)
+
+// ErrInvalidTimezone is returned for time zone names that aren't IANA zones.
+var ErrInvalidTimezone = errors.New("fuso horário inválido")
+
+// ErrDatePassed is returned when buying tickets for a date whose sales
+// window has closed.
+var ErrDatePassed = errors.New("a data do evento já passou")
+
+// Load loads an IANA time zone ("America/Manaus"). The server's own zone
+// ("Local" or "") is refused: event times must not depend on where the API
+// runs.
+func Load(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimezone, name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimezone, name)
+	}
+	return loc, nil
+}
+
+// DateStart returns the instant an event date starts: date (YYYY-MM-DD) at
+// startTime (HH:MM) in the named zone. Without a start time it is the end of
+// that day (the next midnight), so the date stays on sale all day.
+//
+// Wall clock times skipped by a DST change start at the change; times that
+// happen twice when clocks go back start at their first occurrence.
+func DateStart(date, startTime, timezone string) (time.Time, error) {
+	loc, err := Load(timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("data do evento inválida: %s", date)
+	}
+	hour, minute := 0, 0
+	if startTime != "" {
+		clock, err := time.Parse("15:04", startTime)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("horário do evento inválido: %s", startTime)
+		}
+		hour, minute = clock.Hour(), clock.Minute()
+	} else {
+		day = day.AddDate(0, 0, 1)
+	}
+	return wallClockIn(day.Year(), day.Month(), day.Day(), hour, minute, loc), nil
+}
+
+// CheckDateOpen fails with ErrDatePassed once now is past the date's start
+// time (in timezone, see DateStart) plus grace. A date without a start time
+// stays on sale until the end of that day, plus grace.
+func CheckDateOpen(date, startTime, timezone string, now time.Time, grace time.Duration) error {
+	start, err := DateStart(date, startTime, timezone)
+	if err != nil {
+		return err
+	}
+	if now.After(start.Add(grace)) {
+		return ErrDatePassed
+	}
+	return nil
+}
+
+// wallClockIn is time.Date with a defined choice around DST changes, which
+// time.Date leaves unspecified: a skipped wall clock time maps to the
+// instant of the change, a repeated one to its first occurrence.
+func wallClockIn(year int, month time.Month, day, hour, minute int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, hour, minute, 0, 0, loc)
+	want := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	got := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+	switch {
+	case got.Before(want): // skipped; t is still before the change
+		_, end := t.ZoneBounds()
+		return end
+	case got.After(want): // skipped; t is already after the change
+		start, _ := t.ZoneBounds()
+		return start
+	}
+	// Repeated: the same wall clock also existed before the last change if
+	// that change set clocks back by more than the time since.
+	start, _ := t.ZoneBounds()
+	if !start.IsZero() {
+		_, offset := t.Zone()
+		_, before := start.Add(-time.Second).Zone()
+		if back := time.Duration(before-offset) * time.Second; back > 0 && t.Sub(start) < back {
+			return t.Add(-back)
+		}
+	}
+	return t
+}
@@ -0,0 +1,99 @@
+package eventtime
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDateStartUsesEventTimezone(t *testing.T) {
+	cases := []struct {
+		date, start, tz string
+		want            string // UTC
+	}{
+		{"2025-03-10", "20:00", "America/Sao_Paulo", "2025-03-10T23:00:00Z"},
+		{"2025-03-10", "20:00", "America/Manaus", "2025-03-11T00:00:00Z"},
+		{"2025-03-10", "20:00", "America/Noronha", "2025-03-10T22:00:00Z"},
+		{"2025-03-10", "", "America/Sao_Paulo", "2025-03-11T03:00:00Z"}, // end of the day
+		// Brazil observed DST until 2019: clocks went from 00:00 to 01:00 on
+		// 2018-11-04 and from 00:00 back to 23:00 on 2019-02-17.
+		{"2018-11-04", "00:30", "America/Sao_Paulo", "2018-11-04T03:00:00Z"}, // skipped: starts at the change
+		{"2018-11-03", "", "America/Sao_Paulo", "2018-11-04T03:00:00Z"},      // midnight skipped too
+		{"2018-11-04", "20:00", "America/Sao_Paulo", "2018-11-04T22:00:00Z"}, // -02 after the change
+		{"2019-02-16", "23:30", "America/Sao_Paulo", "2019-02-17T01:30:00Z"}, // repeated: first occurrence
+		{"2019-02-17", "20:00", "America/Sao_Paulo", "2019-02-17T23:00:00Z"}, // back to -03
+		// US DST, forward at 02:00 and back at 02:00.
+		{"2025-03-09", "02:30", "America/New_York", "2025-03-09T07:00:00Z"},
+		{"2025-11-02", "01:30", "America/New_York", "2025-11-02T05:30:00Z"},
+	}
+	for _, c := range cases {
+		got, err := DateStart(c.date, c.start, c.tz)
+		if err != nil {
+			t.Errorf("%s %s %s: %v", c.date, c.start, c.tz, err)
+			continue
+		}
+		if s := got.UTC().Format(time.RFC3339); s != c.want {
+			t.Errorf("%s %s %s = %s, want %s", c.date, c.start, c.tz, s, c.want)
+		}
+	}
+}
+
+func TestDateStartDoesNotDependOnServerZone(t *testing.T) {
+	local := time.Local
+	t.Cleanup(func() { time.Local = local })
+
+	var first time.Time
+	for i, zone := range []*time.Location{time.UTC, time.FixedZone("JST", 9*60*60), time.FixedZone("PST", -8*60*60)} {
+		time.Local = zone
+		got, err := DateStart("2025-03-10", "20:00", "America/Sao_Paulo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 && !got.Equal(first) {
+			t.Errorf("server zone %s: start = %v, want %v", zone, got, first)
+		}
+		first = got
+	}
+}
+
+func TestCheckDateOpen(t *testing.T) {
+	saoPaulo, err := Load("America/Sao_Paulo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 3, 10, 20, 0, 0, 0, saoPaulo)
+	cases := []struct {
+		date, start string
+		grace       time.Duration
+		open        bool
+	}{
+		{"2025-03-11", "18:00", 0, true},
+		{"2025-03-10", "21:00", 0, true},
+		{"2025-03-10", "19:00", 0, false},
+		{"2025-03-10", "19:00", 2 * time.Hour, true},
+		{"2025-03-10", "", 0, true}, // no start time: on sale until the end of the day
+		{"2025-03-09", "", 0, false},
+		{"2025-02-15", "16:00", 0, false},
+		{"2025-02-15", "16:00", 365 * 24 * time.Hour, true},
+	}
+	for _, c := range cases {
+		err := CheckDateOpen(c.date, c.start, "America/Sao_Paulo", now, c.grace)
+		if c.open && err != nil {
+			t.Errorf("%s %s grace %s: %v, want open", c.date, c.start, c.grace, err)
+		}
+		if !c.open && err != ErrDatePassed {
+			t.Errorf("%s %s grace %s: err = %v, want ErrDatePassed", c.date, c.start, c.grace, err)
+		}
+	}
+	if err := CheckDateOpen("10/03/2025", "", "America/Sao_Paulo", now, 0); err == nil || err == ErrDatePassed {
+		t.Errorf("malformed date: err = %v", err)
+	}
+}
+
+func TestLoadRejectsUnknownAndServerZones(t *testing.T) {
+	for _, name := range []string{"", "Local", "Brasil/Brasilia", "America/Sao Paulo"} {
+		if _, err := Load(name); !errors.Is(err, ErrInvalidTimezone) {
+			t.Errorf("Load(%q) = %v, want ErrInvalidTimezone", name, err)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package graphql
+
+import (
+	"context"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/graphql/model"
+	"afterzin/api/internal/testutil"
+)
+
+func TestLoginHidesDatabaseErrors(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	r := &mutationResolver{&Resolver{DB: sqlite, Config: &config.Config{JWTSecret: "test-secret"}}}
+
+	if _, err := r.Login(context.Background(), model.LoginInput{Email: "ninguem@email.com", Password: "segredo123"}); err != errInvalidCredentials {
+		t.Errorf("unknown email: err = %v, want errInvalidCredentials", err)
+	}
+	sqlite.Close()
+	if _, err := r.Login(context.Background(), model.LoginInput{Email: "ninguem@email.com", Password: "segredo123"}); err != errLoginFailed {
+		t.Errorf("closed database: err = %v, want errLoginFailed", err)
+	}
+}
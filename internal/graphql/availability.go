@@ -0,0 +1,59 @@
+package graphql
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"afterzin/api/internal/repository"
+)
+
+// availabilityCacheTTL is how long an event's remaining places are reused.
+// Every event view reads them, and a few seconds of lag is fine for a
+// "tickets left" message; checkout always checks the lots themselves.
+const availabilityCacheTTL = 10 * time.Second
+
+// maxAvailabilityCacheEntries bounds the cache; expired entries are dropped
+// when it fills up.
+const maxAvailabilityCacheEntries = 1000
+
+type eventAvailability struct {
+	available int
+	lots      int // active lots still on sale
+	fetchedAt time.Time
+}
+
+// availabilityCache holds repository.EventTotalAvailability per event.
+var availabilityCache = struct {
+	sync.Mutex
+	entries map[string]eventAvailability
+}{entries: map[string]eventAvailability{}}
+
+// lookupEventAvailability returns the event's remaining places, from the
+// cache when fresh.
+func lookupEventAvailability(db *sql.DB, eventID string) (eventAvailability, error) {
+	now := time.Now()
+	availabilityCache.Lock()
+	cached, ok := availabilityCache.entries[eventID]
+	availabilityCache.Unlock()
+	if ok && now.Sub(cached.fetchedAt) < availabilityCacheTTL {
+		return cached, nil
+	}
+
+	available, lots, err := repository.EventTotalAvailability(db, eventID, now)
+	if err != nil {
+		return eventAvailability{}, err
+	}
+	entry := eventAvailability{available: available, lots: lots, fetchedAt: now}
+	availabilityCache.Lock()
+	defer availabilityCache.Unlock()
+	if len(availabilityCache.entries) >= maxAvailabilityCacheEntries {
+		for id, e := range availabilityCache.entries {
+			if now.Sub(e.fetchedAt) >= availabilityCacheTTL {
+				delete(availabilityCache.entries, id)
+			}
+		}
+	}
+	availabilityCache.entries[eventID] = entry
+	return entry, nil
+}
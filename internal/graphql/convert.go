@@ -1,10 +1,16 @@
 package graphql
 
 import (
+	"afterzin/api/internal/auth"
 	"afterzin/api/internal/graphql/model"
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/pagarme"
 	"afterzin/api/internal/repository"
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 func userRowToModel(u *repository.UserRow) *model.User {
@@ -36,7 +42,29 @@ func userRowToModel(u *repository.UserRow) *model.User {
 		PhoneNumber:      phoneNumber,
 		PhotoURL:         photoURL,
 		Role:             model.UserRole(u.Role),
-		CreatedAt:        u.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+		CreatedAt:        repository.FormatTime(u.CreatedAt),
+	}
+}
+
+// publicUserRowToModel is userRowToModel for a user reached through someone
+// else's data; the personal fields are filtered by @restricted.
+func publicUserRowToModel(u *repository.UserRow) *model.PublicUser {
+	m := userRowToModel(u)
+	if m == nil {
+		return nil
+	}
+	return &model.PublicUser{
+		ID:               m.ID,
+		Name:             m.Name,
+		Email:            &m.Email,
+		Cpf:              &m.Cpf,
+		BirthDate:        &m.BirthDate,
+		PhoneCountryCode: m.PhoneCountryCode,
+		PhoneAreaCode:    m.PhoneAreaCode,
+		PhoneNumber:      m.PhoneNumber,
+		PhotoURL:         m.PhotoURL,
+		Role:             m.Role,
+		CreatedAt:        m.CreatedAt,
 	}
 }
 
@@ -59,9 +87,20 @@ func eventRowToModel(e *repository.EventRow, db *sql.DB) (*model.Event, error) {
 		Address:     addr,
 		Status:      model.EventStatus(e.Status),
 		Featured:    &feat,
+		Timezone:    e.Timezone.String, // own zone only; the resolver applies the default
 		Dates:       nil,
 		Producer:    nil,
 	}
+	if capacity, _ := repository.EventCapacity(db, e.ID); capacity > 0 {
+		ev.Capacity = &capacity
+	}
+	ev.Transferable, ev.MaxTicketTransfers = true, nil
+	if transferable, maxTransfers, err := repository.EventTransferPolicy(db, e.ID); err == nil {
+		ev.Transferable = transferable
+		if maxTransfers > 0 {
+			ev.MaxTicketTransfers = &maxTransfers
+		}
+	}
 	dateIDs, err := repository.EventDateIDsByEvent(db, e.ID)
 	if err != nil {
 		return nil, err
@@ -82,16 +121,7 @@ func eventRowToModel(e *repository.EventRow, db *sql.DB) (*model.Event, error) {
 		return nil, err
 	}
 	if prod != nil {
-		owner, _ := repository.UserByID(db, prod.UserID)
-		ev.Producer = &model.Producer{
-			ID:          prod.ID,
-			User:        userRowToModel(owner),
-			CompanyName: nil,
-			Approved:    prod.Approved == 1,
-		}
-		if prod.CompanyName.Valid {
-			ev.Producer.CompanyName = &prod.CompanyName.String
-		}
+		ev.Producer = producerRowToModel(db, prod)
 	}
 	return ev, nil
 }
@@ -158,23 +188,28 @@ func lotToModel(db *sql.DB, lotID string) (*model.Lot, error) {
 		if err != nil || tt == nil {
 			continue
 		}
-		var desc *string
-		if tt.Description.Valid {
-			desc = &tt.Description.String
-		}
-		lot.TicketTypes = append(lot.TicketTypes, &model.TicketType{
-			ID:           tt.ID,
-			Name:         tt.Name,
-			Description:  desc,
-			Price:        tt.Price,
-			Audience:     model.AudienceType(tt.Audience),
-			MaxQuantity:  tt.MaxQuantity,
-			SoldQuantity: tt.SoldQuantity,
-		})
+		lot.TicketTypes = append(lot.TicketTypes, ticketTypeRowToModel(tt))
 	}
 	return lot, nil
 }
 
+func ticketTypeRowToModel(tt *repository.TicketTypeRow) *model.TicketType {
+	var desc *string
+	if tt.Description.Valid {
+		desc = &tt.Description.String
+	}
+	return &model.TicketType{
+		ID:           tt.ID,
+		Name:         tt.Name,
+		Description:  desc,
+		Price:        tt.Price,
+		Audience:     model.AudienceType(tt.Audience),
+		MaxQuantity:  tt.MaxQuantity,
+		SoldQuantity: tt.SoldQuantity,
+		Admits:       tt.Admits,
+	}
+}
+
 func ticketRowToModel(db *sql.DB, t *repository.TicketRow) (*model.Ticket, error) {
 	if t == nil {
 		return nil, nil
@@ -197,19 +232,23 @@ func ticketRowToModel(db *sql.DB, t *repository.TicketRow) (*model.Ticket, error
 			Audience:     model.AudienceType(tt.Audience),
 			MaxQuantity:  tt.MaxQuantity,
 			SoldQuantity: tt.SoldQuantity,
+			Admits:       tt.Admits,
 		}
 	}
 	owner, _ := repository.UserByID(db, t.UserID)
 	ticket := &model.Ticket{
-		ID:         t.ID,
-		Code:       t.Code,
-		QRCode:     t.QRCode,
-		Event:      ev,
-		EventDate:  ed,
-		TicketType: ttModel,
-		Owner:      userRowToModel(owner),
-		Used:       t.Used == 1,
-		CreatedAt:  t.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+		ID:              t.ID,
+		Code:            t.Code,
+		QRCode:          t.QRCode,
+		Event:           ev,
+		EventDate:       ed,
+		TicketType:      ttModel,
+		Owner:           publicUserRowToModel(owner),
+		Admits:          t.Admits,
+		AdmitsRemaining: t.AdmitsRemaining,
+		Source:          model.TicketSource(t.Source),
+		Used:            t.Used == 1,
+		CreatedAt:       repository.FormatTime(t.CreatedAt),
 	}
 	if t.UsedAt.Valid && t.UsedAt.String != "" {
 		usedAt := parseDateTimeToRFC3339(t.UsedAt.String)
@@ -218,10 +257,297 @@ func ticketRowToModel(db *sql.DB, t *repository.TicketRow) (*model.Ticket, error
 	return ticket, nil
 }
 
+// parseDateTimeToRFC3339 normalizes a stored timestamp to UTC RFC3339.
 func parseDateTimeToRFC3339(s string) string {
-	t, err := time.Parse("2006-01-02 15:04:05", s)
+	t, _ := repository.ParseTime(s)
+	return repository.FormatTime(t)
+}
+
+// orderRefPtr returns the order's human-friendly reference, or nil if it has none.
+func orderRefPtr(db *sql.DB, orderID string) *string {
+	ref, _ := repository.OrderRefByID(db, orderID)
+	if ref == "" {
+		return nil
+	}
+	return &ref
+}
+
+func notificationRowToModel(n *repository.NotificationRow) *model.Notification {
+	m := &model.Notification{
+		ID:        n.ID,
+		Type:      n.Type,
+		Payload:   n.Payload,
+		CreatedAt: parseDateTimeToRFC3339(n.CreatedAt),
+	}
+	if n.ReadAt.Valid {
+		readAt := parseDateTimeToRFC3339(n.ReadAt.String)
+		m.ReadAt = &readAt
+	}
+	return m
+}
+
+// userAddressFromInput normalizes and validates an AddressInput into an
+// address-book entry of userID.
+func userAddressFromInput(userID string, input model.AddressInput) (repository.UserAddress, error) {
+	addr := pagarme.Address{
+		Street:         input.Street,
+		StreetNumber:   input.StreetNumber,
+		Complementary:  derefString(input.Complementary),
+		Neighborhood:   input.Neighborhood,
+		City:           input.City,
+		State:          input.State,
+		ZipCode:        input.ZipCode,
+		ReferencePoint: derefString(input.ReferencePoint),
+	}
+	addr.Normalize()
+	if err := addr.Validate(); err != nil {
+		return repository.UserAddress{}, addressInputError(err)
+	}
+	label := strings.TrimSpace(derefString(input.Label))
+	if utf8.RuneCountInString(label) > maxAddressLabelLength {
+		return repository.UserAddress{}, fmt.Errorf("apelido do endereço deve ter no máximo %d caracteres", maxAddressLabelLength)
+	}
+	return repository.UserAddress{
+		UserID:         userID,
+		Label:          label,
+		Street:         addr.Street,
+		StreetNumber:   addr.StreetNumber,
+		Complementary:  addr.Complementary,
+		Neighborhood:   addr.Neighborhood,
+		City:           addr.City,
+		State:          addr.State,
+		ZipCode:        addr.ZipCode,
+		ReferencePoint: addr.ReferencePoint,
+		IsDefault:      input.IsDefault != nil && *input.IsDefault,
+	}, nil
+}
+
+func userAddressToModel(a *repository.UserAddress) *model.UserAddress {
+	optional := func(s string) *string {
+		if s == "" {
+			return nil
+		}
+		return &s
+	}
+	return &model.UserAddress{
+		ID:             a.ID,
+		Label:          optional(a.Label),
+		Street:         a.Street,
+		StreetNumber:   a.StreetNumber,
+		Complementary:  optional(a.Complementary),
+		Neighborhood:   a.Neighborhood,
+		City:           a.City,
+		State:          a.State,
+		ZipCode:        a.ZipCode,
+		ReferencePoint: optional(a.ReferencePoint),
+		IsDefault:      a.IsDefault,
+		CreatedAt:      parseDateTimeToRFC3339(a.CreatedAt),
+		UpdatedAt:      parseDateTimeToRFC3339(a.UpdatedAt),
+	}
+}
+
+// newAuthPayload issues an access token for the user and bundles it with the
+// profile, so login and register answer in a single round trip.
+func newAuthPayload(secret string, user *model.User) (*model.AuthPayload, error) {
+	token, err := auth.NewToken(user.ID, string(user.Role), secret, auth.AccessTokenTTL)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao gerar token: %w", err)
+	}
+	return &model.AuthPayload{
+		Token:     token,
+		ExpiresAt: time.Now().Add(auth.AccessTokenTTL).UTC().Format(time.RFC3339),
+		User:      user,
+	}, nil
+}
+
+// producerRowToModel converts a producer row, with its owner and public
+// profile, to the GraphQL model.
+func producerRowToModel(db *sql.DB, p *repository.ProducerRow) *model.Producer {
+	owner, _ := repository.UserByID(db, p.UserID)
+	return &model.Producer{
+		ID:                  p.ID,
+		User:                publicUserRowToModel(owner),
+		CompanyName:         nullStringPtr(p.CompanyName),
+		Approved:            p.Approved == 1,
+		DisplayName:         nullStringPtr(p.DisplayName),
+		Description:         nullStringPtr(p.Description),
+		LogoURL:             nullStringPtr(p.LogoURL),
+		ContactEmail:        nullStringPtr(p.ContactEmail),
+		StatementDescriptor: nullStringPtr(p.StatementDescriptor),
+	}
+}
+
+// nullStringPtr returns nil for a NULL column and a pointer to the value otherwise.
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+// derefString returns the value of an optional input, or "" when absent.
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func paymentStatusToModel(st pagarme.RecipientStatusResult) *model.ProducerPaymentStatus {
+	out := &model.ProducerPaymentStatus{
+		HasRecipient:       st.HasRecipient,
+		OnboardingComplete: st.OnboardingComplete,
+		ActionRequired:     st.ActionRequired,
+	}
+	if st.RecipientID != "" {
+		out.RecipientID = strPtr(st.RecipientID)
+	}
+	if st.Status != "" {
+		out.Status = strPtr(st.Status)
+	}
+	if st.State != "" {
+		out.State = strPtr(st.State)
+		out.StateMessage = strPtr(st.StateMessage)
+	}
+	if st.RefuseReason != "" {
+		out.RefuseReason = strPtr(st.RefuseReason)
+	}
+	if st.Name != "" {
+		out.Name = strPtr(st.Name)
+	}
+	if st.Error != "" {
+		out.Error = strPtr(st.Error)
+	}
+	return out
+}
+
+// attributionFromInput converts the checkout's channel/utm input.
+func attributionFromInput(channel *string, utm *model.UtmInput) pagarme.Attribution {
+	str := func(p *string) string {
+		if p == nil {
+			return ""
+		}
+		return *p
+	}
+	a := pagarme.Attribution{Channel: str(channel)}
+	if utm != nil {
+		a.UTM = &pagarme.UTM{
+			Source:   str(utm.Source),
+			Medium:   str(utm.Medium),
+			Campaign: str(utm.Campaign),
+			Term:     str(utm.Term),
+			Content:  str(utm.Content),
+		}
+	}
+	return a
+}
+
+// orderToModel builds the buyer's view of an order. Paid comes from the
+// database status only, never from the gateway, so the page can't show an
+// order as paid before the webhook issued its tickets. The PIX of a pending
+// order is the one saved when it was created, left out once it or the order
+// has expired. Pagar.me is only asked for it when refresh is set, and the
+// answer replaces the saved one.
+func orderToModel(db *sql.DB, client pagarme.PagarmeAPI, o *repository.OrderRow, refresh bool) (*model.Order, error) {
+	out := &model.Order{
+		ID:        o.ID,
+		Status:    o.Status,
+		Paid:      o.Status == "PAID" || o.Status == "CONFIRMED",
+		Total:     o.Total,
+		CreatedAt: parseDateTimeToRFC3339(o.CreatedAt),
+		Items:     []*model.OrderItem{},
+		Tickets:   []*model.Ticket{},
+	}
+	if o.OrderRef != "" {
+		out.OrderRef = &o.OrderRef
+	}
+	if o.ExpiresAt != "" {
+		out.ExpiresAt = &o.ExpiresAt
+	}
+
+	items, err := repository.OrderItemsByOrderID(db, o.ID)
 	if err != nil {
-		t, _ = time.Parse(time.RFC3339, s)
+		return nil, err
+	}
+	for _, it := range items {
+		item := &model.OrderItem{
+			Quantity:  it.Quantity,
+			UnitPrice: it.UnitPrice,
+			Subtotal:  float64(it.Quantity) * it.UnitPrice,
+		}
+		if ed, _ := repository.EventDateByID(db, it.EventDateID); ed != nil {
+			item.EventID, item.EventDate = ed.EventID, ed.Date
+			if ed.StartTime.Valid && ed.StartTime.String != "" {
+				item.StartTime = &ed.StartTime.String
+			}
+			if ev, _ := repository.EventByID(db, ed.EventID); ev != nil {
+				item.EventTitle = ev.Title
+			}
+		}
+		if tt, _ := repository.TicketTypeByID(db, it.TicketTypeID); tt != nil {
+			item.TicketTypeName = tt.Name
+		}
+		out.Items = append(out.Items, item)
+	}
+
+	if out.Paid {
+		tickets, err := repository.TicketsByOrderID(db, o.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tickets {
+			if ticket, err := ticketRowToModel(db, t); err == nil && ticket != nil {
+				out.Tickets = append(out.Tickets, ticket)
+			}
+		}
+	}
+
+	if o.Status != "PENDING" || o.PagarmeOrderID == "" || orderExpired(o.ExpiresAt) {
+		return out, nil
+	}
+	if refresh && client != nil {
+		refreshOrderPix(db, client, o)
+	}
+	if o.PixQRCode != "" && !orderExpired(o.PixExpiresAt) {
+		out.Pix = &model.OrderPix{QRCode: o.PixQRCode}
+		if o.PixQRCodeURL != "" {
+			out.Pix.QRCodeURL = &o.PixQRCodeURL
+		}
+		if o.PixExpiresAt != "" {
+			out.Pix.ExpiresAt = &o.PixExpiresAt
+		}
+	}
+	return out, nil
+}
+
+// refreshOrderPix asks Pagar.me for the order's current PIX and saves it,
+// updating o. A cancelled or failed charge clears the PIX; a gateway error
+// is logged and leaves the saved one in place. Without an expiry in the
+// answer the PIX lifetime is counted from now, as CreatePayment does.
+func refreshOrderPix(db *sql.DB, client pagarme.PagarmeAPI, o *repository.OrderRow) {
+	pix, err := client.GetOrderStatus(o.PagarmeOrderID)
+	if err != nil {
+		logger.Warnf("erro ao buscar PIX do pedido %s no Pagar.me: %v", o.ID, err)
+		return
+	}
+	if pix.Status == "canceled" || pix.Status == "failed" {
+		pix.PixQRCode, pix.PixQRCodeURL = "", ""
+	}
+	expiresAt, err := repository.ParseTime(pix.ExpiresAt)
+	if err != nil {
+		expiresAt = time.Now().Add(pagarme.PixExpirationSeconds * time.Second)
+	}
+	if err := repository.SetOrderPix(db, o.ID, pix.PixQRCode, pix.PixQRCodeURL, expiresAt); err != nil {
+		logger.Errorf("erro ao gravar PIX do pedido %s: %v", o.ID, err)
+	}
+	o.PixQRCode, o.PixQRCodeURL, o.PixExpiresAt = pix.PixQRCode, pix.PixQRCodeURL, repository.FormatTime(expiresAt)
+}
+
+// orderExpired reports whether an order's expires_at has passed.
+func orderExpired(expiresAt string) bool {
+	if expiresAt == "" {
+		return false
 	}
-	return t.UTC().Format(time.RFC3339)
+	t, err := repository.ParseTime(expiresAt)
+	return err == nil && time.Now().After(t)
 }
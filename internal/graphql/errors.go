@@ -0,0 +1,40 @@
+package graphql
+
+import (
+	"errors"
+
+	"afterzin/api/internal/pagarme"
+	"afterzin/api/internal/validate"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// errInvalidCredentials is the only login failure message, whether the email
+// is unknown or the password is wrong.
+var errInvalidCredentials = errors.New("credenciais inválidas")
+
+// errLoginFailed is returned when login can't check the credentials (e.g. a
+// database error); the cause is only logged.
+var errLoginFailed = errors.New("erro ao fazer login; tente novamente")
+
+// phoneInputError wraps a ValidatePhone error as "telefone inválido: ..." and,
+// for structured phone errors, adds extensions.field with the input field name.
+func phoneInputError(err error) error {
+	gqlErr := &gqlerror.Error{Message: "telefone inválido: " + err.Error()}
+	var phoneErr *validate.PhoneValidationError
+	if errors.As(err, &phoneErr) {
+		gqlErr.Extensions = map[string]interface{}{"field": phoneErr.InputField()}
+	}
+	return gqlErr
+}
+
+// addressInputError wraps an Address.Validate error as "endereço inválido:
+// ..." with extensions.field set to the AddressInput field name.
+func addressInputError(err error) error {
+	gqlErr := &gqlerror.Error{Message: "endereço inválido: " + err.Error()}
+	var addrErr *pagarme.AddressValidationError
+	if errors.As(err, &addrErr) {
+		gqlErr.Extensions = map[string]interface{}{"field": addrErr.InputField()}
+	}
+	return gqlErr
+}
@@ -0,0 +1,76 @@
+package graphql
+
+import (
+	"context"
+
+	"afterzin/api/internal/graphql/model"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/repository"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// Caller's access to a PublicUser's personal fields, from least to most.
+const (
+	accessNone = iota
+	// accessTicketProducer: the event's producer reading a ticket's owner
+	accessTicketProducer
+	// accessFull: the user themselves or an admin
+	accessFull
+)
+
+// restricted implements the @restricted directive on PublicUser fields: the
+// value is resolved for the user themselves, for admins and, with
+// ticketProducer, for the producer of the event when the user is reached as
+// a ticket's owner. Everyone else gets null, so a shared query (e.g.
+// event.producer.user) can't leak another user's personal data. The access
+// is resolved once per user object and reused by its other fields.
+func (r *Resolver) restricted(ctx context.Context, obj interface{}, next graphql.Resolver, ticketProducer *bool) (interface{}, error) {
+	user, ok := obj.(*model.PublicUser)
+	if !ok || user == nil {
+		return nil, nil
+	}
+	access := user.Access(func() int {
+		return r.personalDataAccess(ctx, user.ID, graphql.GetFieldContext(ctx))
+	})
+	if access == accessFull || (access == accessTicketProducer && ticketProducer != nil && *ticketProducer) {
+		return next(ctx)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) personalDataAccess(ctx context.Context, userID string, fc *graphql.FieldContext) int {
+	callerID := middleware.UserID(ctx)
+	if callerID == "" {
+		return accessNone
+	}
+	if callerID == userID {
+		return accessFull
+	}
+	if caller, _ := repository.UserByID(r.DB, callerID); caller != nil && caller.Role == "ADMIN" {
+		return accessFull
+	}
+
+	// fc is the restricted field; its parent is the field that returned the
+	// user, the same for every field of the object
+	if fc == nil || fc.Parent == nil || fc.Parent.Object != "Ticket" || fc.Parent.Parent == nil {
+		return accessNone
+	}
+	// A single ticket field holds *Ticket; a list element holds a pointer to it
+	var ticket *model.Ticket
+	switch t := fc.Parent.Parent.Result.(type) {
+	case *model.Ticket:
+		ticket = t
+	case **model.Ticket:
+		ticket = *t
+	}
+	if ticket == nil || ticket.Event == nil {
+		return accessNone
+	}
+	producerID, _ := repository.ProducerIDByUser(r.DB, callerID)
+	eventProducerID, _ := repository.EventProducerID(r.DB, ticket.Event.ID)
+	if producerID != "" && producerID == eventProducerID {
+		return accessTicketProducer
+	}
+	return accessNone
+}
@@ -0,0 +1,166 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+type fieldAuthFixture struct {
+	handler   http.Handler
+	eventID   string
+	buyerID   string
+	otherID   string
+	producer  string // producer's user ID
+	adminID   string
+	ownerMail string
+}
+
+func newFieldAuthFixture(t *testing.T) *fieldAuthFixture {
+	t.Helper()
+	sqlite := testutil.NewDB(t)
+
+	f := &fieldAuthFixture{ownerMail: "produtor@email.com"}
+	mustUser := func(name, email, cpf string) string {
+		id, err := repository.CreateUser(sqlite, name, email, "hash", cpf, "1990-01-01", nil, nil, nil)
+		if err != nil {
+			t.Fatalf("create user %s: %v", email, err)
+		}
+		return id
+	}
+	f.buyerID = mustUser("Comprador", "comprador@email.com", "52998224725")
+	f.otherID = mustUser("Outro", "outro@email.com", "39053344705")
+	f.producer = mustUser("Produtor", f.ownerMail, "11144477735")
+	f.adminID = mustUser("Admin", "admin@email.com", "15350946056")
+	if _, err := sqlite.Exec(`UPDATE users SET role = 'ADMIN' WHERE id = ?`, f.adminID); err != nil {
+		t.Fatal(err)
+	}
+
+	producerID, _ := repository.CreateProducer(sqlite, f.producer)
+	f.eventID, _ = repository.CreateEvent(sqlite, producerID, "Show", "desc", "shows", "cover", "Local", nil, "")
+	dateID, _ := repository.CreateEventDate(sqlite, f.eventID, "2099-01-01", nil, nil)
+	lotID, _ := repository.CreateLot(sqlite, dateID, "Lote 1", "2000-01-01T00:00:00Z", "2099-01-01T00:00:00Z", 10)
+	ttID, _ := repository.CreateTicketType(sqlite, lotID, "Pista", nil, 50, "GENERAL", 10, 1)
+	seedTicket(t, sqlite, f.buyerID, f.eventID, dateID, ttID)
+
+	f.handler = NewHandler(sqlite, &config.Config{JWTSecret: "test-secret"}, nil, nil, nil)
+	return f
+}
+
+func seedTicket(t *testing.T, sqlite *sql.DB, userID, eventID, dateID, ttID string) {
+	t.Helper()
+	orderID, err := repository.CreateOrder(sqlite, userID, 50, time.Hour)
+	if err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+	itemID, err := repository.CreateOrderItem(sqlite, orderID, dateID, ttID, 1, 50)
+	if err != nil {
+		t.Fatalf("create order item: %v", err)
+	}
+	if err := repository.CreateTicketWithID(sqlite, "ticket-1", "CODE0001", "qr-1", orderID, itemID, userID, eventID, dateID, ttID, repository.TicketSourcePurchase); err != nil {
+		t.Fatalf("create ticket: %v", err)
+	}
+}
+
+// query runs a GraphQL query as userID ("" = anonymous) and returns the data.
+func (f *fieldAuthFixture) query(t *testing.T, userID, q string) map[string]interface{} {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"query": q})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+	rec := httptest.NewRecorder()
+	f.handler.ServeHTTP(rec, req)
+
+	var resp struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []interface{}          `json:"errors"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors: %v", resp.Errors)
+	}
+	return resp.Data
+}
+
+// dig follows keys (and 0 for the first list element) into a response.
+func dig(v interface{}, path ...interface{}) interface{} {
+	for _, p := range path {
+		switch k := p.(type) {
+		case string:
+			m, _ := v.(map[string]interface{})
+			v = m[k]
+		case int:
+			l, _ := v.([]interface{})
+			if len(l) <= k {
+				return nil
+			}
+			v = l[k]
+		}
+	}
+	return v
+}
+
+func TestRestrictedFieldsOnSharedEvent(t *testing.T) {
+	f := newFieldAuthFixture(t)
+	q := `{ event(id: "` + f.eventID + `") { producer { user { name email cpf birthDate phoneNumber } } } }`
+
+	for _, who := range []struct{ name, id string }{{"another user", f.otherID}, {"anonymous", ""}} {
+		user := dig(f.query(t, who.id, q), "event", "producer", "user")
+		if dig(user, "name") != "Produtor" {
+			t.Errorf("%s: name = %v, want it public", who.name, dig(user, "name"))
+		}
+		for _, field := range []string{"email", "cpf", "birthDate", "phoneNumber"} {
+			if v := dig(user, field); v != nil {
+				t.Errorf("%s read the producer's %s: %v", who.name, field, v)
+			}
+		}
+	}
+
+	if got := dig(f.query(t, f.producer, q), "event", "producer", "user", "email"); got != f.ownerMail {
+		t.Errorf("producer reading own email = %v", got)
+	}
+	if got := dig(f.query(t, f.adminID, q), "event", "producer", "user", "cpf"); got != "11144477735" {
+		t.Errorf("admin reading cpf = %v", got)
+	}
+}
+
+func TestRestrictedFieldsOnTicketOwner(t *testing.T) {
+	f := newFieldAuthFixture(t)
+	q := `{ eventTickets(eventId: "` + f.eventID + `") { items { owner { email cpf } } } }`
+
+	// The event's producer sees the holder's email, as in the attendee export, but not the CPF
+	owner := dig(f.query(t, f.producer, q), "eventTickets", "items", 0, "owner")
+	if dig(owner, "email") != "comprador@email.com" {
+		t.Errorf("producer: holder email = %v", dig(owner, "email"))
+	}
+	if dig(owner, "cpf") != nil {
+		t.Errorf("producer read the holder's cpf: %v", dig(owner, "cpf"))
+	}
+
+	mine := dig(f.query(t, f.buyerID, `{ myTickets { owner { email cpf } } }`), "myTickets", 0, "owner")
+	if dig(mine, "email") != "comprador@email.com" || dig(mine, "cpf") != "52998224725" {
+		t.Errorf("holder reading own ticket = %v", mine)
+	}
+}
+
+func TestRegisterReturnsOwnPersonalData(t *testing.T) {
+	f := newFieldAuthFixture(t)
+	q := `mutation { register(input: {name: "Novo", email: "novo@email.com", password: "segredo123", cpf: "71428793860", birthDate: "1995-05-05", phoneCountryCode: "55", phoneAreaCode: "11", phoneNumber: "987654321"}) { user { email cpf } } }`
+	user := dig(f.query(t, "", q), "register", "user")
+	if dig(user, "email") != "novo@email.com" || dig(user, "cpf") != "71428793860" {
+		t.Errorf("register payload user = %v", user)
+	}
+}
@@ -0,0 +1,47 @@
+package graphql
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// maxImageURLLength bounds image URLs stored instead of an uploaded image.
+const maxImageURLLength = 2048
+
+// normalizeImageBase64 turns an uploaded image (plain base64 or a data URI)
+// into the data URI stored on the row, enforcing the ~300 KB upload limit.
+func normalizeImageBase64(payload string) (string, error) {
+	if len(payload) > 7 && (strings.HasPrefix(payload, "data:img") || strings.HasPrefix(payload, "data:image/")) {
+		// use last comma to handle cases where multiple data URI prefixes
+		// were concatenated (e.g. "data:image/jpeg;base64,data:image/png;base64,....")
+		if idx := strings.LastIndex(payload, ","); idx != -1 && idx+1 < len(payload) {
+			payload = payload[idx+1:]
+		}
+	}
+	if len(payload) > 300*1024 { // ~300 KB in base64 is ~400k chars; allow up to 400k for safety
+		return "", errors.New("imagem muito grande; máximo 300 KB")
+	}
+	// Accept only base64 that decodes to image (we store as data URI for display)
+	normalized := "data:image/jpeg;base64," + payload
+	if len(normalized) > 500*1024 {
+		return "", errors.New("imagem muito grande; máximo 300 KB")
+	}
+	return normalized, nil
+}
+
+// normalizeImage accepts either an http(s) URL, stored as is, or an uploaded
+// image as in normalizeImageBase64.
+func normalizeImage(value string) (string, error) {
+	if !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+		return normalizeImageBase64(value)
+	}
+	if len(value) > maxImageURLLength {
+		return "", errors.New("URL da imagem muito longa")
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return "", errors.New("URL da imagem inválida")
+	}
+	return value, nil
+}
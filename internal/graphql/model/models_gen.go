@@ -9,9 +9,52 @@ import (
 	"strconv"
 )
 
+// Endereço do usuário. O CEP pode vir formatado (só os dígitos são guardados)
+// e state é a sigla da UF.
+type AddressInput struct {
+	Label          *string `json:"label,omitempty"`
+	Street         string  `json:"street"`
+	StreetNumber   string  `json:"streetNumber"`
+	Complementary  *string `json:"complementary,omitempty"`
+	Neighborhood   string  `json:"neighborhood"`
+	City           string  `json:"city"`
+	State          string  `json:"state"`
+	ZipCode        string  `json:"zipCode"`
+	ReferencePoint *string `json:"referencePoint,omitempty"`
+	// Torna este o endereço padrão. O primeiro endereço do usuário sempre é.
+	IsDefault *bool `json:"isDefault,omitempty"`
+}
+
+// Resultado de login e cadastro: token de acesso e perfil do usuário.
 type AuthPayload struct {
+	// JWT para o cabeçalho Authorization: Bearer
 	Token string `json:"token"`
-	User  *User  `json:"user"`
+	// Quando o token expira (RFC 3339); faça login novamente depois disso
+	ExpiresAt string `json:"expiresAt"`
+	User      *User  `json:"user"`
+}
+
+// Vendas pagas de um evento por canal de venda (salesByChannel).
+type ChannelSales struct {
+	// Canal do pedido; pedidos sem canal contam como direct
+	Channel     string `json:"channel"`
+	Orders      int    `json:"orders"`
+	TicketsSold int    `json:"ticketsSold"`
+	// Receita bruta dos pedidos pagos do canal
+	Revenue float64 `json:"revenue"`
+}
+
+// Estatísticas de leituras na portaria de um evento.
+type CheckinStats struct {
+	EventID string `json:"eventId"`
+	// Total de leituras registradas (aceitas e rejeitadas)
+	TotalScans int `json:"totalScans"`
+	// Ingressos distintos lidos com sucesso ou repetidos
+	UniqueTickets int `json:"uniqueTickets"`
+	// Leituras de ingressos já utilizados
+	Duplicates int `json:"duplicates"`
+	// Leituras de códigos inválidos ou de outro evento
+	InvalidAttempts int `json:"invalidAttempts"`
 }
 
 // Input para criação de sessão de checkout.
@@ -19,6 +62,11 @@ type AuthPayload struct {
 type CheckoutInput struct {
 	// Lista de itens a serem comprados (não pode estar vazia)
 	Items []*CheckoutItemInput `json:"items"`
+	// Canal de venda que trouxe o comprador: direct, instagram, facebook, tiktok,
+	// whatsapp, google, email, partner ou other (valores desconhecidos viram other)
+	Channel *string `json:"channel,omitempty"`
+	// Parâmetros utm_* do link de origem
+	Utm *UtmInput `json:"utm,omitempty"`
 }
 
 // Input para seleção de ingressos no checkout.
@@ -40,7 +88,9 @@ type CheckoutPayInput struct {
 }
 
 type CheckoutPayResult struct {
-	Success       bool     `json:"success"`
+	Success bool `json:"success"`
+	// Referência do pedido para atendimento (ex.: AFZ-2025-000123)
+	OrderRef      *string  `json:"orderRef,omitempty"`
 	TicketIds     []string `json:"ticketIds,omitempty"`
 	QRCodePayload *string  `json:"qrCodePayload,omitempty"`
 	QRCodeNumber  *string  `json:"qrCodeNumber,omitempty"`
@@ -57,9 +107,19 @@ type CheckoutPreviewItem struct {
 }
 
 type CheckoutPreviewResult struct {
-	CheckoutID string                 `json:"checkoutId"`
-	Total      float64                `json:"total"`
-	Items      []*CheckoutPreviewItem `json:"items"`
+	CheckoutID string `json:"checkoutId"`
+	// Referência do pedido para atendimento (ex.: AFZ-2025-000123)
+	OrderRef *string                `json:"orderRef,omitempty"`
+	Total    float64                `json:"total"`
+	Items    []*CheckoutPreviewItem `json:"items"`
+}
+
+// Convidado que recebe um ingresso cortesia. O CPF só é exigido quando ainda não
+// existe conta com o email; nesse caso uma conta de convidado é criada.
+type CompRecipientInput struct {
+	Name  string  `json:"name"`
+	Email string  `json:"email"`
+	Cpf   *string `json:"cpf,omitempty"`
 }
 
 type CreateEventInput struct {
@@ -69,6 +129,13 @@ type CreateEventInput struct {
 	CoverImage  string  `json:"coverImage"`
 	Location    string  `json:"location"`
 	Address     *string `json:"address,omitempty"`
+	Capacity    *int    `json:"capacity,omitempty"`
+	// Fuso horário IANA (ex.: America/Manaus); omitido usa o padrão da plataforma.
+	Timezone *string `json:"timezone,omitempty"`
+	// Se os ingressos podem ser transferidos; omitido = true.
+	Transferable *bool `json:"transferable,omitempty"`
+	// Máximo de transferências por ingresso; omitido usa o padrão da plataforma.
+	MaxTicketTransfers *int `json:"maxTicketTransfers,omitempty"`
 }
 
 type Event struct {
@@ -83,6 +150,19 @@ type Event struct {
 	Dates       []*EventDate `json:"dates"`
 	Producer    *Producer    `json:"producer"`
 	Featured    *bool        `json:"featured,omitempty"`
+	// Capacidade do local; os lotes de cada data não podem somar mais que isso. Nulo = sem limite.
+	Capacity *int `json:"capacity,omitempty"`
+	// Fuso horário IANA das datas e horários do evento (ex.: America/Manaus). Sem fuso próprio, usa o padrão da plataforma.
+	Timezone string `json:"timezone"`
+	// Se os ingressos podem ser transferidos para outra conta (transferTicket)
+	Transferable bool `json:"transferable"`
+	// Máximo de transferências por ingresso definido pelo produtor. Nulo = padrão da plataforma.
+	MaxTicketTransfers *int `json:"maxTicketTransfers,omitempty"`
+	// Lugares restantes somando os lotes ativos e ainda não encerrados de todas
+	// as datas do evento. Pode atrasar alguns segundos em relação às vendas.
+	TotalAvailable int `json:"totalAvailable"`
+	// Verdadeiro quando há lotes à venda e todos estão esgotados
+	SoldOut bool `json:"soldOut"`
 }
 
 type EventDate struct {
@@ -106,6 +186,15 @@ type EventFilter struct {
 	City     *string `json:"city,omitempty"`
 }
 
+// Página de eventos.
+type EventPage struct {
+	Items []*Event `json:"items"`
+	// Total de eventos (todas as páginas)
+	TotalCount int `json:"totalCount"`
+	Limit      int `json:"limit"`
+	Offset     int `json:"offset"`
+}
+
 type LoginInput struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -132,11 +221,157 @@ type LotInput struct {
 type Mutation struct {
 }
 
+// Notificação exibida no app (além do e-mail).
+type Notification struct {
+	ID string `json:"id"`
+	// Tipo da notificação, ex.: ticket_purchased
+	Type string `json:"type"`
+	// Dados da notificação em JSON; o formato depende do tipo
+	Payload   string  `json:"payload"`
+	ReadAt    *string `json:"readAt,omitempty"`
+	CreatedAt string  `json:"createdAt"`
+}
+
+// Pedido do comprador, como exibido ao voltar para o link de pagamento.
+type Order struct {
+	ID string `json:"id"`
+	// Referência do pedido para atendimento (ex.: AFZ-2025-000123)
+	OrderRef *string `json:"orderRef,omitempty"`
+	// Status no banco, fonte da verdade do pagamento: PENDING, PROCESSING, PAID, CANCELLED, FRAUD_ALERT...
+	Status string `json:"status"`
+	// true só depois que o webhook confirma o pagamento (status PAID).
+	Paid      bool    `json:"paid"`
+	Total     float64 `json:"total"`
+	CreatedAt string  `json:"createdAt"`
+	// Até quando o pedido pendente pode ser pago.
+	ExpiresAt *string      `json:"expiresAt,omitempty"`
+	Items     []*OrderItem `json:"items"`
+	// PIX para pagamento enquanto o pedido está pendente; nulo se ainda não gerado, expirado ou indisponível.
+	Pix *OrderPix `json:"pix,omitempty"`
+	// Ingressos emitidos; vazio até o pedido ser pago.
+	Tickets []*Ticket `json:"tickets"`
+}
+
+type OrderItem struct {
+	EventID        string  `json:"eventId"`
+	EventTitle     string  `json:"eventTitle"`
+	EventDate      string  `json:"eventDate"`
+	StartTime      *string `json:"startTime,omitempty"`
+	TicketTypeName string  `json:"ticketTypeName"`
+	Quantity       int     `json:"quantity"`
+	UnitPrice      float64 `json:"unitPrice"`
+	Subtotal       float64 `json:"subtotal"`
+}
+
+type OrderPix struct {
+	// PIX copia e cola
+	QRCode string `json:"qrCode"`
+	// Imagem do QR code
+	QRCodeURL *string `json:"qrCodeUrl,omitempty"`
+	ExpiresAt *string `json:"expiresAt,omitempty"`
+}
+
+type OrderStatusCount struct {
+	Status string `json:"status"`
+	Orders int    `json:"orders"`
+}
+
+// Transferência do saldo do produtor para a conta bancária.
+type PayoutTransfer struct {
+	ID string `json:"id"`
+	// Valor em centavos
+	Amount    int     `json:"amount"`
+	Status    string  `json:"status"`
+	CreatedAt *string `json:"createdAt,omitempty"`
+}
+
+// Números da plataforma em um período, para o painel de administradores.
+type PlatformStats struct {
+	// Primeiro dia do período (inclusivo)
+	From string `json:"from"`
+	// Último dia do período (inclusivo)
+	To string `json:"to"`
+	// Valor bruto dos pedidos pagos confirmados no período (GMV), em reais
+	Gmv float64 `json:"gmv"`
+	// Taxas da plataforma sobre esses pedidos, em reais, à taxa atual por ingresso
+	PlatformFees float64 `json:"platformFees"`
+	PaidOrders   int     `json:"paidOrders"`
+	TicketsSold  int     `json:"ticketsSold"`
+	// Produtores com ao menos um pedido pago no período
+	ActiveProducers int `json:"activeProducers"`
+	// Eventos publicados agora, independentemente do período
+	PublishedEvents int `json:"publishedEvents"`
+	// Pedidos criados no período, por status. Cortesias não contam.
+	OrdersByStatus []*OrderStatusCount `json:"ordersByStatus"`
+}
+
 type Producer struct {
-	ID          string  `json:"id"`
-	User        *User   `json:"user"`
-	CompanyName *string `json:"companyName,omitempty"`
-	Approved    bool    `json:"approved"`
+	ID          string      `json:"id"`
+	User        *PublicUser `json:"user"`
+	CompanyName *string     `json:"companyName,omitempty"`
+	Approved    bool        `json:"approved"`
+	// Nome exibido aos compradores (perfil público)
+	DisplayName *string `json:"displayName,omitempty"`
+	Description *string `json:"description,omitempty"`
+	// URL http(s) ou data URI da logo
+	LogoURL *string `json:"logoUrl,omitempty"`
+	// E-mail de contato público do produtor
+	ContactEmail *string `json:"contactEmail,omitempty"`
+	// Nome exibido ao comprador na cobrança (até 13 caracteres, sem acentos);
+	// null usa o nome da plataforma
+	StatementDescriptor *string `json:"statementDescriptor,omitempty"`
+}
+
+// Saldo do produtor no Pagar.me (valores em centavos).
+type ProducerBalance struct {
+	HasRecipient bool    `json:"hasRecipient"`
+	RecipientID  *string `json:"recipientId,omitempty"`
+	// Status do recebedor no Pagar.me; o saldo só existe para recebedores ativos
+	RecipientStatus    *string `json:"recipientStatus,omitempty"`
+	Currency           *string `json:"currency,omitempty"`
+	AvailableAmount    *int    `json:"availableAmount,omitempty"`
+	WaitingFundsAmount *int    `json:"waitingFundsAmount,omitempty"`
+	TransferredAmount  *int    `json:"transferredAmount,omitempty"`
+	// Transferências mais recentes
+	Transfers []*PayoutTransfer `json:"transfers"`
+	Error     *string           `json:"error,omitempty"`
+}
+
+// Página de eventos do produtor.
+type ProducerEventPage struct {
+	Items []*ProducerEventSummary `json:"items"`
+	// Total de eventos que atendem ao filtro (todas as páginas)
+	TotalCount int `json:"totalCount"`
+	Limit      int `json:"limit"`
+	Offset     int `json:"offset"`
+}
+
+// Evento do produtor com totais de vendas, para a tela de gestão (myEvents).
+type ProducerEventSummary struct {
+	Event       *Event `json:"event"`
+	TicketsSold int    `json:"ticketsSold"`
+	// Receita bruta dos pedidos pagos
+	Revenue float64 `json:"revenue"`
+}
+
+// Status de recebimento de pagamentos (Pagar.me) do produtor autenticado.
+type ProducerPaymentStatus struct {
+	HasRecipient       bool    `json:"hasRecipient"`
+	OnboardingComplete bool    `json:"onboardingComplete"`
+	RecipientID        *string `json:"recipientId,omitempty"`
+	// Status bruto do recebedor no Pagar.me (ex.: active, registration)
+	Status *string `json:"status,omitempty"`
+	// Status traduzido para exibição (ex.: Em análise, Recusado)
+	State *string `json:"state,omitempty"`
+	// Orientação para o produtor sobre o status atual
+	StateMessage *string `json:"stateMessage,omitempty"`
+	// Verdadeiro quando o produtor precisa agir (ex.: reenviar dados após recusa)
+	ActionRequired bool `json:"actionRequired"`
+	// Motivo da recusa, quando informado pelo Pagar.me
+	RefuseReason *string `json:"refuseReason,omitempty"`
+	Name         *string `json:"name,omitempty"`
+	// Preenchido quando o Pagar.me não pôde ser consultado (status em cache)
+	Error *string `json:"error,omitempty"`
 }
 
 // Perfil público do produtor: dados do produtor + eventos publicados (excl. rascunho).
@@ -145,6 +380,17 @@ type ProducerPublicProfile struct {
 	Events   []*Event  `json:"events"`
 }
 
+// Página pública de um produtor: perfil e eventos publicados.
+type PublicProducer struct {
+	ID           string     `json:"id"`
+	DisplayName  *string    `json:"displayName,omitempty"`
+	CompanyName  *string    `json:"companyName,omitempty"`
+	Description  *string    `json:"description,omitempty"`
+	LogoURL      *string    `json:"logoUrl,omitempty"`
+	ContactEmail *string    `json:"contactEmail,omitempty"`
+	Events       *EventPage `json:"events"`
+}
+
 type Query struct {
 }
 
@@ -168,10 +414,31 @@ type Ticket struct {
 	Event      *Event      `json:"event"`
 	EventDate  *EventDate  `json:"eventDate"`
 	TicketType *TicketType `json:"ticketType"`
-	Owner      *User       `json:"owner"`
-	Used       bool        `json:"used"`
-	UsedAt     *string     `json:"usedAt,omitempty"`
-	CreatedAt  string      `json:"createdAt"`
+	Owner      *PublicUser `json:"owner"`
+	// Entradas permitidas por este ingresso
+	Admits int `json:"admits"`
+	// Entradas ainda não utilizadas; used fica true quando chega a zero
+	AdmitsRemaining int          `json:"admitsRemaining"`
+	Source          TicketSource `json:"source"`
+	Used            bool         `json:"used"`
+	UsedAt          *string      `json:"usedAt,omitempty"`
+	CreatedAt       string       `json:"createdAt"`
+}
+
+// Página de ingressos de um evento.
+type TicketPage struct {
+	Items []*Ticket `json:"items"`
+	// Total de ingressos que atendem ao filtro (todas as páginas)
+	TotalCount int `json:"totalCount"`
+	Limit      int `json:"limit"`
+	Offset     int `json:"offset"`
+}
+
+// Uma transferência de ingresso entre titulares (cadeia de custódia).
+type TicketTransfer struct {
+	From      *PublicUser `json:"from"`
+	To        *PublicUser `json:"to"`
+	CreatedAt string      `json:"createdAt"`
 }
 
 type TicketType struct {
@@ -182,6 +449,8 @@ type TicketType struct {
 	Audience     AudienceType `json:"audience"`
 	MaxQuantity  int          `json:"maxQuantity"`
 	SoldQuantity int          `json:"soldQuantity"`
+	// Pessoas que entram com um único ingresso deste tipo (ex.: mesa para 6)
+	Admits int `json:"admits"`
 }
 
 type TicketTypeInput struct {
@@ -190,6 +459,10 @@ type TicketTypeInput struct {
 	Price       float64      `json:"price"`
 	Audience    AudienceType `json:"audience"`
 	MaxQuantity int          `json:"maxQuantity"`
+	// Pessoas por ingresso (padrão 1). Acima de 1, cada unidade vendida emite um
+	// único ingresso com esse número de entradas e ocupa o mesmo número de lugares
+	// no lote.
+	Admits *int `json:"admits,omitempty"`
 }
 
 type UpdateEventInput struct {
@@ -199,8 +472,38 @@ type UpdateEventInput struct {
 	CoverImage  *string `json:"coverImage,omitempty"`
 	Location    *string `json:"location,omitempty"`
 	Address     *string `json:"address,omitempty"`
-}
-
+	// Nova capacidade do local; 0 remove o limite.
+	Capacity *int `json:"capacity,omitempty"`
+	// Novo fuso horário IANA; string vazia volta ao padrão da plataforma.
+	Timezone *string `json:"timezone,omitempty"`
+	// Permite (true) ou proíbe (false) transferir os ingressos do evento.
+	Transferable *bool `json:"transferable,omitempty"`
+	// Máximo de transferências por ingresso; 0 volta ao padrão da plataforma.
+	MaxTicketTransfers *int `json:"maxTicketTransfers,omitempty"`
+}
+
+// Perfil público do produtor. Campos omitidos não mudam; string vazia apaga o
+// campo. logo aceita uma URL http(s) ou uma imagem em base64 (como a foto do
+// usuário).
+type UpdateProducerProfileInput struct {
+	DisplayName  *string `json:"displayName,omitempty"`
+	Description  *string `json:"description,omitempty"`
+	Logo         *string `json:"logo,omitempty"`
+	ContactEmail *string `json:"contactEmail,omitempty"`
+	// Descritor da cobrança; acentos e símbolos são removidos, "" volta ao padrão
+	StatementDescriptor *string `json:"statementDescriptor,omitempty"`
+}
+
+// Alteração de um tipo de ingresso. Campos omitidos não mudam. O preço deve ser
+// maior que zero e maxQuantity não pode ficar abaixo do já vendido; pedidos já
+// criados mantêm o preço do momento da compra.
+type UpdateTicketTypeInput struct {
+	Name        *string  `json:"name,omitempty"`
+	Price       *float64 `json:"price,omitempty"`
+	MaxQuantity *int     `json:"maxQuantity,omitempty"`
+}
+
+// Usuário autenticado: a própria conta (me, login, cadastro e edição de perfil).
 type User struct {
 	ID               string   `json:"id"`
 	Name             string   `json:"name"`
@@ -215,6 +518,36 @@ type User struct {
 	CreatedAt        string   `json:"createdAt"`
 }
 
+// Endereço salvo no catálogo de endereços do usuário.
+type UserAddress struct {
+	ID string `json:"id"`
+	// Apelido opcional, ex.: Casa
+	Label         *string `json:"label,omitempty"`
+	Street        string  `json:"street"`
+	StreetNumber  string  `json:"streetNumber"`
+	Complementary *string `json:"complementary,omitempty"`
+	Neighborhood  string  `json:"neighborhood"`
+	City          string  `json:"city"`
+	// Sigla da UF, ex.: SP
+	State string `json:"state"`
+	// CEP, apenas dígitos
+	ZipCode        string  `json:"zipCode"`
+	ReferencePoint *string `json:"referencePoint,omitempty"`
+	// Endereço usado por padrão no pagamento quando a requisição não traz um
+	IsDefault bool   `json:"isDefault"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// Parâmetros UTM (até 100 caracteres cada).
+type UtmInput struct {
+	Source   *string `json:"source,omitempty"`
+	Medium   *string `json:"medium,omitempty"`
+	Campaign *string `json:"campaign,omitempty"`
+	Term     *string `json:"term,omitempty"`
+	Content  *string `json:"content,omitempty"`
+}
+
 // Resultado da validação de ingresso por QR Code.
 type ValidateTicketResult struct {
 	Success   bool    `json:"success"`
@@ -341,23 +674,142 @@ func (e EventStatus) MarshalJSON() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// Como o ingresso foi emitido; uma transferência não muda a origem.
+type TicketSource string
+
+const (
+	// Compra paga
+	TicketSourcePurchase TicketSource = "PURCHASE"
+	// Cortesia emitida pelo produtor
+	TicketSourceComp TicketSource = "COMP"
+)
+
+var AllTicketSource = []TicketSource{
+	TicketSourcePurchase,
+	TicketSourceComp,
+}
+
+func (e TicketSource) IsValid() bool {
+	switch e {
+	case TicketSourcePurchase, TicketSourceComp:
+		return true
+	}
+	return false
+}
+
+func (e TicketSource) String() string {
+	return string(e)
+}
+
+func (e *TicketSource) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TicketSource(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TicketSource", str)
+	}
+	return nil
+}
+
+func (e TicketSource) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *TicketSource) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e TicketSource) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+// Filtro de uso dos ingressos em eventTickets.
+type TicketUsageFilter string
+
+const (
+	TicketUsageFilterAll    TicketUsageFilter = "ALL"
+	TicketUsageFilterUsed   TicketUsageFilter = "USED"
+	TicketUsageFilterUnused TicketUsageFilter = "UNUSED"
+)
+
+var AllTicketUsageFilter = []TicketUsageFilter{
+	TicketUsageFilterAll,
+	TicketUsageFilterUsed,
+	TicketUsageFilterUnused,
+}
+
+func (e TicketUsageFilter) IsValid() bool {
+	switch e {
+	case TicketUsageFilterAll, TicketUsageFilterUsed, TicketUsageFilterUnused:
+		return true
+	}
+	return false
+}
+
+func (e TicketUsageFilter) String() string {
+	return string(e)
+}
+
+func (e *TicketUsageFilter) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TicketUsageFilter(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TicketUsageFilter", str)
+	}
+	return nil
+}
+
+func (e TicketUsageFilter) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *TicketUsageFilter) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e TicketUsageFilter) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
 type UserRole string
 
 const (
 	UserRoleUser     UserRole = "USER"
 	UserRoleProducer UserRole = "PRODUCER"
 	UserRoleAdmin    UserRole = "ADMIN"
+	// Comprador sem senha criado no checkout como convidado
+	UserRoleGuest UserRole = "GUEST"
 )
 
 var AllUserRole = []UserRole{
 	UserRoleUser,
 	UserRoleProducer,
 	UserRoleAdmin,
+	UserRoleGuest,
 }
 
 func (e UserRole) IsValid() bool {
 	switch e {
-	case UserRoleUser, UserRoleProducer, UserRoleAdmin:
+	case UserRoleUser, UserRoleProducer, UserRoleAdmin, UserRoleGuest:
 		return true
 	}
 	return false
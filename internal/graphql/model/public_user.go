@@ -0,0 +1,31 @@
+package model
+
+import "sync"
+
+// PublicUser is a User reached through someone else's data (an event's
+// producer, a ticket's owner). It is bound by hand in gqlgen.yml so the
+// @restricted directive can decide the caller's access once per object
+// instead of once per field.
+type PublicUser struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Email            *string  `json:"email,omitempty"`
+	Cpf              *string  `json:"cpf,omitempty"`
+	BirthDate        *string  `json:"birthDate,omitempty"`
+	PhoneCountryCode *string  `json:"phoneCountryCode,omitempty"`
+	PhoneAreaCode    *string  `json:"phoneAreaCode,omitempty"`
+	PhoneNumber      *string  `json:"phoneNumber,omitempty"`
+	PhotoURL         *string  `json:"photoUrl,omitempty"`
+	Role             UserRole `json:"role"`
+	CreatedAt        string   `json:"createdAt"`
+
+	accessOnce sync.Once
+	access     int
+}
+
+// Access returns the caller's access to the personal fields, running
+// resolve on the first call only.
+func (u *PublicUser) Access(resolve func() int) int {
+	u.accessOnce.Do(func() { u.access = resolve() })
+	return u.access
+}
@@ -0,0 +1,126 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/pagarme"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+// pixGateway answers GetOrderStatus with a pending PIX; other PagarmeAPI
+// methods are not used by the order query.
+type pixGateway struct {
+	pagarme.PagarmeAPI
+	calls int
+}
+
+func (g *pixGateway) GetOrderStatus(pagarmeOrderID string) (*pagarme.PixOrderResult, error) {
+	g.calls++
+	return &pagarme.PixOrderResult{
+		PagarmeOrderID: pagarmeOrderID,
+		Status:         "pending",
+		PixQRCode:      "00020126pix",
+		PixQRCodeURL:   "https://pagar.me/qr.png",
+		ExpiresAt:      "2099-01-01T00:30:00Z",
+	}, nil
+}
+
+func TestOrderQueryForBuyer(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	orderID := f.PendingOrder(t, sqlite, 2)
+	if _, err := sqlite.Exec(`UPDATE orders SET pagarme_order_id = 'or_1' WHERE id = ?`, orderID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.SetOrderPix(sqlite, orderID, "00020126saved", "", time.Now().Add(10*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	otherID, err := repository.CreateUser(sqlite, "Outro", "outro@email.com", "hash", "39053344705", "1990-01-01", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gateway := &pixGateway{}
+	handler := NewHandler(sqlite, &config.Config{JWTSecret: "test-secret"}, gateway, nil, nil)
+
+	orderQuery := func(userID, id string, refresh ...bool) map[string]interface{} {
+		t.Helper()
+		args := `id: "` + id + `"`
+		if len(refresh) > 0 && refresh[0] {
+			args += `, refreshPix: true`
+		}
+		q := `{ order(` + args + `) { id orderRef status paid total items { eventTitle ticketTypeName quantity subtotal } pix { qrCode qrCodeUrl } tickets { id } } }`
+		body, _ := json.Marshal(map[string]string{"query": q})
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var resp struct {
+			Data   map[string]interface{} `json:"data"`
+			Errors []interface{}          `json:"errors"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(resp.Errors) > 0 {
+			t.Fatalf("errors: %v", resp.Errors)
+		}
+		order, _ := resp.Data["order"].(map[string]interface{})
+		return order
+	}
+
+	order := orderQuery(f.BuyerID, orderID)
+	if order == nil || order["status"] != "PENDING" || order["paid"] != false || order["total"] != 100.0 {
+		t.Fatalf("pending order = %v", order)
+	}
+	if dig(order, "items", 0, "eventTitle") != "Show" || dig(order, "items", 0, "ticketTypeName") != "Pista" || dig(order, "items", 0, "subtotal") != 100.0 {
+		t.Errorf("items = %v", order["items"])
+	}
+	if dig(order, "pix", "qrCode") != "00020126saved" || gateway.calls != 0 {
+		t.Errorf("saved pix = %v, gateway calls = %d, want the saved code without asking the gateway", order["pix"], gateway.calls)
+	}
+	order = orderQuery(f.BuyerID, orderID, true)
+	if dig(order, "pix", "qrCode") != "00020126pix" || dig(order, "pix", "qrCodeUrl") != "https://pagar.me/qr.png" || gateway.calls != 1 {
+		t.Errorf("refreshed pix = %v, gateway calls = %d", order["pix"], gateway.calls)
+	}
+	if saved, _ := repository.OrderRowByID(sqlite, orderID); saved.PixQRCode != "00020126pix" || saved.PixExpiresAt != "2099-01-01T00:30:00Z" {
+		t.Errorf("refresh not saved: %+v", saved)
+	}
+	if tickets, _ := order["tickets"].([]interface{}); len(tickets) != 0 {
+		t.Errorf("tickets before payment = %v", tickets)
+	}
+
+	ref, _ := order["orderRef"].(string)
+	if byRef := orderQuery(f.BuyerID, ref); ref == "" || byRef == nil || byRef["id"] != orderID {
+		t.Errorf("order by ref %q = %v", ref, byRef)
+	}
+	if got := orderQuery(otherID, orderID); got != nil {
+		t.Errorf("another user read the order: %v", got)
+	}
+
+	// Paid: tickets are listed and the gateway is no longer asked for the PIX.
+	items, _ := repository.OrderItemsByOrderID(sqlite, orderID)
+	if err := repository.CreateTicketWithID(sqlite, "ticket-1", "CODE0001", "qr-1", orderID, items[0].ID, f.BuyerID, f.EventID, f.EventDateID, f.TicketTypeID, repository.TicketSourcePurchase); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.ConfirmOrder(sqlite, orderID); err != nil {
+		t.Fatal(err)
+	}
+	calls := gateway.calls
+	order = orderQuery(f.BuyerID, orderID)
+	if order["paid"] != true || order["pix"] != nil || dig(order, "tickets", 0, "id") != "ticket-1" {
+		t.Errorf("paid order = %v", order)
+	}
+	if gateway.calls != calls {
+		t.Errorf("gateway called for a paid order")
+	}
+}
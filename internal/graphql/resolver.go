@@ -3,7 +3,10 @@ package graphql
 import (
 	"database/sql"
 
+	"afterzin/api/internal/checkin"
 	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/pagarme"
 )
 
 // This file will not be regenerated automatically.
@@ -11,6 +14,33 @@ import (
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	DB     *sql.DB
-	Config *config.Config
+	DB       *sql.DB
+	Config   *config.Config
+	Pagarme  pagarme.PagarmeAPI // nil when PAGARME_API_KEY is not set
+	Checkins *checkin.Recorder  // scan attempt log for checkinStats
+	Mailer   mailer.Mailer      // complimentary ticket emails; nil skips them
 }
+
+// maxEventTicketsPageSize caps the eventTickets page size.
+const maxEventTicketsPageSize = 200
+
+// maxMyEventsPageSize caps the myEvents page size.
+const maxMyEventsPageSize = 100
+
+// maxProducerEventsPageSize caps the events page of the public producer query.
+const maxProducerEventsPageSize = 50
+
+// maxNotificationsPageSize caps how many notifications myNotifications returns.
+const maxNotificationsPageSize = 100
+
+// maxTicketAdmits caps how many people a single ticket of a type can admit.
+const maxTicketAdmits = 50
+
+// Length limits of the producer's public profile fields.
+const (
+	maxProducerDisplayNameLength = 100
+	maxProducerDescriptionLength = 2000
+)
+
+// maxAddressLabelLength caps the label of a saved address.
+const maxAddressLabelLength = 50
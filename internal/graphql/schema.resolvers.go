@@ -5,18 +5,30 @@ package graphql
 // Code generated by github.com/99designs/gqlgen version v0.17.49
 
 import (
-	"afterzin/api/internal/auth"
-	"afterzin/api/internal/graphql/model"
-	"afterzin/api/internal/middleware"
-	"afterzin/api/internal/pagarme"
-	"afterzin/api/internal/qrcode"
-	"afterzin/api/internal/repository"
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"afterzin/api/internal/audit"
+	"afterzin/api/internal/auth"
+	"afterzin/api/internal/blocklist"
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/eventtime"
+	"afterzin/api/internal/graphql/model"
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/money"
+	"afterzin/api/internal/outbox"
+	"afterzin/api/internal/pagarme"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+	"afterzin/api/internal/tickets"
+	"afterzin/api/internal/validate"
 
 	"github.com/google/uuid"
 )
@@ -26,48 +38,21 @@ func sanitizeDocument(doc string) string {
 	return regexp.MustCompile(`[^\d]`).ReplaceAllString(doc, "")
 }
 
-// isValidCPF implements the standard CPF checksum validation.
-func isValidCPF(cpf string) bool {
-	if len(cpf) != 11 {
-		return false
-	}
-	// reject repeated digits
-	repeat := true
-	for i := 1; i < 11; i++ {
-		if cpf[i] != cpf[0] {
-			repeat = false
-			break
-		}
-	}
-	if repeat {
-		return false
-	}
-	toInt := func(b byte) int { return int(b - '0') }
-	// first digit
-	sum := 0
-	for i := 0; i < 9; i++ {
-		sum += toInt(cpf[i]) * (10 - i)
-	}
-	r := sum % 11
-	var d1 int
-	if r < 2 {
-		d1 = 0
-	} else {
-		d1 = 11 - r
-	}
-	// second digit
-	sum = 0
-	for i := 0; i < 10; i++ {
-		sum += toInt(cpf[i]) * (11 - i)
-	}
-	r = sum % 11
-	var d2 int
-	if r < 2 {
-		d2 = 0
-	} else {
-		d2 = 11 - r
-	}
-	return toInt(cpf[9]) == d1 && toInt(cpf[10]) == d2
+// Timezone is the resolver for the timezone field.
+func (r *eventResolver) Timezone(ctx context.Context, obj *model.Event) (string, error) {
+	return r.Config.EventTimezone(obj.Timezone), nil
+}
+
+// TotalAvailable is the resolver for the totalAvailable field.
+func (r *eventResolver) TotalAvailable(ctx context.Context, obj *model.Event) (int, error) {
+	a, err := lookupEventAvailability(r.DB, obj.ID)
+	return a.available, err
+}
+
+// SoldOut is the resolver for the soldOut field.
+func (r *eventResolver) SoldOut(ctx context.Context, obj *model.Event) (bool, error) {
+	a, err := lookupEventAvailability(r.DB, obj.ID)
+	return a.lots > 0 && a.available == 0, err
 }
 
 // Register is the resolver for the register field.
@@ -83,9 +68,12 @@ func (r *mutationResolver) Register(ctx context.Context, input model.RegisterInp
 	if len(sanitizedCPF) != 11 {
 		return nil, fmt.Errorf("CPF inválido: deve conter 11 dígitos")
 	}
-	if !isValidCPF(sanitizedCPF) {
+	if !validate.IsValidCPF(sanitizedCPF) {
 		return nil, fmt.Errorf("CPF inválido: checksum falhou")
 	}
+	if err := blocklist.Check(r.DB, sanitizedCPF, input.Email, "cadastro"); err != nil {
+		return nil, err
+	}
 
 	// Validate birth date and ensure user is at least 16 years old
 	var bd time.Time
@@ -111,14 +99,17 @@ func (r *mutationResolver) Register(ctx context.Context, input model.RegisterInp
 		phoneCC = "55" // Default Brasil
 	}
 
-	err := pagarme.ValidatePhone(phoneCC, input.PhoneAreaCode, input.PhoneNumber)
+	err := validate.ValidatePhone(phoneCC, input.PhoneAreaCode, input.PhoneNumber)
 	if err != nil {
-		return nil, fmt.Errorf("telefone inválido: %w", err)
+		return nil, phoneInputError(err)
 	}
 
-	phone := pagarme.ParsePhone(phoneCC, input.PhoneAreaCode, input.PhoneNumber)
+	phone := validate.ParsePhone(phoneCC, input.PhoneAreaCode, input.PhoneNumber)
 
-	// 4. Hash de senha
+	// 4. Validar e gerar hash da senha
+	if err := auth.ValidatePassword(input.Password); err != nil {
+		return nil, err
+	}
 	hash, err := auth.HashPassword(input.Password)
 	if err != nil {
 		return nil, err
@@ -158,28 +149,36 @@ func (r *mutationResolver) Register(ctx context.Context, input model.RegisterInp
 			PhoneNumber:      strPtr(phone.Number),
 			PhotoURL:         nil,
 			Role:             model.UserRoleUser,
-			CreatedAt:        time.Now().UTC().Format("2006-01-02T15:04:05Z07:00"),
+			CreatedAt:        repository.FormatTime(time.Now()),
 		}
 	}
 
-	// 7. Gera token
-	token, _ := auth.NewToken(id, "USER", r.Config.JWTSecret, 24*time.Hour)
-
-	// 8. Retorna
-	return &model.AuthPayload{Token: token, User: userModel}, nil
+	// 7. Gera token e retorna
+	return newAuthPayload(r.Config.JWTSecret, userModel)
 }
 
 // Login is the resolver for the login field.
 func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (*model.AuthPayload, error) {
 	user, err := repository.UserByEmail(r.DB, input.Email)
-	if err != nil || user == nil {
-		return nil, errors.New("credenciais inválidas")
+	if err != nil {
+		logger.Errorf("erro ao buscar usuário no login: %v", err)
+		return nil, errLoginFailed
+	}
+	if user == nil {
+		auth.CheckPasswordUnknownUser(input.Password)
+		return nil, errInvalidCredentials
+	}
+	ok, upgraded := auth.VerifyPassword(user.PasswordHash, input.Password)
+	if !ok {
+		return nil, errInvalidCredentials
 	}
-	if !auth.CheckPassword(user.PasswordHash, input.Password) {
-		return nil, errors.New("credenciais inválidas")
+	if upgraded != "" {
+		// Best effort: the old hash keeps working if this fails
+		if err := repository.UpdateUserPasswordHash(r.DB, user.ID, upgraded); err != nil {
+			logger.Warnf("erro ao atualizar hash de senha do usuário %s: %v", user.ID, err)
+		}
 	}
-	token, _ := auth.NewToken(user.ID, user.Role, r.Config.JWTSecret, 24*time.Hour)
-	return &model.AuthPayload{Token: token, User: userRowToModel(user)}, nil
+	return newAuthPayload(r.Config.JWTSecret, userRowToModel(user))
 }
 
 // CreateEvent is the resolver for the createEvent field.
@@ -195,10 +194,40 @@ func (r *mutationResolver) CreateEvent(ctx context.Context, input model.CreateEv
 			return nil, errors.New("erro ao criar perfil de produtor")
 		}
 	}
-	id, err := repository.CreateEvent(r.DB, prodID, input.Title, input.Description, input.Category, input.CoverImage, input.Location, input.Address)
+	if input.Capacity != nil && *input.Capacity <= 0 {
+		return nil, errors.New("capacidade deve ser maior que zero")
+	}
+	if input.Timezone != nil {
+		if _, err := eventtime.Load(*input.Timezone); err != nil {
+			return nil, err
+		}
+	}
+	if input.MaxTicketTransfers != nil && *input.MaxTicketTransfers < 0 {
+		return nil, errors.New("máximo de transferências não pode ser negativo")
+	}
+	var timezone string
+	if input.Timezone != nil {
+		timezone = *input.Timezone
+	}
+	id, err := repository.CreateEvent(r.DB, prodID, input.Title, input.Description, input.Category, input.CoverImage, input.Location, input.Address, timezone)
 	if err != nil {
 		return nil, err
 	}
+	if input.Capacity != nil {
+		if err := repository.SetEventCapacity(r.DB, id, *input.Capacity); err != nil {
+			return nil, err
+		}
+	}
+	if input.Transferable != nil {
+		if err := repository.SetEventTransferable(r.DB, id, *input.Transferable); err != nil {
+			return nil, err
+		}
+	}
+	if input.MaxTicketTransfers != nil {
+		if err := repository.SetEventMaxTicketTransfers(r.DB, id, *input.MaxTicketTransfers); err != nil {
+			return nil, err
+		}
+	}
 	row, _ := repository.EventByID(r.DB, id)
 	return eventRowToModel(row, r.DB)
 }
@@ -217,9 +246,51 @@ func (r *mutationResolver) UpdateEvent(ctx context.Context, id string, input mod
 	if prod == nil || prod.UserID != userID {
 		return nil, errors.New("sem permissão")
 	}
+	if input.Capacity != nil {
+		if *input.Capacity < 0 {
+			return nil, errors.New("capacidade não pode ser negativa")
+		}
+		if *input.Capacity > 0 {
+			date, total, err := repository.EventDateOverCapacity(r.DB, id, *input.Capacity)
+			if err != nil {
+				return nil, err
+			}
+			if date != "" {
+				return nil, fmt.Errorf("capacidade insuficiente: os lotes da data %s somam %d ingressos (capacidade %d)", date, total, *input.Capacity)
+			}
+		}
+	}
+	if input.Timezone != nil && *input.Timezone != "" {
+		if _, err := eventtime.Load(*input.Timezone); err != nil {
+			return nil, err
+		}
+	}
+	if input.MaxTicketTransfers != nil && *input.MaxTicketTransfers < 0 {
+		return nil, errors.New("máximo de transferências não pode ser negativo")
+	}
 	if err := repository.UpdateEvent(r.DB, id, input.Title, input.Description, input.Category, input.CoverImage, input.Location, input.Address, nil); err != nil {
 		return nil, err
 	}
+	if input.Capacity != nil {
+		if err := repository.SetEventCapacity(r.DB, id, *input.Capacity); err != nil {
+			return nil, err
+		}
+	}
+	if input.Timezone != nil {
+		if err := repository.SetEventTimezone(r.DB, id, *input.Timezone); err != nil {
+			return nil, err
+		}
+	}
+	if input.Transferable != nil {
+		if err := repository.SetEventTransferable(r.DB, id, *input.Transferable); err != nil {
+			return nil, err
+		}
+	}
+	if input.MaxTicketTransfers != nil {
+		if err := repository.SetEventMaxTicketTransfers(r.DB, id, *input.MaxTicketTransfers); err != nil {
+			return nil, err
+		}
+	}
 	row, _ = repository.EventByID(r.DB, id)
 	return eventRowToModel(row, r.DB)
 }
@@ -305,6 +376,13 @@ func (r *mutationResolver) CreateLot(ctx context.Context, dateID string, input m
 	if prod == nil || prod.UserID != userID {
 		return nil, errors.New("sem permissão")
 	}
+	remaining, limited, err := repository.EventRemainingCapacity(r.DB, dateID)
+	if err != nil {
+		return nil, err
+	}
+	if limited && input.TotalQuantity > remaining {
+		return nil, fmt.Errorf("lote excede a capacidade do evento na data %s: restam %d lugares", ed.Date, remaining)
+	}
 	id, err := repository.CreateLot(r.DB, dateID, input.Name, input.StartsAt, input.EndsAt, input.TotalQuantity)
 	if err != nil {
 		return nil, err
@@ -334,7 +412,14 @@ func (r *mutationResolver) CreateTicketType(ctx context.Context, lotID string, i
 	if prod == nil || prod.UserID != userID {
 		return nil, errors.New("sem permissão")
 	}
-	id, err := repository.CreateTicketType(r.DB, lotID, input.Name, input.Description, input.Price, string(input.Audience), input.MaxQuantity)
+	admits := 1
+	if input.Admits != nil {
+		admits = *input.Admits
+	}
+	if admits < 1 || admits > maxTicketAdmits {
+		return nil, fmt.Errorf("pessoas por ingresso deve estar entre 1 e %d", maxTicketAdmits)
+	}
+	id, err := repository.CreateTicketType(r.DB, lotID, input.Name, input.Description, input.Price, string(input.Audience), input.MaxQuantity, admits)
 	if err != nil {
 		return nil, err
 	}
@@ -342,19 +427,152 @@ func (r *mutationResolver) CreateTicketType(ctx context.Context, lotID string, i
 	if tt == nil {
 		return nil, err
 	}
-	var desc *string
-	if tt.Description.Valid {
-		desc = &tt.Description.String
+	return ticketTypeRowToModel(tt), nil
+}
+
+// UpdateTicketType is the resolver for the updateTicketType field.
+func (r *mutationResolver) UpdateTicketType(ctx context.Context, id string, input model.UpdateTicketTypeInput) (*model.TicketType, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
 	}
-	return &model.TicketType{
-		ID:           tt.ID,
-		Name:         tt.Name,
-		Description:  desc,
-		Price:        tt.Price,
-		Audience:     model.AudienceType(tt.Audience),
-		MaxQuantity:  tt.MaxQuantity,
-		SoldQuantity: tt.SoldQuantity,
-	}, nil
+	tt, _ := repository.TicketTypeByID(r.DB, id)
+	if tt == nil {
+		return nil, errors.New("tipo de ingresso não encontrado")
+	}
+	lot, _ := repository.LotByID(r.DB, tt.LotID)
+	if lot == nil {
+		return nil, errors.New("lote não encontrado")
+	}
+	ed, _ := repository.EventDateByID(r.DB, lot.EventDateID)
+	if ed == nil {
+		return nil, errors.New("data não encontrada")
+	}
+	ev, _ := repository.EventByID(r.DB, ed.EventID)
+	if ev == nil {
+		return nil, errors.New("evento não encontrado")
+	}
+	prod, _ := repository.ProducerByID(r.DB, ev.ProducerID)
+	if prod == nil || prod.UserID != userID {
+		return nil, errors.New("sem permissão")
+	}
+	if input.Name != nil && strings.TrimSpace(*input.Name) == "" {
+		return nil, errors.New("nome não pode ser vazio")
+	}
+	updated, err := repository.UpdateTicketType(r.DB, id, input.Name, input.Price, input.MaxQuantity, userID)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, errors.New("tipo de ingresso não encontrado")
+	}
+	return ticketTypeRowToModel(updated), nil
+}
+
+// IssueComplimentaryTickets is the resolver for the issueComplimentaryTickets field.
+func (r *mutationResolver) IssueComplimentaryTickets(ctx context.Context, eventDateID string, ticketTypeID string, recipients []*model.CompRecipientInput, sendEmail *bool) ([]*model.Ticket, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	req := tickets.CompRequest{
+		ProducerUserID: userID,
+		EventDateID:    eventDateID,
+		TicketTypeID:   ticketTypeID,
+		SendEmail:      sendEmail != nil && *sendEmail,
+	}
+	for _, rc := range recipients {
+		comp := tickets.CompRecipient{Name: rc.Name, Email: rc.Email}
+		if rc.Cpf != nil {
+			comp.CPF = *rc.Cpf
+		}
+		req.Recipients = append(req.Recipients, comp)
+	}
+	ids, err := tickets.IssueComplimentaryTickets(r.DB, r.Config, r.Mailer, req)
+	if err != nil {
+		return nil, err
+	}
+	tickets := make([]*model.Ticket, 0, len(ids))
+	for _, id := range ids {
+		t, _ := repository.TicketByID(r.DB, id)
+		if ticket, _ := ticketRowToModel(r.DB, t); ticket != nil {
+			tickets = append(tickets, ticket)
+		}
+	}
+	return tickets, nil
+}
+
+// UpdateProducerProfile is the resolver for the updateProducerProfile field.
+func (r *mutationResolver) UpdateProducerProfile(ctx context.Context, input model.UpdateProducerProfileInput) (*model.Producer, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	prodID, _ := repository.ProducerIDByUser(r.DB, userID)
+	if prodID == "" {
+		return nil, errors.New("usuário não é produtor")
+	}
+
+	profile := repository.ProducerProfile{Description: input.Description}
+	if input.DisplayName != nil {
+		name := strings.TrimSpace(*input.DisplayName)
+		if utf8.RuneCountInString(name) > maxProducerDisplayNameLength {
+			return nil, fmt.Errorf("nome de exibição deve ter no máximo %d caracteres", maxProducerDisplayNameLength)
+		}
+		profile.DisplayName = &name
+	}
+	if input.Description != nil && utf8.RuneCountInString(*input.Description) > maxProducerDescriptionLength {
+		return nil, fmt.Errorf("descrição deve ter no máximo %d caracteres", maxProducerDescriptionLength)
+	}
+	if input.Logo != nil {
+		logo := strings.TrimSpace(*input.Logo)
+		if logo != "" {
+			normalized, err := normalizeImage(logo)
+			if err != nil {
+				return nil, err
+			}
+			logo = normalized
+		}
+		profile.LogoURL = &logo
+	}
+	if input.ContactEmail != nil {
+		email := validate.NormalizeEmail(*input.ContactEmail)
+		if email != "" {
+			if err := validate.ValidateEmail(email); err != nil {
+				return nil, fmt.Errorf("e-mail de contato inválido: %w", err)
+			}
+		}
+		profile.ContactEmail = &email
+	}
+	if input.StatementDescriptor != nil {
+		descriptor := pagarme.SanitizeStatementDescriptor(*input.StatementDescriptor)
+		if descriptor == "" && strings.TrimSpace(*input.StatementDescriptor) != "" {
+			return nil, errors.New("descritor da cobrança inválido: use letras, números, espaço, ponto ou hífen")
+		}
+		profile.StatementDescriptor = &descriptor
+	}
+
+	if err := repository.UpdateProducerProfile(r.DB, prodID, profile); err != nil {
+		return nil, err
+	}
+	prod, err := repository.ProducerByID(r.DB, prodID)
+	if err != nil {
+		return nil, err
+	}
+	return producerRowToModel(r.DB, prod), nil
+}
+
+// RefreshRecipientStatus is the resolver for the refreshRecipientStatus field.
+func (r *mutationResolver) RefreshRecipientStatus(ctx context.Context) (*model.ProducerPaymentStatus, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	st, err := pagarme.RefreshRecipientStatus(r.Pagarme, r.DB, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return paymentStatusToModel(st), nil
 }
 
 // CheckoutPreview is the resolver for the checkoutPreview field.
@@ -366,14 +584,22 @@ func (r *mutationResolver) CheckoutPreview(ctx context.Context, input model.Chec
 	if len(input.Items) == 0 {
 		return nil, errors.New("nenhum item")
 	}
+	cart := make([]pagarme.CartItem, len(input.Items))
+	for i, it := range input.Items {
+		cart[i] = pagarme.CartItem{EventDateID: it.EventDateID, TicketTypeID: it.TicketTypeID, Quantity: it.Quantity}
+	}
+	if err := pagarme.CheckItemLimits(cart, r.Config.MaxOrderItems, r.Config.MaxItemQuantity); err != nil {
+		return nil, err
+	}
 	var total float64
 	var items []*model.CheckoutPreviewItem
+	var lines []repository.NewOrderItem
 	for _, it := range input.Items {
 		tt, _ := repository.TicketTypeByID(r.DB, it.TicketTypeID)
 		if tt == nil {
 			return nil, errors.New("tipo de ingresso não encontrado")
 		}
-		if it.Quantity <= 0 || tt.SoldQuantity+it.Quantity > tt.MaxQuantity {
+		if it.Quantity <= 0 || it.Quantity > pagarme.AvailableUnits(r.DB, tt) {
 			return nil, errors.New("quantidade indisponível")
 		}
 		ed, _ := repository.EventDateByID(r.DB, it.EventDateID)
@@ -384,8 +610,13 @@ func (r *mutationResolver) CheckoutPreview(ctx context.Context, input model.Chec
 		if ev == nil {
 			return nil, errors.New("evento não encontrado")
 		}
+		tz := r.Config.EventTimezone(ev.Timezone.String)
+		if err := eventtime.CheckDateOpen(ed.Date, ed.StartTime.String, tz, time.Now(), r.Config.EventSalesGrace); err != nil {
+			return nil, err
+		}
 		sub := float64(it.Quantity) * tt.Price
 		total += sub
+		lines = append(lines, repository.NewOrderItem{EventDateID: it.EventDateID, TicketTypeID: it.TicketTypeID, Quantity: it.Quantity, UnitPrice: tt.Price})
 		items = append(items, &model.CheckoutPreviewItem{
 			EventTitle:     ev.Title,
 			EventDate:      ed.Date,
@@ -395,19 +626,16 @@ func (r *mutationResolver) CheckoutPreview(ctx context.Context, input model.Chec
 			Subtotal:       sub,
 		})
 	}
-	orderID, err := repository.CreateOrder(r.DB, userID, total, 30*time.Minute)
+	orderID, err := repository.CreateOrderWithItems(r.DB, userID, total, 30*time.Minute, lines, repository.OrderLimits{MaxPending: r.Config.MaxPendingOrders})
 	if err != nil {
-		return nil, err
+		return nil, pagarme.PendingOrderLimit(r.DB, userID, r.Config.MaxPendingOrders, err)
 	}
-	for _, it := range input.Items {
-		tt, _ := repository.TicketTypeByID(r.DB, it.TicketTypeID)
-		if tt == nil {
-			continue
-		}
-		_, _ = repository.CreateOrderItem(r.DB, orderID, it.EventDateID, it.TicketTypeID, it.Quantity, tt.Price)
+	if r.Config.Feature(config.FeatureSalesAttribution) {
+		pagarme.RecordOrderAttribution(r.DB, orderID, attributionFromInput(input.Channel, input.Utm))
 	}
 	return &model.CheckoutPreviewResult{
 		CheckoutID: orderID,
+		OrderRef:   orderRefPtr(r.DB, orderID),
 		Total:      total,
 		Items:      items,
 	}, nil
@@ -419,22 +647,37 @@ func (r *mutationResolver) CheckoutPay(ctx context.Context, input model.Checkout
 	if userID == "" {
 		return nil, errors.New("não autenticado")
 	}
-	orderUserID, status, _, err := repository.OrderByID(r.DB, input.CheckoutID)
-	if err != nil || orderUserID == "" {
-		return nil, errors.New("pedido não encontrado")
-	}
-	if orderUserID != userID {
-		return nil, errors.New("pedido não pertence ao usuário")
+	order, err := repository.AuthorizeOrderAccess(r.DB, input.CheckoutID, userID)
+	if err != nil {
+		return nil, err
 	}
-	if status == "PAID" {
+	if order.Status == "PAID" {
 		msg := "Pedido já pago."
-		return &model.CheckoutPayResult{Success: true, Message: &msg}, nil
+		return &model.CheckoutPayResult{Success: true, OrderRef: orderRefPtr(r.DB, input.CheckoutID), Message: &msg}, nil
+	}
+	if order.Status != "PENDING" && order.Status != "PROCESSING" {
+		return nil, repository.ErrOrderNotConfirmable
 	}
 	items, err := repository.OrderItemsByOrderID(r.DB, input.CheckoutID)
 	if err != nil {
 		return nil, err
 	}
+	for _, it := range items {
+		evDate, _ := repository.EventDateByID(r.DB, it.EventDateID)
+		if evDate == nil {
+			continue
+		}
+		var eventTZ string
+		if ev, _ := repository.EventByID(r.DB, evDate.EventID); ev != nil {
+			eventTZ = ev.Timezone.String
+		}
+		tz := r.Config.EventTimezone(eventTZ)
+		if err := eventtime.CheckDateOpen(evDate.Date, evDate.StartTime.String, tz, time.Now(), r.Config.EventSalesGrace); err != nil {
+			return nil, err
+		}
+	}
 	var ticketIDs []string
+	eventTitle := ""
 	for _, it := range items {
 		evDate, _ := repository.EventDateByID(r.DB, it.EventDateID)
 		if evDate == nil {
@@ -444,26 +687,35 @@ func (r *mutationResolver) CheckoutPay(ctx context.Context, input model.Checkout
 		if ev == nil {
 			continue
 		}
+		eventTitle = ev.Title
 		for i := 0; i < it.Quantity; i++ {
 			id := uuid.New().String()
 			code := repository.GenerateTicketCode()
-			qrPayload := qrcode.GenerateSignedPayload(id, []byte(r.Config.JWTSecret))
-			err := repository.CreateTicketWithID(r.DB, id, code, qrPayload, input.CheckoutID, it.ID, userID, ev.ID, it.EventDateID, it.TicketTypeID)
+			qrPayload := r.Config.QRRing().Sign(id)
+			err := repository.CreateTicketWithID(r.DB, id, code, qrPayload, input.CheckoutID, it.ID, userID, ev.ID, it.EventDateID, it.TicketTypeID, repository.TicketSourcePurchase)
 			if err != nil {
 				continue
 			}
 			ticketIDs = append(ticketIDs, id)
 			repository.IncrementTicketTypeSold(r.DB, it.TicketTypeID, 1)
 			lotID, _ := repository.LotIDByTicketTypeID(r.DB, it.TicketTypeID)
-			repository.DecrementLotAvailable(r.DB, lotID, 1)
+			admits := 1
+			if tt, _ := repository.TicketTypeByID(r.DB, it.TicketTypeID); tt != nil {
+				admits = tt.Admits
+			}
+			repository.DecrementLotAvailable(r.DB, lotID, admits)
 		}
 	}
 	if err := repository.ConfirmOrder(r.DB, input.CheckoutID); err != nil {
 		return nil, err
 	}
+	outbox.Notify(r.DB, userID, repository.NotificationTicketPurchased, outbox.TicketPurchasedPayload{
+		OrderID: input.CheckoutID, OrderRef: order.OrderRef, EventTitle: eventTitle, Tickets: len(ticketIDs),
+	})
 	msg := "Após a confirmação do pagamento, o ingresso ficará disponível na sua Mochila de Tickets."
 	return &model.CheckoutPayResult{
 		Success:   true,
+		OrderRef:  orderRefPtr(r.DB, input.CheckoutID),
 		TicketIds: ticketIDs,
 		Message:   &msg,
 	}, nil
@@ -475,21 +727,9 @@ func (r *mutationResolver) UpdateProfilePhoto(ctx context.Context, photoBase64 s
 	if userID == "" {
 		return nil, errors.New("não autenticado")
 	}
-	payload := photoBase64
-	if len(payload) > 7 && (strings.HasPrefix(payload, "data:img") || strings.HasPrefix(payload, "data:image/")) {
-		// use last comma to handle cases where multiple data URI prefixes
-		// were concatenated (e.g. "data:image/jpeg;base64,data:image/png;base64,....")
-		if idx := strings.LastIndex(payload, ","); idx != -1 && idx+1 < len(payload) {
-			payload = payload[idx+1:]
-		}
-	}
-	if len(payload) > 300*1024 { // ~300 KB in base64 is ~400k chars; allow up to 400k for safety
-		return nil, errors.New("imagem muito grande; máximo 300 KB")
-	}
-	// Accept only base64 that decodes to image (we store as data URI for display)
-	normalized := "data:image/jpeg;base64," + payload
-	if len(normalized) > 500*1024 {
-		return nil, errors.New("imagem muito grande; máximo 300 KB")
+	normalized, err := normalizeImageBase64(photoBase64)
+	if err != nil {
+		return nil, err
 	}
 	if err := repository.UpdateUserPhotoURL(r.DB, userID, normalized); err != nil {
 		return nil, err
@@ -511,13 +751,13 @@ func (r *mutationResolver) UpdatePhone(ctx context.Context, phoneCountryCode, ph
 	}
 
 	// Validar telefone
-	err := pagarme.ValidatePhone(phoneCountryCode, phoneAreaCode, phoneNumber)
+	err := validate.ValidatePhone(phoneCountryCode, phoneAreaCode, phoneNumber)
 	if err != nil {
-		return nil, fmt.Errorf("telefone inválido: %w", err)
+		return nil, phoneInputError(err)
 	}
 
 	// Parsear e sanitizar
-	phone := pagarme.ParsePhone(phoneCountryCode, phoneAreaCode, phoneNumber)
+	phone := validate.ParsePhone(phoneCountryCode, phoneAreaCode, phoneNumber)
 
 	// Atualizar no banco
 	err = repository.UpdateUserPhone(r.DB, userID, phone.CountryCode, phone.AreaCode, phone.Number)
@@ -552,24 +792,18 @@ func (r *mutationResolver) ValidateTicket(ctx context.Context, eventID string, q
 	if eventProducerID != prodID {
 		return &model.ValidateTicketResult{Success: false, ErrorCode: strPtr("WRONG_EVENT"), Message: strPtr("ingresso não pertence a este evento ou você não é o produtor")}, nil
 	}
-	// QR lookup: try direct DB match first, then V2 signed payload, then V1 signed payload.
-	t, err := repository.TicketByQRCode(r.DB, qrCode)
-	if err != nil || t == nil {
-		// Try V2 QR payload: ticketID:paymentIntentID:eventID.signature
-		if ticketID, _, _, sigOK := qrcode.VerifySignedPayloadV2(qrCode, []byte(r.Config.JWTSecret)); sigOK {
-			t, err = repository.TicketByID(r.DB, ticketID)
-		}
-		// Try V1 QR payload: ticketID.signature
-		if err != nil || t == nil {
-			if ticketID, sigOK := qrcode.VerifySignedPayload(qrCode, []byte(r.Config.JWTSecret)); sigOK {
-				t, err = repository.TicketByID(r.DB, ticketID)
-			}
-		}
-	}
+	// QR lookup: direct DB match first, then V2 and V1 signed payloads
+	t, _, err := tickets.TicketByQRPayload(r.DB, r.Config.QRRing(), qrCode)
+	attempt := repository.CheckinAttempt{EventID: eventID, ProducerID: prodID}
 	if err != nil || t == nil {
+		attempt.Result = repository.CheckinResultInvalid
+		r.Checkins.Record(attempt)
 		return &model.ValidateTicketResult{Success: false, ErrorCode: strPtr("NOT_FOUND"), Message: strPtr("ingresso não encontrado")}, nil
 	}
+	attempt.TicketID = t.ID
 	if t.EventID != eventID {
+		attempt.Result = repository.CheckinResultWrongEvent
+		r.Checkins.Record(attempt)
 		return &model.ValidateTicketResult{Success: false, ErrorCode: strPtr("WRONG_EVENT"), Message: strPtr("ingresso não pertence a este evento")}, nil
 	}
 	// Atomic update: only one validation can succeed (prevents concurrent double use)
@@ -578,12 +812,176 @@ func (r *mutationResolver) ValidateTicket(ctx context.Context, eventID string, q
 		return &model.ValidateTicketResult{Success: false, Message: strPtr("erro ao validar")}, nil
 	}
 	if !updated {
+		attempt.Result = repository.CheckinResultAlreadyUsed
+		r.Checkins.Record(attempt)
 		return &model.ValidateTicketResult{Success: false, ErrorCode: strPtr("ALREADY_USED"), Message: strPtr("ingresso já utilizado")}, nil
 	}
 	_ = repository.InsertTicketValidation(r.DB, t.ID, eventID, prodID)
-	t.Used = 1
+	attempt.Result = repository.CheckinResultOK
+	r.Checkins.Record(attempt)
+	// Re-read so a multi-admit ticket reports the entries it has left
+	if fresh, _ := repository.TicketByID(r.DB, t.ID); fresh != nil {
+		t = fresh
+	} else {
+		t.Used = 1
+	}
 	ticket, _ := ticketRowToModel(r.DB, t)
-	return &model.ValidateTicketResult{Success: true, Ticket: ticket}, nil
+	result := &model.ValidateTicketResult{Success: true, Ticket: ticket}
+	if t.Admits > 1 {
+		result.Message = strPtr(fmt.Sprintf("entrada registrada: restam %d de %d", t.AdmitsRemaining, t.Admits))
+	}
+	return result, nil
+}
+
+// SetEventMaxOrderAmount is the resolver for the setEventMaxOrderAmount field.
+func (r *mutationResolver) SetEventMaxOrderAmount(ctx context.Context, eventID string, maxOrderCentavos *int) (bool, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return false, errors.New("não autenticado")
+	}
+	user, _ := repository.UserByID(r.DB, userID)
+	if user == nil || user.Role != string(model.UserRoleAdmin) {
+		return false, errors.New("sem permissão")
+	}
+	if producerID, _ := repository.EventProducerID(r.DB, eventID); producerID == "" {
+		return false, errors.New("evento não encontrado")
+	}
+	var max int64
+	if maxOrderCentavos != nil {
+		if *maxOrderCentavos <= 0 {
+			return false, errors.New("valor máximo deve ser maior que zero")
+		}
+		max = int64(*maxOrderCentavos)
+	}
+	err := audit.Admin(r.DB, userID, "event.max_order_amount", "event", eventID, fmt.Sprintf("max_order_centavos=%d", max), func(tx *sql.Tx) error {
+		return repository.SetEventMaxOrderCentavos(tx, eventID, max)
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// TransferTicket is the resolver for the transferTicket field.
+func (r *mutationResolver) TransferTicket(ctx context.Context, ticketID string, recipientEmail string) (bool, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return false, errors.New("não autenticado")
+	}
+	if err := tickets.TransferTicket(r.DB, r.Config, ticketID, userID, recipientEmail); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// MarkNotificationRead is the resolver for the markNotificationRead field.
+func (r *mutationResolver) MarkNotificationRead(ctx context.Context, id string) (*model.Notification, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	n, err := repository.MarkNotificationRead(r.DB, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, errors.New("notificação não encontrada")
+	}
+	return notificationRowToModel(n), nil
+}
+
+// CreateAddress is the resolver for the createAddress field.
+func (r *mutationResolver) CreateAddress(ctx context.Context, input model.AddressInput) (*model.UserAddress, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	if !r.Config.Feature(config.FeatureAddressBook) {
+		return nil, rest.ErrFeatureDisabled
+	}
+	addr, err := userAddressFromInput(userID, input)
+	if err != nil {
+		return nil, err
+	}
+	id, err := repository.CreateUserAddress(r.DB, addr)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := repository.UserAddressByID(r.DB, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return userAddressToModel(saved), nil
+}
+
+// UpdateAddress is the resolver for the updateAddress field.
+func (r *mutationResolver) UpdateAddress(ctx context.Context, id string, input model.AddressInput) (*model.UserAddress, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	if !r.Config.Feature(config.FeatureAddressBook) {
+		return nil, rest.ErrFeatureDisabled
+	}
+	addr, err := userAddressFromInput(userID, input)
+	if err != nil {
+		return nil, err
+	}
+	addr.ID = id
+	ok, err := repository.UpdateUserAddress(r.DB, addr)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("endereço não encontrado")
+	}
+	saved, err := repository.UserAddressByID(r.DB, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return userAddressToModel(saved), nil
+}
+
+// SetDefaultAddress is the resolver for the setDefaultAddress field.
+func (r *mutationResolver) SetDefaultAddress(ctx context.Context, id string) (*model.UserAddress, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	if !r.Config.Feature(config.FeatureAddressBook) {
+		return nil, rest.ErrFeatureDisabled
+	}
+	ok, err := repository.SetDefaultUserAddress(r.DB, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("endereço não encontrado")
+	}
+	saved, err := repository.UserAddressByID(r.DB, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return userAddressToModel(saved), nil
+}
+
+// DeleteAddress is the resolver for the deleteAddress field.
+func (r *mutationResolver) DeleteAddress(ctx context.Context, id string) (bool, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return false, errors.New("não autenticado")
+	}
+	if !r.Config.Feature(config.FeatureAddressBook) {
+		return false, rest.ErrFeatureDisabled
+	}
+	ok, err := repository.DeleteUserAddress(r.DB, id, userID)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, errors.New("endereço não encontrado")
+	}
+	return true, nil
 }
 
 func strPtr(s string) *string { return &s }
@@ -651,26 +1049,62 @@ func (r *queryResolver) ProducerEvents(ctx context.Context) ([]*model.Event, err
 	return out, nil
 }
 
+// MyEvents is the resolver for the myEvents field.
+func (r *queryResolver) MyEvents(ctx context.Context, status *model.EventStatus, includeDrafts *bool, limit *int, offset *int) (*model.ProducerEventPage, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+
+	lim, off := 20, 0
+	if limit != nil {
+		lim = *limit
+	}
+	if offset != nil {
+		off = *offset
+	}
+	if lim < 1 || lim > maxMyEventsPageSize {
+		return nil, fmt.Errorf("limit deve estar entre 1 e %d", maxMyEventsPageSize)
+	}
+	if off < 0 {
+		return nil, errors.New("offset não pode ser negativo")
+	}
+	page := &model.ProducerEventPage{Items: []*model.ProducerEventSummary{}, Limit: lim, Offset: off}
+
+	prodID, _ := repository.ProducerIDByUser(r.DB, userID)
+	if prodID == "" {
+		return page, nil
+	}
+	drafts := includeDrafts == nil || *includeDrafts
+	var st string
+	if status != nil {
+		st = string(*status)
+	}
+	rows, total, err := repository.EventsByProducer(r.DB, prodID, drafts, st, lim, off)
+	if err != nil {
+		return nil, err
+	}
+	page.TotalCount = total
+	for _, row := range rows {
+		ev, err := eventRowToModel(&row.EventRow, r.DB)
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, &model.ProducerEventSummary{Event: ev, TicketsSold: row.TicketsSold, Revenue: row.Revenue})
+	}
+	return page, nil
+}
+
 // ProducerPublicProfile is the resolver for the producerPublicProfile field.
 func (r *queryResolver) ProducerPublicProfile(ctx context.Context, producerID string) (*model.ProducerPublicProfile, error) {
 	prod, err := repository.ProducerByID(r.DB, producerID)
 	if err != nil || prod == nil {
 		return nil, nil
 	}
-	user, _ := repository.UserByID(r.DB, prod.UserID)
-	if user == nil {
+	if user, _ := repository.UserByID(r.DB, prod.UserID); user == nil {
 		return nil, nil
 	}
-	var company *string
-	if prod.CompanyName.Valid {
-		company = &prod.CompanyName.String
-	}
-	producer := &model.Producer{
-		ID:          prod.ID,
-		User:        userRowToModel(user),
-		CompanyName: company,
-		Approved:    prod.Approved == 1,
-	}
+	producer := producerRowToModel(r.DB, prod)
 	eventIDs, err := repository.ListEventsByProducerIDExcludingDraft(r.DB, producerID)
 	if err != nil {
 		return &model.ProducerPublicProfile{Producer: producer, Events: []*model.Event{}}, nil
@@ -690,6 +1124,49 @@ func (r *queryResolver) ProducerPublicProfile(ctx context.Context, producerID st
 	return &model.ProducerPublicProfile{Producer: producer, Events: events}, nil
 }
 
+// Producer is the resolver for the producer field.
+func (r *queryResolver) Producer(ctx context.Context, id string, limit *int, offset *int) (*model.PublicProducer, error) {
+	lim, off := 20, 0
+	if limit != nil {
+		lim = *limit
+	}
+	if offset != nil {
+		off = *offset
+	}
+	if lim < 1 || lim > maxProducerEventsPageSize {
+		return nil, fmt.Errorf("limit deve estar entre 1 e %d", maxProducerEventsPageSize)
+	}
+	if off < 0 {
+		return nil, errors.New("offset não pode ser negativo")
+	}
+
+	prod, err := repository.ProducerByID(r.DB, id)
+	if err != nil || prod == nil {
+		return nil, err
+	}
+	rows, total, err := repository.EventsByProducer(r.DB, prod.ID, false, "PUBLISHED", lim, off)
+	if err != nil {
+		return nil, err
+	}
+	page := &model.EventPage{Items: make([]*model.Event, 0, len(rows)), TotalCount: total, Limit: lim, Offset: off}
+	for _, row := range rows {
+		ev, err := eventRowToModel(&row.EventRow, r.DB)
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, ev)
+	}
+	return &model.PublicProducer{
+		ID:           prod.ID,
+		DisplayName:  nullStringPtr(prod.DisplayName),
+		CompanyName:  nullStringPtr(prod.CompanyName),
+		Description:  nullStringPtr(prod.Description),
+		LogoURL:      nullStringPtr(prod.LogoURL),
+		ContactEmail: nullStringPtr(prod.ContactEmail),
+		Events:       page,
+	}, nil
+}
+
 // MyTickets is the resolver for the myTickets field.
 func (r *queryResolver) MyTickets(ctx context.Context) ([]*model.Ticket, error) {
 	userID := middleware.UserID(ctx)
@@ -724,6 +1201,159 @@ func (r *queryResolver) MyTicket(ctx context.Context, id string) (*model.Ticket,
 	return ticketRowToModel(r.DB, t)
 }
 
+// Order is the resolver for the order field.
+func (r *queryResolver) Order(ctx context.Context, id string, refreshPix *bool) (*model.Order, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	orderID := id
+	if byRef, _ := repository.OrderIDByRef(r.DB, strings.ToUpper(strings.TrimSpace(id))); byRef != "" {
+		orderID = byRef
+	}
+	order, err := repository.AuthorizeOrderAccess(r.DB, orderID, userID)
+	if errors.Is(err, repository.ErrOrderNotFound) || errors.Is(err, repository.ErrOrderForbidden) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return orderToModel(r.DB, r.Pagarme, order, refreshPix != nil && *refreshPix)
+}
+
+// TicketByCode is the resolver for the ticketByCode field.
+func (r *queryResolver) TicketByCode(ctx context.Context, code string) (*model.Ticket, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	prodID, _ := repository.ProducerIDByUser(r.DB, userID)
+	if prodID == "" {
+		return nil, errors.New("apenas produtores podem consultar ingressos")
+	}
+	t, err := repository.TicketByCode(r.DB, strings.ToLower(strings.TrimSpace(code)))
+	if err != nil || t == nil {
+		return nil, nil
+	}
+	eventProducerID, _ := repository.EventProducerID(r.DB, t.EventID)
+	if eventProducerID != prodID {
+		return nil, errors.New("ingresso não pertence a um evento seu")
+	}
+	return ticketRowToModel(r.DB, t)
+}
+
+// CheckinStats is the resolver for the checkinStats field.
+func (r *queryResolver) CheckinStats(ctx context.Context, eventID string) (*model.CheckinStats, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	prodID, _ := repository.ProducerIDByUser(r.DB, userID)
+	eventProducerID, _ := repository.EventProducerID(r.DB, eventID)
+	if eventProducerID == "" {
+		return nil, errors.New("evento não encontrado")
+	}
+	if prodID == "" || eventProducerID != prodID {
+		return nil, errors.New("sem permissão")
+	}
+	stats, err := repository.CheckinStats(r.DB, eventID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CheckinStats{
+		EventID:         eventID,
+		TotalScans:      stats.TotalScans,
+		UniqueTickets:   stats.UniqueTickets,
+		Duplicates:      stats.Duplicates,
+		InvalidAttempts: stats.InvalidAttempts,
+	}, nil
+}
+
+// SalesByChannel is the resolver for the salesByChannel field.
+func (r *queryResolver) SalesByChannel(ctx context.Context, eventID string) ([]*model.ChannelSales, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	prodID, _ := repository.ProducerIDByUser(r.DB, userID)
+	eventProducerID, _ := repository.EventProducerID(r.DB, eventID)
+	if eventProducerID == "" {
+		return nil, errors.New("evento não encontrado")
+	}
+	if prodID == "" || eventProducerID != prodID {
+		return nil, errors.New("sem permissão")
+	}
+	rows, err := repository.SalesByChannel(r.DB, eventID, pagarme.ChannelDirect)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*model.ChannelSales, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, &model.ChannelSales{
+			Channel:     row.Channel,
+			Orders:      row.Orders,
+			TicketsSold: row.Tickets,
+			Revenue:     row.Revenue,
+		})
+	}
+	return out, nil
+}
+
+// EventTickets is the resolver for the eventTickets field.
+func (r *queryResolver) EventTickets(ctx context.Context, eventID string, limit *int, offset *int, filter *model.TicketUsageFilter, search *string, source *model.TicketSource) (*model.TicketPage, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	prodID, _ := repository.ProducerIDByUser(r.DB, userID)
+	eventProducerID, _ := repository.EventProducerID(r.DB, eventID)
+	if eventProducerID == "" {
+		return nil, errors.New("evento não encontrado")
+	}
+	if prodID == "" || eventProducerID != prodID {
+		return nil, errors.New("sem permissão")
+	}
+
+	lim, off := 50, 0
+	if limit != nil {
+		lim = *limit
+	}
+	if offset != nil {
+		off = *offset
+	}
+	if lim < 1 || lim > maxEventTicketsPageSize {
+		return nil, fmt.Errorf("limit deve estar entre 1 e %d", maxEventTicketsPageSize)
+	}
+	if off < 0 {
+		return nil, errors.New("offset não pode ser negativo")
+	}
+	var f repository.TicketFilter
+	if filter != nil && *filter != model.TicketUsageFilterAll {
+		used := *filter == model.TicketUsageFilterUsed
+		f.Used = &used
+	}
+	if search != nil {
+		f.Search = *search
+	}
+	if source != nil {
+		f.Source = string(*source)
+	}
+
+	rows, total, err := repository.TicketsByEvent(r.DB, eventID, lim, off, f)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*model.Ticket, 0, len(rows))
+	for _, t := range rows {
+		m, err := ticketRowToModel(r.DB, t)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, m)
+	}
+	return &model.TicketPage{Items: items, TotalCount: total, Limit: lim, Offset: off}, nil
+}
+
 // Me is the resolver for the me field.
 func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
 	userID := middleware.UserID(ctx)
@@ -748,24 +1378,182 @@ func (r *queryResolver) ProducerMe(ctx context.Context) (*model.Producer, error)
 	if prod == nil {
 		return nil, nil
 	}
-	user, _ := repository.UserByID(r.DB, prod.UserID)
-	var company *string
-	if prod.CompanyName.Valid {
-		company = &prod.CompanyName.String
+	return producerRowToModel(r.DB, prod), nil
+}
+
+// ProducerPaymentStatus is the resolver for the producerPaymentStatus field.
+func (r *queryResolver) ProducerPaymentStatus(ctx context.Context) (*model.ProducerPaymentStatus, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	return paymentStatusToModel(pagarme.LookupRecipientStatus(r.Pagarme, r.DB, userID)), nil
+}
+
+// ProducerBalance is the resolver for the producerBalance field.
+func (r *queryResolver) ProducerBalance(ctx context.Context) (*model.ProducerBalance, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	res := pagarme.LookupRecipientBalance(r.Pagarme, r.DB, userID)
+	out := &model.ProducerBalance{
+		HasRecipient: res.HasRecipient,
+		Transfers:    make([]*model.PayoutTransfer, 0, len(res.Transfers)),
+	}
+	if res.RecipientID != "" {
+		out.RecipientID = strPtr(res.RecipientID)
+	}
+	if res.RecipientStatus != "" {
+		out.RecipientStatus = strPtr(res.RecipientStatus)
+	}
+	if res.Balance != nil {
+		available := int(res.Balance.AvailableAmount)
+		waiting := int(res.Balance.WaitingFundsAmount)
+		transferred := int(res.Balance.TransferredAmount)
+		out.Currency = strPtr(res.Balance.Currency)
+		out.AvailableAmount = &available
+		out.WaitingFundsAmount = &waiting
+		out.TransferredAmount = &transferred
+	}
+	for _, t := range res.Transfers {
+		tr := &model.PayoutTransfer{ID: t.ID, Amount: int(t.Amount), Status: t.Status}
+		if t.CreatedAt != "" {
+			tr.CreatedAt = strPtr(t.CreatedAt)
+		}
+		out.Transfers = append(out.Transfers, tr)
+	}
+	if res.Error != "" {
+		out.Error = strPtr(res.Error)
+	}
+	return out, nil
+}
+
+// PlatformStats is the resolver for the platformStats field.
+func (r *queryResolver) PlatformStats(ctx context.Context, from *string, to *string) (*model.PlatformStats, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	user, _ := repository.UserByID(r.DB, userID)
+	if user == nil || user.Role != string(model.UserRoleAdmin) {
+		return nil, errors.New("sem permissão")
+	}
+	start, end, err := statsPeriod(from, to, r.Config.EventTimezone(""), time.Now())
+	if err != nil {
+		return nil, err
 	}
-	return &model.Producer{
-		ID:          prod.ID,
-		User:        userRowToModel(user),
-		CompanyName: company,
-		Approved:    prod.Approved == 1,
+	firstDay, lastDay := start.Format("2006-01-02"), end.AddDate(0, 0, -1).Format("2006-01-02")
+	stats, err := repository.PlatformStats(r.DB, start, end, r.Config.PagarmeAppFee)
+	if err != nil {
+		return nil, err
+	}
+	byStatus := make([]*model.OrderStatusCount, 0, len(stats.OrdersByStatus))
+	for _, c := range stats.OrdersByStatus {
+		byStatus = append(byStatus, &model.OrderStatusCount{Status: c.Status, Orders: c.Orders})
+	}
+	return &model.PlatformStats{
+		From:            firstDay,
+		To:              lastDay,
+		Gmv:             money.ToReais(stats.GMV),
+		PlatformFees:    money.ToReais(stats.PlatformFees),
+		PaidOrders:      stats.PaidOrders,
+		TicketsSold:     stats.TicketsSold,
+		ActiveProducers: stats.ActiveProducers,
+		PublishedEvents: stats.PublishedEvents,
+		OrdersByStatus:  byStatus,
 	}, nil
 }
 
+// TicketTransfers is the resolver for the ticketTransfers field.
+func (r *queryResolver) TicketTransfers(ctx context.Context, ticketID string) ([]*model.TicketTransfer, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	t, _ := repository.TicketByID(r.DB, ticketID)
+	if t == nil {
+		return nil, errors.New("ingresso não encontrado")
+	}
+	if t.UserID != userID {
+		prodID, _ := repository.ProducerIDByUser(r.DB, userID)
+		eventProducerID, _ := repository.EventProducerID(r.DB, t.EventID)
+		user, _ := repository.UserByID(r.DB, userID)
+		isAdmin := user != nil && user.Role == string(model.UserRoleAdmin)
+		if !isAdmin && (prodID == "" || prodID != eventProducerID) {
+			return nil, errors.New("ingresso não encontrado")
+		}
+	}
+	transfers, err := repository.TicketTransfers(r.DB, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*model.TicketTransfer, 0, len(transfers))
+	for _, tr := range transfers {
+		from, _ := repository.UserByID(r.DB, tr.FromUserID)
+		to, _ := repository.UserByID(r.DB, tr.ToUserID)
+		out = append(out, &model.TicketTransfer{
+			From:      publicUserRowToModel(from),
+			To:        publicUserRowToModel(to),
+			CreatedAt: parseDateTimeToRFC3339(tr.CreatedAt),
+		})
+	}
+	return out, nil
+}
+
+// MyNotifications is the resolver for the myNotifications field.
+func (r *queryResolver) MyNotifications(ctx context.Context, unreadOnly *bool, limit *int) ([]*model.Notification, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	lim := 50
+	if limit != nil {
+		lim = *limit
+	}
+	if lim < 1 || lim > maxNotificationsPageSize {
+		return nil, fmt.Errorf("limit deve estar entre 1 e %d", maxNotificationsPageSize)
+	}
+	rows, err := repository.NotificationsByUser(r.DB, userID, unreadOnly != nil && *unreadOnly, lim)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*model.Notification, 0, len(rows))
+	for _, n := range rows {
+		out = append(out, notificationRowToModel(n))
+	}
+	return out, nil
+}
+
+// MyAddresses is the resolver for the myAddresses field.
+func (r *queryResolver) MyAddresses(ctx context.Context) ([]*model.UserAddress, error) {
+	userID := middleware.UserID(ctx)
+	if userID == "" {
+		return nil, errors.New("não autenticado")
+	}
+	if !r.Config.Feature(config.FeatureAddressBook) {
+		return nil, rest.ErrFeatureDisabled
+	}
+	rows, err := repository.UserAddresses(r.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*model.UserAddress, 0, len(rows))
+	for _, a := range rows {
+		out = append(out, userAddressToModel(a))
+	}
+	return out, nil
+}
+
+// Event returns EventResolver implementation.
+func (r *Resolver) Event() EventResolver { return &eventResolver{r} }
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+type eventResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
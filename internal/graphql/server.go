@@ -6,7 +6,10 @@ import (
 	"io/fs"
 	"net/http"
 
+	"afterzin/api/internal/checkin"
 	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/pagarme"
 	"github.com/99designs/gqlgen/graphql/handler"
 	gqlparser "github.com/vektah/gqlparser/v2"
 	"github.com/vektah/gqlparser/v2/ast"
@@ -15,15 +18,16 @@ import (
 //go:embed schema/*.graphqls
 var schemaFS embed.FS
 
-func NewHandler(db *sql.DB, cfg *config.Config) http.Handler {
+func NewHandler(db *sql.DB, cfg *config.Config, pagarmeClient pagarme.PagarmeAPI, checkins *checkin.Recorder, mail mailer.Mailer) http.Handler {
 	schema, err := loadSchema()
 	if err != nil {
 		panic("load schema: " + err.Error())
 	}
-	resolver := &Resolver{DB: db, Config: cfg}
+	resolver := &Resolver{DB: db, Config: cfg, Pagarme: pagarmeClient, Checkins: checkins, Mailer: mail}
 	es := NewExecutableSchema(Config{
-		Schema:    schema,
-		Resolvers: resolver,
+		Schema:     schema,
+		Resolvers:  resolver,
+		Directives: DirectiveRoot{Restricted: resolver.restricted},
 	})
 	return handler.NewDefaultServer(es)
 }
@@ -0,0 +1,39 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"afterzin/api/internal/eventtime"
+)
+
+// defaultStatsDays is the period of platformStats when from is not given.
+const defaultStatsDays = 30
+
+// statsPeriod resolves the from/to days (YYYY-MM-DD, inclusive) of
+// platformStats into the instants [start, end) in the named zone. Missing
+// days default to today and defaultStatsDays before it.
+func statsPeriod(from, to *string, timezone string, now time.Time) (start, end time.Time, err error) {
+	loc, err := eventtime.Load(timezone)
+	if err != nil {
+		return start, end, err
+	}
+	now = now.In(loc)
+	lastDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if to != nil && *to != "" {
+		if lastDay, err = time.ParseInLocation("2006-01-02", *to, loc); err != nil {
+			return start, end, fmt.Errorf("data final inválida: %s", *to)
+		}
+	}
+	start = lastDay.AddDate(0, 0, 1-defaultStatsDays)
+	if from != nil && *from != "" {
+		if start, err = time.ParseInLocation("2006-01-02", *from, loc); err != nil {
+			return start, end, fmt.Errorf("data inicial inválida: %s", *from)
+		}
+	}
+	if start.After(lastDay) {
+		return start, end, errors.New("data inicial posterior à data final")
+	}
+	return start, lastDay.AddDate(0, 0, 1), nil
+}
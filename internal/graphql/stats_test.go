@@ -0,0 +1,35 @@
+package graphql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsPeriod(t *testing.T) {
+	// 02:00 UTC on March 10 is still March 9 in São Paulo (UTC-3).
+	now := time.Date(2026, 3, 10, 2, 0, 0, 0, time.UTC)
+	start, end, err := statsPeriod(nil, nil, "America/Sao_Paulo", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := start.UTC().Format(time.RFC3339); got != "2026-02-08T03:00:00Z" {
+		t.Errorf("default start = %s, want 2026-02-08T03:00:00Z", got)
+	}
+	if got := end.UTC().Format(time.RFC3339); got != "2026-03-10T03:00:00Z" {
+		t.Errorf("default end = %s, want 2026-03-10T03:00:00Z", got)
+	}
+
+	from, to := "2026-01-01", "2026-01-31"
+	start, end, err = statsPeriod(&from, &to, "America/Sao_Paulo", now)
+	if err != nil || start.Format("2006-01-02") != from || end.Format("2006-01-02") != "2026-02-01" {
+		t.Errorf("period = %s..%s (%v), want 2026-01-01..2026-02-01", start, end, err)
+	}
+
+	if _, _, err := statsPeriod(&to, &from, "America/Sao_Paulo", now); err == nil {
+		t.Error("from after to accepted")
+	}
+	bad := "01/02/2026"
+	if _, _, err := statsPeriod(&bad, nil, "America/Sao_Paulo", now); err == nil {
+		t.Error("malformed from accepted")
+	}
+}
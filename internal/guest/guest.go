@@ -0,0 +1,129 @@
+// Package guest serves the endpoints that turn a GUEST account, created by a
+// purchase without signup or a complimentary ticket, into a regular account
+// with a password.
+package guest
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"afterzin/api/internal/auth"
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/outbox"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+	"afterzin/api/internal/validate"
+)
+
+// claimTokenTTL é a validade do link enviado por email para reivindicar a conta
+const claimTokenTTL = 24 * time.Hour
+
+// Handler provides the HTTP handlers for guest account endpoints.
+type Handler struct {
+	db     *sql.DB
+	cfg    *config.Config
+	mailer mailer.Mailer
+}
+
+// NewHandler creates a guest account HTTP handler.
+func NewHandler(db *sql.DB, cfg *config.Config, mail mailer.Mailer) *Handler {
+	return &Handler{db: db, cfg: cfg, mailer: mail}
+}
+
+// Claim handles POST /v1/guest/claim
+// Emails a single-use link that lets a guest set a password and keep their tickets.
+// Always answers 200 so the endpoint can't be used to probe registered emails.
+func (h *Handler) Claim(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if !rest.Decode(w, r, &req) {
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "se houver uma compra como convidado para este email, enviaremos um link para criar sua senha",
+	}
+
+	user, _ := repository.UserByEmail(h.db, validate.NormalizeEmail(req.Email))
+	if user == nil || user.Role != "GUEST" {
+		rest.JSON(w, http.StatusOK, response)
+		return
+	}
+
+	token, err := auth.NewToken(user.ID, auth.RoleGuestClaim, h.cfg.JWTSecret, claimTokenTTL)
+	if err != nil {
+		logger.Errorf("erro ao gerar token de reivindicação: %v", err)
+		rest.JSON(w, http.StatusOK, response)
+		return
+	}
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Crie sua senha na Afterzin",
+		Body: fmt.Sprintf("Olá, %s!\n\nPara acessar seus ingressos com uma senha, use o link abaixo (válido por 24 horas):\n\n%s/claim?token=%s\n",
+			user.Name, h.cfg.BaseURL, token),
+	}
+	if err := outbox.QueueEmail(h.db, h.mailer, msg); err != nil {
+		logger.Errorf("erro ao enfileirar email de reivindicação: %v", err)
+	}
+	rest.JSON(w, http.StatusOK, response)
+}
+
+// ConfirmClaim handles POST /v1/guest/claim/confirm
+// Sets the password for a guest account using the emailed claim token.
+func (h *Handler) ConfirmClaim(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if !rest.DecodeStrict(w, r, &req, h.cfg.StrictJSON) {
+		return
+	}
+	if err := auth.ValidatePassword(req.Password); err != nil {
+		rest.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, role, err := auth.ParseToken(req.Token, h.cfg.JWTSecret)
+	if err != nil || role != auth.RoleGuestClaim || userID == "" {
+		rest.Error(w, http.StatusBadRequest, "link inválido ou expirado")
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		rest.Error(w, http.StatusInternalServerError, "erro ao definir senha")
+		return
+	}
+	claimed, err := repository.ClaimGuestUser(h.db, userID, hash)
+	if err != nil {
+		logger.Errorf("erro ao reivindicar conta %s: %v", userID, err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao definir senha")
+		return
+	}
+	if !claimed {
+		rest.Error(w, http.StatusBadRequest, "conta já reivindicada")
+		return
+	}
+
+	token, _ := auth.NewToken(userID, "USER", h.cfg.JWTSecret, auth.AccessTokenTTL)
+	logger.Infof("conta de convidado reivindicada: id=%s", userID)
+	rest.JSON(w, http.StatusOK, map[string]interface{}{
+		"token":   token,
+		"message": "conta criada com sucesso",
+	})
+}
@@ -0,0 +1,74 @@
+package guest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/auth"
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+// captureMailer records every message instead of sending it.
+type captureMailer struct {
+	sent []mailer.Message
+}
+
+func (m *captureMailer) Send(msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestClaimGuestAccount(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	testutil.Seed(t, sqlite, 10, 50)
+	guestID, err := repository.CreateGuestUser(sqlite, "Convidado", "convidado@email.com", "16899535009", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mail := &captureMailer{}
+	h := NewHandler(sqlite, &config.Config{JWTSecret: "test-secret", BaseURL: "http://localhost"}, mail)
+
+	claim := func(email string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.Claim(rec, httptest.NewRequest(http.MethodPost, "/v1/guest/claim", strings.NewReader(`{"email":"`+email+`"}`)))
+		return rec
+	}
+	// Registered and unknown emails get the same answer and no email.
+	for _, email := range []string{testutil.BuyerEmail, "ninguem@email.com"} {
+		if rec := claim(email); rec.Code != http.StatusOK {
+			t.Errorf("claim %s: status = %d, want 200", email, rec.Code)
+		}
+	}
+	if len(mail.sent) != 0 {
+		t.Fatalf("claim emails for non-guests = %d, want 0", len(mail.sent))
+	}
+
+	// Claim: token is emailed, then exchanged for a password.
+	claim(" Convidado@Email.com ")
+	if len(mail.sent) != 1 {
+		t.Fatalf("claim emails = %d, want 1", len(mail.sent))
+	}
+	token := mail.sent[0].Body[strings.Index(mail.sent[0].Body, "token=")+len("token="):]
+	token = strings.TrimSpace(token)
+
+	confirm := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ConfirmClaim(rec, httptest.NewRequest(http.MethodPost, "/v1/guest/claim/confirm", strings.NewReader(`{"token":"`+token+`","password":"segredo123"}`)))
+		return rec
+	}
+	if rec := confirm(); rec.Code != http.StatusOK {
+		t.Fatalf("confirm claim: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	claimed, _ := repository.UserByID(sqlite, guestID)
+	if claimed.Role != "USER" || !auth.CheckPassword(claimed.PasswordHash, "segredo123") {
+		t.Errorf("guest not promoted: role=%s", claimed.Role)
+	}
+	if rec := confirm(); rec.Code != http.StatusBadRequest {
+		t.Errorf("second confirm: status = %d, want 400", rec.Code)
+	}
+}
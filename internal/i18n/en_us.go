@@ -0,0 +1,166 @@
+package i18n
+
+// enUS holds the en-US translations of the messages returned by the REST
+// handlers, keyed by the pt-BR text. Keep keys byte-for-byte identical to
+// the source strings.
+var enUS = map[string]string{
+	// Generic request errors
+	"erro interno do servidor":            "internal server error",
+	"method not allowed":                  "method not allowed",
+	"não autenticado":                     "not authenticated",
+	"sem permissão":                       "forbidden",
+	"corpo inválido":                      "invalid body",
+	"campo desconhecido":                  "unknown field",
+	"erro ao ler corpo":                   "error reading body",
+	"corpo da requisição muito grande":    "request body too large",
+	"tipo de conteúdo não aceito":         "content type not acceptable",
+	"informe orderId ou items, não ambos": "send either orderId or items, not both",
+	"erro ao validar":                     "validation error",
+	"id é obrigatório":                    "id is required",
+	"orderId é obrigatório":               "orderId is required",
+	"eventId é obrigatório":               "eventId is required",
+	"ticketId é obrigatório":              "ticketId is required",
+	"qrCode é obrigatório":                "qrCode is required",
+	"limit inválido":                      "invalid limit",
+	"days inválido":                       "invalid days",
+	"offset inválido":                     "invalid offset",
+
+	// Users and accounts
+	"usuário não encontrado":                          "user not found",
+	"conflito de conta":                               "account conflict",
+	"conta já reivindicada":                           "account already claimed",
+	"email já cadastrado, faça login para continuar":  "email already registered, please log in to continue",
+	"CPF já vinculado a outra conta":                  "CPF already linked to another account",
+	"email já vinculado a outro CPF":                  "email already linked to another CPF",
+	"CPF é obrigatório para quem ainda não tem conta": "CPF is required for people without an account",
+	"erro ao definir senha":                           "error setting password",
+	"senha muito longa":                               "password too long",
+	"token inválido":                                  "invalid token",
+	"nome inválido":                                   "invalid name",
+	"nome é obrigatório":                              "name is required",
+	"CPF inválido":                                    "invalid CPF",
+	"email inválido":                                  "invalid email",
+	"email é obrigatório":                             "email is required",
+	"formato de email inválido":                       "invalid email format",
+	"domínio do email inválido":                       "invalid email domain",
+	"telefone inválido":                               "invalid phone",
+	"country code é obrigatório":                      "country code is required",
+	"country code inválido":                           "invalid country code",
+	"area code é obrigatório":                         "area code is required",
+	"DDD deve ter 2 dígitos":                          "area code must have 2 digits",
+	"DDD inválido: deve estar entre 11 e 99":          "invalid area code: must be between 11 and 99",
+	"número de telefone muito curto":                  "phone number too short",
+
+	// Producers and recipients
+	"perfil de produtor não encontrado":                             "producer profile not found",
+	"erro ao criar perfil de produtor":                              "error creating producer profile",
+	"documento, banco, agência e conta são obrigatórios":            "document, bank, branch and account are required",
+	"erro ao consultar recebedor existente; tente novamente":        "error looking up existing recipient; please try again",
+	"tipo de conta inválido: use checking ou savings":               "invalid account type: use checking or savings",
+	"o documento do titular da conta deve ser o mesmo do recebedor": "the account holder's document must match the recipient's",
+	"erro ao salvar recebedor":                                      "error saving recipient",
+	"funcionalidade indisponível":                                   "feature unavailable",
+	"compra sem conta indisponível; entre na sua conta":             "checkout without an account is unavailable; please sign in",
+	"erro ao conciliar repasses":                                    "error reconciling payouts",
+	"erro ao remover recebedor":                                     "error removing recipient",
+	"nenhum recebedor configurado":                                  "no recipient configured",
+	"produtor não configurou recebimento de pagamentos":             "producer has not set up payment receiving",
+	"não foi possível verificar status com Pagar.me":                "could not check status with Pagar.me",
+	"pagamentos não configurados":                                   "payments not configured",
+
+	// Orders and payments
+	"pedido não encontrado":                                   "order not found",
+	"pedido não está em processamento":                        "order is not being processed",
+	"pedido não pode ser confirmado no status atual":          "order cannot be confirmed in its current status",
+	"pedido não pode mais ser alterado":                       "order can no longer be changed",
+	"pedido já tem pagamento gerado":                          "order already has a payment",
+	"quantidade insuficiente no lote":                         "not enough places left in the batch",
+	"pedido não pertence ao usuário":                          "order does not belong to the user",
+	"pedido já processado":                                    "order already processed",
+	"pedido não está pago":                                    "order is not paid",
+	"pedido sem itens":                                        "order has no items",
+	"pedido sem ingressos emitidos":                           "order has no issued tickets",
+	"erro ao buscar pedido":                                   "error fetching order",
+	"nenhum item":                                             "no items",
+	"quantidade indisponível":                                 "quantity unavailable",
+	"tipo de ingresso não encontrado":                         "ticket type not found",
+	"tipo de ingresso não pertence a esta data":               "ticket type does not belong to this date",
+	"data não encontrada":                                     "date not found",
+	"data do evento não encontrada":                           "event date not found",
+	"evento não encontrado":                                   "event not found",
+	"a data do evento já passou":                              "the event date has passed",
+	"data do evento inválida":                                 "invalid event date",
+	"data inicial inválida":                                   "invalid start date",
+	"data final inválida":                                     "invalid end date",
+	"data inicial posterior à data final":                     "start date is after the end date",
+	"horário do evento inválido":                              "invalid event start time",
+	"fuso horário inválido":                                   "invalid time zone",
+	"valor total deve ser maior que zero":                     "total amount must be greater than zero",
+	"valor do pedido excede o limite permitido":               "order amount exceeds the allowed limit",
+	"valor do pedido abaixo do mínimo para pagamento via PIX": "order amount is below the PIX payment minimum",
+	"limite de reenvios atingido; tente novamente mais tarde": "resend limit reached; please try again later",
+	"erro ao reenviar ingressos":                              "error resending tickets",
+	"erro ao enviar email":                                    "error sending email",
+	"erro ao processar webhook":                               "error processing webhook",
+
+	// Tickets, check-in and exports
+	"ingresso não encontrado":                     "ticket not found",
+	"ingresso não pertence ao usuário":            "ticket does not belong to the user",
+	"dados do ingresso incompletos":               "incomplete ticket data",
+	"erro ao gerar PDF":                           "error generating PDF",
+	"apenas produtores podem validar ingressos":   "only producers can validate tickets",
+	"nenhuma leitura enviada":                     "no scans sent",
+	"lote excede o limite de leituras":            "batch exceeds the scan limit",
+	"formato inválido: use csv ou json":           "invalid format: use csv or json",
+	"scope inválido: use attendees ou ticket_pdf": "invalid scope: use attendees or ticket_pdf",
+	"link inválido ou expirado":                   "invalid or expired link",
+	"link de download inválido":                   "invalid download link",
+	"link de download expirado":                   "download link expired",
+	"limite de cortesias do evento atingido":      "event complimentary ticket limit reached",
+	"nenhum convidado informado":                  "no guests given",
+
+	// Admin
+	"erro ao verificar inventário":      "error checking inventory",
+	"erro ao listar auditoria":          "error listing audit log",
+	"erro ao contar eventos do webhook": "error counting webhook events",
+	"erro ao listar confirmações":       "error listing confirmations",
+	"erro ao registrar auditoria":       "error recording audit entry",
+
+	// Denylist
+	"não foi possível concluir a operação; entre em contato com o suporte": "the operation could not be completed; please contact support",
+	"erro ao validar cadastro":        "error validating account",
+	"erro ao listar bloqueios":        "error listing blocks",
+	"erro ao bloquear":                "error blocking",
+	"erro ao desbloquear":             "error unblocking",
+	"bloqueio não encontrado":         "block not found",
+	"kind inválido: use CPF ou EMAIL": "invalid kind: use CPF or EMAIL",
+
+	// Ticket transfers
+	"o produtor não permite transferir ingressos deste evento": "the producer does not allow transferring tickets for this event",
+	"limite de transferências do ingresso atingido":            "ticket transfer limit reached",
+	"ingresso não pode mais ser transferido":                   "ticket can no longer be transferred",
+	"cortesias não podem ser transferidas":                     "complimentary tickets cannot be transferred",
+	"destinatário não encontrado":                              "recipient not found",
+	"o e-mail precisa ter uma conta":                           "the email must belong to an account",
+	"ingresso já utilizado":                                    "ticket already used",
+	"o ingresso já é seu":                                      "the ticket is already yours",
+	"máximo de transferências não pode ser negativo":           "maximum transfers cannot be negative",
+
+	// Customer address
+	"rua é obrigatória":    "street is required",
+	"número é obrigatório": "street number is required",
+	"bairro é obrigatório": "neighborhood is required",
+	"cidade é obrigatória": "city is required",
+	"estado inválido":      "invalid state",
+	"use a sigla da UF":    "use the two-letter state code",
+	"CEP inválido":         "invalid ZIP code",
+	"deve ter 8 dígitos":   "must have 8 digits",
+	"endereço muito longo": "address too long",
+	"endereço é obrigatório para este meio de pagamento": "address is required for this payment method",
+	"endereço inválido":            "invalid address",
+	"endereço não encontrado":      "address not found",
+	"limite de endereços atingido": "address limit reached",
+
+	// Outbox
+	"erro ao consultar outbox": "error querying the outbox",
+}
@@ -0,0 +1,89 @@
+// Package i18n translates user-facing messages. Messages are written in
+// pt-BR throughout the code base and double as catalog keys, so a message
+// missing from a catalog is simply shown in Portuguese.
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported languages (BCP 47 tags).
+const (
+	PtBR = "pt-BR"
+	EnUS = "en-US"
+
+	Default = PtBR
+)
+
+// catalogs maps a language to its translations, keyed by the pt-BR message.
+var catalogs = map[string]map[string]string{
+	EnUS: enUS,
+}
+
+// T translates a pt-BR message into lang. Wrapped errors ("nome inválido:
+// muito curto") are translated segment by segment, leaving unknown segments
+// as they are. Unsupported languages get the message unchanged.
+func T(lang, msg string) string {
+	catalog := catalogs[lang]
+	if catalog == nil {
+		return msg
+	}
+	if t, ok := catalog[msg]; ok {
+		return t
+	}
+	parts := strings.Split(msg, ": ")
+	if len(parts) == 1 {
+		return msg
+	}
+	for i, p := range parts {
+		if t, ok := catalog[p]; ok {
+			parts[i] = t
+		}
+	}
+	return strings.Join(parts, ": ")
+}
+
+// Negotiate picks the supported language that best matches an
+// Accept-Language header ("en-US,en;q=0.9,pt;q=0.8"), honouring q-values.
+// Any English or Portuguese variant maps to en-US or pt-BR; an empty or
+// unmatched header yields Default.
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var cands []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if lang := supported(tag); lang != "" && q > 0 {
+			cands = append(cands, candidate{lang, q})
+		}
+	}
+	if len(cands) == 0 {
+		return Default
+	}
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].q > cands[j].q })
+	return cands[0].lang
+}
+
+// supported maps a language tag to the supported language it falls under.
+func supported(tag string) string {
+	primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	switch primary {
+	case "pt":
+		return PtBR
+	case "en":
+		return EnUS
+	}
+	return ""
+}
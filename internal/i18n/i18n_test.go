@@ -0,0 +1,49 @@
+package i18n
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                            PtBR,
+		"*":                           PtBR,
+		"fr-FR":                       PtBR,
+		"en":                          EnUS,
+		"en-GB":                       EnUS,
+		"EN-us":                       EnUS,
+		"pt-PT":                       PtBR,
+		"en-US,en;q=0.9,pt;q=0.8":     EnUS,
+		"pt-BR,pt;q=0.9,en;q=0.8":     PtBR,
+		"fr;q=1, en;q=0.5, pt;q=0.7":  PtBR,
+		"fr, de;q=0.9, en;q=0.1":      EnUS,
+		"en;q=0, pt;q=0.2":            PtBR,
+		"en;q=abc":                    PtBR,
+		" en-US ; q=0.8 , pt ; q=0.5": EnUS,
+	}
+	for header, want := range cases {
+		if got := Negotiate(header); got != want {
+			t.Errorf("Negotiate(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T(EnUS, "pedido não encontrado"); got != "order not found" {
+		t.Errorf("exact = %q", got)
+	}
+	if got := T(PtBR, "pedido não encontrado"); got != "pedido não encontrado" {
+		t.Errorf("pt-BR = %q", got)
+	}
+	if got := T("", "pedido não encontrado"); got != "pedido não encontrado" {
+		t.Errorf("no language = %q", got)
+	}
+	if got := T(EnUS, "mensagem sem tradução"); got != "mensagem sem tradução" {
+		t.Errorf("missing = %q", got)
+	}
+	// Wrapped errors are translated segment by segment.
+	if got := T(EnUS, "nome inválido: nome é obrigatório"); got != "invalid name: name is required" {
+		t.Errorf("wrapped = %q", got)
+	}
+	if got := T(EnUS, "email inválido: detalhe qualquer"); got != "invalid email: detalhe qualquer" {
+		t.Errorf("partially known = %q", got)
+	}
+}
@@ -0,0 +1,120 @@
+// Package jobs runs named periodic background jobs.
+package jobs
+
+import (
+	"context"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"afterzin/api/internal/logger"
+)
+
+// jitterFraction is the width, relative to the interval, of the random window
+// each run falls in, so jobs registered together (and replicas started
+// together) don't fire in lockstep.
+const jitterFraction = 0.1
+
+// Func is the body of a job. It should return promptly when ctx is cancelled.
+type Func func(ctx context.Context) error
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      Func
+}
+
+// Scheduler runs registered jobs at their interval until its context is cancelled.
+type Scheduler struct {
+	disabled map[string]bool
+	jobs     []job
+	wg       sync.WaitGroup
+}
+
+// New creates a scheduler. Jobs whose names are in disabled are skipped when registered.
+func New(disabled []string) *Scheduler {
+	s := &Scheduler{disabled: map[string]bool{}}
+	for _, name := range disabled {
+		s.disabled[name] = true
+	}
+	return s
+}
+
+// Register adds a job. Must be called before Start.
+func (s *Scheduler) Register(name string, interval time.Duration, fn Func) {
+	if s.disabled[name] {
+		logger.Infof("job %s desabilitado pela configuração", name)
+		return
+	}
+	if interval <= 0 {
+		logger.Warnf("job %s ignorado: intervalo inválido (%s)", name, interval)
+		return
+	}
+	s.jobs = append(s.jobs, job{name: name, interval: interval, run: fn})
+}
+
+// Start launches one goroutine per registered job. Each job first runs after
+// a short random delay, then about every interval until ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, j)
+	}
+	if len(s.jobs) > 0 {
+		logger.Infof("agendador iniciado com %d job(s)", len(s.jobs))
+	}
+}
+
+// Wait blocks until every job goroutine has returned after ctx cancellation,
+// including runs that were in progress.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, j job) {
+	defer s.wg.Done()
+	timer := time.NewTimer(jitter(j.interval))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			s.runOnce(ctx, j)
+			timer.Reset(nextDelay(j.interval))
+		}
+	}
+}
+
+// runOnce executes a job, logging its duration at debug level and failures
+// as errors. A panicking job is logged and retried on its next tick instead
+// of taking the process down.
+func (s *Scheduler) runOnce(ctx context.Context, j job) {
+	start := time.Now()
+	logger.Debugf("job %s iniciado", j.name)
+	defer func() {
+		if rec := recover(); rec != nil {
+			logger.Errorf("job %s entrou em pânico após %s: %v\n%s", j.name, time.Since(start), rec, debug.Stack())
+		}
+	}()
+	if err := j.run(ctx); err != nil {
+		logger.Errorf("job %s falhou após %s: %v", j.name, time.Since(start), err)
+		return
+	}
+	logger.Debugf("job %s concluído em %s", j.name, time.Since(start))
+}
+
+// jitter returns a random duration in [0, interval*jitterFraction).
+func jitter(interval time.Duration) time.Duration {
+	window := int64(float64(interval) * jitterFraction)
+	if window <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(window))
+}
+
+// nextDelay returns interval shifted randomly by up to half the jitter window either way.
+func nextDelay(interval time.Duration) time.Duration {
+	return interval - time.Duration(float64(interval)*jitterFraction/2) + jitter(interval)
+}
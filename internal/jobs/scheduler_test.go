@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRunsJobsAndRecoversFromPanics(t *testing.T) {
+	var runs, panics, failures, disabled atomic.Int32
+	s := New([]string{"desligado"})
+	s.Register("contador", 10*time.Millisecond, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+	s.Register("panico", 10*time.Millisecond, func(ctx context.Context) error {
+		panics.Add(1)
+		panic("boom")
+	})
+	s.Register("falha", 10*time.Millisecond, func(ctx context.Context) error {
+		failures.Add(1)
+		return errors.New("falhou")
+	})
+	s.Register("desligado", 10*time.Millisecond, func(ctx context.Context) error {
+		disabled.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	time.Sleep(80 * time.Millisecond)
+	cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop after cancel")
+	}
+
+	if runs.Load() < 2 {
+		t.Errorf("runs = %d, want at least 2", runs.Load())
+	}
+	// A panicking job keeps being scheduled
+	if panics.Load() < 2 {
+		t.Errorf("panicking job runs = %d, want at least 2", panics.Load())
+	}
+	if failures.Load() < 2 {
+		t.Errorf("failing job runs = %d, want at least 2", failures.Load())
+	}
+	if disabled.Load() != 0 {
+		t.Errorf("disabled job ran %d times", disabled.Load())
+	}
+
+	after := runs.Load()
+	time.Sleep(30 * time.Millisecond)
+	if runs.Load() != after {
+		t.Errorf("job ran after Wait returned")
+	}
+}
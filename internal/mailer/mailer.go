@@ -0,0 +1,77 @@
+// Package mailer sends transactional emails (ticket confirmations, etc.).
+// Uses net/smtp when SMTP_HOST is configured; otherwise emails are only logged.
+package mailer
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/logger"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New returns an SMTP mailer when SMTP_HOST is set, or a LogMailer otherwise.
+func New(cfg *config.Config) Mailer {
+	if cfg.SMTPHost == "" {
+		return LogMailer{}
+	}
+	return &SMTPMailer{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.MailFrom,
+	}
+}
+
+// SMTPMailer sends email through an SMTP server with PLAIN auth.
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers msg via SMTP.
+func (m *SMTPMailer) Send(msg Message) error {
+	addr := fmt.Sprintf("%s:%d", m.Host, m.Port)
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	var b strings.Builder
+	b.WriteString("From: " + m.From + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	if err := smtp.SendMail(addr, auth, m.From, []string{msg.To}, []byte(b.String())); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return nil
+}
+
+// LogMailer only logs messages. Used in development when SMTP is not configured.
+type LogMailer struct{}
+
+// Send logs the message recipient and subject.
+func (LogMailer) Send(msg Message) error {
+	logger.Infof("email (não enviado, SMTP não configurado): para=%s assunto=%q", msg.To, msg.Subject)
+	return nil
+}
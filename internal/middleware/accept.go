@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"afterzin/api/internal/i18n"
+)
+
+// NotAcceptableMessage is the error of the 406 response; the types the
+// route can produce are appended ("...: use application/json").
+const NotAcceptableMessage = "tipo de conteúdo não aceito"
+
+// NegotiateType picks, among the media types a handler can produce (in order
+// of preference), the one that best matches an Accept header
+// ("text/csv;q=0.9, application/*"), honouring q-values and wildcards. The
+// most specific range decides an offer's q-value; ties go to the earlier
+// offer. An empty or unparseable header accepts the first offer. It returns
+// "" when the client accepts none of them.
+func NegotiateType(accept string, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	type mediaRange struct {
+		typ, sub string
+		q        float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		typ, sub, ok := strings.Cut(strings.ToLower(strings.TrimSpace(mt)), "/")
+		if !ok || typ == "" || sub == "" || (typ == "*" && sub != "*") {
+			continue
+		}
+		q := 1.0
+		valid := true
+		for _, p := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(p), "q="); ok {
+				parsed, err := strconv.ParseFloat(v, 64)
+				if err != nil || parsed < 0 || parsed > 1 {
+					valid = false
+				}
+				q = parsed
+			}
+		}
+		if valid {
+			ranges = append(ranges, mediaRange{typ, sub, q})
+		}
+	}
+	if len(ranges) == 0 {
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		typ, sub, _ := strings.Cut(offer, "/")
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			s := -1
+			switch {
+			case r.typ == typ && r.sub == sub:
+				s = 2
+			case r.typ == typ && r.sub == "*":
+				s = 1
+			case r.typ == "*":
+				s = 0
+			}
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// Produces answers 406 to requests whose Accept header rules out every type
+// the routes can produce: offers by default (e.g. application/json), or
+// perPath[r.URL.Path] for routes that produce something else. A nil entry
+// skips the check for routes that negotiate on their own (e.g. exports
+// choosing between CSV and JSON) or whose clients aren't browsers (/graphql,
+// webhooks).
+func Produces(offers []string, perPath map[string][]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			types := offers
+			if v, ok := perPath[r.URL.Path]; ok {
+				types = v
+			}
+			if len(types) > 0 && NegotiateType(r.Header.Get("Accept"), types...) == "" {
+				RespondNotAcceptable(w, types...)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RespondNotAcceptable writes the 406 {"error": ...} response listing the
+// types that can be produced. The body is JSON even if the client ruled it
+// out, as RFC 9110 allows.
+func RespondNotAcceptable(w http.ResponseWriter, offers ...string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotAcceptable)
+	msg := NotAcceptableMessage + ": use " + strings.Join(offers, ", ")
+	json.NewEncoder(w).Encode(map[string]string{"error": i18n.T(w.Header().Get("Content-Language"), msg)})
+}
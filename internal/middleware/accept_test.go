@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateType(t *testing.T) {
+	offers := []string{"text/csv", "application/json"}
+	for accept, want := range map[string]string{
+		"":                                     "text/csv",
+		"*/*":                                  "text/csv",
+		"application/json":                     "application/json",
+		"Application/JSON; charset=utf-8":      "application/json",
+		"application/*":                        "application/json",
+		"text/csv;q=0.4, application/json":     "application/json",
+		"text/csv;q=0, */*":                    "application/json",
+		"application/json;q=0.5, text/*;q=0.5": "text/csv",
+		"application/pdf":                      "",
+		"*/*;q=0":                              "",
+		"garbage":                              "text/csv",
+	} {
+		if got := NegotiateType(accept, offers...); got != want {
+			t.Errorf("NegotiateType(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
+
+func TestProduces(t *testing.T) {
+	h := Language(Produces([]string{"application/json"}, map[string][]string{
+		"/pdf":     {"application/pdf"},
+		"/graphql": nil,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	for _, tc := range []struct {
+		path, accept string
+		status       int
+	}{
+		{"/v1/payment/status", "", http.StatusOK},
+		{"/v1/payment/status", "text/html,application/xhtml+xml,*/*;q=0.8", http.StatusOK},
+		{"/v1/payment/status", "text/html", http.StatusNotAcceptable},
+		{"/pdf", "application/pdf", http.StatusOK},
+		{"/pdf", "application/json", http.StatusNotAcceptable},
+		{"/graphql", "text/event-stream", http.StatusOK},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("%s Accept %q: status = %d, want %d", tc.path, tc.accept, rec.Code, tc.status)
+		}
+	}
+}
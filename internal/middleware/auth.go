@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"strings"
 
+	"afterzin/api/internal/auth"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -16,16 +18,16 @@ const UserRoleKey contextKey = "user_role"
 func Auth(jwtSecret string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			auth := r.Header.Get("Authorization")
-			if auth == "" {
+			header := r.Header.Get("Authorization")
+			if header == "" {
 				next.ServeHTTP(w, r)
 				return
 			}
-			if !strings.HasPrefix(auth, "Bearer ") {
+			if !strings.HasPrefix(header, "Bearer ") {
 				next.ServeHTTP(w, r)
 				return
 			}
-			tokenStr := strings.TrimPrefix(auth, "Bearer ")
+			tokenStr := strings.TrimPrefix(header, "Bearer ")
 			token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 				return []byte(jwtSecret), nil
 			})
@@ -40,6 +42,12 @@ func Auth(jwtSecret string) func(http.Handler) http.Handler {
 			}
 			sub, _ := claims["sub"].(string)
 			role, _ := claims["role"].(string)
+			if role == auth.RoleGuestClaim || role == auth.RoleGuestOrder {
+				// Account-claim and guest order tokens are only valid for
+				// the claim endpoint and the guest's order status
+				next.ServeHTTP(w, r)
+				return
+			}
 			ctx := context.WithValue(r.Context(), UserIDKey, sub)
 			ctx = context.WithValue(ctx, UserRoleKey, role)
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"afterzin/api/internal/i18n"
+)
+
+// BodyTooLargeMessage is the error of the 413 response for oversized bodies.
+const BodyTooLargeMessage = "corpo da requisição muito grande"
+
+// MaxBody caps request bodies at limit bytes, or at perPath[r.URL.Path] for
+// routes that need a different cap (e.g. /graphql, which carries base64
+// image uploads). A limit of 0 disables the cap. Bodies that declare a
+// larger Content-Length are refused with 413 up front; others are wrapped in
+// http.MaxBytesReader, so reading past the limit fails with
+// *http.MaxBytesError and the handler answers 413 itself.
+func MaxBody(limit int64, perPath map[string]int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			max := limit
+			if v, ok := perPath[r.URL.Path]; ok {
+				max = v
+			}
+			if max > 0 && r.Body != nil && r.Body != http.NoBody {
+				if r.ContentLength > max {
+					respondBodyTooLarge(w)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, max)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// respondBodyTooLarge writes the 413 {"error": ...} response.
+func respondBodyTooLarge(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Connection", "close")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	json.NewEncoder(w).Encode(map[string]string{"error": i18n.T(w.Header().Get("Content-Language"), BodyTooLargeMessage)})
+}
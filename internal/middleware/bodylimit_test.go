@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxBodyLimitsPerPath(t *testing.T) {
+	var readErr error
+	h := MaxBody(10, map[string]int64{"/graphql": 100})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+	serve := func(path, body string, chunked bool) int {
+		readErr = nil
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if chunked {
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve("/v1/payment/create", strings.Repeat("x", 11), false); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("declared oversized body: status = %d, want 413", code)
+	}
+	serve("/v1/payment/create", strings.Repeat("x", 11), true)
+	var tooLarge *http.MaxBytesError
+	if !errors.As(readErr, &tooLarge) {
+		t.Errorf("undeclared oversized body: read err = %v, want *http.MaxBytesError", readErr)
+	}
+	if code := serve("/graphql", strings.Repeat("x", 50), false); code != http.StatusOK || readErr != nil {
+		t.Errorf("/graphql under its own limit: status = %d, err = %v", code, readErr)
+	}
+	if code := serve("/graphql", strings.Repeat("x", 101), false); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("/graphql over its limit: status = %d, want 413", code)
+	}
+}
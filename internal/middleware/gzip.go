@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// incompressibleTypes are content types that are already compressed, so
+// gzipping them again only costs CPU.
+var incompressibleTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/pdf",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/octet-stream",
+	"text/event-stream", // streams must reach the client as they are written
+}
+
+var gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+
+// Gzip compresses responses for clients that send Accept-Encoding: gzip once
+// the body reaches minSize bytes. Smaller bodies, already-compressed content
+// types, streams and websocket upgrades are passed through untouched.
+func Gzip(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" ||
+				!acceptsGzip(r.Header.Get("Accept-Encoding")) ||
+				strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Accept-Encoding")
+			gw := &gzipResponseWriter{ResponseWriter: w, minSize: minSize, status: http.StatusOK}
+			defer gw.finish()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		enc, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(enc), "gzip") {
+			// "gzip;q=0" means the client explicitly refuses it
+			return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the first minSize bytes to decide whether the
+// response is worth compressing, then either streams through a gzip.Writer or
+// writes the buffered bytes as they are.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize     int
+	status      int
+	buf         []byte
+	gz          *gzip.Writer
+	passthrough bool
+	wroteHeader bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+	}
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.minSize {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// decide starts compression or switches to passthrough, then writes the
+// header and anything buffered so far.
+func (w *gzipResponseWriter) decide() error {
+	h := w.Header()
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if len(w.buf) < w.minSize || h.Get("Content-Encoding") != "" || !compressible(h.Get("Content-Type")) ||
+		w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return w.startPassthrough()
+	}
+
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(w.status)
+	w.gz = gzipWriters.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+	_, err := w.gz.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *gzipResponseWriter) startPassthrough() error {
+	w.passthrough = true
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(w.status)
+	var err error
+	if len(w.buf) > 0 {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+// Flush sends buffered data to the client. A handler flushing before minSize
+// is reached is streaming, so the response is left uncompressed.
+func (w *gzipResponseWriter) Flush() {
+	switch {
+	case w.gz != nil:
+		w.gz.Flush()
+	case !w.passthrough:
+		w.startPassthrough()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *gzipResponseWriter) finish() {
+	if w.gz != nil {
+		w.gz.Close()
+		w.gz.Reset(nil)
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+		return
+	}
+	if !w.passthrough {
+		if len(w.buf) == 0 {
+			w.passthrough = true
+			w.ResponseWriter.WriteHeader(w.status)
+			return
+		}
+		w.decide()
+	}
+}
+
+func compressible(contentType string) bool {
+	ct := strings.ToLower(contentType)
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(ct, prefix) {
+			return strings.HasPrefix(ct, "image/svg") // SVG is text
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzip(t *testing.T) {
+	large := strings.Repeat(`{"id":"evt","title":"Festival"}`, 100)
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		flush          bool
+		wantGzip       bool
+	}{
+		{name: "json grande", acceptEncoding: "gzip, deflate", contentType: "application/json", body: large, wantGzip: true},
+		{name: "csv grande", acceptEncoding: "gzip", contentType: "text/csv; charset=utf-8", body: large, wantGzip: true},
+		{name: "resposta pequena", acceptEncoding: "gzip", contentType: "application/json", body: `{"ok":true}`, wantGzip: false},
+		{name: "cliente sem gzip", acceptEncoding: "", contentType: "application/json", body: large, wantGzip: false},
+		{name: "gzip recusado", acceptEncoding: "gzip;q=0", contentType: "application/json", body: large, wantGzip: false},
+		{name: "pdf", acceptEncoding: "gzip", contentType: "application/pdf", body: large, wantGzip: false},
+		{name: "imagem", acceptEncoding: "gzip", contentType: "image/png", body: large, wantGzip: false},
+		{name: "stream", acceptEncoding: "gzip", contentType: "text/event-stream", body: large, wantGzip: false},
+		{name: "flush antes do limite", acceptEncoding: "gzip", contentType: "application/json", body: large, flush: true, wantGzip: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Gzip(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(http.StatusCreated)
+				if tt.flush {
+					io.WriteString(w, "data: ping\n\n")
+					w.(http.Flusher).Flush()
+				}
+				io.WriteString(w, tt.body)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusCreated {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+			}
+			gotGzip := rec.Header().Get("Content-Encoding") == "gzip"
+			if gotGzip != tt.wantGzip {
+				t.Fatalf("gzip = %v, want %v", gotGzip, tt.wantGzip)
+			}
+			body := rec.Body.String()
+			if gotGzip {
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("gzip reader: %v", err)
+				}
+				b, _ := io.ReadAll(zr)
+				body = string(b)
+			}
+			want := tt.body
+			if tt.flush {
+				want = "data: ping\n\n" + tt.body
+			}
+			if body != want {
+				t.Errorf("body mismatch: got %d bytes, want %d", len(body), len(want))
+			}
+		})
+	}
+}
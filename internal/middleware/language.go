@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+
+	"afterzin/api/internal/i18n"
+)
+
+// Language negotiates the response language from Accept-Language (pt-BR by
+// default) and announces it in Content-Language, where handlers writing
+// error messages pick it up (see i18n.T).
+func Language(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Language", i18n.Negotiate(r.Header.Get("Accept-Language")))
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLanguageTranslatesRecoveredPanic(t *testing.T) {
+	h := Language(Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+
+	for accept, want := range map[string]string{
+		"":                  "erro interno do servidor",
+		"en-US,en;q=0.9":    "internal server error",
+		"pt-BR,en-US;q=0.5": "erro interno do servidor",
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if accept != "" {
+			req.Header.Set("Accept-Language", accept)
+		}
+		h.ServeHTTP(rec, req)
+		var body map[string]string
+		json.NewDecoder(rec.Body).Decode(&body)
+		if body["error"] != want {
+			t.Errorf("Accept-Language %q: error = %q, want %q", accept, body["error"], want)
+		}
+		if rec.Header().Get("Content-Language") == "" || rec.Header().Get("Vary") != "Accept-Language" {
+			t.Errorf("Accept-Language %q: headers = %v", accept, rec.Header())
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+
+	"afterzin/api/internal/i18n"
+	"afterzin/api/internal/logger"
+)
+
+// Recover turns a handler panic into a logged 500 with the usual
+// {"error": ...} body instead of a dropped connection. If the handler had
+// already started the response, the connection is aborted, since a partial
+// body can't be turned into an error.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoverWriter{ResponseWriter: w}
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			logger.Errorf("pânico em %s %s (request_id=%s): %v\n%s", r.Method, r.URL.Path, RequestIDFrom(r.Context()), rec, debug.Stack())
+			if rw.written {
+				panic(http.ErrAbortHandler)
+			}
+			w.Header().Del("Content-Length")
+			w.Header().Del("Content-Disposition")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": i18n.T(w.Header().Get("Content-Language"), "erro interno do servidor")})
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// recoverWriter records whether the response has started.
+type recoverWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *recoverWriter) WriteHeader(status int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recoverWriter) Write(p []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *recoverWriter) Flush() {
+	w.written = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *recoverWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
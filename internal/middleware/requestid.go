@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const RequestIDKey contextKey = "request_id"
+
+// RequestID tags each request with an ID, reusing a sane X-Request-ID from a
+// proxy when present, and echoes it in the response so logs can be matched
+// with client reports.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > 64 {
+			id = uuid.New().String()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), RequestIDKey, id)))
+	})
+}
+
+func RequestIDFrom(ctx context.Context) string {
+	v, _ := ctx.Value(RequestIDKey).(string)
+	return v
+}
@@ -0,0 +1,158 @@
+// Package money formats and parses amounts kept in centavos (the smallest
+// currency unit), following pt-BR conventions: "R$ 1.234,56".
+package money
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// symbols maps ISO 4217 codes to their display symbol. Currencies missing
+// here are shown with their code ("USD 10,00").
+var symbols = map[string]string{
+	"BRL": "R$",
+}
+
+// ErrInvalidAmount is returned by ParseBRL for text that isn't an amount.
+var ErrInvalidAmount = errors.New("valor inválido")
+
+// FormatBRL formats centavos as reais: 123456 → "R$ 1.234,56", -990 → "-R$ 9,90".
+func FormatBRL(centavos int64) string {
+	return Format(centavos, "BRL")
+}
+
+// Format formats an amount in the currency's smallest unit (two decimals)
+// with pt-BR separators and the currency symbol. Negative amounts (refunds,
+// chargebacks) carry a leading minus sign.
+func Format(amount int64, currency string) string {
+	symbol, ok := symbols[strings.ToUpper(currency)]
+	if !ok {
+		symbol = strings.ToUpper(currency)
+	}
+	sign := ""
+	u := uint64(amount)
+	if amount < 0 {
+		sign = "-"
+		u = uint64(-(amount + 1)) + 1 // no overflow on math.MinInt64
+	}
+	cents := u % 100
+	var b strings.Builder
+	b.WriteString(sign)
+	b.WriteString(symbol)
+	b.WriteByte(' ')
+	b.WriteString(groupThousands(strconv.FormatUint(u/100, 10)))
+	b.WriteByte(',')
+	if cents < 10 {
+		b.WriteByte('0')
+	}
+	b.WriteString(strconv.FormatUint(cents, 10))
+	return b.String()
+}
+
+// FormatReais formats an amount in reais, as stored on orders.total.
+func FormatReais(reais float64) string {
+	return FormatBRL(FromReais(reais))
+}
+
+// groupThousands inserts "." every three digits from the right.
+func groupThousands(digits string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
+
+// FromReais converts an amount in reais to centavos. Rounds rather than
+// truncates: 19.99*100 is 1998.999... in float64.
+func FromReais(reais float64) int64 {
+	return int64(math.Round(reais * 100))
+}
+
+// ToReais converts centavos to reais.
+func ToReais(centavos int64) float64 {
+	return float64(centavos) / 100
+}
+
+// ParseBRL parses an amount typed by a person into centavos. It accepts an
+// optional "R$" and minus sign, pt-BR separators ("1.234,56") and a plain
+// decimal point ("1234.56"). A lone "." followed by three digits is read as
+// a thousands separator ("1.234" is R$ 1.234,00), as pt-BR readers expect.
+func ParseBRL(s string) (int64, error) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "\u00a0", " "))
+	neg := false
+	if strings.HasPrefix(s, "-") {
+		neg, s = true, strings.TrimSpace(s[1:])
+	}
+	if strings.HasPrefix(strings.ToUpper(s), "R$") {
+		s = strings.TrimSpace(s[2:])
+	}
+	if !neg && strings.HasPrefix(s, "-") {
+		neg, s = true, strings.TrimSpace(s[1:])
+	}
+	if s == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	intPart, frac, decimal := s, "", false
+	if i := strings.LastIndexByte(s, ','); i >= 0 {
+		intPart, frac, decimal = s[:i], s[i+1:], true
+	} else if i := strings.LastIndexByte(s, '.'); i >= 0 && strings.Count(s, ".") == 1 && len(s)-i-1 <= 2 {
+		intPart, frac, decimal = s[:i], s[i+1:], true
+	}
+	if (decimal && frac == "") || len(frac) > 2 || strings.Trim(frac, "0123456789") != "" {
+		return 0, ErrInvalidAmount
+	}
+	intPart, err := stripThousands(intPart)
+	if err != nil {
+		return 0, err
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	units, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || units > (math.MaxInt64-99)/100 {
+		return 0, ErrInvalidAmount
+	}
+	cents, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	v := units*100 + cents
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
+// stripThousands removes "." separators, requiring groups of three digits.
+func stripThousands(s string) (string, error) {
+	if !strings.Contains(s, ".") {
+		if strings.Trim(s, "0123456789") != "" {
+			return "", ErrInvalidAmount
+		}
+		return s, nil
+	}
+	groups := strings.Split(s, ".")
+	for i, g := range groups {
+		if g == "" || len(g) > 3 || (i > 0 && len(g) != 3) || strings.Trim(g, "0123456789") != "" {
+			return "", ErrInvalidAmount
+		}
+	}
+	return strings.Join(groups, ""), nil
+}
@@ -0,0 +1,102 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestFormatBRL(t *testing.T) {
+	cases := []struct {
+		centavos int64
+		want     string
+	}{
+		{0, "R$ 0,00"},
+		{5, "R$ 0,05"},
+		{99, "R$ 0,99"},
+		{100, "R$ 1,00"},
+		{99999, "R$ 999,99"},
+		{100000, "R$ 1.000,00"},
+		{123456, "R$ 1.234,56"},
+		{5_000_000, "R$ 50.000,00"},
+		{123456789012, "R$ 1.234.567.890,12"},
+		{-1, "-R$ 0,01"},
+		{-990, "-R$ 9,90"},
+		{-123456, "-R$ 1.234,56"},
+		{math.MaxInt64, "R$ 92.233.720.368.547.758,07"},
+		{math.MinInt64, "-R$ 92.233.720.368.547.758,08"},
+	}
+	for _, c := range cases {
+		if got := FormatBRL(c.centavos); got != c.want {
+			t.Errorf("FormatBRL(%d) = %q, want %q", c.centavos, got, c.want)
+		}
+	}
+}
+
+func TestFormatOtherCurrency(t *testing.T) {
+	if got := Format(150050, "usd"); got != "USD 1.500,50" {
+		t.Errorf("Format(USD) = %q", got)
+	}
+}
+
+func TestFromReais(t *testing.T) {
+	cases := map[float64]int64{
+		19.99:  1999,
+		0.1:    10,
+		0.29:   29,
+		-12.34: -1234,
+		50:     5000,
+	}
+	for reais, want := range cases {
+		if got := FromReais(reais); got != want {
+			t.Errorf("FromReais(%v) = %d, want %d", reais, got, want)
+		}
+	}
+	if got := FormatReais(19.99); got != "R$ 19,99" {
+		t.Errorf("FormatReais(19.99) = %q", got)
+	}
+}
+
+func TestParseBRL(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int64
+	}{
+		{"R$ 1.234,56", 123456},
+		{"r$1.234,56", 123456},
+		{"1234,56", 123456},
+		{"1234.56", 123456},
+		{"1234.5", 123450},
+		{"1.234", 123400},
+		{"1.234.567", 123456700},
+		{"10", 1000},
+		{",5", 50},
+		{"0,01", 1},
+		{"-R$ 9,90", -990},
+		{"R$ -9,90", -990},
+		{"R$ 50,00", 5000},
+		{"  7,00  ", 700},
+		{"R$\u00a012,00", 1200},
+	}
+	for _, c := range cases {
+		got, err := ParseBRL(c.in)
+		if err != nil || got != c.want {
+			t.Errorf("ParseBRL(%q) = %d, %v; want %d", c.in, got, err, c.want)
+		}
+	}
+
+	for _, in := range []string{"", "R$", "-", "abc", "1,234,56", "1,234", "12.34.56", "1.23,00", "10,", "1e3", "99999999999999999999", "--5"} {
+		if _, err := ParseBRL(in); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("ParseBRL(%q) err = %v, want ErrInvalidAmount", in, err)
+		}
+	}
+}
+
+func TestFormatParseRoundTrip(t *testing.T) {
+	for _, v := range []int64{0, 1, 99, 100, 123456, -123456, 987654321} {
+		got, err := ParseBRL(FormatBRL(v))
+		if err != nil || got != v {
+			t.Errorf("round trip %d = %d, %v", v, got, err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package outbox
+
+import (
+	"database/sql"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/repository"
+)
+
+// TicketPurchasedPayload is the payload of a ticket_purchased notification.
+type TicketPurchasedPayload struct {
+	OrderID    string `json:"orderId"`
+	OrderRef   string `json:"orderRef,omitempty"`
+	EventTitle string `json:"eventTitle,omitempty"`
+	Tickets    int    `json:"tickets"`
+}
+
+// Notify creates an in-app notification for userID through the outbox, so
+// a failed write is retried by the outbox-dispatch job. Failures to even
+// queue it are logged and swallowed: a notification must never fail the
+// operation that triggered it. Inside a transaction, use NotifyTx.
+func Notify(db *sql.DB, userID, typ string, payload interface{}) {
+	data, err := notificationPayload(userID, typ, payload)
+	if err != nil {
+		logger.Warnf("erro ao serializar notificação %s para usuário %s (não-fatal): %v", typ, userID, err)
+		return
+	}
+	id, err := repository.EnqueueOutbox(db, repository.OutboxNotification, data)
+	if err != nil {
+		logger.Warnf("erro ao criar notificação %s para usuário %s (não-fatal): %v", typ, userID, err)
+		return
+	}
+	Deliver(db, nil, id)
+}
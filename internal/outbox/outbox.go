@@ -0,0 +1,267 @@
+// Package outbox delivers emails and in-app notifications through the outbox
+// table: a message is stored first, in the same transaction as the change
+// that triggered it when there is one, then sent, and retried by the
+// outbox-dispatch job until it is delivered or dead-lettered.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+)
+
+const (
+	// claimLease is how long a claimed message is hidden from other
+	// workers; longer than any SMTP timeout.
+	claimLease = 5 * time.Minute
+	// immediateRetryDelay is the wait before the job retries a message
+	// whose first, immediate delivery failed.
+	immediateRetryDelay = time.Minute
+	// maxBackoff caps the wait between retries.
+	maxBackoff = 6 * time.Hour
+	// batchSize caps the messages delivered per job run.
+	batchSize = 100
+	// sentRetention is how long delivered messages are kept.
+	sentRetention = 7 * 24 * time.Hour
+	// deadRetention is how long dead-lettered messages keep their payload.
+	deadRetention = 7 * 24 * time.Hour
+)
+
+// Delivery outcomes counted per message kind.
+const (
+	outcomeSent   = "sent"
+	outcomeFailed = "failed"
+	outcomeDead   = "dead"
+)
+
+// dispatched counts deliveries since startup per message kind and outcome
+// ({"email": {"sent": 10, "failed": 2, "dead": 1}}), published through
+// expvar as "outbox_dispatch". "failed" counts every failed attempt; "dead"
+// the messages given up on.
+var dispatched = struct {
+	sync.Mutex
+	byKind *expvar.Map
+}{byKind: expvar.NewMap("outbox_dispatch")}
+
+func countDelivery(kind, outcome string) {
+	dispatched.Lock()
+	defer dispatched.Unlock()
+	m, _ := dispatched.byKind.Get(kind).(*expvar.Map)
+	if m == nil {
+		m = new(expvar.Map)
+		dispatched.byKind.Set(kind, m)
+	}
+	m.Add(outcome, 1)
+}
+
+// Counts returns the delivery counters since startup per message kind and
+// outcome (sent, failed, dead).
+func Counts() map[string]map[string]int64 {
+	snapshot := map[string]map[string]int64{}
+	dispatched.Lock()
+	defer dispatched.Unlock()
+	dispatched.byKind.Do(func(kv expvar.KeyValue) {
+		counts := map[string]int64{outcomeSent: 0, outcomeFailed: 0, outcomeDead: 0}
+		kv.Value.(*expvar.Map).Do(func(c expvar.KeyValue) {
+			counts[c.Key] = c.Value.(*expvar.Int).Value()
+		})
+		snapshot[kv.Key] = counts
+	})
+	return snapshot
+}
+
+// Policy controls the retries of the outbox-dispatch job.
+type Policy struct {
+	MaxAttempts int           // attempts, the immediate one included, before a message is dead-lettered
+	RetryBase   time.Duration // wait after the first retry fails, doubled after each further failure
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func (p Policy) backoff(failures int) time.Duration {
+	d := p.RetryBase
+	for i := 1; i < failures && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// emailMessage is the payload of an email message.
+type emailMessage struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// notificationMessage is the payload of a notification message.
+type notificationMessage struct {
+	UserID  string          `json:"userId"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// QueueEmail stores msg in the outbox and tries to send it right away. A
+// failed send is retried by the outbox-dispatch job, so callers never need
+// to handle it; an error means the message couldn't even be stored.
+func QueueEmail(db *sql.DB, mail mailer.Mailer, msg mailer.Message) error {
+	data, err := json.Marshal(emailMessage{To: msg.To, Subject: msg.Subject, Body: msg.Body})
+	if err != nil {
+		return err
+	}
+	id, err := repository.EnqueueOutbox(db, repository.OutboxEmail, string(data))
+	if err != nil {
+		return err
+	}
+	Deliver(db, mail, id)
+	return nil
+}
+
+// NotifyTx stores an in-app notification for userID in the outbox within tx,
+// so it is sent if and only if the triggering change commits. After the
+// commit, pass the returned id to Deliver to show it right away.
+func NotifyTx(tx *sql.Tx, userID, typ string, payload interface{}) (string, error) {
+	data, err := notificationPayload(userID, typ, payload)
+	if err != nil {
+		return "", err
+	}
+	return repository.EnqueueOutboxTx(tx, repository.OutboxNotification, data)
+}
+
+func notificationPayload(userID, typ string, payload interface{}) (string, error) {
+	inner, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(notificationMessage{UserID: userID, Type: typ, Payload: inner})
+	return string(data), err
+}
+
+// Deliver makes the immediate delivery attempt of a message just
+// stored. A failure is logged and left for the outbox-dispatch job; mail may
+// be nil for notifications.
+func Deliver(db *sql.DB, mail mailer.Mailer, id string) {
+	now := time.Now()
+	m, err := repository.ClaimOutbox(db, id, now, claimLease)
+	if err != nil {
+		logger.Warnf("erro ao reservar mensagem %s do outbox (não-fatal): %v", id, err)
+		return
+	}
+	if m == nil {
+		return
+	}
+	if err := send(db, mail, m); err != nil {
+		countDelivery(m.Kind, outcomeFailed)
+		logger.Warnf("envio de %s %s falhou, nova tentativa em %s: %v", m.Kind, m.ID, immediateRetryDelay, err)
+		if err := repository.MarkOutboxFailed(db, m.ID, err.Error(), now.Add(immediateRetryDelay), false); err != nil {
+			logger.Errorf("erro ao registrar falha da mensagem %s do outbox: %v", m.ID, err)
+		}
+		return
+	}
+	markSent(db, m)
+}
+
+// Dispatch is the body of the outbox-dispatch job: it retries the
+// messages that are due, backing off after each failure and moving a message
+// to DEAD once it has failed policy.MaxAttempts times. Delivered messages
+// are deleted after a week, and dead ones lose their payload (email bodies
+// with claim links, notification contents) after a week too.
+func Dispatch(ctx context.Context, db *sql.DB, mail mailer.Mailer, policy Policy, now time.Time) error {
+	if n, err := repository.DeleteSentOutbox(db, now.Add(-sentRetention)); err != nil {
+		logger.Warnf("erro ao apagar mensagens enviadas do outbox (não-fatal): %v", err)
+	} else if n > 0 {
+		logger.Debugf("outbox: %d mensagem(ns) enviada(s) apagada(s)", n)
+	}
+	if n, err := repository.RedactDeadOutbox(db, now.Add(-deadRetention)); err != nil {
+		logger.Warnf("erro ao limpar mensagens descartadas do outbox (não-fatal): %v", err)
+	} else if n > 0 {
+		logger.Debugf("outbox: conteúdo de %d mensagem(ns) descartada(s) apagado", n)
+	}
+
+	ids, err := repository.DueOutboxIDs(db, now, batchSize)
+	if err != nil {
+		return err
+	}
+	sent, dead := 0, 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+		m, err := repository.ClaimOutbox(db, id, now, claimLease)
+		if err != nil {
+			logger.Errorf("erro ao reservar mensagem %s do outbox: %v", id, err)
+			continue
+		}
+		if m == nil {
+			continue
+		}
+		sendErr := errors.New("limite de tentativas atingido")
+		if m.Attempts < policy.MaxAttempts {
+			sendErr = send(db, mail, m)
+		}
+		if sendErr == nil {
+			markSent(db, m)
+			sent++
+			continue
+		}
+		failures := m.Attempts + 1
+		giveUp := failures >= policy.MaxAttempts
+		next := now.Add(policy.backoff(failures))
+		if giveUp {
+			next = now
+		}
+		countDelivery(m.Kind, outcomeFailed)
+		if err := repository.MarkOutboxFailed(db, m.ID, sendErr.Error(), next, giveUp); err != nil {
+			logger.Errorf("erro ao registrar falha da mensagem %s do outbox: %v", m.ID, err)
+			continue
+		}
+		if giveUp {
+			countDelivery(m.Kind, outcomeDead)
+			dead++
+			logger.Errorf("outbox: %s %s descartado após %d tentativa(s): %v", m.Kind, m.ID, failures, sendErr)
+		} else {
+			logger.Warnf("outbox: %s %s falhou (tentativa %d), nova tentativa em %s: %v", m.Kind, m.ID, failures, next.Sub(now), sendErr)
+		}
+	}
+	if sent > 0 || dead > 0 {
+		logger.Infof("outbox: %d mensagem(ns) enviada(s), %d descartada(s)", sent, dead)
+	}
+	return nil
+}
+
+// send delivers a message. Notifications reuse the message id,
+// so a retry after a delivery that wasn't marked as sent doesn't duplicate it.
+func send(db *sql.DB, mail mailer.Mailer, m *repository.OutboxMessage) error {
+	switch m.Kind {
+	case repository.OutboxEmail:
+		var e emailMessage
+		if err := json.Unmarshal([]byte(m.Payload), &e); err != nil {
+			return fmt.Errorf("payload inválido: %w", err)
+		}
+		if mail == nil {
+			return errors.New("mailer não configurado")
+		}
+		return mail.Send(mailer.Message{To: e.To, Subject: e.Subject, Body: e.Body})
+	case repository.OutboxNotification:
+		var n notificationMessage
+		if err := json.Unmarshal([]byte(m.Payload), &n); err != nil {
+			return fmt.Errorf("payload inválido: %w", err)
+		}
+		return repository.CreateNotificationWithID(db, m.ID, n.UserID, n.Type, string(n.Payload))
+	}
+	return fmt.Errorf("tipo de mensagem desconhecido: %s", m.Kind)
+}
+
+func markSent(db *sql.DB, m *repository.OutboxMessage) {
+	countDelivery(m.Kind, outcomeSent)
+	if err := repository.MarkOutboxSent(db, m.ID); err != nil {
+		logger.Errorf("erro ao marcar mensagem %s do outbox como enviada: %v", m.ID, err)
+	}
+}
@@ -0,0 +1,167 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+// flakyMailer fails the first failures sends, then records messages.
+type flakyMailer struct {
+	failures int
+	sent     []mailer.Message
+}
+
+func (m *flakyMailer) Send(msg mailer.Message) error {
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("smtp: 421 serviço indisponível")
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func outboxRow(t *testing.T, sqlite *sql.DB) (status string, attempts int) {
+	t.Helper()
+	if err := sqlite.QueryRow(`SELECT status, attempts FROM outbox`).Scan(&status, &attempts); err != nil {
+		t.Fatal(err)
+	}
+	return status, attempts
+}
+
+func countRows(t *testing.T, sqlite *sql.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := sqlite.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("count %q: %v", query, err)
+	}
+	return n
+}
+
+func TestOutboxRetriesEmailWithBackoff(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	mail := &flakyMailer{failures: 2}
+	policy := Policy{MaxAttempts: 5, RetryBase: time.Minute}
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := QueueEmail(sqlite, mail, mailer.Message{To: "a@email.com", Subject: "Oi", Body: "corpo"}); err != nil {
+		t.Fatal(err)
+	}
+	if status, attempts := outboxRow(t, sqlite); status != repository.OutboxPending || attempts != 1 {
+		t.Fatalf("after the immediate attempt: %s/%d, want PENDING/1", status, attempts)
+	}
+
+	// Not due yet: the immediate failure waits immediateRetryDelay
+	if err := Dispatch(ctx, sqlite, mail, policy, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, attempts := outboxRow(t, sqlite); attempts != 1 {
+		t.Fatalf("retried before it was due: %d attempts", attempts)
+	}
+
+	now = now.Add(2 * time.Minute)
+	Dispatch(ctx, sqlite, mail, policy, now)
+	if status, attempts := outboxRow(t, sqlite); status != repository.OutboxPending || attempts != 2 {
+		t.Fatalf("after the first retry: %s/%d, want PENDING/2", status, attempts)
+	}
+	// Second failure backs off for twice the base
+	Dispatch(ctx, sqlite, mail, policy, now.Add(time.Minute))
+	if len(mail.sent) != 0 {
+		t.Fatal("retried before the backoff elapsed")
+	}
+	Dispatch(ctx, sqlite, mail, policy, now.Add(3*time.Minute))
+	if status, attempts := outboxRow(t, sqlite); status != repository.OutboxSent || attempts != 3 {
+		t.Fatalf("after the second retry: %s/%d, want SENT/3", status, attempts)
+	}
+	if len(mail.sent) != 1 || mail.sent[0].To != "a@email.com" || mail.sent[0].Body != "corpo" {
+		t.Errorf("sent = %+v", mail.sent)
+	}
+}
+
+func TestOutboxDeadLettersAfterMaxAttempts(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	mail := &flakyMailer{failures: 100}
+	policy := Policy{MaxAttempts: 2, RetryBase: time.Minute}
+	deadBefore := Counts()[repository.OutboxEmail][outcomeDead]
+
+	if err := QueueEmail(sqlite, mail, mailer.Message{To: "a@email.com", Subject: "Oi"}); err != nil {
+		t.Fatal(err)
+	}
+	Dispatch(context.Background(), sqlite, mail, policy, time.Now().Add(2*time.Minute))
+	if status, attempts := outboxRow(t, sqlite); status != repository.OutboxDead || attempts != 2 {
+		t.Fatalf("status = %s/%d, want DEAD/2", status, attempts)
+	}
+	Dispatch(context.Background(), sqlite, mail, policy, time.Now().Add(time.Hour))
+	if mail.failures != 98 {
+		t.Errorf("dead message retried: %d sends", 100-mail.failures)
+	}
+	if got := Counts()[repository.OutboxEmail][outcomeDead]; got != deadBefore+1 {
+		t.Errorf("dead counter = %d, want %d", got, deadBefore+1)
+	}
+	dead, _ := repository.DeadOutboxMessages(sqlite, 10)
+	if len(dead) != 1 || dead[0].LastError == "" {
+		t.Errorf("dead letters = %+v", dead)
+	}
+
+	// The payload goes once the retention window has passed; the row stays
+	var payload string
+	sqlite.QueryRow(`SELECT payload FROM outbox`).Scan(&payload)
+	if payload == "{}" {
+		t.Fatal("payload redacted before the retention window")
+	}
+	Dispatch(context.Background(), sqlite, mail, policy, time.Now().Add(deadRetention+time.Hour))
+	sqlite.QueryRow(`SELECT payload FROM outbox`).Scan(&payload)
+	if payload != "{}" {
+		t.Errorf("payload = %q after the retention window, want it redacted", payload)
+	}
+	if status, _ := outboxRow(t, sqlite); status != repository.OutboxDead {
+		t.Errorf("status = %s, want the redacted message kept as DEAD", status)
+	}
+}
+
+func TestNotifyTxFollowsTheTransaction(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	orderID, buyerID := f.PendingOrder(t, sqlite, 1), f.BuyerID
+	payload := TicketPurchasedPayload{OrderID: orderID, Tickets: 1}
+
+	tx, _ := sqlite.Begin()
+	if _, err := NotifyTx(tx, buyerID, repository.NotificationTicketPurchased, payload); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM outbox`); got != 0 {
+		t.Fatalf("rolled back notification queued: %d", got)
+	}
+
+	tx, _ = sqlite.Begin()
+	id, err := NotifyTx(tx, buyerID, repository.NotificationTicketPurchased, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+	Deliver(sqlite, nil, id)
+	Deliver(sqlite, nil, id)
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM notifications WHERE id = ? AND user_id = ?`, id, buyerID); got != 1 {
+		t.Errorf("notifications = %d, want 1", got)
+	}
+	if status, _ := outboxRow(t, sqlite); status != repository.OutboxSent {
+		t.Errorf("outbox status = %s, want SENT", status)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	p := Policy{RetryBase: time.Minute}
+	for failures, want := range map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 4: 8 * time.Minute, 30: maxBackoff} {
+		if got := p.backoff(failures); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
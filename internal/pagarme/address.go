@@ -0,0 +1,153 @@
+package pagarme
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/validate"
+)
+
+// maxAddressLineLength is Pagar.me's limit for the customer address lines.
+const maxAddressLineLength = 256
+
+// brazilianStates are the UF codes accepted in Address.State.
+var brazilianStates = map[string]bool{
+	"AC": true, "AL": true, "AP": true, "AM": true, "BA": true, "CE": true, "DF": true,
+	"ES": true, "GO": true, "MA": true, "MT": true, "MS": true, "MG": true, "PA": true,
+	"PB": true, "PR": true, "PE": true, "PI": true, "RJ": true, "RN": true, "RS": true,
+	"RO": true, "RR": true, "SC": true, "SP": true, "SE": true, "TO": true,
+}
+
+// AddressValidationError indicates which address field is invalid. Field is
+// the JSON name (zip_code, state...); Error() returns only the message.
+type AddressValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *AddressValidationError) Error() string { return e.Reason }
+
+// InputField returns the name of the field in the GraphQL address input
+// (zipCode, streetNumber...).
+func (e *AddressValidationError) InputField() string {
+	switch e.Field {
+	case "street_number":
+		return "streetNumber"
+	case "zip_code":
+		return "zipCode"
+	default:
+		return e.Field
+	}
+}
+
+// Normalize trims every field, keeps only the digits of ZipCode and
+// upper-cases State.
+func (a *Address) Normalize() {
+	a.Street = strings.TrimSpace(a.Street)
+	a.Complementary = strings.TrimSpace(a.Complementary)
+	a.StreetNumber = strings.TrimSpace(a.StreetNumber)
+	a.Neighborhood = strings.TrimSpace(a.Neighborhood)
+	a.City = strings.TrimSpace(a.City)
+	a.State = strings.ToUpper(strings.TrimSpace(a.State))
+	a.ZipCode = validate.SanitizeDocument(a.ZipCode)
+	a.ReferencePoint = strings.TrimSpace(a.ReferencePoint)
+}
+
+// Validate checks a normalized Brazilian address: street, number,
+// neighborhood and city are required, State must be a UF and ZipCode a CEP
+// (8 digits). Errors are *AddressValidationError.
+func (a Address) Validate() error {
+	required := []struct{ field, value, reason string }{
+		{"street", a.Street, "rua é obrigatória"},
+		{"street_number", a.StreetNumber, "número é obrigatório"},
+		{"neighborhood", a.Neighborhood, "bairro é obrigatório"},
+		{"city", a.City, "cidade é obrigatória"},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			return &AddressValidationError{Field: r.field, Reason: r.reason}
+		}
+	}
+	if !brazilianStates[a.State] {
+		return &AddressValidationError{Field: "state", Reason: "estado inválido: use a sigla da UF"}
+	}
+	if len(a.ZipCode) != 8 || validate.SanitizeDocument(a.ZipCode) != a.ZipCode {
+		return &AddressValidationError{Field: "zip_code", Reason: "CEP inválido: deve ter 8 dígitos"}
+	}
+	if utf8.RuneCountInString(a.line1()) > maxAddressLineLength || utf8.RuneCountInString(a.line2()) > maxAddressLineLength {
+		return &AddressValidationError{Field: "street", Reason: fmt.Sprintf("endereço muito longo (máximo %d caracteres)", maxAddressLineLength)}
+	}
+	return nil
+}
+
+// line1 is the first address line in Pagar.me's format: "number, street,
+// neighborhood".
+func (a Address) line1() string {
+	return a.StreetNumber + ", " + a.Street + ", " + a.Neighborhood
+}
+
+// line2 is the second address line: complement and reference point.
+func (a Address) line2() string {
+	parts := make([]string, 0, 2)
+	for _, p := range []string{a.Complementary, a.ReferencePoint} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " - ")
+}
+
+// customerAddress is the address object of a Pagar.me order customer.
+func (a Address) customerAddress() map[string]interface{} {
+	addr := map[string]interface{}{
+		"line_1":   a.line1(),
+		"zip_code": a.ZipCode,
+		"city":     a.City,
+		"state":    a.State,
+		"country":  DefaultRegion,
+	}
+	if line2 := a.line2(); line2 != "" {
+		addr["line_2"] = line2
+	}
+	return addr
+}
+
+// SavedAddress converts an address-book entry to an Address.
+func SavedAddress(a *repository.UserAddress) Address {
+	return Address{
+		Street:         a.Street,
+		Complementary:  a.Complementary,
+		StreetNumber:   a.StreetNumber,
+		Neighborhood:   a.Neighborhood,
+		City:           a.City,
+		State:          a.State,
+		ZipCode:        a.ZipCode,
+		ReferencePoint: a.ReferencePoint,
+	}
+}
+
+// defaultCustomerAddress returns the buyer's default saved address, or nil if
+// there is none. A saved address that no longer validates is skipped, so it
+// can't make the gateway refuse the order.
+func defaultCustomerAddress(db *sql.DB, userID string) (*Address, error) {
+	saved, err := repository.DefaultUserAddress(db, userID)
+	if err != nil || saved == nil {
+		return nil, err
+	}
+	addr := SavedAddress(saved)
+	if err := addr.Validate(); err != nil {
+		logger.Warnf("endereço padrão %s do usuário %s ignorado: %v", saved.ID, userID, err)
+		return nil, nil
+	}
+	return &addr, nil
+}
+
+// requiresCustomerAddress reports whether a payment method needs the buyer's
+// address. PIX doesn't; boleto does, should it ever be enabled.
+func requiresCustomerAddress(paymentMethod string) bool {
+	return paymentMethod == "boleto"
+}
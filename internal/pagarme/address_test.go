@@ -0,0 +1,112 @@
+package pagarme
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+)
+
+func TestAddressValidate(t *testing.T) {
+	valid := Address{Street: "Rua A", StreetNumber: "10", Neighborhood: "Centro", City: "São Paulo", State: "SP", ZipCode: "01001000"}
+	tests := []struct {
+		name      string
+		edit      func(a *Address)
+		wantField string
+	}{
+		{name: "válido", edit: func(a *Address) {}},
+		{name: "sem rua", edit: func(a *Address) { a.Street = "" }, wantField: "street"},
+		{name: "sem número", edit: func(a *Address) { a.StreetNumber = "" }, wantField: "street_number"},
+		{name: "sem bairro", edit: func(a *Address) { a.Neighborhood = "" }, wantField: "neighborhood"},
+		{name: "sem cidade", edit: func(a *Address) { a.City = "" }, wantField: "city"},
+		{name: "UF inexistente", edit: func(a *Address) { a.State = "XX" }, wantField: "state"},
+		{name: "estado por extenso", edit: func(a *Address) { a.State = "São Paulo" }, wantField: "state"},
+		{name: "CEP curto", edit: func(a *Address) { a.ZipCode = "0100100" }, wantField: "zip_code"},
+		{name: "CEP com letras", edit: func(a *Address) { a.ZipCode = "0100100A" }, wantField: "zip_code"},
+		{name: "linha longa demais", edit: func(a *Address) { a.Street = strings.Repeat("a", maxAddressLineLength) }, wantField: "street"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := valid
+			tt.edit(&a)
+			err := a.Validate()
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			var addrErr *AddressValidationError
+			if !errors.As(err, &addrErr) || addrErr.Field != tt.wantField {
+				t.Fatalf("Validate() = %v, want error on %s", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestAddressNormalize(t *testing.T) {
+	a := Address{Street: " Rua A ", StreetNumber: "10", Neighborhood: "Centro", City: "São Paulo ", State: " sp", ZipCode: "01001-000", Complementary: "apto 3", ReferencePoint: "perto da praça"}
+	a.Normalize()
+	if a.Street != "Rua A" || a.City != "São Paulo" || a.State != "SP" || a.ZipCode != "01001000" {
+		t.Errorf("Normalize() = %+v", a)
+	}
+	got := a.customerAddress()
+	if got["line_1"] != "10, Rua A, Centro" || got["line_2"] != "apto 3 - perto da praça" || got["country"] != DefaultRegion {
+		t.Errorf("customerAddress() = %v", got)
+	}
+}
+
+func TestCreatePaymentForwardsAddress(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+
+	rec := httptest.NewRecorder()
+	body := `{"orderId":"` + orderID + `","address":{"street":"Rua A","street_number":"10","neighborhood":"Centro","city":"São Paulo","state":"sp","zip_code":"0100-100"}}`
+	h.CreatePayment(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(body)), buyerID))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"field":"address.zip_code"`) {
+		t.Fatalf("invalid CEP: status = %d, body = %s; want 400 on address.zip_code", rec.Code, rec.Body.String())
+	}
+	if fake.callCount("CreatePixOrder") != 0 {
+		t.Fatal("gateway called with an invalid address")
+	}
+
+	rec = httptest.NewRecorder()
+	body = strings.Replace(body, "0100-100", "01001-000", 1)
+	h.CreatePayment(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(body)), buyerID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	addr := fake.lastPixOrder.CustomerAddress
+	if addr == nil || addr.State != "SP" || addr.ZipCode != "01001000" {
+		t.Errorf("CustomerAddress = %+v, want the normalized address", addr)
+	}
+}
+
+func TestCreatePaymentUsesDefaultAddress(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+	if _, err := repository.CreateUserAddress(sqlite, repository.UserAddress{UserID: buyerID, Street: "Rua B", StreetNumber: "20", Neighborhood: "Centro", City: "Recife", State: "PE", ZipCode: "50010000"}); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	rec := httptest.NewRecorder()
+	h.CreatePayment(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(`{"orderId":"`+orderID+`"}`)), buyerID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if addr := fake.lastPixOrder.CustomerAddress; addr == nil || addr.City != "Recife" || addr.ZipCode != "50010000" {
+		t.Errorf("CustomerAddress = %+v, want the default address", addr)
+	}
+}
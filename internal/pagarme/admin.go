@@ -0,0 +1,43 @@
+package pagarme
+
+import (
+	"net/http"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/rest"
+)
+
+// AdminPagarmeOrder handles GET /v1/admin/pagarme/order?id=or_xxx
+// Returns the Pagar.me order object as the gateway sends it, so admins can
+// inspect charges and transactions during incidents without dashboard access.
+func (h *Handler) AdminPagarmeOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	userID := middleware.UserID(r.Context())
+	if userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return
+	}
+	if !rest.IsAdmin(h.db, userID) {
+		rest.Error(w, http.StatusForbidden, "sem permissão")
+		return
+	}
+
+	pagarmeOrderID := r.URL.Query().Get("id")
+	if pagarmeOrderID == "" {
+		rest.Error(w, http.StatusBadRequest, "id é obrigatório")
+		return
+	}
+
+	order, err := h.client.GetOrder(pagarmeOrderID)
+	if err != nil {
+		logger.Errorf("erro ao buscar pedido %s no Pagar.me: %v", pagarmeOrderID, err)
+		rest.Error(w, http.StatusBadGateway, "erro ao consultar pedido no Pagar.me: "+err.Error())
+		return
+	}
+	rest.JSON(w, http.StatusOK, order)
+}
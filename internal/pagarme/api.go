@@ -0,0 +1,32 @@
+package pagarme
+
+// PagarmeAPI is the set of Pagar.me operations the HTTP handler depends on.
+// *Client is the production implementation; tests can supply a fake so
+// handlers run without a live gateway.
+type PagarmeAPI interface {
+	CreateRecipient(params CreateRecipientParams) (*RecipientResult, error)
+	GetRecipient(recipientID string) (map[string]interface{}, error)
+	FindRecipientByCode(code string) (*RecipientResult, error)
+	DeactivateRecipient(recipientID string) error
+	GetRecipientBalance(recipientID string) (*RecipientBalance, error)
+	ListTransfers(recipientID string) ([]Transfer, error)
+	CreatePixOrder(params PixOrderParams) (*PixOrderResult, error)
+	GetOrder(pagarmeOrderID string) (map[string]interface{}, error)
+	GetOrderStatus(pagarmeOrderID string) (*PixOrderResult, error)
+	GetOrderPaidAmount(pagarmeOrderID string) (int64, error)
+	VerifyWebhookSignature(payload []byte, signatureHeader string) (*WebhookEvent, error)
+	ApplicationFeePerTicket() int64
+	Environment() string
+}
+
+var _ PagarmeAPI = (*Client)(nil)
+
+// ApplicationFeePerTicket returns the platform fee charged per ticket, in centavos.
+func (c *Client) ApplicationFeePerTicket() int64 {
+	return c.ApplicationFee
+}
+
+// Environment returns "test" or "live" depending on the API key in use.
+func (c *Client) Environment() string {
+	return c.Env
+}
@@ -0,0 +1,97 @@
+package pagarme
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/repository"
+)
+
+// SalesChannels are the accepted values of an order's channel. Anything else
+// is stored as ChannelOther; orders without a channel count as ChannelDirect.
+var SalesChannels = []string{
+	ChannelDirect, "instagram", "facebook", "tiktok", "whatsapp", "google", "email", "partner", ChannelOther,
+}
+
+const (
+	ChannelDirect = "direct"
+	ChannelOther  = "other"
+)
+
+// maxUTMValueLength caps each UTM parameter.
+const maxUTMValueLength = 100
+
+// UTM holds the utm_* parameters of the link that brought the buyer.
+type UTM struct {
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Term     string `json:"term,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+// Attribution is the marketing origin of an order.
+type Attribution struct {
+	Channel string `json:"channel,omitempty"`
+	UTM     *UTM   `json:"utm,omitempty"`
+}
+
+// NormalizeChannel maps a channel to one of SalesChannels (case-insensitive);
+// unknown values become ChannelOther and "" stays "".
+func NormalizeChannel(channel string) string {
+	channel = strings.ToLower(strings.TrimSpace(channel))
+	if channel == "" {
+		return ""
+	}
+	for _, c := range SalesChannels {
+		if c == channel {
+			return c
+		}
+	}
+	return ChannelOther
+}
+
+// normalize trims and caps the UTM values and, without an explicit channel,
+// derives it from utm_source when that names a known channel.
+func (a Attribution) normalize() Attribution {
+	out := Attribution{Channel: NormalizeChannel(a.Channel)}
+	if a.UTM != nil {
+		clip := func(s string) string {
+			s = strings.TrimSpace(s)
+			if utf8.RuneCountInString(s) > maxUTMValueLength {
+				s = string([]rune(s)[:maxUTMValueLength])
+			}
+			return s
+		}
+		u := UTM{clip(a.UTM.Source), clip(a.UTM.Medium), clip(a.UTM.Campaign), clip(a.UTM.Term), clip(a.UTM.Content)}
+		if u != (UTM{}) {
+			out.UTM = &u
+		}
+		if out.Channel == "" {
+			if c := NormalizeChannel(u.Source); c != ChannelOther {
+				out.Channel = c
+			}
+		}
+	}
+	return out
+}
+
+// RecordOrderAttribution stores where an order came from. Attribution is
+// best effort: a failure is logged and never blocks the sale.
+func RecordOrderAttribution(db *sql.DB, orderID string, a Attribution) {
+	a = a.normalize()
+	if a.Channel == "" && a.UTM == nil {
+		return
+	}
+	var utmJSON string
+	if a.UTM != nil {
+		b, _ := json.Marshal(a.UTM)
+		utmJSON = string(b)
+	}
+	if err := repository.SetOrderAttribution(db, orderID, a.Channel, utmJSON); err != nil {
+		logger.Warnf("erro ao salvar origem do pedido %s (não-fatal): %v", orderID, err)
+	}
+}
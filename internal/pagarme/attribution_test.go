@@ -0,0 +1,109 @@
+package pagarme
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/tickets"
+)
+
+func TestNormalizeAttribution(t *testing.T) {
+	for in, want := range map[string]string{"": "", " Instagram ": "instagram", "TIKTOK": "tiktok", "outdoor": "other"} {
+		if got := NormalizeChannel(in); got != want {
+			t.Errorf("NormalizeChannel(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	a := Attribution{UTM: &UTM{Source: "Instagram", Campaign: " " + strings.Repeat("x", 150)}}.normalize()
+	if a.Channel != "instagram" {
+		t.Errorf("channel from utm_source = %q, want instagram", a.Channel)
+	}
+	if n := len(a.UTM.Campaign); n != maxUTMValueLength {
+		t.Errorf("campaign length = %d, want %d", n, maxUTMValueLength)
+	}
+	// An explicit channel wins over utm_source; unknown sources don't set one.
+	if a := (Attribution{Channel: "email", UTM: &UTM{Source: "google"}}).normalize(); a.Channel != "email" {
+		t.Errorf("explicit channel = %q, want email", a.Channel)
+	}
+	if a := (Attribution{UTM: &UTM{Source: "newsletter"}}).normalize(); a.Channel != "" || a.UTM.Source != "newsletter" {
+		t.Errorf("unknown source = %+v", a)
+	}
+	if a := (Attribution{UTM: &UTM{Source: "  "}}).normalize(); a.UTM != nil {
+		t.Errorf("blank utm kept: %+v", a.UTM)
+	}
+}
+
+func TestSalesByChannel(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 2, 20, 50)
+	items, _ := repository.OrderItemsByOrderID(sqlite, orderID)
+	item := items[0]
+	var eventID string
+	sqlite.QueryRow(`SELECT event_id FROM event_dates WHERE id = ?`, item.EventDateID).Scan(&eventID)
+
+	h := NewHandler(newFakeClient(), sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	guestCheckout := func(email, cpf, extra string) string {
+		t.Helper()
+		body := `{"guest":{"name":"Convidado","email":"` + email + `","cpf":"` + cpf + `","phoneAreaCode":"11","phoneNumber":"987654321"},` +
+			`"items":[{"eventDateId":"` + item.EventDateID + `","ticketTypeId":"` + item.TicketTypeID + `","quantity":1}]` + extra + `}`
+		rec := httptest.NewRecorder()
+		h.CreatePayment(rec, httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("checkout: status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		var res PixOrderResult
+		json.NewDecoder(rec.Body).Decode(&res)
+		return res.OrderID
+	}
+	insta := guestCheckout("a@email.com", "16899535009", `,"channel":"Instagram","utm":{"source":"ig","campaign":"verao"}`)
+	fromUTM := guestCheckout("b@email.com", "39053344705", `,"utm":{"source":"instagram"}`)
+	guestCheckout("c@email.com", "71428793860", `,"channel":"whatsapp"`) // stays PENDING
+
+	var channel, utm string
+	sqlite.QueryRow(`SELECT channel, utm FROM orders WHERE id = ?`, insta).Scan(&channel, &utm)
+	if channel != "instagram" || utm != `{"source":"ig","campaign":"verao"}` {
+		t.Errorf("stored attribution = %q %q", channel, utm)
+	}
+
+	for _, id := range []string{orderID, insta} {
+		if _, err := sqlite.Exec(`UPDATE orders SET status = 'PAID' WHERE id = ?`, id); err != nil {
+			t.Fatalf("mark paid: %v", err)
+		}
+	}
+	// Legacy CONFIRMED orders count as paid, and revenue is what the order
+	// charged (here after a R$10 discount), not the list price.
+	if _, err := sqlite.Exec(`UPDATE orders SET status = 'CONFIRMED', total = total - 10 WHERE id = ?`, fromUTM); err != nil {
+		t.Fatalf("mark confirmed: %v", err)
+	}
+	// Comps are PAID orders too, but not sales.
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	if _, err := tickets.IssueComplimentaryTickets(sqlite, &config.Config{JWTSecret: "test-secret"}, nil, tickets.CompRequest{
+		ProducerUserID: producer.ID, EventDateID: item.EventDateID, TicketTypeID: item.TicketTypeID,
+		Recipients: []tickets.CompRecipient{{Email: "comprador@email.com"}},
+	}); err != nil {
+		t.Fatalf("IssueComplimentaryTickets: %v", err)
+	}
+
+	sales, err := repository.SalesByChannel(sqlite, eventID, ChannelDirect)
+	if err != nil {
+		t.Fatalf("SalesByChannel: %v", err)
+	}
+	want := []repository.ChannelSales{
+		{Channel: "direct", Orders: 1, Tickets: 2, Revenue: 100},
+		{Channel: "instagram", Orders: 2, Tickets: 2, Revenue: 90},
+	}
+	if len(sales) != len(want) {
+		t.Fatalf("sales = %+v, want %+v", sales, want)
+	}
+	for i := range want {
+		if sales[i] != want[i] {
+			t.Errorf("sales[%d] = %+v, want %+v", i, sales[i], want[i])
+		}
+	}
+}
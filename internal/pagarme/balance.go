@@ -0,0 +1,191 @@
+package pagarme
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+)
+
+const (
+	// balanceCacheTTL evita consultar o Pagar.me a cada atualização do painel
+	balanceCacheTTL = time.Minute
+	// recentTransfersLimit é a quantidade de transferências exibidas ao produtor
+	recentTransfersLimit = 10
+)
+
+// RecipientBalance is a recipient's balance in centavos.
+type RecipientBalance struct {
+	Currency           string `json:"currency"`
+	AvailableAmount    int64  `json:"availableAmount"`
+	WaitingFundsAmount int64  `json:"waitingFundsAmount"`
+	TransferredAmount  int64  `json:"transferredAmount"`
+}
+
+// Transfer is a payout from a recipient's balance to their bank account.
+type Transfer struct {
+	ID        string `json:"id"`
+	Amount    int64  `json:"amount"` // centavos
+	Status    string `json:"status"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// GetRecipientBalance retrieves the available and waiting-funds balance of a recipient.
+func (c *Client) GetRecipientBalance(recipientID string) (*RecipientBalance, error) {
+	result, err := c.doRequest("GET", "/recipients/"+recipientID+"/balance", nil)
+	if err != nil {
+		return nil, fmt.Errorf("get balance: %w", err)
+	}
+	currency, _ := result["currency"].(string)
+	available, _ := result["available_amount"].(float64)
+	waiting, _ := result["waiting_funds_amount"].(float64)
+	transferred, _ := result["transferred_amount"].(float64)
+	return &RecipientBalance{
+		Currency:           currency,
+		AvailableAmount:    int64(available),
+		WaitingFundsAmount: int64(waiting),
+		TransferredAmount:  int64(transferred),
+	}, nil
+}
+
+// ListTransfers returns the most recent transfers of a recipient.
+func (c *Client) ListTransfers(recipientID string) ([]Transfer, error) {
+	result, err := c.doRequest("GET", fmt.Sprintf("/recipients/%s/transfers?page=1&size=%d", recipientID, recentTransfersLimit), nil)
+	if err != nil {
+		return nil, fmt.Errorf("list transfers: %w", err)
+	}
+	data, _ := result["data"].([]interface{})
+	transfers := make([]Transfer, 0, len(data))
+	for _, item := range data {
+		t, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := t["id"].(string)
+		amount, _ := t["amount"].(float64)
+		status, _ := t["status"].(string)
+		createdAt, _ := t["created_at"].(string)
+		transfers = append(transfers, Transfer{ID: id, Amount: int64(amount), Status: status, CreatedAt: createdAt})
+	}
+	return transfers, nil
+}
+
+// RecipientBalanceResult is a producer's balance and recent transfers.
+// Shared by the REST endpoint and the GraphQL producerBalance query.
+type RecipientBalanceResult struct {
+	HasRecipient    bool              `json:"hasRecipient"`
+	RecipientID     string            `json:"recipientId,omitempty"`
+	RecipientStatus string            `json:"recipientStatus,omitempty"`
+	Balance         *RecipientBalance `json:"balance,omitempty"`
+	Transfers       []Transfer        `json:"transfers"`
+	Error           string            `json:"error,omitempty"`
+}
+
+type cachedBalance struct {
+	result    RecipientBalanceResult
+	fetchedAt time.Time
+}
+
+// balanceCache holds successful lookups per recipient for balanceCacheTTL.
+var balanceCache = struct {
+	sync.Mutex
+	entries map[string]cachedBalance
+}{entries: map[string]cachedBalance{}}
+
+// PruneBalanceCache drops expired balance cache entries. Run periodically so
+// recipients that stopped checking their balance don't stay in memory.
+func PruneBalanceCache() int {
+	balanceCache.Lock()
+	defer balanceCache.Unlock()
+	pruned := 0
+	for id, entry := range balanceCache.entries {
+		if time.Since(entry.fetchedAt) >= balanceCacheTTL {
+			delete(balanceCache.entries, id)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// LookupRecipientBalance returns the balance of the producer owned by userID.
+// Only active recipients have a balance; others get their status and a message.
+func LookupRecipientBalance(client PagarmeAPI, db *sql.DB, userID string) RecipientBalanceResult {
+	prodID, _ := repository.ProducerIDByUser(db, userID)
+	if prodID == "" {
+		return RecipientBalanceResult{Transfers: []Transfer{}}
+	}
+	recipientID, _ := repository.GetProducerPagarmeRecipientID(db, prodID)
+	if recipientID == "" {
+		return RecipientBalanceResult{Transfers: []Transfer{}}
+	}
+
+	balanceCache.Lock()
+	cached, ok := balanceCache.entries[recipientID]
+	balanceCache.Unlock()
+	if ok && time.Since(cached.fetchedAt) < balanceCacheTTL {
+		return cached.result
+	}
+
+	result := RecipientBalanceResult{HasRecipient: true, RecipientID: recipientID, Transfers: []Transfer{}}
+	if client == nil {
+		result.Error = "pagamentos não configurados"
+		return result
+	}
+
+	recipientData, err := client.GetRecipient(recipientID)
+	if err != nil {
+		logger.Errorf("erro ao obter recebedor %s no Pagar.me: %v", recipientID, err)
+		result.Error = "não foi possível consultar o saldo no Pagar.me"
+		return result
+	}
+	result.RecipientStatus, _ = recipientData["status"].(string)
+	if result.RecipientStatus != "active" {
+		result.Error = "saldo disponível após a ativação do recebedor no Pagar.me"
+		return result
+	}
+
+	balance, err := client.GetRecipientBalance(recipientID)
+	if err != nil {
+		logger.Errorf("erro ao obter saldo do recebedor %s: %v", recipientID, err)
+		result.Error = "não foi possível consultar o saldo no Pagar.me"
+		return result
+	}
+	result.Balance = balance
+
+	transfers, err := client.ListTransfers(recipientID)
+	if err != nil {
+		// Balance alone is still useful; don't cache so transfers are retried
+		logger.Errorf("erro ao listar transferências do recebedor %s: %v", recipientID, err)
+		result.Error = "não foi possível consultar as transferências no Pagar.me"
+		return result
+	}
+	result.Transfers = transfers
+
+	balanceCache.Lock()
+	balanceCache.entries[recipientID] = cachedBalance{result: result, fetchedAt: time.Now()}
+	balanceCache.Unlock()
+	return result
+}
+
+// GetRecipientBalance handles GET /v1/recipient/balance
+// Returns the producer's available/waiting balance and recent transfers.
+func (h *Handler) GetRecipientBalance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	userID := middleware.UserID(r.Context())
+	if userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return
+	}
+
+	rest.JSON(w, http.StatusOK, LookupRecipientBalance(h.client, h.db, userID))
+}
@@ -0,0 +1,28 @@
+package pagarme
+
+import (
+	"testing"
+
+	"afterzin/api/internal/repository"
+)
+
+func TestLookupRecipientBalanceCaches(t *testing.T) {
+	sqlite := newTestDB(t)
+	seedPendingOrder(t, sqlite, 1, 10, 50)
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	fake := newFakeClient()
+
+	first := LookupRecipientBalance(fake, sqlite, producer.ID)
+	if first.Error != "" || first.Balance == nil || first.Balance.AvailableAmount != 15000 || len(first.Transfers) != 1 {
+		t.Fatalf("unexpected result: %+v", first)
+	}
+	LookupRecipientBalance(fake, sqlite, producer.ID)
+	if n := fake.callCount("GetRecipientBalance"); n != 1 {
+		t.Errorf("balance calls = %d, want 1 (cached)", n)
+	}
+
+	buyer, _ := repository.UserByEmail(sqlite, "comprador@email.com")
+	if res := LookupRecipientBalance(fake, sqlite, buyer.ID); res.HasRecipient {
+		t.Errorf("non-producer got a recipient: %+v", res)
+	}
+}
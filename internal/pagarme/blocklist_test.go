@@ -0,0 +1,50 @@
+package pagarme
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/blocklist"
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestBlockedBuyerCannotCreatePayment(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+
+	pay := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.CreatePayment(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(`{"orderId":"`+orderID+`"}`)), buyerID))
+		return rec
+	}
+
+	if err := repository.BlockIdentity(sqlite, repository.BlockedCPF, testutil.BuyerCPF, "chargeback", ""); err != nil {
+		t.Fatal(err)
+	}
+	rec := pay()
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), blocklist.ErrIdentityBlocked.Error()) {
+		t.Errorf("blocked buyer: status = %d, body = %s; want 403 with the generic error", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), testutil.BuyerCPF) || strings.Contains(strings.ToLower(rec.Body.String()), "bloque") {
+		t.Errorf("response leaks the denylist: %s", rec.Body.String())
+	}
+	if fake.callCount("CreatePixOrder") != 0 {
+		t.Error("gateway called for a blocked buyer")
+	}
+
+	// Unblocking applies to the next request, without a restart.
+	if _, err := repository.UnblockIdentity(sqlite, repository.BlockedCPF, testutil.BuyerCPF); err != nil {
+		t.Fatal(err)
+	}
+	if rec := pay(); rec.Code != http.StatusOK {
+		t.Errorf("after unblock: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,85 @@
+package pagarme
+
+import "strings"
+
+// ChargeStatus is the lifecycle state of an order's charge at Pagar.me.
+//
+// Card charges go pending → authorized → captured → paid; PIX charges are
+// captured at payment time and go straight from pending to paid.
+type ChargeStatus string
+
+const (
+	ChargePending    ChargeStatus = "pending"
+	ChargeAuthorized ChargeStatus = "authorized" // funds held, awaiting capture
+	ChargeCaptured   ChargeStatus = "captured"   // capture requested, settlement pending
+	ChargePaid       ChargeStatus = "paid"
+	ChargeFailed     ChargeStatus = "failed"
+	ChargeCanceled   ChargeStatus = "canceled"
+)
+
+// chargeTransitions lists the states each charge state may move to. Paid,
+// failed and canceled are final: a new attempt creates a new charge.
+var chargeTransitions = map[ChargeStatus][]ChargeStatus{
+	ChargePending:    {ChargeAuthorized, ChargePaid, ChargeFailed, ChargeCanceled},
+	ChargeAuthorized: {ChargeCaptured, ChargePaid, ChargeFailed, ChargeCanceled},
+	ChargeCaptured:   {ChargePaid, ChargeFailed},
+}
+
+// CanTransitionTo reports whether a charge in state s may move to next.
+// An unknown (empty) current state is treated as pending.
+func (s ChargeStatus) CanTransitionTo(next ChargeStatus) bool {
+	if s == "" {
+		s = ChargePending
+	}
+	for _, allowed := range chargeTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseChargeStatus maps a Pagar.me charge or transaction status to a
+// ChargeStatus. ok is false for statuses outside the lifecycle (e.g.
+// chargedback), which callers should log and leave alone.
+func ParseChargeStatus(raw string) (status ChargeStatus, ok bool) {
+	switch strings.ToLower(raw) {
+	case "pending", "processing", "waiting_payment", "generated":
+		return ChargePending, true
+	case "authorized_pending_capture", "waiting_capture":
+		return ChargeAuthorized, true
+	case "captured", "partial_capture":
+		return ChargeCaptured, true
+	case "paid":
+		return ChargePaid, true
+	case "failed", "not_authorized", "with_error":
+		return ChargeFailed, true
+	case "canceled", "voided":
+		return ChargeCanceled, true
+	}
+	return "", false
+}
+
+// PaymentMethod describes how charges of a payment method settle.
+type PaymentMethod struct {
+	Name string
+	// ImmediateCapture is true when payment and capture are one step, so the
+	// authorized and captured states are never observed (PIX).
+	ImmediateCapture bool
+}
+
+// paymentMethods lists the methods the platform can charge with. Card
+// payments will be added here with ImmediateCapture false.
+var paymentMethods = map[string]PaymentMethod{
+	AllowedPaymentMethod: {Name: AllowedPaymentMethod, ImmediateCapture: true},
+}
+
+// paymentMethodByName returns the PaymentMethod for a Pagar.me payment_method
+// value. Unknown methods are assumed to authorize before capturing, so no
+// immediate-paid assumption is made for them.
+func paymentMethodByName(name string) PaymentMethod {
+	if m, ok := paymentMethods[name]; ok {
+		return m
+	}
+	return PaymentMethod{Name: name}
+}
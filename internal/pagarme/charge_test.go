@@ -0,0 +1,126 @@
+package pagarme
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+)
+
+func TestChargeStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to ChargeStatus
+		want     bool
+	}{
+		{"", ChargePaid, true},
+		{ChargePending, ChargePaid, true},
+		{ChargePending, ChargeAuthorized, true},
+		{ChargeAuthorized, ChargeCaptured, true},
+		{ChargeCaptured, ChargePaid, true},
+		{ChargeCaptured, ChargeAuthorized, false},
+		{ChargePaid, ChargeAuthorized, false},
+		{ChargeCanceled, ChargePaid, false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%q → %q = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	if s, ok := ParseChargeStatus("authorized_pending_capture"); !ok || s != ChargeAuthorized {
+		t.Errorf("ParseChargeStatus(authorized_pending_capture) = %q, %v", s, ok)
+	}
+	if _, ok := ParseChargeStatus("chargedback"); ok {
+		t.Error("chargedback should be outside the lifecycle")
+	}
+}
+
+func TestChargeAuthorizedWaitsForCapture(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 2, 10, 50)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+
+	fake := newFakeClient()
+	fake.paidAmounts["or_card"] = 10000
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	post := func(eventID, typ, method string) {
+		t.Helper()
+		body := `{"id":"` + eventID + `","type":"` + typ + `","data":{"id":"ch_card","payment_method":"` + method + `",` +
+			`"order":{"id":"or_card","code":"` + orderID + `"}}}`
+		rec := httptest.NewRecorder()
+		h.HandleWebhook(rec, httptest.NewRequest(http.MethodPost, "/v1/webhook", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body = %s", typ, rec.Code, rec.Body.String())
+		}
+	}
+	chargeStatus := func() string {
+		s, _ := repository.GetOrderChargeStatus(sqlite, orderID)
+		return s
+	}
+
+	// PIX captures on payment: an authorization event changes nothing.
+	post("hook_pix", "charge.authorized", "pix")
+	if got := chargeStatus(); got != "" {
+		t.Fatalf("charge status after PIX authorization = %q, want unset", got)
+	}
+
+	post("hook_auth", "charge.authorized", "credit_card")
+	if got := chargeStatus(); got != string(ChargeAuthorized) {
+		t.Fatalf("charge status = %q, want authorized", got)
+	}
+	if _, status, _, _ := repository.OrderByID(sqlite, orderID); status != "PENDING" {
+		t.Fatalf("order status = %s, want PENDING until capture", status)
+	}
+	if n := countRows(t, sqlite, `SELECT COUNT(*) FROM tickets WHERE order_id = ?`, orderID); n != 0 {
+		t.Fatalf("tickets issued before capture: %d", n)
+	}
+	rec := httptest.NewRecorder()
+	h.GetPaymentStatus(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/payment/status?orderId="+orderID, nil), buyerID))
+	var st map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&st)
+	if st["status"] != "authorized" || st["paid"] != false {
+		t.Fatalf("payment status = %v, want authorized/unpaid", st)
+	}
+
+	post("hook_paid", "charge.paid", "credit_card")
+	if got := chargeStatus(); got != string(ChargePaid) {
+		t.Fatalf("charge status after capture = %q, want paid", got)
+	}
+	if n := countRows(t, sqlite, `SELECT COUNT(*) FROM tickets WHERE order_id = ?`, orderID); n != 2 {
+		t.Fatalf("tickets = %d, want 2", n)
+	}
+
+	// A late authorization can't move a paid charge backwards.
+	post("hook_late", "charge.authorized", "credit_card")
+	if got := chargeStatus(); got != string(ChargePaid) {
+		t.Fatalf("charge status after late authorization = %q, want paid", got)
+	}
+}
+
+func TestPaymentOnFinalChargeIsNotConfirmed(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	if err := repository.SetOrderChargeStatus(sqlite, orderID, string(ChargeCanceled)); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := newFakeClient()
+	fake.paidAmounts["or_test"] = 5000
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	postWebhook(t, h, "hook_1", orderID, "or_test")
+
+	if _, status, _, _ := repository.OrderByID(sqlite, orderID); status != "PENDING" {
+		t.Errorf("order status = %s, want PENDING", status)
+	}
+	if n := countRows(t, sqlite, `SELECT COUNT(*) FROM tickets WHERE order_id = ?`, orderID); n != 0 {
+		t.Errorf("tickets issued for a canceled charge: %d", n)
+	}
+	if s, _ := repository.GetOrderChargeStatus(sqlite, orderID); s != string(ChargeCanceled) {
+		t.Errorf("charge status = %q, want canceled", s)
+	}
+}
@@ -0,0 +1,79 @@
+package pagarme
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+)
+
+func TestCreatePaymentWithItemsCreatesOrderInOneCall(t *testing.T) {
+	sqlite := newTestDB(t)
+	seeded, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	items, _ := repository.OrderItemsByOrderID(sqlite, seeded)
+	buyer, _ := repository.UserByEmail(sqlite, "comprador@email.com")
+
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	pay := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.CreatePayment(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(body)), buyer.ID))
+		return rec
+	}
+	cart := `"items":[{"eventDateId":"` + items[0].EventDateID + `","ticketTypeId":"` + items[0].TicketTypeID + `","quantity":2}]`
+	orders := func(status string) int {
+		return countRows(t, sqlite, `SELECT COUNT(*) FROM orders WHERE user_id = ? AND status = ?`, buyer.ID, status)
+	}
+
+	rec := pay(`{` + cart + `}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var res PixOrderResult
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	if res.OrderID == "" || res.OrderID == seeded || res.PixQRCode == "" || res.OrderRef == "" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if fake.lastPixOrder.OrderID != res.OrderID || fake.lastPixOrder.TotalTickets != 2 {
+		t.Errorf("PIX order = %+v", fake.lastPixOrder)
+	}
+	if n := countRows(t, sqlite, `SELECT COUNT(*) FROM order_items WHERE order_id = ?`, res.OrderID); n != 1 {
+		t.Errorf("order items = %d, want 1", n)
+	}
+	if got, _ := repository.GetOrderPagarmeOrderID(sqlite, res.OrderID); got != "or_"+res.OrderID {
+		t.Errorf("pagarme order id = %q", got)
+	}
+
+	if rec := pay(`{"orderId":"` + seeded + `",` + cart + `}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("orderId and items: status = %d, want 400", rec.Code)
+	}
+
+	// A gateway failure cancels the order created by the call.
+	fake.pixErr = errors.New("gateway indisponível")
+	if rec := pay(`{` + cart + `}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("gateway failure: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if n := orders("CANCELLED"); n != 1 {
+		t.Fatalf("cancelled orders = %d, want 1", n)
+	}
+	if n := orders("PENDING"); n != 2 {
+		t.Errorf("pending orders = %d, want 2 (seeded + paid-for)", n)
+	}
+	if n := countRows(t, sqlite, `SELECT COUNT(*) FROM order_status_history WHERE new_status = 'CANCELLED' AND reason = 'checkout_failed'`); n != 1 {
+		t.Errorf("cancellation history rows = %d, want 1", n)
+	}
+
+	// The orderId mode is unchanged and never cancels an existing order.
+	if rec := pay(`{"orderId":"` + seeded + `"}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("orderId mode: status = %d", rec.Code)
+	}
+	if order, _ := repository.AuthorizeOrderAccess(sqlite, seeded, buyer.ID); order == nil || order.Status != "PENDING" {
+		t.Errorf("existing order was changed: %+v", order)
+	}
+}
@@ -21,6 +21,9 @@ type Client struct {
 	PlatformRecipientID string // Pagar.me recipient ID for the Afterzin platform
 	ApplicationFee      int64  // centavos per ticket (default 500 = R$5.00)
 	BaseURL             string // platform frontend URL for redirects
+	APIPrefix           string // path prefix the REST routes are mounted under (default "/v1")
+	Env                 string // "test" or "live", derived from the API key
+	apiURL              string // Pagar.me API base URL (overridable in tests)
 	httpClient          *http.Client
 }
 
@@ -38,6 +41,9 @@ func NewClient(apiKey, webhookSecret, platformRecipientID string, applicationFee
 		PlatformRecipientID: platformRecipientID,
 		ApplicationFee:      applicationFee,
 		BaseURL:             strings.TrimRight(baseURL, "/"),
+		APIPrefix:           DefaultAPIPrefix,
+		Env:                 EnvironmentForKey(apiKey),
+		apiURL:              apiBaseURL,
 		httpClient:          &http.Client{},
 	}
 }
@@ -51,7 +57,7 @@ func (c *Client) authHeader() string {
 
 // doRequest makes a JSON request to the Pagar.me V5 API.
 func (c *Client) doRequest(method, path string, body interface{}) (map[string]interface{}, error) {
-	url := apiBaseURL + path
+	url := c.apiURL + path
 
 	var reqBody io.Reader
 	if body != nil {
@@ -14,6 +14,12 @@ const (
 
 	// AllowedCustomerType define o tipo de cliente aceito
 	AllowedCustomerType = "individual"
+
+	// DefaultAPIPrefix é o prefixo das rotas REST quando API_PREFIX não é definido
+	DefaultAPIPrefix = "/v1"
+
+	// WebhookPath é o caminho, relativo ao prefixo da API, do endpoint que recebe os webhooks do Pagar.me
+	WebhookPath = "/webhook"
 )
 
 // ValidatePaymentMethod verifica se o método de pagamento fornecido é válido.
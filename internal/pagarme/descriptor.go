@@ -0,0 +1,94 @@
+package pagarme
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"afterzin/api/internal/repository"
+)
+
+// Gateway limits for the text shown to the buyer.
+const (
+	// MaxStatementDescriptorLength is the longest statement descriptor
+	// Pagar.me accepts (13 characters).
+	MaxStatementDescriptorLength = 13
+	// maxPaymentDescriptionLength caps the description sent with the charge.
+	maxPaymentDescriptionLength = 100
+)
+
+// defaultPlatformName is used when PLATFORM_NAME is unset.
+const defaultPlatformName = "Afterzin"
+
+// accentFold maps pt-BR accented letters to ASCII for statement descriptors,
+// which only take unaccented letters, digits, spaces, "." and "-".
+var accentFold = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A",
+	"É", "E", "È", "E", "Ê", "E", "Ë", "E",
+	"Í", "I", "Ì", "I", "Î", "I", "Ï", "I",
+	"Ó", "O", "Ò", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U",
+	"Ç", "C", "Ñ", "N",
+)
+
+// SanitizeStatementDescriptor makes s acceptable as a statement descriptor:
+// accents folded, other characters outside [A-Za-z0-9 .-] dropped, spaces
+// collapsed and the result cut to MaxStatementDescriptorLength.
+// Returns "" when nothing usable is left.
+func SanitizeStatementDescriptor(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range accentFold.Replace(s) {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-'):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			space = true
+		}
+	}
+	out := b.String()
+	if len(out) > MaxStatementDescriptorLength {
+		out = strings.TrimSpace(out[:MaxStatementDescriptorLength])
+	}
+	return out
+}
+
+// statementDescriptor returns the descriptor for a producer's charges: the
+// producer's override, else STATEMENT_DESCRIPTOR, else the platform name.
+func (h *Handler) statementDescriptor(producerID string) string {
+	if prod, _ := repository.ProducerByID(h.db, producerID); prod != nil && prod.StatementDescriptor.String != "" {
+		return prod.StatementDescriptor.String
+	}
+	if d := SanitizeStatementDescriptor(h.cfg.StatementDescriptor); d != "" {
+		return d
+	}
+	if d := SanitizeStatementDescriptor(h.cfg.PlatformName); d != "" {
+		return d
+	}
+	return SanitizeStatementDescriptor(defaultPlatformName)
+}
+
+// paymentDescription builds the charge description ("Afterzin - Show X"),
+// without control characters and cut to maxPaymentDescriptionLength runes.
+func paymentDescription(platformName, eventTitle string) string {
+	if platformName == "" {
+		platformName = defaultPlatformName
+	}
+	desc := strings.Join(strings.FieldsFunc(platformName+" - "+eventTitle, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}), " ")
+	if utf8.RuneCountInString(desc) > maxPaymentDescriptionLength {
+		desc = strings.TrimSpace(string([]rune(desc)[:maxPaymentDescriptionLength]))
+	}
+	return desc
+}
@@ -0,0 +1,124 @@
+package pagarme
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+)
+
+func TestSanitizeStatementDescriptor(t *testing.T) {
+	cases := map[string]string{
+		"Afterzin":               "Afterzin",
+		"  Forró   do Zé  ":      "Forro do Ze",
+		"Ação & Música!":         "Acao Musica",
+		"Produções Muito Longas": "Producoes Mui",
+		"ABCDEFGHIJKL MNOP":      "ABCDEFGHIJKL",
+		"Show.Bar-SP":            "Show.Bar-SP",
+		"🎉🎉":                     "",
+		"":                       "",
+	}
+	for in, want := range cases {
+		got := SanitizeStatementDescriptor(in)
+		if got != want {
+			t.Errorf("SanitizeStatementDescriptor(%q) = %q, want %q", in, got, want)
+		}
+		if len(got) > MaxStatementDescriptorLength {
+			t.Errorf("SanitizeStatementDescriptor(%q) too long: %d", in, len(got))
+		}
+	}
+}
+
+func TestPaymentDescription(t *testing.T) {
+	if got := paymentDescription("", "Show\tde\nVerão"); got != "Afterzin - Show de Verão" {
+		t.Errorf("default platform = %q", got)
+	}
+	if got := paymentDescription("Minha Plataforma", "Festa"); got != "Minha Plataforma - Festa" {
+		t.Errorf("configured platform = %q", got)
+	}
+	long := paymentDescription("Afterzin", strings.Repeat("ã", 200))
+	if n := len([]rune(long)); n != maxPaymentDescriptionLength {
+		t.Errorf("long description has %d runes, want %d", n, maxPaymentDescriptionLength)
+	}
+}
+
+func TestCreatePaymentSendsDescriptor(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+	fake := newFakeClient()
+	cfg := &config.Config{JWTSecret: "test-secret", PlatformName: "Ingressos Já", StatementDescriptor: "Ingressos Já!"}
+	h := NewHandler(fake, sqlite, cfg, mailer.LogMailer{})
+	pay := func() {
+		t.Helper()
+		sqlite.Exec(`UPDATE orders SET pagarme_order_id = NULL, payment_started_at = NULL WHERE id = ?`, orderID)
+		rec := httptest.NewRecorder()
+		h.CreatePayment(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(`{"orderId":"`+orderID+`"}`)), buyerID))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	pay()
+	if got := fake.lastPixOrder.StatementDescriptor; got != "Ingressos Ja" {
+		t.Errorf("platform descriptor = %q", got)
+	}
+	if got := fake.lastPixOrder.Description; !strings.HasPrefix(got, "Ingressos Já - ") {
+		t.Errorf("description = %q", got)
+	}
+
+	// The producer's override wins.
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	prodID, _ := repository.ProducerIDByUser(sqlite, producer.ID)
+	descriptor := "Casa Show"
+	if err := repository.UpdateProducerProfile(sqlite, prodID, repository.ProducerProfile{StatementDescriptor: &descriptor}); err != nil {
+		t.Fatalf("UpdateProducerProfile: %v", err)
+	}
+	pay()
+	if got := fake.lastPixOrder.StatementDescriptor; got != "Casa Show" {
+		t.Errorf("producer descriptor = %q", got)
+	}
+}
+
+func TestCreatePixOrderSendsAdditionalInformation(t *testing.T) {
+	var sent map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"or_1","status":"pending"}`))
+	}))
+	defer server.Close()
+	c := NewClient("sk_test", "", "", 500, "https://api.afterzin.com/")
+	c.apiURL = server.URL
+
+	_, err := c.CreatePixOrder(PixOrderParams{
+		OrderID:             "o1",
+		AmountCentavos:      1000,
+		TotalTickets:        1,
+		Description:         "Afterzin - Festa",
+		StatementDescriptor: "AFTERZIN",
+		CustomerDocument:    "52998224725",
+		CustomerName:        "Maria Silva",
+		CustomerEmail:       "maria@example.com",
+		Items:               []OrderItem{{Code: "tt", Description: "x", Quantity: 1, Amount: 1000}},
+	})
+	if err != nil {
+		t.Fatalf("CreatePixOrder: %v", err)
+	}
+	payment := sent["payments"].([]interface{})[0].(map[string]interface{})
+	info, _ := payment["pix"].(map[string]interface{})["additional_information"].([]interface{})
+	if len(info) != 2 {
+		t.Fatalf("additional_information = %v", payment["pix"])
+	}
+	if first := info[0].(map[string]interface{}); first["value"] != "AFTERZIN" {
+		t.Errorf("descriptor entry = %v", first)
+	}
+	if second := info[1].(map[string]interface{}); second["value"] != "Afterzin - Festa" {
+		t.Errorf("description entry = %v", second)
+	}
+}
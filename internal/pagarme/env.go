@@ -0,0 +1,40 @@
+package pagarme
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// EnvTest identifica chaves de teste (prefixo sk_test_)
+	EnvTest = "test"
+	// EnvLive identifica chaves de produção (prefixo sk_)
+	EnvLive = "live"
+)
+
+// EnvironmentForKey returns the environment implied by a secret key prefix.
+func EnvironmentForKey(apiKey string) string {
+	if strings.HasPrefix(apiKey, "sk_test_") {
+		return EnvTest
+	}
+	return EnvLive
+}
+
+// ValidateEnvironment checks that the configured environment matches the key prefix.
+// An empty env is accepted and resolved from the key.
+func ValidateEnvironment(env, apiKey string) (string, error) {
+	keyEnv := EnvironmentForKey(apiKey)
+	if env == "" {
+		return keyEnv, nil
+	}
+	if env != EnvTest && env != EnvLive {
+		return "", fmt.Errorf("PAGARME_ENV inválido: esperado '%s' ou '%s', recebido '%s'", EnvTest, EnvLive, env)
+	}
+	if !strings.HasPrefix(apiKey, "sk_") {
+		return "", fmt.Errorf("PAGARME_API_KEY inválida: esperado prefixo sk_")
+	}
+	if env != keyEnv {
+		return "", fmt.Errorf("PAGARME_ENV=%s não corresponde à chave configurada (chave de %s)", env, keyEnv)
+	}
+	return env, nil
+}
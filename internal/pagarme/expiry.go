@@ -0,0 +1,73 @@
+package pagarme
+
+import (
+	"database/sql"
+	"time"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/repository"
+)
+
+// pixExpiryGrace is added to the PIX lifetime before an unpaid order is
+// cancelled, so a payment made in the last seconds can still be confirmed
+// by a webhook that arrives late.
+const pixExpiryGrace = 5 * time.Minute
+
+// ExpirePendingOrders is the body of the order-expiry job. It cancels, with
+// distinct reasons in the status history:
+//   - abandoned: PENDING orders that never got a PIX, abandonedAge after they
+//     were created (0 leaves them to the data-retention job);
+//   - pix_expired: PENDING orders whose PIX has expired, i.e. once the
+//     expiry of the order's current PIX (pix_expires_at, as returned by
+//     Pagar.me) plus a grace period is past. A PIX generated again for an
+//     older order is only expired when that new PIX is.
+//
+// Pending orders hold no inventory (lots are decremented on payment), so
+// cancelling them is all it takes to free their places for other buyers.
+func ExpirePendingOrders(db *sql.DB, abandonedAge time.Duration, now time.Time) error {
+	if abandonedAge > 0 {
+		ids, err := repository.AbandonedOrderIDs(db, now.Add(-abandonedAge))
+		if err != nil {
+			return err
+		}
+		if n := cancelExpiredOrders(db, ids, repository.CancelReasonAbandoned, "pedido abandonado antes do pagamento"); n > 0 {
+			logger.Infof("expiração: %d pedido(s) abandonado(s) sem PIX cancelado(s)", n)
+		}
+	}
+
+	ids, err := repository.PixExpiredOrderIDs(db, now.Add(-pixExpiryGrace))
+	if err != nil {
+		return err
+	}
+	if n := cancelExpiredOrders(db, ids, repository.CancelReasonPixExpired, "PIX expirado sem pagamento"); n > 0 {
+		logger.Infof("expiração: %d pedido(s) com PIX expirado cancelado(s)", n)
+	}
+	return nil
+}
+
+// pixExpiresAt parses the expiry Pagar.me returned for a new PIX, falling
+// back to the PIX lifetime from now when it is missing or malformed.
+func pixExpiresAt(expiresAt string, now time.Time) time.Time {
+	if at, err := time.Parse(time.RFC3339, expiresAt); err == nil {
+		return at
+	}
+	return now.Add(PixExpirationSeconds * time.Second)
+}
+
+// cancelExpiredOrders cancels each order that is still PENDING and returns
+// how many were. A webhook confirming one of them in the meantime wins: the
+// order is no longer PENDING and is skipped.
+func cancelExpiredOrders(db *sql.DB, ids []string, reason, message string) int {
+	n := 0
+	for _, id := range ids {
+		cancelled, err := repository.CancelPendingOrder(db, id, reason, message)
+		if err != nil {
+			logger.Errorf("erro ao cancelar pedido %s (%s): %v", id, reason, err)
+			continue
+		}
+		if cancelled {
+			n++
+		}
+	}
+	return n
+}
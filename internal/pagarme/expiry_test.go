@@ -0,0 +1,89 @@
+package pagarme
+
+import (
+	"testing"
+	"time"
+
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestExpirePendingOrders(t *testing.T) {
+	sqlite := newTestDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	now := time.Now()
+
+	order := func(createdAgo time.Duration, pagarmeOrderID string) string {
+		t.Helper()
+		id := f.PendingOrder(t, sqlite, 1)
+		created := now.Add(-createdAgo)
+		if _, err := sqlite.Exec(`UPDATE orders SET created_at = ?, expires_at = ?, pagarme_order_id = NULLIF(?, '') WHERE id = ?`,
+			repository.FormatTime(created), repository.FormatTime(created.Add(30*time.Minute)), pagarmeOrderID, id); err != nil {
+			t.Fatal(err)
+		}
+		if pagarmeOrderID != "" {
+			// PIX generated with the order
+			if err := repository.SetOrderPix(sqlite, id, "00020126pix", "", created.Add(PixExpirationSeconds*time.Second)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return id
+	}
+	abandoned := order(31*time.Minute, "")
+	fresh := order(10*time.Minute, "")
+	pixExpired := order(time.Hour, "or_expired")
+	// PIX expired a moment ago, but may still have been paid.
+	pixSettling := order(18*time.Minute, "or_settling")
+	// Old order whose PIX was generated again a few minutes ago: still payable.
+	pixRenewed := order(2*time.Hour, "or_renewed")
+	if err := repository.SetOrderPix(sqlite, pixRenewed, "00020126pix", "", pixExpiresAt("", now.Add(-5*time.Minute))); err != nil {
+		t.Fatal(err)
+	}
+	paid := order(2*time.Hour, "or_paid")
+	if err := repository.ConfirmOrder(sqlite, paid); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ExpirePendingOrders(sqlite, 30*time.Minute, now); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+
+	for id, want := range map[string]string{
+		abandoned:   "CANCELLED",
+		fresh:       "PENDING",
+		pixExpired:  "CANCELLED",
+		pixSettling: "PENDING",
+		pixRenewed:  "PENDING",
+		paid:        "PAID",
+	} {
+		if _, status, _, _ := repository.OrderByID(sqlite, id); status != want {
+			t.Errorf("order %s: status = %s, want %s", id, status, want)
+		}
+	}
+	for id, reason := range map[string]string{abandoned: repository.CancelReasonAbandoned, pixExpired: repository.CancelReasonPixExpired} {
+		if got := countRows(t, sqlite, `SELECT COUNT(*) FROM order_status_history WHERE order_id = ? AND new_status = 'CANCELLED' AND reason = ?`, id, reason); got != 1 {
+			t.Errorf("order %s: %d history entries with reason %s, want 1", id, got, reason)
+		}
+	}
+
+	// With the abandoned age disabled, orders without a PIX are left alone.
+	stale := order(3*time.Hour, "")
+	if err := ExpirePendingOrders(sqlite, 0, now); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	if _, status, _, _ := repository.OrderByID(sqlite, stale); status != "PENDING" {
+		t.Errorf("abandoned age disabled: status = %s, want PENDING", status)
+	}
+}
+
+func TestPixExpiresAt(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if got := pixExpiresAt("2025-03-01T12:15:00Z", now); !got.Equal(now.Add(15 * time.Minute)) {
+		t.Errorf("gateway expiry = %s", got)
+	}
+	for _, v := range []string{"", "amanhã"} {
+		if got := pixExpiresAt(v, now); !got.Equal(now.Add(PixExpirationSeconds * time.Second)) {
+			t.Errorf("pixExpiresAt(%q) = %s, want the PIX lifetime from now", v, got)
+		}
+	}
+}
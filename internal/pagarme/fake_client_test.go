@@ -0,0 +1,158 @@
+package pagarme
+
+import (
+	"fmt"
+	"sync"
+)
+
+// fakeClient is an in-memory PagarmeAPI used by handler tests.
+// Paid amounts are keyed by Pagar.me order ID; every call is counted.
+type fakeClient struct {
+	mu            sync.Mutex
+	paidAmounts   map[string]int64
+	settling      map[string][]int64 // amounts returned, in order, before paidAmounts
+	paidErr       error
+	pixErr        error // CreatePixOrder failure
+	fee           int64
+	calls         map[string]int
+	recipients    map[string]*RecipientResult
+	recipient     map[string]interface{} // GetRecipient payload override
+	lastRecipient CreateRecipientParams
+	balance       *RecipientBalance // GetRecipientBalance override
+	transfers     []Transfer        // ListTransfers override
+	orderStatus   string            // GetOrderStatus status override
+
+	lastPixOrder PixOrderParams
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		paidAmounts: map[string]int64{},
+		fee:         500,
+		calls:       map[string]int{},
+		recipients:  map[string]*RecipientResult{},
+	}
+}
+
+func (f *fakeClient) record(method string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[method]++
+}
+
+func (f *fakeClient) callCount(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+func (f *fakeClient) CreateRecipient(params CreateRecipientParams) (*RecipientResult, error) {
+	f.record("CreateRecipient")
+	rec := &RecipientResult{RecipientID: "re_fake", Status: "active", Name: params.Name}
+	f.mu.Lock()
+	f.lastRecipient = params
+	f.recipients[params.Document] = rec
+	f.mu.Unlock()
+	return rec, nil
+}
+
+func (f *fakeClient) FindRecipientByCode(code string) (*RecipientResult, error) {
+	f.record("FindRecipientByCode")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.recipients[code], nil
+}
+
+func (f *fakeClient) GetRecipient(recipientID string) (map[string]interface{}, error) {
+	f.record("GetRecipient")
+	if f.recipient != nil {
+		return f.recipient, nil
+	}
+	return map[string]interface{}{"id": recipientID, "status": "active"}, nil
+}
+
+func (f *fakeClient) DeactivateRecipient(recipientID string) error {
+	f.record("DeactivateRecipient")
+	return nil
+}
+
+func (f *fakeClient) GetRecipientBalance(recipientID string) (*RecipientBalance, error) {
+	f.record("GetRecipientBalance")
+	if f.balance != nil {
+		return f.balance, nil
+	}
+	return &RecipientBalance{Currency: "BRL", AvailableAmount: 15000, WaitingFundsAmount: 5000}, nil
+}
+
+func (f *fakeClient) ListTransfers(recipientID string) ([]Transfer, error) {
+	f.record("ListTransfers")
+	if f.transfers != nil {
+		return f.transfers, nil
+	}
+	return []Transfer{{ID: "tran_1", Amount: 10000, Status: "transferred"}}, nil
+}
+
+func (f *fakeClient) CreatePixOrder(params PixOrderParams) (*PixOrderResult, error) {
+	f.record("CreatePixOrder")
+	f.mu.Lock()
+	f.lastPixOrder = params
+	f.mu.Unlock()
+	if f.pixErr != nil {
+		return nil, f.pixErr
+	}
+	return &PixOrderResult{
+		PagarmeOrderID:  "or_" + params.OrderID,
+		PagarmeChargeID: "ch_" + params.OrderID,
+		PixQRCode:       "000201fake",
+		Status:          "pending",
+	}, nil
+}
+
+func (f *fakeClient) GetOrder(pagarmeOrderID string) (map[string]interface{}, error) {
+	f.record("GetOrder")
+	return map[string]interface{}{"id": pagarmeOrderID, "status": "pending"}, nil
+}
+
+func (f *fakeClient) GetOrderStatus(pagarmeOrderID string) (*PixOrderResult, error) {
+	f.record("GetOrderStatus")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status := "pending"
+	if f.orderStatus != "" {
+		status = f.orderStatus
+	}
+	return &PixOrderResult{PagarmeOrderID: pagarmeOrderID, Status: status}, nil
+}
+
+func (f *fakeClient) GetOrderPaidAmount(pagarmeOrderID string) (int64, error) {
+	f.record("GetOrderPaidAmount")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.paidErr != nil {
+		return 0, f.paidErr
+	}
+	if seq := f.settling[pagarmeOrderID]; len(seq) > 0 {
+		f.settling[pagarmeOrderID] = seq[1:]
+		return seq[0], nil
+	}
+	amount, ok := f.paidAmounts[pagarmeOrderID]
+	if !ok {
+		return 0, fmt.Errorf("order not paid (status: pending)")
+	}
+	return amount, nil
+}
+
+func (f *fakeClient) VerifyWebhookSignature(payload []byte, signatureHeader string) (*WebhookEvent, error) {
+	f.record("VerifyWebhookSignature")
+	return nil, fmt.Errorf("not supported by fake")
+}
+
+func (f *fakeClient) ApplicationFeePerTicket() int64 {
+	return f.fee
+}
+
+func (f *fakeClient) Environment() string {
+	return EnvTest
+}
+
+var _ PagarmeAPI = (*fakeClient)(nil)
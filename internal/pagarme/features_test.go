@@ -0,0 +1,52 @@
+package pagarme
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+)
+
+func TestFeatureFlagsGateEndpoints(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	items, _ := repository.OrderItemsByOrderID(sqlite, orderID)
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+
+	flags, _ := config.ParseFeatures("-guest_checkout,payout_reconciliation=false")
+	cfg := &config.Config{JWTSecret: "test-secret", Features: flags}
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, cfg, mailer.LogMailer{})
+
+	body := `{"guest":{"name":"Convidado","email":"convidado@email.com","cpf":"16899535009","phoneAreaCode":"11","phoneNumber":"987654321"},` +
+		`"items":[{"eventDateId":"` + items[0].EventDateID + `","ticketTypeId":"` + items[0].TicketTypeID + `","quantity":1}]}`
+	rec := httptest.NewRecorder()
+	h.CreatePayment(rec, httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("guest checkout disabled: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if u, _ := repository.UserByEmail(sqlite, "convidado@email.com"); u != nil {
+		t.Error("guest user created with guest checkout disabled")
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetRecipientPayouts(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/recipient/payouts", nil), producer.ID))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("payouts disabled: status = %d, want 404", rec.Code)
+	}
+	if n := fake.callCount("GetRecipientBalance"); n != 0 {
+		t.Errorf("gateway called %d times for a disabled endpoint", n)
+	}
+
+	// Flags not mentioned keep their defaults.
+	if !cfg.Feature(config.FeatureLivePaymentStatus) || cfg.Feature("boleto") {
+		t.Error("unexpected defaults for unlisted flags")
+	}
+	if got := strings.Join(cfg.EnabledFeatures(), ","); strings.Contains(got, config.FeatureGuestCheckout) {
+		t.Errorf("enabled features = %s", got)
+	}
+}
@@ -0,0 +1,53 @@
+package pagarme
+
+import (
+	"time"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/money"
+	"afterzin/api/internal/repository"
+)
+
+// settledPaidAmount fetches the amount Pagar.me reports as paid for an order
+// and, while it doesn't match the order total (or the order isn't reported
+// paid yet), fetches it again up to cfg.FraudCheckRetries times,
+// cfg.FraudCheckDelay apart. Right after payment Pagar.me may still report
+// a partial or in-progress amount; only a discrepancy that survives the
+// retries should become a fraud alert. Runs before the payment transaction
+// opens, so the waits never hold the database lock. They do delay the
+// acknowledgement Pagar.me is waiting for, so retries stop early once the
+// waits would add up to more than cfg.FraudCheckMaxWait (0 = no cap).
+func (h *Handler) settledPaidAmount(orderID, pagarmeOrderID string) (int64, error) {
+	paid, err := h.client.GetOrderPaidAmount(pagarmeOrderID)
+	if h.cfg.FraudCheckRetries <= 0 {
+		return paid, err
+	}
+
+	_, _, total, lookupErr := repository.OrderByID(h.db, orderID)
+	if lookupErr != nil {
+		// The transaction reports a missing order; nothing to compare against here
+		return paid, err
+	}
+	expected := money.FromReais(total)
+
+	var waited time.Duration
+	for attempt := 1; attempt <= h.cfg.FraudCheckRetries; attempt++ {
+		if err == nil && paid == expected {
+			break
+		}
+		if max := h.cfg.FraudCheckMaxWait; max > 0 && waited+h.cfg.FraudCheckDelay > max {
+			logger.Warnf("valor pago do pedido %s: novas consultas excederiam %s de espera no webhook; encerrando após %d tentativa(s)", orderID, max, attempt-1)
+			break
+		}
+		waited += h.cfg.FraudCheckDelay
+		if err != nil {
+			logger.Warnf("valor pago do pedido %s ainda indisponível (tentativa %d/%d): %v", orderID, attempt, h.cfg.FraudCheckRetries, err)
+		} else {
+			logger.Warnf("valor pago do pedido %s diverge (esperado %s, pago %s); aguardando liquidação (tentativa %d/%d)",
+				orderID, money.FormatBRL(expected), money.FormatBRL(paid), attempt, h.cfg.FraudCheckRetries)
+		}
+		time.Sleep(h.cfg.FraudCheckDelay)
+		paid, err = h.client.GetOrderPaidAmount(pagarmeOrderID)
+	}
+	return paid, err
+}
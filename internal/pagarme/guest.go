@@ -0,0 +1,181 @@
+package pagarme
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"afterzin/api/internal/auth"
+	"afterzin/api/internal/blocklist"
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/validate"
+)
+
+// guestTokenTTL é a validade do token emitido para acompanhar o status de um pedido de convidado
+const guestTokenTTL = 2 * time.Hour
+
+// GuestInput holds buyer data for guest (no signup) checkout.
+type GuestInput struct {
+	Name             string `json:"name"`
+	Email            string `json:"email"`
+	CPF              string `json:"cpf"`
+	PhoneCountryCode string `json:"phoneCountryCode"`
+	PhoneAreaCode    string `json:"phoneAreaCode"`
+	PhoneNumber      string `json:"phoneNumber"`
+}
+
+// CartItem is a ticket selection sent with a checkout request.
+type CartItem struct {
+	EventDateID  string `json:"eventDateId"`
+	TicketTypeID string `json:"ticketTypeId"`
+	Quantity     int    `json:"quantity"`
+	// UnitPrice is the price captured on order_items when the order was
+	// created. When set it is charged instead of the current catalog price,
+	// so a price change mid-purchase doesn't alter the order total.
+	UnitPrice float64 `json:"-"`
+}
+
+// errGuestConflict is returned when guest data collides with another account.
+var errGuestConflict = errors.New("conflito de conta")
+
+// resolveGuestUser validates guest data and returns the GUEST user to attach the order to.
+// Reuses an existing guest only when email and CPF both match that same user;
+// never attaches to a registered account (the buyer must log in instead).
+func (h *Handler) resolveGuestUser(g GuestInput) (string, error) {
+	email := validate.NormalizeEmail(g.Email)
+	name := strings.TrimSpace(g.Name)
+	if err := validate.ValidateCustomerName(name); err != nil {
+		return "", fmt.Errorf("nome inválido: %w", err)
+	}
+	if err := validate.ValidateEmail(email); err != nil {
+		return "", fmt.Errorf("email inválido: %w", err)
+	}
+	cpf := validate.SanitizeDocument(g.CPF)
+	if err := h.region.ValidateDocument(h.region.BuyerDocumentType, cpf); err != nil {
+		return "", err
+	}
+	cc := g.PhoneCountryCode
+	if cc == "" {
+		cc = "55"
+	}
+	if err := validate.ValidatePhone(cc, g.PhoneAreaCode, g.PhoneNumber); err != nil {
+		return "", fmt.Errorf("telefone inválido: %w", err)
+	}
+	phone := validate.ParsePhone(cc, g.PhoneAreaCode, g.PhoneNumber)
+	if err := blocklist.Check(h.db, cpf, email, "compra sem conta"); err != nil {
+		return "", err
+	}
+
+	byEmail, err := repository.UserByEmail(h.db, email)
+	if err != nil {
+		return "", err
+	}
+	byCPF, err := repository.UserByCPF(h.db, cpf)
+	if err != nil {
+		return "", err
+	}
+	if byEmail != nil && byEmail.Role != "GUEST" {
+		return "", fmt.Errorf("%w: email já cadastrado, faça login para continuar", errGuestConflict)
+	}
+	if byCPF != nil && (byEmail == nil || byCPF.ID != byEmail.ID) {
+		return "", fmt.Errorf("%w: CPF já vinculado a outra conta", errGuestConflict)
+	}
+	if byEmail != nil && byCPF == nil {
+		return "", fmt.Errorf("%w: email já vinculado a outro CPF", errGuestConflict)
+	}
+	if byEmail != nil {
+		// Same guest buying again (email and CPF match)
+		return byEmail.ID, nil
+	}
+
+	id, err := repository.CreateGuestUser(h.db, name, email, cpf, &phone.CountryCode, &phone.AreaCode, &phone.Number)
+	if err != nil {
+		return "", err
+	}
+	logger.Infof("usuário convidado criado: id=%s", id)
+	return id, nil
+}
+
+// clientIP is the address a request came from, as seen by the server: the
+// host part of RemoteAddr. Behind a reverse proxy that is the proxy's.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// guestOrderToken issues the token a guest uses to follow the status of the
+// order just created. It is scoped to that order (see auth.RoleGuestOrder):
+// it doesn't authenticate the guest user, so it can't list their tickets or
+// other orders.
+func (h *Handler) guestOrderToken(orderID string) string {
+	token, _ := auth.NewToken(orderID, auth.RoleGuestOrder, h.cfg.JWTSecret, guestTokenTTL)
+	return token
+}
+
+// guestOrderOwner returns the owner of orderID when the request carries a
+// guest order token for that order, or "" otherwise.
+func (h *Handler) guestOrderOwner(r *http.Request, orderID string) string {
+	tokenStr, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || orderID == "" {
+		return ""
+	}
+	sub, role, err := auth.ParseToken(tokenStr, h.cfg.JWTSecret)
+	if err != nil || role != auth.RoleGuestOrder || sub != orderID {
+		return ""
+	}
+	ownerID, _, _, err := repository.OrderByID(h.db, orderID)
+	if err != nil {
+		return ""
+	}
+	return ownerID
+}
+
+// createPendingOrder validates cart availability and creates a PENDING order
+// with its items, atomically. clientIP is set for guest checkouts, which are
+// also limited per address (MaxGuestOrdersPerIP).
+func (h *Handler) createPendingOrder(userID, clientIP string, items []CartItem) (string, error) {
+	quote, err := PriceCart(h.db, items)
+	if err != nil {
+		return "", err
+	}
+	if err := quote.CheckAvailability(); err != nil {
+		return "", err
+	}
+	if err := quote.CheckEventDates(time.Now(), h.cfg); err != nil {
+		return "", err
+	}
+	lines := make([]repository.NewOrderItem, len(quote.Lines))
+	for i, l := range quote.Lines {
+		lines[i] = repository.NewOrderItem{EventDateID: l.EventDateID, TicketTypeID: l.TicketTypeID, Quantity: l.Quantity, UnitPrice: l.UnitPrice}
+	}
+	limits := repository.OrderLimits{MaxPending: h.cfg.MaxPendingOrders}
+	if clientIP != "" {
+		limits.ClientIP, limits.MaxPendingPerIP = clientIP, h.cfg.MaxGuestOrdersPerIP
+	}
+	orderID, err := repository.CreateOrderWithItems(h.db, userID, quote.Total(), 30*time.Minute, lines, limits)
+	if err != nil {
+		return "", PendingOrderLimit(h.db, userID, h.cfg.MaxPendingOrders, err)
+	}
+	return orderID, nil
+}
+
+// cancelFailedCheckout cancels an order created by CreatePayment when no
+// PIX could be generated for it, so it doesn't count as a pending order or
+// linger until it expires.
+func (h *Handler) cancelFailedCheckout(orderID string) {
+	cancelled, err := repository.CancelPendingOrder(h.db, orderID, "checkout_failed", "PIX não gerado na criação do pedido")
+	if err != nil {
+		logger.Errorf("erro ao cancelar pedido %s após falha no checkout: %v", orderID, err)
+		return
+	}
+	if cancelled {
+		logger.Infof("pedido %s cancelado: PIX não gerado", orderID)
+	}
+}
@@ -0,0 +1,187 @@
+package pagarme
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/repository"
+)
+
+func TestCreatePaymentGuestCheckout(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	items, _ := repository.OrderItemsByOrderID(sqlite, orderID)
+	item := items[0]
+
+	h := NewHandler(newFakeClient(), sqlite, &config.Config{JWTSecret: "test-secret", BaseURL: "http://localhost"}, mailer.LogMailer{})
+
+	checkout := func(email, cpf string) *httptest.ResponseRecorder {
+		body := `{"guest":{"name":"Convidado","email":"` + email + `","cpf":"` + cpf + `","phoneAreaCode":"11","phoneNumber":"987654321"},` +
+			`"items":[{"eventDateId":"` + item.EventDateID + `","ticketTypeId":"` + item.TicketTypeID + `","quantity":2}]}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.CreatePayment(rec, req)
+		return rec
+	}
+
+	rec := checkout("Convidado@Email.com", "529.982.247-25")
+	// CPF already belongs to the seeded buyer: must not silently attach.
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("CPF collision: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := checkout("comprador@email.com", "168.995.350-09"); rec.Code != http.StatusConflict {
+		t.Fatalf("email collision: status = %d, want 409", rec.Code)
+	}
+
+	rec = checkout("Convidado@Email.com", "168.995.350-09")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("guest checkout: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var result PixOrderResult
+	json.NewDecoder(rec.Body).Decode(&result)
+	if result.OrderID == "" || result.GuestToken == "" {
+		t.Fatalf("missing orderId/guestToken: %+v", result)
+	}
+	guest, _ := repository.UserByEmail(sqlite, "convidado@email.com")
+	if guest == nil || guest.Role != "GUEST" {
+		t.Fatalf("guest user not created: %+v", guest)
+	}
+	ownerID, status, total, _ := repository.OrderByID(sqlite, result.OrderID)
+	if ownerID != guest.ID || status != "PENDING" || total != 100 {
+		t.Errorf("order = (%s, %s, %.2f), want (%s, PENDING, 100)", ownerID, status, total, guest.ID)
+	}
+
+	// Same guest again reuses the account.
+	if rec := checkout("convidado@email.com", "16899535009"); rec.Code != http.StatusOK {
+		t.Fatalf("repeat guest checkout: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	// Knowing the guest's email isn't enough: an unused CPF doesn't take
+	// over the account.
+	if rec := checkout("convidado@email.com", "15350946056"); rec.Code != http.StatusConflict {
+		t.Fatalf("guest email with another CPF: status = %d, want 409 (body %s)", rec.Code, rec.Body.String())
+	}
+
+	// The guest token only reads this order's status; it is not a session.
+	pollStatus := func(orderID string) int {
+		req := httptest.NewRequest(http.MethodGet, "/v1/payment/status?orderId="+orderID, nil)
+		req.Header.Set("Authorization", "Bearer "+result.GuestToken)
+		rec := httptest.NewRecorder()
+		middleware.Auth("test-secret")(http.HandlerFunc(h.GetPaymentStatus)).ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := pollStatus(result.OrderID); code != http.StatusOK {
+		t.Errorf("status with guest token: %d, want 200", code)
+	}
+	if code := pollStatus(orderID); code != http.StatusUnauthorized {
+		t.Errorf("status of another order with guest token: %d, want 401", code)
+	}
+	var sessionUser string
+	req := httptest.NewRequest(http.MethodGet, "/graphql", nil)
+	req.Header.Set("Authorization", "Bearer "+result.GuestToken)
+	middleware.Auth("test-secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessionUser = middleware.UserID(r.Context())
+	})).ServeHTTP(httptest.NewRecorder(), req)
+	if sessionUser != "" {
+		t.Errorf("guest token authenticated user %q", sessionUser)
+	}
+}
+
+func TestCreatePaymentLimitsPendingOrdersPerUser(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	items, _ := repository.OrderItemsByOrderID(sqlite, orderID)
+	item := items[0]
+
+	h := NewHandler(newFakeClient(), sqlite, &config.Config{JWTSecret: "test-secret", MaxPendingOrders: 2}, &captureMailer{})
+	checkout := func() *httptest.ResponseRecorder {
+		body := `{"guest":{"name":"Convidado","email":"convidado@email.com","cpf":"16899535009","phoneAreaCode":"11","phoneNumber":"987654321"},` +
+			`"items":[{"eventDateId":"` + item.EventDateID + `","ticketTypeId":"` + item.TicketTypeID + `","quantity":1}]}`
+		rec := httptest.NewRecorder()
+		h.CreatePayment(rec, httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(body)))
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := checkout(); rec.Code != http.StatusOK {
+			t.Fatalf("checkout %d: status = %d, body = %s", i+1, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := checkout()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("third checkout: status = %d, want 429 (body %s)", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Error         string   `json:"error"`
+		PendingOrders []string `json:"pendingOrders"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.PendingOrders) != 2 || !strings.Contains(resp.Error, resp.PendingOrders[0]) {
+		t.Fatalf("response = %+v, want both pending orders listed", resp)
+	}
+
+	guest, _ := repository.UserByEmail(sqlite, "convidado@email.com")
+	if n, _ := repository.CountPendingOrders(sqlite, guest.ID); n != 2 {
+		t.Fatalf("pending orders = %d, want 2", n)
+	}
+}
+
+func TestCreatePaymentLimitsGuestOrdersPerIP(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	items, _ := repository.OrderItemsByOrderID(sqlite, orderID)
+	item := items[0]
+
+	h := NewHandler(newFakeClient(), sqlite, &config.Config{JWTSecret: "test-secret", MaxPendingOrders: 3, MaxGuestOrdersPerIP: 2}, &captureMailer{})
+	checkout := func(email, cpf, remoteAddr string) *httptest.ResponseRecorder {
+		body := `{"guest":{"name":"Convidado","email":"` + email + `","cpf":"` + cpf + `","phoneAreaCode":"11","phoneNumber":"987654321"},` +
+			`"items":[{"eventDateId":"` + item.EventDateID + `","ticketTypeId":"` + item.TicketTypeID + `","quantity":1}]}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		h.CreatePayment(rec, req)
+		return rec
+	}
+
+	// A new guest account per email: only the address ties them together
+	if rec := checkout("um@email.com", "16899535009", "203.0.113.7:5000"); rec.Code != http.StatusOK {
+		t.Fatalf("first guest: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := checkout("dois@email.com", "39053344705", "203.0.113.7:5001"); rec.Code != http.StatusOK {
+		t.Fatalf("second guest: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := checkout("tres@email.com", "71428793860", "203.0.113.7:5002"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("third guest from the same address: status = %d, want 429 (body %s)", rec.Code, rec.Body.String())
+	}
+	if rec := checkout("tres@email.com", "71428793860", "198.51.100.4:5000"); rec.Code != http.StatusOK {
+		t.Fatalf("guest from another address: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if n := countRows(t, sqlite, `SELECT COUNT(*) FROM orders WHERE client_ip = '203.0.113.7' AND status = 'PENDING'`); n != 2 {
+		t.Errorf("pending orders from the address = %d, want 2", n)
+	}
+}
+
+func TestCreatePaymentRejectsOversizedBody(t *testing.T) {
+	sqlite := newTestDB(t)
+	h := NewHandler(newFakeClient(), sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	limited := middleware.MaxBody(1024, nil)(http.HandlerFunc(h.CreatePayment))
+
+	// No Content-Length, so the limit is only hit while decoding.
+	body := `{"guest":{"name":"` + strings.Repeat("a", 4096) + `"}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(body))
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	limited.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413 (body %s)", rec.Code, rec.Body.String())
+	}
+	if n := countRows(t, sqlite, `SELECT COUNT(*) FROM users`); n != 0 {
+		t.Errorf("users = %d, want 0", n)
+	}
+}
@@ -3,16 +3,25 @@ package pagarme
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"afterzin/api/internal/blocklist"
 	"afterzin/api/internal/config"
 	"afterzin/api/internal/logger"
+	"afterzin/api/internal/mailer"
 	"afterzin/api/internal/middleware"
-	"afterzin/api/internal/qrcode"
+	"afterzin/api/internal/money"
+	"afterzin/api/internal/outbox"
 	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+	"afterzin/api/internal/validate"
 
 	"github.com/google/uuid"
 )
@@ -21,44 +30,54 @@ import (
 // These complement the GraphQL API with payment-specific operations
 // that are naturally REST (webhooks, PIX flow, etc.).
 type Handler struct {
-	client *Client
+	client PagarmeAPI
 	db     *sql.DB
 	cfg    *config.Config
-}
+	mailer mailer.Mailer
+	region Region
 
-// NewHandler creates a new Pagar.me HTTP handler.
-func NewHandler(client *Client, db *sql.DB, cfg *config.Config) *Handler {
-	return &Handler{client: client, db: db, cfg: cfg}
+	payments paymentsInFlight // see PaymentsInFlight
 }
 
-func respondJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
+// NewHandler creates a new Pagar.me HTTP handler. An invalid PAYMENT_REGION
+// falls back to DefaultRegion; main validates it at startup.
+func NewHandler(client PagarmeAPI, db *sql.DB, cfg *config.Config, mail mailer.Mailer) *Handler {
+	region, err := RegionByCode(cfg.PaymentRegion)
+	if err != nil {
+		region, _ = RegionByCode(DefaultRegion)
+	}
+	return &Handler{client: client, db: db, cfg: cfg, mailer: mail, region: region}
 }
 
-func respondError(w http.ResponseWriter, status int, message string) {
-	respondJSON(w, status, map[string]string{"error": message})
-}
+// ---------- Recipient Management ----------
 
-// sanitizeDocument remove todos os caracteres não numéricos de um documento (CPF/CNPJ).
-func sanitizeDocument(doc string) string {
-	return regexp.MustCompile(`[^\d]`).ReplaceAllString(doc, "")
-}
+// saveRecipient links a Pagar.me recipient to the producer and marks
+// onboarding complete. It writes the error response and returns false on failure.
+func (h *Handler) saveRecipient(w http.ResponseWriter, prodID, recipientID string) bool {
+	if err := repository.SetProducerPagarmeRecipientID(h.db, prodID, recipientID); err != nil {
+		logger.Errorf("erro ao salvar recipient id: %v", err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao salvar recebedor")
+		return false
+	}
 
-// ---------- Recipient Management ----------
+	repository.SetProducerPagarmeEnv(h.db, prodID, h.client.Environment())
+
+	// Mark onboarding as complete
+	repository.SetProducerOnboardingComplete(h.db, prodID, true)
+	return true
+}
 
 // CreateRecipient handles POST /api/pagarme/recipient/create
 // Creates a Pagar.me recipient for the authenticated producer using bank account data.
 func (h *Handler) CreateRecipient(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	userID := middleware.UserID(r.Context())
 	if userID == "" {
-		respondError(w, http.StatusUnauthorized, "não autenticado")
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
 		return
 	}
 
@@ -68,7 +87,7 @@ func (h *Handler) CreateRecipient(w http.ResponseWriter, r *http.Request) {
 		var err error
 		prodID, err = repository.CreateProducer(h.db, userID)
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "erro ao criar perfil de produtor")
+			rest.Error(w, http.StatusInternalServerError, "erro ao criar perfil de produtor")
 			return
 		}
 	}
@@ -76,7 +95,7 @@ func (h *Handler) CreateRecipient(w http.ResponseWriter, r *http.Request) {
 	// Check if already has a recipient
 	existing, _ := repository.GetProducerPagarmeRecipientID(h.db, prodID)
 	if existing != "" {
-		respondJSON(w, http.StatusOK, map[string]interface{}{
+		rest.JSON(w, http.StatusOK, map[string]interface{}{
 			"recipientId": existing,
 			"message":     "recebedor Pagar.me já existe",
 		})
@@ -108,37 +127,39 @@ func (h *Handler) CreateRecipient(w http.ResponseWriter, r *http.Request) {
 		AccountNumber     string `json:"accountNumber"`
 		AccountCheckDigit string `json:"accountCheckDigit"`
 		AccountType       string `json:"accountType"`
+		HolderDocument    string `json:"holderDocument"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "corpo inválido")
+	if !rest.DecodeStrict(w, r, &req, h.cfg.StrictJSON) {
 		return
 	}
 
 	if req.Document == "" || req.BankCode == "" || req.BranchNumber == "" || req.AccountNumber == "" {
-		respondError(w, http.StatusBadRequest, "documento, banco, agência e conta são obrigatórios")
+		rest.Error(w, http.StatusBadRequest, "documento, banco, agência e conta são obrigatórios")
 		return
 	}
 
 	// Default values
 	if req.DocumentType == "" {
-		req.DocumentType = "CPF"
+		req.DocumentType = h.region.RecipientDocumentTypes[0]
+	}
+	req.DocumentType = strings.ToUpper(req.DocumentType)
+	req.Document = validate.SanitizeDocument(req.Document)
+	if err := h.region.ValidateDocument(req.DocumentType, req.Document); err != nil {
+		rest.Error(w, http.StatusBadRequest, err.Error())
+		return
 	}
 	if req.Type == "" {
 		req.Type = "individual"
 	}
+	req.AccountType = strings.ToLower(strings.TrimSpace(req.AccountType))
 	if req.AccountType == "" {
-		req.AccountType = "checking"
+		req.AccountType = h.cfg.DefaultAccountType
 	}
-
-	// Get user info
-	user, _ := repository.UserByID(h.db, userID)
-	if user == nil {
-		respondError(w, http.StatusInternalServerError, "usuário não encontrado")
-		return
+	if req.AccountType == "" {
+		req.AccountType = AccountTypeChecking
 	}
 
-	// Create recipient in Pagar.me
-	result, err := h.client.CreateRecipient(CreateRecipientParams{
+	params := CreateRecipientParams{
 		Name:                   req.Name,
 		Email:                  req.Email,
 		Phone:                  req.Phone,
@@ -158,26 +179,70 @@ func (h *Handler) CreateRecipient(w http.ResponseWriter, r *http.Request) {
 		AccountNumber:          req.AccountNumber,
 		AccountCheckDigit:      req.AccountCheckDigit,
 		AccountType:            req.AccountType,
-	})
+		HolderDocument:         req.HolderDocument,
+	}
+	if err := ValidateBankAccount(params); err != nil {
+		rest.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Get user info
+	user, _ := repository.UserByID(h.db, userID)
+	if user == nil {
+		rest.Error(w, http.StatusInternalServerError, "usuário não encontrado")
+		return
+	}
+
+	// A previous attempt may have created the recipient in Pagar.me and
+	// failed before saving its id; reuse it instead of creating a duplicate.
+	// Only the owner of the document gets it, and only while no other
+	// producer is linked to it: anyone can type someone else's CPF.
+	found, err := h.client.FindRecipientByCode(req.Document)
 	if err != nil {
-		logger.Errorf("erro ao criar recebedor no Pagar.me: %v", err)
-		respondError(w, http.StatusInternalServerError, "erro ao criar recebedor: "+err.Error())
+		logger.Errorf("erro ao consultar recebedor no Pagar.me: %v", err)
+		rest.Error(w, http.StatusBadGateway, "erro ao consultar recebedor existente; tente novamente")
+		return
+	}
+	if found != nil {
+		owner, _, err := repository.ProducerByPagarmeRecipientID(h.db, found.RecipientID)
+		if err != nil {
+			logger.Errorf("erro ao buscar produtor do recebedor %s: %v", found.RecipientID, err)
+			rest.Error(w, http.StatusInternalServerError, "erro ao consultar recebedor existente")
+			return
+		}
+		if (owner != "" && owner != prodID) || req.Document != user.CPF {
+			logger.Warnf("recebedor %s do documento informado não pertence ao produtor %s — não vinculado", found.RecipientID, prodID)
+			rest.Error(w, http.StatusConflict, "já existe um recebedor para este documento; entre em contato com o suporte")
+			return
+		}
+		if !h.saveRecipient(w, prodID, found.RecipientID) {
+			return
+		}
+		logger.Infof("recebedor existente reaproveitado para produtor %s (recipient: %s)", prodID, found.RecipientID)
+		rest.JSON(w, http.StatusOK, map[string]interface{}{
+			"recipientId": found.RecipientID,
+			"status":      found.Status,
+			"message":     "recebedor Pagar.me já existente foi vinculado",
+		})
 		return
 	}
 
-	// Persist recipient ID
-	if err := repository.SetProducerPagarmeRecipientID(h.db, prodID, result.RecipientID); err != nil {
-		logger.Errorf("erro ao salvar recipient id: %v", err)
-		respondError(w, http.StatusInternalServerError, "erro ao salvar recebedor")
+	// Create recipient in Pagar.me
+	result, err := h.client.CreateRecipient(params)
+	if err != nil {
+		logger.Errorf("erro ao criar recebedor no Pagar.me: %v", err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao criar recebedor: "+err.Error())
 		return
 	}
 
-	// Mark onboarding as complete
-	repository.SetProducerOnboardingComplete(h.db, prodID, true)
+	// Persist recipient ID right away; anything after this is recoverable.
+	if !h.saveRecipient(w, prodID, result.RecipientID) {
+		return
+	}
 
 	logger.Infof("recebedor criado para produtor %s (recipient: %s)", prodID, result.RecipientID)
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
+	rest.JSON(w, http.StatusOK, map[string]interface{}{
 		"recipientId": result.RecipientID,
 		"status":      result.Status,
 		"message":     "recebedor criado com sucesso",
@@ -188,102 +253,243 @@ func (h *Handler) CreateRecipient(w http.ResponseWriter, r *http.Request) {
 // Returns the current recipient status of the producer.
 func (h *Handler) GetRecipientStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	userID := middleware.UserID(r.Context())
+	if userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return
+	}
+
+	rest.JSON(w, http.StatusOK, LookupRecipientStatus(h.client, h.db, userID))
+}
+
+// RefreshRecipientStatus handles POST /v1/recipient/status/refresh
+// Fetches the recipient status live from Pagar.me and updates the cached
+// status, e.g. right after the producer submits KYC documents.
+func (h *Handler) RefreshRecipientStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	userID := middleware.UserID(r.Context())
+	if userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return
+	}
+
+	res, err := RefreshRecipientStatus(h.client, h.db, userID, time.Now())
+	if err != nil {
+		var tooSoon *RefreshTooSoonError
+		if errors.As(err, &tooSoon) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(tooSoon.RetryAfter.Round(time.Second).Seconds())))
+			rest.Error(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		rest.Error(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	rest.JSON(w, http.StatusOK, res)
+}
+
+// DeleteRecipient handles DELETE /v1/recipient
+// Deactivates the producer's Pagar.me recipient and clears the local association.
+// Blocked while orders on the producer's events may still need the split.
+func (h *Handler) DeleteRecipient(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	userID := middleware.UserID(r.Context())
 	if userID == "" {
-		respondError(w, http.StatusUnauthorized, "não autenticado")
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
 		return
 	}
 
 	prodID, _ := repository.ProducerIDByUser(h.db, userID)
 	if prodID == "" {
-		respondJSON(w, http.StatusOK, map[string]interface{}{
-			"hasRecipient":       false,
-			"onboardingComplete": false,
-		})
+		rest.Error(w, http.StatusNotFound, "perfil de produtor não encontrado")
 		return
 	}
-
 	recipientID, _ := repository.GetProducerPagarmeRecipientID(h.db, prodID)
 	if recipientID == "" {
-		respondJSON(w, http.StatusOK, map[string]interface{}{
-			"hasRecipient":       false,
-			"onboardingComplete": false,
-		})
+		rest.Error(w, http.StatusNotFound, "nenhum recebedor configurado")
 		return
 	}
 
-	// Check live status from Pagar.me
-	recipientData, err := h.client.GetRecipient(recipientID)
+	pending, paid, err := repository.CountProducerOrdersAwaitingTransfer(h.db, prodID, time.Now(), h.cfg.EventTimezone(""))
 	if err != nil {
-		logger.Errorf("erro ao obter status do recebedor no Pagar.me: %v", err)
-		// Return cached local status
-		onboardingComplete, _ := repository.GetProducerOnboardingComplete(h.db, prodID)
-		respondJSON(w, http.StatusOK, map[string]interface{}{
-			"hasRecipient":       true,
-			"recipientId":        recipientID,
-			"onboardingComplete": onboardingComplete,
-			"error":              "não foi possível verificar status com Pagar.me",
-		})
+		logger.Errorf("erro ao verificar pedidos do produtor %s: %v", prodID, err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao remover recebedor")
+		return
+	}
+	if pending > 0 || paid > 0 {
+		rest.Error(w, http.StatusConflict, fmt.Sprintf(
+			"não é possível remover o recebedor: há %d pedido(s) pendente(s) e %d pedido(s) pago(s) de eventos ainda não realizados aguardando repasse",
+			pending, paid))
 		return
 	}
 
-	status, _ := recipientData["status"].(string)
-	name, _ := recipientData["name"].(string)
+	// Pagar.me may refuse (e.g. recipient with balance); still unlink locally so
+	// the producer can onboard again, and report whether deactivation succeeded.
+	deactivated := true
+	if err := h.client.DeactivateRecipient(recipientID); err != nil {
+		deactivated = false
+		logger.Warnf("Pagar.me não desativou o recebedor %s: %v", recipientID, err)
+	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"hasRecipient":       true,
-		"recipientId":        recipientID,
-		"onboardingComplete": true,
-		"status":             status,
-		"name":               name,
+	if err := repository.ClearProducerPagarmeRecipient(h.db, prodID); err != nil {
+		logger.Errorf("erro ao remover recebedor do produtor %s: %v", prodID, err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao remover recebedor")
+		return
+	}
+	details := fmt.Sprintf("recipient_id=%s desativado_no_pagarme=%v", recipientID, deactivated)
+	if err := repository.InsertAuditLog(h.db, userID, "recipient.delete", "producer", prodID, details); err != nil {
+		logger.Errorf("erro ao registrar auditoria de remoção de recebedor: %v", err)
+	}
+
+	logger.Infof("recebedor removido: produtor=%s recipient=%s desativado=%v", prodID, recipientID, deactivated)
+	rest.JSON(w, http.StatusOK, map[string]interface{}{
+		"recipientId": recipientID,
+		"deactivated": deactivated,
+		"message":     "recebedor removido",
 	})
 }
 
 // ---------- Payment: PIX via Pagar.me ----------
 
+// paymentClaimLease is how long a CreatePayment claim on an order blocks
+// other payment attempts; a claim older than that was left by a request that
+// died before releasing it.
+const paymentClaimLease = 2 * time.Minute
+
 // CreatePayment handles POST /api/pagarme/payment/create
 // Creates a Pagar.me order with PIX payment and split for an existing order.
 // Returns QR code + copia-e-cola for the customer to pay.
+//
+// Unauthenticated requests may check out as a guest by sending "guest" buyer
+// data and the cart "items"; a GUEST user and PENDING order are created and a
+// short-lived guestToken, valid only for that order's payment status, is
+// returned.
+// Authenticated buyers may also send "items" instead of "orderId" to create
+// the order and its PIX in a single call.
 func (h *Handler) CreatePayment(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req struct {
+		OrderID string      `json:"orderId"`
+		Guest   *GuestInput `json:"guest"`
+		Items   []CartItem  `json:"items"`
+		Address *Address    `json:"address"`
+		Attribution
+	}
+	if !rest.DecodeStrict(w, r, &req, h.cfg.StrictJSON) {
+		return
+	}
+	if req.Address != nil {
+		req.Address.Normalize()
+		if err := req.Address.Validate(); err != nil {
+			var addrErr *AddressValidationError
+			errors.As(err, &addrErr)
+			rest.JSON(w, http.StatusBadRequest, map[string]string{"error": rest.Localize(w, err.Error()), "field": "address." + addrErr.Field})
+			return
+		}
+	}
+	if err := CheckItemLimits(req.Items, h.cfg.MaxOrderItems, h.cfg.MaxItemQuantity); err != nil {
+		rest.Error(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	userID := middleware.UserID(r.Context())
+	guest := false
 	if userID == "" {
-		respondError(w, http.StatusUnauthorized, "não autenticado")
+		if req.Guest == nil {
+			rest.Error(w, http.StatusUnauthorized, "não autenticado")
+			return
+		}
+		if !h.cfg.Feature(config.FeatureGuestCheckout) {
+			rest.Error(w, http.StatusUnauthorized, "compra sem conta indisponível; entre na sua conta")
+			return
+		}
+		guestID, err := h.resolveGuestUser(*req.Guest)
+		if err != nil {
+			status := http.StatusBadRequest
+			switch {
+			case errors.Is(err, errGuestConflict):
+				status = http.StatusConflict
+			case errors.Is(err, blocklist.ErrIdentityBlocked):
+				status = http.StatusForbidden
+			case errors.Is(err, blocklist.ErrCheckFailed):
+				status = http.StatusInternalServerError
+			}
+			var phoneErr *validate.PhoneValidationError
+			if errors.As(err, &phoneErr) {
+				rest.JSON(w, status, map[string]string{"error": rest.Localize(w, err.Error()), "field": "guest." + phoneErr.InputField()})
+				return
+			}
+			rest.Error(w, status, err.Error())
+			return
+		}
+		userID, req.OrderID, guest = guestID, "", true
+	} else if req.OrderID != "" && len(req.Items) > 0 {
+		rest.Error(w, http.StatusBadRequest, "informe orderId ou items, não ambos")
 		return
 	}
 
-	var req struct {
-		OrderID string `json:"orderId"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "corpo inválido")
-		return
+	// Guests, and buyers who send the cart instead of an orderId, get their
+	// order created here. If no PIX comes out of this call the order is
+	// cancelled, so a failed checkout doesn't leave an orphaned order.
+	pixCreated := false
+	if req.OrderID == "" && (guest || len(req.Items) > 0) {
+		ip := ""
+		if guest {
+			ip = clientIP(r)
+		}
+		orderID, err := h.createPendingOrder(userID, ip, req.Items)
+		if err != nil {
+			var limitErr *PendingOrderLimitError
+			if errors.As(err, &limitErr) {
+				rest.JSON(w, http.StatusTooManyRequests, map[string]interface{}{"error": rest.Localize(w, err.Error()), "pendingOrders": limitErr.Refs})
+				return
+			}
+			if errors.Is(err, repository.ErrClientOrderLimit) {
+				rest.Error(w, http.StatusTooManyRequests, err.Error())
+				return
+			}
+			rest.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if h.cfg.Feature(config.FeatureSalesAttribution) {
+			RecordOrderAttribution(h.db, orderID, req.Attribution)
+		}
+		req.OrderID = orderID
+		defer func() {
+			if !pixCreated {
+				h.cancelFailedCheckout(orderID)
+			}
+		}()
 	}
+
 	if req.OrderID == "" {
-		respondError(w, http.StatusBadRequest, "orderId é obrigatório")
+		rest.Error(w, http.StatusBadRequest, "orderId é obrigatório")
 		return
 	}
 
 	// Verify order ownership and status
-	orderUserID, status, _, err := repository.OrderByID(h.db, req.OrderID)
-	if err != nil || orderUserID == "" {
-		respondError(w, http.StatusNotFound, "pedido não encontrado")
-		return
-	}
-	if orderUserID != userID {
-		respondError(w, http.StatusForbidden, "pedido não pertence ao usuário")
+	order, err := repository.AuthorizeOrderAccess(h.db, req.OrderID, userID)
+	if err != nil {
+		rest.OrderAccessError(w, req.OrderID, err)
 		return
 	}
-	if status != "PENDING" {
-		respondError(w, http.StatusBadRequest, "pedido já processado")
+	if order.Status != "PENDING" {
+		rest.Error(w, http.StatusBadRequest, "pedido já processado")
 		return
 	}
 
@@ -293,177 +499,265 @@ func (h *Handler) CreatePayment(w http.ResponseWriter, r *http.Request) {
 		// Return existing order status
 		orderStatus, err := h.client.GetOrderStatus(existingOrderID)
 		if err == nil && orderStatus.Status != "canceled" && orderStatus.Status != "failed" {
-			respondJSON(w, http.StatusOK, orderStatus)
+			orderStatus.OrderRef, _ = repository.OrderRefByID(h.db, req.OrderID)
+			rest.JSON(w, http.StatusOK, orderStatus)
 			return
 		}
 		// If cancelled or errored, allow creating a new one
 	}
 
+	// Claim the order before reading its items: from here on
+	// UpdateOrderItems refuses it, so the PIX charges what is stored. The
+	// claim is dropped if no PIX comes out of this call.
+	claimed, err := repository.ClaimOrderPayment(h.db, req.OrderID, time.Now(), paymentClaimLease)
+	if errors.Is(err, repository.ErrPaymentInProgress) {
+		rest.Error(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		logger.Errorf("erro ao iniciar pagamento do pedido %s: %v", req.OrderID, err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao iniciar pagamento")
+		return
+	}
+	if !claimed {
+		rest.Error(w, http.StatusBadRequest, "pedido já processado")
+		return
+	}
+	defer func() {
+		if !pixCreated {
+			if err := repository.ReleaseOrderPayment(h.db, req.OrderID); err != nil {
+				logger.Errorf("erro ao liberar pedido %s após falha no pagamento: %v", req.OrderID, err)
+			}
+		}
+	}()
+
 	// Get order items
 	items, err := repository.OrderItemsByOrderID(h.db, req.OrderID)
 	if err != nil || len(items) == 0 {
-		respondError(w, http.StatusBadRequest, "pedido sem itens")
+		rest.Error(w, http.StatusBadRequest, "pedido sem itens")
 		return
 	}
 
 	// Get customer (buyer) info
 	buyer, _ := repository.UserByID(h.db, userID)
 	if buyer == nil {
-		respondError(w, http.StatusInternalServerError, "usuário não encontrado")
+		rest.Error(w, http.StatusInternalServerError, "usuário não encontrado")
 		return
 	}
 
-	// Sanitizar e validar CPF do comprador
-	sanitizedCPF := sanitizeDocument(buyer.CPF)
-	if len(sanitizedCPF) != 11 {
-		respondError(w, http.StatusBadRequest, fmt.Sprintf("CPF inválido: deve conter 11 dígitos (recebido %d)", len(sanitizedCPF)))
+	// Sanitizar e validar documento do comprador
+	sanitizedCPF := validate.SanitizeDocument(buyer.CPF)
+	if err := h.region.ValidateDocument(h.region.BuyerDocumentType, sanitizedCPF); err != nil {
+		rest.Error(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Calculate total amount, resolve producer recipient, build order items
-	var producerRecipientID string
-	var totalCentavos int64
-	var totalTickets int
-	var eventTitle string
-	var orderItems []OrderItem
-
-	for _, item := range items {
-		// Validar quantidade
-		if item.Quantity <= 0 {
-			respondError(w, http.StatusBadRequest, fmt.Sprintf("quantidade do item deve ser maior que zero (item: %s)", item.TicketTypeID))
-			return
-		}
-
-		totalTickets += item.Quantity
-
-		tt, _ := repository.TicketTypeByID(h.db, item.TicketTypeID)
-		if tt == nil {
-			respondError(w, http.StatusBadRequest, "tipo de ingresso não encontrado")
-			return
-		}
-
-		// Validar preço unitário
-		if tt.Price <= 0 {
-			respondError(w, http.StatusBadRequest, fmt.Sprintf("preço unitário deve ser maior que zero (ticket: %s)", item.TicketTypeID))
-			return
-		}
-
-		totalCentavos += int64(tt.Price*100) * int64(item.Quantity)
-
-		// Resolve event → producer → recipient
-		ed, _ := repository.EventDateByID(h.db, item.EventDateID)
-		if ed == nil {
-			respondError(w, http.StatusBadRequest, "data do evento não encontrada")
-			return
-		}
-		ev, _ := repository.EventByID(h.db, ed.EventID)
-		if ev == nil {
-			respondError(w, http.StatusBadRequest, "evento não encontrado")
-			return
-		}
-		if eventTitle == "" {
-			eventTitle = ev.Title
-		}
-
-		if producerRecipientID == "" {
-			recipientID, _ := repository.GetProducerPagarmeRecipientID(h.db, ev.ProducerID)
-			if recipientID == "" {
-				respondError(w, http.StatusBadRequest, "produtor não configurou recebimento de pagamentos")
-				return
-			}
-			producerRecipientID = recipientID
+	// Compradores na lista de bloqueio recebem um erro genérico
+	if err := blocklist.Check(h.db, sanitizedCPF, buyer.Email, "pagamento do pedido "+req.OrderID); err != nil {
+		status := http.StatusForbidden
+		if !errors.Is(err, blocklist.ErrIdentityBlocked) {
+			status = http.StatusInternalServerError
 		}
+		rest.Error(w, status, err.Error())
+		return
+	}
 
-		orderItems = append(orderItems, OrderItem{
-			Code:        item.TicketTypeID,
-			Description: fmt.Sprintf("%s - %s", tt.Name, eventTitle),
-			Quantity:    item.Quantity,
-			Amount:      int64(tt.Price * 100), // unit price in centavos
-		})
+	// Validar nome e email antes do gateway, que rejeita o pedido com erro genérico
+	customerName := strings.TrimSpace(buyer.Name)
+	if err := validate.ValidateCustomerName(customerName); err != nil {
+		rest.Error(w, http.StatusBadRequest, "nome do comprador inválido: "+err.Error()+"; atualize seu cadastro")
+		return
+	}
+	customerEmail := validate.NormalizeEmail(buyer.Email)
+	if err := validate.ValidateEmail(customerEmail); err != nil {
+		rest.Error(w, http.StatusBadRequest, "email do comprador inválido: "+err.Error()+"; atualize seu cadastro")
+		return
 	}
 
-	// Validar valor total
-	if totalCentavos <= 0 {
-		respondError(w, http.StatusBadRequest, "valor total deve ser maior que zero")
+	cart := make([]CartItem, len(items))
+	for i, item := range items {
+		cart[i] = CartItem{EventDateID: item.EventDateID, TicketTypeID: item.TicketTypeID, Quantity: item.Quantity, UnitPrice: item.UnitPrice}
+	}
+	// Orders created elsewhere (GraphQL checkout) are bounded here as well
+	if err := CheckItemLimits(cart, h.cfg.MaxOrderItems, h.cfg.MaxItemQuantity); err != nil {
+		rest.Error(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	quote, err := PriceCart(h.db, cart)
+	if err != nil {
+		rest.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := quote.CheckEventDates(time.Now(), h.cfg); err != nil {
+		rest.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if quote.RecipientID == "" {
+		rest.Error(w, http.StatusBadRequest, "produtor não configurou recebimento de pagamentos")
+		return
+	}
+	if limit, err := quote.CheckMaxAmount(h.db, h.cfg.MaxOrderCentavos); err != nil {
+		logger.Warnf("pedido acima do limite: pedido=%s usuario=%s total=%s limite=%s",
+			req.OrderID, userID, money.FormatBRL(quote.TotalCentavos), money.FormatBRL(limit))
+		rest.Error(w, http.StatusBadRequest, fmt.Sprintf("%s (máximo de %s por pedido)", err.Error(), money.FormatBRL(limit)))
+		return
+	}
+	if err := CheckMinAmount(quote.TotalCentavos, h.cfg.MinOrderCentavos); err != nil {
+		logger.Warnf("pedido abaixo do mínimo: pedido=%s usuario=%s total=%s minimo=%s",
+			req.OrderID, userID, money.FormatBRL(quote.TotalCentavos), money.FormatBRL(h.cfg.MinOrderCentavos))
+		rest.Error(w, http.StatusBadRequest, fmt.Sprintf("%s (mínimo de %s)", err.Error(), money.FormatBRL(h.cfg.MinOrderCentavos)))
+		return
+	}
+	totalCentavos, totalTickets := quote.TotalCentavos, quote.TotalTickets
+	eventTitle := quote.Lines[0].EventTitle
+	orderItems := make([]OrderItem, 0, len(quote.Lines))
+	for _, l := range quote.Lines {
+		orderItems = append(orderItems, OrderItem{
+			Code:        l.TicketTypeID,
+			Description: fmt.Sprintf("%s - %s", l.TicketName, l.EventTitle),
+			Quantity:    l.Quantity,
+			Amount:      l.UnitAmount,
+		})
+	}
 
 	// Extrair telefone do comprador (se disponível)
-	var customerPhone *PhoneData
+	var customerPhone *validate.PhoneData
 	if buyer.PhoneCountryCode.Valid && buyer.PhoneAreaCode.Valid && buyer.PhoneNumber.Valid {
-		customerPhone = &PhoneData{
+		customerPhone = &validate.PhoneData{
 			CountryCode: buyer.PhoneCountryCode.String,
 			AreaCode:    buyer.PhoneAreaCode.String,
 			Number:      buyer.PhoneNumber.String,
 		}
 	}
 
+	// Sem endereço na requisição, usa o endereço padrão do comprador (se houver)
+	if req.Address == nil && h.cfg.Feature(config.FeatureAddressBook) {
+		addr, err := defaultCustomerAddress(h.db, userID)
+		if err != nil {
+			logger.Errorf("erro ao buscar endereço padrão do usuário %s: %v", userID, err)
+		}
+		req.Address = addr
+	}
+	if req.Address == nil && requiresCustomerAddress(AllowedPaymentMethod) {
+		rest.JSON(w, http.StatusBadRequest, map[string]string{"error": rest.Localize(w, "endereço é obrigatório para este meio de pagamento"), "field": "address"})
+		return
+	}
+
 	// Log estruturado antes de enviar ao Pagar.me
-	logger.Debugf("enviando pedido ao Pagar.me: orderID=%s total=%d centavos items=%d ingressos=%d metodo=%s temTelefone=%v",
-		req.OrderID, totalCentavos, len(orderItems), totalTickets, AllowedPaymentMethod, customerPhone != nil)
+	logger.Debugf("enviando pedido ao Pagar.me: orderID=%s total=%s items=%d ingressos=%d metodo=%s temTelefone=%v",
+		req.OrderID, money.FormatBRL(totalCentavos), len(orderItems), totalTickets, AllowedPaymentMethod, customerPhone != nil)
 
 	// Create Pagar.me order with PIX + split
+	orderRef, _ := repository.OrderRefByID(h.db, req.OrderID)
 	pixResult, err := h.client.CreatePixOrder(PixOrderParams{
 		OrderID:             req.OrderID,
-		ProducerRecipientID: producerRecipientID,
+		OrderRef:            orderRef,
+		ProducerRecipientID: quote.RecipientID,
 		AmountCentavos:      totalCentavos,
 		TotalTickets:        totalTickets,
-		Description:         fmt.Sprintf("Afterzin - %s", eventTitle),
-		CustomerName:        buyer.Name,
-		CustomerEmail:       buyer.Email,
-		CustomerDocument:    sanitizedCPF,  // CPF sanitizado (apenas dígitos)
+		Description:         paymentDescription(h.cfg.PlatformName, eventTitle),
+		StatementDescriptor: h.statementDescriptor(quote.Lines[0].ProducerID),
+		CustomerName:        customerName,
+		CustomerEmail:       customerEmail,
+		CustomerDocument:    sanitizedCPF, // CPF sanitizado (apenas dígitos)
+		CustomerDocType:     h.region.BuyerDocumentType,
 		CustomerPhone:       customerPhone, // Telefone estruturado (opcional)
+		CustomerAddress:     req.Address,   // Endereço (opcional no PIX)
+		Currency:            h.region.Currency,
 		Items:               orderItems,
 	})
 	if err != nil {
 		logger.Errorf("erro ao criar pedido PIX no Pagar.me: %v", err)
-		respondError(w, http.StatusInternalServerError, "erro ao criar pagamento PIX: "+err.Error())
+		rest.Error(w, http.StatusInternalServerError, "erro ao criar pagamento PIX: "+err.Error())
 		return
 	}
+	pixCreated = true
 
-	// Persist Pagar.me IDs on order
-	repository.SetOrderPagarmeOrderID(h.db, req.OrderID, pixResult.PagarmeOrderID)
-	repository.SetOrderPagarmeChargeID(h.db, req.OrderID, pixResult.PagarmeChargeID)
+	// Persist Pagar.me IDs on order. The PIX exists either way: a failure
+	// here is logged, and the webhook still finds the order by its code.
+	if err := repository.SetOrderPagarmeOrderID(h.db, req.OrderID, pixResult.PagarmeOrderID); err != nil {
+		logger.Errorf("erro ao gravar pedido Pagar.me %s no pedido %s: %v", pixResult.PagarmeOrderID, req.OrderID, err)
+	}
+	if err := repository.SetOrderPagarmeChargeID(h.db, req.OrderID, pixResult.PagarmeChargeID); err != nil {
+		logger.Errorf("erro ao gravar cobrança %s no pedido %s: %v", pixResult.PagarmeChargeID, req.OrderID, err)
+	}
+	if err := repository.SetOrderPagarmeEnv(h.db, req.OrderID, h.client.Environment()); err != nil {
+		logger.Errorf("erro ao gravar ambiente do Pagar.me no pedido %s: %v", req.OrderID, err)
+	}
+	if err := repository.SetOrderPix(h.db, req.OrderID, pixResult.PixQRCode, pixResult.PixQRCodeURL, pixExpiresAt(pixResult.ExpiresAt, time.Now())); err != nil {
+		logger.Errorf("erro ao gravar PIX do pedido %s: %v", req.OrderID, err)
+	}
+	if pixResult.ChargeStatus != "" {
+		repository.SetOrderChargeStatus(h.db, req.OrderID, pixResult.ChargeStatus)
+	}
 
-	logger.Infof("pedido PIX criado: pedido=%s pagarme_order=%s charge=%s valor=%d centavos taxa=%d×%d",
+	logger.Infof("pedido PIX criado: pedido=%s pagarme_order=%s charge=%s valor=%s taxa=%s×%d",
 		req.OrderID, pixResult.PagarmeOrderID, pixResult.PagarmeChargeID,
-		totalCentavos, h.client.ApplicationFee, totalTickets)
+		money.FormatBRL(totalCentavos), money.FormatBRL(h.client.ApplicationFeePerTicket()), totalTickets)
 
-	respondJSON(w, http.StatusOK, pixResult)
+	pixResult.OrderRef = orderRef
+	pixResult.OrderID = req.OrderID
+	if guest {
+		pixResult.GuestToken = h.guestOrderToken(req.OrderID)
+	}
+
+	rest.JSON(w, http.StatusOK, pixResult)
 }
 
 // GetPaymentStatus handles GET /api/pagarme/payment/status?orderId=xxx
 // Frontend polls this to check if PIX was paid.
 // IMPORTANT: Returns status based ONLY on local database (source of truth),
 // not from Pagar.me API, to prevent showing "paid" before webhook processes.
+// With ?live=true it also reports the gateway's view of the order for
+// support/debugging (see liveGatewayStatus); "paid" still comes from the DB.
+// Guests authenticate with the guestToken of the order (?orderId= only).
 func (h *Handler) GetPaymentStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	userID := middleware.UserID(r.Context())
 	if userID == "" {
-		respondError(w, http.StatusUnauthorized, "não autenticado")
+		// A guest polls with the token returned by CreatePayment
+		userID = h.guestOrderOwner(r, r.URL.Query().Get("orderId"))
+	}
+	if userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return
+	}
+
+	if pagarmeOrderID := r.URL.Query().Get("pagarmeOrderId"); pagarmeOrderID != "" {
+		h.paymentStatusByPagarmeOrder(w, userID, pagarmeOrderID)
 		return
 	}
 
 	orderID := r.URL.Query().Get("orderId")
 	if orderID == "" {
-		respondError(w, http.StatusBadRequest, "orderId é obrigatório")
+		rest.Error(w, http.StatusBadRequest, "orderId é obrigatório")
 		return
 	}
 
 	// Verify order ownership and get status FROM DATABASE (source of truth)
-	orderUserID, orderStatus, _, err := repository.OrderByID(h.db, orderID)
-	if err != nil || orderUserID == "" {
-		respondError(w, http.StatusNotFound, "pedido não encontrado")
+	order, err := repository.AuthorizeOrderAccess(h.db, orderID, userID)
+	if err != nil {
+		rest.OrderAccessError(w, orderID, err)
 		return
 	}
-	if orderUserID != userID {
-		respondError(w, http.StatusForbidden, "pedido não pertence ao usuário")
+	orderStatus := order.Status
+
+	live := r.URL.Query().Get("live") == "true"
+	if live && !rest.RequireFeature(w, h.cfg, config.FeatureLivePaymentStatus) {
 		return
 	}
+	if live {
+		if wait := takeLiveStatusSlot(orderID, time.Now()); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Round(time.Second).Seconds())))
+			rest.Error(w, http.StatusTooManyRequests,
+				fmt.Sprintf("status consultado há pouco; tente novamente em %d segundos", int(wait.Round(time.Second).Seconds())))
+			return
+		}
+	}
 
 	// Determine if paid based ONLY on database status
 	// This ensures frontend doesn't show "paid" before webhook completes
@@ -474,6 +768,10 @@ func (h *Handler) GetPaymentStatus(w http.ResponseWriter, r *http.Request) {
 	switch orderStatus {
 	case "PENDING":
 		displayStatus = "pending"
+		// A card charge may already be authorized while the order waits for capture
+		if cs, _ := repository.GetOrderChargeStatus(h.db, orderID); cs == string(ChargeAuthorized) {
+			displayStatus = "authorized"
+		}
 	case "PROCESSING":
 		displayStatus = "processing"
 	case "PAID", "CONFIRMED":
@@ -486,10 +784,167 @@ func (h *Handler) GetPaymentStatus(w http.ResponseWriter, r *http.Request) {
 		displayStatus = orderStatus
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
+	orderRef, _ := repository.OrderRefByID(h.db, orderID)
+	resp := map[string]interface{}{
 		"status":      displayStatus,
 		"orderStatus": orderStatus, // Raw status for debugging
+		"orderRef":    orderRef,
 		"paid":        paid,
+	}
+	if live {
+		h.liveGatewayStatus(resp, order)
+	}
+	rest.JSON(w, http.StatusOK, resp)
+}
+
+// LiveStatusInterval is the minimum time between two ?live=true payment
+// status checks for the same order, so polling clients can't hammer Pagar.me.
+const LiveStatusInterval = 10 * time.Second
+
+// liveStatusChecks remembers the last live check per order.
+var liveStatusChecks = struct {
+	sync.Mutex
+	last map[string]time.Time
+}{last: map[string]time.Time{}}
+
+// takeLiveStatusSlot records a live check for the order, or returns how long
+// to wait when the previous one was less than LiveStatusInterval ago.
+func takeLiveStatusSlot(orderID string, now time.Time) time.Duration {
+	liveStatusChecks.Lock()
+	defer liveStatusChecks.Unlock()
+	if last, ok := liveStatusChecks.last[orderID]; ok && now.Sub(last) < LiveStatusInterval {
+		return LiveStatusInterval - now.Sub(last)
+	}
+	for id, last := range liveStatusChecks.last {
+		if now.Sub(last) >= LiveStatusInterval {
+			delete(liveStatusChecks.last, id)
+		}
+	}
+	liveStatusChecks.last[orderID] = now
+	return 0
+}
+
+// liveGatewayStatus adds the order's status as Pagar.me currently reports it
+// ("gatewayStatus", "gatewayChargeStatus") to a payment status response.
+// Informational only: nothing is written and a gateway failure is reported in
+// "gatewayError" instead of failing the request.
+func (h *Handler) liveGatewayStatus(resp map[string]interface{}, order *repository.OrderRow) {
+	if order.PagarmeOrderID == "" {
+		resp["gatewayError"] = "pedido ainda não enviado ao Pagar.me"
+		return
+	}
+	res, err := h.client.GetOrderStatus(order.PagarmeOrderID)
+	if err != nil {
+		logger.Warnf("erro ao consultar status do pedido %s no Pagar.me: %v", order.PagarmeOrderID, err)
+		resp["gatewayError"] = "não foi possível consultar o Pagar.me"
+		return
+	}
+	resp["gatewayStatus"] = res.Status
+	if res.ChargeStatus != "" {
+		resp["gatewayChargeStatus"] = res.ChargeStatus
+	}
+	if gatewayPaid := res.Status == "paid"; gatewayPaid != (resp["paid"] == true) {
+		logger.Infof("status divergente: pedido=%s banco=%v pagarme=%s", order.ID, resp["orderStatus"], res.Status)
+	}
+}
+
+// paymentStatusByPagarmeOrder serves GET /v1/payment/status?pagarmeOrderId=or_xxx
+// Admin-only: support staff usually have just the Pagar.me order ID from the
+// dashboard. Returns the internal order with its status history, items and tickets.
+func (h *Handler) paymentStatusByPagarmeOrder(w http.ResponseWriter, userID, pagarmeOrderID string) {
+	if !rest.IsAdmin(h.db, userID) {
+		rest.Error(w, http.StatusForbidden, "sem permissão")
+		return
+	}
+	order, err := repository.OrderByPagarmeOrderID(h.db, pagarmeOrderID)
+	if err != nil {
+		logger.Errorf("erro ao buscar pedido pelo pagarme_order_id %s: %v", pagarmeOrderID, err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao buscar pedido")
+		return
+	}
+	if order == nil {
+		rest.Error(w, http.StatusNotFound, "pedido não encontrado")
+		return
+	}
+
+	// An admin diagnosing an order needs the full picture: any read error
+	// fails the request rather than returning partial data
+	history, err := repository.OrderStatusHistory(h.db, order.ID)
+	if err != nil {
+		logger.Errorf("erro ao buscar histórico do pedido %s: %v", order.ID, err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao buscar pedido")
+		return
+	}
+	historyOut := make([]map[string]interface{}, 0, len(history))
+	for _, hr := range history {
+		historyOut = append(historyOut, map[string]interface{}{
+			"oldStatus":       hr.OldStatus,
+			"newStatus":       hr.NewStatus,
+			"reason":          hr.Reason,
+			"pagarmeEventId":  hr.PagarmeEventID,
+			"pagarmeChargeId": hr.PagarmeChargeID,
+			"errorMessage":    hr.ErrorMessage,
+			"createdAt":       hr.CreatedAt,
+		})
+	}
+
+	items, err := repository.OrderItemsByOrderID(h.db, order.ID)
+	if err != nil {
+		logger.Errorf("erro ao buscar itens do pedido %s: %v", order.ID, err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao buscar pedido")
+		return
+	}
+	itemsOut := make([]map[string]interface{}, 0, len(items))
+	for _, it := range items {
+		itemsOut = append(itemsOut, map[string]interface{}{
+			"eventDateId":  it.EventDateID,
+			"ticketTypeId": it.TicketTypeID,
+			"quantity":     it.Quantity,
+			"unitPrice":    it.UnitPrice,
+		})
+	}
+
+	tickets, err := repository.TicketsByOrderID(h.db, order.ID)
+	if err != nil {
+		logger.Errorf("erro ao buscar ingressos do pedido %s: %v", order.ID, err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao buscar pedido")
+		return
+	}
+	ticketsOut := make([]map[string]interface{}, 0, len(tickets))
+	for _, t := range tickets {
+		ticketsOut = append(ticketsOut, map[string]interface{}{
+			"id":     t.ID,
+			"code":   t.Code,
+			"used":   t.Used == 1,
+			"usedAt": t.UsedAt.String,
+		})
+	}
+
+	buyer := map[string]interface{}{"id": order.UserID}
+	u, err := repository.UserByID(h.db, order.UserID)
+	if err != nil {
+		logger.Errorf("erro ao buscar comprador do pedido %s: %v", order.ID, err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao buscar pedido")
+		return
+	}
+	if u != nil {
+		buyer["name"], buyer["email"], buyer["role"] = u.Name, u.Email, u.Role
+	}
+
+	rest.JSON(w, http.StatusOK, map[string]interface{}{
+		"orderId":         order.ID,
+		"orderRef":        order.OrderRef,
+		"orderStatus":     order.Status,
+		"total":           order.Total,
+		"createdAt":       order.CreatedAt,
+		"expiresAt":       order.ExpiresAt,
+		"pagarmeOrderId":  order.PagarmeOrderID,
+		"pagarmeChargeId": order.PagarmeChargeID,
+		"pagarmeEnv":      order.PagarmeEnv,
+		"buyer":           buyer,
+		"history":         historyOut,
+		"items":           itemsOut,
+		"tickets":         ticketsOut,
 	})
 }
 
@@ -501,9 +956,14 @@ func (h *Handler) GetPaymentStatus(w http.ResponseWriter, r *http.Request) {
 // Handled events:
 //   - order.paid → confirms order, creates tickets, generates QR codes
 //   - charge.paid → fallback handler
+//   - charge.authorized → records a card authorization awaiting capture
+//   - recipient.* → refreshes the producer's cached recipient status
+//
+// Only types in the allowlist (WEBHOOK_EVENT_TYPES) are processed; others are
+// acknowledged with 200, recorded as "ignored" and counted per type.
 func (h *Handler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
@@ -511,51 +971,73 @@ func (h *Handler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(io.LimitReader(r.Body, 65536))
 	if err != nil {
 		logger.Errorf("erro ao ler corpo do webhook: %v", err)
-		respondError(w, http.StatusBadRequest, "erro ao ler corpo")
+		rest.Error(w, http.StatusBadRequest, "erro ao ler corpo")
 		return
 	}
 	// NOTE: signature verification intentionally disabled.
 	// Always parse the incoming payload and proceed without checking
 	// the `x-hub-signature` header. Use with caution in production.
+	// Without a signature there is no replay window either (a replay can
+	// carry any created_at); what protects the handlers is the event-id
+	// dedup below and re-reading the order or recipient from Pagar.me
+	// instead of trusting the payload.
 	var event *WebhookEvent
 	var evt WebhookEvent
 	if err := json.Unmarshal(body, &evt); err != nil {
 		logger.Errorf("erro ao parsear payload do webhook: %v", err)
-		respondError(w, http.StatusBadRequest, "corpo inválido")
+		rest.Error(w, http.StatusBadRequest, "corpo inválido")
 		return
 	}
 	event = &evt
 	logger.Infof("verificação de assinatura desabilitada — evento recebido: id=%s tipo=%s", event.ID, event.Type)
 
-	// Idempotency check - prevent processing same event twice
-	if repository.PagarmeWebhookEventExists(h.db, event.ID) {
+	// Idempotency: the insert itself decides who processes the event, so two
+	// concurrent deliveries of the same id can't both get past this point
+	inserted, err := repository.InsertPagarmeWebhookEvent(h.db, event.ID, event.Type)
+	if err != nil {
+		logger.Errorf("erro ao inserir evento do webhook no banco: %v", err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao processar webhook")
+		return
+	}
+	if !inserted {
 		logger.Warnf("evento %s já recebido — ignorando", event.ID)
 		w.WriteHeader(http.StatusOK)
 		return
 	}
+	logger.Infof("evento registrado no banco: id=%s tipo=%s", event.ID, event.Type)
 
-	// Log the event immediately (prevents duplicate processing if request retries)
-	if err := repository.InsertPagarmeWebhookEvent(h.db, event.ID, event.Type); err != nil {
-		logger.Errorf("erro ao inserir evento do webhook no banco: %v", err)
-		respondError(w, http.StatusInternalServerError, "erro ao processar webhook")
-		return
+	// Types outside the allowlist are acknowledged and recorded, not processed
+	outcome := webhookHandled
+	if !h.webhookTypeAllowed(event.Type) {
+		outcome = webhookIgnored
+		h.ignoreWebhookEvent(event, "fora da lista de tipos permitidos")
 	}
-	logger.Infof("evento registrado no banco: id=%s tipo=%s", event.ID, event.Type)
 
 	// Route by event type
-	switch event.Type {
-	case "order.paid":
+	switch {
+	case outcome == webhookIgnored:
+	case event.Type == "order.paid":
 		logger.Infof("processando evento order.paid")
 		h.handleOrderPaid(event)
-	case "charge.paid":
+	case event.Type == "charge.paid":
 		logger.Infof("processando evento charge.paid")
 		h.handleChargePaid(event)
+	case event.Type == "charge.authorized":
+		logger.Infof("processando evento charge.authorized")
+		h.handleChargeAuthorized(event)
+	case strings.HasPrefix(event.Type, "recipient."):
+		logger.Infof("processando evento %s", event.Type)
+		h.handleRecipientUpdated(event)
 	default:
-		logger.Warnf("tipo de evento não tratado: %s", event.Type)
+		outcome = webhookIgnored
+		h.ignoreWebhookEvent(event, "tipo permitido mas sem tratamento")
+	}
+	if outcome == webhookHandled {
+		h.countWebhookEvent(event.Type, webhookHandled)
 	}
 
 	// Mark as processed with timestamp
-	if err := repository.MarkPagarmeWebhookEventProcessedAt(h.db, event.ID); err != nil {
+	if err := repository.MarkPagarmeWebhookEventProcessedAt(h.db, event.ID, outcome); err != nil {
 		logger.Errorf("erro ao marcar evento do webhook como processado: %v", err)
 	}
 
@@ -575,8 +1057,7 @@ func (h *Handler) handleOrderPaid(event *WebhookEvent) {
 		return
 	}
 
-	// The "code" field is our internal order ID (set when creating the order)
-	orderID, _ := data["code"].(string)
+	orderID := h.internalOrderID(data)
 	pagarmeOrderID, _ := data["id"].(string)
 
 	if orderID == "" {
@@ -605,6 +1086,25 @@ func (h *Handler) handleOrderPaid(event *WebhookEvent) {
 	h.processOrderPayment(orderID, pagarmeOrderID, chargeID)
 }
 
+// internalOrderID resolves our order ID from a Pagar.me order payload.
+// Prefers metadata.order_id; the "code" is the order reference, or the
+// internal ID itself for orders created before references existed.
+func (h *Handler) internalOrderID(order map[string]interface{}) string {
+	if meta, ok := order["metadata"].(map[string]interface{}); ok {
+		if id, _ := meta["order_id"].(string); id != "" {
+			return id
+		}
+	}
+	code, _ := order["code"].(string)
+	if code == "" {
+		return ""
+	}
+	if id, _ := repository.OrderIDByRef(h.db, code); id != "" {
+		return id
+	}
+	return code
+}
+
 // handleChargePaid processes charge.paid as a fallback.
 // Tries to extract the order code from the charge's order reference.
 // Checks idempotency to avoid processing if order.paid already handled this.
@@ -624,7 +1124,7 @@ func (h *Handler) handleChargePaid(event *WebhookEvent) {
 		return
 	}
 
-	orderID, _ := orderData["code"].(string)
+	orderID := h.internalOrderID(orderData)
 	pagarmeOrderID, _ := orderData["id"].(string)
 
 	if orderID == "" {
@@ -645,13 +1145,92 @@ func (h *Handler) handleChargePaid(event *WebhookEvent) {
 	h.processOrderPayment(orderID, pagarmeOrderID, chargeID)
 }
 
+// handleChargeAuthorized processes charge.authorized: the charge holds the
+// funds but isn't captured yet, so no tickets are issued; they are created
+// when charge.paid/order.paid arrives after capture. Methods that capture on
+// payment (PIX) never stop at this state and the event is ignored.
+func (h *Handler) handleChargeAuthorized(event *WebhookEvent) {
+	data := event.Data
+	if data == nil {
+		logger.Errorf("pagarme: charge.authorized - sem dados no evento")
+		return
+	}
+
+	chargeID, _ := data["id"].(string)
+	method, _ := data["payment_method"].(string)
+	if paymentMethodByName(method).ImmediateCapture {
+		logger.Infof("charge.authorized ignorado para %s (captura imediata): charge=%s", method, chargeID)
+		return
+	}
+
+	orderData, ok := data["order"].(map[string]interface{})
+	if !ok {
+		logger.Errorf("pagarme: charge.authorized sem order no charge (charge: %s)", chargeID)
+		return
+	}
+	orderID := h.internalOrderID(orderData)
+	pagarmeOrderID, _ := orderData["id"].(string)
+	if orderID == "" {
+		logger.Errorf("pagarme: charge.authorized sem código de pedido (charge: %s)", chargeID)
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		logger.Errorf("erro ao iniciar transação para pedido %s: %v", orderID, err)
+		return
+	}
+	defer tx.Rollback()
+
+	current, err := repository.GetOrderChargeStatusTx(tx, orderID)
+	if err != nil {
+		logger.Errorf("erro ao ler status da cobrança do pedido %s: %v", orderID, err)
+		return
+	}
+	if !ChargeStatus(current).CanTransitionTo(ChargeAuthorized) {
+		logger.Warnf("cobrança do pedido %s está em %q — ignorando charge.authorized", orderID, current)
+		return
+	}
+	if err := repository.SetOrderChargeStatusTx(tx, orderID, string(ChargeAuthorized)); err != nil {
+		logger.Errorf("erro ao registrar autorização do pedido %s: %v", orderID, err)
+		return
+	}
+	_, status, _, err := repository.OrderByIDTx(tx, orderID)
+	if err != nil {
+		logger.Errorf("pedido %s não encontrado na transação: %v", orderID, err)
+		return
+	}
+	if err := repository.RecordOrderStatusChange(tx, orderID, status, status, "webhook_charge_authorized", event.ID, pagarmeOrderID, chargeID); err != nil {
+		logger.Errorf("erro ao registrar autorização no histórico do pedido %s: %v", orderID, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Errorf("erro ao commitar autorização do pedido %s: %v", orderID, err)
+		return
+	}
+	logger.Infof("cobrança autorizada, aguardando captura: pedido=%s charge=%s", orderID, chargeID)
+}
+
 // processOrderPayment handles the common logic for confirming an order:
 // Uses atomic transaction with optimistic locking to prevent race conditions.
 // Validates payment amount to prevent fraud.
 // Creates audit trail of status changes.
+//
+// The paid amount is fetched from Pagar.me once, before the transaction begins,
+// so the SQLite write lock is never held across a network round trip.
 func (h *Handler) processOrderPayment(orderID, pagarmeOrderID, chargeID string) {
+	h.payments.start()
+	defer h.payments.done()
 	logger.Infof("processando pagamento do pedido: pedido=%s pagarme_order=%s charge=%s", orderID, pagarmeOrderID, chargeID)
 
+	// Fetch the paid amount before opening the transaction (network call),
+	// retrying while it may still be settling
+	var paidAmount int64
+	var paidAmountErr error
+	if pagarmeOrderID != "" {
+		paidAmount, paidAmountErr = h.settledPaidAmount(orderID, pagarmeOrderID)
+	}
+
 	// Begin atomic transaction
 	tx, err := h.db.Begin()
 	if err != nil {
@@ -689,30 +1268,45 @@ func (h *Handler) processOrderPayment(orderID, pagarmeOrderID, chargeID string)
 
 	// 3. Get order details
 	orderUserID, orderStatus, orderTotal, err := repository.OrderByIDTx(tx, orderID)
-	if err != nil || orderUserID == "" {
+	if err != nil {
 		logger.Errorf("pedido %s não encontrado na transação: %v", orderID, err)
 		return
 	}
+	// Paid, failed and canceled charges are final (see chargeTransitions): a
+	// payment on one is an anomaly for support, not a confirmation
+	chargeStatus, err := repository.GetOrderChargeStatusTx(tx, orderID)
+	if err != nil {
+		logger.Errorf("erro ao ler status da cobrança do pedido %s: %v", orderID, err)
+		return
+	}
+	if !ChargeStatus(chargeStatus).CanTransitionTo(ChargePaid) {
+		logger.Errorf("cobrança do pedido %s está em %q — pagamento não confirmado, verificar no Pagar.me", orderID, chargeStatus)
+		return
+	}
 
 	// 4. Validate payment amount (CRITICAL SECURITY CHECK)
 	if pagarmeOrderID != "" {
-		paidAmount, err := h.client.GetOrderPaidAmount(pagarmeOrderID)
-		if err != nil {
-			logger.Errorf("erro ao obter valor pago no Pagar.me para pedido %s: %v", orderID, err)
-			// Record failed validation
-			repository.RecordOrderStatusChangeWithError(tx, orderID, orderStatus, "FRAUD_ALERT", "payment_validation_failed", err.Error())
+		if paidAmountErr != nil {
+			logger.Errorf("erro ao obter valor pago no Pagar.me para pedido %s: %v", orderID, paidAmountErr)
+			// Release the claim (back to PENDING) so a retry can confirm the
+			// order, then record the failed validation on its own so the
+			// rollback doesn't drop it
+			tx.Rollback()
+			if err := repository.RecordOrderStatusChangeWithError(h.db, orderID, "PENDING", "FRAUD_ALERT", "payment_validation_failed", paidAmountErr.Error()); err != nil {
+				logger.Errorf("erro ao registrar falha de validação do pedido %s: %v", orderID, err)
+			}
 			return
 		}
 
-		expectedAmount := int64(orderTotal * 100) // Convert to centavos
+		expectedAmount := money.FromReais(orderTotal)
 		if paidAmount != expectedAmount {
-			logger.Warnf("alerta de fraude no pedido %s: esperado %d centavos, pago %d centavos", orderID, expectedAmount, paidAmount)
+			logger.Warnf("alerta de fraude no pedido %s: esperado %s, pago %s", orderID, money.FormatBRL(expectedAmount), money.FormatBRL(paidAmount))
 			// Record fraud attempt
 			repository.RecordOrderStatusChange(tx, orderID, orderStatus, "FRAUD_ALERT", "amount_mismatch", "", pagarmeOrderID, chargeID)
 			tx.Commit() // Commit the fraud record
 			return
 		}
-		logger.Infof("pagamento validado: pedido=%s valor=%d centavos", orderID, paidAmount)
+		logger.Infof("pagamento validado: pedido=%s valor=%s", orderID, money.FormatBRL(paidAmount))
 	}
 
 	// 5. Get order items within transaction
@@ -721,9 +1315,16 @@ func (h *Handler) processOrderPayment(orderID, pagarmeOrderID, chargeID string)
 		logger.Errorf("erro ao obter itens do pedido %s: %v", orderID, err)
 		return
 	}
+	// Orders created with their items already hold their lot places
+	reserved, err := repository.OrderInventoryReservedTx(tx, orderID)
+	if err != nil {
+		logger.Errorf("erro ao verificar reserva do pedido %s: %v", orderID, err)
+		return
+	}
 
 	// 6. Create tickets atomically
 	ticketsCreated := 0
+	eventTitle := ""
 	for _, item := range items {
 		evDate, err := repository.EventDateByIDTx(tx, item.EventDateID)
 		if err != nil || evDate == nil {
@@ -736,6 +1337,7 @@ func (h *Handler) processOrderPayment(orderID, pagarmeOrderID, chargeID string)
 			logger.Errorf("evento não encontrado: %s", evDate.EventID)
 			return
 		}
+		eventTitle = ev.Title
 
 		tt, err := repository.TicketTypeByIDTx(tx, item.TicketTypeID)
 		if err != nil || tt == nil {
@@ -749,12 +1351,12 @@ func (h *Handler) processOrderPayment(orderID, pagarmeOrderID, chargeID string)
 			code := repository.GenerateTicketCode()
 
 			// QR payload with charge_id and event_id for traceability
-			qrPayload := qrcode.GenerateSignedPayloadV2(ticketID, chargeID, ev.ID, []byte(h.cfg.JWTSecret))
+			qrPayload := h.cfg.QRRing().SignV2(ticketID, chargeID, ev.ID)
 
 			err := repository.CreateTicketWithIDTx(
 				tx, ticketID, code, qrPayload,
 				orderID, item.ID, orderUserID,
-				ev.ID, item.EventDateID, item.TicketTypeID,
+				ev.ID, item.EventDateID, item.TicketTypeID, repository.TicketSourcePurchase,
 			)
 			if err != nil {
 				logger.Errorf("erro ao criar ingresso: %v", err)
@@ -768,14 +1370,21 @@ func (h *Handler) processOrderPayment(orderID, pagarmeOrderID, chargeID string)
 				return
 			}
 
-			// Decrement available quantity (with validation)
+			if reserved {
+				continue
+			}
+			// Decrement available quantity (with validation); lot capacity
+			// counts people, so a multi-admit ticket takes tt.Admits places
 			lotID, err := repository.LotIDByTicketTypeIDTx(tx, item.TicketTypeID)
 			if err != nil {
 				logger.Errorf("erro ao obter lote: %v", err)
 				return
 			}
 
-			if err := repository.DecrementLotAvailableTx(tx, lotID, 1); err != nil {
+			if err := repository.DecrementLotAvailableTx(tx, lotID, tt.Admits); errors.Is(err, repository.ErrInsufficientInventory) {
+				logger.Errorf("lote %s esgotado ao confirmar pedido %s (evitando oversell)", lotID, orderID)
+				return
+			} else if err != nil {
 				logger.Errorf("erro ao decrementar disponível no lote (evitando oversell): %v", err)
 				return
 			}
@@ -785,17 +1394,37 @@ func (h *Handler) processOrderPayment(orderID, pagarmeOrderID, chargeID string)
 	logger.Infof("ingressos criados: pedido=%s quantidade=%d", orderID, ticketsCreated)
 
 	// 7. Confirm the order (PROCESSING → PAID)
-	if err := repository.ConfirmOrderTx(tx, orderID); err != nil {
+	if err := repository.ConfirmOrderTx(tx, orderID); errors.Is(err, repository.ErrOrderNotProcessing) {
+		logger.Errorf("pedido %s saiu de PROCESSING durante o processamento — revertendo", orderID)
+		return
+	} else if err != nil {
 		logger.Errorf("erro ao confirmar pedido %s: %v", orderID, err)
 		return
 	}
 
+	// Immediate-capture charges (PIX) arrive here straight from pending;
+	// authorized card charges were recorded by handleChargeAuthorized.
+	if err := repository.SetOrderChargeStatusTx(tx, orderID, string(ChargePaid)); err != nil {
+		logger.Errorf("erro ao registrar status da cobrança do pedido %s: %v", orderID, err)
+		return
+	}
+
 	// 8. Record status change for audit trail
 	if err := repository.RecordOrderStatusChange(tx, orderID, "PROCESSING", "PAID", "webhook_payment_confirmed", "", pagarmeOrderID, chargeID); err != nil {
 		logger.Warnf("erro ao registrar alteração de status (não-fatal): %v", err)
 		// Continue - this is just for audit
 	}
 
+	// The buyer's notification is queued with the confirmation, so it can't
+	// be lost once the order is PAID
+	orderRef, _ := repository.OrderRefByIDTx(tx, orderID)
+	notificationID, err := outbox.NotifyTx(tx, orderUserID, repository.NotificationTicketPurchased, outbox.TicketPurchasedPayload{
+		OrderID: orderID, OrderRef: orderRef, EventTitle: eventTitle, Tickets: ticketsCreated,
+	})
+	if err != nil {
+		logger.Warnf("erro ao enfileirar notificação do pedido %s (não-fatal): %v", orderID, err)
+	}
+
 	// 9. COMMIT transaction (all-or-nothing)
 	if err := tx.Commit(); err != nil {
 		logger.Errorf("erro ao commitar transação do pedido %s: %v", orderID, err)
@@ -804,4 +1433,9 @@ func (h *Handler) processOrderPayment(orderID, pagarmeOrderID, chargeID string)
 
 	logger.Infof("pedido confirmado: pedido=%s status=PAID ingressos=%d pagarme_order=%s charge=%s",
 		orderID, ticketsCreated, pagarmeOrderID, chargeID)
+	recordConfirmationLatency(h.db, orderID)
+
+	if notificationID != "" {
+		outbox.Deliver(h.db, h.mailer, notificationID)
+	}
 }
@@ -0,0 +1,770 @@
+package pagarme
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/qrcode"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+// newTestDB opens a migrated in-memory SQLite database.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	return testutil.NewDB(t)
+}
+
+// seedPendingOrder creates a buyer, an event with one lot/ticket type and a
+// PENDING order for quantity tickets (see testutil.Seed). Returns the order ID
+// and the lot ID.
+func seedPendingOrder(t *testing.T, sqlite *sql.DB, quantity, lotQuantity int, price float64) (orderID, lotID string) {
+	t.Helper()
+	f := testutil.Seed(t, sqlite, lotQuantity, price)
+	return f.PendingOrder(t, sqlite, quantity), f.LotID
+}
+
+// withUser returns a copy of r authenticated as userID.
+func withUser(r *http.Request, userID string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, userID))
+}
+
+// captureMailer records every message instead of sending it.
+type captureMailer struct {
+	sent []mailer.Message
+}
+
+func (m *captureMailer) Send(msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestProcessOrderPaymentDoesNotBlockWritersDuringGatewayCall(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 2, 10, 50)
+
+	const gatewayDelay = 500 * time.Millisecond
+	requested := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- struct{}{}
+		time.Sleep(gatewayDelay)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"or_test","status":"paid","amount":10000}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("sk_test", "", "", 500, "http://localhost")
+	client.apiURL = server.URL
+	h := NewHandler(client, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+
+	done := make(chan struct{})
+	go func() {
+		h.processOrderPayment(orderID, "or_test", "ch_test")
+		close(done)
+	}()
+
+	// While the gateway call is in flight, another writer must not be blocked.
+	<-requested
+	start := time.Now()
+	if _, err := repository.CreateUser(sqlite, "Outro", "outro@email.com", "hash", "39053344705", "1990-01-01", nil, nil, nil); err != nil {
+		t.Fatalf("concurrent write: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > gatewayDelay/2 {
+		t.Errorf("concurrent write took %v; transaction appears to be held during gateway call", elapsed)
+	}
+
+	<-done
+	_, status, _, err := repository.OrderByID(sqlite, orderID)
+	if err != nil {
+		t.Fatalf("order by id: %v", err)
+	}
+	if status != "PAID" {
+		t.Errorf("order status = %s, want PAID", status)
+	}
+}
+
+func TestCreatePaymentWithFakeClient(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 2, 10, 50)
+	buyerID, _, _, err := repository.OrderByID(sqlite, orderID)
+	if err != nil {
+		t.Fatalf("order by id: %v", err)
+	}
+
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(`{"orderId":"`+orderID+`"}`))
+	rec := httptest.NewRecorder()
+	h.CreatePayment(rec, withUser(req, buyerID))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var result PixOrderResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.PagarmeOrderID != "or_"+orderID {
+		t.Errorf("pagarmeOrderId = %s, want or_%s", result.PagarmeOrderID, orderID)
+	}
+	if got := fake.callCount("CreatePixOrder"); got != 1 {
+		t.Errorf("CreatePixOrder calls = %d, want 1", got)
+	}
+	stored, _ := repository.OrderRowByID(sqlite, orderID)
+	if stored.PagarmeOrderID != result.PagarmeOrderID {
+		t.Errorf("stored pagarme_order_id = %s, want %s", stored.PagarmeOrderID, result.PagarmeOrderID)
+	}
+	if stored.PixQRCode != "000201fake" || stored.PixExpiresAt == "" {
+		t.Errorf("stored pix = %q expiring %q, want the PIX code and its expiry", stored.PixQRCode, stored.PixExpiresAt)
+	}
+}
+
+// postWebhook sends an order.paid event for orderID through HandleWebhook.
+func postWebhook(t *testing.T, h *Handler, eventID, orderID, pagarmeOrderID string) {
+	t.Helper()
+	body := `{"id":"` + eventID + `","type":"order.paid","data":{"id":"` + pagarmeOrderID + `","code":"` + orderID + `","charges":[{"id":"ch_test"}]}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/webhook", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.HandleWebhook(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("webhook status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func countRows(t *testing.T, sqlite *sql.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := sqlite.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("count %q: %v", query, err)
+	}
+	return n
+}
+
+func TestHandleWebhookOrderPaidConfirmsOrder(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, lotID := seedPendingOrder(t, sqlite, 3, 10, 50)
+
+	fake := newFakeClient()
+	fake.paidAmounts["or_test"] = 15000
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+
+	postWebhook(t, h, "hook_1", orderID, "or_test")
+
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM tickets WHERE order_id = ?`, orderID); got != 3 {
+		t.Errorf("tickets = %d, want 3", got)
+	}
+	if got := countRows(t, sqlite, `SELECT sold_quantity FROM ticket_types WHERE lot_id = ?`, lotID); got != 3 {
+		t.Errorf("sold_quantity = %d, want 3", got)
+	}
+	if got := countRows(t, sqlite, `SELECT available_quantity FROM lots WHERE id = ?`, lotID); got != 7 {
+		t.Errorf("available_quantity = %d, want 7", got)
+	}
+	_, status, _, _ := repository.OrderByID(sqlite, orderID)
+	if status != "PAID" {
+		t.Errorf("order status = %s, want PAID", status)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM order_status_history WHERE order_id = ? AND new_status = 'PAID'`, orderID); got != 1 {
+		t.Errorf("PAID history rows = %d, want 1", got)
+	}
+	if cs, _ := repository.GetOrderChargeStatus(sqlite, orderID); cs != "paid" {
+		t.Errorf("charge status = %q, want paid", cs)
+	}
+
+	// Same event delivered again, and a second event for the same order: no new tickets.
+	postWebhook(t, h, "hook_1", orderID, "or_test")
+	postWebhook(t, h, "hook_2", orderID, "or_test")
+
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM tickets WHERE order_id = ?`, orderID); got != 3 {
+		t.Errorf("tickets after duplicates = %d, want 3", got)
+	}
+	if got := countRows(t, sqlite, `SELECT available_quantity FROM lots WHERE id = ?`, lotID); got != 7 {
+		t.Errorf("available_quantity after duplicates = %d, want 7", got)
+	}
+}
+
+func TestConcurrentDuplicateWebhookIsProcessedOnce(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, lotID := seedPendingOrder(t, sqlite, 2, 10, 50)
+
+	fake := newFakeClient()
+	fake.paidAmounts["or_test"] = 10000
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+
+	body := `{"id":"hook_dup","type":"order.paid","data":{"id":"or_test","code":"` + orderID + `","charges":[{"id":"ch_test"}]}}`
+	const deliveries = 8
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < deliveries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			rec := httptest.NewRecorder()
+			h.HandleWebhook(rec, httptest.NewRequest(http.MethodPost, "/v1/webhook", strings.NewReader(body)))
+			if rec.Code != http.StatusOK {
+				t.Errorf("webhook status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// Only the delivery that inserted the event may reach the gateway
+	if got := fake.callCount("GetOrderPaidAmount"); got != 1 {
+		t.Errorf("GetOrderPaidAmount calls = %d, want 1", got)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM pagarme_webhook_events WHERE pagarme_event_id = 'hook_dup'`); got != 1 {
+		t.Errorf("webhook event rows = %d, want 1", got)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM tickets WHERE order_id = ?`, orderID); got != 2 {
+		t.Errorf("tickets = %d, want 2", got)
+	}
+	if got := countRows(t, sqlite, `SELECT available_quantity FROM lots WHERE id = ?`, lotID); got != 8 {
+		t.Errorf("available_quantity = %d, want 8", got)
+	}
+}
+
+func TestHandleWebhookAmountMismatchRaisesFraudAlert(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, lotID := seedPendingOrder(t, sqlite, 2, 10, 50)
+
+	fake := newFakeClient()
+	fake.paidAmounts["or_test"] = 100 // R$1,00 paid for a R$100,00 order
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+
+	postWebhook(t, h, "hook_fraud", orderID, "or_test")
+
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM tickets WHERE order_id = ?`, orderID); got != 0 {
+		t.Errorf("tickets = %d, want 0", got)
+	}
+	// The order keeps the places it reserved while it is reviewed
+	if got := countRows(t, sqlite, `SELECT available_quantity FROM lots WHERE id = ?`, lotID); got != 8 {
+		t.Errorf("available_quantity = %d, want 8", got)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM order_status_history WHERE order_id = ? AND new_status = 'FRAUD_ALERT' AND reason = 'amount_mismatch'`, orderID); got != 1 {
+		t.Errorf("FRAUD_ALERT history rows = %d, want 1", got)
+	}
+	_, status, _, _ := repository.OrderByID(sqlite, orderID)
+	if status == "PAID" {
+		t.Errorf("order status = PAID, want unconfirmed")
+	}
+}
+
+func TestPaidAmountLookupFailureIsRecorded(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+
+	fake := newFakeClient()
+	fake.paidErr = fmt.Errorf("gateway fora do ar")
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	postWebhook(t, h, "hook_1", orderID, "or_test")
+
+	if _, status, _, _ := repository.OrderByID(sqlite, orderID); status != "PENDING" {
+		t.Errorf("order status = %s, want PENDING so a retry can confirm it", status)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM order_status_history WHERE order_id = ? AND new_status = 'FRAUD_ALERT' AND reason = 'payment_validation_failed' AND error_message LIKE '%fora do ar%'`, orderID); got != 1 {
+		t.Errorf("validation failure rows = %d, want 1", got)
+	}
+}
+
+func TestFraudCheckWaitsForSettlingAmount(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 2, 10, 50)
+
+	fake := newFakeClient()
+	fake.settling = map[string][]int64{"or_test": {5000}} // half reported on the first read
+	fake.paidAmounts["or_test"] = 10000
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret", FraudCheckRetries: 2, FraudCheckDelay: time.Millisecond}, mailer.LogMailer{})
+
+	postWebhook(t, h, "hook_settling", orderID, "or_test")
+
+	if _, status, _, _ := repository.OrderByID(sqlite, orderID); status != "PAID" {
+		t.Fatalf("order status = %s, want PAID once the amount settled", status)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM order_status_history WHERE order_id = ? AND new_status = 'FRAUD_ALERT'`, orderID); got != 0 {
+		t.Errorf("FRAUD_ALERT rows = %d, want 0", got)
+	}
+	if n := fake.callCount("GetOrderPaidAmount"); n != 2 {
+		t.Errorf("GetOrderPaidAmount calls = %d, want 2", n)
+	}
+}
+
+func TestFraudCheckFlagsPersistentMismatchAfterRetries(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 2, 10, 50)
+
+	fake := newFakeClient()
+	fake.paidAmounts["or_test"] = 100
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret", FraudCheckRetries: 2, FraudCheckDelay: time.Millisecond}, mailer.LogMailer{})
+
+	postWebhook(t, h, "hook_fraud", orderID, "or_test")
+
+	if n := fake.callCount("GetOrderPaidAmount"); n != 3 {
+		t.Errorf("GetOrderPaidAmount calls = %d, want 3 (1 + 2 retries)", n)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM order_status_history WHERE order_id = ? AND new_status = 'FRAUD_ALERT' AND reason = 'amount_mismatch'`, orderID); got != 1 {
+		t.Errorf("FRAUD_ALERT rows = %d, want 1", got)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM tickets WHERE order_id = ?`, orderID); got != 0 {
+		t.Errorf("tickets = %d, want 0", got)
+	}
+}
+
+func TestFraudCheckWaitsAreCapped(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 2, 10, 50)
+
+	fake := newFakeClient()
+	fake.paidAmounts["or_test"] = 100
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret", FraudCheckRetries: 10, FraudCheckDelay: 10 * time.Millisecond, FraudCheckMaxWait: 25 * time.Millisecond}, mailer.LogMailer{})
+
+	postWebhook(t, h, "hook_fraud", orderID, "or_test")
+
+	if n := fake.callCount("GetOrderPaidAmount"); n != 3 {
+		t.Errorf("GetOrderPaidAmount calls = %d, want 3 (1 + the 2 retries that fit in the wait cap)", n)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM order_status_history WHERE order_id = ? AND new_status = 'FRAUD_ALERT'`, orderID); got != 1 {
+		t.Errorf("FRAUD_ALERT rows = %d, want 1", got)
+	}
+}
+
+func TestConcurrentConfirmationsDoNotOversellLot(t *testing.T) {
+	sqlite := newTestDB(t)
+	// Lot has 5 seats; 4 orders of 2 tickets compete for them.
+	firstOrderID, lotID := seedPendingOrder(t, sqlite, 2, 5, 50)
+	items, err := repository.OrderItemsByOrderID(sqlite, firstOrderID)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("order items: %v", err)
+	}
+	item := items[0]
+
+	orderIDs := []string{firstOrderID}
+	for _, cpf := range []string{"16899535009", "71428793860", "87748248800"} {
+		buyerID, err := repository.CreateUser(sqlite, "Comprador", cpf+"@email.com", "hash", cpf, "1990-01-01", nil, nil, nil)
+		if err != nil {
+			t.Fatalf("create buyer: %v", err)
+		}
+		orderID, err := repository.CreateOrder(sqlite, buyerID, 100, 30*time.Minute)
+		if err != nil {
+			t.Fatalf("create order: %v", err)
+		}
+		if _, err := repository.CreateOrderItem(sqlite, orderID, item.EventDateID, item.TicketTypeID, 2, 50); err != nil {
+			t.Fatalf("create order item: %v", err)
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+
+	fake := newFakeClient()
+	for _, id := range orderIDs {
+		fake.paidAmounts["or_"+id] = 10000
+	}
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+
+	var wg sync.WaitGroup
+	for _, id := range orderIDs {
+		wg.Add(1)
+		go func(orderID string) {
+			defer wg.Done()
+			h.processOrderPayment(orderID, "or_"+orderID, "ch_"+orderID)
+		}(id)
+	}
+	wg.Wait()
+
+	available := countRows(t, sqlite, `SELECT available_quantity FROM lots WHERE id = ?`, lotID)
+	if available < 0 {
+		t.Fatalf("available_quantity went negative: %d", available)
+	}
+	if available != 1 {
+		t.Errorf("available_quantity = %d, want 1", available)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM tickets`); got != 4 {
+		t.Errorf("tickets = %d, want 4", got)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM orders WHERE status = 'PAID'`); got != 2 {
+		t.Errorf("paid orders = %d, want 2", got)
+	}
+	if got := countRows(t, sqlite, `SELECT sold_quantity FROM ticket_types WHERE id = ?`, item.TicketTypeID); got != 4 {
+		t.Errorf("sold_quantity = %d, want 4", got)
+	}
+}
+
+func TestOrderRefsAreSequentialAndResolveWebhooks(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+
+	const n = 20
+	var wg sync.WaitGroup
+	refs := make(chan string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := repository.CreateOrder(sqlite, buyerID, 10, 30*time.Minute)
+			if err != nil {
+				t.Errorf("create order: %v", err)
+				return
+			}
+			ref, _ := repository.OrderRefByID(sqlite, id)
+			refs <- ref
+		}()
+	}
+	wg.Wait()
+	close(refs)
+	seen := map[string]bool{}
+	for ref := range refs {
+		if seen[ref] {
+			t.Errorf("duplicate order ref %s", ref)
+		}
+		seen[ref] = true
+	}
+	year := time.Now().UTC().Year()
+	if want := fmt.Sprintf("AFZ-%d-%06d", year, n+1); !seen[want] {
+		t.Errorf("expected last ref %s among %v", want, seen)
+	}
+
+	// Pagar.me echoes the reference as "code"; the webhook must map it back
+	ref, _ := repository.OrderRefByID(sqlite, orderID)
+	fake := newFakeClient()
+	fake.paidAmounts["or_test"] = 5000
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	postWebhook(t, h, "hook_ref", ref, "or_test")
+	if _, status, _, _ := repository.OrderByID(sqlite, orderID); status != "PAID" {
+		t.Errorf("status = %s, want PAID", status)
+	}
+}
+
+func TestCreatePaymentRejectsInvalidBuyerEmail(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+	if _, err := sqlite.Exec(`UPDATE users SET email = 'sem-arroba' WHERE id = ?`, buyerID); err != nil {
+		t.Fatalf("update email: %v", err)
+	}
+
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	rec := httptest.NewRecorder()
+	h.CreatePayment(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(`{"orderId":"`+orderID+`"}`)), buyerID))
+
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "email do comprador") {
+		t.Errorf("status = %d, body = %s; want 400 about the buyer email", rec.Code, rec.Body.String())
+	}
+	if fake.callCount("CreatePixOrder") != 0 {
+		t.Error("gateway called with an invalid email")
+	}
+}
+
+func TestCreatePaymentRejectsInvalidBuyerCPF(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+	// Eleven digits, but not a valid CPF
+	if _, err := sqlite.Exec(`UPDATE users SET cpf = '11111111111' WHERE id = ?`, buyerID); err != nil {
+		t.Fatalf("update cpf: %v", err)
+	}
+
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	rec := httptest.NewRecorder()
+	h.CreatePayment(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(`{"orderId":"`+orderID+`"}`)), buyerID))
+
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "CPF inválido") {
+		t.Errorf("status = %d, body = %s; want 400 about the CPF", rec.Code, rec.Body.String())
+	}
+	if fake.callCount("CreatePixOrder") != 0 {
+		t.Error("gateway called with an invalid CPF")
+	}
+}
+
+func TestStrictJSONRejectsUnknownFields(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+
+	for _, strict := range []bool{true, false} {
+		h := NewHandler(newFakeClient(), sqlite, &config.Config{JWTSecret: "test-secret", StrictJSON: strict}, mailer.LogMailer{})
+		rec := httptest.NewRecorder()
+		h.CreatePayment(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(`{"order_id":"`+orderID+`"}`)), buyerID))
+
+		var body map[string]string
+		json.NewDecoder(rec.Body).Decode(&body)
+		if strict {
+			if rec.Code != http.StatusBadRequest || body["error"] != "campo desconhecido: order_id" || body["field"] != "order_id" {
+				t.Errorf("strict: status = %d, body = %v", rec.Code, body)
+			}
+		} else if rec.Code != http.StatusBadRequest || body["error"] != "orderId é obrigatório" {
+			// Lenient decoding drops the misspelled field, as before.
+			t.Errorf("lenient: status = %d, body = %v", rec.Code, body)
+		}
+	}
+}
+
+func TestCreatePaymentRejectsOversizedCart(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 4, 10, 50)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret", MaxOrderItems: 2, MaxItemQuantity: 3}, mailer.LogMailer{})
+
+	item := `{"eventDateId":"d","ticketTypeId":"t","quantity":1}`
+	body := `{"guest":{"name":"Convidado","email":"c@email.com","cpf":"52998224725"},"items":[` + item + `,` + item + `,` + item + `]}`
+	rec := httptest.NewRecorder()
+	h.CreatePayment(rec, httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "itens demais") {
+		t.Errorf("too many items: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.CreatePayment(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(`{"orderId":"`+orderID+`"}`)), buyerID))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "máximo de 3 ingressos") {
+		t.Errorf("quantity above the cap: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if fake.callCount("CreatePixOrder") != 0 {
+		t.Error("gateway called for an oversized cart")
+	}
+}
+
+func TestPaymentStatusByPagarmeOrderIDIsAdminOnly(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 2, 10, 50)
+	fake := newFakeClient()
+	fake.paidAmounts["or_dash"] = 10000
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	if err := repository.SetOrderPagarmeOrderID(sqlite, orderID, "or_dash"); err != nil {
+		t.Fatalf("set pagarme order id: %v", err)
+	}
+	postWebhook(t, h, "hook_1", orderID, "or_dash")
+
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+	admin, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	if _, err := sqlite.Exec(`UPDATE users SET role = 'ADMIN' WHERE id = ?`, admin.ID); err != nil {
+		t.Fatalf("promote admin: %v", err)
+	}
+
+	lookup := func(userID, pagarmeOrderID string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.GetPaymentStatus(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/payment/status?pagarmeOrderId="+pagarmeOrderID, nil), userID))
+		return rec
+	}
+
+	if rec := lookup(buyerID, "or_dash"); rec.Code != http.StatusForbidden {
+		t.Errorf("buyer: status = %d, want 403", rec.Code)
+	}
+	if rec := lookup(admin.ID, "or_unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown: status = %d, want 404", rec.Code)
+	}
+
+	rec := lookup(admin.ID, "or_dash")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("admin: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		OrderID     string                   `json:"orderId"`
+		OrderStatus string                   `json:"orderStatus"`
+		History     []map[string]interface{} `json:"history"`
+		Tickets     []map[string]interface{} `json:"tickets"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.OrderID != orderID || resp.OrderStatus != "PAID" {
+		t.Errorf("order = %s/%s, want %s/PAID", resp.OrderID, resp.OrderStatus, orderID)
+	}
+	if len(resp.History) == 0 || resp.History[len(resp.History)-1]["newStatus"] != "PAID" {
+		t.Errorf("history = %v", resp.History)
+	}
+	if len(resp.Tickets) != 2 {
+		t.Errorf("tickets = %d, want 2", len(resp.Tickets))
+	}
+}
+
+func TestPaymentStatusChecksOrderOwner(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+
+	rec := httptest.NewRecorder()
+	h := NewHandler(newFakeClient(), sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	h.GetPaymentStatus(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/payment/status?orderId="+orderID, nil), producer.ID))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("GetPaymentStatus other user: status = %d, want 403", rec.Code)
+	}
+}
+
+func TestErrorMessagesFollowAcceptLanguage(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	h := NewHandler(newFakeClient(), sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	status := middleware.Language(http.HandlerFunc(h.GetPaymentStatus))
+
+	cases := []struct {
+		accept, query, want string
+	}{
+		{"", "?orderId=" + orderID, "pedido não pertence ao usuário"},
+		{"en-US,en;q=0.9", "?orderId=" + orderID, "order does not belong to the user"},
+		{"en", "", "orderId is required"},
+		{"pt-BR", "", "orderId é obrigatório"},
+	}
+	for _, c := range cases {
+		req := withUser(httptest.NewRequest(http.MethodGet, "/v1/payment/status"+c.query, nil), producer.ID)
+		if c.accept != "" {
+			req.Header.Set("Accept-Language", c.accept)
+		}
+		rec := httptest.NewRecorder()
+		status.ServeHTTP(rec, req)
+		var body map[string]string
+		json.NewDecoder(rec.Body).Decode(&body)
+		if body["error"] != c.want {
+			t.Errorf("Accept-Language %q: error = %q, want %q", c.accept, body["error"], c.want)
+		}
+	}
+}
+
+func TestGetPaymentStatusLive(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	get := func(query string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		h.GetPaymentStatus(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/payment/status?orderId="+orderID+query, nil), buyerID))
+		var body map[string]interface{}
+		json.NewDecoder(rec.Body).Decode(&body)
+		return rec, body
+	}
+
+	// Not sent to Pagar.me yet: reported, not fetched.
+	_, body := get("&live=true")
+	if body["gatewayError"] == nil || fake.callCount("GetOrderStatus") != 0 {
+		t.Fatalf("unsent order: body = %v, calls = %d", body, fake.callCount("GetOrderStatus"))
+	}
+	liveStatusChecks.Lock()
+	delete(liveStatusChecks.last, orderID)
+	liveStatusChecks.Unlock()
+
+	// The gateway already says paid, but the webhook hasn't landed: the DB wins.
+	if err := repository.SetOrderPagarmeOrderID(sqlite, orderID, "or_live"); err != nil {
+		t.Fatalf("set pagarme order: %v", err)
+	}
+	fake.orderStatus = "paid"
+	rec, body := get("&live=true")
+	if rec.Code != http.StatusOK || body["gatewayStatus"] != "paid" || body["paid"] != false || body["status"] != "pending" {
+		t.Fatalf("live status = %d %v, want gateway paid / db pending", rec.Code, body)
+	}
+
+	rec, _ = get("&live=true")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("second live check: status = %d, want 429 with Retry-After", rec.Code)
+	}
+	if fake.callCount("GetOrderStatus") != 1 {
+		t.Errorf("GetOrderStatus calls = %d, want 1", fake.callCount("GetOrderStatus"))
+	}
+
+	// Plain polling is never limited and never calls the gateway.
+	rec, body = get("")
+	if rec.Code != http.StatusOK || body["gatewayStatus"] != nil {
+		t.Errorf("db-only status = %d %v", rec.Code, body)
+	}
+}
+
+func TestAdminPagarmeOrderPassthrough(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+	admin, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	if _, err := sqlite.Exec(`UPDATE users SET role = 'ADMIN' WHERE id = ?`, admin.ID); err != nil {
+		t.Fatalf("promote admin: %v", err)
+	}
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+
+	get := func(userID string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.AdminPagarmeOrder(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/admin/pagarme/order?id=or_raw", nil), userID))
+		return rec
+	}
+
+	if rec := get(buyerID); rec.Code != http.StatusForbidden {
+		t.Errorf("buyer: status = %d, want 403", rec.Code)
+	}
+	if fake.callCount("GetOrder") != 0 {
+		t.Error("gateway called for a non-admin")
+	}
+
+	rec := get(admin.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("admin: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var order map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&order)
+	if order["id"] != "or_raw" || order["status"] != "pending" {
+		t.Errorf("order = %v", order)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM audit_log`); got != 0 {
+		t.Errorf("audit rows = %d, want none for a read", got)
+	}
+}
+
+func TestMultiAdmitTicketIssuance(t *testing.T) {
+	sqlite := newTestDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	if _, err := sqlite.Exec(`UPDATE ticket_types SET admits = 6`); err != nil {
+		t.Fatal(err)
+	}
+	orderID, lotID := f.PendingOrder(t, sqlite, 1), f.LotID
+	fake := newFakeClient()
+	fake.paidAmounts["or_test"] = 5000
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	postWebhook(t, h, "hook_1", orderID, "or_test")
+
+	tickets, _ := repository.TicketsByOrderID(sqlite, orderID)
+	if len(tickets) != 1 || tickets[0].Admits != 6 || tickets[0].AdmitsRemaining != 6 {
+		t.Fatalf("tickets = %+v, want one ticket admitting 6", tickets)
+	}
+	lot, _ := repository.LotByID(sqlite, lotID)
+	if lot.AvailableQuantity != 4 {
+		t.Errorf("lot available = %d, want 4 (10 - 6 places)", lot.AvailableQuantity)
+	}
+	if v, _ := repository.VerifyInventoryInvariants(sqlite); len(v) != 0 {
+		t.Errorf("inventory violations = %+v", v)
+	}
+	tt, _ := repository.TicketTypeByID(sqlite, tickets[0].TicketTypeID)
+	if got := AvailableUnits(sqlite, tt); got != 0 {
+		t.Errorf("available units = %d, want 0 (4 places left, 6 per unit)", got)
+	}
+}
+
+func TestTicketsSignedWithCurrentQRKey(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	fake := newFakeClient()
+	fake.paidAmounts["or_test"] = 5000
+	cfg := &config.Config{JWTSecret: "test-secret", QRSigningKeys: []string{"qr-new", "qr-old"}}
+	h := NewHandler(fake, sqlite, cfg, mailer.LogMailer{})
+	postWebhook(t, h, "hook_1", orderID, "or_test")
+
+	tickets, _ := repository.TicketsByOrderID(sqlite, orderID)
+	if len(tickets) != 1 {
+		t.Fatalf("tickets = %d, want 1", len(tickets))
+	}
+	if _, _, _, ok := qrcode.NewKeyRing("qr-new").VerifyV2(tickets[0].QRCode); !ok {
+		t.Errorf("ticket QR %q isn't signed with the current key", tickets[0].QRCode)
+	}
+	if _, _, _, ok := qrcode.NewKeyRing("qr-old").VerifyV2(tickets[0].QRCode); ok {
+		t.Errorf("ticket QR %q is signed with a previous key", tickets[0].QRCode)
+	}
+}
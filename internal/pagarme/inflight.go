@@ -0,0 +1,22 @@
+package pagarme
+
+import "sync/atomic"
+
+// paymentsInFlight counts processOrderPayment calls still running. Payments
+// are only processed inside webhook requests, so http.Server.Shutdown already
+// waits for them; the count is for the shutdown log, which reports how many
+// were running and whether any outlived the shutdown timeout.
+type paymentsInFlight struct {
+	n atomic.Int64
+}
+
+func (p *paymentsInFlight) start() { p.n.Add(1) }
+func (p *paymentsInFlight) done()  { p.n.Add(-1) }
+
+// PaymentsInFlight returns how many payments are being processed right now.
+func (h *Handler) PaymentsInFlight() int {
+	if h == nil {
+		return 0
+	}
+	return int(h.payments.n.Load())
+}
@@ -0,0 +1,58 @@
+package pagarme
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+)
+
+func TestPaymentsInFlight(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+
+	requested := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"or_test","status":"paid","amount":5000}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("sk_test", "", "", 500, "http://localhost")
+	client.apiURL = server.URL
+	h := NewHandler(client, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+
+	if got := h.PaymentsInFlight(); got != 0 {
+		t.Fatalf("idle PaymentsInFlight = %d, want 0", got)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.processOrderPayment(orderID, "or_test", "ch_test")
+		close(done)
+	}()
+	<-requested
+	if got := h.PaymentsInFlight(); got != 1 {
+		t.Fatalf("PaymentsInFlight = %d, want 1", got)
+	}
+
+	close(release)
+	<-done
+	if got := h.PaymentsInFlight(); got != 0 {
+		t.Errorf("PaymentsInFlight after processing = %d, want 0", got)
+	}
+	if _, status, _, _ := repository.OrderByID(sqlite, orderID); status != "PAID" {
+		t.Errorf("order status = %s, want PAID", status)
+	}
+
+	var nilHandler *Handler
+	if nilHandler.PaymentsInFlight() != 0 {
+		t.Error("nil handler should have no payments in flight")
+	}
+}
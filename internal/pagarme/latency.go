@@ -0,0 +1,141 @@
+package pagarme
+
+import (
+	"database/sql"
+	"expvar"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+)
+
+// confirmationLatencyBuckets are the upper bounds of the confirmation latency
+// histogram. PIX payments are usually confirmed within seconds; minutes point
+// at delayed webhook deliveries or a contended database.
+var confirmationLatencyBuckets = []time.Duration{
+	5 * time.Second, 15 * time.Second, 30 * time.Second, time.Minute,
+	5 * time.Minute, 15 * time.Minute, time.Hour,
+}
+
+// confirmationLatency is a histogram of the time between an order's PIX
+// creation and its webhook confirmation since startup, published through
+// expvar as "pagarme_confirmation_latency". Buckets are cumulative, as in
+// Prometheus: "le_30s" counts every confirmation that took 30s or less, and
+// "le_inf" equals "count".
+var confirmationLatency = struct {
+	sync.Mutex
+	m *expvar.Map
+}{m: expvar.NewMap("pagarme_confirmation_latency")}
+
+// observeConfirmationLatency adds a confirmation to the histogram.
+func observeConfirmationLatency(d time.Duration) {
+	confirmationLatency.Lock()
+	defer confirmationLatency.Unlock()
+	for _, b := range confirmationLatencyBuckets {
+		if d <= b {
+			confirmationLatency.m.Add("le_"+b.String(), 1)
+		}
+	}
+	confirmationLatency.m.Add("le_inf", 1)
+	confirmationLatency.m.Add("count", 1)
+	confirmationLatency.m.AddFloat("sum_seconds", d.Seconds())
+}
+
+// confirmationLatencySnapshot returns the histogram as a plain map, with
+// every bucket present even when empty.
+func confirmationLatencySnapshot() map[string]float64 {
+	snapshot := map[string]float64{"le_inf": 0, "count": 0, "sum_seconds": 0}
+	for _, b := range confirmationLatencyBuckets {
+		snapshot["le_"+b.String()] = 0
+	}
+	confirmationLatency.Lock()
+	defer confirmationLatency.Unlock()
+	confirmationLatency.m.Do(func(kv expvar.KeyValue) {
+		switch v := kv.Value.(type) {
+		case *expvar.Int:
+			snapshot[kv.Key] = float64(v.Value())
+		case *expvar.Float:
+			snapshot[kv.Key] = v.Value()
+		}
+	})
+	return snapshot
+}
+
+// recordConfirmationLatency observes the latency of an order that was just
+// confirmed, and logs confirmations slower than the PIX lifetime.
+func recordConfirmationLatency(db *sql.DB, orderID string) {
+	latency, ok, err := repository.ConfirmationLatency(db, orderID)
+	if err != nil {
+		logger.Errorf("erro ao calcular latência de confirmação do pedido %s: %v", orderID, err)
+		return
+	}
+	if !ok {
+		return
+	}
+	observeConfirmationLatency(latency)
+	if latency > PixExpirationSeconds*time.Second {
+		logger.Warnf("confirmação lenta: pedido=%s latência=%s desde a criação do PIX", orderID, latency)
+	}
+}
+
+// maxSlowConfirmations caps the limit of AdminSlowConfirmations.
+const maxSlowConfirmations = 200
+
+// AdminSlowConfirmations handles GET /v1/admin/webhook/latency
+// Returns the confirmation latency histogram since startup and the slowest
+// confirmations of the last days (default 7) from the database, up to limit
+// (default 20).
+func (h *Handler) AdminSlowConfirmations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	userID := middleware.UserID(r.Context())
+	if userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return
+	}
+	if !rest.IsAdmin(h.db, userID) {
+		rest.Error(w, http.StatusForbidden, "sem permissão")
+		return
+	}
+
+	q := r.URL.Query()
+	days, limit := 7, 20
+	if v := q.Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			rest.Error(w, http.StatusBadRequest, "days inválido")
+			return
+		}
+		days = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			rest.Error(w, http.StatusBadRequest, "limit inválido")
+			return
+		}
+		limit = min(n, maxSlowConfirmations)
+	}
+
+	slowest, err := repository.SlowestConfirmations(h.db, time.Now().AddDate(0, 0, -days), limit)
+	if err != nil {
+		logger.Errorf("erro ao listar confirmações lentas: %v", err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao listar confirmações")
+		return
+	}
+	if slowest == nil {
+		slowest = []repository.ConfirmationLatencyRow{}
+	}
+	rest.JSON(w, http.StatusOK, map[string]interface{}{
+		"sinceStart": confirmationLatencySnapshot(),
+		"slowest":    slowest,
+	})
+}
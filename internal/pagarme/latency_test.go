@@ -0,0 +1,79 @@
+package pagarme
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+)
+
+func TestConfirmationLatencyIsRecorded(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+	admin, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	if _, err := sqlite.Exec(`UPDATE users SET role = 'ADMIN' WHERE id = ?`, admin.ID); err != nil {
+		t.Fatalf("promote admin: %v", err)
+	}
+	fake := newFakeClient()
+	fake.paidAmounts["or_"+orderID] = 5000
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+
+	pay := httptest.NewRecorder()
+	h.CreatePayment(pay, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(`{"orderId":"`+orderID+`"}`)), buyerID))
+	if pay.Code != http.StatusOK {
+		t.Fatalf("create payment: status = %d, body = %s", pay.Code, pay.Body.String())
+	}
+	// The PIX was created two minutes before the webhook arrives.
+	if _, err := sqlite.Exec(`UPDATE orders SET pix_created_at = ? WHERE id = ?`, repository.FormatTime(time.Now().Add(-2*time.Minute)), orderID); err != nil {
+		t.Fatal(err)
+	}
+
+	before := confirmationLatencySnapshot()
+	postWebhook(t, h, "hook_1", orderID, "or_"+orderID)
+
+	var confirmedAt string
+	if err := sqlite.QueryRow(`SELECT COALESCE(confirmed_at, '') FROM orders WHERE id = ?`, orderID).Scan(&confirmedAt); err != nil || confirmedAt == "" {
+		t.Fatalf("confirmed_at = %q, %v", confirmedAt, err)
+	}
+	latency, ok, err := repository.ConfirmationLatency(sqlite, orderID)
+	if err != nil || !ok || latency < 115*time.Second || latency > 125*time.Second {
+		t.Errorf("latency = %v, %v, %v; want about 2m", latency, ok, err)
+	}
+	after := confirmationLatencySnapshot()
+	if after["count"] != before["count"]+1 || after["le_5m0s"] != before["le_5m0s"]+1 || after["le_1m0s"] != before["le_1m0s"] {
+		t.Errorf("histogram before = %v, after = %v", before, after)
+	}
+
+	get := func(userID string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.AdminSlowConfirmations(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/admin/webhook/latency", nil), userID))
+		return rec
+	}
+	if rec := get(buyerID); rec.Code != http.StatusForbidden {
+		t.Errorf("buyer: status = %d, want 403", rec.Code)
+	}
+	rec := get(admin.ID)
+	var resp struct {
+		SinceStart map[string]float64                  `json:"sinceStart"`
+		Slowest    []repository.ConfirmationLatencyRow `json:"slowest"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("admin: status = %d, err = %v", rec.Code, err)
+	}
+	if len(resp.Slowest) != 1 || resp.Slowest[0].OrderID != orderID || resp.Slowest[0].LatencySeconds < 115 {
+		t.Errorf("slowest = %+v", resp.Slowest)
+	}
+	if resp.SinceStart["count"] < 1 {
+		t.Errorf("sinceStart = %v", resp.SinceStart)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM audit_log`); got != 0 {
+		t.Errorf("audit entries written by a read = %d, want 0", got)
+	}
+}
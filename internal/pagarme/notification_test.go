@@ -0,0 +1,57 @@
+package pagarme
+
+import (
+	"encoding/json"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/outbox"
+	"afterzin/api/internal/repository"
+)
+
+func TestOrderPaidCreatesNotificationOnce(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 2, 10, 50)
+
+	fake := newFakeClient()
+	fake.paidAmounts["or_test"] = 10000
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+
+	postWebhook(t, h, "hook_1", orderID, "or_test")
+	postWebhook(t, h, "hook_2", orderID, "or_test")
+
+	order, err := repository.OrderRowByID(sqlite, orderID)
+	if err != nil || order == nil {
+		t.Fatalf("pedido não encontrado: %v", err)
+	}
+	list, err := repository.NotificationsByUser(sqlite, order.UserID, true, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("notificações = %d, want 1", len(list))
+	}
+	n := list[0]
+	if n.Type != repository.NotificationTicketPurchased {
+		t.Errorf("type = %s", n.Type)
+	}
+	var payload outbox.TicketPurchasedPayload
+	if err := json.Unmarshal([]byte(n.Payload), &payload); err != nil {
+		t.Fatalf("payload inválido: %v", err)
+	}
+	if payload.OrderID != orderID || payload.Tickets != 2 {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	if got, _ := repository.MarkNotificationRead(sqlite, n.ID, "outro-usuario"); got != nil {
+		t.Error("outro usuário não deveria marcar a notificação")
+	}
+	read, err := repository.MarkNotificationRead(sqlite, n.ID, order.UserID)
+	if err != nil || read == nil || !read.ReadAt.Valid {
+		t.Fatalf("MarkNotificationRead = %+v, %v", read, err)
+	}
+	if unread, _ := repository.NotificationsByUser(sqlite, order.UserID, true, 10); len(unread) != 0 {
+		t.Errorf("não lidas = %d, want 0", len(unread))
+	}
+}
@@ -0,0 +1,210 @@
+package pagarme
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/repository"
+)
+
+// RecipientStatusResult describes a producer's payment onboarding state.
+// Shared by the REST endpoint and the GraphQL producerPaymentStatus query.
+type RecipientStatusResult struct {
+	HasRecipient       bool   `json:"hasRecipient"`
+	RecipientID        string `json:"recipientId,omitempty"`
+	OnboardingComplete bool   `json:"onboardingComplete"`
+	Status             string `json:"status,omitempty"` // raw Pagar.me status, kept for debugging
+	State              string `json:"state,omitempty"`
+	StateMessage       string `json:"stateMessage,omitempty"`
+	ActionRequired     bool   `json:"actionRequired"`
+	RefuseReason       string `json:"refuseReason,omitempty"`
+	Name               string `json:"name,omitempty"`
+	Error              string `json:"error,omitempty"`
+}
+
+// recipientState is the producer-facing reading of a Pagar.me recipient status.
+type recipientState struct {
+	label          string
+	message        string
+	actionRequired bool
+}
+
+// recipientStates maps Pagar.me V5 recipient statuses to what the producer
+// sees. Unknown statuses fall back to a generic "em análise" state.
+var recipientStates = map[string]recipientState{
+	"registration": {"Em cadastro", "Seus dados estão sendo registrados no Pagar.me. Isso costuma levar alguns minutos.", false},
+	"affiliation":  {"Em análise", "O Pagar.me está analisando seus dados. Você será avisado quando a conta for aprovada.", false},
+	"active":       {"Ativo", "Tudo certo: você já pode receber pagamentos.", false},
+	"refused":      {"Recusado", "O cadastro foi recusado pelo Pagar.me. Revise seus dados bancários e documentos e envie novamente.", true},
+	"suspended":    {"Suspenso", "O recebedor foi suspenso pelo Pagar.me. Entre em contato com o suporte.", true},
+	"blocked":      {"Bloqueado", "O recebedor foi bloqueado pelo Pagar.me. Entre em contato com o suporte.", true},
+	"inactive":     {"Inativo", "O recebedor está inativo. Cadastre uma nova conta para voltar a receber pagamentos.", true},
+}
+
+// describeRecipientStatus returns the localized state for a raw status.
+func describeRecipientStatus(status string) recipientState {
+	if st, ok := recipientStates[status]; ok {
+		return st
+	}
+	return recipientState{"Em análise", "O status do recebedor está sendo atualizado pelo Pagar.me.", false}
+}
+
+// recipientRefuseReason extracts the refusal reason when Pagar.me provides one,
+// either at the top level or inside kyc_details.
+func recipientRefuseReason(data map[string]interface{}) string {
+	if reason, _ := data["refuse_reason"].(string); reason != "" {
+		return reason
+	}
+	if kyc, ok := data["kyc_details"].(map[string]interface{}); ok {
+		if reason, _ := kyc["status_reason"].(string); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// LookupRecipientStatus resolves the onboarding status for the producer owned by userID.
+// Checks the live recipient status with Pagar.me and falls back to the cached
+// local flag when the gateway is unreachable (or client is nil).
+func LookupRecipientStatus(client PagarmeAPI, db *sql.DB, userID string) RecipientStatusResult {
+	prodID, _ := repository.ProducerIDByUser(db, userID)
+	if prodID == "" {
+		return RecipientStatusResult{}
+	}
+
+	recipientID, _ := repository.GetProducerPagarmeRecipientID(db, prodID)
+	if recipientID == "" {
+		return RecipientStatusResult{}
+	}
+
+	var recipientData map[string]interface{}
+	var err error
+	if client != nil {
+		recipientData, err = client.GetRecipient(recipientID)
+	}
+	if client == nil || err != nil {
+		if err != nil {
+			logger.Errorf("erro ao obter status do recebedor no Pagar.me: %v", err)
+		}
+		// Return cached local status
+		onboardingComplete, _ := repository.GetProducerOnboardingComplete(db, prodID)
+		res := RecipientStatusResult{
+			HasRecipient:       true,
+			RecipientID:        recipientID,
+			OnboardingComplete: onboardingComplete,
+			Error:              "não foi possível verificar status com Pagar.me",
+		}
+		// Last status pushed by the recipient webhook, if any
+		if cached, _ := repository.GetProducerRecipientStatus(db, prodID); cached != "" {
+			state := describeRecipientStatus(cached)
+			res.Status = cached
+			res.State = state.label
+			res.StateMessage = state.message
+			res.ActionRequired = state.actionRequired
+		}
+		return res
+	}
+
+	return liveRecipientStatus(recipientID, recipientData)
+}
+
+// liveRecipientStatus builds the result from a Pagar.me recipient payload.
+func liveRecipientStatus(recipientID string, recipientData map[string]interface{}) RecipientStatusResult {
+	status, _ := recipientData["status"].(string)
+	name, _ := recipientData["name"].(string)
+	state := describeRecipientStatus(status)
+
+	return RecipientStatusResult{
+		HasRecipient:       true,
+		RecipientID:        recipientID,
+		OnboardingComplete: true,
+		Status:             status,
+		State:              state.label,
+		StateMessage:       state.message,
+		ActionRequired:     state.actionRequired,
+		RefuseReason:       recipientRefuseReason(recipientData),
+		Name:               name,
+	}
+}
+
+// RecipientRefreshInterval is the minimum time between two forced refreshes
+// of the same producer's recipient status.
+const RecipientRefreshInterval = 30 * time.Second
+
+// RefreshTooSoonError is returned by RefreshRecipientStatus when the producer
+// refreshed less than RecipientRefreshInterval ago.
+type RefreshTooSoonError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RefreshTooSoonError) Error() string {
+	return fmt.Sprintf("status atualizado há pouco; tente novamente em %d segundos", int(e.RetryAfter.Round(time.Second).Seconds()))
+}
+
+// recipientRefreshes remembers the last forced refresh per producer. It is
+// shared by the REST and GraphQL entry points.
+var recipientRefreshes = struct {
+	sync.Mutex
+	last map[string]time.Time
+}{last: map[string]time.Time{}}
+
+// PruneRecipientRefreshes drops refresh times older than
+// RecipientRefreshInterval, which no longer limit anything. Run periodically
+// so producers that stopped refreshing don't stay in memory.
+func PruneRecipientRefreshes(now time.Time) int {
+	recipientRefreshes.Lock()
+	defer recipientRefreshes.Unlock()
+	pruned := 0
+	for prodID, last := range recipientRefreshes.last {
+		if now.Sub(last) >= RecipientRefreshInterval {
+			delete(recipientRefreshes.last, prodID)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// RefreshRecipientStatus forces a live fetch of the producer's recipient
+// status from Pagar.me and stores it (and the onboarding flag derived from
+// it) locally. Unlike LookupRecipientStatus it doesn't fall back to the cached
+// status: a gateway failure is returned as an error. Limited to one call per
+// producer every RecipientRefreshInterval.
+func RefreshRecipientStatus(client PagarmeAPI, db *sql.DB, userID string, now time.Time) (RecipientStatusResult, error) {
+	prodID, _ := repository.ProducerIDByUser(db, userID)
+	if prodID == "" {
+		return RecipientStatusResult{}, nil
+	}
+	recipientID, _ := repository.GetProducerPagarmeRecipientID(db, prodID)
+	if recipientID == "" {
+		return RecipientStatusResult{}, nil
+	}
+	if client == nil {
+		return RecipientStatusResult{}, fmt.Errorf("pagamentos não configurados")
+	}
+
+	recipientRefreshes.Lock()
+	if last, ok := recipientRefreshes.last[prodID]; ok && now.Sub(last) < RecipientRefreshInterval {
+		recipientRefreshes.Unlock()
+		return RecipientStatusResult{}, &RefreshTooSoonError{RetryAfter: RecipientRefreshInterval - now.Sub(last)}
+	}
+	recipientRefreshes.last[prodID] = now
+	recipientRefreshes.Unlock()
+
+	recipientData, err := client.GetRecipient(recipientID)
+	if err != nil {
+		logger.Errorf("erro ao atualizar status do recebedor %s no Pagar.me: %v", recipientID, err)
+		return RecipientStatusResult{}, fmt.Errorf("não foi possível verificar status com Pagar.me")
+	}
+	res := liveRecipientStatus(recipientID, recipientData)
+	if res.Status != "" {
+		if _, err := repository.SetProducerRecipientStatus(db, prodID, res.Status, recipientUpdatedAt(recipientData, now)); err != nil {
+			logger.Errorf("erro ao salvar status do recebedor %s: %v", recipientID, err)
+			return RecipientStatusResult{}, fmt.Errorf("erro ao salvar status do recebedor")
+		}
+	}
+	res.OnboardingComplete, _ = repository.GetProducerOnboardingComplete(db, prodID)
+	return res, nil
+}
@@ -1,22 +1,30 @@
 package pagarme
 
 import (
-	"afterzin/api/internal/logger"
 	"fmt"
+	"strings"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/validate"
 )
 
 // PixOrderParams holds parameters for creating a Pagar.me order with PIX.
 type PixOrderParams struct {
-	OrderID             string      // Internal order ID (used as order "code" in Pagar.me)
-	ProducerRecipientID string      // Producer's Pagar.me recipient ID (for split)
-	AmountCentavos      int64       // Total amount in BRL centavos
-	TotalTickets        int         // Ticket count for fee calculation
-	Description         string      // Description for the payment
-	CustomerName        string      // Buyer's name
-	CustomerEmail       string      // Buyer's email
-	CustomerDocument    string      // Buyer's CPF
-	CustomerPhone       *PhoneData  // Buyer's phone (optional for backward compatibility)
-	Items               []OrderItem // Line items
+	OrderID             string              // Internal order ID (sent as metadata.order_id)
+	OrderRef            string              // Human-friendly reference, used as the Pagar.me order "code" (falls back to OrderID)
+	ProducerRecipientID string              // Producer's Pagar.me recipient ID (for split)
+	AmountCentavos      int64               // Total amount in the currency's minor unit (centavos for BRL)
+	Currency            string              // ISO 4217 currency (default DefaultCurrency)
+	TotalTickets        int                 // Ticket count for fee calculation
+	Description         string              // Description for the payment
+	StatementDescriptor string              // Name shown to the buyer for the charge (see SanitizeStatementDescriptor)
+	CustomerName        string              // Buyer's name
+	CustomerEmail       string              // Buyer's email
+	CustomerDocument    string              // Buyer's document (digits only)
+	CustomerDocType     string              // Buyer's document type (default AllowedDocumentType)
+	CustomerPhone       *validate.PhoneData // Buyer's phone (optional for backward compatibility)
+	CustomerAddress     *Address            // Buyer's address (optional for PIX; see requiresCustomerAddress)
+	Items               []OrderItem         // Line items
 }
 
 // OrderItem represents a single line item in the order.
@@ -31,10 +39,14 @@ type OrderItem struct {
 type PixOrderResult struct {
 	PagarmeOrderID  string `json:"pagarmeOrderId"`
 	PagarmeChargeID string `json:"pagarmeChargeId"`
-	PixQRCode       string `json:"pixQrCode"`    // PIX copia-e-cola string
-	PixQRCodeURL    string `json:"pixQrCodeUrl"` // URL to QR code image
-	ExpiresAt       string `json:"expiresAt"`    // ISO timestamp when PIX expires
-	Status          string `json:"status"`       // pending, paid, etc.
+	PixQRCode       string `json:"pixQrCode"`              // PIX copia-e-cola string
+	PixQRCodeURL    string `json:"pixQrCodeUrl"`           // URL to QR code image
+	ExpiresAt       string `json:"expiresAt"`              // ISO timestamp when PIX expires
+	Status          string `json:"status"`                 // pending, paid, etc.
+	ChargeStatus    string `json:"chargeStatus,omitempty"` // lifecycle state of the charge (see ChargeStatus)
+	OrderID         string `json:"orderId,omitempty"`      // internal order ID
+	OrderRef        string `json:"orderRef,omitempty"`     // human-friendly order reference
+	GuestToken      string `json:"guestToken,omitempty"`   // token for guest status polling (guest checkout only)
 }
 
 // CreatePixOrder creates a Pagar.me order with PIX payment method and split.
@@ -61,11 +73,25 @@ func (c *Client) CreatePixOrder(params PixOrderParams) (*PixOrderResult, error)
 	if len(params.Items) == 0 {
 		return nil, fmt.Errorf("items não pode estar vazio")
 	}
-	if params.CustomerName == "" {
-		return nil, fmt.Errorf("nome do cliente é obrigatório")
+	if params.Currency == "" {
+		params.Currency = DefaultCurrency
+	}
+	if !strings.EqualFold(params.Currency, "BRL") {
+		return nil, fmt.Errorf("PIX aceita apenas BRL (recebido: %s)", params.Currency)
+	}
+	if params.CustomerDocType == "" {
+		params.CustomerDocType = AllowedDocumentType
 	}
-	if params.CustomerEmail == "" {
-		return nil, fmt.Errorf("email do cliente é obrigatório")
+	if err := validate.ValidateCustomerName(params.CustomerName); err != nil {
+		return nil, fmt.Errorf("nome do cliente inválido: %w", err)
+	}
+	if err := validate.ValidateEmail(validate.NormalizeEmail(params.CustomerEmail)); err != nil {
+		return nil, fmt.Errorf("email do cliente inválido: %w", err)
+	}
+	if params.CustomerAddress != nil {
+		if err := params.CustomerAddress.Validate(); err != nil {
+			return nil, fmt.Errorf("endereço do cliente inválido: %w", err)
+		}
 	}
 
 	// Calculate split amounts
@@ -117,11 +143,11 @@ func (c *Client) CreatePixOrder(params PixOrderParams) (*PixOrderResult, error)
 	// Build customer data
 	logger.Debugf("criar pedido PIX - documento do cliente: %s", params.CustomerDocument)
 	customer := map[string]interface{}{
-		"name":          params.CustomerName,
-		"email":         params.CustomerEmail,
+		"name":          strings.TrimSpace(params.CustomerName),
+		"email":         validate.NormalizeEmail(params.CustomerEmail),
 		"document":      params.CustomerDocument,
-		"document_type": AllowedDocumentType, // Apenas CPF é aceito
-		"type":          AllowedCustomerType, // Apenas pessoa física
+		"document_type": params.CustomerDocType, // CPF na região padrão
+		"type":          AllowedCustomerType,    // Apenas pessoa física
 	}
 
 	// Add phone if available
@@ -135,18 +161,44 @@ func (c *Client) CreatePixOrder(params PixOrderParams) (*PixOrderResult, error)
 		}
 	}
 
+	if params.CustomerAddress != nil {
+		customer["address"] = params.CustomerAddress.customerAddress()
+	}
+
+	// PIX has no statement descriptor: the description and the name the buyer
+	// should recognize go in additional_information, shown by the bank app.
+	pix := map[string]interface{}{
+		"expires_in": PixExpirationSeconds, // 15 minutos (900 segundos)
+	}
+	var info []map[string]string
+	if params.StatementDescriptor != "" {
+		info = append(info, map[string]string{"name": "Recebedor", "value": params.StatementDescriptor})
+	}
+	if params.Description != "" {
+		info = append(info, map[string]string{"name": "Descrição", "value": params.Description})
+	}
+	if len(info) > 0 {
+		pix["additional_information"] = info
+	}
+
+	code := params.OrderRef
+	if code == "" {
+		code = params.OrderID
+	}
 	body := map[string]interface{}{
-		"code":     params.OrderID,
+		"code":     code,
 		"customer": customer,
 		"items":    items,
+		"metadata": map[string]string{
+			"env":      c.Env,
+			"order_id": params.OrderID,
+		},
 		"payments": []map[string]interface{}{
 			{
 				"payment_method": AllowedPaymentMethod, // Apenas PIX é permitido
-				"pix": map[string]interface{}{
-					"expires_in": PixExpirationSeconds, // 15 minutos (900 segundos)
-				},
-				"amount": params.AmountCentavos,
-				"split":  split,
+				"pix":            pix,
+				"amount":         params.AmountCentavos,
+				"split":          split,
 			},
 		},
 	}
@@ -234,6 +286,11 @@ func extractChargeData(result map[string]interface{}, pixResult *PixOrderResult)
 		return
 	}
 	pixResult.PagarmeChargeID, _ = charge["id"].(string)
+	if raw, _ := charge["status"].(string); raw != "" {
+		if status, ok := ParseChargeStatus(raw); ok {
+			pixResult.ChargeStatus = string(status)
+		}
+	}
 
 	lastTxn, ok := charge["last_transaction"].(map[string]interface{})
 	if !ok {
@@ -0,0 +1,117 @@
+package pagarme
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/money"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+)
+
+// UpdatedOrder is the response of UpdateOrderItems: the order's new price
+// breakdown, as PreviewOrder would show it.
+type UpdatedOrder struct {
+	OrderID  string `json:"orderId"`
+	OrderRef string `json:"orderRef"`
+	OrderPreview
+}
+
+// UpdateOrderItems handles POST /v1/order/items
+// Replaces the items of the buyer's PENDING order ({"orderId", "items"}), so
+// quantities can change before paying without cancelling and ordering again.
+// The new cart goes through the same checks as a new order; once the PIX has
+// been generated the order can no longer change (409).
+func (h *Handler) UpdateOrderItems(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	userID := middleware.UserID(r.Context())
+	if userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return
+	}
+	var req struct {
+		OrderID string     `json:"orderId"`
+		Items   []CartItem `json:"items"`
+	}
+	if !rest.DecodeStrict(w, r, &req, h.cfg.StrictJSON) {
+		return
+	}
+	if req.OrderID == "" {
+		rest.Error(w, http.StatusBadRequest, "orderId é obrigatório")
+		return
+	}
+	if _, err := repository.AuthorizeOrderAccess(h.db, req.OrderID, userID); err != nil {
+		rest.OrderAccessError(w, req.OrderID, err)
+		return
+	}
+
+	if err := CheckItemLimits(req.Items, h.cfg.MaxOrderItems, h.cfg.MaxItemQuantity); err != nil {
+		rest.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	quote, err := PriceCart(h.db, req.Items)
+	if err != nil {
+		rest.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.countReservedItems(quote, req.OrderID); err != nil {
+		logger.Errorf("erro ao ler reserva do pedido %s: %v", req.OrderID, err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao alterar pedido")
+		return
+	}
+	err = quote.CheckAvailability()
+	if err == nil {
+		err = quote.CheckEventDates(time.Now(), h.cfg)
+	}
+	if err != nil {
+		rest.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if limit, err := quote.CheckMaxAmount(h.db, h.cfg.MaxOrderCentavos); err != nil {
+		rest.Error(w, http.StatusBadRequest, fmt.Sprintf("%s (máximo de %s por pedido)", err.Error(), money.FormatBRL(limit)))
+		return
+	}
+	if err := CheckMinAmount(quote.TotalCentavos, h.cfg.MinOrderCentavos); err != nil {
+		rest.Error(w, http.StatusBadRequest, fmt.Sprintf("%s (mínimo de %s)", err.Error(), money.FormatBRL(h.cfg.MinOrderCentavos)))
+		return
+	}
+
+	lines := make([]repository.NewOrderItem, len(quote.Lines))
+	for i, l := range quote.Lines {
+		lines[i] = repository.NewOrderItem{EventDateID: l.EventDateID, TicketTypeID: l.TicketTypeID, Quantity: l.Quantity, UnitPrice: l.UnitPrice}
+	}
+	if _, err := repository.UpdateOrderItems(h.db, req.OrderID, lines); errors.Is(err, repository.ErrInsufficientInventory) {
+		rest.Error(w, http.StatusBadRequest, "quantidade indisponível")
+		return
+	} else if err != nil {
+		rest.OrderAccessError(w, req.OrderID, err)
+		return
+	}
+	logger.Infof("itens do pedido %s alterados pelo comprador %s: %d ingresso(s), %s", req.OrderID, userID, quote.TotalTickets, money.FormatBRL(quote.TotalCentavos))
+
+	ref, _ := repository.OrderRefByID(h.db, req.OrderID)
+	rest.JSON(w, http.StatusOK, UpdatedOrder{OrderID: req.OrderID, OrderRef: ref, OrderPreview: h.previewOf(quote)})
+}
+
+// countReservedItems lets quote reuse the places orderID reserved, which
+// UpdateOrderItems gives back before reserving the new items.
+func (h *Handler) countReservedItems(quote *Quote, orderID string) error {
+	reserved, err := repository.OrderInventoryReserved(h.db, orderID)
+	if err != nil || !reserved {
+		return err
+	}
+	held, err := repository.OrderItemsByOrderID(h.db, orderID)
+	if err != nil {
+		return err
+	}
+	quote.CountHeld(held)
+	return nil
+}
@@ -0,0 +1,101 @@
+package pagarme
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+)
+
+func TestUpdateOrderItemsBeforePayment(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 5, 50)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+	items, _ := repository.OrderItemsByOrderID(sqlite, orderID)
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+
+	update := func(userID string, quantity int) *httptest.ResponseRecorder {
+		body := `{"orderId":"` + orderID + `","items":[{"eventDateId":"` + items[0].EventDateID + `","ticketTypeId":"` + items[0].TicketTypeID + `","quantity":` + strconv.Itoa(quantity) + `}]}`
+		rec := httptest.NewRecorder()
+		h.UpdateOrderItems(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/order/items", strings.NewReader(body)), userID))
+		return rec
+	}
+
+	if rec := update(producer.ID, 2); rec.Code != http.StatusForbidden {
+		t.Errorf("other user: status = %d, want 403", rec.Code)
+	}
+	if rec := update(buyerID, 6); rec.Code != http.StatusBadRequest {
+		t.Errorf("more than on sale: status = %d, want 400", rec.Code)
+	}
+
+	rec := update(buyerID, 3)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var updated UpdatedOrder
+	if err := json.NewDecoder(rec.Body).Decode(&updated); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if updated.OrderID != orderID || updated.OrderRef == "" || updated.Total != 15000 || updated.Items[0].Quantity != 3 {
+		t.Errorf("updated = %+v", updated)
+	}
+	if _, _, total, _ := repository.OrderByID(sqlite, orderID); total != 150 {
+		t.Errorf("stored total = %v, want 150", total)
+	}
+
+	pay := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.CreatePayment(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(`{"orderId":"`+orderID+`"}`)), buyerID))
+		return rec
+	}
+
+	// A gateway failure releases the order for editing again.
+	fake.pixErr = errors.New("gateway fora do ar")
+	if rec := pay(); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("failed payment: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	fake.pixErr = nil
+	if rec := update(buyerID, 3); rec.Code != http.StatusOK {
+		t.Fatalf("update after failed payment: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	// While a payment is being created, edits and other payment attempts
+	// are refused.
+	if _, err := repository.ClaimOrderPayment(sqlite, orderID, time.Now(), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if rec := update(buyerID, 1); rec.Code != http.StatusConflict {
+		t.Errorf("during payment: status = %d, want 409", rec.Code)
+	}
+	if rec := pay(); rec.Code != http.StatusConflict {
+		t.Errorf("concurrent payment: status = %d, want 409", rec.Code)
+	}
+	if fake.callCount("CreatePixOrder") != 1 {
+		t.Errorf("gateway called during another payment")
+	}
+	// A claim left by a request that died expires.
+	if _, err := sqlite.Exec(`UPDATE orders SET payment_started_at = '2020-01-01T00:00:00Z' WHERE id = ?`, orderID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once the PIX exists, the order is frozen.
+	if rec := pay(); rec.Code != http.StatusOK {
+		t.Fatalf("create payment: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := update(buyerID, 1); rec.Code != http.StatusConflict {
+		t.Errorf("after PIX: status = %d, want 409", rec.Code)
+	}
+	if _, _, total, _ := repository.OrderByID(sqlite, orderID); total != 150 {
+		t.Errorf("total after refused update = %v, want 150", total)
+	}
+}
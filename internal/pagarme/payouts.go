@@ -0,0 +1,198 @@
+package pagarme
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/money"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+)
+
+// payoutOrdersLimit é a quantidade de pedidos (mais recentes) devolvidos na conciliação
+const payoutOrdersLimit = 200
+
+// Reconciliation status of a paid order.
+const (
+	PayoutSettled         = "settled"          // covered by transfers to the producer's bank account
+	PayoutPendingTransfer = "pending_transfer" // still in the recipient's balance
+	PayoutUnknown         = "unknown"          // gateway data unavailable
+)
+
+// transferDone is the Pagar.me status of a transfer that reached the bank.
+const transferDone = "transferred"
+
+// PayoutOrder is a paid order with the amount it adds to the producer's
+// balance and whether that amount has been transferred yet.
+type PayoutOrder struct {
+	OrderID     string `json:"orderId"`
+	OrderRef    string `json:"orderRef,omitempty"`
+	PaidAt      string `json:"paidAt"`
+	Tickets     int    `json:"tickets"`
+	Amount      int64  `json:"amount"`      // centavos charged to the buyer
+	PlatformFee int64  `json:"platformFee"` // centavos kept by the platform
+	NetAmount   int64  `json:"netAmount"`   // centavos split to the producer
+	Status      string `json:"status"`
+	TransferID  string `json:"transferId,omitempty"`
+}
+
+// PayoutReconciliation matches a producer's paid orders with the transfers
+// Pagar.me made from their recipient balance.
+type PayoutReconciliation struct {
+	HasRecipient      bool          `json:"hasRecipient"`
+	RecipientID       string        `json:"recipientId,omitempty"`
+	TransferredAmount int64         `json:"transferredAmount"`
+	SettledAmount     int64         `json:"settledAmount"`
+	PendingAmount     int64         `json:"pendingAmount"`
+	SettledOrders     int           `json:"settledOrders"`
+	PendingOrders     int           `json:"pendingOrders"`
+	Orders            []PayoutOrder `json:"orders"`
+	Transfers         []Transfer    `json:"transfers"`
+	Error             string        `json:"error,omitempty"`
+}
+
+// ReconcilePayouts reconciles the paid orders of the producer owned by userID
+// with their Pagar.me transfers. Transfers don't reference orders, so the
+// balance is assumed to be paid out in payment order: an order is settled
+// once the recipient's total transferred amount covers it and every order
+// paid before it. Net amounts only discount the platform fee (at the current
+// rate); gateway processing fees are not known locally, so orders near the
+// boundary may show as pending a little longer than they really are.
+// Gateway data comes from LookupRecipientBalance and shares its cache.
+// Orders are returned newest first, up to payoutOrdersLimit; totals cover all.
+func ReconcilePayouts(client PagarmeAPI, db *sql.DB, userID string) (PayoutReconciliation, error) {
+	result := PayoutReconciliation{Orders: []PayoutOrder{}, Transfers: []Transfer{}}
+	prodID, _ := repository.ProducerIDByUser(db, userID)
+	if prodID == "" {
+		return result, nil
+	}
+	rows, err := repository.ProducerPayoutOrders(db, prodID)
+	if err != nil {
+		return result, err
+	}
+
+	balance := LookupRecipientBalance(client, db, userID)
+	result.HasRecipient, result.RecipientID, result.Error = balance.HasRecipient, balance.RecipientID, balance.Error
+	known := balance.Balance != nil && balance.Error == ""
+	if known {
+		result.TransferredAmount = balance.Balance.TransferredAmount
+		result.Transfers = balance.Transfers
+	}
+	if !result.HasRecipient && result.Error == "" && len(rows) > 0 {
+		result.Error = "nenhum recebedor configurado"
+	}
+
+	var fee int64
+	if client != nil {
+		fee = client.ApplicationFeePerTicket()
+	}
+	ranges := transferRanges(result.Transfers, result.TransferredAmount)
+	orders := make([]PayoutOrder, 0, len(rows))
+	var cumulative int64
+	for _, row := range rows {
+		o := PayoutOrder{
+			OrderID:  row.ID,
+			OrderRef: row.OrderRef,
+			PaidAt:   row.PaidAt,
+			Tickets:  row.Tickets,
+			Amount:   money.FromReais(row.Total),
+		}
+		o.PlatformFee = fee * int64(o.Tickets)
+		if o.PlatformFee > o.Amount {
+			o.PlatformFee = o.Amount
+		}
+		o.NetAmount = o.Amount - o.PlatformFee
+		cumulative += o.NetAmount
+
+		switch {
+		case !known:
+			o.Status = PayoutUnknown
+		case cumulative <= result.TransferredAmount:
+			o.Status = PayoutSettled
+			o.TransferID = ranges.find(cumulative)
+			result.SettledOrders++
+			result.SettledAmount += o.NetAmount
+		default:
+			o.Status = PayoutPendingTransfer
+			result.PendingOrders++
+			result.PendingAmount += o.NetAmount
+		}
+		orders = append(orders, o)
+	}
+
+	for i := len(orders) - 1; i >= 0 && len(result.Orders) < payoutOrdersLimit; i-- {
+		result.Orders = append(result.Orders, orders[i])
+	}
+	return result, nil
+}
+
+// transferRange is the slice (start, end] of the cumulative transferred
+// amount paid out by one transfer.
+type transferRange struct {
+	id         string
+	start, end int64
+}
+
+type transferRangeList []transferRange
+
+// transferRanges lays the completed transfers end to end, newest ending at
+// total. Only the most recent transfers are listed by Pagar.me, so older
+// amounts are left without a range.
+func transferRanges(transfers []Transfer, total int64) transferRangeList {
+	done := make([]Transfer, 0, len(transfers))
+	for _, t := range transfers {
+		if t.Status == transferDone {
+			done = append(done, t)
+		}
+	}
+	sort.SliceStable(done, func(i, j int) bool { return done[i].CreatedAt > done[j].CreatedAt })
+	ranges := make(transferRangeList, 0, len(done))
+	end := total
+	for _, t := range done {
+		ranges = append(ranges, transferRange{id: t.ID, start: end - t.Amount, end: end})
+		end -= t.Amount
+	}
+	return ranges
+}
+
+// find returns the transfer that paid out the given cumulative amount, or ""
+// when it predates the listed transfers.
+func (l transferRangeList) find(cumulative int64) string {
+	for _, r := range l {
+		if cumulative > r.start && cumulative <= r.end {
+			return r.id
+		}
+	}
+	return ""
+}
+
+// GetRecipientPayouts handles GET /v1/recipient/payouts
+// Returns the producer's paid orders reconciled with Pagar.me transfers.
+func (h *Handler) GetRecipientPayouts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if !rest.RequireFeature(w, h.cfg, config.FeaturePayoutReconcile) {
+		return
+	}
+
+	userID := middleware.UserID(r.Context())
+	if userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return
+	}
+
+	result, err := ReconcilePayouts(h.client, h.db, userID)
+	if err != nil {
+		logger.Errorf("erro ao conciliar repasses do usuário %s: %v", userID, err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao conciliar repasses")
+		return
+	}
+	rest.JSON(w, http.StatusOK, result)
+}
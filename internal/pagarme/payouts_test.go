@@ -0,0 +1,104 @@
+package pagarme
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+)
+
+func TestReconcilePayoutsMatchesTransfersInPaymentOrder(t *testing.T) {
+	balanceCache.Lock()
+	delete(balanceCache.entries, "re_producer")
+	balanceCache.Unlock()
+
+	sqlite := newTestDB(t)
+	first, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	buyer, _ := repository.UserByEmail(sqlite, "comprador@email.com")
+	var dateID, ttID string
+	if err := sqlite.QueryRow(`SELECT event_date_id, ticket_type_id FROM order_items WHERE order_id = ?`, first).Scan(&dateID, &ttID); err != nil {
+		t.Fatalf("order item: %v", err)
+	}
+	newOrder := func(qty int) string {
+		id, err := repository.CreateOrder(sqlite, buyer.ID, 50*float64(qty), 30*time.Minute)
+		if err != nil {
+			t.Fatalf("create order: %v", err)
+		}
+		if _, err := repository.CreateOrderItem(sqlite, id, dateID, ttID, qty, 50); err != nil {
+			t.Fatalf("create order item: %v", err)
+		}
+		return id
+	}
+	pay := func(orderID, gatewayID, paidAt string) {
+		if _, err := sqlite.Exec(`UPDATE orders SET status = 'PAID', pagarme_order_id = NULLIF(?, '') WHERE id = ?`, gatewayID, orderID); err != nil {
+			t.Fatalf("pay order: %v", err)
+		}
+		if _, err := sqlite.Exec(`INSERT INTO order_status_history (id, order_id, old_status, new_status, created_at)
+			VALUES (?, ?, 'PENDING', 'PAID', ?)`, orderID+"-paid", orderID, paidAt); err != nil {
+			t.Fatalf("history: %v", err)
+		}
+	}
+	second, third, comp := newOrder(2), newOrder(1), newOrder(1)
+	pay(third, "or_3", "2026-03-03T10:00:00Z")
+	pay(first, "or_1", "2026-03-01T10:00:00Z")
+	pay(second, "or_2", "2026-03-02T10:00:00Z")
+	pay(comp, "", "2026-03-02T11:00:00Z") // never charged through the gateway
+
+	// Net amounts (R$5 fee per ticket): 45, 90 and 45. R$135 has been
+	// transferred, covering the first two orders.
+	fake := newFakeClient()
+	fake.balance = &RecipientBalance{Currency: "BRL", AvailableAmount: 4500, TransferredAmount: 13500}
+	fake.transfers = []Transfer{
+		{ID: "tran_b", Amount: 9000, Status: "transferred", CreatedAt: "2026-03-05T10:00:00Z"},
+		{ID: "tran_failed", Amount: 4500, Status: "failed", CreatedAt: "2026-03-04T10:00:00Z"},
+		{ID: "tran_a", Amount: 4500, Status: "transferred", CreatedAt: "2026-03-02T10:00:00Z"},
+	}
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	get := func() PayoutReconciliation {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.GetRecipientPayouts(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/recipient/payouts", nil), producer.ID))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		var res PayoutReconciliation
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return res
+	}
+
+	res := get()
+	if res.Error != "" || len(res.Orders) != 3 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	want := []struct {
+		id, status, transfer string
+		net                  int64
+	}{
+		{third, PayoutPendingTransfer, "", 4500},
+		{second, PayoutSettled, "tran_b", 9000},
+		{first, PayoutSettled, "tran_a", 4500},
+	}
+	for i, w := range want {
+		o := res.Orders[i]
+		if o.OrderID != w.id || o.Status != w.status || o.TransferID != w.transfer || o.NetAmount != w.net {
+			t.Errorf("order %d = %+v, want %+v", i, o, w)
+		}
+	}
+	if res.SettledAmount != 13500 || res.PendingAmount != 4500 || res.SettledOrders != 2 || res.PendingOrders != 1 {
+		t.Errorf("totals = %+v", res)
+	}
+
+	// The gateway side is cached.
+	get()
+	if n := fake.callCount("ListTransfers"); n != 1 {
+		t.Errorf("ListTransfers calls = %d, want 1", n)
+	}
+}
@@ -0,0 +1,77 @@
+package pagarme
+
+import (
+	"net/http"
+
+	"afterzin/api/internal/money"
+	"afterzin/api/internal/rest"
+)
+
+// PreviewItem is one priced line of an order preview.
+type PreviewItem struct {
+	TicketTypeID string `json:"ticketTypeId"`
+	EventDateID  string `json:"eventDateId"`
+	Name         string `json:"name"`
+	EventTitle   string `json:"eventTitle"`
+	Quantity     int    `json:"quantity"`
+	UnitAmount   int64  `json:"unitAmount"` // centavos
+	Subtotal     int64  `json:"subtotal"`   // centavos
+}
+
+// OrderPreview is the price breakdown returned by PreviewOrder (all amounts in centavos).
+// The platform fee is taken from the producer's split, so buyers pay no fees;
+// Discount stays zero until coupons exist.
+type OrderPreview struct {
+	Items    []PreviewItem `json:"items"`
+	Subtotal int64         `json:"subtotal"`
+	Fees     int64         `json:"fees"`
+	Discount int64         `json:"discount"`
+	Total    int64         `json:"total"`
+	// TotalFormatted is Total ready for display in the region currency ("R$ 1.234,56")
+	TotalFormatted string `json:"totalFormatted"`
+}
+
+// PreviewOrder handles POST /v1/order/preview
+// Prices a cart without creating an order or calling Pagar.me.
+func (h *Handler) PreviewOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req struct {
+		Items []CartItem `json:"items"`
+	}
+	if !rest.DecodeStrict(w, r, &req, h.cfg.StrictJSON) {
+		return
+	}
+	quote, err := PriceCart(h.db, req.Items)
+	if err == nil {
+		err = quote.CheckAvailability()
+	}
+	if err != nil {
+		rest.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rest.JSON(w, http.StatusOK, h.previewOf(quote))
+}
+
+// previewOf breaks a priced cart down as an OrderPreview.
+func (h *Handler) previewOf(quote *Quote) OrderPreview {
+	preview := OrderPreview{Items: make([]PreviewItem, 0, len(quote.Lines)), Subtotal: quote.TotalCentavos}
+	for _, l := range quote.Lines {
+		preview.Items = append(preview.Items, PreviewItem{
+			TicketTypeID: l.TicketTypeID,
+			EventDateID:  l.EventDateID,
+			Name:         l.TicketName,
+			EventTitle:   l.EventTitle,
+			Quantity:     l.Quantity,
+			UnitAmount:   l.UnitAmount,
+			Subtotal:     l.Subtotal,
+		})
+	}
+	preview.Total = preview.Subtotal + preview.Fees - preview.Discount
+	preview.TotalFormatted = money.Format(preview.Total, h.region.Currency)
+	return preview
+}
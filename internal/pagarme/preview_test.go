@@ -0,0 +1,57 @@
+package pagarme
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/money"
+	"afterzin/api/internal/repository"
+)
+
+func TestPreviewOrderMatchesPixCharge(t *testing.T) {
+	sqlite := newTestDB(t)
+	// 19.99 exercises the centavo conversion shared with CreatePayment.
+	orderID, _ := seedPendingOrder(t, sqlite, 3, 10, 19.99)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+	items, _ := repository.OrderItemsByOrderID(sqlite, orderID)
+	item := items[0]
+
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+
+	body := `{"items":[{"eventDateId":"` + item.EventDateID + `","ticketTypeId":"` + item.TicketTypeID + `","quantity":3}]}`
+	rec := httptest.NewRecorder()
+	h.PreviewOrder(rec, httptest.NewRequest(http.MethodPost, "/v1/order/preview", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("preview: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var preview OrderPreview
+	json.NewDecoder(rec.Body).Decode(&preview)
+
+	rec = httptest.NewRecorder()
+	h.CreatePayment(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(`{"orderId":"`+orderID+`"}`)), buyerID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create payment: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if preview.TotalFormatted != money.FormatBRL(preview.Total) {
+		t.Errorf("totalFormatted = %q", preview.TotalFormatted)
+	}
+	if preview.Total != fake.lastPixOrder.AmountCentavos {
+		t.Errorf("preview total = %d, charged = %d", preview.Total, fake.lastPixOrder.AmountCentavos)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM orders`); got != 1 {
+		t.Errorf("orders = %d, preview must not create orders", got)
+	}
+
+	over := `{"items":[{"eventDateId":"` + item.EventDateID + `","ticketTypeId":"` + item.TicketTypeID + `","quantity":11}]}`
+	rec = httptest.NewRecorder()
+	h.PreviewOrder(rec, httptest.NewRequest(http.MethodPost, "/v1/order/preview", strings.NewReader(over)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("over capacity: status = %d, want 400", rec.Code)
+	}
+}
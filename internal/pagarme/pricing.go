@@ -0,0 +1,227 @@
+package pagarme
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/eventtime"
+	"afterzin/api/internal/money"
+	"afterzin/api/internal/repository"
+)
+
+// PricedLine is a cart item resolved against the catalog.
+type PricedLine struct {
+	CartItem
+	TicketName  string
+	EventID     string
+	EventTitle  string
+	ProducerID  string
+	Date        string  // event_dates.date, YYYY-MM-DD
+	StartTime   string  // event_dates.start_time, HH:MM ("" if unset)
+	Timezone    string  // events.timezone ("" if unset, see config.EventTimezone)
+	UnitPrice   float64 // reais, as stored on order_items
+	UnitAmount  int64   // centavos
+	Subtotal    int64   // centavos
+	Available   int     // units still on sale at pricing time (see AvailableUnits)
+	RecipientID string  // producer's Pagar.me recipient ("" if not configured)
+}
+
+// Quote is the priced result of a cart, shared by preview, order creation and payment.
+type Quote struct {
+	Lines         []PricedLine
+	TotalCentavos int64
+	TotalTickets  int
+	// RecipientID is the recipient of the first line; the PIX split pays a single producer.
+	RecipientID string
+}
+
+// Total returns the quote total in reais, as stored on orders.total.
+func (q *Quote) Total() float64 {
+	var total float64
+	for _, l := range q.Lines {
+		total += float64(l.Quantity) * l.UnitPrice
+	}
+	return total
+}
+
+// CheckAvailability fails if any line asks for more tickets than are left.
+func (q *Quote) CheckAvailability() error {
+	for _, l := range q.Lines {
+		if l.Quantity > l.Available {
+			return fmt.Errorf("quantidade indisponível")
+		}
+	}
+	return nil
+}
+
+// CountHeld adds to each line the units of its ticket type that held, the
+// items of an order whose lot places are reserved, already take: a cart
+// replacing that order may use them again.
+func (q *Quote) CountHeld(held []repository.OrderItemRow) {
+	for i := range q.Lines {
+		for _, it := range held {
+			if it.TicketTypeID == q.Lines[i].TicketTypeID {
+				q.Lines[i].Available += it.Quantity
+			}
+		}
+	}
+}
+
+// CheckEventDates fails if any line is for a date that is no longer on sale,
+// with cfg's sales grace and, for events without their own zone,
+// DEFAULT_TIMEZONE.
+func (q *Quote) CheckEventDates(now time.Time, cfg *config.Config) error {
+	for _, l := range q.Lines {
+		if err := eventtime.CheckDateOpen(l.Date, l.StartTime, cfg.EventTimezone(l.Timezone), now, cfg.EventSalesGrace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckItemLimits bounds the number of lines in a cart and the quantity of
+// each line (0 disables a limit), so an oversized cart is rejected before it
+// is priced or sent to the gateway.
+func CheckItemLimits(items []CartItem, maxItems, maxPerItem int) error {
+	if maxItems > 0 && len(items) > maxItems {
+		return fmt.Errorf("pedido com itens demais: máximo de %d por pedido", maxItems)
+	}
+	if maxPerItem > 0 {
+		for _, it := range items {
+			if it.Quantity > maxPerItem {
+				return fmt.Errorf("quantidade do item acima do permitido: máximo de %d ingressos por item (item: %s)", maxPerItem, it.TicketTypeID)
+			}
+		}
+	}
+	return nil
+}
+
+// AvailableUnits returns how many units of a ticket type can still be sold:
+// what's left of its max_quantity, capped by the lot's free places. A unit
+// takes tt.Admits places from the lot, since lot capacity counts people.
+func AvailableUnits(db *sql.DB, tt *repository.TicketTypeRow) int {
+	available := tt.MaxQuantity - tt.SoldQuantity
+	admits := tt.Admits
+	if admits < 1 {
+		admits = 1
+	}
+	if lot, _ := repository.LotByID(db, tt.LotID); lot != nil && lot.AvailableQuantity/admits < available {
+		available = lot.AvailableQuantity / admits
+	}
+	return available
+}
+
+// PriceCart validates cart items and computes per-item and total amounts in centavos.
+// Errors are validation messages suitable for a 400 response.
+func PriceCart(db *sql.DB, items []CartItem) (*Quote, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("nenhum item")
+	}
+
+	q := &Quote{Lines: make([]PricedLine, 0, len(items))}
+	recipients := map[string]string{}
+	for _, it := range items {
+		if it.Quantity <= 0 {
+			return nil, fmt.Errorf("quantidade do item deve ser maior que zero (item: %s)", it.TicketTypeID)
+		}
+		tt, _ := repository.TicketTypeByID(db, it.TicketTypeID)
+		if tt == nil {
+			return nil, fmt.Errorf("tipo de ingresso não encontrado")
+		}
+		price := tt.Price
+		if it.UnitPrice > 0 {
+			price = it.UnitPrice
+		}
+		if price <= 0 {
+			return nil, fmt.Errorf("preço unitário deve ser maior que zero (ticket: %s)", it.TicketTypeID)
+		}
+		ed, _ := repository.EventDateByID(db, it.EventDateID)
+		if ed == nil {
+			return nil, fmt.Errorf("data do evento não encontrada")
+		}
+		ev, _ := repository.EventByID(db, ed.EventID)
+		if ev == nil {
+			return nil, fmt.Errorf("evento não encontrado")
+		}
+
+		recipientID, ok := recipients[ev.ProducerID]
+		if !ok {
+			recipientID, _ = repository.GetProducerPagarmeRecipientID(db, ev.ProducerID)
+			recipients[ev.ProducerID] = recipientID
+		}
+
+		unit := money.FromReais(price)
+		line := PricedLine{
+			CartItem:    it,
+			TicketName:  tt.Name,
+			EventID:     ev.ID,
+			EventTitle:  ev.Title,
+			ProducerID:  ev.ProducerID,
+			Date:        ed.Date,
+			StartTime:   ed.StartTime.String,
+			Timezone:    ev.Timezone.String,
+			UnitPrice:   price,
+			UnitAmount:  unit,
+			Subtotal:    unit * int64(it.Quantity),
+			Available:   AvailableUnits(db, tt),
+			RecipientID: recipientID,
+		}
+		q.Lines = append(q.Lines, line)
+		q.TotalCentavos += line.Subtotal
+		q.TotalTickets += it.Quantity
+	}
+	q.RecipientID = q.Lines[0].RecipientID
+
+	if q.TotalCentavos <= 0 {
+		return nil, fmt.Errorf("valor total deve ser maior que zero")
+	}
+	return q, nil
+}
+
+// errOrderAmountExceeded is returned when a quote is above the applicable cap.
+var errOrderAmountExceeded = errors.New("valor do pedido excede o limite permitido")
+
+// CheckMaxAmount enforces the order amount cap. Each event's cap is its own
+// override or defaultMax; a cart spanning events gets the smallest of them.
+func (q *Quote) CheckMaxAmount(db *sql.DB, defaultMax int64) (limit int64, err error) {
+	if defaultMax <= 0 {
+		return 0, nil
+	}
+	limit = -1
+	seen := map[string]bool{}
+	for _, l := range q.Lines {
+		if seen[l.EventID] {
+			continue
+		}
+		seen[l.EventID] = true
+		eventMax, _ := repository.EventMaxOrderCentavos(db, l.EventID)
+		if eventMax <= 0 {
+			eventMax = defaultMax
+		}
+		if limit < 0 || eventMax < limit {
+			limit = eventMax
+		}
+	}
+	if q.TotalCentavos > limit {
+		return limit, errOrderAmountExceeded
+	}
+	return limit, nil
+}
+
+// errOrderAmountBelowMinimum is returned when a charge is below the gateway's
+// PIX minimum.
+var errOrderAmountBelowMinimum = errors.New("valor do pedido abaixo do mínimo para pagamento via PIX")
+
+// CheckMinAmount rejects charges the gateway would refuse for being below its
+// PIX minimum (min <= 0 disables the check). chargedCentavos is the amount
+// actually charged, after any discount, so a discount that brings an order
+// under the minimum is caught here and not by the gateway.
+func CheckMinAmount(chargedCentavos, min int64) error {
+	if min > 0 && chargedCentavos < min {
+		return errOrderAmountBelowMinimum
+	}
+	return nil
+}
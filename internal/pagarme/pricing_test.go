@@ -0,0 +1,154 @@
+package pagarme
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/eventtime"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+)
+
+func TestPriceCart(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 5, 19.99)
+	items, _ := repository.OrderItemsByOrderID(sqlite, orderID)
+	item := CartItem{EventDateID: items[0].EventDateID, TicketTypeID: items[0].TicketTypeID}
+
+	item.Quantity = 3
+	quote, err := PriceCart(sqlite, []CartItem{item})
+	if err != nil {
+		t.Fatalf("PriceCart: %v", err)
+	}
+	if quote.TotalCentavos != 5997 || quote.TotalTickets != 3 {
+		t.Errorf("total = %d centavos / %d tickets, want 5997 / 3", quote.TotalCentavos, quote.TotalTickets)
+	}
+	if l := quote.Lines[0]; l.UnitAmount != 1999 || l.Subtotal != 5997 || l.TicketName != "Pista" || l.EventTitle != "Show" {
+		t.Errorf("unexpected line: %+v", l)
+	}
+	if quote.RecipientID != "re_producer" {
+		t.Errorf("recipient = %q, want re_producer", quote.RecipientID)
+	}
+	if err := quote.CheckAvailability(); err != nil {
+		t.Errorf("CheckAvailability: %v", err)
+	}
+
+	item.Quantity = 6
+	quote, err = PriceCart(sqlite, []CartItem{item})
+	if err != nil {
+		t.Fatalf("PriceCart: %v", err)
+	}
+	if err := quote.CheckAvailability(); err == nil {
+		t.Error("expected availability error for 6 of 5 tickets")
+	}
+
+	cases := map[string][]CartItem{
+		"empty cart":     nil,
+		"zero quantity":  {{EventDateID: item.EventDateID, TicketTypeID: item.TicketTypeID}},
+		"unknown ticket": {{EventDateID: item.EventDateID, TicketTypeID: "missing", Quantity: 1}},
+		"unknown date":   {{EventDateID: "missing", TicketTypeID: item.TicketTypeID, Quantity: 1}},
+	}
+	for name, cart := range cases {
+		if _, err := PriceCart(sqlite, cart); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestCreatePaymentRejectsPastEventDate(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+	items, _ := repository.OrderItemsByOrderID(sqlite, orderID)
+	if _, err := sqlite.Exec(`UPDATE event_dates SET date = '2020-01-01' WHERE id = ?`, items[0].EventDateID); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	rec := httptest.NewRecorder()
+	h.CreatePayment(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(`{"orderId":"`+orderID+`"}`)), buyerID))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), eventtime.ErrDatePassed.Error()) {
+		t.Errorf("status = %d, body = %s; want 400 about the past date", rec.Code, rec.Body.String())
+	}
+	if fake.callCount("CreatePixOrder") != 0 {
+		t.Error("gateway called for a past event date")
+	}
+}
+
+func TestQuoteCheckMaxAmount(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 100)
+	items, _ := repository.OrderItemsByOrderID(sqlite, orderID)
+	quote, err := PriceCart(sqlite, []CartItem{{EventDateID: items[0].EventDateID, TicketTypeID: items[0].TicketTypeID, Quantity: 3}})
+	if err != nil {
+		t.Fatalf("PriceCart: %v", err)
+	}
+
+	if _, err := quote.CheckMaxAmount(sqlite, 50000); err != nil {
+		t.Errorf("R$300 under R$500 cap: %v", err)
+	}
+	limit, err := quote.CheckMaxAmount(sqlite, 20000)
+	if err == nil || limit != 20000 {
+		t.Errorf("R$300 over R$200 cap: limit=%d err=%v", limit, err)
+	}
+
+	// Premium event override raises the cap
+	if err := repository.SetEventMaxOrderCentavos(sqlite, quote.Lines[0].EventID, 100000); err != nil {
+		t.Fatalf("set override: %v", err)
+	}
+	if limit, err := quote.CheckMaxAmount(sqlite, 20000); err != nil || limit != 100000 {
+		t.Errorf("with override: limit=%d err=%v", limit, err)
+	}
+}
+
+func TestPendingOrderKeepsCapturedPrice(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 2, 10, 50)
+	items, _ := repository.OrderItemsByOrderID(sqlite, orderID)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+
+	// Producer raises the price while the buyer is paying
+	price := 75.0
+	if _, err := repository.UpdateTicketType(sqlite, items[0].TicketTypeID, nil, &price, nil, ""); err != nil {
+		t.Fatalf("UpdateTicketType: %v", err)
+	}
+
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	rec := httptest.NewRecorder()
+	h.CreatePayment(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(`{"orderId":"`+orderID+`"}`)), buyerID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if got := fake.lastPixOrder.AmountCentavos; got != 10000 {
+		t.Errorf("charged %d centavos, want 10000 (price at order time)", got)
+	}
+}
+
+func TestCreatePaymentRejectsAmountBelowMinimum(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 0.5)
+	buyerID, _, _, _ := repository.OrderByID(sqlite, orderID)
+
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret", MinOrderCentavos: 100}, mailer.LogMailer{})
+	rec := httptest.NewRecorder()
+	h.CreatePayment(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/payment/create", strings.NewReader(`{"orderId":"`+orderID+`"}`)), buyerID))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), errOrderAmountBelowMinimum.Error()) {
+		t.Errorf("status = %d, body = %s; want 400 about the minimum", rec.Code, rec.Body.String())
+	}
+	if fake.callCount("CreatePixOrder") != 0 {
+		t.Error("gateway called for an order below the PIX minimum")
+	}
+
+	if err := CheckMinAmount(100, 100); err != nil {
+		t.Errorf("amount equal to the minimum: %v", err)
+	}
+	if err := CheckMinAmount(50, 0); err != nil {
+		t.Errorf("minimum disabled: %v", err)
+	}
+}
@@ -1,8 +1,12 @@
 package pagarme
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"regexp"
+
+	"afterzin/api/internal/validate"
 )
 
 // CreateRecipientParams holds the data needed to create a Pagar.me recipient.
@@ -26,6 +30,41 @@ type CreateRecipientParams struct {
 	AccountNumber          string
 	AccountCheckDigit      string
 	AccountType            string // "checking" ou "savings"
+	HolderDocument         string // bank account holder; defaults to Document
+}
+
+// Account types accepted by Pagar.me for recipient bank accounts.
+const (
+	AccountTypeChecking = "checking"
+	AccountTypeSavings  = "savings"
+)
+
+// Errors returned by ValidateBankAccount. Pagar.me rejects both cases, but
+// only after a round trip and with a generic validation message.
+var (
+	ErrInvalidAccountType     = errors.New("tipo de conta inválido: use checking ou savings")
+	ErrHolderDocumentMismatch = errors.New("o documento do titular da conta deve ser o mesmo do recebedor")
+)
+
+// ValidateAccountType reports whether accountType is checking or savings.
+func ValidateAccountType(accountType string) error {
+	if accountType != AccountTypeChecking && accountType != AccountTypeSavings {
+		return ErrInvalidAccountType
+	}
+	return nil
+}
+
+// ValidateBankAccount checks the bank account fields of params before they
+// reach Pagar.me: the account type must be supported and the account must be
+// held by the recipient itself (a third party's account is refused).
+func ValidateBankAccount(params CreateRecipientParams) error {
+	if err := ValidateAccountType(params.AccountType); err != nil {
+		return err
+	}
+	if params.HolderDocument != "" && validate.SanitizeDocument(params.HolderDocument) != validate.SanitizeDocument(params.Document) {
+		return ErrHolderDocumentMismatch
+	}
+	return nil
 }
 
 // Address for PF
@@ -54,6 +93,9 @@ type RecipientResult struct {
 
 func (c *Client) CreateRecipient(params CreateRecipientParams) (*RecipientResult, error) {
 	// Não logamos o payload completo por segurança
+	if err := ValidateBankAccount(params); err != nil {
+		return nil, err
+	}
 	holderType := "individual"
 	if params.Type == "company" {
 		holderType = "company"
@@ -120,7 +162,7 @@ func (c *Client) CreateRecipient(params CreateRecipientParams) (*RecipientResult
 	bankAccount := map[string]interface{}{
 		"holder_name":         params.Name,
 		"holder_type":         holderType,
-		"holder_document":     params.Document,
+		"holder_document":     validate.SanitizeDocument(params.Document),
 		"bank":                params.BankCode,
 		"branch_number":       params.BranchNumber,
 		"account_number":      params.AccountNumber,
@@ -135,6 +177,9 @@ func (c *Client) CreateRecipient(params CreateRecipientParams) (*RecipientResult
 		"code":                 params.Document, // pode ser ajustado para um identificador único
 		"register_information": registerInfo,
 		"default_bank_account": bankAccount,
+		"metadata": map[string]string{
+			"env": c.Env,
+		},
 		"transfer_settings": map[string]interface{}{
 			"transfer_enabled":  true,
 			"transfer_interval": "daily",
@@ -166,3 +211,45 @@ func (c *Client) CreateRecipient(params CreateRecipientParams) (*RecipientResult
 func (c *Client) GetRecipient(recipientID string) (map[string]interface{}, error) {
 	return c.doRequest("GET", "/recipients/"+recipientID, nil)
 }
+
+// FindRecipientByCode looks up an active recipient whose code matches the
+// given value (the producer's document). It returns nil when none exists, so
+// a retried onboarding can reuse a recipient created by an interrupted call.
+func (c *Client) FindRecipientByCode(code string) (*RecipientResult, error) {
+	result, err := c.doRequest("GET", "/recipients?code="+url.QueryEscape(code), nil)
+	if err != nil {
+		return nil, fmt.Errorf("find recipient: %w", err)
+	}
+
+	data, _ := result["data"].([]interface{})
+	for _, item := range data {
+		rec, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// O filtro por code nem sempre é aplicado pela API; conferimos aqui.
+		if recCode, _ := rec["code"].(string); recCode != code {
+			continue
+		}
+		status, _ := rec["status"].(string)
+		if status == "inactive" || status == "refused" {
+			continue
+		}
+		id, _ := rec["id"].(string)
+		if id == "" {
+			continue
+		}
+		name, _ := rec["name"].(string)
+		return &RecipientResult{RecipientID: id, Status: status, Name: name}, nil
+	}
+	return nil, nil
+}
+
+// DeactivateRecipient sets a recipient's status to inactive.
+// Pagar.me V5 has no recipient deletion; inactive recipients can't receive splits.
+func (c *Client) DeactivateRecipient(recipientID string) error {
+	_, err := c.doRequest("PUT", "/recipients/"+recipientID, map[string]interface{}{
+		"status": "inactive",
+	})
+	return err
+}
@@ -0,0 +1,329 @@
+package pagarme
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestDeleteRecipientBlockedByOpenOrders(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	prodID, _ := repository.ProducerIDByUser(sqlite, producer.ID)
+
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	del := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.DeleteRecipient(rec, withUser(httptest.NewRequest(http.MethodDelete, "/v1/recipient", nil), producer.ID))
+		return rec
+	}
+
+	if rec := del(); rec.Code != http.StatusConflict {
+		t.Fatalf("pending order: status = %d, want 409 (body %s)", rec.Code, rec.Body.String())
+	}
+	if fake.callCount("DeactivateRecipient") != 0 {
+		t.Error("recipient deactivated despite pending order")
+	}
+
+	if _, err := sqlite.Exec(`UPDATE orders SET status = 'CANCELLED' WHERE id = ?`, orderID); err != nil {
+		t.Fatalf("cancel order: %v", err)
+	}
+	if rec := del(); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+	if fake.callCount("DeactivateRecipient") != 1 {
+		t.Error("expected Pagar.me deactivation")
+	}
+	if id, _ := repository.GetProducerPagarmeRecipientID(sqlite, prodID); id != "" {
+		t.Errorf("recipient still linked: %q", id)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM audit_log WHERE action = 'recipient.delete' AND entity_id = ?`, prodID); got != 1 {
+		t.Errorf("audit rows = %d, want 1", got)
+	}
+
+	if rec := del(); rec.Code != http.StatusNotFound {
+		t.Errorf("second delete: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestCreateRecipientReusesOrphanedRecipient(t *testing.T) {
+	sqlite := newTestDB(t)
+	userID, err := repository.CreateUser(sqlite, "Produtor", "produtor@email.com", "hash", "11144477735", "1985-01-01", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// Simulates a crash after Pagar.me created the recipient but before the
+	// id was saved: the gateway knows the document, the database doesn't.
+	fake := newFakeClient()
+	fake.recipients["11144477735"] = &RecipientResult{RecipientID: "re_orphan", Status: "active"}
+
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	create := func() *httptest.ResponseRecorder {
+		body := `{"document":"111.444.777-35","name":"Produtor","bankCode":"341","branchNumber":"0001","accountNumber":"12345","accountCheckDigit":"6"}`
+		rec := httptest.NewRecorder()
+		h.CreateRecipient(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/recipient/create", strings.NewReader(body)), userID))
+		return rec
+	}
+
+	rec := create()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "re_orphan") {
+		t.Fatalf("body = %s, want the orphaned recipient", rec.Body.String())
+	}
+	if n := fake.callCount("CreateRecipient"); n != 0 {
+		t.Fatalf("CreateRecipient called %d times, want 0", n)
+	}
+	prodID, _ := repository.ProducerIDByUser(sqlite, userID)
+	if got, _ := repository.GetProducerPagarmeRecipientID(sqlite, prodID); got != "re_orphan" {
+		t.Fatalf("saved recipient = %q, want re_orphan", got)
+	}
+
+	// A second call short-circuits on the saved id without asking Pagar.me.
+	if rec := create(); rec.Code != http.StatusOK {
+		t.Fatalf("retry status = %d, want 200", rec.Code)
+	}
+	if n := fake.callCount("FindRecipientByCode"); n != 1 {
+		t.Fatalf("FindRecipientByCode called %d times, want 1", n)
+	}
+}
+
+func TestCreateRecipientValidatesBankAccount(t *testing.T) {
+	sqlite := newTestDB(t)
+	userID, err := repository.CreateUser(sqlite, "Produtor", "produtor@email.com", "hash", "11144477735", "1985-01-01", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret", DefaultAccountType: "savings"}, mailer.LogMailer{})
+	create := func(extra string) *httptest.ResponseRecorder {
+		body := `{"document":"111.444.777-35","name":"Produtor","bankCode":"341","branchNumber":"0001","accountNumber":"12345"` + extra + `}`
+		rec := httptest.NewRecorder()
+		h.CreateRecipient(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/recipient/create", strings.NewReader(body)), userID))
+		return rec
+	}
+
+	rec := create(`,"holderDocument":"529.982.247-25"`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), ErrHolderDocumentMismatch.Error()) {
+		t.Fatalf("mismatched holder: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	rec = create(`,"accountType":"investment"`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), ErrInvalidAccountType.Error()) {
+		t.Fatalf("invalid account type: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if n := fake.callCount("FindRecipientByCode") + fake.callCount("CreateRecipient"); n != 0 {
+		t.Fatalf("gateway called %d times for invalid bank accounts", n)
+	}
+
+	// The same document, formatted differently, is accepted; the account
+	// type falls back to the configured default.
+	if rec := create(`,"holderDocument":"11144477735"`); rec.Code != http.StatusOK {
+		t.Fatalf("matching holder: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if fake.lastRecipient.AccountType != AccountTypeSavings {
+		t.Errorf("account type = %q, want the configured default", fake.lastRecipient.AccountType)
+	}
+}
+
+func TestValidateBankAccount(t *testing.T) {
+	base := CreateRecipientParams{Document: "11144477735", AccountType: AccountTypeChecking}
+	if err := ValidateBankAccount(base); err != nil {
+		t.Errorf("no holder document: %v", err)
+	}
+	p := base
+	p.HolderDocument = "111.444.777-35"
+	if err := ValidateBankAccount(p); err != nil {
+		t.Errorf("formatted holder document: %v", err)
+	}
+	p.HolderDocument = "52998224725"
+	if err := ValidateBankAccount(p); !errors.Is(err, ErrHolderDocumentMismatch) {
+		t.Errorf("mismatch: err = %v", err)
+	}
+	p = base
+	p.AccountType = "Checking"
+	if err := ValidateBankAccount(p); !errors.Is(err, ErrInvalidAccountType) {
+		t.Errorf("account type: err = %v", err)
+	}
+}
+
+func TestLookupRecipientStatusExplainsRefusal(t *testing.T) {
+	sqlite := newTestDB(t)
+	seedPendingOrder(t, sqlite, 1, 10, 50)
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+
+	fake := newFakeClient()
+	fake.recipient = map[string]interface{}{
+		"id":          "re_producer",
+		"status":      "refused",
+		"kyc_details": map[string]interface{}{"status_reason": "documento ilegível"},
+	}
+
+	st := LookupRecipientStatus(fake, sqlite, producer.ID)
+	if st.Status != "refused" {
+		t.Fatalf("raw status = %q, want refused", st.Status)
+	}
+	if st.State != "Recusado" || !st.ActionRequired {
+		t.Fatalf("state = %q actionRequired = %v, want Recusado/true", st.State, st.ActionRequired)
+	}
+	if st.RefuseReason != "documento ilegível" {
+		t.Fatalf("refuseReason = %q", st.RefuseReason)
+	}
+
+	fake.recipient["status"] = "affiliation"
+	if st := LookupRecipientStatus(fake, sqlite, producer.ID); st.State != "Em análise" || st.ActionRequired {
+		t.Fatalf("affiliation: state = %q actionRequired = %v", st.State, st.ActionRequired)
+	}
+}
+
+func TestRecipientWebhookUpdatesStatusOnce(t *testing.T) {
+	sqlite := newTestDB(t)
+	seedPendingOrder(t, sqlite, 1, 10, 50)
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	prodID, _ := repository.ProducerIDByUser(sqlite, producer.ID)
+
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	// post delivers an event claiming claimed, while the gateway reports
+	// status as of updatedAt.
+	post := func(eventID, claimed, status, updatedAt string) {
+		t.Helper()
+		fake.recipient = map[string]interface{}{"id": "re_producer", "status": status, "updated_at": updatedAt}
+		body := `{"id":"` + eventID + `","type":"recipient.updated","created_at":"2026-01-05T00:00:00Z","data":{"id":"re_producer","status":"` + claimed + `"}}`
+		rec := httptest.NewRecorder()
+		h.HandleWebhook(rec, httptest.NewRequest(http.MethodPost, "/v1/webhook", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("webhook status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+	}
+	notifications := func() int {
+		return countRows(t, sqlite, `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND type = ?`,
+			producer.ID, repository.NotificationRecipientStatus)
+	}
+
+	post("hook_1", "active", "active", "2026-01-02T10:00:00.123Z")
+	if got, _ := repository.GetProducerRecipientStatus(sqlite, prodID); got != "active" {
+		t.Fatalf("status = %q, want active", got)
+	}
+	if complete, _ := repository.GetProducerOnboardingComplete(sqlite, prodID); !complete {
+		t.Fatal("onboarding should be complete once active")
+	}
+	if n := notifications(); n != 1 {
+		t.Fatalf("notifications = %d, want 1", n)
+	}
+
+	// Redelivery, a new event with the same status, a payload whose status
+	// the gateway doesn't confirm and a status older than the stored one
+	// (even if sent later) are all no-ops.
+	post("hook_1", "active", "active", "2026-01-02T10:00:00Z")
+	post("hook_2", "active", "active", "2026-01-02T11:00:00Z")
+	post("hook_3", "refused", "active", "2026-01-02T12:00:00Z")
+	post("hook_4", "refused", "refused", "2026-01-01T09:00:00Z")
+	if got, _ := repository.GetProducerRecipientStatus(sqlite, prodID); got != "active" {
+		t.Fatalf("status after stale event = %q, want active", got)
+	}
+	if n := notifications(); n != 1 {
+		t.Fatalf("notifications = %d, want 1", n)
+	}
+
+	post("hook_5", "refused", "refused", "2026-01-03T09:00:00Z")
+	if complete, _ := repository.GetProducerOnboardingComplete(sqlite, prodID); complete {
+		t.Fatal("onboarding should be cleared when refused")
+	}
+	if n := notifications(); n != 2 {
+		t.Fatalf("notifications = %d, want 2", n)
+	}
+}
+
+func TestRefreshRecipientStatusCachesAndRateLimits(t *testing.T) {
+	sqlite := newTestDB(t)
+	seedPendingOrder(t, sqlite, 1, 10, 50)
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	prodID, _ := repository.ProducerIDByUser(sqlite, producer.ID)
+	repository.SetProducerOnboardingComplete(sqlite, prodID, false)
+
+	fake := newFakeClient()
+	fake.recipient = map[string]interface{}{"id": "re_producer", "status": "active"}
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	refresh := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.RefreshRecipientStatus(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/recipient/status/refresh", nil), producer.ID))
+		return rec
+	}
+
+	rec := refresh()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"onboardingComplete":true`) {
+		t.Errorf("body = %s, want onboardingComplete true", rec.Body.String())
+	}
+	if got, _ := repository.GetProducerRecipientStatus(sqlite, prodID); got != "active" {
+		t.Errorf("cached status = %q, want active", got)
+	}
+
+	rec = refresh()
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("second refresh: status = %d, Retry-After = %q, want 429 with header", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if n := fake.callCount("GetRecipient"); n != 1 {
+		t.Errorf("GetRecipient calls = %d, want 1", n)
+	}
+
+	// Pruning keeps the limit while it applies and forgets it afterwards
+	PruneRecipientRefreshes(time.Now())
+	if rec := refresh(); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("refresh after early prune: status = %d, want 429", rec.Code)
+	}
+	PruneRecipientRefreshes(time.Now().Add(RecipientRefreshInterval))
+	if rec := refresh(); rec.Code != http.StatusOK {
+		t.Errorf("refresh after prune: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestCreateRecipientDoesNotReuseSomeoneElsesRecipient(t *testing.T) {
+	sqlite := newTestDB(t)
+	seedPendingOrder(t, sqlite, 1, 10, 50)
+	fake := newFakeClient()
+	h := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	create := func(userID, document string) *httptest.ResponseRecorder {
+		body := `{"document":"` + document + `","name":"Produtor","bankCode":"341","branchNumber":"0001","accountNumber":"12345","accountCheckDigit":"6"}`
+		rec := httptest.NewRecorder()
+		h.CreateRecipient(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/recipient/create", strings.NewReader(body)), userID))
+		return rec
+	}
+	otherID, err := repository.CreateUser(sqlite, "Outro", "outro@email.com", "hash", "15350946056", "1990-01-01", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An orphaned recipient of the buyer's CPF, claimed by another user
+	fake.recipients[testutil.BuyerCPF] = &RecipientResult{RecipientID: "re_orphan", Status: "active"}
+	if rec := create(otherID, testutil.BuyerCPF); rec.Code != http.StatusConflict {
+		t.Errorf("someone else's document: status = %d, want 409 (body %s)", rec.Code, rec.Body.String())
+	}
+	// The producer's recipient, already linked, found through the other
+	// user's own CPF
+	fake.recipients["15350946056"] = &RecipientResult{RecipientID: testutil.RecipientID, Status: "active"}
+	if rec := create(otherID, "15350946056"); rec.Code != http.StatusConflict {
+		t.Errorf("recipient linked to another producer: status = %d, want 409 (body %s)", rec.Code, rec.Body.String())
+	}
+	prodID, _ := repository.ProducerIDByUser(sqlite, otherID)
+	if got, _ := repository.GetProducerPagarmeRecipientID(sqlite, prodID); got != "" {
+		t.Errorf("linked recipient = %q, want none", got)
+	}
+	if n := fake.callCount("CreateRecipient"); n != 0 {
+		t.Errorf("CreateRecipient called %d times, want 0", n)
+	}
+}
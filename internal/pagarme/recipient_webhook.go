@@ -0,0 +1,89 @@
+package pagarme
+
+import (
+	"time"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/outbox"
+	"afterzin/api/internal/repository"
+)
+
+// RecipientStatusPayload is the payload of a recipient_status notification.
+type RecipientStatusPayload struct {
+	RecipientID  string `json:"recipientId"`
+	Status       string `json:"status"`
+	State        string `json:"state"`
+	Message      string `json:"message"`
+	RefuseReason string `json:"refuseReason,omitempty"`
+}
+
+// handleRecipientUpdated processes recipient.* events: re-fetches the
+// recipient from Pagar.me, so a forged or replayed payload can't set its
+// status, stores the status on the producer and notifies them when the
+// recipient becomes active or is refused. Updates are ordered by the
+// recipient's updated_at on the gateway rather than by when the event was
+// sent. Besides the event-id dedup in HandleWebhook, repeated or
+// out-of-order statuses are dropped by SetProducerRecipientStatus, so each
+// transition notifies at most once.
+func (h *Handler) handleRecipientUpdated(event *WebhookEvent) {
+	recipientID, _ := event.Data["id"].(string)
+	if recipientID == "" {
+		logger.Errorf("pagarme: %s sem id do recebedor (evento: %s)", event.Type, event.ID)
+		return
+	}
+
+	prodID, userID, err := repository.ProducerByPagarmeRecipientID(h.db, recipientID)
+	if err != nil {
+		logger.Errorf("erro ao buscar produtor do recebedor %s: %v", recipientID, err)
+		return
+	}
+	if prodID == "" {
+		logger.Warnf("recebedor %s não está vinculado a nenhum produtor — ignorando", recipientID)
+		return
+	}
+
+	data, err := h.client.GetRecipient(recipientID)
+	if err != nil {
+		logger.Errorf("erro ao consultar recebedor %s no Pagar.me (evento: %s): %v", recipientID, event.ID, err)
+		return
+	}
+	status, _ := data["status"].(string)
+	if status == "" {
+		logger.Errorf("recebedor %s sem status no Pagar.me (evento: %s)", recipientID, event.ID)
+		return
+	}
+
+	changed, err := repository.SetProducerRecipientStatus(h.db, prodID, status, recipientUpdatedAt(data, time.Now()))
+	if err != nil {
+		logger.Errorf("erro ao atualizar status do recebedor %s: %v", recipientID, err)
+		return
+	}
+	if !changed {
+		logger.Infof("status %s do recebedor %s já registrado ou desatualizado — ignorando", status, recipientID)
+		return
+	}
+	logger.Infof("status do recebedor %s atualizado para %s (produtor %s)", recipientID, status, prodID)
+
+	if status != "active" && status != "refused" {
+		return
+	}
+	state := describeRecipientStatus(status)
+	outbox.Notify(h.db, userID, repository.NotificationRecipientStatus, RecipientStatusPayload{
+		RecipientID:  recipientID,
+		Status:       status,
+		State:        state.label,
+		Message:      state.message,
+		RefuseReason: recipientRefuseReason(data),
+	})
+}
+
+// recipientUpdatedAt returns when Pagar.me last changed the recipient (its
+// updated_at), in the stored format, or now when the gateway didn't say.
+func recipientUpdatedAt(data map[string]interface{}, now time.Time) string {
+	if v, _ := data["updated_at"].(string); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return repository.FormatTime(t)
+		}
+	}
+	return repository.FormatTime(now)
+}
@@ -0,0 +1,92 @@
+package pagarme
+
+import (
+	"fmt"
+	"strings"
+
+	"afterzin/api/internal/validate"
+)
+
+// DefaultRegion and DefaultCurrency keep the platform's Brazil-only behavior
+// when PAYMENT_REGION/PAYMENT_CURRENCY are not set.
+const (
+	DefaultRegion   = "BR"
+	DefaultCurrency = "BRL"
+)
+
+// Region groups the locale-dependent rules of payments and payouts.
+type Region struct {
+	Code     string
+	Currency string // ISO 4217
+	// BuyerDocumentType is the document buyers identify with on orders.
+	BuyerDocumentType string
+	// RecipientDocumentTypes are the documents accepted for recipients
+	// (individual first, company second).
+	RecipientDocumentTypes []string
+	validators             map[string]func(string) bool
+}
+
+var regions = map[string]Region{
+	"BR": {
+		Code:                   "BR",
+		Currency:               "BRL",
+		BuyerDocumentType:      AllowedDocumentType,
+		RecipientDocumentTypes: []string{"CPF", "CNPJ"},
+		validators: map[string]func(string) bool{
+			"CPF":  validate.IsValidCPF,
+			"CNPJ": validate.IsValidCNPJ,
+		},
+	},
+}
+
+// RegionByCode returns the payment rules for a region code (e.g. "BR").
+// An empty code returns DefaultRegion.
+func RegionByCode(code string) (Region, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		code = DefaultRegion
+	}
+	r, ok := regions[code]
+	if !ok {
+		return Region{}, fmt.Errorf("região de pagamento não suportada: %s", code)
+	}
+	return r, nil
+}
+
+// ValidateDocument checks that docType is accepted in the region and that doc
+// (digits only) is a valid document of that type.
+func (r Region) ValidateDocument(docType, doc string) error {
+	docType = strings.ToUpper(docType)
+	valid, ok := r.validators[docType]
+	if !ok {
+		return fmt.Errorf("tipo de documento %s não aceito na região %s", docType, r.Code)
+	}
+	if !valid(doc) {
+		return fmt.Errorf("%s inválido", docType)
+	}
+	return nil
+}
+
+// RegionFromConfig resolves PAYMENT_REGION/PAYMENT_CURRENCY, checking that
+// the configured currency matches the region.
+func RegionFromConfig(region, currency string) (Region, error) {
+	r, err := RegionByCode(region)
+	if err != nil {
+		return Region{}, err
+	}
+	if currency != "" {
+		if err := r.ValidateCurrency(currency); err != nil {
+			return Region{}, err
+		}
+	}
+	return r, nil
+}
+
+// ValidateCurrency rejects currencies other than the region's. PIX settles
+// in BRL only, so this also guards the payment method.
+func (r Region) ValidateCurrency(currency string) error {
+	if !strings.EqualFold(currency, r.Currency) {
+		return fmt.Errorf("moeda %s não suportada na região %s (esperado %s)", currency, r.Code, r.Currency)
+	}
+	return nil
+}
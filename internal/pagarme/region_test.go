@@ -0,0 +1,61 @@
+package pagarme
+
+import "testing"
+
+func TestRegionByCodeDefaultsToBR(t *testing.T) {
+	r, err := RegionByCode("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Code != "BR" || r.Currency != "BRL" || r.BuyerDocumentType != "CPF" {
+		t.Errorf("região padrão = %+v", r)
+	}
+	if _, err := RegionByCode("us"); err == nil {
+		t.Error("região não suportada deveria falhar")
+	}
+}
+
+func TestRegionValidateDocument(t *testing.T) {
+	r, _ := RegionByCode("br")
+	tests := []struct {
+		docType, doc string
+		wantErr      bool
+	}{
+		{"CPF", "52998224725", false},
+		{"cpf", "52998224725", false},
+		{"CPF", "52998224724", true},
+		{"CNPJ", "11222333000181", false},
+		{"CNPJ", "11222333000182", true},
+		{"CNPJ", "11111111111111", true},
+		{"SSN", "123456789", true},
+	}
+	for _, tt := range tests {
+		if err := r.ValidateDocument(tt.docType, tt.doc); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateDocument(%q, %q) error = %v, wantErr %v", tt.docType, tt.doc, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRegionFromConfigRejectsForeignCurrency(t *testing.T) {
+	if _, err := RegionFromConfig("BR", "USD"); err == nil {
+		t.Error("USD na região BR deveria falhar")
+	}
+	if r, err := RegionFromConfig("", ""); err != nil || r.Currency != DefaultCurrency {
+		t.Errorf("RegionFromConfig padrão = %+v, %v", r, err)
+	}
+}
+
+func TestCreatePixOrderRejectsNonBRL(t *testing.T) {
+	c := &Client{}
+	_, err := c.CreatePixOrder(PixOrderParams{
+		AmountCentavos:   1000,
+		CustomerDocument: "52998224725",
+		CustomerName:     "Maria Silva",
+		CustomerEmail:    "maria@example.com",
+		Currency:         "USD",
+		Items:            []OrderItem{{Code: "tt", Description: "x", Quantity: 1, Amount: 1000}},
+	})
+	if err == nil {
+		t.Fatal("pedido PIX em USD deveria falhar")
+	}
+}
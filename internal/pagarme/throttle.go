@@ -0,0 +1,37 @@
+package pagarme
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"afterzin/api/internal/repository"
+)
+
+// PendingOrderLimitError is returned when a user already holds the maximum
+// number of unpaid orders. Refs lists those orders so the buyer can pay or
+// abandon one of them.
+type PendingOrderLimitError struct {
+	Max  int
+	Refs []string
+}
+
+func (e *PendingOrderLimitError) Error() string {
+	return fmt.Sprintf("você já tem %d pedido(s) pendente(s) (%s); pague ou aguarde a expiração de um deles antes de criar outro",
+		len(e.Refs), strings.Join(e.Refs, ", "))
+}
+
+// PendingOrderLimit explains a repository.ErrPendingOrderLimit returned
+// when creating an order for userID as a PendingOrderLimitError listing the
+// user's pending orders; other errors are returned unchanged.
+func PendingOrderLimit(db *sql.DB, userID string, max int, err error) error {
+	if !errors.Is(err, repository.ErrPendingOrderLimit) {
+		return err
+	}
+	refs, refsErr := repository.PendingOrderRefs(db, userID)
+	if refsErr != nil {
+		return fmt.Errorf("erro ao verificar pedidos pendentes: %w", refsErr)
+	}
+	return &PendingOrderLimitError{Max: max, Refs: refs}
+}
@@ -52,54 +52,66 @@ func TestValidatePaymentMethod(t *testing.T) {
 	}
 }
 
-func TestSanitizeDocument(t *testing.T) {
+func TestValidateEnvironment(t *testing.T) {
 	tests := []struct {
-		name     string
-		input    string
-		expected string
+		name    string
+		env     string
+		apiKey  string
+		want    string
+		wantErr bool
 	}{
 		{
-			name:     "CPF com pontos e hífen",
-			input:    "123.456.789-00",
-			expected: "12345678900",
+			name:   "vazio inferido como test",
+			env:    "",
+			apiKey: "sk_test_abc",
+			want:   EnvTest,
 		},
 		{
-			name:     "CPF já limpo",
-			input:    "12345678900",
-			expected: "12345678900",
+			name:   "vazio inferido como live",
+			env:    "",
+			apiKey: "sk_abc",
+			want:   EnvLive,
 		},
 		{
-			name:     "CPF com hífens múltiplos",
-			input:    "123-456-789-00",
-			expected: "12345678900",
+			name:   "test com chave de teste",
+			env:    "test",
+			apiKey: "sk_test_abc",
+			want:   EnvTest,
 		},
 		{
-			name:     "CPF vazio",
-			input:    "",
-			expected: "",
+			name:   "live com chave de produção",
+			env:    "live",
+			apiKey: "sk_abc",
+			want:   EnvLive,
 		},
 		{
-			name:     "CPF com espaços",
-			input:    "123 456 789 00",
-			expected: "12345678900",
+			name:    "live com chave de teste",
+			env:     "live",
+			apiKey:  "sk_test_abc",
+			wantErr: true,
 		},
 		{
-			name:     "CNPJ com formatação",
-			input:    "12.345.678/0001-90",
-			expected: "12345678000190",
+			name:    "test com chave de produção",
+			env:     "test",
+			apiKey:  "sk_abc",
+			wantErr: true,
 		},
 		{
-			name:     "Apenas caracteres não numéricos",
-			input:    "abc-def.ghi/jkl",
-			expected: "",
+			name:    "ambiente desconhecido",
+			env:     "staging",
+			apiKey:  "sk_test_abc",
+			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := sanitizeDocument(tt.input)
-			if result != tt.expected {
-				t.Errorf("sanitizeDocument() = %v, want %v", result, tt.expected)
+			got, err := ValidateEnvironment(tt.env, tt.apiKey)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateEnvironment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ValidateEnvironment() = %v, want %v", got, tt.want)
 			}
 		})
 	}
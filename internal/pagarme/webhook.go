@@ -51,3 +51,49 @@ func (c *Client) VerifyWebhookSignature(payload []byte, signatureHeader string)
 
 	return &event, nil
 }
+
+// WebhookURL returns the public URL Pagar.me should deliver webhooks to.
+func (c *Client) WebhookURL() string {
+	return c.BaseURL + c.APIPrefix + WebhookPath
+}
+
+// EnsureWebhook makes sure a webhook subscription pointing at WebhookURL exists
+// for every one of events (see WebhookSubscriptions), creating only the
+// missing ones. Safe to call on every startup.
+func (c *Client) EnsureWebhook(events []string) error {
+	url := c.WebhookURL()
+
+	result, err := c.doRequest("GET", "/hooks", nil)
+	if err != nil {
+		return fmt.Errorf("list hooks: %w", err)
+	}
+
+	registered := map[string]bool{}
+	if hooks, ok := result["data"].([]interface{}); ok {
+		for _, item := range hooks {
+			hook, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			hookURL, _ := hook["url"].(string)
+			event, _ := hook["event"].(string)
+			if hookURL == url {
+				registered[event] = true
+			}
+		}
+	}
+
+	for _, event := range events {
+		if registered[event] {
+			continue
+		}
+		body := map[string]interface{}{
+			"url":   url,
+			"event": event,
+		}
+		if _, err := c.doRequest("POST", "/hooks", body); err != nil {
+			return fmt.Errorf("create hook %s: %w", event, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package pagarme
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+)
+
+func TestEnsureWebhookCreatesOnlyMissingHooks(t *testing.T) {
+	var created []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`{"data":[
+				{"id":"hook_1","url":"https://api.afterzin.com/v1/webhook","event":"order.paid"},
+				{"id":"hook_2","url":"https://old.afterzin.com/v1/webhook","event":"charge.paid"}
+			]}`))
+		case http.MethodPost:
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			created = append(created, body["event"])
+			w.Write([]byte(`{"id":"hook_new"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("sk_test", "", "", 500, "https://api.afterzin.com/")
+	client.apiURL = server.URL
+
+	if err := client.EnsureWebhook(WebhookSubscriptions(nil)); err != nil {
+		t.Fatalf("EnsureWebhook() error = %v", err)
+	}
+	if got, want := strings.Join(created, ","), "charge.paid,charge.authorized,recipient.created,recipient.updated"; got != want {
+		t.Errorf("created hooks = %s, want %s", got, want)
+	}
+}
+
+func TestWebhookSubscriptionsFollowAllowlist(t *testing.T) {
+	h := NewHandler(newFakeClient(), nil, &config.Config{}, mailer.LogMailer{})
+	for _, event := range WebhookSubscriptions(nil) {
+		if !h.webhookTypeAllowed(event) {
+			t.Errorf("subscribed to %s, which the default allowlist doesn't process", event)
+		}
+	}
+	got := WebhookSubscriptions([]string{"order.paid", "recipient.*", "order.paid", "charge.refunded"})
+	if want := "order.paid,recipient.created,recipient.updated,charge.refunded"; strings.Join(got, ",") != want {
+		t.Errorf("subscriptions = %v, want %s", got, want)
+	}
+}
+
+func TestWebhookURLUsesAPIPrefix(t *testing.T) {
+	client := NewClient("sk_test", "", "", 500, "https://api.afterzin.com/")
+	if got, want := client.WebhookURL(), "https://api.afterzin.com/v1/webhook"; got != want {
+		t.Errorf("default WebhookURL() = %q, want %q", got, want)
+	}
+	client.APIPrefix = "/api/v1"
+	if got, want := client.WebhookURL(), "https://api.afterzin.com/api/v1/webhook"; got != want {
+		t.Errorf("prefixed WebhookURL() = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookAllowlistIgnoresAndCountsTypes(t *testing.T) {
+	sqlite := newTestDB(t)
+	orderID, _ := seedPendingOrder(t, sqlite, 1, 10, 50)
+	fake := newFakeClient()
+	fake.paidAmounts["or_test"] = 5000
+	cfg := &config.Config{JWTSecret: "test-secret", WebhookEventTypes: []string{"order.paid", "charge.refunded", "recipient.*"}}
+	h := NewHandler(fake, sqlite, cfg, mailer.LogMailer{})
+
+	count := func(eventType, outcome string) int64 {
+		m, _ := webhookEventCounts.byType.Get(eventType).(*expvar.Map)
+		if m == nil {
+			return 0
+		}
+		v, _ := m.Get(outcome).(*expvar.Int)
+		if v == nil {
+			return 0
+		}
+		return v.Value()
+	}
+	post := func(eventID, eventType string) {
+		t.Helper()
+		body := `{"id":"` + eventID + `","type":"` + eventType + `","data":{"id":"or_test","code":"` + orderID + `","charges":[{"id":"ch_test"}]}}`
+		rec := httptest.NewRecorder()
+		h.HandleWebhook(rec, httptest.NewRequest(http.MethodPost, "/v1/webhook", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200", eventType, rec.Code)
+		}
+	}
+	outcome := func(eventID string) string {
+		var o string
+		sqlite.QueryRow(`SELECT COALESCE(outcome, '') FROM pagarme_webhook_events WHERE pagarme_event_id = ?`, eventID).Scan(&o)
+		return o
+	}
+
+	paidBefore, otherBefore, refundBefore := count("order.paid", webhookHandled), count(webhookOtherType, webhookIgnored), count("charge.refunded", webhookIgnored)
+
+	// charge.paid is handled by default but not in this allowlist.
+	post("hook_charge", "charge.paid")
+	post("hook_new", "charge.created")
+	post("hook_refund", "charge.refunded") // allowed, but nothing handles it yet
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM tickets WHERE order_id = ?`, orderID); got != 0 {
+		t.Fatalf("tickets issued by ignored events = %d", got)
+	}
+	post("hook_paid", "order.paid")
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM tickets WHERE order_id = ?`, orderID); got != 1 {
+		t.Fatalf("tickets = %d, want 1", got)
+	}
+
+	for id, want := range map[string]string{"hook_charge": "ignored", "hook_new": "ignored", "hook_refund": "ignored", "hook_paid": "handled"} {
+		if got := outcome(id); got != want {
+			t.Errorf("%s outcome = %q, want %q", id, got, want)
+		}
+	}
+	if count("order.paid", webhookHandled) != paidBefore+1 || count(webhookOtherType, webhookIgnored) != otherBefore+1 ||
+		count("charge.refunded", webhookIgnored) != refundBefore+1 {
+		t.Errorf("counters not incremented: %s", webhookEventCounts.byType.String())
+	}
+	// Types nobody handles or lists share one counter, whatever is sent.
+	for i := 0; i < 3; i++ {
+		post(fmt.Sprintf("hook_junk_%d", i), fmt.Sprintf("junk.%d", i))
+	}
+	if count(webhookOtherType, webhookIgnored) != otherBefore+4 || webhookEventCounts.byType.Get("junk.0") != nil {
+		t.Errorf("unknown types counted apart: %s", webhookEventCounts.byType.String())
+	}
+
+	// Default allowlist: families match by prefix, unknown types don't.
+	def := NewHandler(fake, sqlite, &config.Config{JWTSecret: "test-secret"}, mailer.LogMailer{})
+	for eventType, want := range map[string]bool{"order.paid": true, "recipient.updated": true, "charge.created": false, "orders.paid": false} {
+		if got := def.webhookTypeAllowed(eventType); got != want {
+			t.Errorf("default allowlist %s = %v, want %v", eventType, got, want)
+		}
+	}
+
+	admin, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	rec := httptest.NewRecorder()
+	h.AdminWebhookStats(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/admin/webhook/stats", nil), admin.ID))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin stats: status = %d, want 403", rec.Code)
+	}
+	if _, err := sqlite.Exec(`UPDATE users SET role = 'ADMIN' WHERE id = ?`, admin.ID); err != nil {
+		t.Fatalf("promote admin: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	h.AdminWebhookStats(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/admin/webhook/stats", nil), admin.ID))
+	var stats struct {
+		SinceStart map[string]map[string]int64        `json:"sinceStart"`
+		Recorded   []repository.WebhookEventTypeCount `json:"recorded"`
+	}
+	json.NewDecoder(rec.Body).Decode(&stats)
+	if rec.Code != http.StatusOK || stats.SinceStart[webhookOtherType]["ignored"] < 4 || len(stats.Recorded) != 7 {
+		t.Errorf("stats = %d %+v", rec.Code, stats)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM audit_log`); got != 0 {
+		t.Errorf("audit entries written by a read = %d, want 0", got)
+	}
+}
@@ -0,0 +1,182 @@
+package pagarme
+
+import (
+	"expvar"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+)
+
+// DefaultWebhookEventTypes are the event types handled when
+// WEBHOOK_EVENT_TYPES is not set. A trailing ".*" matches a whole family.
+var DefaultWebhookEventTypes = []string{"order.paid", "charge.paid", "charge.authorized", "recipient.*"}
+
+// webhookEventCatalog are the Pagar.me events HandleWebhook has a handler
+// for; wildcards of the allowlist expand against it when subscribing.
+var webhookEventCatalog = []string{"order.paid", "charge.paid", "charge.authorized", "recipient.created", "recipient.updated"}
+
+// WebhookAllowlist returns the event types to process: types (the
+// WEBHOOK_EVENT_TYPES value), or DefaultWebhookEventTypes when empty.
+func WebhookAllowlist(types []string) []string {
+	if len(types) == 0 {
+		return DefaultWebhookEventTypes
+	}
+	return types
+}
+
+// WebhookSubscriptions returns the events to register with Pagar.me for the
+// allowlist of types (see WebhookAllowlist): exact names as given, and each
+// wildcard expanded to the handled events of its family, so everything the
+// webhook processes is subscribed and nothing else.
+func WebhookSubscriptions(types []string) []string {
+	var events []string
+	seen := map[string]bool{}
+	add := func(event string) {
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	for _, pattern := range WebhookAllowlist(types) {
+		if !strings.HasSuffix(pattern, "*") {
+			add(pattern)
+			continue
+		}
+		for _, event := range webhookEventCatalog {
+			if webhookTypeMatches(pattern, event) {
+				add(event)
+			}
+		}
+	}
+	return events
+}
+
+// webhookTypeMatches reports whether eventType matches an allowlist pattern.
+func webhookTypeMatches(pattern, eventType string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(eventType, prefix)
+	}
+	return eventType == pattern
+}
+
+// Webhook event outcomes, recorded per event and counted per type.
+const (
+	webhookHandled = "handled"
+	webhookIgnored = "ignored"
+)
+
+// webhookOtherType is the counter of event types that are neither handled
+// nor listed by name in the allowlist. The type comes from an
+// unauthenticated request, so a counter per type would let anyone grow the
+// map (and /debug/vars) without bound; the exact types are still recorded
+// per event in pagarme_webhook_events.
+const webhookOtherType = "other"
+
+// webhookEventCounts counts webhook events since startup per type and
+// outcome ({"order.paid": {"handled": 10}, "other": {"ignored": 2}}),
+// published through expvar as "pagarme_webhook_events".
+var webhookEventCounts = struct {
+	sync.Mutex
+	byType *expvar.Map
+}{byType: expvar.NewMap("pagarme_webhook_events")}
+
+// webhookCounterKey returns the counter eventType is counted under: its own
+// for the handled types and those named (without wildcard) in the
+// allowlist, webhookOtherType for everything else.
+func (h *Handler) webhookCounterKey(eventType string) string {
+	if slices.Contains(webhookEventCatalog, eventType) || slices.Contains(WebhookAllowlist(h.cfg.WebhookEventTypes), eventType) {
+		return eventType
+	}
+	return webhookOtherType
+}
+
+// countWebhookEvent records an outcome for an event type. It returns true the
+// first time the type's counter is used since startup.
+func (h *Handler) countWebhookEvent(eventType, outcome string) (first bool) {
+	key := h.webhookCounterKey(eventType)
+	webhookEventCounts.Lock()
+	defer webhookEventCounts.Unlock()
+	m, _ := webhookEventCounts.byType.Get(key).(*expvar.Map)
+	if m == nil {
+		m = new(expvar.Map)
+		webhookEventCounts.byType.Set(key, m)
+		first = true
+	}
+	m.Add(outcome, 1)
+	return first
+}
+
+// webhookTypeAllowed reports whether eventType is in the allowlist.
+func (h *Handler) webhookTypeAllowed(eventType string) bool {
+	for _, pattern := range WebhookAllowlist(h.cfg.WebhookEventTypes) {
+		if webhookTypeMatches(pattern, eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreWebhookEvent logs an ignored event. The first one of each counter
+// since startup is a warning, so types sent by Pagar.me that we don't handle
+// stand out in the logs; the "recorded" totals of AdminWebhookStats list
+// them by name.
+func (h *Handler) ignoreWebhookEvent(event *WebhookEvent, reason string) {
+	if h.countWebhookEvent(event.Type, webhookIgnored) {
+		logger.Warnf("novo tipo de evento ignorado: %s (%s) id=%s", event.Type, reason, event.ID)
+		return
+	}
+	logger.Debugf("evento ignorado: id=%s tipo=%s (%s)", event.ID, event.Type, reason)
+}
+
+// AdminWebhookStats handles GET /v1/admin/webhook/stats
+// Reports webhook volume per event type and outcome: counters since startup
+// ("sinceStart") and totals recorded in pagarme_webhook_events ("recorded").
+func (h *Handler) AdminWebhookStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	userID := middleware.UserID(r.Context())
+	if userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return
+	}
+	if !rest.IsAdmin(h.db, userID) {
+		rest.Error(w, http.StatusForbidden, "sem permissão")
+		return
+	}
+
+	recorded, err := repository.WebhookEventTypeCounts(h.db)
+	if err != nil {
+		logger.Errorf("erro ao contar eventos do webhook: %v", err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao contar eventos do webhook")
+		return
+	}
+	if recorded == nil {
+		recorded = []repository.WebhookEventTypeCount{}
+	}
+
+	sinceStart := map[string]map[string]int64{}
+	webhookEventCounts.Lock()
+	webhookEventCounts.byType.Do(func(kv expvar.KeyValue) {
+		counts := map[string]int64{}
+		kv.Value.(*expvar.Map).Do(func(c expvar.KeyValue) {
+			counts[c.Key] = c.Value.(*expvar.Int).Value()
+		})
+		sinceStart[kv.Key] = counts
+	})
+	webhookEventCounts.Unlock()
+
+	rest.JSON(w, http.StatusOK, map[string]interface{}{
+		"allowedTypes": WebhookAllowlist(h.cfg.WebhookEventTypes),
+		"sinceStart":   sinceStart,
+		"recorded":     recorded,
+	})
+}
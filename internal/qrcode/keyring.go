@@ -0,0 +1,71 @@
+package qrcode
+
+// KeyRing holds the keys ticket QR payloads are signed with, current key
+// first. New payloads are signed with the current key only; a payload signed
+// with any key of the ring is accepted, so a key can be rotated without
+// invalidating tickets already issued: put the new key in front and drop the
+// old one once those tickets no longer matter.
+type KeyRing [][]byte
+
+// NewKeyRing builds a ring from keys, current first. Empty keys are skipped;
+// an empty ring signs and verifies with the package default secret, like the
+// functions taking a single secret.
+func NewKeyRing(keys ...string) KeyRing {
+	ring := make(KeyRing, 0, len(keys))
+	for _, k := range keys {
+		if k != "" {
+			ring = append(ring, []byte(k))
+		}
+	}
+	return ring
+}
+
+// current returns the signing key, or nil (the default secret) for an empty
+// ring.
+func (k KeyRing) current() []byte {
+	if len(k) == 0 {
+		return nil
+	}
+	return k[0]
+}
+
+// verifyKeys returns the keys to try, current first.
+func (k KeyRing) verifyKeys() [][]byte {
+	if len(k) == 0 {
+		return [][]byte{nil}
+	}
+	return k
+}
+
+// Sign produces a V1 payload (see GenerateSignedPayload) with the current key.
+func (k KeyRing) Sign(ticketID string) string {
+	return GenerateSignedPayload(ticketID, k.current())
+}
+
+// SignV2 produces a V2 payload (see GenerateSignedPayloadV2) with the current
+// key.
+func (k KeyRing) SignV2(ticketID, chargeID, eventID string) string {
+	return GenerateSignedPayloadV2(ticketID, chargeID, eventID, k.current())
+}
+
+// Verify verifies a V1 payload against every key of the ring and returns the
+// ticket ID if any of them signed it.
+func (k KeyRing) Verify(payload string) (ticketID string, ok bool) {
+	for _, key := range k.verifyKeys() {
+		if ticketID, ok = VerifySignedPayload(payload, key); ok {
+			return ticketID, true
+		}
+	}
+	return ticketID, false
+}
+
+// VerifyV2 verifies a V2 payload against every key of the ring and extracts
+// its components if any of them signed it.
+func (k KeyRing) VerifyV2(payload string) (ticketID, chargeID, eventID string, ok bool) {
+	for _, key := range k.verifyKeys() {
+		if ticketID, chargeID, eventID, ok = VerifySignedPayloadV2(payload, key); ok {
+			return ticketID, chargeID, eventID, true
+		}
+	}
+	return "", "", "", false
+}
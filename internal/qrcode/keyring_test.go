@@ -0,0 +1,55 @@
+package qrcode
+
+import "testing"
+
+func TestKeyRingEmptyUsesDefaultSecret(t *testing.T) {
+	ring := NewKeyRing("", "")
+	if len(ring) != 0 {
+		t.Fatalf("NewKeyRing with empty keys = %d keys, want 0", len(ring))
+	}
+	if got, want := ring.Sign("t1"), GenerateSignedPayload("t1", []byte("default-secret")); got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+	if id, ok := ring.Verify(GenerateSignedPayload("t1", nil)); !ok || id != "t1" {
+		t.Errorf("Verify default-secret payload = (%q, %v), want (t1, true)", id, ok)
+	}
+	if _, _, _, ok := ring.VerifyV2(GenerateSignedPayloadV2("t1", "ch", "ev", nil)); !ok {
+		t.Error("VerifyV2 default-secret payload failed")
+	}
+}
+
+func TestKeyRingSignsWithCurrentKey(t *testing.T) {
+	ring := NewKeyRing("new", "old")
+	if got, want := ring.Sign("t1"), GenerateSignedPayload("t1", []byte("new")); got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+	if got, want := ring.SignV2("t1", "ch", "ev"), GenerateSignedPayloadV2("t1", "ch", "ev", []byte("new")); got != want {
+		t.Errorf("SignV2 = %q, want %q", got, want)
+	}
+}
+
+func TestKeyRingVerifiesPreviousKeys(t *testing.T) {
+	ring := NewKeyRing("new", "old")
+	for _, key := range []string{"new", "old"} {
+		if id, ok := ring.Verify(GenerateSignedPayload("t1", []byte(key))); !ok || id != "t1" {
+			t.Errorf("Verify payload signed with %s = (%q, %v), want (t1, true)", key, id, ok)
+		}
+		id, charge, event, ok := ring.VerifyV2(GenerateSignedPayloadV2("t1", "ch", "ev", []byte(key)))
+		if !ok || id != "t1" || charge != "ch" || event != "ev" {
+			t.Errorf("VerifyV2 payload signed with %s = (%q, %q, %q, %v)", key, id, charge, event, ok)
+		}
+	}
+}
+
+func TestKeyRingRejectsDroppedKey(t *testing.T) {
+	ring := NewKeyRing("new")
+	if _, ok := ring.Verify(GenerateSignedPayload("t1", []byte("old"))); ok {
+		t.Error("Verify accepted a payload signed with a dropped key")
+	}
+	if _, _, _, ok := ring.VerifyV2(GenerateSignedPayloadV2("t1", "ch", "ev", []byte("old"))); ok {
+		t.Error("VerifyV2 accepted a payload signed with a dropped key")
+	}
+	if _, ok := ring.Verify("t1.zz"); ok {
+		t.Error("Verify accepted a malformed payload")
+	}
+}
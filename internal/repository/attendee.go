@@ -0,0 +1,49 @@
+package repository
+
+import "database/sql"
+
+// AttendeeRow is a ticket joined with its holder, ticket type and date, as
+// listed in the attendee export.
+type AttendeeRow struct {
+	TicketRow
+	HolderName     string
+	HolderEmail    string
+	TicketTypeName string
+	Date           string
+	StartTime      sql.NullString
+}
+
+// StreamAttendeesByEvent calls fn for each ticket of an event with its holder
+// data, one row at a time. Like StreamTicketsByEvent, fn must not query db.
+func StreamAttendeesByEvent(db *sql.DB, eventID string, fn func(AttendeeRow) error) error {
+	rows, err := db.Query(`
+		SELECT t.id, t.code, t.qr_code, t.order_id, t.order_item_id, t.user_id, t.event_id, t.event_date_id, t.ticket_type_id, t.admits, t.admits_remaining, t.source, t.used, t.used_at, t.created_at,
+			u.name, u.email, tt.name, ed.date, ed.start_time
+		FROM tickets t
+		JOIN users u ON u.id = t.user_id
+		JOIN ticket_types tt ON tt.id = t.ticket_type_id
+		JOIN event_dates ed ON ed.id = t.event_date_id
+		WHERE t.event_id = ?
+		ORDER BY ed.date, u.name, t.id`, eventID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a AttendeeRow
+		var usedAt, createdAt sql.NullString
+		err := rows.Scan(&a.ID, &a.Code, &a.QRCode, &a.OrderID, &a.OrderItemID, &a.UserID, &a.EventID, &a.EventDateID, &a.TicketTypeID, &a.Admits, &a.AdmitsRemaining, &a.Source, &a.Used, &usedAt, &createdAt,
+			&a.HolderName, &a.HolderEmail, &a.TicketTypeName, &a.Date, &a.StartTime)
+		if err != nil {
+			return err
+		}
+		a.UsedAt = usedAt
+		if createdAt.Valid {
+			a.CreatedAt = parseTimestamp(createdAt.String)
+		}
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// InsertAuditLog records a sensitive action taken by actorUserID on an entity.
+func InsertAuditLog(db *sql.DB, actorUserID, action, entityType, entityID, details string) error {
+	_, err := db.Exec(`INSERT INTO audit_log (id, actor_user_id, action, entity_type, entity_id, details, created_at) VALUES (?, ?, ?, ?, ?, ?, `+nowSQL+`)`,
+		uuid.New().String(), nullIfEmpty(actorUserID), action, entityType, entityID, nullIfEmpty(details),
+	)
+	return err
+}
+
+// InsertAuditLogTx records an audit entry in the caller's transaction.
+func InsertAuditLogTx(tx *sql.Tx, actorUserID, action, entityType, entityID, details string) error {
+	_, err := tx.Exec(`INSERT INTO audit_log (id, actor_user_id, action, entity_type, entity_id, details, created_at) VALUES (?, ?, ?, ?, ?, ?, `+nowSQL+`)`,
+		uuid.New().String(), nullIfEmpty(actorUserID), action, entityType, entityID, nullIfEmpty(details),
+	)
+	return err
+}
+
+// AuditLogRow is one audit_log entry.
+type AuditLogRow struct {
+	ID          string `json:"id"`
+	ActorUserID string `json:"actorUserId,omitempty"`
+	ActorEmail  string `json:"actorEmail,omitempty"`
+	Action      string `json:"action"`
+	EntityType  string `json:"entityType"`
+	EntityID    string `json:"entityId"`
+	Details     string `json:"details,omitempty"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+// AuditLogFilter narrows ListAuditLog. Empty fields match everything;
+// ActionPrefix matches actions starting with it (e.g. "admin."). AdminsOnly
+// keeps entries whose actor is currently an ADMIN.
+type AuditLogFilter struct {
+	ActorUserID  string
+	AdminsOnly   bool
+	ActionPrefix string
+	EntityType   string
+	EntityID     string
+}
+
+// ListAuditLog returns a page of audit entries, newest first, and the total
+// number of entries matching the filter.
+func ListAuditLog(db *sql.DB, f AuditLogFilter, limit, offset int) ([]AuditLogRow, int, error) {
+	var conds []string
+	var args []interface{}
+	if f.ActorUserID != "" {
+		conds = append(conds, "a.actor_user_id = ?")
+		args = append(args, f.ActorUserID)
+	}
+	if f.AdminsOnly {
+		conds = append(conds, "a.actor_user_id IN (SELECT id FROM users WHERE role = 'ADMIN')")
+	}
+	if f.ActionPrefix != "" {
+		conds = append(conds, "substr(a.action, 1, length(?)) = ?")
+		args = append(args, f.ActionPrefix, f.ActionPrefix)
+	}
+	if f.EntityType != "" {
+		conds = append(conds, "a.entity_type = ?")
+		args = append(args, f.EntityType)
+	}
+	if f.EntityID != "" {
+		conds = append(conds, "a.entity_id = ?")
+		args = append(args, f.EntityID)
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM audit_log a`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(`SELECT a.id, COALESCE(a.actor_user_id, ''), COALESCE(u.email, ''), a.action, a.entity_type, a.entity_id, COALESCE(a.details, ''), a.created_at
+		FROM audit_log a LEFT JOIN users u ON u.id = a.actor_user_id`+where+`
+		ORDER BY a.created_at DESC, a.rowid DESC LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list := []AuditLogRow{}
+	for rows.Next() {
+		var a AuditLogRow
+		if err := rows.Scan(&a.ID, &a.ActorUserID, &a.ActorEmail, &a.Action, &a.EntityType, &a.EntityID, &a.Details, &a.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		list = append(list, a)
+	}
+	return list, total, rows.Err()
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"database/sql"
+)
+
+// Kinds of blocked identity.
+const (
+	BlockedCPF   = "CPF"
+	BlockedEmail = "EMAIL"
+)
+
+// BlockedIdentity is one denylist entry. Value is a CPF (digits only) or a
+// lowercased email, as normalized by the caller.
+type BlockedIdentity struct {
+	Kind      string `json:"kind"`
+	Value     string `json:"value"`
+	Reason    string `json:"reason,omitempty"`
+	CreatedBy string `json:"createdBy,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// BlockIdentity adds an entry to the denylist, or updates the reason of an
+// existing one.
+func BlockIdentity(db execer, kind, value, reason, actorUserID string) error {
+	_, err := db.Exec(`INSERT INTO blocked_identities (kind, value, reason, created_by) VALUES (?, ?, ?, ?)
+		ON CONFLICT (kind, value) DO UPDATE SET reason = excluded.reason`,
+		kind, value, nullIfEmpty(reason), nullIfEmpty(actorUserID),
+	)
+	return err
+}
+
+// UnblockIdentity removes an entry from the denylist, reporting whether it
+// was there.
+func UnblockIdentity(db execer, kind, value string) (bool, error) {
+	res, err := db.Exec(`DELETE FROM blocked_identities WHERE kind = ? AND value = ?`, kind, value)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ListBlockedIdentities returns the denylist, newest first.
+func ListBlockedIdentities(db *sql.DB) ([]BlockedIdentity, error) {
+	rows, err := db.Query(`SELECT kind, value, reason, created_by, created_at FROM blocked_identities ORDER BY created_at DESC, kind, value`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []BlockedIdentity
+	for rows.Next() {
+		var b BlockedIdentity
+		var reason, createdBy sql.NullString
+		if err := rows.Scan(&b.Kind, &b.Value, &reason, &createdBy, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		b.Reason, b.CreatedBy = reason.String, createdBy.String
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
+
+// BlockedIdentityMatch returns the denylist entry matching cpf or email, or
+// nil. Empty arguments match nothing.
+func BlockedIdentityMatch(db *sql.DB, cpf, email string) (*BlockedIdentity, error) {
+	var b BlockedIdentity
+	var reason, createdBy sql.NullString
+	err := db.QueryRow(`SELECT kind, value, reason, created_by, created_at FROM blocked_identities
+		WHERE (kind = 'CPF' AND value = ? AND ? <> '') OR (kind = 'EMAIL' AND value = ? AND ? <> '')
+		LIMIT 1`, cpf, cpf, email, email,
+	).Scan(&b.Kind, &b.Value, &reason, &createdBy, &b.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b.Reason, b.CreatedBy = reason.String, createdBy.String
+	return &b, nil
+}
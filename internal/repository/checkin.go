@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Check-in attempt results
+const (
+	CheckinResultOK          = "ok"
+	CheckinResultAlreadyUsed = "already_used"
+	CheckinResultInvalid     = "invalid"
+	CheckinResultWrongEvent  = "wrong_event"
+)
+
+// CheckinAttempt is one scan at the gate. EventID/TicketID are empty when unknown.
+type CheckinAttempt struct {
+	EventID    string
+	TicketID   string
+	ProducerID string
+	Result     string
+	ScannedAt  time.Time
+}
+
+// CheckinStatsRow aggregates scan attempts for an event.
+type CheckinStatsRow struct {
+	TotalScans      int
+	UniqueTickets   int
+	Duplicates      int
+	InvalidAttempts int
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// InsertCheckinAttemptsTx records scan attempts in the caller's transaction.
+func InsertCheckinAttemptsTx(tx *sql.Tx, attempts []CheckinAttempt) error {
+	if len(attempts) == 0 {
+		return nil
+	}
+	stmt, err := tx.Prepare(`INSERT INTO checkin_attempts (id, event_id, ticket_id, producer_id, result, scanned_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ` + nowSQL + `)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, a := range attempts {
+		scannedAt := a.ScannedAt
+		if scannedAt.IsZero() {
+			scannedAt = time.Now()
+		}
+		if _, err := stmt.Exec(uuid.New().String(), nullIfEmpty(a.EventID), nullIfEmpty(a.TicketID), nullIfEmpty(a.ProducerID), a.Result, FormatTime(scannedAt)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InsertCheckinAttempts records scan attempts in a single transaction.
+func InsertCheckinAttempts(db *sql.DB, attempts []CheckinAttempt) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := InsertCheckinAttemptsTx(tx, attempts); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CheckinStats returns scan totals for an event: all attempts, distinct tickets
+// scanned, repeat scans of used tickets and rejected (invalid/wrong event) scans.
+func CheckinStats(db *sql.DB, eventID string) (*CheckinStatsRow, error) {
+	var s CheckinStatsRow
+	err := db.QueryRow(`SELECT COUNT(*),
+		COUNT(DISTINCT CASE WHEN result IN ('ok', 'already_used') THEN ticket_id END),
+		COALESCE(SUM(CASE WHEN result = 'already_used' THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN result IN ('invalid', 'wrong_event') THEN 1 ELSE 0 END), 0)
+		FROM checkin_attempts WHERE event_id = ?`, eventID).Scan(&s.TotalScans, &s.UniqueTickets, &s.Duplicates, &s.InvalidAttempts)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CountCompTicketsTx returns how many complimentary tickets an event has issued.
+func CountCompTicketsTx(tx *sql.Tx, eventID string) (int, error) {
+	var n int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM tickets WHERE event_id = ? AND source = ?`, eventID, TicketSourceComp).Scan(&n)
+	return n, err
+}
+
+// CreateCompOrderTx creates the PAID, zero-total order and single item a
+// complimentary ticket hangs from, so comps show up in the recipient's orders
+// and keep the tickets → orders references intact.
+func CreateCompOrderTx(tx *sql.Tx, userID, eventDateID, ticketTypeID string) (orderID, orderItemID string, err error) {
+	orderID = uuid.New().String()
+	ref, err := nextOrderRefTx(tx, time.Now())
+	if err != nil {
+		return "", "", err
+	}
+	if _, err = tx.Exec(`INSERT INTO orders (id, user_id, status, total, order_ref, created_at) VALUES (?, ?, 'PAID', 0, ?, `+nowSQL+`)`, orderID, userID, ref); err != nil {
+		return "", "", err
+	}
+	orderItemID = uuid.New().String()
+	_, err = tx.Exec(`INSERT INTO order_items (id, order_id, event_date_id, ticket_type_id, quantity, unit_price, created_at) VALUES (?, ?, ?, ?, 1, 0, `+nowSQL+`)`,
+		orderItemID, orderID, eventDateID, ticketTypeID)
+	return orderID, orderItemID, err
+}
+
+// ReserveTicketTypeUnitsTx adds n to sold_quantity unless that would exceed
+// max_quantity. Returns false when there aren't n units left.
+func ReserveTicketTypeUnitsTx(tx *sql.Tx, ticketTypeID string, n int) (bool, error) {
+	res, err := tx.Exec(`UPDATE ticket_types SET sold_quantity = sold_quantity + ? WHERE id = ? AND sold_quantity + ? <= max_quantity`, n, ticketTypeID, n)
+	if err != nil {
+		return false, err
+	}
+	rows, _ := res.RowsAffected()
+	return rows == 1, nil
+}
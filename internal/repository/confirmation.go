@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+// confirmationLatencySQL is the seconds between an order's PIX creation and
+// its payment confirmation.
+const confirmationLatencySQL = `CAST(ROUND((julianday(o.confirmed_at) - julianday(o.pix_created_at)) * 86400) AS INTEGER)`
+
+// ConfirmationLatency returns how long after its PIX was created an order
+// was confirmed. ok is false when either time is missing (orders confirmed
+// without a PIX, or paid before the times were recorded).
+func ConfirmationLatency(db *sql.DB, orderID string) (latency time.Duration, ok bool, err error) {
+	var seconds sql.NullInt64
+	err = db.QueryRow(`SELECT `+confirmationLatencySQL+` FROM orders o WHERE o.id = ?`, orderID).Scan(&seconds)
+	if err == sql.ErrNoRows {
+		return 0, false, ErrOrderNotFound
+	}
+	if err != nil || !seconds.Valid {
+		return 0, false, err
+	}
+	return time.Duration(seconds.Int64) * time.Second, true, nil
+}
+
+// ConfirmationLatencyRow is a confirmed order with its confirmation latency.
+type ConfirmationLatencyRow struct {
+	OrderID        string `json:"orderId"`
+	OrderRef       string `json:"orderRef,omitempty"`
+	PagarmeOrderID string `json:"pagarmeOrderId,omitempty"`
+	PixCreatedAt   string `json:"pixCreatedAt"`
+	ConfirmedAt    string `json:"confirmedAt"`
+	LatencySeconds int64  `json:"latencySeconds"`
+}
+
+// SlowestConfirmations returns the orders confirmed since the given time
+// with the longest confirmation latency, slowest first.
+func SlowestConfirmations(db *sql.DB, since time.Time, limit int) ([]ConfirmationLatencyRow, error) {
+	rows, err := db.Query(`SELECT o.id, o.order_ref, o.pagarme_order_id, o.pix_created_at, o.confirmed_at, `+confirmationLatencySQL+` AS latency
+		FROM orders o
+		WHERE o.confirmed_at >= ? AND o.pix_created_at IS NOT NULL
+		ORDER BY latency DESC, o.confirmed_at DESC
+		LIMIT ?`, FormatTime(since), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []ConfirmationLatencyRow
+	for rows.Next() {
+		var c ConfirmationLatencyRow
+		var ref, pagarmeOrderID sql.NullString
+		if err := rows.Scan(&c.OrderID, &ref, &pagarmeOrderID, &c.PixCreatedAt, &c.ConfirmedAt, &c.LatencySeconds); err != nil {
+			return nil, err
+		}
+		c.OrderRef, c.PagarmeOrderID = ref.String, pagarmeOrderID.String
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// Sales (order_items) under each level of the catalog. Tickets always belong
+// to an order item, so counting order items is enough.
+const (
+	salesByTicketType = `SELECT COUNT(*) FROM order_items WHERE ticket_type_id = ?`
+	salesByLot        = `SELECT COUNT(*) FROM order_items oi JOIN ticket_types tt ON tt.id = oi.ticket_type_id WHERE tt.lot_id = ?`
+	salesByEventDate  = `SELECT COUNT(*) FROM order_items WHERE event_date_id = ?`
+	salesByEvent      = `SELECT COUNT(*) FROM order_items oi JOIN event_dates ed ON ed.id = oi.event_date_id WHERE ed.event_id = ?`
+	salesByProducer   = `SELECT COUNT(*) FROM order_items oi JOIN event_dates ed ON ed.id = oi.event_date_id
+		JOIN events e ON e.id = ed.event_id WHERE e.producer_id = ?`
+)
+
+// DeleteProducer removes a producer and, by cascade, all its events. Returns
+// ErrHasSales if any of them sold anything, and false if it doesn't exist.
+func DeleteProducer(db *sql.DB, id string) (bool, error) {
+	return deleteCatalogRow(db, "producers", id, salesByProducer)
+}
+
+// DeleteEvent removes an event with its dates, lots and ticket types.
+// Returns ErrHasSales if it sold anything, and false if it doesn't exist.
+func DeleteEvent(db *sql.DB, id string) (bool, error) {
+	return deleteCatalogRow(db, "events", id, salesByEvent)
+}
+
+// DeleteEventDate removes an event date with its lots and ticket types.
+func DeleteEventDate(db *sql.DB, id string) (bool, error) {
+	return deleteCatalogRow(db, "event_dates", id, salesByEventDate)
+}
+
+// DeleteLot removes a lot with its ticket types.
+func DeleteLot(db *sql.DB, id string) (bool, error) {
+	return deleteCatalogRow(db, "lots", id, salesByLot)
+}
+
+// DeleteTicketType removes a ticket type that has never been ordered.
+func DeleteTicketType(db *sql.DB, id string) (bool, error) {
+	return deleteCatalogRow(db, "ticket_types", id, salesByTicketType)
+}
+
+// deleteCatalogRow checks for sales and deletes in one transaction, so an
+// order created in between can't slip past the check. The RESTRICT foreign
+// keys on order_items and tickets are the backstop if it ever does.
+func deleteCatalogRow(db *sql.DB, table, id, salesQuery string) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var sales int
+	if err := tx.QueryRow(salesQuery, id).Scan(&sales); err != nil {
+		return false, err
+	}
+	if sales > 0 {
+		return false, ErrHasSales
+	}
+	res, err := tx.Exec(`DELETE FROM `+table+` WHERE id = ?`, id)
+	if err != nil {
+		if isForeignKeyError(err) {
+			return false, ErrHasSales
+		}
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return false, nil
+	}
+	return true, tx.Commit()
+}
+
+func isForeignKeyError(err error) bool {
+	return strings.Contains(err.Error(), "FOREIGN KEY constraint failed")
+}
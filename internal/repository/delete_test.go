@@ -0,0 +1,83 @@
+package repository_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+// countRows runs a COUNT query.
+func countRows(t *testing.T, sqlite *sql.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := sqlite.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("count %q: %v", query, err)
+	}
+	return n
+}
+
+func TestForeignKeysAreEnforced(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	orderID := f.PendingOrder(t, sqlite, 1)
+	items, _ := repository.OrderItemsByOrderID(sqlite, orderID)
+	it := items[0]
+
+	err := repository.CreateTicketWithID(sqlite, "ticket-orphan", "ORPHAN01", "qr-orphan",
+		"pedido-inexistente", it.ID, f.BuyerID, f.EventID, f.EventDateID, f.TicketTypeID, repository.TicketSourcePurchase)
+	if err == nil {
+		t.Fatal("ticket with a bogus order_id was inserted")
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM tickets WHERE id = 'ticket-orphan'`); got != 0 {
+		t.Errorf("orphan tickets = %d, want 0", got)
+	}
+
+	// The same ticket with a real order goes in
+	if err := repository.CreateTicketWithID(sqlite, "ticket-ok", "VALID001", "qr-ok",
+		orderID, it.ID, f.BuyerID, f.EventID, f.EventDateID, f.TicketTypeID, repository.TicketSourcePurchase); err != nil {
+		t.Errorf("valid ticket: %v", err)
+	}
+}
+
+func TestDeleteCatalogRespectsSales(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	f.PendingOrder(t, sqlite, 1)
+
+	if _, err := repository.DeleteEvent(sqlite, f.EventID); err != repository.ErrHasSales {
+		t.Errorf("DeleteEvent with sales: err = %v, want ErrHasSales", err)
+	}
+	if _, err := repository.DeleteTicketType(sqlite, f.TicketTypeID); err != repository.ErrHasSales {
+		t.Errorf("DeleteTicketType with sales: err = %v, want ErrHasSales", err)
+	}
+	if _, err := repository.DeleteProducer(sqlite, f.ProducerID); err != repository.ErrHasSales {
+		t.Errorf("DeleteProducer with sales: err = %v, want ErrHasSales", err)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM lots WHERE id = ?`, f.LotID); got != 1 {
+		t.Errorf("lot deleted despite sales")
+	}
+
+	// The RESTRICT keys hold even when the repository check is bypassed
+	if _, err := sqlite.Exec(`DELETE FROM events WHERE id = ?`, f.EventID); err == nil {
+		t.Error("raw delete of an event with sales succeeded")
+	}
+
+	// An event without sales goes with its whole structure
+	eventID, _ := repository.CreateEvent(sqlite, f.ProducerID, "Sem vendas", "desc", "shows", "cover", "Local", nil, "")
+	dateID, _ := repository.CreateEventDate(sqlite, eventID, "2099-02-01", nil, nil)
+	emptyLotID, _ := repository.CreateLot(sqlite, dateID, "Lote 1", "2000-01-01T00:00:00Z", "2099-01-01T00:00:00Z", 10)
+	repository.CreateTicketType(sqlite, emptyLotID, "Inteira", nil, 50, "GENERAL", 10, 1)
+
+	deleted, err := repository.DeleteEvent(sqlite, eventID)
+	if err != nil || !deleted {
+		t.Fatalf("DeleteEvent without sales = %v, %v", deleted, err)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM ticket_types WHERE lot_id = ?`, emptyLotID); got != 0 {
+		t.Errorf("ticket types left after delete = %d", got)
+	}
+	if deleted, err := repository.DeleteEvent(sqlite, eventID); err != nil || deleted {
+		t.Errorf("second DeleteEvent = %v, %v, want false, nil", deleted, err)
+	}
+}
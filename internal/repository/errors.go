@@ -0,0 +1,42 @@
+package repository
+
+import "errors"
+
+// Sentinel errors for business conditions, so callers can tell "not there"
+// from "not allowed in this state" with errors.Is instead of reading
+// sql.ErrNoRows, which only ever means a lookup found nothing.
+var (
+	// ErrOrderNotFound is returned when the order doesn't exist.
+	ErrOrderNotFound = errors.New("pedido não encontrado")
+	// ErrOrderForbidden is returned by AuthorizeOrderAccess when the order
+	// belongs to another user.
+	ErrOrderForbidden = errors.New("pedido não pertence ao usuário")
+	// ErrOrderNotProcessing is returned by ConfirmOrderTx when the order
+	// wasn't claimed for processing (ClaimOrderProcessingTx) first.
+	ErrOrderNotProcessing = errors.New("pedido não está em processamento")
+	// ErrOrderNotConfirmable is returned by ConfirmOrder for orders that are
+	// neither PENDING nor PROCESSING (already paid, cancelled, refunded...).
+	ErrOrderNotConfirmable = errors.New("pedido não pode ser confirmado no status atual")
+	// ErrOrderNotEditable is returned by UpdateOrderItems for orders that are
+	// no longer PENDING.
+	ErrOrderNotEditable = errors.New("pedido não pode mais ser alterado")
+	// ErrOrderHasPayment is returned by UpdateOrderItems once the payment
+	// has started or a Pagar.me order exists: its PIX charges the old total.
+	ErrOrderHasPayment = errors.New("pedido já tem pagamento gerado")
+	// ErrPaymentInProgress is returned by ClaimOrderPayment while another
+	// request is creating the order's payment.
+	ErrPaymentInProgress = errors.New("pagamento do pedido já está sendo gerado; tente novamente em instantes")
+	// ErrPendingOrderLimit is returned by CreateOrderWithItems when the
+	// buyer already holds OrderLimits.MaxPending unexpired PENDING orders.
+	ErrPendingOrderLimit = errors.New("limite de pedidos pendentes atingido")
+	// ErrClientOrderLimit is returned by CreateOrderWithItems when
+	// OrderLimits.ClientIP already holds MaxPendingPerIP of them.
+	ErrClientOrderLimit = errors.New("muitos pedidos pendentes a partir deste endereço; tente novamente mais tarde")
+	// ErrInsufficientInventory is returned when a lot doesn't have enough
+	// places left for the requested quantity.
+	ErrInsufficientInventory = errors.New("quantidade insuficiente no lote")
+	// ErrHasSales is returned when deleting a producer, event, date, lot or
+	// ticket type that orders or tickets still point to. Cancel or refund
+	// the sales and unpublish the event instead.
+	ErrHasSales = errors.New("não é possível excluir: existem pedidos ou ingressos vinculados")
+)
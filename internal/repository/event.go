@@ -2,6 +2,8 @@ package repository
 
 import (
 	"database/sql"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -41,6 +43,56 @@ func ListEventsByProducerIDExcludingDraft(db *sql.DB, producerID string) ([]stri
 	return ids, rows.Err()
 }
 
+// ProducerEventRow is an event with its sales totals, for the producer's own
+// event list. Revenue is the gross amount of PAID orders.
+type ProducerEventRow struct {
+	EventRow
+	TicketsSold int
+	Revenue     float64
+}
+
+// EventsByProducer returns a page of a producer's events, newest first, and
+// the total number matching. DRAFT events are left out unless
+// includeUnpublished is set; a non-empty status keeps only that status.
+func EventsByProducer(db *sql.DB, producerID string, includeUnpublished bool, status string, limit, offset int) ([]*ProducerEventRow, int, error) {
+	where := ` WHERE e.producer_id = ?`
+	args := []interface{}{producerID}
+	if !includeUnpublished {
+		where += ` AND e.status != 'DRAFT'`
+	}
+	if status != "" {
+		where += ` AND e.status = ?`
+		args = append(args, status)
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM events e`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(`SELECT e.id, e.producer_id, e.title, e.description, e.category, e.cover_image, e.location, e.address, e.status, e.featured, e.timezone,
+		(SELECT COUNT(*) FROM tickets t WHERE t.event_id = e.id),
+		(SELECT COALESCE(SUM(oi.quantity * oi.unit_price), 0) FROM order_items oi
+			JOIN event_dates ed ON ed.id = oi.event_date_id
+			JOIN orders o ON o.id = oi.order_id
+			WHERE ed.event_id = e.id AND o.status = 'PAID')
+		FROM events e`+where+` ORDER BY e.created_at DESC, e.id LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var list []*ProducerEventRow
+	for rows.Next() {
+		var e ProducerEventRow
+		if err := rows.Scan(&e.ID, &e.ProducerID, &e.Title, &e.Description, &e.Category, &e.CoverImage, &e.Location, &e.Address, &e.Status, &e.Featured, &e.Timezone,
+			&e.TicketsSold, &e.Revenue); err != nil {
+			return nil, 0, err
+		}
+		list = append(list, &e)
+	}
+	return list, total, rows.Err()
+}
+
 // EventProducerID returns the producer_id for an event.
 func EventProducerID(db *sql.DB, eventID string) (string, error) {
 	var producerID string
@@ -51,6 +103,136 @@ func EventProducerID(db *sql.DB, eventID string) (string, error) {
 	return producerID, err
 }
 
+func EventProducerIDTx(tx *sql.Tx, eventID string) (string, error) {
+	var producerID string
+	err := tx.QueryRow(`SELECT producer_id FROM events WHERE id = ?`, eventID).Scan(&producerID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return producerID, err
+}
+
+// EventMaxOrderCentavos returns the event's order amount cap override (0 when unset).
+func EventMaxOrderCentavos(db *sql.DB, eventID string) (int64, error) {
+	var max sql.NullInt64
+	err := db.QueryRow(`SELECT max_order_centavos FROM events WHERE id = ?`, eventID).Scan(&max)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return max.Int64, err
+}
+
+// SetEventMaxOrderCentavos sets (or clears, with 0) the event's order amount cap override.
+func SetEventMaxOrderCentavos(db execer, eventID string, max int64) error {
+	var v interface{}
+	if max > 0 {
+		v = max
+	}
+	_, err := db.Exec(`UPDATE events SET max_order_centavos = ? WHERE id = ?`, v, eventID)
+	return err
+}
+
+// SetEventTimezone sets (or clears, with "") the event's IANA time zone.
+// Callers validate the name.
+func SetEventTimezone(db *sql.DB, eventID, timezone string) error {
+	var v interface{}
+	if timezone != "" {
+		v = timezone
+	}
+	_, err := db.Exec(`UPDATE events SET timezone = ?, updated_at = `+nowSQL+` WHERE id = ?`, v, eventID)
+	return err
+}
+
+// EventCapacity returns the event's venue capacity (0 when unlimited).
+func EventCapacity(db *sql.DB, eventID string) (int, error) {
+	var capacity sql.NullInt64
+	err := db.QueryRow(`SELECT capacity FROM events WHERE id = ?`, eventID).Scan(&capacity)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return int(capacity.Int64), err
+}
+
+// SetEventCapacity sets (or clears, with 0) the event's venue capacity.
+func SetEventCapacity(db *sql.DB, eventID string, capacity int) error {
+	var v interface{}
+	if capacity > 0 {
+		v = capacity
+	}
+	_, err := db.Exec(`UPDATE events SET capacity = ?, updated_at = `+nowSQL+` WHERE id = ?`, v, eventID)
+	return err
+}
+
+// EventTotalAvailability returns the places left across the active lots of
+// all of an event's dates whose sale hasn't ended by now, and how many such
+// lots there are. Lot times are stored as sent by the producer (RFC3339 with
+// any offset), so they are compared after parsing; a lot whose end can't be
+// parsed counts as still on sale.
+func EventTotalAvailability(db *sql.DB, eventID string, now time.Time) (available, lots int, err error) {
+	rows, err := db.Query(`SELECT l.available_quantity, l.ends_at FROM lots l
+		JOIN event_dates ed ON ed.id = l.event_date_id
+		WHERE ed.event_id = ? AND l.active = 1`, eventID)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var n int
+		var endsAt string
+		if err := rows.Scan(&n, &endsAt); err != nil {
+			return 0, 0, err
+		}
+		if end, err := ParseTime(endsAt); err == nil && !end.After(now) {
+			continue
+		}
+		available += max(n, 0)
+		lots++
+	}
+	return available, lots, rows.Err()
+}
+
+// EventDateLotsTotal returns the sum of total_quantity over the lots of an event date.
+func EventDateLotsTotal(db *sql.DB, eventDateID string) (int, error) {
+	var total int
+	err := db.QueryRow(`SELECT COALESCE(SUM(total_quantity), 0) FROM lots WHERE event_date_id = ?`, eventDateID).Scan(&total)
+	return total, err
+}
+
+// EventRemainingCapacity returns how many more places lots of an event date
+// may add before reaching the event capacity. limited is false (and remaining
+// 0) when the event has no capacity set.
+func EventRemainingCapacity(db *sql.DB, eventDateID string) (remaining int, limited bool, err error) {
+	var capacity sql.NullInt64
+	var total int
+	err = db.QueryRow(`SELECT e.capacity, COALESCE((SELECT SUM(l.total_quantity) FROM lots l WHERE l.event_date_id = ed.id), 0)
+		FROM event_dates ed JOIN events e ON e.id = ed.event_id WHERE ed.id = ?`, eventDateID).Scan(&capacity, &total)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil || !capacity.Valid {
+		return 0, false, err
+	}
+	remaining = int(capacity.Int64) - total
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true, nil
+}
+
+// EventDateOverCapacity returns the first date of an event whose lots add up
+// to more than capacity, with that total ("" when every date fits).
+func EventDateOverCapacity(db *sql.DB, eventID string, capacity int) (date string, total int, err error) {
+	err = db.QueryRow(`SELECT ed.date, SUM(l.total_quantity) AS total
+		FROM event_dates ed JOIN lots l ON l.event_date_id = ed.id
+		WHERE ed.event_id = ?
+		GROUP BY ed.id HAVING total > ?
+		ORDER BY ed.date LIMIT 1`, eventID, capacity).Scan(&date, &total)
+	if err == sql.ErrNoRows {
+		return "", 0, nil
+	}
+	return date, total, err
+}
+
 func ListPublishedEvents(db *sql.DB, category, date, city *string) ([]string, error) {
 	q := `SELECT id FROM events WHERE status = 'PUBLISHED'`
 	args := []interface{}{}
@@ -81,8 +263,8 @@ func ListPublishedEvents(db *sql.DB, category, date, city *string) ([]string, er
 
 func EventByID(db *sql.DB, id string) (*EventRow, error) {
 	var e EventRow
-	err := db.QueryRow(`SELECT id, producer_id, title, description, category, cover_image, location, address, status, featured FROM events WHERE id = ?`, id).Scan(
-		&e.ID, &e.ProducerID, &e.Title, &e.Description, &e.Category, &e.CoverImage, &e.Location, &e.Address, &e.Status, &e.Featured,
+	err := db.QueryRow(`SELECT id, producer_id, title, description, category, cover_image, location, address, status, featured, timezone FROM events WHERE id = ?`, id).Scan(
+		&e.ID, &e.ProducerID, &e.Title, &e.Description, &e.Category, &e.CoverImage, &e.Location, &e.Address, &e.Status, &e.Featured, &e.Timezone,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -104,6 +286,7 @@ type EventRow struct {
 	Address     sql.NullString
 	Status      string
 	Featured    int
+	Timezone    sql.NullString // IANA zone; NULL uses the configured default
 }
 
 func EventDateIDsByEvent(db *sql.DB, eventID string) ([]string, error) {
@@ -213,12 +396,13 @@ type TicketTypeRow struct {
 	Audience     string
 	MaxQuantity  int
 	SoldQuantity int
+	Admits       int // people let in per ticket; lot capacity is consumed in admits
 }
 
 func TicketTypeByID(db *sql.DB, id string) (*TicketTypeRow, error) {
 	var t TicketTypeRow
-	err := db.QueryRow(`SELECT id, lot_id, name, description, price, audience, max_quantity, sold_quantity FROM ticket_types WHERE id = ?`, id).Scan(
-		&t.ID, &t.LotID, &t.Name, &t.Description, &t.Price, &t.Audience, &t.MaxQuantity, &t.SoldQuantity,
+	err := db.QueryRow(`SELECT id, lot_id, name, description, price, audience, max_quantity, sold_quantity, admits FROM ticket_types WHERE id = ?`, id).Scan(
+		&t.ID, &t.LotID, &t.Name, &t.Description, &t.Price, &t.Audience, &t.MaxQuantity, &t.SoldQuantity, &t.Admits,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -239,16 +423,23 @@ func ProducerIDByUser(db *sql.DB, userID string) (string, error) {
 }
 
 type ProducerRow struct {
-	ID          string
-	UserID      string
-	CompanyName sql.NullString
-	Approved    int
+	ID           string
+	UserID       string
+	CompanyName  sql.NullString
+	Approved     int
+	DisplayName  sql.NullString
+	Description  sql.NullString
+	LogoURL      sql.NullString
+	ContactEmail sql.NullString
+	// StatementDescriptor overrides the platform's descriptor on this
+	// producer's charges (NULL = default).
+	StatementDescriptor sql.NullString
 }
 
 func ProducerByID(db *sql.DB, id string) (*ProducerRow, error) {
 	var p ProducerRow
-	err := db.QueryRow(`SELECT id, user_id, company_name, approved FROM producers WHERE id = ?`, id).Scan(
-		&p.ID, &p.UserID, &p.CompanyName, &p.Approved,
+	err := db.QueryRow(`SELECT id, user_id, company_name, approved, display_name, description, logo_url, contact_email, statement_descriptor FROM producers WHERE id = ?`, id).Scan(
+		&p.ID, &p.UserID, &p.CompanyName, &p.Approved, &p.DisplayName, &p.Description, &p.LogoURL, &p.ContactEmail, &p.StatementDescriptor,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -259,20 +450,59 @@ func ProducerByID(db *sql.DB, id string) (*ProducerRow, error) {
 	return &p, nil
 }
 
+// ProducerProfile holds the producer's public profile fields for
+// UpdateProducerProfile. A nil field is left as is; "" clears it.
+type ProducerProfile struct {
+	DisplayName  *string
+	Description  *string
+	LogoURL      *string
+	ContactEmail *string
+	// StatementDescriptor is stored as given; callers sanitize it.
+	StatementDescriptor *string
+}
+
+// UpdateProducerProfile updates the producer's public profile fields.
+func UpdateProducerProfile(db *sql.DB, producerID string, p ProducerProfile) error {
+	var sets []string
+	var args []interface{}
+	for _, f := range []struct {
+		column string
+		value  *string
+	}{
+		{"display_name", p.DisplayName},
+		{"description", p.Description},
+		{"logo_url", p.LogoURL},
+		{"contact_email", p.ContactEmail},
+		{"statement_descriptor", p.StatementDescriptor},
+	} {
+		if f.value != nil {
+			sets = append(sets, f.column+" = ?")
+			args = append(args, nullIfEmpty(*f.value))
+		}
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	_, err := db.Exec(`UPDATE producers SET `+strings.Join(sets, ", ")+` WHERE id = ?`, append(args, producerID)...)
+	return err
+}
+
 func CreateProducer(db *sql.DB, userID string) (string, error) {
 	id := uuid.New().String()
-	_, err := db.Exec(`INSERT INTO producers (id, user_id, approved) VALUES (?, ?, 1)`, id, userID)
+	_, err := db.Exec(`INSERT INTO producers (id, user_id, approved, created_at) VALUES (?, ?, 1, `+nowSQL+`)`, id, userID)
 	return id, err
 }
 
-func CreateEvent(db *sql.DB, producerID, title, description, category, coverImage, location string, address *string) (string, error) {
+// CreateEvent inserts a DRAFT event. timezone is its IANA zone ("" for
+// DEFAULT_TIMEZONE); callers validate the name.
+func CreateEvent(db *sql.DB, producerID, title, description, category, coverImage, location string, address *string, timezone string) (string, error) {
 	id := uuid.New().String()
 	var addr sql.NullString
 	if address != nil {
 		addr = sql.NullString{String: *address, Valid: true}
 	}
-	_, err := db.Exec(`INSERT INTO events (id, producer_id, title, description, category, cover_image, location, address, status) VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'DRAFT')`,
-		id, producerID, title, description, category, coverImage, location, addr,
+	_, err := db.Exec(`INSERT INTO events (id, producer_id, title, description, category, cover_image, location, address, timezone, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 'DRAFT', `+nowSQL+`, `+nowSQL+`)`,
+		id, producerID, title, description, category, coverImage, location, addr, nullIfEmpty(timezone),
 	)
 	return id, err
 }
@@ -286,7 +516,7 @@ func CreateEventDate(db *sql.DB, eventID, date string, startTime, endTime *strin
 	if endTime != nil {
 		et = sql.NullString{String: *endTime, Valid: true}
 	}
-	_, err := db.Exec(`INSERT INTO event_dates (id, event_id, date, start_time, end_time) VALUES (?, ?, ?, ?, ?)`,
+	_, err := db.Exec(`INSERT INTO event_dates (id, event_id, date, start_time, end_time, created_at) VALUES (?, ?, ?, ?, ?, `+nowSQL+`)`,
 		id, eventID, date, st, et,
 	)
 	return id, err
@@ -294,26 +524,26 @@ func CreateEventDate(db *sql.DB, eventID, date string, startTime, endTime *strin
 
 func CreateLot(db *sql.DB, eventDateID, name, startsAt, endsAt string, totalQuantity int) (string, error) {
 	id := uuid.New().String()
-	_, err := db.Exec(`INSERT INTO lots (id, event_date_id, name, starts_at, ends_at, total_quantity, available_quantity, active) VALUES (?, ?, ?, ?, ?, ?, ?, 1)`,
+	_, err := db.Exec(`INSERT INTO lots (id, event_date_id, name, starts_at, ends_at, total_quantity, available_quantity, active, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, 1, `+nowSQL+`)`,
 		id, eventDateID, name, startsAt, endsAt, totalQuantity, totalQuantity,
 	)
 	return id, err
 }
 
-func CreateTicketType(db *sql.DB, lotID, name string, description *string, price float64, audience string, maxQuantity int) (string, error) {
+func CreateTicketType(db *sql.DB, lotID, name string, description *string, price float64, audience string, maxQuantity, admits int) (string, error) {
 	id := uuid.New().String()
 	var desc sql.NullString
 	if description != nil {
 		desc = sql.NullString{String: *description, Valid: true}
 	}
-	_, err := db.Exec(`INSERT INTO ticket_types (id, lot_id, name, description, price, audience, max_quantity, sold_quantity) VALUES (?, ?, ?, ?, ?, ?, ?, 0)`,
-		id, lotID, name, desc, price, audience, maxQuantity,
+	_, err := db.Exec(`INSERT INTO ticket_types (id, lot_id, name, description, price, audience, max_quantity, sold_quantity, admits, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?, `+nowSQL+`)`,
+		id, lotID, name, desc, price, audience, maxQuantity, admits,
 	)
 	return id, err
 }
 
 func UpdateEventStatus(db *sql.DB, eventID, status string) error {
-	_, err := db.Exec(`UPDATE events SET status = ?, updated_at = datetime('now') WHERE id = ?`, status, eventID)
+	_, err := db.Exec(`UPDATE events SET status = ?, updated_at = `+nowSQL+` WHERE id = ?`, status, eventID)
 	return err
 }
 
@@ -321,7 +551,7 @@ func UpdateEvent(db *sql.DB, eventID string, title, description, category, cover
 	if title == nil && description == nil && category == nil && coverImage == nil && location == nil && address == nil && featured == nil {
 		return nil
 	}
-	q := `UPDATE events SET updated_at = datetime('now')`
+	q := `UPDATE events SET updated_at = ` + nowSQL
 	args := []interface{}{}
 	if title != nil {
 		q += `, title = ?`
@@ -366,8 +596,8 @@ func UpdateEvent(db *sql.DB, eventID string, title, description, category, cover
 // EventByIDTx retrieves an event within a transaction.
 func EventByIDTx(tx *sql.Tx, id string) (*EventRow, error) {
 	var e EventRow
-	err := tx.QueryRow(`SELECT id, producer_id, title, description, category, cover_image, location, address, status, featured FROM events WHERE id = ?`, id).Scan(
-		&e.ID, &e.ProducerID, &e.Title, &e.Description, &e.Category, &e.CoverImage, &e.Location, &e.Address, &e.Status, &e.Featured,
+	err := tx.QueryRow(`SELECT id, producer_id, title, description, category, cover_image, location, address, status, featured, timezone FROM events WHERE id = ?`, id).Scan(
+		&e.ID, &e.ProducerID, &e.Title, &e.Description, &e.Category, &e.CoverImage, &e.Location, &e.Address, &e.Status, &e.Featured, &e.Timezone,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -396,8 +626,8 @@ func EventDateByIDTx(tx *sql.Tx, id string) (*EventDateRow, error) {
 // TicketTypeByIDTx retrieves a ticket type within a transaction.
 func TicketTypeByIDTx(tx *sql.Tx, id string) (*TicketTypeRow, error) {
 	var t TicketTypeRow
-	err := tx.QueryRow(`SELECT id, lot_id, name, description, price, audience, max_quantity, sold_quantity FROM ticket_types WHERE id = ?`, id).Scan(
-		&t.ID, &t.LotID, &t.Name, &t.Description, &t.Price, &t.Audience, &t.MaxQuantity, &t.SoldQuantity,
+	err := tx.QueryRow(`SELECT id, lot_id, name, description, price, audience, max_quantity, sold_quantity, admits FROM ticket_types WHERE id = ?`, id).Scan(
+		&t.ID, &t.LotID, &t.Name, &t.Description, &t.Price, &t.Audience, &t.MaxQuantity, &t.SoldQuantity, &t.Admits,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
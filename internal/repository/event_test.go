@@ -0,0 +1,130 @@
+package repository_test
+
+import (
+	"testing"
+
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestEventsByProducer(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	orderID := f.PendingOrder(t, sqlite, 2)
+	if _, err := sqlite.Exec(`UPDATE orders SET status = 'PAID' WHERE id = ?`, orderID); err != nil {
+		t.Fatal(err)
+	}
+	repository.UpdateEventStatus(sqlite, f.EventID, "PUBLISHED")
+	draftID, _ := repository.CreateEvent(sqlite, f.ProducerID, "Rascunho", "desc", "shows", "cover", "Local", nil, "")
+
+	rows, total, err := repository.EventsByProducer(sqlite, f.ProducerID, true, "", 20, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(rows) != 2 {
+		t.Fatalf("with drafts: total = %d, rows = %d, want 2", total, len(rows))
+	}
+	for _, r := range rows {
+		if r.ID == f.EventID && r.Revenue != 100 {
+			t.Errorf("revenue = %v, want 100", r.Revenue)
+		}
+	}
+
+	rows, total, _ = repository.EventsByProducer(sqlite, f.ProducerID, false, "", 20, 0)
+	if total != 1 || rows[0].ID != f.EventID {
+		t.Errorf("without drafts: total = %d, want only the published event", total)
+	}
+	rows, total, _ = repository.EventsByProducer(sqlite, f.ProducerID, true, "DRAFT", 20, 0)
+	if total != 1 || rows[0].ID != draftID {
+		t.Errorf("status DRAFT: total = %d, want only the draft", total)
+	}
+	rows, total, _ = repository.EventsByProducer(sqlite, f.ProducerID, true, "", 1, 1)
+	if total != 2 || len(rows) != 1 {
+		t.Errorf("second page: total = %d, rows = %d, want 2 and 1", total, len(rows))
+	}
+	if _, total, _ := repository.EventsByProducer(sqlite, "outro-produtor", true, "", 20, 0); total != 0 {
+		t.Errorf("other producer sees %d events", total)
+	}
+}
+
+func TestUpdateProducerProfile(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+
+	name, desc, email := "Casa de Shows", "Shows toda sexta", "contato@casa.com"
+	if err := repository.UpdateProducerProfile(sqlite, f.ProducerID, repository.ProducerProfile{
+		DisplayName: &name, Description: &desc, ContactEmail: &email,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Omitted fields are kept; "" clears
+	empty := ""
+	if err := repository.UpdateProducerProfile(sqlite, f.ProducerID, repository.ProducerProfile{Description: &empty}); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := repository.ProducerByID(sqlite, f.ProducerID)
+	if p.DisplayName.String != name || p.ContactEmail.String != email {
+		t.Errorf("profile = %+v, want name and email kept", p)
+	}
+	if p.Description.Valid || p.LogoURL.Valid {
+		t.Errorf("description = %v, logo = %v, want NULL", p.Description, p.LogoURL)
+	}
+}
+
+func TestEventCapacityAcrossLots(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 600, 100)
+	f.PendingOrder(t, sqlite, 1)
+	ed, _ := repository.EventDateByID(sqlite, f.EventDateID)
+
+	if _, limited, _ := repository.EventRemainingCapacity(sqlite, ed.ID); limited {
+		t.Errorf("event without capacity reported as limited")
+	}
+
+	if err := repository.SetEventCapacity(sqlite, ed.EventID, 1000); err != nil {
+		t.Fatalf("set capacity: %v", err)
+	}
+	if remaining, limited, _ := repository.EventRemainingCapacity(sqlite, ed.ID); !limited || remaining != 400 {
+		t.Errorf("remaining = %d (limited %v), want 400", remaining, limited)
+	}
+
+	// A second lot of 500 oversubscribes the 1000-place venue
+	if _, err := repository.CreateLot(sqlite, ed.ID, "Lote 2", "2000-01-01T00:00:00Z", "2099-01-01T00:00:00Z", 500); err != nil {
+		t.Fatalf("create lot: %v", err)
+	}
+	date, total, err := repository.EventDateOverCapacity(sqlite, ed.EventID, 1000)
+	if err != nil || date != ed.Date || total != 1100 {
+		t.Errorf("over capacity = %q/%d (%v), want %s/1100", date, total, err, ed.Date)
+	}
+	if date, _, _ := repository.EventDateOverCapacity(sqlite, ed.EventID, 1100); date != "" {
+		t.Errorf("capacity 1100 reported %s as oversubscribed", date)
+	}
+}
+
+func TestUpdateTicketTypeGuards(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	if _, err := sqlite.Exec(`UPDATE ticket_types SET sold_quantity = 4 WHERE id = ?`, f.TicketTypeID); err != nil {
+		t.Fatal(err)
+	}
+
+	zero, below := 0.0, 3
+	if _, err := repository.UpdateTicketType(sqlite, f.TicketTypeID, nil, &zero, nil, ""); err != repository.ErrTicketTypeInvalidPrice {
+		t.Errorf("price 0: err = %v, want ErrTicketTypeInvalidPrice", err)
+	}
+	if _, err := repository.UpdateTicketType(sqlite, f.TicketTypeID, nil, nil, &below, ""); err != repository.ErrTicketTypeBelowSold {
+		t.Errorf("max below sold: err = %v, want ErrTicketTypeBelowSold", err)
+	}
+
+	price, max := 80.0, 4
+	updated, err := repository.UpdateTicketType(sqlite, f.TicketTypeID, nil, &price, &max, "")
+	if err != nil {
+		t.Fatalf("UpdateTicketType: %v", err)
+	}
+	if updated.Price != 80 || updated.MaxQuantity != 4 {
+		t.Errorf("updated = %+v", updated)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM audit_log WHERE action = 'ticket_type.update' AND entity_id = ?`, f.TicketTypeID); got != 1 {
+		t.Errorf("audit rows = %d, want 1", got)
+	}
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Inventory invariant kinds reported by VerifyInventoryInvariants.
+const (
+	InventoryLotOversold        = "lot_oversold"         // sold + available > total
+	InventoryTicketCountDrift   = "ticket_count_drift"   // issued tickets != sold_quantity
+	InventoryTicketTypeOversold = "ticket_type_oversold" // sold_quantity > max_quantity
+)
+
+// InventoryViolation is one inconsistency found in lots/ticket_types.
+// TicketTypeID is empty for lot-level violations.
+type InventoryViolation struct {
+	Kind         string `json:"kind"`
+	EventID      string `json:"eventId"`
+	LotID        string `json:"lotId"`
+	TicketTypeID string `json:"ticketTypeId,omitempty"`
+	Expected     int    `json:"expected"`
+	Actual       int    `json:"actual"`
+	Detail       string `json:"detail"`
+}
+
+// VerifyInventoryInvariants checks that, per lot, the places sold across its
+// ticket types (units times admits) plus what is still available never exceed
+// total_quantity, and, per ticket type, that sold_quantity matches the issued
+// tickets and stays within max_quantity. Read-only; returns every violation found.
+//
+// Both checks run in one transaction, so a sale confirmed mid-check can't make
+// the lot and ticket type figures disagree.
+func VerifyInventoryInvariants(db *sql.DB) ([]InventoryViolation, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var out []InventoryViolation
+	lots, err := tx.Query(`
+		SELECT l.id, ed.event_id, l.total_quantity, l.available_quantity, COALESCE(SUM(tt.sold_quantity * tt.admits), 0)
+		FROM lots l
+		JOIN event_dates ed ON ed.id = l.event_date_id
+		LEFT JOIN ticket_types tt ON tt.lot_id = l.id
+		GROUP BY l.id
+		HAVING COALESCE(SUM(tt.sold_quantity * tt.admits), 0) + l.available_quantity > l.total_quantity`)
+	if err != nil {
+		return nil, err
+	}
+	for lots.Next() {
+		var v InventoryViolation
+		var total, available, sold int
+		if err := lots.Scan(&v.LotID, &v.EventID, &total, &available, &sold); err != nil {
+			lots.Close()
+			return nil, err
+		}
+		v.Kind = InventoryLotOversold
+		v.Expected, v.Actual = total, sold+available
+		v.Detail = fmt.Sprintf("vendidos (%d) + disponíveis (%d) > total (%d)", sold, available, total)
+		out = append(out, v)
+	}
+	lots.Close()
+	if err := lots.Err(); err != nil {
+		return nil, err
+	}
+
+	types, err := tx.Query(`
+		SELECT tt.id, tt.lot_id, ed.event_id, tt.sold_quantity, tt.max_quantity,
+		       (SELECT COUNT(*) FROM tickets t WHERE t.ticket_type_id = tt.id)
+		FROM ticket_types tt
+		JOIN lots l ON l.id = tt.lot_id
+		JOIN event_dates ed ON ed.id = l.event_date_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer types.Close()
+	for types.Next() {
+		var ttID, lotID, eventID string
+		var sold, max, issued int
+		if err := types.Scan(&ttID, &lotID, &eventID, &sold, &max, &issued); err != nil {
+			return nil, err
+		}
+		if issued != sold {
+			out = append(out, InventoryViolation{
+				Kind: InventoryTicketCountDrift, EventID: eventID, LotID: lotID, TicketTypeID: ttID,
+				Expected: sold, Actual: issued,
+				Detail: fmt.Sprintf("%d ingresso(s) emitido(s), sold_quantity = %d", issued, sold),
+			})
+		}
+		if sold > max {
+			out = append(out, InventoryViolation{
+				Kind: InventoryTicketTypeOversold, EventID: eventID, LotID: lotID, TicketTypeID: ttID,
+				Expected: max, Actual: sold,
+				Detail: fmt.Sprintf("sold_quantity (%d) > max_quantity (%d)", sold, max),
+			})
+		}
+	}
+	return out, types.Err()
+}
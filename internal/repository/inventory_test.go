@@ -0,0 +1,39 @@
+package repository_test
+
+import (
+	"testing"
+
+	"afterzin/api/internal/qrcode"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestVerifyInventoryInvariants(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	f.PaidOrder(t, sqlite, 3, qrcode.NewKeyRing("test-secret"))
+
+	violations, err := repository.VerifyInventoryInvariants(sqlite)
+	if err != nil || len(violations) != 0 {
+		t.Fatalf("after a clean sale: violations = %+v, err = %v", violations, err)
+	}
+
+	// Simulate a decrement path that forgot the lot, and one that forgot a ticket
+	sqlite.Exec(`UPDATE lots SET available_quantity = available_quantity + 1 WHERE id = ?`, f.LotID)
+	sqlite.Exec(`UPDATE ticket_types SET sold_quantity = sold_quantity + 1 WHERE id = ?`, f.TicketTypeID)
+
+	violations, err = repository.VerifyInventoryInvariants(sqlite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[string]repository.InventoryViolation{}
+	for _, v := range violations {
+		kinds[v.Kind] = v
+	}
+	if v, ok := kinds[repository.InventoryLotOversold]; !ok || v.LotID != f.LotID || v.Expected != 10 || v.Actual != 12 {
+		t.Errorf("lot violation = %+v", v)
+	}
+	if v, ok := kinds[repository.InventoryTicketCountDrift]; !ok || v.TicketTypeID != f.TicketTypeID || v.Expected != 4 || v.Actual != 3 {
+		t.Errorf("ticket drift violation = %+v", v)
+	}
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+// Notification types
+const (
+	NotificationTicketPurchased = "ticket_purchased"
+	NotificationRecipientStatus = "recipient_status"
+	NotificationTicketReceived  = "ticket_received"
+)
+
+// NotificationRow is an in-app notification. Payload is a JSON object.
+type NotificationRow struct {
+	ID        string
+	UserID    string
+	Type      string
+	Payload   string
+	ReadAt    sql.NullString
+	CreatedAt string
+}
+
+const notificationColumns = `id, user_id, type, payload, read_at, created_at`
+
+func scanNotification(s interface{ Scan(...interface{}) error }) (*NotificationRow, error) {
+	var n NotificationRow
+	if err := s.Scan(&n.ID, &n.UserID, &n.Type, &n.Payload, &n.ReadAt, &n.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// CreateNotification stores a notification for userID and returns its id.
+func CreateNotification(db *sql.DB, userID, typ, payload string) (string, error) {
+	if payload == "" {
+		payload = "{}"
+	}
+	id := uuid.New().String()
+	_, err := db.Exec(`INSERT INTO notifications (id, user_id, type, payload, created_at) VALUES (?, ?, ?, ?, `+nowSQL+`)`, id, userID, typ, payload)
+	return id, err
+}
+
+// CreateNotificationWithID stores a notification under a given id, doing
+// nothing if it already exists, so a retried delivery doesn't duplicate it.
+func CreateNotificationWithID(db *sql.DB, id, userID, typ, payload string) error {
+	if payload == "" {
+		payload = "{}"
+	}
+	_, err := db.Exec(`INSERT INTO notifications (id, user_id, type, payload, created_at) VALUES (?, ?, ?, ?, `+nowSQL+`) ON CONFLICT (id) DO NOTHING`, id, userID, typ, payload)
+	return err
+}
+
+// NotificationsByUser returns the user's most recent notifications, newest first.
+func NotificationsByUser(db *sql.DB, userID string, unreadOnly bool, limit int) ([]*NotificationRow, error) {
+	q := `SELECT ` + notificationColumns + ` FROM notifications WHERE user_id = ?`
+	if unreadOnly {
+		q += ` AND read_at IS NULL`
+	}
+	q += ` ORDER BY created_at DESC, rowid DESC LIMIT ?`
+	rows, err := db.Query(q, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*NotificationRow
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, n)
+	}
+	return list, rows.Err()
+}
+
+// MarkNotificationRead marks the user's notification as read (keeping the
+// first read_at) and returns it; nil if it doesn't exist or belongs to someone else.
+func MarkNotificationRead(db *sql.DB, id, userID string) (*NotificationRow, error) {
+	res, err := db.Exec(`UPDATE notifications SET read_at = COALESCE(read_at, `+nowSQL+`) WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, nil
+	}
+	n, err := scanNotification(db.QueryRow(`SELECT `+notificationColumns+` FROM notifications WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return n, err
+}
@@ -2,46 +2,400 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 
 	"afterzin/api/internal/logger"
+	"afterzin/api/internal/money"
 
 	"github.com/google/uuid"
 )
 
+// OrderRefPrefix is the prefix of human-friendly order references (ORDER_REF_PREFIX).
+var OrderRefPrefix = "AFZ"
+
+// nextOrderRefTx draws the next reference for the current year. The counter
+// upsert is a single statement, so concurrent orders never share a number.
+func nextOrderRefTx(tx *sql.Tx, now time.Time) (string, error) {
+	year := now.UTC().Year()
+	var n int
+	err := tx.QueryRow(`INSERT INTO order_ref_counters (year, last_value) VALUES (?, 1)
+		ON CONFLICT(year) DO UPDATE SET last_value = last_value + 1
+		RETURNING last_value`, year).Scan(&n)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%d-%06d", OrderRefPrefix, year, n), nil
+}
+
 func CreateOrder(db *sql.DB, userID string, total float64, exp time.Duration) (string, error) {
+	return CreateOrderWithItems(db, userID, total, exp, nil, OrderLimits{})
+}
+
+// NewOrderItem is a line of an order being created.
+type NewOrderItem struct {
+	EventDateID  string
+	TicketTypeID string
+	Quantity     int
+	UnitPrice    float64
+}
+
+// OrderLimits caps the unexpired PENDING orders CreateOrderWithItems lets a
+// buyer hold: MaxPending per user and, for guest checkouts, MaxPendingPerIP
+// per ClientIP (recorded on the order). Zero disables a cap.
+type OrderLimits struct {
+	MaxPending      int
+	ClientIP        string
+	MaxPendingPerIP int
+}
+
+// CreateOrderWithItems creates a PENDING order and its items in one
+// transaction, so a failure never leaves an order without items. The
+// pending orders are counted in that transaction, after the order reference
+// counter is bumped, so the write lock is held: concurrent checkouts can't
+// both pass the limits. The items' lot places are reserved in it too (see
+// reservation.go). Returns ErrPendingOrderLimit or ErrClientOrderLimit when a
+// cap is reached, ErrInsufficientInventory when a lot is short.
+func CreateOrderWithItems(db *sql.DB, userID string, total float64, exp time.Duration, items []NewOrderItem, limits OrderLimits) (string, error) {
 	id := uuid.New().String()
-	expAt := time.Now().Add(exp).UTC().Format(time.RFC3339)
-	logger.Debugf("criando pedido: id=%s usuario=%s total=%.2f expAt=%s", id, userID, total, expAt)
-	_, err := db.Exec(`INSERT INTO orders (id, user_id, status, total, expires_at) VALUES (?, ?, 'PENDING', ?, ?)`, id, userID, total, expAt)
+	now := time.Now()
+	expAt := FormatTime(now.Add(exp))
+	logger.Debugf("criando pedido: id=%s usuario=%s total=%s expAt=%s itens=%d", id, userID, money.FormatReais(total), expAt, len(items))
+
+	tx, err := db.Begin()
 	if err != nil {
 		logger.Errorf("erro ao criar pedido: %v", err)
-	} else {
-		logger.Infof("pedido criado com sucesso: id=%s", id)
+		return "", err
+	}
+	defer tx.Rollback()
+	ref, err := nextOrderRefTx(tx, now)
+	if err == nil {
+		err = checkOrderLimitsTx(tx, userID, limits)
+	}
+	if err == nil {
+		_, err = tx.Exec(`INSERT INTO orders (id, user_id, status, total, expires_at, order_ref, client_ip, created_at) VALUES (?, ?, 'PENDING', ?, ?, ?, ?, `+nowSQL+`)`,
+			id, userID, total, expAt, ref, nullIfEmpty(limits.ClientIP))
+	}
+	for _, it := range items {
+		if err != nil {
+			break
+		}
+		_, err = tx.Exec(`INSERT INTO order_items (id, order_id, event_date_id, ticket_type_id, quantity, unit_price, created_at) VALUES (?, ?, ?, ?, ?, ?, `+nowSQL+`)`,
+			uuid.New().String(), id, it.EventDateID, it.TicketTypeID, it.Quantity, it.UnitPrice)
+	}
+	if err == nil && len(items) > 0 {
+		err = reserveOrderInventoryTx(tx, id)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	switch {
+	case errors.Is(err, ErrPendingOrderLimit), errors.Is(err, ErrClientOrderLimit), errors.Is(err, ErrInsufficientInventory):
+		logger.Warnf("pedido recusado: usuario=%s: %v", userID, err)
+		return "", err
+	case err != nil:
+		logger.Errorf("erro ao criar pedido: %v", err)
+	default:
+		logger.Infof("pedido criado com sucesso: id=%s ref=%s", id, ref)
+	}
+	return id, err
+}
+
+// checkOrderLimitsTx returns ErrPendingOrderLimit or ErrClientOrderLimit
+// when a new order would exceed limits.
+func checkOrderLimitsTx(tx *sql.Tx, userID string, limits OrderLimits) error {
+	var n int
+	if limits.MaxPending > 0 {
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM orders WHERE `+pendingOrdersWhere, userID).Scan(&n); err != nil {
+			return err
+		}
+		if n >= limits.MaxPending {
+			return ErrPendingOrderLimit
+		}
+	}
+	if limits.MaxPendingPerIP > 0 && limits.ClientIP != "" {
+		err := tx.QueryRow(`SELECT COUNT(*) FROM orders WHERE client_ip = ? AND status = 'PENDING'
+			AND (expires_at IS NULL OR expires_at > `+nowSQL+`)`, limits.ClientIP).Scan(&n)
+		if err != nil {
+			return err
+		}
+		if n >= limits.MaxPendingPerIP {
+			return ErrClientOrderLimit
+		}
+	}
+	return nil
+}
+
+// UpdateOrderItems replaces the items of a PENDING order and sets its total
+// to their sum, in one transaction, so a failure leaves the order as it was.
+// Orders whose payment has started (ClaimOrderPayment) or that have a
+// Pagar.me order are refused with ErrOrderHasPayment, other states with
+// ErrOrderNotEditable. A reserved order gives back the places of its old
+// items and reserves the new ones, or fails with ErrInsufficientInventory.
+// Returns the new total.
+func UpdateOrderItems(db *sql.DB, orderID string, items []NewOrderItem) (float64, error) {
+	var total float64
+	for _, it := range items {
+		total += float64(it.Quantity) * it.UnitPrice
+	}
+	logger.Debugf("alterando itens do pedido: id=%s total=%s itens=%d", orderID, money.FormatReais(total), len(items))
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(`UPDATE orders SET total = ? WHERE id = ? AND status = 'PENDING'
+		AND payment_started_at IS NULL AND COALESCE(pagarme_order_id, '') = ''`, total, orderID)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var status string
+		var pagarmeOrderID, paymentStartedAt sql.NullString
+		err := tx.QueryRow(`SELECT status, pagarme_order_id, payment_started_at FROM orders WHERE id = ?`, orderID).Scan(&status, &pagarmeOrderID, &paymentStartedAt)
+		switch {
+		case err == sql.ErrNoRows:
+			return 0, ErrOrderNotFound
+		case err != nil:
+			return 0, err
+		case status == "PENDING" && (pagarmeOrderID.String != "" || paymentStartedAt.Valid):
+			return 0, ErrOrderHasPayment
+		}
+		return 0, ErrOrderNotEditable
+	}
+	reserved, err := releaseOrderInventoryTx(tx, orderID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM order_items WHERE order_id = ?`, orderID); err != nil {
+		return 0, err
+	}
+	for _, it := range items {
+		if _, err := tx.Exec(`INSERT INTO order_items (id, order_id, event_date_id, ticket_type_id, quantity, unit_price, created_at) VALUES (?, ?, ?, ?, ?, ?, `+nowSQL+`)`,
+			uuid.New().String(), orderID, it.EventDateID, it.TicketTypeID, it.Quantity, it.UnitPrice); err != nil {
+			return 0, err
+		}
+	}
+	if reserved {
+		if err := reserveOrderInventoryTx(tx, orderID); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	logger.Infof("itens do pedido alterados: id=%s total=%s", orderID, money.FormatReais(total))
+	return total, nil
+}
+
+// CancelPendingOrder cancels an order that is still PENDING, recording why
+// in its status history and giving back the lot places it reserved. Returns
+// false if the order wasn't PENDING.
+func CancelPendingOrder(db *sql.DB, orderID, reason, errorMessage string) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(`UPDATE orders SET status = 'CANCELLED' WHERE id = ? AND status = 'PENDING'`, orderID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if _, err := releaseOrderInventoryTx(tx, orderID); err != nil {
+		return false, err
+	}
+	if err := RecordOrderStatusChangeWithError(tx, orderID, "PENDING", "CANCELLED", reason, errorMessage); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// OrderRefByID returns the human-friendly reference of an order ("" if unknown).
+func OrderRefByID(db *sql.DB, orderID string) (string, error) {
+	return orderRefByID(db, orderID)
+}
+
+// OrderRefByIDTx is OrderRefByID within a transaction.
+func OrderRefByIDTx(tx *sql.Tx, orderID string) (string, error) {
+	return orderRefByID(tx, orderID)
+}
+
+func orderRefByID(q queryRower, orderID string) (string, error) {
+	var ref sql.NullString
+	err := q.QueryRow(`SELECT order_ref FROM orders WHERE id = ?`, orderID).Scan(&ref)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return ref.String, err
+}
+
+// OrderIDByRef resolves an order reference to the internal order ID ("" if unknown).
+func OrderIDByRef(db *sql.DB, ref string) (string, error) {
+	var id string
+	err := db.QueryRow(`SELECT id FROM orders WHERE order_ref = ?`, ref).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
 	}
 	return id, err
 }
 
+// OrderRow is an order with its reference and Pagar.me identifiers.
+type OrderRow struct {
+	ID              string
+	OrderRef        string
+	UserID          string
+	Status          string
+	Total           float64
+	PagarmeOrderID  string
+	PagarmeChargeID string
+	PagarmeEnv      string
+	CreatedAt       string
+	ExpiresAt       string
+	PixQRCode       string
+	PixQRCodeURL    string
+	PixExpiresAt    string
+}
+
+const orderRowColumns = `id, order_ref, user_id, status, total, pagarme_order_id, pagarme_charge_id, pagarme_env, created_at, expires_at,
+	pix_qr_code, pix_qr_code_url, pix_expires_at`
+
+// pendingOrdersWhere selects a user's PENDING orders that haven't expired yet.
+const pendingOrdersWhere = `user_id = ? AND status = 'PENDING'
+	AND (expires_at IS NULL OR expires_at > ` + nowSQL + `)`
+
+// CountPendingOrders counts the user's unexpired PENDING orders.
+func CountPendingOrders(db *sql.DB, userID string) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM orders WHERE `+pendingOrdersWhere, userID).Scan(&n)
+	return n, err
+}
+
+// PendingOrderRefs lists the references of the user's unexpired PENDING
+// orders, oldest first. Orders without a reference are listed by id.
+func PendingOrderRefs(db *sql.DB, userID string) ([]string, error) {
+	rows, err := db.Query(`SELECT COALESCE(order_ref, id) FROM orders WHERE `+pendingOrdersWhere+` ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var refs []string
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+func scanOrderRow(row *sql.Row) (*OrderRow, error) {
+	var o OrderRow
+	var ref, pgOrderID, chargeID, env, expiresAt, pixQRCode, pixQRCodeURL, pixExpiresAt sql.NullString
+	err := row.Scan(&o.ID, &ref, &o.UserID, &o.Status, &o.Total, &pgOrderID, &chargeID, &env, &o.CreatedAt, &expiresAt,
+		&pixQRCode, &pixQRCodeURL, &pixExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	o.OrderRef, o.PagarmeOrderID, o.PagarmeChargeID, o.PagarmeEnv, o.ExpiresAt = ref.String, pgOrderID.String, chargeID.String, env.String, expiresAt.String
+	o.PixQRCode, o.PixQRCodeURL, o.PixExpiresAt = pixQRCode.String, pixQRCodeURL.String, pixExpiresAt.String
+	return &o, nil
+}
+
+// OrderRowByID returns the full order row (nil if unknown).
+func OrderRowByID(db *sql.DB, id string) (*OrderRow, error) {
+	return scanOrderRow(db.QueryRow(`SELECT `+orderRowColumns+` FROM orders WHERE id = ?`, id))
+}
+
+// AuthorizeOrderAccess loads an order on behalf of userID. Returns
+// ErrOrderNotFound or ErrOrderForbidden so no caller can skip the ownership
+// check. Status checks stay with callers.
+func AuthorizeOrderAccess(db *sql.DB, orderID, userID string) (*OrderRow, error) {
+	if orderID == "" {
+		return nil, ErrOrderNotFound
+	}
+	order, err := OrderRowByID(db, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+	if userID == "" || order.UserID != userID {
+		return nil, ErrOrderForbidden
+	}
+	return order, nil
+}
+
+// OrderByID returns an order's buyer, status and total, or ErrOrderNotFound.
 func OrderByID(db *sql.DB, id string) (userID string, status string, total float64, err error) {
 	logger.Debugf("buscando pedido por id: %s", id)
 	err = db.QueryRow(`SELECT user_id, status, total FROM orders WHERE id = ?`, id).Scan(&userID, &status, &total)
+	if err == sql.ErrNoRows {
+		err = ErrOrderNotFound
+	}
 	if err != nil {
 		logger.Errorf("erro ao buscar pedido %s: %v", id, err)
 	} else {
-		logger.Infof("pedido encontrado: usuario=%s status=%s total=%.2f", userID, status, total)
+		logger.Infof("pedido encontrado: usuario=%s status=%s total=%s", userID, status, money.FormatReais(total))
 	}
 	return
 }
 
+// ConfirmOrder marks a PENDING or PROCESSING order as PAID, stamping
+// confirmed_at. Returns ErrOrderNotFound or ErrOrderNotConfirmable when
+// nothing was updated.
 func ConfirmOrder(db *sql.DB, orderID string) error {
 	logger.Debugf("confirmando pedido: id=%s", orderID)
-	_, err := db.Exec(`UPDATE orders SET status = 'PAID' WHERE id = ? AND status IN ('PENDING','PROCESSING')`, orderID)
+	res, err := db.Exec(`UPDATE orders SET status = 'PAID', confirmed_at = `+nowSQL+` WHERE id = ? AND status IN ('PENDING','PROCESSING')`, orderID)
 	if err != nil {
 		logger.Errorf("erro ao confirmar pedido %s: %v", orderID, err)
-	} else {
-		logger.Infof("pedido confirmado: id=%s", orderID)
+		return err
 	}
-	return err
+	ra, err := res.RowsAffected()
+	if err != nil {
+		logger.Errorf("erro ao obter linhas afetadas ao confirmar pedido %s: %v", orderID, err)
+		return err
+	}
+	if ra != 1 {
+		err := orderStateError(db, orderID, ErrOrderNotConfirmable)
+		logger.Warnf("pedido não confirmado: id=%s: %v", orderID, err)
+		return err
+	}
+	logger.Infof("pedido confirmado: id=%s", orderID)
+	return nil
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// orderStateError explains why a status transition matched no row:
+// ErrOrderNotFound if the order doesn't exist, otherwise wrongState.
+func orderStateError(q queryRower, orderID string, wrongState error) error {
+	var status string
+	err := q.QueryRow(`SELECT status FROM orders WHERE id = ?`, orderID).Scan(&status)
+	switch {
+	case err == sql.ErrNoRows:
+		return ErrOrderNotFound
+	case err != nil:
+		return err
+	}
+	return wrongState
 }
 
 // ClaimOrderProcessing atomically marks an order as PROCESSING if it's currently PENDING.
@@ -64,8 +418,8 @@ func ClaimOrderProcessing(db *sql.DB, orderID string) (bool, error) {
 
 func CreateOrderItem(db *sql.DB, orderID, eventDateID, ticketTypeID string, quantity int, unitPrice float64) (string, error) {
 	id := uuid.New().String()
-	logger.Debugf("criando item do pedido: id=%s pedido=%s dataEvento=%s tipoIngresso=%s quantidade=%d precoUnitario=%.2f", id, orderID, eventDateID, ticketTypeID, quantity, unitPrice)
-	_, err := db.Exec(`INSERT INTO order_items (id, order_id, event_date_id, ticket_type_id, quantity, unit_price) VALUES (?, ?, ?, ?, ?, ?)`,
+	logger.Debugf("criando item do pedido: id=%s pedido=%s dataEvento=%s tipoIngresso=%s quantidade=%d precoUnitario=%s", id, orderID, eventDateID, ticketTypeID, quantity, money.FormatReais(unitPrice))
+	_, err := db.Exec(`INSERT INTO order_items (id, order_id, event_date_id, ticket_type_id, quantity, unit_price, created_at) VALUES (?, ?, ?, ?, ?, ?, `+nowSQL+`)`,
 		id, orderID, eventDateID, ticketTypeID, quantity, unitPrice,
 	)
 	if err != nil {
@@ -106,11 +460,15 @@ type OrderItemRow struct {
 	UnitPrice    float64
 }
 
-func CreateTicket(db *sql.DB, code, qrCode, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID string) (string, error) {
+// ticketTypeAdmits copies the ticket type's admit count onto a new ticket, so
+// one ticket of a multi-admit type (a table, a family pass) covers every entry.
+const ticketTypeAdmits = `COALESCE((SELECT admits FROM ticket_types WHERE id = ?), 1)`
+
+func CreateTicket(db *sql.DB, code, qrCode, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID, source string) (string, error) {
 	id := uuid.New().String()
 	logger.Debugf("criando ingresso: id=%s codigo=%s pedido=%s itemPedido=%s usuario=%s evento=%s dataEvento=%s tipoIngresso=%s", id, code, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID)
-	_, err := db.Exec(`INSERT INTO tickets (id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, used) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0)`,
-		id, code, qrCode, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID,
+	_, err := db.Exec(`INSERT INTO tickets (id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, admits, admits_remaining, used, source, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, `+ticketTypeAdmits+`, `+ticketTypeAdmits+`, 0, ?, `+nowSQL+`)`,
+		id, code, qrCode, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID, ticketTypeID, ticketTypeID, source,
 	)
 	if err != nil {
 		logger.Errorf("erro ao criar ingresso: %v", err)
@@ -121,10 +479,10 @@ func CreateTicket(db *sql.DB, code, qrCode, orderID, orderItemID, userID, eventI
 }
 
 // CreateTicketWithID inserts a ticket with the given id and qr_code (e.g. signed payload). Used when QR is generated from ticket id.
-func CreateTicketWithID(db *sql.DB, id, code, qrCode, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID string) error {
+func CreateTicketWithID(db *sql.DB, id, code, qrCode, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID, source string) error {
 	logger.Debugf("criando ingresso com id fornecido: id=%s codigo=%s pedido=%s itemPedido=%s usuario=%s evento=%s dataEvento=%s tipoIngresso=%s", id, code, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID)
-	_, err := db.Exec(`INSERT INTO tickets (id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, used) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0)`,
-		id, code, qrCode, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID,
+	_, err := db.Exec(`INSERT INTO tickets (id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, admits, admits_remaining, used, source, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, `+ticketTypeAdmits+`, `+ticketTypeAdmits+`, 0, ?, `+nowSQL+`)`,
+		id, code, qrCode, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID, ticketTypeID, ticketTypeID, source,
 	)
 	if err != nil {
 		logger.Errorf("erro ao criar ingresso com id: %v", err)
@@ -145,15 +503,23 @@ func IncrementTicketTypeSold(db *sql.DB, ticketTypeID string, n int) error {
 	return err
 }
 
+// DecrementLotAvailable takes n places from a lot, or returns
+// ErrInsufficientInventory if fewer are left.
 func DecrementLotAvailable(db *sql.DB, lotID string, n int) error {
 	logger.Debugf("decrementando disponível no lote: lote=%s n=%d", lotID, n)
-	_, err := db.Exec(`UPDATE lots SET available_quantity = available_quantity - ? WHERE id = ? AND available_quantity >= ?`, n, lotID, n)
+	res, err := db.Exec(`UPDATE lots SET available_quantity = available_quantity - ? WHERE id = ? AND available_quantity >= ?`, n, lotID, n)
 	if err != nil {
 		logger.Errorf("erro ao decrementar disponível no lote: %v", err)
-	} else {
-		logger.Debugf("lote decrementado: %s", lotID)
+		return err
 	}
-	return err
+	if ra, err := res.RowsAffected(); err != nil {
+		return err
+	} else if ra != 1 {
+		logger.Warnf("quantidade insuficiente no lote: %s", lotID)
+		return ErrInsufficientInventory
+	}
+	logger.Debugf("lote decrementado: %s", lotID)
+	return nil
 }
 
 func LotIDByTicketTypeID(db *sql.DB, ticketTypeID string) (string, error) {
@@ -189,11 +555,13 @@ func ClaimOrderProcessingTx(tx *sql.Tx, orderID string) (bool, error) {
 	return ra == 1, nil
 }
 
-// ConfirmOrderTx confirms an order within a transaction.
-// Only updates if order is in PROCESSING state to ensure proper state transition.
+// ConfirmOrderTx confirms an order within a transaction, stamping
+// confirmed_at in the same statement. Only updates if order is in PROCESSING
+// state to ensure proper state transition; returns ErrOrderNotFound or
+// ErrOrderNotProcessing otherwise.
 func ConfirmOrderTx(tx *sql.Tx, orderID string) error {
 	logger.Debugf("confirmando pedido (tx): id=%s", orderID)
-	res, err := tx.Exec(`UPDATE orders SET status = 'PAID' WHERE id = ? AND status = 'PROCESSING'`, orderID)
+	res, err := tx.Exec(`UPDATE orders SET status = 'PAID', confirmed_at = `+nowSQL+` WHERE id = ? AND status = 'PROCESSING'`, orderID)
 	if err != nil {
 		logger.Errorf("erro ao confirmar pedido (tx) %s: %v", orderID, err)
 		return err
@@ -206,7 +574,7 @@ func ConfirmOrderTx(tx *sql.Tx, orderID string) error {
 	logger.Debugf("linhas afetadas ao confirmar (tx): %d", ra)
 	if ra != 1 {
 		logger.Warnf("pedido não está em estado PROCESSING: id=%s", orderID)
-		return sql.ErrNoRows // Order not in PROCESSING state
+		return orderStateError(tx, orderID, ErrOrderNotProcessing)
 	}
 	logger.Infof("pedido confirmado (tx): id=%s", orderID)
 	return nil
@@ -220,19 +588,22 @@ func GetOrderTotalTx(tx *sql.Tx, orderID string) (float64, error) {
 	if err != nil {
 		logger.Errorf("erro ao obter total do pedido (tx) %s: %v", orderID, err)
 	} else {
-		logger.Debugf("total do pedido (tx): %.2f", total)
+		logger.Debugf("total do pedido (tx): %s", money.FormatReais(total))
 	}
 	return total, err
 }
 
-// OrderByIDTx returns order details within a transaction.
+// OrderByIDTx returns order details within a transaction, or ErrOrderNotFound.
 func OrderByIDTx(tx *sql.Tx, id string) (userID string, status string, total float64, err error) {
 	logger.Debugf("buscando pedido por id (tx): %s", id)
 	err = tx.QueryRow(`SELECT user_id, status, total FROM orders WHERE id = ?`, id).Scan(&userID, &status, &total)
+	if err == sql.ErrNoRows {
+		err = ErrOrderNotFound
+	}
 	if err != nil {
 		logger.Errorf("erro ao buscar pedido (tx) %s: %v", id, err)
 	} else {
-		logger.Debugf("pedido encontrado (tx): usuario=%s status=%s total=%.2f", userID, status, total)
+		logger.Debugf("pedido encontrado (tx): usuario=%s status=%s total=%s", userID, status, money.FormatReais(total))
 	}
 	return
 }
@@ -259,11 +630,11 @@ func OrderItemsByOrderIDTx(tx *sql.Tx, orderID string) ([]OrderItemRow, error) {
 	return list, rows.Err()
 }
 
-// CreateTicketWithIDTx inserts a ticket within a transaction.
-func CreateTicketWithIDTx(tx *sql.Tx, id, code, qrCode, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID string) error {
-	logger.Debugf("criando ingresso (tx) com id: id=%s codigo=%s pedido=%s itemPedido=%s usuario=%s evento=%s dataEvento=%s tipoIngresso=%s", id, code, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID)
-	_, err := tx.Exec(`INSERT INTO tickets (id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, used) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0)`,
-		id, code, qrCode, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID,
+// CreateTicketWithIDTx inserts a ticket within a transaction; source is one of the TicketSource constants.
+func CreateTicketWithIDTx(tx *sql.Tx, id, code, qrCode, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID, source string) error {
+	logger.Debugf("criando ingresso (tx) com id: id=%s codigo=%s pedido=%s itemPedido=%s usuario=%s evento=%s dataEvento=%s tipoIngresso=%s origem=%s", id, code, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID, source)
+	_, err := tx.Exec(`INSERT INTO tickets (id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, admits, admits_remaining, used, source, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, `+ticketTypeAdmits+`, `+ticketTypeAdmits+`, 0, ?, `+nowSQL+`)`,
+		id, code, qrCode, orderID, orderItemID, userID, eventID, eventDateID, ticketTypeID, ticketTypeID, ticketTypeID, source,
 	)
 	if err != nil {
 		logger.Errorf("erro ao criar ingresso (tx): %v", err)
@@ -286,7 +657,8 @@ func IncrementTicketTypeSoldTx(tx *sql.Tx, ticketTypeID string, n int) error {
 }
 
 // DecrementLotAvailableTx decrements available_quantity within a transaction.
-// Only decrements if sufficient quantity is available (prevents negative values).
+// Only decrements if sufficient quantity is available (prevents negative values);
+// returns ErrInsufficientInventory otherwise.
 func DecrementLotAvailableTx(tx *sql.Tx, lotID string, n int) error {
 	logger.Debugf("decrementando disponível no lote (tx): lote=%s n=%d", lotID, n)
 	res, err := tx.Exec(`UPDATE lots SET available_quantity = available_quantity - ? WHERE id = ? AND available_quantity >= ?`, n, lotID, n)
@@ -302,10 +674,10 @@ func DecrementLotAvailableTx(tx *sql.Tx, lotID string, n int) error {
 	logger.Debugf("linhas afetadas (tx) no lote: %d", ra)
 	if ra != 1 {
 		logger.Warnf("quantidade insuficiente no lote: %s", lotID)
-		return sql.ErrNoRows // Insufficient quantity available
+		return ErrInsufficientInventory
 	}
 	logger.Debugf("lote decrementado (tx): %s", lotID)
-	return err
+	return nil
 }
 
 // LotIDByTicketTypeIDTx retrieves lot ID within a transaction.
@@ -320,3 +692,49 @@ func LotIDByTicketTypeIDTx(tx *sql.Tx, ticketTypeID string) (string, error) {
 	}
 	return lotID, err
 }
+
+// SetOrderAttribution stores the sales channel and UTM parameters (JSON) of
+// an order. Empty values are stored as NULL.
+func SetOrderAttribution(db *sql.DB, orderID, channel, utmJSON string) error {
+	_, err := db.Exec(`UPDATE orders SET channel = ?, utm = ? WHERE id = ?`, nullIfEmpty(channel), nullIfEmpty(utmJSON), orderID)
+	return err
+}
+
+// ChannelSales is the paid sales of an event through one channel.
+type ChannelSales struct {
+	Channel string
+	Orders  int
+	Tickets int
+	Revenue float64
+}
+
+// SalesByChannel sums an event's paid orders (see paidOrderStatuses) per
+// sales channel, highest revenue first. Revenue is what the orders charged
+// (their total, after discounts and fees), not list price. Orders without a
+// channel are reported under noChannel; complimentary tickets are not sales
+// and are left out.
+func SalesByChannel(db *sql.DB, eventID, noChannel string) ([]ChannelSales, error) {
+	rows, err := db.Query(`SELECT COALESCE(o.channel, ?) AS ch, COUNT(*), COALESCE(SUM(q.tickets), 0), COALESCE(SUM(o.total), 0)
+		FROM orders o
+		JOIN (SELECT oi.order_id, SUM(oi.quantity) AS tickets
+			FROM order_items oi
+			JOIN event_dates ed ON ed.id = oi.event_date_id
+			WHERE ed.event_id = ?
+			GROUP BY oi.order_id) q ON q.order_id = o.id
+		WHERE o.status IN `+paidOrderStatuses+`
+			AND NOT EXISTS (SELECT 1 FROM tickets t WHERE t.order_id = o.id AND t.source = '`+TicketSourceComp+`')
+		GROUP BY ch ORDER BY 4 DESC, ch`, noChannel, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ChannelSales
+	for rows.Next() {
+		var s ChannelSales
+		if err := rows.Scan(&s.Channel, &s.Orders, &s.Tickets, &s.Revenue); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
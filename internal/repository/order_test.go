@@ -0,0 +1,433 @@
+package repository_test
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func orderStatus(t *testing.T, sqlite *sql.DB, orderID string) string {
+	t.Helper()
+	_, status, _, err := repository.OrderByID(sqlite, orderID)
+	if err != nil {
+		t.Fatalf("order %s: %v", orderID, err)
+	}
+	return status
+}
+
+// inTx runs fn in a transaction and commits it.
+func inTx(t *testing.T, sqlite *sql.DB, fn func(tx *sql.Tx)) {
+	t.Helper()
+	tx, err := sqlite.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	fn(tx)
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClaimOrderProcessingTxSucceedsOnce(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	orderID := testutil.Seed(t, sqlite, 10, 50).PendingOrder(t, sqlite, 1)
+
+	inTx(t, sqlite, func(tx *sql.Tx) {
+		if claimed, err := repository.ClaimOrderProcessingTx(tx, orderID); err != nil || !claimed {
+			t.Fatalf("first claim = %v, %v; want true", claimed, err)
+		}
+		// Same transaction, already PROCESSING: no row matches.
+		if claimed, err := repository.ClaimOrderProcessingTx(tx, orderID); err != nil || claimed {
+			t.Errorf("second claim = %v, %v; want false", claimed, err)
+		}
+	})
+	if got := orderStatus(t, sqlite, orderID); got != "PROCESSING" {
+		t.Errorf("status = %s, want PROCESSING", got)
+	}
+
+	// A later webhook for the same order loses the claim too.
+	inTx(t, sqlite, func(tx *sql.Tx) {
+		if claimed, err := repository.ClaimOrderProcessingTx(tx, orderID); err != nil || claimed {
+			t.Errorf("claim after commit = %v, %v; want false", claimed, err)
+		}
+		if claimed, err := repository.ClaimOrderProcessingTx(tx, "missing"); err != nil || claimed {
+			t.Errorf("claim of unknown order = %v, %v; want false", claimed, err)
+		}
+	})
+}
+
+func TestClaimOrderProcessingTxRollsBack(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	orderID := testutil.Seed(t, sqlite, 10, 50).PendingOrder(t, sqlite, 1)
+
+	tx, err := sqlite.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed, err := repository.ClaimOrderProcessingTx(tx, orderID); err != nil || !claimed {
+		t.Fatalf("claim = %v, %v; want true", claimed, err)
+	}
+	tx.Rollback()
+
+	// A failed payment transaction leaves the order claimable again.
+	if got := orderStatus(t, sqlite, orderID); got != "PENDING" {
+		t.Errorf("status after rollback = %s, want PENDING", got)
+	}
+}
+
+func TestConfirmOrderTxRequiresProcessing(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	orderID := testutil.Seed(t, sqlite, 10, 50).PendingOrder(t, sqlite, 1)
+
+	inTx(t, sqlite, func(tx *sql.Tx) {
+		if err := repository.ConfirmOrderTx(tx, orderID); !errors.Is(err, repository.ErrOrderNotProcessing) {
+			t.Errorf("confirm PENDING = %v, want ErrOrderNotProcessing", err)
+		}
+		if err := repository.ConfirmOrderTx(tx, "missing"); !errors.Is(err, repository.ErrOrderNotFound) {
+			t.Errorf("confirm unknown = %v, want ErrOrderNotFound", err)
+		}
+	})
+	if got := orderStatus(t, sqlite, orderID); got != "PENDING" {
+		t.Fatalf("status = %s, want PENDING", got)
+	}
+
+	inTx(t, sqlite, func(tx *sql.Tx) {
+		if claimed, err := repository.ClaimOrderProcessingTx(tx, orderID); err != nil || !claimed {
+			t.Fatalf("claim = %v, %v; want true", claimed, err)
+		}
+		if err := repository.ConfirmOrderTx(tx, orderID); err != nil {
+			t.Fatalf("confirm PROCESSING: %v", err)
+		}
+		// Confirming twice matches no row.
+		if err := repository.ConfirmOrderTx(tx, orderID); !errors.Is(err, repository.ErrOrderNotProcessing) {
+			t.Errorf("confirm PAID = %v, want ErrOrderNotProcessing", err)
+		}
+	})
+	if got := orderStatus(t, sqlite, orderID); got != "PAID" {
+		t.Errorf("status = %s, want PAID", got)
+	}
+}
+
+func TestConfirmOrderRefusesCancelledOrder(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	cancelled := f.PendingOrder(t, sqlite, 1)
+	if ok, err := repository.CancelPendingOrder(sqlite, cancelled, "test", ""); err != nil || !ok {
+		t.Fatalf("cancel = %v, %v", ok, err)
+	}
+
+	if err := repository.ConfirmOrder(sqlite, cancelled); !errors.Is(err, repository.ErrOrderNotConfirmable) {
+		t.Errorf("confirm CANCELLED = %v, want ErrOrderNotConfirmable", err)
+	}
+	if got := orderStatus(t, sqlite, cancelled); got != "CANCELLED" {
+		t.Errorf("status = %s, want CANCELLED", got)
+	}
+	if err := repository.ConfirmOrder(sqlite, "missing"); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Errorf("confirm unknown = %v, want ErrOrderNotFound", err)
+	}
+
+	pending := f.PendingOrder(t, sqlite, 1)
+	if err := repository.ConfirmOrder(sqlite, pending); err != nil {
+		t.Fatalf("confirm PENDING: %v", err)
+	}
+	if got := orderStatus(t, sqlite, pending); got != "PAID" {
+		t.Errorf("status = %s, want PAID", got)
+	}
+	if err := repository.ConfirmOrder(sqlite, pending); !errors.Is(err, repository.ErrOrderNotConfirmable) {
+		t.Errorf("confirm PAID = %v, want ErrOrderNotConfirmable", err)
+	}
+}
+
+func TestDecrementLotAvailableTxNeverOversells(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 3, 50)
+
+	inTx(t, sqlite, func(tx *sql.Tx) {
+		if err := repository.DecrementLotAvailableTx(tx, f.LotID, 2); err != nil {
+			t.Fatalf("decrement 2 of 3: %v", err)
+		}
+		if err := repository.DecrementLotAvailableTx(tx, f.LotID, 2); !errors.Is(err, repository.ErrInsufficientInventory) {
+			t.Errorf("decrement 2 of 1 = %v, want ErrInsufficientInventory", err)
+		}
+		if err := repository.DecrementLotAvailableTx(tx, f.LotID, 1); err != nil {
+			t.Errorf("decrement last place: %v", err)
+		}
+		if err := repository.DecrementLotAvailableTx(tx, "missing", 1); !errors.Is(err, repository.ErrInsufficientInventory) {
+			t.Errorf("decrement unknown lot = %v, want ErrInsufficientInventory", err)
+		}
+	})
+	var left int
+	if err := sqlite.QueryRow(`SELECT available_quantity FROM lots WHERE id = ?`, f.LotID).Scan(&left); err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("available = %d, want 0", left)
+	}
+}
+
+func TestCreateOrderWithItemsLimitsPendingOrders(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	items := []repository.NewOrderItem{{EventDateID: f.EventDateID, TicketTypeID: f.TicketTypeID, Quantity: 1, UnitPrice: 50}}
+
+	// Concurrent checkouts are counted in the insert transaction, so only
+	// MaxPending of them get through.
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repository.CreateOrderWithItems(sqlite, f.BuyerID, 50, time.Hour, items, repository.OrderLimits{MaxPending: 2})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, repository.ErrPendingOrderLimit):
+			t.Errorf("create = %v, want ErrPendingOrderLimit", err)
+		}
+	}
+	if created != 2 {
+		t.Errorf("created %d orders, want 2", created)
+	}
+
+	limits := repository.OrderLimits{ClientIP: "203.0.113.7", MaxPendingPerIP: 1}
+	if _, err := repository.CreateOrderWithItems(sqlite, f.ProducerUserID, 50, time.Hour, items, limits); err != nil {
+		t.Fatalf("first order from the address: %v", err)
+	}
+	if _, err := repository.CreateOrderWithItems(sqlite, f.ProducerUserID, 50, time.Hour, items, limits); !errors.Is(err, repository.ErrClientOrderLimit) {
+		t.Errorf("second order from the address = %v, want ErrClientOrderLimit", err)
+	}
+}
+
+func TestUpdateOrderItems(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	orderID := f.PendingOrder(t, sqlite, 1)
+
+	total, err := repository.UpdateOrderItems(sqlite, orderID, []repository.NewOrderItem{
+		{EventDateID: f.EventDateID, TicketTypeID: f.TicketTypeID, Quantity: 3, UnitPrice: 50},
+	})
+	if err != nil || total != 150 {
+		t.Fatalf("update = %v, %v; want 150", total, err)
+	}
+	if _, _, stored, _ := repository.OrderByID(sqlite, orderID); stored != 150 {
+		t.Errorf("stored total = %v, want 150", stored)
+	}
+	if items, _ := repository.OrderItemsByOrderID(sqlite, orderID); len(items) != 1 || items[0].Quantity != 3 {
+		t.Errorf("items = %+v", items)
+	}
+
+	// A failing item (unknown ticket type) leaves the order as it was.
+	_, err = repository.UpdateOrderItems(sqlite, orderID, []repository.NewOrderItem{
+		{EventDateID: f.EventDateID, TicketTypeID: f.TicketTypeID, Quantity: 1, UnitPrice: 50},
+		{EventDateID: f.EventDateID, TicketTypeID: "missing", Quantity: 1, UnitPrice: 50},
+	})
+	if err == nil {
+		t.Fatal("update with an unknown ticket type succeeded")
+	}
+	if _, _, stored, _ := repository.OrderByID(sqlite, orderID); stored != 150 {
+		t.Errorf("total after failed update = %v, want 150", stored)
+	}
+	if items, _ := repository.OrderItemsByOrderID(sqlite, orderID); len(items) != 1 || items[0].Quantity != 3 {
+		t.Errorf("items after failed update = %+v", items)
+	}
+
+	one := []repository.NewOrderItem{{EventDateID: f.EventDateID, TicketTypeID: f.TicketTypeID, Quantity: 1, UnitPrice: 50}}
+	if _, err := repository.UpdateOrderItems(sqlite, "missing", one); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Errorf("update unknown = %v, want ErrOrderNotFound", err)
+	}
+	if _, err := sqlite.Exec(`UPDATE orders SET pagarme_order_id = 'or_1' WHERE id = ?`, orderID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repository.UpdateOrderItems(sqlite, orderID, one); !errors.Is(err, repository.ErrOrderHasPayment) {
+		t.Errorf("update with PIX = %v, want ErrOrderHasPayment", err)
+	}
+	cancelled := f.PendingOrder(t, sqlite, 1)
+	if ok, err := repository.CancelPendingOrder(sqlite, cancelled, "test", ""); err != nil || !ok {
+		t.Fatalf("cancel = %v, %v", ok, err)
+	}
+	if _, err := repository.UpdateOrderItems(sqlite, cancelled, one); !errors.Is(err, repository.ErrOrderNotEditable) {
+		t.Errorf("update CANCELLED = %v, want ErrOrderNotEditable", err)
+	}
+}
+
+func TestPlatformStats(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	if _, err := sqlite.Exec(`UPDATE events SET status = 'PUBLISHED' WHERE id = ?`, f.EventID); err != nil {
+		t.Fatal(err)
+	}
+	paid := f.PendingOrder(t, sqlite, 2)
+	if err := repository.ConfirmOrder(sqlite, paid); err != nil {
+		t.Fatal(err)
+	}
+	f.PendingOrder(t, sqlite, 1)
+	inTx(t, sqlite, func(tx *sql.Tx) {
+		if _, _, err := repository.CreateCompOrderTx(tx, f.BuyerID, f.EventDateID, f.TicketTypeID); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	now := time.Now()
+	s, err := repository.PlatformStats(sqlite, now.Add(-time.Hour), now.Add(time.Hour), 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.GMV != 10000 || s.PlatformFees != 1000 || s.PaidOrders != 1 || s.TicketsSold != 2 {
+		t.Errorf("sales = %+v; want R$100 from 1 order of 2 tickets, R$10 in fees", s)
+	}
+	if s.ActiveProducers != 1 || s.PublishedEvents != 1 {
+		t.Errorf("producers = %d, events = %d; want 1 and 1", s.ActiveProducers, s.PublishedEvents)
+	}
+	want := []repository.StatusCount{{Status: "PAID", Orders: 1}, {Status: "PENDING", Orders: 1}}
+	if len(s.OrdersByStatus) != len(want) || s.OrdersByStatus[0] != want[0] || s.OrdersByStatus[1] != want[1] {
+		t.Errorf("by status = %+v, want %+v (complimentary order left out)", s.OrdersByStatus, want)
+	}
+
+	// Legacy CONFIRMED orders count as paid too.
+	if _, err := sqlite.Exec(`UPDATE orders SET status = 'CONFIRMED' WHERE id = ?`, paid); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := repository.PlatformStats(sqlite, now.Add(-time.Hour), now.Add(time.Hour), 500); err != nil || s.PaidOrders != 1 || s.GMV != 10000 {
+		t.Errorf("stats with a CONFIRMED order = %+v, %v; want it counted as paid", s, err)
+	}
+
+	// The fee never exceeds the order total.
+	if s, _ := repository.PlatformStats(sqlite, now.Add(-time.Hour), now.Add(time.Hour), 10000); s.PlatformFees != 10000 {
+		t.Errorf("capped fees = %d, want 10000", s.PlatformFees)
+	}
+	if s, _ := repository.PlatformStats(sqlite, now.Add(time.Hour), now.Add(2*time.Hour), 500); s.PaidOrders != 0 || s.GMV != 0 || len(s.OrdersByStatus) != 0 {
+		t.Errorf("stats of a later period = %+v, want none", s)
+	}
+}
+
+func TestEventTotalAvailability(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	now := time.Now()
+
+	dateID, err := repository.CreateEventDate(sqlite, f.EventID, "2099-02-01", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repository.CreateLot(sqlite, dateID, "Encerrado", "2000-01-01T00:00:00Z", "2001-01-01T00:00:00-03:00", 5); err != nil {
+		t.Fatal(err)
+	}
+	inactive, err := repository.CreateLot(sqlite, dateID, "Inativo", "2000-01-01T00:00:00Z", "2099-01-01T00:00:00Z", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlite.Exec(`UPDATE lots SET active = 0 WHERE id = ?`, inactive); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repository.CreateLot(sqlite, dateID, "Lote 2", "2000-01-01T00:00:00Z", "2099-01-01T00:00:00Z", 4); err != nil {
+		t.Fatal(err)
+	}
+
+	if available, lots, err := repository.EventTotalAvailability(sqlite, f.EventID, now); err != nil || available != 14 || lots != 2 {
+		t.Errorf("availability = %d in %d lots (%v), want 14 in 2", available, lots, err)
+	}
+	if err := repository.DecrementLotAvailable(sqlite, f.LotID, 10); err != nil {
+		t.Fatal(err)
+	}
+	if available, lots, _ := repository.EventTotalAvailability(sqlite, f.EventID, now); available != 4 || lots != 2 {
+		t.Errorf("after selling lot 1 = %d in %d lots, want 4 in 2", available, lots)
+	}
+	if available, lots, _ := repository.EventTotalAvailability(sqlite, "missing", now); available != 0 || lots != 0 {
+		t.Errorf("unknown event = %d in %d lots", available, lots)
+	}
+}
+
+func TestAuthorizeOrderAccess(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	orderID := f.PendingOrder(t, sqlite, 1)
+
+	order, err := repository.AuthorizeOrderAccess(sqlite, orderID, f.BuyerID)
+	if err != nil || order.ID != orderID || order.Status != "PENDING" || order.OrderRef == "" {
+		t.Errorf("owner: order = %+v, err = %v", order, err)
+	}
+	if _, err := repository.AuthorizeOrderAccess(sqlite, orderID, f.ProducerUserID); !errors.Is(err, repository.ErrOrderForbidden) {
+		t.Errorf("other user: err = %v, want ErrOrderForbidden", err)
+	}
+	if _, err := repository.AuthorizeOrderAccess(sqlite, orderID, ""); !errors.Is(err, repository.ErrOrderForbidden) {
+		t.Errorf("anonymous: err = %v, want ErrOrderForbidden", err)
+	}
+	if _, err := repository.AuthorizeOrderAccess(sqlite, "missing", f.BuyerID); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Errorf("missing: err = %v, want ErrOrderNotFound", err)
+	}
+}
+
+func TestOrderInventoryReservation(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	available := func() int {
+		t.Helper()
+		var n int
+		if err := sqlite.QueryRow(`SELECT available_quantity FROM lots WHERE id = ?`, f.LotID).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	items := func(qty int) []repository.NewOrderItem {
+		return []repository.NewOrderItem{{EventDateID: f.EventDateID, TicketTypeID: f.TicketTypeID, Quantity: qty, UnitPrice: 50}}
+	}
+
+	orderID := f.PendingOrder(t, sqlite, 3)
+	if got := available(); got != 7 {
+		t.Fatalf("available after order = %d, want 7", got)
+	}
+	if reserved, err := repository.OrderInventoryReserved(sqlite, orderID); err != nil || !reserved {
+		t.Fatalf("reserved = %v, %v", reserved, err)
+	}
+	if _, err := repository.CreateOrderWithItems(sqlite, f.BuyerID, 400, time.Hour, items(8), repository.OrderLimits{}); !errors.Is(err, repository.ErrInsufficientInventory) {
+		t.Errorf("order above what is left = %v, want ErrInsufficientInventory", err)
+	}
+
+	// Changing the items moves the reservation; asking for more than the
+	// lot has leaves it as it was.
+	if _, err := repository.UpdateOrderItems(sqlite, orderID, items(5)); err != nil {
+		t.Fatal(err)
+	}
+	if got := available(); got != 5 {
+		t.Errorf("available after update = %d, want 5", got)
+	}
+	if _, err := repository.UpdateOrderItems(sqlite, orderID, items(11)); !errors.Is(err, repository.ErrInsufficientInventory) {
+		t.Errorf("update above the lot = %v, want ErrInsufficientInventory", err)
+	}
+	if got := available(); got != 5 {
+		t.Errorf("available after failed update = %d, want 5", got)
+	}
+
+	// Cancelling gives the places back, once.
+	for i := 0; i < 2; i++ {
+		if _, err := repository.CancelPendingOrder(sqlite, orderID, "test", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := available(); got != 10 {
+		t.Errorf("available after cancel = %d, want 10", got)
+	}
+
+	// So does purging an abandoned order.
+	f.PendingOrder(t, sqlite, 4)
+	if n, err := repository.PurgeAbandonedOrders(sqlite, time.Now().Add(time.Hour), false); err != nil || n != 1 {
+		t.Fatalf("purge = %d, %v", n, err)
+	}
+	if got := available(); got != 10 {
+		t.Errorf("available after purge = %d, want 10", got)
+	}
+}
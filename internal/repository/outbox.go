@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of outbox message.
+const (
+	OutboxEmail        = "email"
+	OutboxNotification = "notification"
+)
+
+// Statuses of an outbox message.
+const (
+	OutboxPending = "PENDING"
+	OutboxSent    = "SENT"
+	OutboxDead    = "DEAD"
+)
+
+// OutboxMessage is a row of the outbox. Payload is a JSON object whose format
+// depends on Kind.
+type OutboxMessage struct {
+	ID            string `json:"id"`
+	Kind          string `json:"kind"`
+	Payload       string `json:"-"`
+	Status        string `json:"status"`
+	Attempts      int    `json:"attempts"`
+	LastError     string `json:"lastError,omitempty"`
+	NextAttemptAt string `json:"nextAttemptAt"`
+	CreatedAt     string `json:"createdAt"`
+}
+
+const (
+	outboxColumns = `id, kind, payload, status, attempts, last_error, next_attempt_at, created_at`
+	insertOutbox  = `INSERT INTO outbox (id, kind, payload) VALUES (?, ?, ?)`
+)
+
+func scanOutboxMessage(s interface{ Scan(...interface{}) error }) (*OutboxMessage, error) {
+	var m OutboxMessage
+	var lastError sql.NullString
+	if err := s.Scan(&m.ID, &m.Kind, &m.Payload, &m.Status, &m.Attempts, &lastError, &m.NextAttemptAt, &m.CreatedAt); err != nil {
+		return nil, err
+	}
+	m.LastError = lastError.String
+	return &m, nil
+}
+
+// EnqueueOutbox stores a PENDING message, due now, and returns its id.
+func EnqueueOutbox(db *sql.DB, kind, payload string) (string, error) {
+	id := uuid.New().String()
+	_, err := db.Exec(insertOutbox, id, kind, payload)
+	return id, err
+}
+
+// EnqueueOutboxTx is EnqueueOutbox in the caller's transaction, so the
+// message exists if and only if the change that triggered it commits.
+func EnqueueOutboxTx(tx *sql.Tx, kind, payload string) (string, error) {
+	id := uuid.New().String()
+	_, err := tx.Exec(insertOutbox, id, kind, payload)
+	return id, err
+}
+
+// DueOutboxIDs returns PENDING messages due at now, oldest first.
+func DueOutboxIDs(db *sql.DB, now time.Time, limit int) ([]string, error) {
+	rows, err := db.Query(`SELECT id FROM outbox WHERE status = 'PENDING' AND next_attempt_at <= ? ORDER BY next_attempt_at, rowid LIMIT ?`, FormatTime(now), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ClaimOutbox takes a PENDING message that is due at now for delivery,
+// pushing its next attempt lease into the future so no other worker (or
+// replica) picks it up meanwhile. Returns nil if it isn't due, was already
+// claimed or no longer exists.
+func ClaimOutbox(db *sql.DB, id string, now time.Time, lease time.Duration) (*OutboxMessage, error) {
+	res, err := db.Exec(`UPDATE outbox SET next_attempt_at = ? WHERE id = ? AND status = 'PENDING' AND next_attempt_at <= ?`,
+		FormatTime(now.Add(lease)), id, FormatTime(now))
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, nil
+	}
+	m, err := scanOutboxMessage(db.QueryRow(`SELECT `+outboxColumns+` FROM outbox WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return m, err
+}
+
+// MarkOutboxSent records a successful delivery.
+func MarkOutboxSent(db *sql.DB, id string) error {
+	_, err := db.Exec(`UPDATE outbox SET status = 'SENT', attempts = attempts + 1, last_error = NULL, sent_at = `+nowSQL+` WHERE id = ?`, id)
+	return err
+}
+
+// MarkOutboxFailed records a failed attempt: the message is retried at next,
+// or moved to DEAD when dead is set.
+func MarkOutboxFailed(db *sql.DB, id, lastError string, next time.Time, dead bool) error {
+	status := OutboxPending
+	if dead {
+		status = OutboxDead
+	}
+	_, err := db.Exec(`UPDATE outbox SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?`,
+		status, lastError, FormatTime(next), id)
+	return err
+}
+
+// OutboxCounts returns how many messages are in each status.
+func OutboxCounts(db *sql.DB) (map[string]int, error) {
+	counts := map[string]int{OutboxPending: 0, OutboxSent: 0, OutboxDead: 0}
+	rows, err := db.Query(`SELECT status, COUNT(*) FROM outbox GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+// DeadOutboxMessages returns the most recent dead-lettered messages.
+func DeadOutboxMessages(db *sql.DB, limit int) ([]*OutboxMessage, error) {
+	rows, err := db.Query(`SELECT `+outboxColumns+` FROM outbox WHERE status = 'DEAD' ORDER BY next_attempt_at DESC, rowid DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*OutboxMessage
+	for rows.Next() {
+		m, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// DeleteSentOutbox removes messages delivered before cutoff; their payloads
+// (email bodies, claim links) aren't needed once sent.
+func DeleteSentOutbox(db *sql.DB, cutoff time.Time) (int64, error) {
+	res, err := db.Exec(`DELETE FROM outbox WHERE status = 'SENT' AND sent_at < ?`, FormatTime(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// redactedOutboxPayload replaces the payload of a redacted message.
+const redactedOutboxPayload = `{}`
+
+// RedactDeadOutbox clears the payload of messages dead-lettered before
+// cutoff. The rows stay, so the counts and the admin listing still show
+// them, but email bodies (guest claim links) and addresses don't outlive
+// the retention window.
+func RedactDeadOutbox(db *sql.DB, cutoff time.Time) (int64, error) {
+	res, err := db.Exec(`UPDATE outbox SET payload = ? WHERE status = 'DEAD' AND next_attempt_at < ? AND payload != ?`,
+		redactedOutboxPayload, FormatTime(cutoff), redactedOutboxPayload)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -2,6 +2,9 @@ package repository
 
 import (
 	"database/sql"
+	"time"
+
+	"afterzin/api/internal/eventtime"
 
 	"github.com/google/uuid"
 )
@@ -27,11 +30,108 @@ func SetProducerPagarmeRecipientID(db *sql.DB, producerID, recipientID string) e
 	return err
 }
 
+// SetProducerPagarmeEnv records which Pagar.me environment (test/live) the producer's recipient belongs to.
+func SetProducerPagarmeEnv(db *sql.DB, producerID, env string) error {
+	_, err := db.Exec(`UPDATE producers SET pagarme_env = ? WHERE id = ?`, env, producerID)
+	return err
+}
+
+// ClearProducerPagarmeRecipient removes the producer's recipient association and onboarding flags.
+func ClearProducerPagarmeRecipient(db *sql.DB, producerID string) error {
+	_, err := db.Exec(`UPDATE producers SET pagarme_recipient_id = NULL, pagarme_env = NULL,
+		pagarme_recipient_status = NULL, pagarme_recipient_status_at = NULL,
+		payment_onboarding_complete = 0, stripe_onboarding_complete = 0 WHERE id = ?`, producerID)
+	return err
+}
+
+// CountProducerOrdersAwaitingTransfer counts orders on the producer's events that still
+// depend on the recipient: unexpired PENDING orders, and PAID orders for dates not yet past.
+// Event dates are local to the event's time zone (defaultTimezone when it has none), so a
+// date is past only once its day has ended there; SQL only narrows the candidates.
+func CountProducerOrdersAwaitingTransfer(db *sql.DB, producerID string, now time.Time, defaultTimezone string) (pending, paid int, err error) {
+	err = db.QueryRow(`SELECT COUNT(DISTINCT o.id)
+		FROM orders o
+		JOIN order_items oi ON oi.order_id = o.id
+		JOIN event_dates ed ON ed.id = oi.event_date_id
+		JOIN events e ON e.id = ed.event_id
+		WHERE e.producer_id = ? AND o.status = 'PENDING'
+		AND (o.expires_at IS NULL OR o.expires_at > `+nowSQL+`)`, producerID).Scan(&pending)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// No time zone is a full day behind UTC, so earlier dates are past everywhere
+	rows, err := db.Query(`SELECT DISTINCT o.id, ed.date, COALESCE(NULLIF(e.timezone, ''), ?)
+		FROM orders o
+		JOIN order_items oi ON oi.order_id = o.id
+		JOIN event_dates ed ON ed.id = oi.event_date_id
+		JOIN events e ON e.id = ed.event_id
+		WHERE e.producer_id = ? AND o.status = 'PAID' AND ed.date >= ?`,
+		defaultTimezone, producerID, now.UTC().AddDate(0, 0, -1).Format("2006-01-02"))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	upcoming := make(map[string]bool)
+	for rows.Next() {
+		var orderID, date, timezone string
+		if err := rows.Scan(&orderID, &date, &timezone); err != nil {
+			return 0, 0, err
+		}
+		// An unreadable date or zone counts as upcoming: better to refuse the removal
+		end, err := eventtime.DateStart(date, "", timezone)
+		if err != nil || now.Before(end) {
+			upcoming[orderID] = true
+		}
+	}
+	return pending, len(upcoming), rows.Err()
+}
+
+// PayoutOrder is a PAID order whose PIX split paid the producer.
+type PayoutOrder struct {
+	ID       string
+	OrderRef string
+	Total    float64 // reais
+	Tickets  int
+	PaidAt   string
+}
+
+// ProducerPayoutOrders lists the producer's PAID orders charged through
+// Pagar.me, oldest payment first (the order the balance is paid out in).
+// Complimentary and free orders never reach the gateway and are left out.
+func ProducerPayoutOrders(db *sql.DB, producerID string) ([]PayoutOrder, error) {
+	rows, err := db.Query(`SELECT o.id, COALESCE(o.order_ref, ''), o.total,
+			(SELECT COALESCE(SUM(oi.quantity), 0) FROM order_items oi WHERE oi.order_id = o.id),
+			COALESCE((SELECT MAX(h.created_at) FROM order_status_history h
+				WHERE h.order_id = o.id AND h.new_status = 'PAID'), o.created_at) AS paid_at
+		FROM orders o
+		WHERE o.status = 'PAID' AND o.pagarme_order_id IS NOT NULL
+			AND EXISTS (SELECT 1 FROM order_items oi
+				JOIN event_dates ed ON ed.id = oi.event_date_id
+				JOIN events e ON e.id = ed.event_id
+				WHERE oi.order_id = o.id AND e.producer_id = ?)
+		ORDER BY paid_at, o.id`, producerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []PayoutOrder
+	for rows.Next() {
+		var o PayoutOrder
+		if err := rows.Scan(&o.ID, &o.OrderRef, &o.Total, &o.Tickets, &o.PaidAt); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
 // GetProducerOnboardingComplete returns whether the producer has completed payment onboarding.
-// Reuses the stripe_onboarding_complete column (shared concept).
+// Reads payment_onboarding_complete, falling back to the legacy stripe_onboarding_complete
+// column while rows written by older builds may still only have the old flag set.
 func GetProducerOnboardingComplete(db *sql.DB, producerID string) (bool, error) {
 	var complete int
-	err := db.QueryRow(`SELECT stripe_onboarding_complete FROM producers WHERE id = ?`, producerID).Scan(&complete)
+	err := db.QueryRow(`SELECT MAX(payment_onboarding_complete, stripe_onboarding_complete) FROM producers WHERE id = ?`, producerID).Scan(&complete)
 	if err != nil {
 		return false, err
 	}
@@ -39,20 +139,112 @@ func GetProducerOnboardingComplete(db *sql.DB, producerID string) (bool, error)
 }
 
 // SetProducerOnboardingComplete updates the onboarding complete flag.
+// Writes both the new and the legacy column during the transition window.
 func SetProducerOnboardingComplete(db *sql.DB, producerID string, complete bool) error {
 	v := 0
 	if complete {
 		v = 1
 	}
-	_, err := db.Exec(`UPDATE producers SET stripe_onboarding_complete = ? WHERE id = ?`, v, producerID)
+	_, err := db.Exec(`UPDATE producers SET payment_onboarding_complete = ?, stripe_onboarding_complete = ? WHERE id = ?`, v, v, producerID)
 	return err
 }
 
+// ProducerByPagarmeRecipientID returns the producer (and its owning user)
+// linked to a Pagar.me recipient, or empty strings when none is.
+func ProducerByPagarmeRecipientID(db *sql.DB, recipientID string) (producerID, userID string, err error) {
+	err = db.QueryRow(`SELECT id, user_id FROM producers WHERE pagarme_recipient_id = ?`, recipientID).Scan(&producerID, &userID)
+	if err == sql.ErrNoRows {
+		return "", "", nil
+	}
+	return producerID, userID, err
+}
+
+// GetProducerRecipientStatus returns the last recipient status stored from a webhook.
+func GetProducerRecipientStatus(db *sql.DB, producerID string) (string, error) {
+	var status sql.NullString
+	err := db.QueryRow(`SELECT pagarme_recipient_status FROM producers WHERE id = ?`, producerID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return status.String, err
+}
+
+// SetProducerRecipientStatus stores a recipient status reported at time at
+// (RFC 3339). Updates older than the stored one, or repeating the current
+// status, are ignored and report changed=false. The onboarding flag follows
+// terminal states: set when active, cleared when refused, inactive,
+// suspended or blocked, untouched while under analysis.
+func SetProducerRecipientStatus(db *sql.DB, producerID, status, at string) (changed bool, err error) {
+	res, err := db.Exec(`UPDATE producers SET
+		pagarme_recipient_status = ?1,
+		pagarme_recipient_status_at = ?2,
+		payment_onboarding_complete = CASE
+			WHEN ?1 = 'active' THEN 1
+			WHEN ?1 IN ('refused', 'inactive', 'suspended', 'blocked') THEN 0
+			ELSE payment_onboarding_complete END,
+		stripe_onboarding_complete = CASE
+			WHEN ?1 = 'active' THEN 1
+			WHEN ?1 IN ('refused', 'inactive', 'suspended', 'blocked') THEN 0
+			ELSE stripe_onboarding_complete END
+		WHERE id = ?3
+		AND (pagarme_recipient_status IS NULL OR pagarme_recipient_status != ?1)
+		AND (pagarme_recipient_status_at IS NULL OR pagarme_recipient_status_at <= ?2)`,
+		status, at, producerID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
 // ---------- Order Pagar.me fields ----------
 
-// SetOrderPagarmeOrderID saves the Pagar.me order ID on an order.
+// SetOrderPagarmeOrderID saves the Pagar.me order ID of a PIX just created
+// for an order, stamping pix_created_at.
 func SetOrderPagarmeOrderID(db *sql.DB, orderID, pagarmeOrderID string) error {
-	_, err := db.Exec(`UPDATE orders SET pagarme_order_id = ? WHERE id = ?`, pagarmeOrderID, orderID)
+	_, err := db.Exec(`UPDATE orders SET pagarme_order_id = ?, pix_created_at = `+nowSQL+` WHERE id = ?`, pagarmeOrderID, orderID)
+	return err
+}
+
+// ClaimOrderPayment marks a PENDING order as being paid, before the gateway
+// is called, so UpdateOrderItems can't change the items the PIX is created
+// from. Only one request holds the claim: another one gets
+// ErrPaymentInProgress, unless the order already has a Pagar.me order (a new
+// PIX may replace a cancelled one) or the claim is older than lease, left by
+// a request that died mid-call. Returns false if the order isn't PENDING.
+func ClaimOrderPayment(db *sql.DB, orderID string, now time.Time, lease time.Duration) (bool, error) {
+	res, err := db.Exec(`UPDATE orders SET payment_started_at = ?1
+		WHERE id = ?2 AND status = 'PENDING'
+		AND (payment_started_at IS NULL OR payment_started_at < ?3 OR COALESCE(pagarme_order_id, '') <> '')`,
+		FormatTime(now), orderID, FormatTime(now.Add(-lease)))
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return true, nil
+	}
+	var status string
+	if err := db.QueryRow(`SELECT status FROM orders WHERE id = ?`, orderID).Scan(&status); err != nil {
+		return false, err
+	}
+	if status == "PENDING" {
+		return false, ErrPaymentInProgress
+	}
+	return false, nil
+}
+
+// ReleaseOrderPayment drops the claim of ClaimOrderPayment when no PIX was
+// created, so the order's items can change again.
+func ReleaseOrderPayment(db *sql.DB, orderID string) error {
+	_, err := db.Exec(`UPDATE orders SET payment_started_at = NULL WHERE id = ? AND COALESCE(pagarme_order_id, '') = ''`, orderID)
+	return err
+}
+
+// SetOrderPix records the order's current PIX: its copy-and-paste code, QR
+// image URL and when it expires.
+func SetOrderPix(db *sql.DB, orderID, qrCode, qrCodeURL string, expiresAt time.Time) error {
+	_, err := db.Exec(`UPDATE orders SET pix_qr_code = ?, pix_qr_code_url = ?, pix_expires_at = ? WHERE id = ?`,
+		qrCode, qrCodeURL, FormatTime(expiresAt), orderID)
 	return err
 }
 
@@ -72,6 +264,46 @@ func SetOrderPagarmeChargeID(db *sql.DB, orderID, chargeID string) error {
 	return err
 }
 
+// SetOrderPagarmeEnv records which Pagar.me environment (test/live) the order was created in.
+func SetOrderPagarmeEnv(db *sql.DB, orderID, env string) error {
+	_, err := db.Exec(`UPDATE orders SET pagarme_env = ? WHERE id = ?`, env, orderID)
+	return err
+}
+
+// GetOrderChargeStatus returns the lifecycle state of the order's charge ("" if unset).
+func GetOrderChargeStatus(db *sql.DB, orderID string) (string, error) {
+	var status sql.NullString
+	err := db.QueryRow(`SELECT charge_status FROM orders WHERE id = ?`, orderID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return status.String, err
+}
+
+// GetOrderChargeStatusTx reads the charge state in the caller's transaction.
+func GetOrderChargeStatusTx(tx *sql.Tx, orderID string) (string, error) {
+	var status sql.NullString
+	err := tx.QueryRow(`SELECT charge_status FROM orders WHERE id = ?`, orderID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return status.String, err
+}
+
+// SetOrderChargeStatus records the state of a newly created charge, replacing
+// the state of any previous (failed or canceled) charge of the order.
+func SetOrderChargeStatus(db *sql.DB, orderID, status string) error {
+	_, err := db.Exec(`UPDATE orders SET charge_status = ? WHERE id = ?`, status, orderID)
+	return err
+}
+
+// SetOrderChargeStatusTx records the charge state in the caller's transaction.
+// Transition rules are enforced by the caller.
+func SetOrderChargeStatusTx(tx *sql.Tx, orderID, status string) error {
+	_, err := tx.Exec(`UPDATE orders SET charge_status = ? WHERE id = ?`, status, orderID)
+	return err
+}
+
 // GetOrderPagarmeChargeID retrieves the Pagar.me charge ID for an order.
 func GetOrderPagarmeChargeID(db *sql.DB, orderID string) (string, error) {
 	var chargeID sql.NullString
@@ -91,14 +323,23 @@ func PagarmeWebhookEventExists(db *sql.DB, eventID string) bool {
 	return err == nil && exists > 0
 }
 
-// InsertPagarmeWebhookEvent logs a received Pagar.me webhook event.
-func InsertPagarmeWebhookEvent(db *sql.DB, eventID, eventType string) error {
+// InsertPagarmeWebhookEvent logs a received Pagar.me webhook event. It is the
+// authoritative dedup point: inserted is false when the event id was already
+// logged (including by a concurrent delivery), and the caller must not process it.
+func InsertPagarmeWebhookEvent(db *sql.DB, eventID, eventType string) (inserted bool, err error) {
 	id := uuid.New().String()
-	_, err := db.Exec(
-		`INSERT OR IGNORE INTO pagarme_webhook_events (id, pagarme_event_id, event_type) VALUES (?, ?, ?)`,
+	res, err := db.Exec(
+		`INSERT OR IGNORE INTO pagarme_webhook_events (id, pagarme_event_id, event_type, created_at) VALUES (?, ?, ?, `+nowSQL+`)`,
 		id, eventID, eventType,
 	)
-	return err
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
 }
 
 // MarkPagarmeWebhookEventProcessed marks a Pagar.me webhook event as successfully processed.
@@ -138,30 +379,96 @@ func PagarmeWebhookProcessedForOrder(db *sql.DB, orderID, eventType string) bool
 	return err == nil && exists > 0
 }
 
-// MarkPagarmeWebhookEventProcessedAt marks a webhook event as processed with timestamp.
-func MarkPagarmeWebhookEventProcessedAt(db *sql.DB, eventID string) error {
+// MarkPagarmeWebhookEventProcessedAt marks a webhook event as processed with
+// timestamp and records its outcome ("handled" or "ignored").
+func MarkPagarmeWebhookEventProcessedAt(db *sql.DB, eventID, outcome string) error {
 	_, err := db.Exec(
-		`UPDATE pagarme_webhook_events SET processed = 1, processed_at = datetime('now') WHERE pagarme_event_id = ?`,
-		eventID,
+		`UPDATE pagarme_webhook_events SET processed = 1, processed_at = `+nowSQL+`, outcome = ? WHERE pagarme_event_id = ?`,
+		outcome, eventID,
 	)
 	return err
 }
 
+// WebhookEventTypeCount is the number of recorded webhook events of a type
+// with a given outcome ("handled", "ignored"; empty for older events).
+type WebhookEventTypeCount struct {
+	Type    string `json:"type"`
+	Outcome string `json:"outcome"`
+	Count   int    `json:"count"`
+}
+
+// WebhookEventTypeCounts counts recorded webhook events by type and outcome.
+func WebhookEventTypeCounts(db *sql.DB) ([]WebhookEventTypeCount, error) {
+	rows, err := db.Query(`SELECT event_type, COALESCE(outcome, ''), COUNT(*) FROM pagarme_webhook_events
+		GROUP BY event_type, outcome ORDER BY event_type, outcome`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []WebhookEventTypeCount
+	for rows.Next() {
+		var c WebhookEventTypeCount
+		if err := rows.Scan(&c.Type, &c.Outcome, &c.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// OrderByPagarmeOrderID looks up an order by its Pagar.me order ID (nil if unknown).
+func OrderByPagarmeOrderID(db *sql.DB, pagarmeOrderID string) (*OrderRow, error) {
+	return scanOrderRow(db.QueryRow(`SELECT `+orderRowColumns+` FROM orders WHERE pagarme_order_id = ?`, pagarmeOrderID))
+}
+
+// OrderStatusHistoryRow is one recorded status transition of an order.
+type OrderStatusHistoryRow struct {
+	OldStatus       string
+	NewStatus       string
+	Reason          string
+	PagarmeEventID  string
+	PagarmeChargeID string
+	ErrorMessage    string
+	CreatedAt       string
+}
+
+// OrderStatusHistory returns an order's status transitions, oldest first.
+func OrderStatusHistory(db *sql.DB, orderID string) ([]OrderStatusHistoryRow, error) {
+	rows, err := db.Query(`SELECT old_status, new_status, reason, pagarme_event_id, pagarme_charge_id, error_message, created_at
+		FROM order_status_history WHERE order_id = ? ORDER BY created_at, rowid`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []OrderStatusHistoryRow
+	for rows.Next() {
+		var h OrderStatusHistoryRow
+		var reason, eventID, chargeID, errMsg sql.NullString
+		if err := rows.Scan(&h.OldStatus, &h.NewStatus, &reason, &eventID, &chargeID, &errMsg, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		h.Reason, h.PagarmeEventID, h.PagarmeChargeID, h.ErrorMessage = reason.String, eventID.String, chargeID.String, errMsg.String
+		list = append(list, h)
+	}
+	return list, rows.Err()
+}
+
 // RecordOrderStatusChange logs an order status transition for audit purposes.
 func RecordOrderStatusChange(tx *sql.Tx, orderID, oldStatus, newStatus, reason string, pagarmeEventID, pagarmeOrderID, pagarmeChargeID string) error {
 	id := uuid.New().String()
 	_, err := tx.Exec(
-		`INSERT INTO order_status_history (id, order_id, old_status, new_status, reason, pagarme_event_id, pagarme_order_id, pagarme_charge_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO order_status_history (id, order_id, old_status, new_status, reason, pagarme_event_id, pagarme_order_id, pagarme_charge_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, `+nowSQL+`)`,
 		id, orderID, oldStatus, newStatus, reason, pagarmeEventID, pagarmeOrderID, pagarmeChargeID,
 	)
 	return err
 }
 
-// RecordOrderStatusChangeWithError logs a failed status transition attempt.
-func RecordOrderStatusChangeWithError(tx *sql.Tx, orderID, oldStatus, newStatus, reason, errorMessage string) error {
+// RecordOrderStatusChangeWithError logs a failed status transition attempt,
+// in db or in the caller's transaction.
+func RecordOrderStatusChangeWithError(db execer, orderID, oldStatus, newStatus, reason, errorMessage string) error {
 	id := uuid.New().String()
-	_, err := tx.Exec(
-		`INSERT INTO order_status_history (id, order_id, old_status, new_status, reason, error_message) VALUES (?, ?, ?, ?, ?, ?)`,
+	_, err := db.Exec(
+		`INSERT INTO order_status_history (id, order_id, old_status, new_status, reason, error_message, created_at) VALUES (?, ?, ?, ?, ?, ?, `+nowSQL+`)`,
 		id, orderID, oldStatus, newStatus, reason, errorMessage,
 	)
 	return err
@@ -0,0 +1,95 @@
+package repository
+
+import "database/sql"
+
+// An order created with items reserves the lot places they admit
+// (quantity × ticket_types.admits) in the creating transaction and is marked
+// inventory_reserved. The places are given back when the order leaves
+// PENDING without being paid (CancelPendingOrder, PurgeAbandonedOrders) and
+// moved along when its items change (UpdateOrderItems). Confirming a
+// reserved order issues its tickets without taking the places again.
+
+// lotPlaces is the number of places an order's items take from one lot.
+type lotPlaces struct {
+	lotID  string
+	places int
+}
+
+// orderLotPlacesTx sums the places orderID's items take from each lot.
+func orderLotPlacesTx(tx *sql.Tx, orderID string) ([]lotPlaces, error) {
+	rows, err := tx.Query(`SELECT tt.lot_id, SUM(oi.quantity * tt.admits) FROM order_items oi
+		JOIN ticket_types tt ON tt.id = oi.ticket_type_id
+		WHERE oi.order_id = ? GROUP BY tt.lot_id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []lotPlaces
+	for rows.Next() {
+		var lp lotPlaces
+		if err := rows.Scan(&lp.lotID, &lp.places); err != nil {
+			return nil, err
+		}
+		out = append(out, lp)
+	}
+	return out, rows.Err()
+}
+
+// reserveOrderInventoryTx takes the places of orderID's items from their lots
+// and marks the order as holding them. Returns ErrInsufficientInventory if a
+// lot doesn't have enough left; the caller rolls back.
+func reserveOrderInventoryTx(tx *sql.Tx, orderID string) error {
+	lots, err := orderLotPlacesTx(tx, orderID)
+	if err != nil {
+		return err
+	}
+	for _, lp := range lots {
+		if err := DecrementLotAvailableTx(tx, lp.lotID, lp.places); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`UPDATE orders SET inventory_reserved = 1 WHERE id = ?`, orderID)
+	return err
+}
+
+// releaseOrderInventoryTx gives back the places orderID reserved, if it holds
+// any, and reports whether it did.
+func releaseOrderInventoryTx(tx *sql.Tx, orderID string) (bool, error) {
+	res, err := tx.Exec(`UPDATE orders SET inventory_reserved = 0 WHERE id = ? AND inventory_reserved = 1`, orderID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	lots, err := orderLotPlacesTx(tx, orderID)
+	if err != nil {
+		return false, err
+	}
+	for _, lp := range lots {
+		if _, err := tx.Exec(`UPDATE lots SET available_quantity = available_quantity + ? WHERE id = ?`, lp.places, lp.lotID); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// OrderInventoryReserved reports whether orderID holds the lot places of its
+// items.
+func OrderInventoryReserved(db *sql.DB, orderID string) (bool, error) {
+	return orderInventoryReserved(db, orderID)
+}
+
+// OrderInventoryReservedTx is OrderInventoryReserved within a transaction.
+func OrderInventoryReservedTx(tx *sql.Tx, orderID string) (bool, error) {
+	return orderInventoryReserved(tx, orderID)
+}
+
+func orderInventoryReserved(q queryRower, orderID string) (bool, error) {
+	var reserved bool
+	err := q.QueryRow(`SELECT inventory_reserved FROM orders WHERE id = ?`, orderID).Scan(&reserved)
+	if err == sql.ErrNoRows {
+		return false, ErrOrderNotFound
+	}
+	return reserved, err
+}
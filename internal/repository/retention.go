@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+const abandonedOrdersWhere = ` FROM orders o WHERE o.status = 'PENDING' AND o.created_at < ?
+	AND NOT EXISTS (SELECT 1 FROM tickets t WHERE t.order_id = o.id)`
+
+// PurgeAbandonedOrders deletes PENDING orders created before cutoff, with
+// their items, gives back the lot places they reserved and returns how many
+// there were. Orders that issued tickets are
+// never touched. With dryRun it only counts.
+func PurgeAbandonedOrders(db *sql.DB, cutoff time.Time, dryRun bool) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*)`+abandonedOrdersWhere, FormatTime(cutoff)).Scan(&n); err != nil {
+		return 0, err
+	}
+	if dryRun || n == 0 {
+		return n, nil
+	}
+	ids, err := queryIDs(tx, `SELECT o.id`+abandonedOrdersWhere+` AND o.inventory_reserved = 1`, FormatTime(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if _, err := releaseOrderInventoryTx(tx, id); err != nil {
+			return 0, err
+		}
+	}
+	// order_items and the status history go with the order (ON DELETE CASCADE)
+	if _, err := tx.Exec(`DELETE FROM orders WHERE id IN (SELECT o.id`+abandonedOrdersWhere+`)`, FormatTime(cutoff)); err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// inactiveUsersWhere selects accounts created before the cutoff that placed no
+// order since, hold no ticket for a date from today on, and aren't producers
+// or admins (their records back payouts and audits).
+const inactiveUsersWhere = ` FROM users u WHERE u.anonymized_at IS NULL AND u.role IN ('USER', 'GUEST')
+	AND u.created_at < ?1
+	AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.user_id = u.id AND o.created_at >= ?1)
+	AND NOT EXISTS (SELECT 1 FROM tickets t JOIN event_dates ed ON ed.id = t.event_date_id
+		WHERE t.user_id = u.id AND ed.date >= ?2)
+	AND NOT EXISTS (SELECT 1 FROM producers p WHERE p.user_id = u.id)`
+
+// AnonymizeInactiveUsers replaces the name, email, CPF, phone, photo, birth
+// date and password of inactive accounts (see inactiveUsersWhere) with
+// placeholders and deletes their saved addresses, keeping the row so orders
+// and tickets stay consistent for financial reports. Returns how many
+// accounts qualified; dryRun only counts.
+func AnonymizeInactiveUsers(db *sql.DB, cutoff, now time.Time, dryRun bool) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	args := []interface{}{FormatTime(cutoff), now.UTC().Format("2006-01-02")}
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*)`+inactiveUsersWhere, args...).Scan(&n); err != nil {
+		return 0, err
+	}
+	if dryRun || n == 0 {
+		return n, nil
+	}
+	if _, err := tx.Exec(`DELETE FROM user_addresses WHERE user_id IN (SELECT u.id`+inactiveUsersWhere+`)`, args...); err != nil {
+		return 0, err
+	}
+	// email and cpf are UNIQUE, so the placeholders embed the user id
+	if _, err := tx.Exec(`UPDATE users SET
+		name = 'Usuário anonimizado',
+		email = 'anonimizado+' || id || '@anonimizado.invalid',
+		cpf = 'anon-' || id,
+		password_hash = '',
+		birth_date = '1900-01-01',
+		photo_url = NULL,
+		phone_country_code = NULL,
+		phone_area_code = NULL,
+		phone_number = NULL,
+		anonymized_at = `+nowSQL+`
+		WHERE id IN (SELECT u.id`+inactiveUsersWhere+`)`, args...); err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// Reasons recorded in the status history of orders cancelled by the
+// order-expiry job.
+const (
+	CancelReasonAbandoned  = "abandoned"   // no PIX was ever generated
+	CancelReasonPixExpired = "pix_expired" // the PIX expired unpaid
+)
+
+// AbandonedOrderIDs returns PENDING orders created before cutoff that never
+// got a Pagar.me order, i.e. the buyer left before reaching payment.
+func AbandonedOrderIDs(db *sql.DB, cutoff time.Time) ([]string, error) {
+	return queryIDs(db, `SELECT id FROM orders WHERE status = 'PENDING' AND COALESCE(pagarme_order_id, '') = '' AND created_at < ?`, FormatTime(cutoff))
+}
+
+// PixExpiredOrderIDs returns PENDING orders with a Pagar.me order whose
+// current PIX expired (pix_expires_at) before cutoff.
+func PixExpiredOrderIDs(db *sql.DB, cutoff time.Time) ([]string, error) {
+	return queryIDs(db, `SELECT id FROM orders WHERE status = 'PENDING' AND COALESCE(pagarme_order_id, '') <> '' AND pix_expires_at < ?`, FormatTime(cutoff))
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// queryIDs runs a query selecting a single text column.
+func queryIDs(db querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,31 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestAnonymizeKeepsUsersWithUpcomingTickets(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	orderID := f.PendingOrder(t, sqlite, 1)
+	buyerID := f.BuyerID
+	items, _ := repository.OrderItemsByOrderID(sqlite, orderID)
+	evDate, _ := repository.EventDateByID(sqlite, items[0].EventDateID)
+	if err := repository.CreateTicketWithID(sqlite, "t1", "CODE0001", "qr1", orderID, items[0].ID, buyerID, evDate.EventID, evDate.ID, items[0].TicketTypeID, repository.TicketSourcePurchase); err != nil {
+		t.Fatal(err)
+	}
+	sqlite.Exec(`UPDATE users SET created_at = '2020-01-01 00:00:00'`)
+	sqlite.Exec(`UPDATE orders SET created_at = '2020-01-01 00:00:00', status = 'PAID'`)
+
+	n, err := repository.AnonymizeInactiveUsers(sqlite, time.Now().AddDate(-1, 0, 0), time.Now(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("anonymized %d users holding a ticket for 2099", n)
+	}
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PlatformSales are platform-wide figures over a period, for the admin
+// dashboard. Amounts are in centavos.
+type PlatformSales struct {
+	GMV             int64 // charged on paid orders
+	PlatformFees    int64 // platform fee on those orders
+	PaidOrders      int
+	TicketsSold     int
+	ActiveProducers int // producers with at least one paid order
+	PublishedEvents int // published now, whatever the period
+	OrdersByStatus  []StatusCount
+}
+
+// paidOrderStatuses are the order statuses counted as paid in aggregates:
+// PAID, and CONFIRMED, which the payment status endpoints also treat as paid.
+const paidOrderStatuses = `('PAID', 'CONFIRMED')`
+
+// StatusCount is the number of orders in a status.
+type StatusCount struct {
+	Status string
+	Orders int
+}
+
+// PlatformStats aggregates the platform's sales between from (inclusive) and
+// to (exclusive). Revenue, tickets and active producers only count paid
+// orders (see paidOrderStatuses) confirmed in the period (refunded or
+// cancelled ones drop out); orders by status count every order created in
+// it. Complimentary orders (total 0, never confirmed) are not sales and are
+// left out of both. The platform fee is feePerTicket per ticket, capped at
+// the order total, at the current rate as in the payout reconciliation.
+func PlatformStats(db *sql.DB, from, to time.Time, feePerTicket int64) (*PlatformSales, error) {
+	fromStr, toStr := FormatTime(from), FormatTime(to)
+	s := &PlatformSales{OrdersByStatus: []StatusCount{}}
+
+	err := db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(amount), 0), COALESCE(SUM(MIN(? * tickets, amount)), 0), COALESCE(SUM(tickets), 0)
+		FROM (
+			SELECT CAST(ROUND(o.total * 100) AS INTEGER) AS amount,
+				(SELECT COALESCE(SUM(oi.quantity), 0) FROM order_items oi WHERE oi.order_id = o.id) AS tickets
+			FROM orders o
+			WHERE o.confirmed_at >= ? AND o.confirmed_at < ? AND o.status IN `+paidOrderStatuses+` AND o.total > 0
+		)`, feePerTicket, fromStr, toStr,
+	).Scan(&s.PaidOrders, &s.GMV, &s.PlatformFees, &s.TicketsSold)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.QueryRow(`SELECT COUNT(DISTINCT e.producer_id)
+		FROM orders o
+		JOIN order_items oi ON oi.order_id = o.id
+		JOIN event_dates ed ON ed.id = oi.event_date_id
+		JOIN events e ON e.id = ed.event_id
+		WHERE o.confirmed_at >= ? AND o.confirmed_at < ? AND o.status IN `+paidOrderStatuses+` AND o.total > 0`, fromStr, toStr,
+	).Scan(&s.ActiveProducers)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.QueryRow(`SELECT COUNT(*) FROM events WHERE status = 'PUBLISHED'`).Scan(&s.PublishedEvents); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT status, COUNT(*) FROM orders
+		WHERE created_at >= ? AND created_at < ? AND total > 0
+		GROUP BY status ORDER BY status`, fromStr, toStr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c StatusCount
+		if err := rows.Scan(&c.Status, &c.Orders); err != nil {
+			return nil, err
+		}
+		s.OrdersByStatus = append(s.OrdersByStatus, c)
+	}
+	return s, rows.Err()
+}
@@ -2,39 +2,59 @@ package repository
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Ticket sources (tickets.source): how a ticket was issued. A transfer keeps
+// it; the holders are in ticket_transfers.
+const (
+	TicketSourcePurchase = "PURCHASE" // paid order
+	TicketSourceComp     = "COMP"     // complimentary, issued by the producer
+)
+
 type TicketRow struct {
-	ID            string
-	Code          string
-	QRCode        string
-	OrderID       string
-	OrderItemID   string
-	UserID        string
-	EventID       string
-	EventDateID   string
-	TicketTypeID  string
-	Used          int
-	UsedAt        sql.NullString
-	CreatedAt     time.Time
-}
-
-func parseDateTime(s string) time.Time {
-	t, err := time.Parse("2006-01-02 15:04:05", s)
+	ID           string
+	Code         string
+	QRCode       string
+	OrderID      string
+	OrderItemID  string
+	UserID       string
+	EventID      string
+	EventDateID  string
+	TicketTypeID string
+	// Admits is how many people the ticket lets in; AdmitsRemaining counts
+	// down per check-in and reaches 0 when Used is set
+	Admits          int
+	AdmitsRemaining int
+	Source          string // TicketSourcePurchase or TicketSourceComp; a transfer keeps it
+	Used            int
+	UsedAt          sql.NullString
+	CreatedAt       time.Time
+}
+
+func TicketsByUserID(db *sql.DB, userID string) ([]*TicketRow, error) {
+	rows, err := db.Query(`SELECT id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, admits, admits_remaining, source, used, used_at, created_at FROM tickets WHERE user_id = ? ORDER BY created_at DESC`, userID)
 	if err != nil {
-		t, _ = time.Parse(time.RFC3339, s)
+		return nil, err
 	}
-	if t.IsZero() {
-		return time.Now()
+	defer rows.Close()
+	var list []*TicketRow
+	for rows.Next() {
+		t, err := scanTicketRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, t)
 	}
-	return t
+	return list, rows.Err()
 }
 
-func TicketsByUserID(db *sql.DB, userID string) ([]*TicketRow, error) {
-	rows, err := db.Query(`SELECT id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, used, used_at, created_at FROM tickets WHERE user_id = ? ORDER BY created_at DESC`, userID)
+// TicketsByOrderID returns all tickets issued for an order.
+func TicketsByOrderID(db *sql.DB, orderID string) ([]*TicketRow, error) {
+	rows, err := db.Query(`SELECT id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, admits, admits_remaining, source, used, used_at, created_at FROM tickets WHERE order_id = ? ORDER BY created_at`, orderID)
 	if err != nil {
 		return nil, err
 	}
@@ -55,7 +75,7 @@ func scanTicketRow(rows interface {
 }) (*TicketRow, error) {
 	var t TicketRow
 	var usedAt, createdAt sql.NullString
-	err := rows.Scan(&t.ID, &t.Code, &t.QRCode, &t.OrderID, &t.OrderItemID, &t.UserID, &t.EventID, &t.EventDateID, &t.TicketTypeID, &t.Used, &usedAt, &createdAt)
+	err := rows.Scan(&t.ID, &t.Code, &t.QRCode, &t.OrderID, &t.OrderItemID, &t.UserID, &t.EventID, &t.EventDateID, &t.TicketTypeID, &t.Admits, &t.AdmitsRemaining, &t.Source, &t.Used, &usedAt, &createdAt)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +83,7 @@ func scanTicketRow(rows interface {
 		t.UsedAt = usedAt
 	}
 	if createdAt.Valid {
-		t.CreatedAt = parseDateTime(createdAt.String)
+		t.CreatedAt = parseTimestamp(createdAt.String)
 	}
 	return &t, nil
 }
@@ -71,8 +91,8 @@ func scanTicketRow(rows interface {
 func TicketByID(db *sql.DB, id string) (*TicketRow, error) {
 	var t TicketRow
 	var usedAt, createdAt sql.NullString
-	err := db.QueryRow(`SELECT id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, used, COALESCE(used_at,'') as used_at, created_at FROM tickets WHERE id = ?`, id).Scan(
-		&t.ID, &t.Code, &t.QRCode, &t.OrderID, &t.OrderItemID, &t.UserID, &t.EventID, &t.EventDateID, &t.TicketTypeID, &t.Used, &usedAt, &createdAt,
+	err := db.QueryRow(`SELECT id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, admits, admits_remaining, source, used, COALESCE(used_at,'') as used_at, created_at FROM tickets WHERE id = ?`, id).Scan(
+		&t.ID, &t.Code, &t.QRCode, &t.OrderID, &t.OrderItemID, &t.UserID, &t.EventID, &t.EventDateID, &t.TicketTypeID, &t.Admits, &t.AdmitsRemaining, &t.Source, &t.Used, &usedAt, &createdAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -84,7 +104,7 @@ func TicketByID(db *sql.DB, id string) (*TicketRow, error) {
 		t.UsedAt = usedAt
 	}
 	if createdAt.Valid {
-		t.CreatedAt = parseDateTime(createdAt.String)
+		t.CreatedAt = parseTimestamp(createdAt.String)
 	}
 	return &t, nil
 }
@@ -92,8 +112,30 @@ func TicketByID(db *sql.DB, id string) (*TicketRow, error) {
 func TicketByQRCode(db *sql.DB, qrCode string) (*TicketRow, error) {
 	var t TicketRow
 	var usedAt, createdAt sql.NullString
-	err := db.QueryRow(`SELECT id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, used, used_at, created_at FROM tickets WHERE qr_code = ?`, qrCode).Scan(
-		&t.ID, &t.Code, &t.QRCode, &t.OrderID, &t.OrderItemID, &t.UserID, &t.EventID, &t.EventDateID, &t.TicketTypeID, &t.Used, &usedAt, &createdAt,
+	err := db.QueryRow(`SELECT id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, admits, admits_remaining, source, used, used_at, created_at FROM tickets WHERE qr_code = ?`, qrCode).Scan(
+		&t.ID, &t.Code, &t.QRCode, &t.OrderID, &t.OrderItemID, &t.UserID, &t.EventID, &t.EventDateID, &t.TicketTypeID, &t.Admits, &t.AdmitsRemaining, &t.Source, &t.Used, &usedAt, &createdAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if usedAt.Valid {
+		t.UsedAt = usedAt
+	}
+	if createdAt.Valid {
+		t.CreatedAt = parseTimestamp(createdAt.String)
+	}
+	return &t, nil
+}
+
+// TicketByCode looks up a ticket by its short human-readable code.
+func TicketByCode(db *sql.DB, code string) (*TicketRow, error) {
+	var t TicketRow
+	var usedAt, createdAt sql.NullString
+	err := db.QueryRow(`SELECT id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, admits, admits_remaining, source, used, used_at, created_at FROM tickets WHERE code = ?`, code).Scan(
+		&t.ID, &t.Code, &t.QRCode, &t.OrderID, &t.OrderItemID, &t.UserID, &t.EventID, &t.EventDateID, &t.TicketTypeID, &t.Admits, &t.AdmitsRemaining, &t.Source, &t.Used, &usedAt, &createdAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -105,21 +147,29 @@ func TicketByQRCode(db *sql.DB, qrCode string) (*TicketRow, error) {
 		t.UsedAt = usedAt
 	}
 	if createdAt.Valid {
-		t.CreatedAt = parseDateTime(createdAt.String)
+		t.CreatedAt = parseTimestamp(createdAt.String)
 	}
 	return &t, nil
 }
 
 func MarkTicketUsed(db *sql.DB, id string) error {
-	_, err := db.Exec(`UPDATE tickets SET used = 1, used_at = datetime('now') WHERE id = ?`, id)
+	_, err := db.Exec(`UPDATE tickets SET used = 1, admits_remaining = 0, used_at = `+nowSQL+` WHERE id = ?`, id)
 	return err
 }
 
-// MarkTicketUsedIfNotUsed marks the ticket as used only if it is not already used.
+// admitTicketSet consumes one entry: used flips to 1 with the last admit and
+// used_at keeps the first entry's time.
+const admitTicketSet = `admits_remaining = admits_remaining - 1,
+	used = CASE WHEN admits_remaining <= 1 THEN 1 ELSE 0 END,
+	used_at = COALESCE(used_at, ?)`
+
+// MarkTicketUsedIfNotUsed consumes one admit of the ticket if any is left;
+// a regular ticket has a single admit, so this marks it used.
 // Returns true if the row was updated (exactly one row), false if already used or not found.
-// Used for concurrent-safe validation: only one request can succeed.
+// Used for concurrent-safe validation: only one request can take each admit.
 func MarkTicketUsedIfNotUsed(db *sql.DB, id string) (updated bool, err error) {
-	res, err := db.Exec(`UPDATE tickets SET used = 1, used_at = datetime('now') WHERE id = ? AND used = 0`, id)
+	res, err := db.Exec(`UPDATE tickets SET `+admitTicketSet+` WHERE id = ? AND used = 0 AND admits_remaining > 0`,
+		FormatTime(time.Now()), id)
 	if err != nil {
 		return false, err
 	}
@@ -129,12 +179,84 @@ func MarkTicketUsedIfNotUsed(db *sql.DB, id string) (updated bool, err error) {
 
 func InsertTicketValidation(db *sql.DB, ticketID, eventID, producerID string) error {
 	id := uuid.New().String()
-	_, err := db.Exec(`INSERT INTO ticket_validations (id, ticket_id, event_id, producer_id) VALUES (?, ?, ?, ?)`,
+	_, err := db.Exec(`INSERT INTO ticket_validations (id, ticket_id, event_id, producer_id, validated_at, created_at) VALUES (?, ?, ?, ?, `+nowSQL+`, `+nowSQL+`)`,
 		id, ticketID, eventID, producerID,
 	)
 	return err
 }
 
+func TicketByCodeTx(tx *sql.Tx, code string) (*TicketRow, error) {
+	var t TicketRow
+	var usedAt, createdAt sql.NullString
+	err := tx.QueryRow(`SELECT id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, admits, admits_remaining, source, used, used_at, created_at FROM tickets WHERE code = ?`, code).Scan(
+		&t.ID, &t.Code, &t.QRCode, &t.OrderID, &t.OrderItemID, &t.UserID, &t.EventID, &t.EventDateID, &t.TicketTypeID, &t.Admits, &t.AdmitsRemaining, &t.Source, &t.Used, &usedAt, &createdAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if usedAt.Valid {
+		t.UsedAt = usedAt
+	}
+	if createdAt.Valid {
+		t.CreatedAt = parseTimestamp(createdAt.String)
+	}
+	return &t, nil
+}
+
+// MarkTicketUsedAtTx consumes one admit of the ticket at the given (scan) time if any is left.
+func MarkTicketUsedAtTx(tx *sql.Tx, id string, usedAt time.Time) (updated bool, err error) {
+	res, err := tx.Exec(`UPDATE tickets SET `+admitTicketSet+` WHERE id = ? AND used = 0 AND admits_remaining > 0`,
+		FormatTime(usedAt), id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// KeepEarliestTicketUseTx moves used_at back to usedAt when an offline scan predates the recorded one.
+func KeepEarliestTicketUseTx(tx *sql.Tx, id string, usedAt time.Time) error {
+	ts := FormatTime(usedAt)
+	_, err := tx.Exec(`UPDATE tickets SET used_at = ? WHERE id = ? AND (used = 1 OR admits_remaining < admits) AND (used_at IS NULL OR used_at > ?)`, ts, id, ts)
+	return err
+}
+
+// TicketValidatedAtTx reports whether the ticket already has a validation
+// recorded at exactly validatedAt, i.e. the same offline scan sent again.
+func TicketValidatedAtTx(tx *sql.Tx, ticketID string, validatedAt time.Time) (bool, error) {
+	var n int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM ticket_validations WHERE ticket_id = ? AND validated_at = ?`,
+		ticketID, FormatTime(validatedAt)).Scan(&n)
+	return n > 0, err
+}
+
+func InsertTicketValidationTx(tx *sql.Tx, ticketID, eventID, producerID string, validatedAt time.Time) error {
+	id := uuid.New().String()
+	_, err := tx.Exec(`INSERT INTO ticket_validations (id, ticket_id, event_id, producer_id, validated_at, created_at) VALUES (?, ?, ?, ?, ?, `+nowSQL+`)`,
+		id, ticketID, eventID, producerID, FormatTime(validatedAt),
+	)
+	return err
+}
+
+// CountTicketResendsSince returns how many resend emails were sent for an order after since.
+func CountTicketResendsSince(db *sql.DB, orderID string, since time.Time) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM ticket_resends WHERE order_id = ? AND created_at >= ?`,
+		orderID, FormatTime(since),
+	).Scan(&n)
+	return n, err
+}
+
+// InsertTicketResend records a resend email for rate limiting.
+func InsertTicketResend(db *sql.DB, orderID, userID string) error {
+	id := uuid.New().String()
+	_, err := db.Exec(`INSERT INTO ticket_resends (id, order_id, user_id, created_at) VALUES (?, ?, ?, `+nowSQL+`)`, id, orderID, userID)
+	return err
+}
+
 func GenerateTicketCode() string {
 	return uuid.New().String()[:8]
 }
@@ -142,3 +264,84 @@ func GenerateTicketCode() string {
 func GenerateQRCode() string {
 	return uuid.New().String()
 }
+
+// StreamTicketsByEvent calls fn for each ticket of an event, reading rows one at
+// a time instead of loading them into a slice. Iteration stops at the first
+// error returned by fn. fn must not query db: the SQLite pool has a single
+// connection, which is held until iteration ends.
+func StreamTicketsByEvent(db *sql.DB, eventID string, fn func(TicketRow) error) error {
+	rows, err := db.Query(`SELECT id, code, qr_code, order_id, order_item_id, user_id, event_id, event_date_id, ticket_type_id, admits, admits_remaining, source, used, used_at, created_at FROM tickets WHERE event_id = ? ORDER BY created_at, id`, eventID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		t, err := scanTicketRow(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(*t); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// TicketFilter narrows TicketsByEvent. Used nil means used and unused tickets;
+// Source keeps one ticket source; Search matches a code prefix or the holder's name or email.
+type TicketFilter struct {
+	Used   *bool
+	Source string // empty means every source
+	Search string
+}
+
+// TicketsByEvent returns a page of an event's tickets, newest first, and the
+// total number of tickets matching the filter.
+func TicketsByEvent(db *sql.DB, eventID string, limit, offset int, filter TicketFilter) ([]*TicketRow, int, error) {
+	where := ` WHERE t.event_id = ?`
+	args := []interface{}{eventID}
+	if filter.Used != nil {
+		where += ` AND t.used = ?`
+		if *filter.Used {
+			args = append(args, 1)
+		} else {
+			args = append(args, 0)
+		}
+	}
+	if filter.Source != "" {
+		where += ` AND t.source = ?`
+		args = append(args, filter.Source)
+	}
+	if s := strings.TrimSpace(filter.Search); s != "" {
+		like := "%" + escapeLike(strings.ToLower(s)) + "%"
+		where += ` AND (t.code LIKE ? ESCAPE '\' OR LOWER(u.name) LIKE ? ESCAPE '\' OR LOWER(u.email) LIKE ? ESCAPE '\')`
+		args = append(args, escapeLike(strings.ToLower(s))+"%", like, like)
+	}
+	from := ` FROM tickets t JOIN users u ON u.id = t.user_id`
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*)`+from+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(`SELECT t.id, t.code, t.qr_code, t.order_id, t.order_item_id, t.user_id, t.event_id, t.event_date_id, t.ticket_type_id, t.admits, t.admits_remaining, t.source, t.used, t.used_at, t.created_at`+
+		from+where+` ORDER BY t.created_at DESC, t.id LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var list []*TicketRow
+	for rows.Next() {
+		t, err := scanTicketRow(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, t)
+	}
+	return list, total, rows.Err()
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
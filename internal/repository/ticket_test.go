@@ -0,0 +1,50 @@
+package repository_test
+
+import (
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/qrcode"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestTicketsByEventPagination(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	orderID := f.PaidOrder(t, sqlite, 5, qrcode.NewKeyRing("test-secret"))
+
+	tickets, _ := repository.TicketsByOrderID(sqlite, orderID)
+	eventID := tickets[0].EventID
+	if _, err := repository.MarkTicketUsedIfNotUsed(sqlite, tickets[0].ID); err != nil {
+		t.Fatalf("mark used: %v", err)
+	}
+
+	page, total, err := repository.TicketsByEvent(sqlite, eventID, 2, 0, repository.TicketFilter{})
+	if err != nil || len(page) != 2 || total != 5 {
+		t.Fatalf("page 1 = %d items, total %d (%v), want 2/5", len(page), total, err)
+	}
+	last, _, _ := repository.TicketsByEvent(sqlite, eventID, 2, 4, repository.TicketFilter{})
+	if len(last) != 1 {
+		t.Errorf("last page = %d items, want 1", len(last))
+	}
+
+	used, unused := true, false
+	if _, total, _ := repository.TicketsByEvent(sqlite, eventID, 10, 0, repository.TicketFilter{Used: &used}); total != 1 {
+		t.Errorf("used total = %d, want 1", total)
+	}
+	if _, total, _ := repository.TicketsByEvent(sqlite, eventID, 10, 0, repository.TicketFilter{Used: &unused}); total != 4 {
+		t.Errorf("unused total = %d, want 4", total)
+	}
+
+	byCode, total, _ := repository.TicketsByEvent(sqlite, eventID, 10, 0, repository.TicketFilter{Search: strings.ToUpper(tickets[2].Code)})
+	if total != 1 || byCode[0].ID != tickets[2].ID {
+		t.Errorf("search by code: total = %d", total)
+	}
+	if _, total, _ := repository.TicketsByEvent(sqlite, eventID, 10, 0, repository.TicketFilter{Search: "comprador@"}); total != 5 {
+		t.Errorf("search by email: total = %d, want 5", total)
+	}
+	if _, total, _ := repository.TicketsByEvent(sqlite, eventID, 10, 0, repository.TicketFilter{Search: "%"}); total != 0 {
+		t.Errorf("search %% matched %d tickets, want literal match", total)
+	}
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+// TicketTransfer is one step of a ticket's chain of custody.
+type TicketTransfer struct {
+	ID         string
+	TicketID   string
+	FromUserID string
+	ToUserID   string
+	CreatedAt  string
+}
+
+// EventTransferPolicy returns whether an event's tickets may be transferred
+// and its override of the transfers allowed per ticket (0 when unset).
+func EventTransferPolicy(db *sql.DB, eventID string) (transferable bool, maxTransfers int, err error) {
+	var max sql.NullInt64
+	err = db.QueryRow(`SELECT transferable, max_ticket_transfers FROM events WHERE id = ?`, eventID).Scan(&transferable, &max)
+	if err == sql.ErrNoRows {
+		return false, 0, nil
+	}
+	return transferable, int(max.Int64), err
+}
+
+// SetEventTransferable allows or forbids transferring the event's tickets.
+func SetEventTransferable(db *sql.DB, eventID string, transferable bool) error {
+	_, err := db.Exec(`UPDATE events SET transferable = ?, updated_at = `+nowSQL+` WHERE id = ?`, transferable, eventID)
+	return err
+}
+
+// SetEventMaxTicketTransfers sets (or clears, with 0) the event's override of
+// the transfers allowed per ticket.
+func SetEventMaxTicketTransfers(db *sql.DB, eventID string, max int) error {
+	var v interface{}
+	if max > 0 {
+		v = max
+	}
+	_, err := db.Exec(`UPDATE events SET max_ticket_transfers = ?, updated_at = `+nowSQL+` WHERE id = ?`, v, eventID)
+	return err
+}
+
+// CountTicketTransfers returns how many times a ticket has been transferred.
+func CountTicketTransfers(db *sql.DB, ticketID string) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM ticket_transfers WHERE ticket_id = ?`, ticketID).Scan(&n)
+	return n, err
+}
+
+// TransferTicketTx moves a ticket from fromUserID to toUserID and records
+// the transfer in ticket_transfers, within the caller's transaction. The
+// ticket keeps its source and gets the new code and qrCode, so what the
+// previous holder kept no longer admits anyone. The move only happens while
+// fromUserID holds the ticket, no entry has been used and, when
+// maxTransfers > 0, the ticket has been transferred fewer times than that;
+// otherwise it returns false, so two concurrent transfers (or a check-in
+// racing a transfer) can't both win.
+func TransferTicketTx(tx *sql.Tx, ticketID, fromUserID, toUserID, code, qrCode string, maxTransfers int) (bool, error) {
+	res, err := tx.Exec(`UPDATE tickets SET user_id = ?, code = ?, qr_code = ?
+		WHERE id = ? AND user_id = ? AND used = 0 AND admits_remaining = admits
+			AND (? <= 0 OR (SELECT COUNT(*) FROM ticket_transfers WHERE ticket_id = tickets.id) < ?)`,
+		toUserID, code, qrCode, ticketID, fromUserID, maxTransfers, maxTransfers)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(`INSERT INTO ticket_transfers (id, ticket_id, from_user_id, to_user_id, created_at)
+		VALUES (?, ?, ?, ?, `+nowSQL+`)`,
+		uuid.New().String(), ticketID, fromUserID, toUserID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// TicketTransfers returns a ticket's transfers, oldest first.
+func TicketTransfers(db *sql.DB, ticketID string) ([]TicketTransfer, error) {
+	rows, err := db.Query(`SELECT id, ticket_id, from_user_id, to_user_id, created_at FROM ticket_transfers
+		WHERE ticket_id = ? ORDER BY created_at, rowid`, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []TicketTransfer
+	for rows.Next() {
+		var tr TicketTransfer
+		if err := rows.Scan(&tr.ID, &tr.TicketID, &tr.FromUserID, &tr.ToUserID, &tr.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, tr)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"afterzin/api/internal/money"
+)
+
+// Errors returned by UpdateTicketType for changes that would break a live event.
+var (
+	ErrTicketTypeInvalidPrice = errors.New("preço deve ser maior que zero")
+	ErrTicketTypeBelowSold    = errors.New("quantidade máxima não pode ser menor que a quantidade já vendida")
+)
+
+// UpdateTicketType changes a ticket type's name, price and/or max quantity
+// (nil leaves a field as is) and records the change in audit_log as
+// "ticket_type.update". Orders already created keep the price captured on
+// order_items.unit_price. Returns nil if the ticket type doesn't exist.
+func UpdateTicketType(db *sql.DB, id string, name *string, price *float64, maxQuantity *int, actorUserID string) (*TicketTypeRow, error) {
+	if price != nil && *price <= 0 {
+		return nil, ErrTicketTypeInvalidPrice
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := TicketTypeByIDTx(tx, id)
+	if err != nil || cur == nil {
+		return nil, err
+	}
+	next := *cur
+	var changes []string
+	if name != nil && *name != cur.Name {
+		next.Name = *name
+		changes = append(changes, fmt.Sprintf("name: %q → %q", cur.Name, next.Name))
+	}
+	if price != nil && *price != cur.Price {
+		next.Price = *price
+		changes = append(changes, fmt.Sprintf("price: %s → %s", money.FormatReais(cur.Price), money.FormatReais(next.Price)))
+	}
+	if maxQuantity != nil && *maxQuantity != cur.MaxQuantity {
+		next.MaxQuantity = *maxQuantity
+		changes = append(changes, fmt.Sprintf("max_quantity: %d → %d", cur.MaxQuantity, next.MaxQuantity))
+	}
+	if len(changes) == 0 {
+		return cur, nil
+	}
+
+	// The sold_quantity guard is part of the UPDATE so a sale confirmed
+	// concurrently can't slip under the new maximum
+	res, err := tx.Exec(`UPDATE ticket_types SET name = ?, price = ?, max_quantity = ? WHERE id = ? AND sold_quantity <= ?`,
+		next.Name, next.Price, next.MaxQuantity, id, next.MaxQuantity)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return nil, ErrTicketTypeBelowSold
+	}
+	if err := InsertAuditLogTx(tx, actorUserID, "ticket_type.update", "ticket_type", id, strings.Join(changes, "; ")); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"time"
+
+	"afterzin/api/internal/logger"
+)
+
+// Timestamps are stored as UTC RFC3339 text ("2026-01-02T15:04:05Z"), so they
+// compare and sort correctly as strings whatever the server's time zone.
+// Inserts set them with nowSQL or FormatTime; the older column defaults still
+// use SQLite's datetime('now') ("2026-01-02 15:04:05"), which ParseTime reads
+// too, but the code never relies on them.
+
+// nowSQL is the current time in the stored format, for use inside SQL.
+const nowSQL = `strftime('%Y-%m-%dT%H:%M:%SZ', 'now')`
+
+// legacyTimeLayout is the format of SQLite's datetime('now'), always UTC.
+const legacyTimeLayout = "2006-01-02 15:04:05"
+
+// FormatTime formats t in the stored timestamp format (UTC RFC3339).
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ParseTime parses a stored timestamp. The datetime('now') format is still
+// accepted, as UTC, for values that predate migration 0028.
+func ParseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse(legacyTimeLayout, s)
+}
+
+// parseTimestamp is ParseTime for columns that are always set. An unreadable
+// value is logged and read as the zero time, rather than passed off as a
+// real moment.
+func parseTimestamp(s string) time.Time {
+	t, err := ParseTime(s)
+	if err != nil {
+		logger.Warnf("timestamp inválido no banco: %q: %v", s, err)
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,75 @@
+package repository_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestTimestampsRoundTripInAnyTimeZone(t *testing.T) {
+	local := time.Local
+	t.Cleanup(func() { time.Local = local })
+
+	zones := []*time.Location{
+		time.UTC,
+		time.FixedZone("BRT", -3*60*60),
+		time.FixedZone("JST", 9*60*60),
+		time.FixedZone("NPT", 5*60*60+45*60),
+	}
+	for _, zone := range zones {
+		t.Run(zone.String(), func(t *testing.T) {
+			time.Local = zone
+
+			// 23:30 in Brasília is already the next day in UTC.
+			at := time.Date(2026, 3, 1, 23, 30, 15, 0, zone)
+			s := repository.FormatTime(at)
+			if !strings.HasSuffix(s, "Z") {
+				t.Errorf("FormatTime = %q, want UTC", s)
+			}
+			back, err := repository.ParseTime(s)
+			if err != nil || !back.Equal(at) {
+				t.Errorf("ParseTime(%q) = %v, %v; want %v", s, back, err, at)
+			}
+			legacy, err := repository.ParseTime(at.UTC().Format("2006-01-02 15:04:05"))
+			if err != nil || !legacy.Equal(at) {
+				t.Errorf("legacy ParseTime = %v, %v; want %v", legacy, err, at)
+			}
+
+			sqlite := testutil.NewDB(t)
+			before := time.Now().Add(-time.Second).Truncate(time.Second)
+			userID, err := repository.CreateUser(sqlite, "Comprador", "comprador@email.com", "hash", "52998224725", "1990-01-01", nil, nil, nil)
+			if err != nil {
+				t.Fatalf("create user: %v", err)
+			}
+			after := time.Now().Add(time.Second)
+
+			// Inserts store RFC3339 in UTC.
+			var stored string
+			if err := sqlite.QueryRow(`SELECT created_at FROM users WHERE id = ?`, userID).Scan(&stored); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := time.Parse(time.RFC3339, stored); err != nil || !strings.HasSuffix(stored, "Z") {
+				t.Errorf("created_at = %q, want UTC RFC3339", stored)
+			}
+			user, err := repository.UserByID(sqlite, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.CreatedAt.Before(before) || user.CreatedAt.After(after) {
+				t.Errorf("CreatedAt = %v, want between %v and %v", user.CreatedAt, before, after)
+			}
+
+			// Values written from Go compare as strings with SQL's now.
+			orderID, err := repository.CreateOrder(sqlite, userID, 50, 30*time.Minute)
+			if err != nil {
+				t.Fatalf("create order: %v", err)
+			}
+			if got := countRows(t, sqlite, `SELECT COUNT(*) FROM orders WHERE id = '`+orderID+`' AND expires_at > strftime('%Y-%m-%dT%H:%M:%SZ', 'now') AND expires_at > created_at`); got != 1 {
+				t.Errorf("order %s expired on creation", orderID)
+			}
+		})
+	}
+}
@@ -22,17 +22,6 @@ type UserRow struct {
 	CreatedAt        time.Time
 }
 
-func parseCreatedAt(s string) time.Time {
-	t, err := time.Parse("2006-01-02 15:04:05", s)
-	if err != nil {
-		t, _ = time.Parse(time.RFC3339, s)
-	}
-	if t.IsZero() {
-		return time.Now()
-	}
-	return t
-}
-
 func UserByEmail(db *sql.DB, email string) (*UserRow, error) {
 	var u UserRow
 	var createdAt sql.NullString
@@ -52,7 +41,7 @@ func UserByEmail(db *sql.DB, email string) (*UserRow, error) {
 		return nil, err
 	}
 	if createdAt.Valid {
-		u.CreatedAt = parseCreatedAt(createdAt.String)
+		u.CreatedAt = parseTimestamp(createdAt.String)
 	}
 	return &u, nil
 }
@@ -76,7 +65,7 @@ func UserByID(db *sql.DB, id string) (*UserRow, error) {
 		return nil, err
 	}
 	if createdAt.Valid {
-		u.CreatedAt = parseCreatedAt(createdAt.String)
+		u.CreatedAt = parseTimestamp(createdAt.String)
 	}
 	return &u, nil
 }
@@ -86,14 +75,81 @@ func CreateUser(db *sql.DB, name, email, passwordHash, cpf, birthDate string, ph
 	_, err := db.Exec(`
 		INSERT INTO users (
 			id, name, email, password_hash, cpf, birth_date,
-			phone_country_code, phone_area_code, phone_number, role
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 'USER')`,
+			phone_country_code, phone_area_code, phone_number, role, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 'USER', `+nowSQL+`)`,
 		id, name, email, passwordHash, cpf, birthDate,
 		phoneCountryCode, phoneAreaCode, phoneNumber,
 	)
 	return id, err
 }
 
+// CreateGuestUser creates a passwordless GUEST user for guest checkout.
+// The account can later be claimed with ClaimGuestUser.
+func CreateGuestUser(db *sql.DB, name, email, cpf string, phoneCountryCode, phoneAreaCode, phoneNumber *string) (string, error) {
+	return createGuestUser(db, name, email, cpf, phoneCountryCode, phoneAreaCode, phoneNumber)
+}
+
+// CreateGuestUserTx is CreateGuestUser in the caller's transaction.
+func CreateGuestUserTx(tx *sql.Tx, name, email, cpf string, phoneCountryCode, phoneAreaCode, phoneNumber *string) (string, error) {
+	return createGuestUser(tx, name, email, cpf, phoneCountryCode, phoneAreaCode, phoneNumber)
+}
+
+func createGuestUser(ex execer, name, email, cpf string, phoneCountryCode, phoneAreaCode, phoneNumber *string) (string, error) {
+	id := uuid.New().String()
+	_, err := ex.Exec(`
+		INSERT INTO users (
+			id, name, email, password_hash, cpf, birth_date,
+			phone_country_code, phone_area_code, phone_number, role, created_at
+		) VALUES (?, ?, ?, '', ?, '', ?, ?, ?, 'GUEST', `+nowSQL+`)`,
+		id, name, email, cpf,
+		phoneCountryCode, phoneAreaCode, phoneNumber,
+	)
+	return id, err
+}
+
+// UserByCPF returns the user registered with the given (sanitized) CPF.
+func UserByCPF(db *sql.DB, cpf string) (*UserRow, error) {
+	var u UserRow
+	var createdAt sql.NullString
+	err := db.QueryRow(`
+		SELECT id, name, email, password_hash, cpf, birth_date,
+		       phone_country_code, phone_area_code, phone_number,
+		       photo_url, role, created_at
+		FROM users WHERE cpf = ?`, cpf).Scan(
+		&u.ID, &u.Name, &u.Email, &u.PasswordHash, &u.CPF, &u.BirthDate,
+		&u.PhoneCountryCode, &u.PhoneAreaCode, &u.PhoneNumber,
+		&u.PhotoURL, &u.Role, &createdAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if createdAt.Valid {
+		u.CreatedAt = parseTimestamp(createdAt.String)
+	}
+	return &u, nil
+}
+
+// ClaimGuestUser sets a password on a GUEST user and promotes it to USER.
+// Returns false if the user is not (or no longer) a guest.
+func ClaimGuestUser(db *sql.DB, userID, passwordHash string) (bool, error) {
+	res, err := db.Exec(`UPDATE users SET password_hash = ?, role = 'USER' WHERE id = ? AND role = 'GUEST'`, passwordHash, userID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// UpdateUserPasswordHash replaces a user's password hash (e.g. when a login
+// upgrades it to the current bcrypt cost).
+func UpdateUserPasswordHash(db *sql.DB, userID, passwordHash string) error {
+	_, err := db.Exec(`UPDATE users SET password_hash = ? WHERE id = ?`, passwordHash, userID)
+	return err
+}
+
 func UpdateUserPhotoURL(db *sql.DB, userID, photoURL string) error {
 	_, err := db.Exec(`UPDATE users SET photo_url = ? WHERE id = ?`, photoURL, userID)
 	return err
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// MaxUserAddresses caps the address book of a user.
+const MaxUserAddresses = 10
+
+// ErrAddressLimit is returned by CreateUserAddress when the user already has
+// MaxUserAddresses addresses.
+var ErrAddressLimit = errors.New("limite de endereços atingido")
+
+// UserAddress is a saved address of a user. The fields are stored as
+// validated by the caller (pagarme.Address.Validate).
+type UserAddress struct {
+	ID             string
+	UserID         string
+	Label          string
+	Street         string
+	StreetNumber   string
+	Complementary  string
+	Neighborhood   string
+	City           string
+	State          string
+	ZipCode        string
+	ReferencePoint string
+	IsDefault      bool
+	CreatedAt      string
+	UpdatedAt      string
+}
+
+const userAddressColumns = `id, user_id, label, street, street_number, complementary, neighborhood, city, state, zip_code, reference_point, is_default, created_at, updated_at`
+
+func scanUserAddress(s interface{ Scan(...interface{}) error }) (*UserAddress, error) {
+	var a UserAddress
+	var label, complementary, referencePoint sql.NullString
+	if err := s.Scan(&a.ID, &a.UserID, &label, &a.Street, &a.StreetNumber, &complementary, &a.Neighborhood,
+		&a.City, &a.State, &a.ZipCode, &referencePoint, &a.IsDefault, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return nil, err
+	}
+	a.Label, a.Complementary, a.ReferencePoint = label.String, complementary.String, referencePoint.String
+	return &a, nil
+}
+
+// UserAddresses returns the user's addresses, the default first and then
+// oldest first.
+func UserAddresses(db *sql.DB, userID string) ([]*UserAddress, error) {
+	rows, err := db.Query(`SELECT `+userAddressColumns+` FROM user_addresses WHERE user_id = ?
+		ORDER BY is_default DESC, created_at, rowid`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*UserAddress
+	for rows.Next() {
+		a, err := scanUserAddress(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+// UserAddressByID returns the user's address, or nil if it doesn't exist or
+// belongs to someone else.
+func UserAddressByID(db *sql.DB, id, userID string) (*UserAddress, error) {
+	a, err := scanUserAddress(db.QueryRow(`SELECT `+userAddressColumns+` FROM user_addresses WHERE id = ? AND user_id = ?`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return a, err
+}
+
+// DefaultUserAddress returns the user's default address, or nil if there is
+// none.
+func DefaultUserAddress(db *sql.DB, userID string) (*UserAddress, error) {
+	a, err := scanUserAddress(db.QueryRow(`SELECT `+userAddressColumns+` FROM user_addresses WHERE user_id = ? AND is_default = 1`, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return a, err
+}
+
+// CreateUserAddress saves a new address for a.UserID and returns its id. The
+// user's first address becomes the default, as does one with IsDefault set
+// (replacing the previous default). Returns ErrAddressLimit past
+// MaxUserAddresses.
+func CreateUserAddress(db *sql.DB, a UserAddress) (string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if a.IsDefault {
+		if err := clearDefaultAddressTx(tx, a.UserID); err != nil {
+			return "", err
+		}
+	}
+	// The cap and the first-address default are checked by the INSERT itself,
+	// so concurrent creations can't both pass a count read earlier.
+	id := uuid.New().String()
+	res, err := tx.Exec(`INSERT INTO user_addresses (id, user_id, label, street, street_number, complementary, neighborhood, city, state, zip_code, reference_point, is_default, created_at, updated_at)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? OR NOT EXISTS (SELECT 1 FROM user_addresses WHERE user_id = ?), `+nowSQL+`, `+nowSQL+`
+		WHERE (SELECT COUNT(*) FROM user_addresses WHERE user_id = ?) < ?`,
+		id, a.UserID, nullIfEmpty(a.Label), a.Street, a.StreetNumber, nullIfEmpty(a.Complementary), a.Neighborhood,
+		a.City, a.State, a.ZipCode, nullIfEmpty(a.ReferencePoint), a.IsDefault, a.UserID,
+		a.UserID, MaxUserAddresses,
+	)
+	if err != nil {
+		return "", err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "", ErrAddressLimit
+	}
+	return id, tx.Commit()
+}
+
+// UpdateUserAddress replaces the fields of the user's address a.ID. IsDefault
+// set makes it the default; unset leaves the default as it is (use
+// DeleteUserAddress or another address to move it). Returns false if the
+// address doesn't exist or belongs to someone else.
+func UpdateUserAddress(db *sql.DB, a UserAddress) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if a.IsDefault {
+		if err := clearDefaultAddressTx(tx, a.UserID); err != nil {
+			return false, err
+		}
+	}
+	res, err := tx.Exec(`UPDATE user_addresses SET label = ?, street = ?, street_number = ?, complementary = ?, neighborhood = ?,
+		city = ?, state = ?, zip_code = ?, reference_point = ?, is_default = MAX(is_default, ?), updated_at = `+nowSQL+`
+		WHERE id = ? AND user_id = ?`,
+		nullIfEmpty(a.Label), a.Street, a.StreetNumber, nullIfEmpty(a.Complementary), a.Neighborhood,
+		a.City, a.State, a.ZipCode, nullIfEmpty(a.ReferencePoint), a.IsDefault, a.ID, a.UserID,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	return true, tx.Commit()
+}
+
+// SetDefaultUserAddress makes the user's address id the default. Returns
+// false if it doesn't exist or belongs to someone else.
+func SetDefaultUserAddress(db *sql.DB, id, userID string) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := clearDefaultAddressTx(tx, userID); err != nil {
+		return false, err
+	}
+	res, err := tx.Exec(`UPDATE user_addresses SET is_default = 1, updated_at = `+nowSQL+` WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	return true, tx.Commit()
+}
+
+// DeleteUserAddress removes the user's address, reporting whether it was
+// there. Deleting the default leaves the user without one until another is
+// chosen.
+func DeleteUserAddress(db *sql.DB, id, userID string) (bool, error) {
+	res, err := db.Exec(`DELETE FROM user_addresses WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// clearDefaultAddressTx unsets the user's default address, so another can
+// take its place under the one-default-per-user index.
+func clearDefaultAddressTx(tx *sql.Tx, userID string) error {
+	_, err := tx.Exec(`UPDATE user_addresses SET is_default = 0 WHERE user_id = ? AND is_default = 1`, userID)
+	return err
+}
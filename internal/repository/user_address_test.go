@@ -0,0 +1,81 @@
+package repository_test
+
+import (
+	"errors"
+	"testing"
+
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestUserAddresses(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	addr := repository.UserAddress{UserID: f.BuyerID, Street: "Rua A", StreetNumber: "10", Neighborhood: "Centro", City: "São Paulo", State: "SP", ZipCode: "01001000"}
+
+	first, err := repository.CreateUserAddress(sqlite, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := repository.CreateUserAddress(sqlite, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def, _ := repository.DefaultUserAddress(sqlite, f.BuyerID); def == nil || def.ID != first {
+		t.Fatalf("default = %+v, want the first address", def)
+	}
+
+	if ok, err := repository.SetDefaultUserAddress(sqlite, second, f.BuyerID); err != nil || !ok {
+		t.Fatalf("SetDefaultUserAddress = %v, %v", ok, err)
+	}
+	list, _ := repository.UserAddresses(sqlite, f.BuyerID)
+	if len(list) != 2 || list[0].ID != second || !list[0].IsDefault || list[1].IsDefault {
+		t.Fatalf("addresses after moving the default: %+v", list)
+	}
+
+	// Other users can't see, change or delete the address
+	if a, _ := repository.UserAddressByID(sqlite, second, f.ProducerUserID); a != nil {
+		t.Error("address visible to another user")
+	}
+	if ok, _ := repository.SetDefaultUserAddress(sqlite, first, f.ProducerUserID); ok {
+		t.Error("another user set the default")
+	}
+	if def, _ := repository.DefaultUserAddress(sqlite, f.BuyerID); def == nil || def.ID != second {
+		t.Errorf("default changed by another user: %+v", def)
+	}
+
+	// Updating without isDefault keeps the default
+	addr.ID, addr.City = second, "Campinas"
+	if ok, err := repository.UpdateUserAddress(sqlite, addr); err != nil || !ok {
+		t.Fatalf("UpdateUserAddress = %v, %v", ok, err)
+	}
+	if def, _ := repository.DefaultUserAddress(sqlite, f.BuyerID); def == nil || def.City != "Campinas" {
+		t.Errorf("default after update = %+v", def)
+	}
+
+	if ok, _ := repository.DeleteUserAddress(sqlite, second, f.BuyerID); !ok {
+		t.Fatal("default address not deleted")
+	}
+	if def, _ := repository.DefaultUserAddress(sqlite, f.BuyerID); def != nil {
+		t.Errorf("default after deleting it = %+v, want none", def)
+	}
+
+	addr.ID = ""
+	for i := 1; i < repository.MaxUserAddresses; i++ {
+		if _, err := repository.CreateUserAddress(sqlite, addr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repository.CreateUserAddress(sqlite, addr); !errors.Is(err, repository.ErrAddressLimit) {
+		t.Errorf("address past the limit: err = %v, want ErrAddressLimit", err)
+	}
+	// A refused default leaves the current one in place
+	repository.SetDefaultUserAddress(sqlite, first, f.BuyerID)
+	addr.IsDefault = true
+	if _, err := repository.CreateUserAddress(sqlite, addr); !errors.Is(err, repository.ErrAddressLimit) {
+		t.Errorf("default past the limit: err = %v, want ErrAddressLimit", err)
+	}
+	if def, _ := repository.DefaultUserAddress(sqlite, f.BuyerID); def == nil || def.ID != first {
+		t.Errorf("default after a refused address = %+v, want the first", def)
+	}
+}
@@ -0,0 +1,118 @@
+// Package rest holds the helpers shared by the REST handlers: JSON
+// responses and request bodies, feature flags and admin checks.
+package rest
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/i18n"
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/repository"
+)
+
+// JSON writes data as a JSON response with the given status.
+func JSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+// Error writes {"error": message}, translated into the language negotiated
+// by middleware.Language. Messages are written in pt-BR.
+func Error(w http.ResponseWriter, status int, message string) {
+	JSON(w, status, map[string]string{"error": Localize(w, message)})
+}
+
+// Decode decodes the request body into v, ignoring fields v doesn't have.
+// Bodies over the limit set by middleware.MaxBody get a 413, anything else
+// that doesn't decode a 400; in both cases the response is written and false
+// returned.
+func Decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	return decodeBody(w, json.NewDecoder(r.Body), v)
+}
+
+// DecodeStrict is Decode refusing fields v doesn't have when strict is set
+// (STRICT_JSON), so a misspelled "order_id" is a 400 naming the field
+// instead of a missing orderId. Endpoints called by clients that can't be
+// updated in step with the API (offline check-in scanners, public forms)
+// keep Decode.
+func DecodeStrict(w http.ResponseWriter, r *http.Request, v interface{}, strict bool) bool {
+	dec := json.NewDecoder(r.Body)
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	return decodeBody(w, dec, v)
+}
+
+// decodeBody decodes one JSON value into v, writing the error response and
+// returning false when it fails.
+func decodeBody(w http.ResponseWriter, dec *json.Decoder, v interface{}) bool {
+	err := dec.Decode(v)
+	if err == nil {
+		return true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		Error(w, http.StatusRequestEntityTooLarge, middleware.BodyTooLargeMessage)
+		return false
+	}
+	// encoding/json has no error type for this one
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field = strings.Trim(field, `"`)
+		JSON(w, http.StatusBadRequest, map[string]string{"error": Localize(w, "campo desconhecido: "+field), "field": field})
+		return false
+	}
+	Error(w, http.StatusBadRequest, "corpo inválido")
+	return false
+}
+
+// Localize translates a pt-BR message into the response's Content-Language.
+func Localize(w http.ResponseWriter, message string) string {
+	return i18n.T(w.Header().Get("Content-Language"), message)
+}
+
+// ErrFeatureDisabled is returned for operations turned off by a feature flag.
+var ErrFeatureDisabled = errors.New("funcionalidade indisponível")
+
+// RequireFeature writes a 404 and returns false when the feature flag is
+// off, so a disabled endpoint looks like one that doesn't exist.
+func RequireFeature(w http.ResponseWriter, cfg *config.Config, name string) bool {
+	if cfg.Feature(name) {
+		return true
+	}
+	Error(w, http.StatusNotFound, ErrFeatureDisabled.Error())
+	return false
+}
+
+// IsAdmin reports whether userID belongs to an ADMIN. The role is read from
+// the database rather than the token so a demotion takes effect immediately.
+func IsAdmin(db *sql.DB, userID string) bool {
+	user, _ := repository.UserByID(db, userID)
+	return user != nil && user.Role == "ADMIN"
+}
+
+// OrderAccessError writes the response for a repository.AuthorizeOrderAccess
+// error, or for an order state error from the repository (409).
+func OrderAccessError(w http.ResponseWriter, orderID string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrOrderNotFound):
+		Error(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, repository.ErrOrderForbidden):
+		Error(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, repository.ErrOrderNotProcessing),
+		errors.Is(err, repository.ErrOrderNotConfirmable),
+		errors.Is(err, repository.ErrOrderNotEditable),
+		errors.Is(err, repository.ErrOrderHasPayment),
+		errors.Is(err, repository.ErrInsufficientInventory):
+		Error(w, http.StatusConflict, err.Error())
+	default:
+		logger.Errorf("erro ao buscar pedido %s: %v", orderID, err)
+		Error(w, http.StatusInternalServerError, "erro ao buscar pedido")
+	}
+}
@@ -0,0 +1,49 @@
+// Package retention implements the data-retention job (LGPD): abandoned
+// pending orders are deleted and inactive accounts anonymized.
+package retention
+
+import (
+	"database/sql"
+	"time"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/repository"
+)
+
+// Policy configures the data-retention job. A zero window disables that
+// part; DryRun only logs what would change.
+type Policy struct {
+	PendingOrders time.Duration // delete PENDING orders older than this
+	InactiveUsers time.Duration // anonymize accounts inactive for longer than this
+	DryRun        bool
+}
+
+// Apply is the body of the data-retention job: it deletes abandoned PENDING
+// orders and anonymizes the personal data of inactive accounts. Paid orders
+// and tickets are kept for financial records.
+func Apply(db *sql.DB, p Policy, now time.Time) error {
+	verb := "removido(s)"
+	if p.DryRun {
+		verb = "seriam removido(s) (dry run)"
+	}
+	if p.PendingOrders > 0 {
+		n, err := repository.PurgeAbandonedOrders(db, now.Add(-p.PendingOrders), p.DryRun)
+		if err != nil {
+			return err
+		}
+		logger.Infof("retenção: %d pedido(s) pendente(s) abandonado(s) %s", n, verb)
+	}
+
+	verb = "anonimizada(s)"
+	if p.DryRun {
+		verb = "seriam anonimizada(s) (dry run)"
+	}
+	if p.InactiveUsers > 0 {
+		n, err := repository.AnonymizeInactiveUsers(db, now.Add(-p.InactiveUsers), now, p.DryRun)
+		if err != nil {
+			return err
+		}
+		logger.Infof("retenção: %d conta(s) inativa(s) %s", n, verb)
+	}
+	return nil
+}
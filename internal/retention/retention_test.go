@@ -0,0 +1,74 @@
+package retention
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestApply(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	f.PendingOrder(t, sqlite, 1)
+	buyerID := f.BuyerID
+	recentID, _ := repository.CreateUser(sqlite, "Recente", "recente@email.com", "hash", "39053344705", "1990-01-01", nil, nil, nil)
+	if _, err := repository.CreateUserAddress(sqlite, repository.UserAddress{UserID: buyerID, Street: "Rua A", StreetNumber: "10", Neighborhood: "Centro", City: "São Paulo", State: "SP", ZipCode: "01001000"}); err != nil {
+		t.Fatal(err)
+	}
+	old := "2020-01-01T00:00:00Z"
+	if _, err := sqlite.Exec(`UPDATE orders SET created_at = ?`, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlite.Exec(`UPDATE users SET created_at = ? WHERE id != ?`, old, recentID); err != nil {
+		t.Fatal(err)
+	}
+	policy := Policy{PendingOrders: 30 * 24 * time.Hour, InactiveUsers: 365 * 24 * time.Hour, DryRun: true}
+	now := time.Now()
+
+	if err := Apply(sqlite, policy, now); err != nil {
+		t.Fatal(err)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM orders`); got != 1 {
+		t.Errorf("dry run deleted orders: %d left, want 1", got)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM users WHERE anonymized_at IS NOT NULL`); got != 0 {
+		t.Errorf("dry run anonymized %d users", got)
+	}
+
+	policy.DryRun = false
+	if err := Apply(sqlite, policy, now); err != nil {
+		t.Fatal(err)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM orders`); got != 0 {
+		t.Errorf("abandoned orders left = %d, want 0", got)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM order_items`); got != 0 {
+		t.Errorf("order items left = %d, want 0", got)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM user_addresses`); got != 0 {
+		t.Errorf("addresses left = %d, want 0", got)
+	}
+	buyer, _ := repository.UserByID(sqlite, buyerID)
+	if buyer.Name != "Usuário anonimizado" || buyer.CPF == testutil.BuyerCPF || buyer.Email == testutil.BuyerEmail {
+		t.Errorf("buyer not anonymized: %+v", buyer)
+	}
+	// The producer's owner and the recent account are kept
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM users WHERE anonymized_at IS NOT NULL`); got != 1 {
+		t.Errorf("anonymized users = %d, want only the buyer", got)
+	}
+	if recent, _ := repository.UserByID(sqlite, recentID); recent.Email != "recente@email.com" {
+		t.Errorf("recent account anonymized: %+v", recent)
+	}
+}
+
+func countRows(t *testing.T, sqlite *sql.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := sqlite.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("count %q: %v", query, err)
+	}
+	return n
+}
@@ -0,0 +1,141 @@
+// Package testutil holds helpers shared by package tests: a migrated
+// in-memory database and a minimal catalog to sell from.
+package testutil
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"afterzin/api/internal/db"
+	"afterzin/api/internal/qrcode"
+	"afterzin/api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// NewDB opens an in-memory SQLite database with every migration applied and
+// closes it when the test ends. It goes through db.OpenSQLite, so foreign
+// keys are enforced exactly as in production (the WAL pragma is a no-op for
+// in-memory databases). The pool keeps a single connection, which holds the
+// database for the whole test.
+func NewDB(t testing.TB) *sql.DB {
+	t.Helper()
+	sqlite, err := db.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	sqlite.SetConnMaxLifetime(0)
+	sqlite.SetConnMaxIdleTime(0)
+	t.Cleanup(func() { sqlite.Close() })
+	if err := db.Migrate(sqlite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return sqlite
+}
+
+// Fixture users. The CPFs are valid, so they pass document validation.
+const (
+	BuyerEmail    = "comprador@email.com"
+	BuyerCPF      = "52998224725"
+	ProducerEmail = "produtor@email.com"
+	ProducerCPF   = "11144477735"
+	RecipientID   = "re_producer"
+)
+
+// Fixtures are the IDs of the rows created by Seed.
+type Fixtures struct {
+	BuyerID        string
+	ProducerUserID string
+	ProducerID     string
+	EventID        string
+	EventDateID    string
+	LotID          string
+	TicketTypeID   string
+	Price          float64
+}
+
+// Seed creates a buyer and a producer (with Pagar.me recipient RecipientID)
+// whose draft event has one date in 2099, one lot with lotQuantity places on
+// sale and one single-admission ticket type at price.
+func Seed(t testing.TB, sqlite *sql.DB, lotQuantity int, price float64) Fixtures {
+	t.Helper()
+	var f Fixtures
+	var err error
+	must := func(what string) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("seed %s: %v", what, err)
+		}
+	}
+	f.Price = price
+	f.BuyerID, err = repository.CreateUser(sqlite, "Comprador", BuyerEmail, "hash", BuyerCPF, "1990-01-01", nil, nil, nil)
+	must("buyer")
+	f.ProducerUserID, err = repository.CreateUser(sqlite, "Produtor", ProducerEmail, "hash", ProducerCPF, "1985-01-01", nil, nil, nil)
+	must("producer user")
+	f.ProducerID, err = repository.CreateProducer(sqlite, f.ProducerUserID)
+	must("producer")
+	err = repository.SetProducerPagarmeRecipientID(sqlite, f.ProducerID, RecipientID)
+	must("recipient")
+	f.EventID, err = repository.CreateEvent(sqlite, f.ProducerID, "Show", "desc", "shows", "cover", "Local", nil, "")
+	must("event")
+	f.EventDateID, err = repository.CreateEventDate(sqlite, f.EventID, "2099-01-01", nil, nil)
+	must("event date")
+	f.LotID, err = repository.CreateLot(sqlite, f.EventDateID, "Lote 1", "2000-01-01T00:00:00Z", "2099-01-01T00:00:00Z", lotQuantity)
+	must("lot")
+	f.TicketTypeID, err = repository.CreateTicketType(sqlite, f.LotID, "Pista", nil, price, "GENERAL", lotQuantity, 1)
+	must("ticket type")
+	return f
+}
+
+// pendingOrderTTL is how long orders created by PendingOrder stay payable.
+const pendingOrderTTL = 30 * time.Minute
+
+// PendingOrder creates a PENDING order by the fixture buyer for quantity
+// tickets of the fixture ticket type, expiring in 30 minutes.
+func (f Fixtures) PendingOrder(t testing.TB, sqlite *sql.DB, quantity int) string {
+	t.Helper()
+	orderID, err := repository.CreateOrderWithItems(sqlite, f.BuyerID, f.Price*float64(quantity), pendingOrderTTL, []repository.NewOrderItem{
+		{EventDateID: f.EventDateID, TicketTypeID: f.TicketTypeID, Quantity: quantity, UnitPrice: f.Price},
+	}, repository.OrderLimits{})
+	if err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+	return orderID
+}
+
+// PaidOrder creates an order by the fixture buyer for quantity tickets and
+// confirms it the way a paid webhook does: each ticket gets a V2 QR payload
+// signed by keys and the ticket type's sold count goes up. The lot places
+// were reserved when the order was created. Returns the order ID.
+func (f Fixtures) PaidOrder(t testing.TB, sqlite *sql.DB, quantity int, keys qrcode.KeyRing) string {
+	t.Helper()
+	orderID := f.PendingOrder(t, sqlite, quantity)
+	items, err := repository.OrderItemsByOrderID(sqlite, orderID)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("order items: %v", err)
+	}
+	tx, err := sqlite.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	for i := 0; i < quantity; i++ {
+		ticketID := uuid.New().String()
+		payload := keys.SignV2(ticketID, "ch_"+orderID, f.EventID)
+		if err := repository.CreateTicketWithIDTx(tx, ticketID, repository.GenerateTicketCode(), payload, orderID, items[0].ID,
+			f.BuyerID, f.EventID, f.EventDateID, f.TicketTypeID, repository.TicketSourcePurchase); err != nil {
+			t.Fatalf("create ticket: %v", err)
+		}
+		if err := repository.IncrementTicketTypeSoldTx(tx, f.TicketTypeID, 1); err != nil {
+			t.Fatalf("increment sold: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.ConfirmOrder(sqlite, orderID); err != nil {
+		t.Fatalf("confirm order: %v", err)
+	}
+	return orderID
+}
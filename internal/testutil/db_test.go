@@ -0,0 +1,52 @@
+package testutil
+
+import (
+	"testing"
+
+	"afterzin/api/internal/qrcode"
+	"afterzin/api/internal/repository"
+)
+
+func TestNewDBEnforcesForeignKeys(t *testing.T) {
+	sqlite := NewDB(t)
+	_, err := sqlite.Exec(`INSERT INTO order_items (id, order_id, event_date_id, ticket_type_id, quantity, unit_price)
+		VALUES ('oi', 'missing', 'missing', 'missing', 1, 10)`)
+	if err == nil {
+		t.Fatal("insert with dangling references succeeded")
+	}
+}
+
+func TestSeedAndPendingOrder(t *testing.T) {
+	sqlite := NewDB(t)
+	f := Seed(t, sqlite, 10, 50)
+	orderID := f.PendingOrder(t, sqlite, 2)
+
+	var status string
+	var total float64
+	var items int
+	if err := sqlite.QueryRow(`SELECT o.status, o.total, COUNT(oi.id) FROM orders o
+		JOIN order_items oi ON oi.order_id = o.id WHERE o.id = ? AND o.user_id = ?`, orderID, f.BuyerID).Scan(&status, &total, &items); err != nil {
+		t.Fatal(err)
+	}
+	if status != "PENDING" || total != 100 || items != 1 {
+		t.Errorf("order = %s %.2f with %d items, want PENDING 100.00 with 1", status, total, items)
+	}
+}
+
+func TestPaidOrder(t *testing.T) {
+	sqlite := NewDB(t)
+	f := Seed(t, sqlite, 10, 50)
+	keys := qrcode.NewKeyRing("test-secret")
+	orderID := f.PaidOrder(t, sqlite, 2, keys)
+
+	tickets, err := repository.TicketsByOrderID(sqlite, orderID)
+	if err != nil || len(tickets) != 2 {
+		t.Fatalf("tickets = %d (%v), want 2", len(tickets), err)
+	}
+	if id, _, _, ok := keys.VerifyV2(tickets[0].QRCode); !ok || id != tickets[0].ID {
+		t.Errorf("QR %q isn't a V2 payload for ticket %s", tickets[0].QRCode, tickets[0].ID)
+	}
+	if v, _ := repository.VerifyInventoryInvariants(sqlite); len(v) != 0 {
+		t.Errorf("inventory violations = %+v", v)
+	}
+}
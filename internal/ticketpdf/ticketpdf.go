@@ -0,0 +1,170 @@
+// Package ticketpdf renders printable one-page PDF tickets.
+package ticketpdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	qr "github.com/skip2/go-qrcode"
+)
+
+const (
+	maxCoverBytes = 5 << 20
+	coverTimeout  = 5 * time.Second
+)
+
+// Ticket holds everything printed on a ticket.
+type Ticket struct {
+	Code         string
+	QRPayload    string
+	HolderName   string
+	TicketType   string
+	EventTitle   string
+	EventDate    string // already formatted for display
+	Location     string
+	Address      string
+	ProducerName string
+	Used         bool
+	// Cover is an optional JPEG/PNG image shown as a banner; CoverType is "JPG" or "PNG".
+	Cover     []byte
+	CoverType string
+}
+
+// Render writes the ticket as a one-page A4 PDF.
+func Render(w io.Writer, t Ticket) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Ingresso "+t.Code, true)
+	pdf.SetAuthor("Afterzin", true)
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+	// Core fonts are cp1252; translate so accents render correctly
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	y := 20.0
+	if len(t.Cover) > 0 && t.CoverType != "" {
+		pdf.RegisterImageOptionsReader("cover", fpdf.ImageOptions{ImageType: t.CoverType}, bytes.NewReader(t.Cover))
+		if pdf.Ok() {
+			pdf.ImageOptions("cover", 20, y, 170, 60, false, fpdf.ImageOptions{ImageType: t.CoverType}, 0, "")
+			y += 66
+		} else {
+			// A broken cover must not prevent the ticket from rendering
+			pdf.ClearError()
+		}
+	}
+
+	pdf.SetXY(20, y)
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.MultiCell(170, 9, tr(t.EventTitle), "", "L", false)
+
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.SetTextColor(80, 80, 80)
+	for _, line := range []string{t.EventDate, t.Location, t.Address} {
+		if line != "" {
+			pdf.SetX(20)
+			pdf.MultiCell(170, 6, tr(line), "", "L", false)
+		}
+	}
+	if t.ProducerName != "" {
+		pdf.SetX(20)
+		pdf.MultiCell(170, 6, tr("Produzido por "+t.ProducerName), "", "L", false)
+	}
+	pdf.SetTextColor(0, 0, 0)
+
+	top := pdf.GetY() + 8
+	pdf.SetDrawColor(200, 200, 200)
+	pdf.Line(20, top-4, 190, top-4)
+
+	png, err := qr.Encode(t.QRPayload, qr.Medium, 512)
+	if err != nil {
+		return fmt.Errorf("qr code: %w", err)
+	}
+	pdf.RegisterImageOptionsReader("qr", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(png))
+	pdf.ImageOptions("qr", 20, top, 70, 70, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+	field := func(label, value string) {
+		pdf.SetX(100)
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(90, 5, tr(strings.ToUpper(label)), "", 2, "L", false, 0, "")
+		pdf.SetX(100)
+		pdf.SetFont("Helvetica", "B", 13)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.MultiCell(90, 7, tr(value), "", "L", false)
+		pdf.Ln(2)
+	}
+	pdf.SetXY(100, top)
+	field("Titular", t.HolderName)
+	field("Ingresso", t.TicketType)
+	field("Código", strings.ToUpper(t.Code))
+	if t.Used {
+		field("Situação", "Utilizado")
+	}
+
+	pdf.SetXY(20, top+78)
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(120, 120, 120)
+	pdf.MultiCell(170, 5, tr("Apresente este QR Code na entrada do evento. O ingresso é pessoal e válido para um único acesso."), "", "L", false)
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
+
+// FetchCover downloads a cover image for Ticket.Cover. Returns the bytes and
+// fpdf image type, or an error for unreachable or unsupported images.
+func FetchCover(url string) ([]byte, string, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, "", fmt.Errorf("unsupported cover url")
+	}
+	dialer := &net.Dialer{Timeout: coverTimeout, Control: publicOnly}
+	client := &http.Client{
+		Timeout:   coverTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("cover status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCoverBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(body) > maxCoverBytes {
+		return nil, "", fmt.Errorf("cover too large")
+	}
+	switch http.DetectContentType(body) {
+	case "image/jpeg":
+		return body, "JPG", nil
+	case "image/png":
+		return body, "PNG", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported cover type")
+	}
+}
+
+// publicOnly refuses connections to loopback/private addresses, since cover
+// URLs are producer-supplied and must not reach internal services.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return fmt.Errorf("cover address not allowed: %s", host)
+	}
+	return nil
+}
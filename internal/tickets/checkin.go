@@ -0,0 +1,173 @@
+package tickets
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+)
+
+const (
+	// maxCheckinBatchSize limita a quantidade de leituras enviadas de uma vez
+	maxCheckinBatchSize = 500
+
+	CheckinOK          = repository.CheckinResultOK
+	CheckinAlreadyUsed = repository.CheckinResultAlreadyUsed
+	CheckinInvalid     = repository.CheckinResultInvalid
+)
+
+// CheckinScan is a scan queued by an offline gate scanner.
+type CheckinScan struct {
+	Code      string `json:"code"`
+	ScannedAt string `json:"scannedAt"` // RFC3339; empty means "now"
+}
+
+// CheckinResult is the outcome of one scan in a batch.
+type CheckinResult struct {
+	Code   string `json:"code"`
+	Status string `json:"status"` // ok | already_used | invalid
+	// AdmitsRemaining is set for tickets that admit more than one person
+	AdmitsRemaining *int `json:"admitsRemaining,omitempty"`
+}
+
+// CheckinBatch handles POST /v1/checkin/batch
+// Applies scans queued offline in a single transaction. Idempotent: re-sending a
+// batch reports already_used and keeps the earliest scan time on each ticket.
+// A multi-admit ticket takes one entry per scan; a scan already recorded at the
+// same time is treated as a re-send and doesn't take another.
+func (h *Handler) CheckinBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	userID := middleware.UserID(r.Context())
+	if userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return
+	}
+	producerID, _ := repository.ProducerIDByUser(h.db, userID)
+	if producerID == "" {
+		rest.Error(w, http.StatusForbidden, "apenas produtores podem validar ingressos")
+		return
+	}
+
+	var scans []CheckinScan
+	if !rest.Decode(w, r, &scans) {
+		return
+	}
+	if len(scans) == 0 {
+		rest.Error(w, http.StatusBadRequest, "nenhuma leitura enviada")
+		return
+	}
+	if len(scans) > maxCheckinBatchSize {
+		rest.Error(w, http.StatusBadRequest, "lote excede o limite de leituras")
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		rest.Error(w, http.StatusInternalServerError, "erro ao validar")
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	results := make([]CheckinResult, 0, len(scans))
+	attempts := make([]repository.CheckinAttempt, 0, len(scans))
+	var applied int
+	for _, scan := range scans {
+		code := strings.ToLower(strings.TrimSpace(scan.Code))
+		result := CheckinResult{Code: scan.Code, Status: CheckinInvalid}
+
+		scannedAt := now
+		if scan.ScannedAt != "" {
+			parsed, err := time.Parse(time.RFC3339, scan.ScannedAt)
+			if err != nil {
+				results = append(results, result)
+				attempts = append(attempts, repository.CheckinAttempt{ProducerID: producerID, Result: repository.CheckinResultInvalid, ScannedAt: now})
+				continue
+			}
+			// Scanner clocks may run ahead; never record a use in the future
+			if parsed.Before(now) {
+				scannedAt = parsed
+			}
+		}
+
+		t, err := repository.TicketByCodeTx(tx, code)
+		if err != nil {
+			logger.Errorf("erro ao buscar ingresso %s no check-in em lote: %v", code, err)
+			rest.Error(w, http.StatusInternalServerError, "erro ao validar")
+			return
+		}
+		if t == nil {
+			results = append(results, result)
+			attempts = append(attempts, repository.CheckinAttempt{ProducerID: producerID, Result: repository.CheckinResultInvalid, ScannedAt: scannedAt})
+			continue
+		}
+		eventProducerID, err := repository.EventProducerIDTx(tx, t.EventID)
+		if err != nil || eventProducerID != producerID {
+			// Not attributed to the ticket's event: the scanner doesn't own it
+			results = append(results, result)
+			attempts = append(attempts, repository.CheckinAttempt{TicketID: t.ID, ProducerID: producerID, Result: repository.CheckinResultWrongEvent, ScannedAt: scannedAt})
+			continue
+		}
+
+		var updated bool
+		if t.Admits > 1 && scan.ScannedAt != "" {
+			var resent bool
+			if resent, err = repository.TicketValidatedAtTx(tx, t.ID, scannedAt); err == nil && !resent {
+				updated, err = repository.MarkTicketUsedAtTx(tx, t.ID, scannedAt)
+			}
+		} else {
+			updated, err = repository.MarkTicketUsedAtTx(tx, t.ID, scannedAt)
+		}
+		if err == nil {
+			if updated {
+				err = repository.InsertTicketValidationTx(tx, t.ID, t.EventID, producerID, scannedAt)
+			} else {
+				err = repository.KeepEarliestTicketUseTx(tx, t.ID, scannedAt)
+			}
+		}
+		if err != nil {
+			logger.Errorf("erro ao registrar check-in do ingresso %s: %v", t.ID, err)
+			rest.Error(w, http.StatusInternalServerError, "erro ao validar")
+			return
+		}
+		attempt := repository.CheckinAttempt{EventID: t.EventID, TicketID: t.ID, ProducerID: producerID, Result: repository.CheckinResultOK, ScannedAt: scannedAt}
+		if updated {
+			result.Status = CheckinOK
+			applied++
+			if t.Admits > 1 {
+				remaining := t.AdmitsRemaining - 1
+				result.AdmitsRemaining = &remaining
+			}
+		} else {
+			result.Status = CheckinAlreadyUsed
+			attempt.Result = repository.CheckinResultAlreadyUsed
+		}
+		results = append(results, result)
+		attempts = append(attempts, attempt)
+	}
+
+	if err := repository.InsertCheckinAttemptsTx(tx, attempts); err != nil {
+		logger.Errorf("erro ao registrar tentativas de check-in: %v", err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao validar")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Errorf("erro ao confirmar check-in em lote: %v", err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao validar")
+		return
+	}
+
+	logger.Infof("check-in em lote: produtor=%s leituras=%d aplicadas=%d", producerID, len(scans), applied)
+	rest.JSON(w, http.StatusOK, map[string]interface{}{
+		"results": results,
+	})
+}
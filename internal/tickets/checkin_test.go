@@ -0,0 +1,149 @@
+package tickets
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestCheckinBatch(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewHandler(sqlite, cfg, mailer.LogMailer{})
+	orderID := f.PaidOrder(t, sqlite, 2, cfg.QRRing())
+
+	tickets, _ := repository.TicketsByOrderID(sqlite, orderID)
+	if len(tickets) != 2 {
+		t.Fatalf("tickets = %d, want 2", len(tickets))
+	}
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+
+	checkin := func(userID, body string) (*httptest.ResponseRecorder, []CheckinResult) {
+		rec := httptest.NewRecorder()
+		h.CheckinBatch(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/checkin/batch", strings.NewReader(body)), userID))
+		var resp struct {
+			Results []CheckinResult `json:"results"`
+		}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return rec, resp.Results
+	}
+
+	if rec, _ := checkin(f.BuyerID, `[{"code":"`+tickets[0].Code+`"}]`); rec.Code != http.StatusForbidden {
+		t.Errorf("buyer: status = %d, want 403", rec.Code)
+	}
+
+	body := `[
+		{"code":"` + tickets[0].Code + `","scannedAt":"2025-01-01T21:10:00Z"},
+		{"code":"` + strings.ToUpper(tickets[1].Code) + `","scannedAt":"2025-01-01T21:05:00Z"},
+		{"code":"` + tickets[0].Code + `","scannedAt":"2025-01-01T21:00:00Z"},
+		{"code":"nope"}
+	]`
+	rec, results := checkin(producer.ID, body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	wantResults := []string{CheckinOK, CheckinOK, CheckinAlreadyUsed, CheckinInvalid}
+	for i, w := range wantResults {
+		if results[i].Status != w {
+			t.Errorf("result %d = %s, want %s", i, results[i].Status, w)
+		}
+	}
+
+	// Earliest scan wins even though it arrived after a later one
+	first, _ := repository.TicketByID(sqlite, tickets[0].ID)
+	if first.UsedAt.String != "2025-01-01T21:00:00Z" {
+		t.Errorf("used_at = %q, want earliest scan", first.UsedAt.String)
+	}
+
+	// Re-sending the batch is a no-op
+	_, results = checkin(producer.ID, body)
+	if results[0].Status != CheckinAlreadyUsed || results[1].Status != CheckinAlreadyUsed {
+		t.Errorf("resend results = %+v", results)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM ticket_validations`); got != 2 {
+		t.Errorf("validations = %d, want 2", got)
+	}
+
+	stats, err := repository.CheckinStats(sqlite, tickets[0].EventID)
+	if err != nil {
+		t.Fatalf("CheckinStats: %v", err)
+	}
+	// Unknown codes can't be attributed to an event
+	want := repository.CheckinStatsRow{TotalScans: 6, UniqueTickets: 2, Duplicates: 4, InvalidAttempts: 0}
+	if *stats != want {
+		t.Errorf("stats = %+v, want %+v", *stats, want)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM checkin_attempts WHERE result = 'invalid'`); got != 2 {
+		t.Errorf("invalid attempts = %d, want 2", got)
+	}
+}
+
+func TestMultiAdmitTicket(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	if _, err := sqlite.Exec(`UPDATE ticket_types SET admits = 6`); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewHandler(sqlite, cfg, mailer.LogMailer{})
+	orderID := f.PaidOrder(t, sqlite, 1, cfg.QRRing())
+
+	tickets, _ := repository.TicketsByOrderID(sqlite, orderID)
+	if len(tickets) != 1 || tickets[0].AdmitsRemaining != 6 {
+		t.Fatalf("tickets = %+v, want one ticket admitting 6", tickets)
+	}
+
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	code := tickets[0].Code
+	var scans []string
+	for i := 0; i < 7; i++ {
+		scans = append(scans, fmt.Sprintf(`{"code":%q,"scannedAt":"2025-01-01T21:%02d:00Z"}`, code, i))
+	}
+	// An offline scanner re-sending its first two scans takes no extra entries
+	first := "[" + strings.Join(scans[:2], ",") + "]"
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h.CheckinBatch(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/checkin/batch", strings.NewReader(first)), producer.ID))
+	}
+	if got, _ := repository.TicketByID(sqlite, tickets[0].ID); got.AdmitsRemaining != 4 || got.Used != 0 {
+		t.Fatalf("after re-sent scans: %+v, want 4 entries left", got)
+	}
+
+	body := "[" + strings.Join(scans, ",") + "]"
+	rec := httptest.NewRecorder()
+	h.CheckinBatch(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/checkin/batch", strings.NewReader(body)), producer.ID))
+	var resp struct {
+		Results []CheckinResult `json:"results"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Results) != 7 {
+		t.Fatalf("results = %d, body = %s", len(resp.Results), rec.Body.String())
+	}
+	for i, r := range resp.Results {
+		switch {
+		case i < 2 || i == 6:
+			if r.Status != CheckinAlreadyUsed {
+				t.Errorf("scan %d = %s, want already_used", i, r.Status)
+			}
+		case r.Status != CheckinOK || r.AdmitsRemaining == nil || *r.AdmitsRemaining != 5-i:
+			t.Errorf("scan %d = %+v, want ok with %d left", i, r, 5-i)
+		}
+	}
+
+	ticket, _ := repository.TicketByID(sqlite, tickets[0].ID)
+	if ticket.Used != 1 || ticket.AdmitsRemaining != 0 || ticket.UsedAt.String != "2025-01-01T21:00:00Z" {
+		t.Errorf("ticket = %+v, want used with first entry time", ticket)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM ticket_validations`); got != 6 {
+		t.Errorf("validations = %d, want 6", got)
+	}
+}
@@ -0,0 +1,260 @@
+package tickets
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/outbox"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+	"afterzin/api/internal/validate"
+
+	"github.com/google/uuid"
+)
+
+// maxCompBatchSize limita quantos convidados cabem em uma emissão
+const maxCompBatchSize = 200
+
+// Errors returned by IssueComplimentaryTickets.
+var (
+	ErrCompForbidden    = errors.New("sem permissão")
+	ErrCompUnavailable  = errors.New("quantidade indisponível")
+	ErrCompCapExceeded  = errors.New("limite de cortesias do evento atingido")
+	errCompNoRecipients = errors.New("nenhum convidado informado")
+)
+
+// CompRecipient is a guest receiving a complimentary ticket. CPF is only
+// needed when no account exists with the email yet.
+type CompRecipient struct {
+	Name  string
+	Email string
+	CPF   string
+}
+
+// CompRequest is a producer's request to issue complimentary tickets, one per
+// recipient, of a ticket type on an event date.
+type CompRequest struct {
+	ProducerUserID string
+	EventDateID    string
+	TicketTypeID   string
+	Recipients     []CompRecipient
+	SendEmail      bool
+}
+
+// IssueComplimentaryTickets issues one free ticket per recipient without a
+// payment. Comps take the same inventory as paid tickets (sold_quantity and
+// lot places) so they can't oversell, and each event is capped at
+// cfg.MaxCompsPerEvent. Recipients without an account become GUEST users,
+// created in the same transaction as the tickets so a refused issuance
+// leaves no account behind. Returns the issued ticket ids, in recipient
+// order.
+func IssueComplimentaryTickets(db *sql.DB, cfg *config.Config, mail mailer.Mailer, req CompRequest) ([]string, error) {
+	if !cfg.Feature(config.FeatureComps) {
+		return nil, rest.ErrFeatureDisabled
+	}
+	if len(req.Recipients) == 0 {
+		return nil, errCompNoRecipients
+	}
+	if len(req.Recipients) > maxCompBatchSize {
+		return nil, fmt.Errorf("máximo de %d convidados por emissão", maxCompBatchSize)
+	}
+
+	ed, _ := repository.EventDateByID(db, req.EventDateID)
+	if ed == nil {
+		return nil, errors.New("data não encontrada")
+	}
+	ev, _ := repository.EventByID(db, ed.EventID)
+	if ev == nil {
+		return nil, errors.New("evento não encontrado")
+	}
+	prod, _ := repository.ProducerByID(db, ev.ProducerID)
+	if prod == nil || prod.UserID != req.ProducerUserID {
+		return nil, ErrCompForbidden
+	}
+	tt, _ := repository.TicketTypeByID(db, req.TicketTypeID)
+	if tt == nil {
+		return nil, errors.New("tipo de ingresso não encontrado")
+	}
+	if lot, _ := repository.LotByID(db, tt.LotID); lot == nil || lot.EventDateID != ed.ID {
+		return nil, errors.New("tipo de ingresso não pertence a esta data")
+	}
+
+	userIDs := make([]string, len(req.Recipients))
+	guestOf := make([]*compGuest, len(req.Recipients))
+	var guests []*compGuest
+	guestByEmail := map[string]*compGuest{}
+	for i, rc := range req.Recipients {
+		id, guest, err := resolveCompRecipient(db, rc)
+		if err != nil {
+			return nil, fmt.Errorf("convidado %d (%s): %w", i+1, rc.Email, err)
+		}
+		if guest != nil {
+			// The same new guest twice in a batch gets one account
+			if g, ok := guestByEmail[guest.email]; ok {
+				guest = g
+			} else {
+				for _, g := range guests {
+					if g.cpf == guest.cpf {
+						return nil, fmt.Errorf("convidado %d (%s): CPF já vinculado a outra conta", i+1, rc.Email)
+					}
+				}
+				guestByEmail[guest.email] = guest
+				guests = append(guests, guest)
+			}
+			guestOf[i] = guest
+		}
+		userIDs[i] = id
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, g := range guests {
+		if g.id, err = repository.CreateGuestUserTx(tx, g.name, g.email, g.cpf, nil, nil, nil); err != nil {
+			return nil, err
+		}
+	}
+	for i, g := range guestOf {
+		if g != nil {
+			userIDs[i] = g.id
+		}
+	}
+
+	n := len(userIDs)
+	if cfg.MaxCompsPerEvent > 0 {
+		issued, err := repository.CountCompTicketsTx(tx, ev.ID)
+		if err != nil {
+			return nil, err
+		}
+		if issued+n > cfg.MaxCompsPerEvent {
+			return nil, fmt.Errorf("%w: %d de %d já emitidas", ErrCompCapExceeded, issued, cfg.MaxCompsPerEvent)
+		}
+	}
+	reserved, err := repository.ReserveTicketTypeUnitsTx(tx, tt.ID, n)
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		return nil, ErrCompUnavailable
+	}
+	if err := repository.DecrementLotAvailableTx(tx, tt.LotID, n*tt.Admits); errors.Is(err, repository.ErrInsufficientInventory) {
+		return nil, ErrCompUnavailable
+	} else if err != nil {
+		return nil, err
+	}
+
+	ticketIDs := make([]string, 0, n)
+	for _, userID := range userIDs {
+		orderID, itemID, err := repository.CreateCompOrderTx(tx, userID, ed.ID, tt.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := repository.RecordOrderStatusChange(tx, orderID, "", "PAID", "complimentary", "", "", ""); err != nil {
+			return nil, err
+		}
+		ticketID := uuid.New().String()
+		qrPayload := cfg.QRRing().Sign(ticketID)
+		err = repository.CreateTicketWithIDTx(tx, ticketID, repository.GenerateTicketCode(), qrPayload,
+			orderID, itemID, userID, ev.ID, ed.ID, tt.ID, repository.TicketSourceComp)
+		if err != nil {
+			return nil, err
+		}
+		ticketIDs = append(ticketIDs, ticketID)
+	}
+	details := fmt.Sprintf("%d cortesia(s) de %q em %s", n, tt.Name, ed.Date)
+	if err := repository.InsertAuditLogTx(tx, req.ProducerUserID, "ticket.comp", "event", ev.ID, details); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	for _, g := range guests {
+		logger.Infof("usuário convidado criado para cortesia: id=%s", g.id)
+	}
+	logger.Infof("cortesias emitidas: evento=%s tipo=%s quantidade=%d", ev.ID, tt.ID, n)
+
+	if req.SendEmail && mail != nil {
+		for i, ticketID := range ticketIDs {
+			sendCompEmail(db, cfg, mail, ev.Title, ed.Date, req.Recipients[i].Name, ticketID)
+		}
+	}
+	return ticketIDs, nil
+}
+
+// compGuest is a GUEST account to create for a comp recipient without one;
+// id is set once it is created.
+type compGuest struct {
+	id, name, email, cpf string
+}
+
+// resolveCompRecipient returns the account a comp goes to: the id of the
+// user with the recipient's email or, when there is none, the validated data
+// of a new GUEST user (which requires a valid CPF) for the caller to create.
+func resolveCompRecipient(db *sql.DB, rc CompRecipient) (string, *compGuest, error) {
+	email := validate.NormalizeEmail(rc.Email)
+	if err := validate.ValidateEmail(email); err != nil {
+		return "", nil, fmt.Errorf("email inválido: %w", err)
+	}
+	user, err := repository.UserByEmail(db, email)
+	if err != nil {
+		return "", nil, err
+	}
+	if user != nil {
+		return user.ID, nil, nil
+	}
+
+	name := strings.TrimSpace(rc.Name)
+	if err := validate.ValidateCustomerName(name); err != nil {
+		return "", nil, fmt.Errorf("nome inválido: %w", err)
+	}
+	cpf := validate.SanitizeDocument(rc.CPF)
+	if cpf == "" {
+		return "", nil, errors.New("CPF é obrigatório para quem ainda não tem conta")
+	}
+	if !validate.IsValidCPF(cpf) {
+		return "", nil, errors.New("CPF inválido")
+	}
+	if other, err := repository.UserByCPF(db, cpf); err != nil {
+		return "", nil, err
+	} else if other != nil {
+		return "", nil, errors.New("CPF já vinculado a outra conta")
+	}
+	return "", &compGuest{name: name, email: email, cpf: cpf}, nil
+}
+
+// sendCompEmail tells a guest about their complimentary ticket through the
+// outbox, which retries failed sends. Failures to queue it are logged: the
+// ticket is already issued and shows up in the guest's account.
+func sendCompEmail(db *sql.DB, cfg *config.Config, mail mailer.Mailer, eventTitle, date, name, ticketID string) {
+	t, _ := repository.TicketByID(db, ticketID)
+	if t == nil {
+		return
+	}
+	user, _ := repository.UserByID(db, t.UserID)
+	if user == nil {
+		return
+	}
+	if name = strings.TrimSpace(name); name == "" {
+		name = user.Name
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Olá, %s!\n\nVocê recebeu um ingresso cortesia para %s (%s).\n\n", name, eventTitle, date)
+	fmt.Fprintf(&b, "Código: %s\nQR Code: %s\n\n", t.Code, t.QRCode)
+	fmt.Fprintf(&b, "Você também encontra seu ingresso em %s/tickets\n", cfg.BaseURL)
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Seu ingresso cortesia Afterzin - " + eventTitle,
+		Body:    b.String(),
+	}
+	if err := outbox.QueueEmail(db, mail, msg); err != nil {
+		logger.Warnf("erro ao enfileirar cortesia %s para %s (não-fatal): %v", ticketID, user.Email, err)
+	}
+}
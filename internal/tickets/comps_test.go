@@ -0,0 +1,120 @@
+package tickets
+
+import (
+	"errors"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestIssueComplimentaryTickets(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 3, 50)
+	lotID, ttID := f.LotID, f.TicketTypeID
+	cfg := &config.Config{JWTSecret: "test-secret", MaxCompsPerEvent: 3}
+	mail := &captureMailer{}
+
+	req := CompRequest{
+		ProducerUserID: f.ProducerUserID,
+		EventDateID:    f.EventDateID,
+		TicketTypeID:   ttID,
+		Recipients: []CompRecipient{
+			{Email: "Comprador@Email.com"},
+			{Name: "Convidada Nova", Email: "convidada@email.com", CPF: "390.533.447-05"},
+		},
+		SendEmail: true,
+	}
+
+	if _, err := IssueComplimentaryTickets(sqlite, cfg, mail, CompRequest{ProducerUserID: f.BuyerID, EventDateID: req.EventDateID, TicketTypeID: ttID, Recipients: req.Recipients}); !errors.Is(err, ErrCompForbidden) {
+		t.Errorf("non-owner: err = %v, want ErrCompForbidden", err)
+	}
+	missingCPF := req
+	missingCPF.Recipients = []CompRecipient{{Name: "Sem Conta", Email: "semconta@email.com"}}
+	if _, err := IssueComplimentaryTickets(sqlite, cfg, mail, missingCPF); err == nil {
+		t.Error("new recipient without CPF: want error")
+	}
+
+	ids, err := IssueComplimentaryTickets(sqlite, cfg, mail, req)
+	if err != nil {
+		t.Fatalf("IssueComplimentaryTickets: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("tickets = %d, want 2", len(ids))
+	}
+	first, _ := repository.TicketByID(sqlite, ids[0])
+	if first.UserID != f.BuyerID {
+		t.Errorf("first comp owner = %s, want existing buyer account", first.UserID)
+	}
+	guest, _ := repository.UserByEmail(sqlite, "convidada@email.com")
+	if guest == nil || guest.Role != "GUEST" {
+		t.Fatalf("guest = %+v, want GUEST account", guest)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM tickets WHERE source = 'COMP'`); got != 2 {
+		t.Errorf("comp tickets = %d, want 2", got)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM orders o JOIN tickets t ON t.order_id = o.id WHERE t.source = 'COMP' AND o.status = 'PAID' AND o.total = 0`); got != 2 {
+		t.Errorf("comp orders = %d, want 2 zero-total PAID orders", got)
+	}
+	comps, total, err := repository.TicketsByEvent(sqlite, first.EventID, 10, 0, repository.TicketFilter{Source: repository.TicketSourceComp})
+	if err != nil || total != 2 || comps[0].Source != repository.TicketSourceComp {
+		t.Errorf("comps by source: total = %d, err = %v", total, err)
+	}
+	tt, _ := repository.TicketTypeByID(sqlite, ttID)
+	lot, _ := repository.LotByID(sqlite, lotID)
+	if tt.SoldQuantity != 2 || lot.AvailableQuantity != 1 {
+		t.Errorf("sold = %d, lot available = %d; want 2 and 1", tt.SoldQuantity, lot.AvailableQuantity)
+	}
+	if v, _ := repository.VerifyInventoryInvariants(sqlite); len(v) != 0 {
+		t.Errorf("inventory violations = %+v", v)
+	}
+	if len(mail.sent) != 2 || mail.sent[1].To != "convidada@email.com" {
+		t.Errorf("emails = %+v", mail.sent)
+	}
+
+	// Cap: 2 issued, 3 allowed
+	if _, err := IssueComplimentaryTickets(sqlite, cfg, nil, req); !errors.Is(err, ErrCompCapExceeded) {
+		t.Errorf("over cap: err = %v, want ErrCompCapExceeded", err)
+	}
+	// Stock: one unit left, so two comps don't fit even with a higher cap
+	cfg.MaxCompsPerEvent = 10
+	if _, err := IssueComplimentaryTickets(sqlite, cfg, nil, req); !errors.Is(err, ErrCompUnavailable) {
+		t.Errorf("over stock: err = %v, want ErrCompUnavailable", err)
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM tickets WHERE source = 'COMP'`); got != 2 {
+		t.Errorf("comp tickets after rejected batches = %d, want 2", got)
+	}
+
+	// A refused batch creates no guest accounts
+	newGuests := req
+	newGuests.Recipients = []CompRecipient{
+		{Name: "Outra Nova", Email: "outra@email.com", CPF: "153.509.460-56"},
+		{Name: "Outra Nova", Email: "outra@email.com", CPF: "153.509.460-56"},
+	}
+	if _, err := IssueComplimentaryTickets(sqlite, cfg, nil, newGuests); !errors.Is(err, ErrCompUnavailable) {
+		t.Errorf("over stock with new guests: err = %v, want ErrCompUnavailable", err)
+	}
+	if u, _ := repository.UserByEmail(sqlite, "outra@email.com"); u != nil {
+		t.Errorf("refused batch left guest account %s", u.ID)
+	}
+
+	// The same new guest twice in a batch gets one account
+	newGuests.Recipients = newGuests.Recipients[:1]
+	newGuests.Recipients = append(newGuests.Recipients, newGuests.Recipients[0])
+	if _, err := sqlite.Exec(`UPDATE lots SET available_quantity = available_quantity + 2 WHERE id = ?`, lotID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlite.Exec(`UPDATE ticket_types SET max_quantity = max_quantity + 2 WHERE id = ?`, ttID); err != nil {
+		t.Fatal(err)
+	}
+	ids, err = IssueComplimentaryTickets(sqlite, cfg, nil, newGuests)
+	if err != nil {
+		t.Fatalf("repeated new guest: %v", err)
+	}
+	a, _ := repository.TicketByID(sqlite, ids[0])
+	b, _ := repository.TicketByID(sqlite, ids[1])
+	if a.UserID != b.UserID || countRows(t, sqlite, `SELECT COUNT(*) FROM users WHERE email = 'outra@email.com'`) != 1 {
+		t.Errorf("repeated new guest: holders %s and %s", a.UserID, b.UserID)
+	}
+}
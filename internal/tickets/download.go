@@ -0,0 +1,110 @@
+package tickets
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"afterzin/api/internal/auth"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+)
+
+// Scopes of signed download links, one per download endpoint.
+const (
+	downloadScopeAttendees = "attendees"
+	downloadScopeTicketPDF = "ticket_pdf"
+)
+
+// downloadLinkTTL is how long a signed download link works.
+const downloadLinkTTL = 10 * time.Minute
+
+// CreateDownloadLink handles POST /v1/download/link
+// Mints a short-lived signed URL for an attendee export (scope "attendees",
+// id = event ID, producer only) or a ticket PDF (scope "ticket_pdf", id =
+// ticket ID, ticket holder only), usable without the Authorization header,
+// e.g. for a direct browser download or to share with staff.
+func (h *Handler) CreateDownloadLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID := middleware.UserID(r.Context())
+	if userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return
+	}
+
+	var req struct {
+		Scope  string `json:"scope"`
+		ID     string `json:"id"`
+		Format string `json:"format"`
+	}
+	if !rest.DecodeStrict(w, r, &req, h.cfg.StrictJSON) {
+		return
+	}
+	if req.ID == "" {
+		rest.Error(w, http.StatusBadRequest, "id é obrigatório")
+		return
+	}
+
+	var path string
+	params := url.Values{}
+	switch req.Scope {
+	case downloadScopeAttendees:
+		eventProducerID, _ := repository.EventProducerID(h.db, req.ID)
+		if eventProducerID == "" {
+			rest.Error(w, http.StatusNotFound, "evento não encontrado")
+			return
+		}
+		if producerID, _ := repository.ProducerIDByUser(h.db, userID); producerID != eventProducerID {
+			rest.Error(w, http.StatusForbidden, "sem permissão")
+			return
+		}
+		path = "/event/attendees"
+		params.Set("eventId", req.ID)
+		if req.Format != "" {
+			params.Set("format", req.Format)
+		}
+	case downloadScopeTicketPDF:
+		t, _ := repository.TicketByID(h.db, req.ID)
+		if t == nil {
+			rest.Error(w, http.StatusNotFound, "ingresso não encontrado")
+			return
+		}
+		if t.UserID != userID {
+			rest.Error(w, http.StatusForbidden, "ingresso não pertence ao usuário")
+			return
+		}
+		path = "/ticket/pdf"
+		params.Set("ticketId", req.ID)
+	default:
+		rest.Error(w, http.StatusBadRequest, "scope inválido: use attendees ou ticket_pdf")
+		return
+	}
+
+	expires := time.Now().Add(downloadLinkTTL)
+	for k, v := range auth.SignDownload(h.cfg.JWTSecret, req.Scope, req.ID, expires) {
+		params[k] = v
+	}
+	rest.JSON(w, http.StatusOK, map[string]interface{}{
+		"url":       h.cfg.APIPrefix + path + "?" + params.Encode(),
+		"expiresAt": expires.UTC().Format(time.RFC3339),
+	})
+}
+
+// signedDownload reports whether r carries a download signature. A signature
+// that is present but doesn't match scope and resourceID, or has expired, is
+// answered with 403 and ok is false.
+func (h *Handler) signedDownload(w http.ResponseWriter, r *http.Request, scope, resourceID string) (signed, ok bool) {
+	q := r.URL.Query()
+	if q.Get("sig") == "" {
+		return false, true
+	}
+	if err := auth.VerifyDownload(h.cfg.JWTSecret, scope, resourceID, q, time.Now()); err != nil {
+		rest.Error(w, http.StatusForbidden, err.Error())
+		return true, false
+	}
+	return true, true
+}
@@ -0,0 +1,200 @@
+package tickets
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+)
+
+// exportFlushEvery is how many rows are written between flushes to the client.
+const exportFlushEvery = 500
+
+// exportTypes maps the export formats to the media types they are served as.
+var exportTypes = map[string]string{"csv": "text/csv", "json": "application/json"}
+
+// Attendee is one line of the attendee export.
+type Attendee struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+	TicketType string `json:"ticketType"`
+	Date       string `json:"date"`
+	StartTime  string `json:"startTime,omitempty"`
+	Used       bool   `json:"used"`
+	UsedAt     string `json:"usedAt,omitempty"`
+	Source     string `json:"source"` // PURCHASE or COMP
+}
+
+// extendWriteDeadline replaces the server-wide WriteTimeout for a streaming
+// response; d <= 0 removes the deadline. Writers that don't support deadlines
+// (e.g. in tests) are left as they are.
+func extendWriteDeadline(w http.ResponseWriter, d time.Duration) {
+	var deadline time.Time
+	if d > 0 {
+		deadline = time.Now().Add(d)
+	}
+	_ = http.NewResponseController(w).SetWriteDeadline(deadline)
+}
+
+func attendeeFromRow(a repository.AttendeeRow) Attendee {
+	return Attendee{
+		Code:       strings.ToUpper(a.Code),
+		Name:       a.HolderName,
+		Email:      a.HolderEmail,
+		TicketType: a.TicketTypeName,
+		Date:       a.Date,
+		StartTime:  a.StartTime.String,
+		Used:       a.Used == 1,
+		UsedAt:     a.UsedAt.String,
+		Source:     a.Source,
+	}
+}
+
+// ExportAttendees handles GET /v1/event/attendees?eventId=xxx&format=csv|json
+// Streams the event's attendee list row by row to the producer that owns it,
+// or to anyone holding a signed link for the event. Without format, the
+// Accept header chooses between text/csv and application/json (406 if it
+// allows neither).
+func (h *Handler) ExportAttendees(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	eventID := r.URL.Query().Get("eventId")
+	if eventID == "" {
+		rest.Error(w, http.StatusBadRequest, "eventId é obrigatório")
+		return
+	}
+	// An explicit format wins; otherwise Accept picks it (CSV by default)
+	w.Header().Add("Vary", "Accept")
+	accept := r.Header.Get("Accept")
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		offers := []string{exportTypes["csv"], exportTypes["json"]}
+		switch middleware.NegotiateType(accept, offers...) {
+		case exportTypes["csv"]:
+			format = "csv"
+		case exportTypes["json"]:
+			format = "json"
+		default:
+			middleware.RespondNotAcceptable(w, offers...)
+			return
+		}
+	case "csv", "json":
+		if middleware.NegotiateType(accept, exportTypes[format]) == "" {
+			middleware.RespondNotAcceptable(w, exportTypes[format])
+			return
+		}
+	default:
+		rest.Error(w, http.StatusBadRequest, "formato inválido: use csv ou json")
+		return
+	}
+
+	// A signed link (see CreateDownloadLink) replaces the bearer token
+	signed, ok := h.signedDownload(w, r, downloadScopeAttendees, eventID)
+	if !ok {
+		return
+	}
+	userID := middleware.UserID(r.Context())
+	if !signed && userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return
+	}
+	eventProducerID, _ := repository.EventProducerID(h.db, eventID)
+	if eventProducerID == "" {
+		rest.Error(w, http.StatusNotFound, "evento não encontrado")
+		return
+	}
+	if !signed {
+		producerID, _ := repository.ProducerIDByUser(h.db, userID)
+		if producerID != eventProducerID {
+			rest.Error(w, http.StatusForbidden, "sem permissão")
+			return
+		}
+	}
+
+	extendWriteDeadline(w, h.cfg.StreamWriteTimeout)
+	filename := fmt.Sprintf("participantes-%s-%s.%s", eventID, time.Now().Format("20060102"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	flusher, _ := w.(http.Flusher)
+
+	var err error
+	if format == "csv" {
+		err = h.streamAttendeesCSV(w, flusher, eventID)
+	} else {
+		err = h.streamAttendeesJSON(w, flusher, eventID)
+	}
+	if err != nil {
+		// Headers are already sent; the truncated body is all we can signal
+		logger.Errorf("erro ao exportar participantes do evento %s: %v", eventID, err)
+	}
+}
+
+func (h *Handler) streamAttendeesCSV(w http.ResponseWriter, flusher http.Flusher, eventID string) error {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"codigo", "nome", "email", "ingresso", "data", "horario", "utilizado", "utilizado_em", "origem"})
+
+	n := 0
+	err := repository.StreamAttendeesByEvent(h.db, eventID, func(row repository.AttendeeRow) error {
+		a := attendeeFromRow(row)
+		used := "nao"
+		if a.Used {
+			used = "sim"
+		}
+		if err := cw.Write([]string{a.Code, a.Name, a.Email, a.TicketType, a.Date, a.StartTime, used, a.UsedAt, a.Source}); err != nil {
+			return err
+		}
+		if n++; n%exportFlushEvery == 0 {
+			cw.Flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return cw.Error()
+	})
+	cw.Flush()
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func (h *Handler) streamAttendeesJSON(w http.ResponseWriter, flusher http.Flusher, eventID string) error {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write([]byte("[")); err != nil {
+		return err
+	}
+	n := 0
+	err := repository.StreamAttendeesByEvent(h.db, eventID, func(row repository.AttendeeRow) error {
+		line, err := json.Marshal(attendeeFromRow(row))
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			line = append([]byte(","), line...)
+		}
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+		if n++; n%exportFlushEvery == 0 && flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte("]"))
+	return err
+}
@@ -0,0 +1,165 @@
+package tickets
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestExportAttendees(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewHandler(sqlite, cfg, mailer.LogMailer{})
+	orderID := f.PaidOrder(t, sqlite, 3, cfg.QRRing())
+
+	tickets, _ := repository.TicketsByOrderID(sqlite, orderID)
+	eventID := tickets[0].EventID
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	buyer, _ := repository.UserByID(sqlite, f.BuyerID)
+
+	export := func(userID, query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ExportAttendees(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/event/attendees?"+query, nil), userID))
+		return rec
+	}
+
+	if rec := export(f.BuyerID, "eventId="+eventID); rec.Code != http.StatusForbidden {
+		t.Errorf("buyer: status = %d, want 403", rec.Code)
+	}
+
+	rec := export(producer.ID, "eventId="+eventID)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("csv: status = %d, content-type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if len(records) != 4 || records[0][0] != "codigo" {
+		t.Fatalf("csv rows = %d, header = %v", len(records), records[0])
+	}
+	if records[1][1] != buyer.Name || records[1][2] != buyer.Email || records[1][6] != "nao" || records[1][8] != "PURCHASE" {
+		t.Errorf("csv row = %v", records[1])
+	}
+
+	rec = export(producer.ID, "eventId="+eventID+"&format=json")
+	var attendees []Attendee
+	if err := json.NewDecoder(rec.Body).Decode(&attendees); err != nil {
+		t.Fatalf("parse json: %v", err)
+	}
+	if len(attendees) != 3 || attendees[0].Email != buyer.Email || attendees[0].Source != repository.TicketSourcePurchase {
+		t.Errorf("json attendees = %+v", attendees)
+	}
+
+	if rec := export(producer.ID, "eventId="+eventID+"&format=xml"); rec.Code != http.StatusBadRequest {
+		t.Errorf("xml: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestExportAttendeesNegotiatesAccept(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewHandler(sqlite, cfg, mailer.LogMailer{})
+	orderID := f.PaidOrder(t, sqlite, 1, cfg.QRRing())
+
+	tickets, _ := repository.TicketsByOrderID(sqlite, orderID)
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+
+	for _, tc := range []struct {
+		query, accept string
+		status        int
+		contentType   string
+	}{
+		{"", "", http.StatusOK, "text/csv"},
+		{"", "*/*", http.StatusOK, "text/csv"},
+		{"", "application/json", http.StatusOK, "application/json"},
+		{"", "text/csv;q=0.5, application/json", http.StatusOK, "application/json"},
+		{"", "text/*", http.StatusOK, "text/csv"},
+		{"", "application/pdf", http.StatusNotAcceptable, "application/json"},
+		{"&format=json", "application/json, */*;q=0.1", http.StatusOK, "application/json"},
+		{"&format=csv", "application/json", http.StatusNotAcceptable, "application/json"},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/event/attendees?eventId="+tickets[0].EventID+tc.query, nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		h.ExportAttendees(rec, withUser(req, producer.ID))
+		if rec.Code != tc.status || !strings.HasPrefix(rec.Header().Get("Content-Type"), tc.contentType) {
+			t.Errorf("%q Accept %q: status = %d, content-type = %q; want %d, %s", tc.query, tc.accept, rec.Code, rec.Header().Get("Content-Type"), tc.status, tc.contentType)
+		}
+		if rec.Header().Get("Vary") != "Accept" {
+			t.Errorf("%q Accept %q: Vary = %q", tc.query, tc.accept, rec.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestSignedDownloadLink(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	cfg := &config.Config{JWTSecret: "test-secret", APIPrefix: "/v1"}
+	h := NewHandler(sqlite, cfg, mailer.LogMailer{})
+	orderID := f.PaidOrder(t, sqlite, 1, cfg.QRRing())
+
+	tickets, _ := repository.TicketsByOrderID(sqlite, orderID)
+	eventID := tickets[0].EventID
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+
+	mint := func(userID, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.CreateDownloadLink(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/download/link", strings.NewReader(body)), userID))
+		return rec
+	}
+	if rec := mint(f.BuyerID, `{"scope":"attendees","id":"`+eventID+`"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("buyer minting an export link: status = %d, want 403", rec.Code)
+	}
+	rec := mint(producer.ID, `{"scope":"attendees","id":"`+eventID+`","format":"csv"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("mint: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var link struct {
+		URL string `json:"url"`
+	}
+	json.NewDecoder(rec.Body).Decode(&link)
+	if !strings.HasPrefix(link.URL, "/v1/event/attendees?") {
+		t.Fatalf("url = %q", link.URL)
+	}
+
+	// No Authorization header: the signature is the credential
+	rec = httptest.NewRecorder()
+	h.ExportAttendees(rec, httptest.NewRequest(http.MethodGet, link.URL, nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "codigo") {
+		t.Errorf("signed export: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	tampered := strings.Replace(link.URL, "eventId="+eventID, "eventId=outro-evento", 1)
+	rec = httptest.NewRecorder()
+	h.ExportAttendees(rec, httptest.NewRequest(http.MethodGet, tampered, nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("tampered event id: status = %d, want 403", rec.Code)
+	}
+
+	// The event link doesn't open a ticket PDF
+	pdfURL := strings.Replace(link.URL, "/v1/event/attendees?eventId="+eventID, "/v1/ticket/pdf?ticketId="+tickets[0].ID, 1)
+	rec = httptest.NewRecorder()
+	h.TicketPDF(rec, httptest.NewRequest(http.MethodGet, pdfURL, nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("export link used for a ticket PDF: status = %d, want 403", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ExportAttendees(rec, httptest.NewRequest(http.MethodGet, "/v1/event/attendees?eventId="+eventID, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token and no signature: status = %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,41 @@
+package tickets
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+	"afterzin/api/internal/testutil"
+)
+
+func TestFeatureFlagsGateTicketEndpoints(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	flags, _ := config.ParseFeatures("-ticket_transfers,-ticket_verify")
+	cfg := &config.Config{JWTSecret: "test-secret", Features: flags}
+	orderID := f.PaidOrder(t, sqlite, 1, cfg.QRRing())
+	tickets, _ := repository.TicketsByOrderID(sqlite, orderID)
+	if _, err := repository.CreateUser(sqlite, "Amiga", "amiga@email.com", "hash", "15350946056", "1990-01-01", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := TransferTicket(sqlite, cfg, tickets[0].ID, f.BuyerID, "amiga@email.com"); !errors.Is(err, rest.ErrFeatureDisabled) {
+		t.Errorf("transfer with ticket_transfers off = %v, want ErrFeatureDisabled", err)
+	}
+	if still, _ := repository.TicketByID(sqlite, tickets[0].ID); still.UserID != f.BuyerID {
+		t.Error("ticket moved with transfers disabled")
+	}
+
+	h := NewHandler(sqlite, cfg, mailer.LogMailer{})
+	rec := httptest.NewRecorder()
+	h.VerifyTicket(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/ticket/verify", strings.NewReader(`{"qrCode":"`+tickets[0].QRCode+`"}`)), f.ProducerUserID))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("verify with ticket_verify off: status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,25 @@
+// Package tickets serves the REST endpoints for issued tickets: resending
+// and printing them, verifying and checking them in at the gate, and
+// exporting an event's attendees. It also issues complimentary tickets and
+// transfers tickets between accounts. None of this talks to the payment
+// gateway, so it is available whether or not Pagar.me is configured.
+package tickets
+
+import (
+	"database/sql"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+)
+
+// Handler provides the HTTP handlers for ticket endpoints.
+type Handler struct {
+	db     *sql.DB
+	cfg    *config.Config
+	mailer mailer.Mailer
+}
+
+// NewHandler creates a ticket HTTP handler.
+func NewHandler(db *sql.DB, cfg *config.Config, mail mailer.Mailer) *Handler {
+	return &Handler{db: db, cfg: cfg, mailer: mail}
+}
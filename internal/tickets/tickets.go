@@ -0,0 +1,233 @@
+package tickets
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+	"afterzin/api/internal/ticketpdf"
+)
+
+const (
+	// maxTicketResendsPerHour limita reenvios de ingressos por pedido
+	maxTicketResendsPerHour = 3
+)
+
+// ResendTickets handles POST /v1/order/resend-tickets
+// Re-sends the confirmation email with ticket codes and QR payloads for a PAID order.
+// Rate limited per order to avoid abuse.
+func (h *Handler) ResendTickets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	userID := middleware.UserID(r.Context())
+	if userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return
+	}
+
+	var req struct {
+		OrderID string `json:"orderId"`
+	}
+	if !rest.DecodeStrict(w, r, &req, h.cfg.StrictJSON) {
+		return
+	}
+	if req.OrderID == "" {
+		rest.Error(w, http.StatusBadRequest, "orderId é obrigatório")
+		return
+	}
+
+	order, err := repository.AuthorizeOrderAccess(h.db, req.OrderID, userID)
+	if err != nil {
+		rest.OrderAccessError(w, req.OrderID, err)
+		return
+	}
+	if order.Status != "PAID" {
+		rest.Error(w, http.StatusBadRequest, "pedido não está pago")
+		return
+	}
+
+	sent, err := repository.CountTicketResendsSince(h.db, req.OrderID, time.Now().Add(-time.Hour))
+	if err != nil {
+		logger.Errorf("erro ao contar reenvios do pedido %s: %v", req.OrderID, err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao reenviar ingressos")
+		return
+	}
+	if sent >= maxTicketResendsPerHour {
+		rest.Error(w, http.StatusTooManyRequests, "limite de reenvios atingido; tente novamente mais tarde")
+		return
+	}
+
+	buyer, _ := repository.UserByID(h.db, userID)
+	if buyer == nil {
+		rest.Error(w, http.StatusInternalServerError, "usuário não encontrado")
+		return
+	}
+	tickets, err := repository.TicketsByOrderID(h.db, req.OrderID)
+	if err != nil || len(tickets) == 0 {
+		rest.Error(w, http.StatusBadRequest, "pedido sem ingressos emitidos")
+		return
+	}
+
+	subject := "Seus ingressos Afterzin"
+	if ref, _ := repository.OrderRefByID(h.db, req.OrderID); ref != "" {
+		subject += " - pedido " + ref
+	}
+	msg := mailer.Message{
+		To:      buyer.Email,
+		Subject: subject,
+		Body:    h.ticketsEmailBody(buyer.Name, tickets),
+	}
+	if err := h.mailer.Send(msg); err != nil {
+		logger.Errorf("erro ao reenviar ingressos do pedido %s: %v", req.OrderID, err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao enviar email")
+		return
+	}
+	if err := repository.InsertTicketResend(h.db, req.OrderID, userID); err != nil {
+		logger.Warnf("erro ao registrar reenvio do pedido %s (não-fatal): %v", req.OrderID, err)
+	}
+
+	logger.Infof("ingressos reenviados: pedido=%s ingressos=%d", req.OrderID, len(tickets))
+	rest.JSON(w, http.StatusOK, map[string]interface{}{
+		"sent":    true,
+		"tickets": len(tickets),
+		"message": "ingressos reenviados para " + buyer.Email,
+	})
+}
+
+// ticketsEmailBody builds the plain-text confirmation email listing each ticket.
+func (h *Handler) ticketsEmailBody(buyerName string, tickets []*repository.TicketRow) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Olá, %s!\n\nSeguem seus ingressos:\n\n", buyerName)
+	for _, t := range tickets {
+		title := t.EventID
+		if ev, _ := repository.EventByID(h.db, t.EventID); ev != nil {
+			title = ev.Title
+		}
+		date := ""
+		if ed, _ := repository.EventDateByID(h.db, t.EventDateID); ed != nil {
+			date = ed.Date
+		}
+		fmt.Fprintf(&b, "- %s (%s)\n  Código: %s\n  QR Code: %s\n\n", title, date, t.Code, t.QRCode)
+	}
+	fmt.Fprintf(&b, "Você também encontra seus ingressos em %s/tickets\n", h.cfg.BaseURL)
+	return b.String()
+}
+
+// TicketPDF handles GET /v1/ticket/pdf?ticketId=xxx
+// Streams a printable PDF of a ticket owned by the authenticated user, or to
+// anyone holding a signed link for the ticket.
+func (h *Handler) TicketPDF(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ticketID := r.URL.Query().Get("ticketId")
+	if ticketID == "" {
+		rest.Error(w, http.StatusBadRequest, "ticketId é obrigatório")
+		return
+	}
+	// A signed link (see CreateDownloadLink) replaces the bearer token
+	signed, ok := h.signedDownload(w, r, downloadScopeTicketPDF, ticketID)
+	if !ok {
+		return
+	}
+	userID := middleware.UserID(r.Context())
+	if !signed && userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return
+	}
+	t, _ := repository.TicketByID(h.db, ticketID)
+	if t == nil {
+		rest.Error(w, http.StatusNotFound, "ingresso não encontrado")
+		return
+	}
+	if !signed && t.UserID != userID {
+		rest.Error(w, http.StatusForbidden, "ingresso não pertence ao usuário")
+		return
+	}
+
+	ev, _ := repository.EventByID(h.db, t.EventID)
+	ed, _ := repository.EventDateByID(h.db, t.EventDateID)
+	tt, _ := repository.TicketTypeByID(h.db, t.TicketTypeID)
+	holder, _ := repository.UserByID(h.db, t.UserID)
+	if ev == nil || ed == nil || tt == nil || holder == nil {
+		rest.Error(w, http.StatusInternalServerError, "dados do ingresso incompletos")
+		return
+	}
+
+	// Fetching the cover and rendering can outlast the default WriteTimeout
+	extendWriteDeadline(w, h.cfg.StreamWriteTimeout)
+	doc := ticketpdf.Ticket{
+		Code:         t.Code,
+		QRPayload:    t.QRCode,
+		HolderName:   holder.Name,
+		TicketType:   tt.Name,
+		EventTitle:   ev.Title,
+		EventDate:    formatTicketDate(ed),
+		Location:     ev.Location,
+		Address:      ev.Address.String,
+		ProducerName: producerDisplayName(h.db, ev.ProducerID),
+		Used:         t.Used == 1,
+	}
+	if h.cfg.TicketPDFCoverImage && ev.CoverImage != "" {
+		cover, coverType, err := ticketpdf.FetchCover(ev.CoverImage)
+		if err != nil {
+			logger.Warnf("capa do evento %s indisponível para o PDF: %v", ev.ID, err)
+		} else {
+			doc.Cover, doc.CoverType = cover, coverType
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := ticketpdf.Render(&buf, doc); err != nil {
+		logger.Errorf("erro ao gerar PDF do ingresso %s: %v", t.ID, err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao gerar PDF")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="ingresso-%s.pdf"`, t.Code))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
+
+// formatTicketDate renders an event date as "02/01/2006 às 20:00".
+func formatTicketDate(ed *repository.EventDateRow) string {
+	date := ed.Date
+	if d, err := time.Parse("2006-01-02", ed.Date); err == nil {
+		date = d.Format("02/01/2006")
+	}
+	if ed.StartTime.Valid && ed.StartTime.String != "" {
+		date += " às " + ed.StartTime.String
+	}
+	return date
+}
+
+// producerDisplayName returns the producer's company name, falling back to the owner's name.
+func producerDisplayName(db *sql.DB, producerID string) string {
+	p, _ := repository.ProducerByID(db, producerID)
+	if p == nil {
+		return ""
+	}
+	if p.CompanyName.Valid && p.CompanyName.String != "" {
+		return p.CompanyName.String
+	}
+	if u, _ := repository.UserByID(db, p.UserID); u != nil {
+		return u.Name
+	}
+	return ""
+}
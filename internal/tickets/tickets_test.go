@@ -0,0 +1,121 @@
+package tickets
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+// captureMailer records every message instead of sending it.
+type captureMailer struct {
+	sent []mailer.Message
+}
+
+func (m *captureMailer) Send(msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestResendTickets(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	mail := &captureMailer{}
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewHandler(sqlite, cfg, mail)
+	pendingID := f.PendingOrder(t, sqlite, 2)
+	orderID := f.PaidOrder(t, sqlite, 2, cfg.QRRing())
+
+	resend := func(orderID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/order/resend-tickets", strings.NewReader(`{"orderId":"`+orderID+`"}`))
+		rec := httptest.NewRecorder()
+		h.ResendTickets(rec, withUser(req, f.BuyerID))
+		return rec
+	}
+
+	if rec := resend(pendingID); rec.Code != http.StatusBadRequest {
+		t.Fatalf("pending order: status = %d, want 400", rec.Code)
+	}
+
+	for i := 0; i < maxTicketResendsPerHour; i++ {
+		if rec := resend(orderID); rec.Code != http.StatusOK {
+			t.Fatalf("resend %d: status = %d, body = %s", i+1, rec.Code, rec.Body.String())
+		}
+	}
+	if len(mail.sent) != maxTicketResendsPerHour {
+		t.Fatalf("emails sent = %d, want %d", len(mail.sent), maxTicketResendsPerHour)
+	}
+	if msg := mail.sent[0]; msg.To != "comprador@email.com" || strings.Count(msg.Body, "Código:") != 2 {
+		t.Errorf("unexpected email: to=%s body=%q", msg.To, msg.Body)
+	}
+
+	if rec := resend(orderID); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("over limit: status = %d, want 429", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/order/resend-tickets", strings.NewReader(`{"orderId":"`+orderID+`"}`))
+	rec := httptest.NewRecorder()
+	h.ResendTickets(rec, withUser(req, "someone-else"))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("other user: status = %d, want 403", rec.Code)
+	}
+}
+
+func TestTicketPDF(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewHandler(sqlite, cfg, mailer.LogMailer{})
+	orderID := f.PaidOrder(t, sqlite, 1, cfg.QRRing())
+	tickets, _ := repository.TicketsByOrderID(sqlite, orderID)
+	if len(tickets) != 1 {
+		t.Fatalf("tickets = %d, want 1", len(tickets))
+	}
+
+	get := func(userID string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.TicketPDF(rec, withUser(httptest.NewRequest(http.MethodGet, "/v1/ticket/pdf?ticketId="+tickets[0].ID, nil), userID))
+		return rec
+	}
+
+	rec := get(f.BuyerID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("content-type = %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, "ingresso-"+tickets[0].Code+".pdf") {
+		t.Errorf("content-disposition = %q", cd)
+	}
+	if !strings.HasPrefix(rec.Body.String(), "%PDF-") {
+		t.Error("body is not a PDF")
+	}
+
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+	if rec := get(producer.ID); rec.Code != http.StatusForbidden {
+		t.Errorf("other user: status = %d, want 403", rec.Code)
+	}
+}
+
+// withUser returns a copy of r authenticated as userID.
+func withUser(r *http.Request, userID string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, userID))
+}
+
+func countRows(t *testing.T, sqlite *sql.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := sqlite.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("count %q: %v", query, err)
+	}
+	return n
+}
@@ -0,0 +1,139 @@
+package tickets
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/outbox"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+	"afterzin/api/internal/validate"
+)
+
+// Errors returned by TransferTicket.
+var (
+	ErrTransferNotAllowed    = errors.New("o produtor não permite transferir ingressos deste evento")
+	ErrTransferLimitReached  = errors.New("limite de transferências do ingresso atingido")
+	ErrTicketNotTransferable = errors.New("ingresso não pode mais ser transferido")
+	errTicketNotFound        = errors.New("ingresso não encontrado")
+)
+
+// TicketReceivedPayload is the payload of a ticket_received notification.
+type TicketReceivedPayload struct {
+	TicketID   string `json:"ticketId"`
+	EventTitle string `json:"eventTitle,omitempty"`
+	FromName   string `json:"fromName,omitempty"`
+}
+
+// MaxTicketTransfers is how many times a ticket of the event may change
+// holders: the event's override, else cfg.MaxTicketTransfers (0 = no cap).
+func MaxTicketTransfers(cfg *config.Config, eventMax int) int {
+	if eventMax > 0 {
+		return eventMax
+	}
+	return cfg.MaxTicketTransfers
+}
+
+// transferredQRPayload is the QR code of a ticket re-issued by a transfer: the
+// ticket id and its new code, signed with the current QR key. Unlike the
+// payloads issued with the ticket it doesn't resolve to the ticket by its
+// signature alone, only while it is the ticket's stored qr_code.
+func transferredQRPayload(cfg *config.Config, ticketID, code string) string {
+	return cfg.QRRing().Sign(ticketID + ":" + code)
+}
+
+// TransferTicket passes a ticket held by fromUserID on to the account with
+// recipientEmail, which must already exist. Only purchased tickets of
+// transferable events move, before any entry is used and within the event's
+// transfer cap; complimentary tickets are personal invitations and stay with
+// their guest. Each transfer is recorded in ticket_transfers, the ticket's
+// chain of custody, and the recipient is notified. The ticket is re-issued
+// with a new code and QR code (see transferredQRPayload), so the previous
+// holder can't use the ones they kept.
+func TransferTicket(db *sql.DB, cfg *config.Config, ticketID, fromUserID, recipientEmail string) error {
+	if !cfg.Feature(config.FeatureTicketTransfers) {
+		return rest.ErrFeatureDisabled
+	}
+	t, err := repository.TicketByID(db, ticketID)
+	if err != nil {
+		return err
+	}
+	if t == nil || t.UserID != fromUserID {
+		return errTicketNotFound
+	}
+	if t.Source == repository.TicketSourceComp {
+		return errors.New("cortesias não podem ser transferidas")
+	}
+	if t.Used != 0 || t.AdmitsRemaining < t.Admits {
+		return errors.New("ingresso já utilizado")
+	}
+	transferable, eventMax, err := repository.EventTransferPolicy(db, t.EventID)
+	if err != nil {
+		return err
+	}
+	if !transferable {
+		return ErrTransferNotAllowed
+	}
+	max := MaxTicketTransfers(cfg, eventMax)
+	if max > 0 {
+		n, err := repository.CountTicketTransfers(db, t.ID)
+		if err != nil {
+			return err
+		}
+		if n >= max {
+			return ErrTransferLimitReached
+		}
+	}
+
+	email := validate.NormalizeEmail(recipientEmail)
+	if err := validate.ValidateEmail(email); err != nil {
+		return fmt.Errorf("email inválido: %w", err)
+	}
+	to, err := repository.UserByEmail(db, email)
+	if err != nil {
+		return err
+	}
+	if to == nil {
+		return errors.New("destinatário não encontrado: o e-mail precisa ter uma conta")
+	}
+	if to.ID == fromUserID {
+		return errors.New("o ingresso já é seu")
+	}
+
+	payload := TicketReceivedPayload{TicketID: t.ID}
+	if ev, _ := repository.EventByID(db, t.EventID); ev != nil {
+		payload.EventTitle = ev.Title
+	}
+	if from, _ := repository.UserByID(db, fromUserID); from != nil {
+		payload.FromName = from.Name
+	}
+
+	// The recipient's notification is queued with the transfer
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	code := repository.GenerateTicketCode()
+	qrPayload := transferredQRPayload(cfg, t.ID, code)
+	moved, err := repository.TransferTicketTx(tx, t.ID, fromUserID, to.ID, code, qrPayload, max)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		return ErrTicketNotTransferable
+	}
+	notificationID, err := outbox.NotifyTx(tx, to.ID, repository.NotificationTicketReceived, payload)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	logger.Infof("ingresso transferido: ingresso=%s de=%s para=%s", t.ID, fromUserID, to.ID)
+	outbox.Deliver(db, nil, notificationID)
+	return nil
+}
@@ -0,0 +1,105 @@
+package tickets
+
+import (
+	"errors"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/qrcode"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestTransferTicket(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	buyerID := f.BuyerID
+	cfg := &config.Config{JWTSecret: "test-secret", MaxTicketTransfers: 1}
+	orderID := f.PaidOrder(t, sqlite, 2, cfg.QRRing())
+	tickets, _ := repository.TicketsByOrderID(sqlite, orderID)
+	if len(tickets) != 2 {
+		t.Fatalf("tickets = %d, want 2", len(tickets))
+	}
+	friendID, err := repository.CreateUser(sqlite, "Amiga", "amiga@email.com", "hash", "15350946056", "1990-01-01", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := TransferTicket(sqlite, cfg, tickets[0].ID, buyerID, "nobody@email.com"); err == nil {
+		t.Error("transfer to an email without account succeeded")
+	}
+	if err := TransferTicket(sqlite, cfg, tickets[0].ID, buyerID, " Amiga@Email.com "); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	moved, _ := repository.TicketByID(sqlite, tickets[0].ID)
+	if moved.UserID != friendID || moved.Source != repository.TicketSourcePurchase {
+		t.Errorf("after transfer: holder = %s, source = %s", moved.UserID, moved.Source)
+	}
+	chain, _ := repository.TicketTransfers(sqlite, tickets[0].ID)
+	if len(chain) != 1 || chain[0].FromUserID != buyerID || chain[0].ToUserID != friendID || chain[0].CreatedAt == "" {
+		t.Errorf("chain of custody = %+v", chain)
+	}
+	if n := countRows(t, sqlite, `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND type = ?`, friendID, repository.NotificationTicketReceived); n != 1 {
+		t.Errorf("recipient notifications = %d, want 1", n)
+	}
+
+	// The former holder can't move it again; the new one hits the cap of 1.
+	if err := TransferTicket(sqlite, cfg, tickets[0].ID, buyerID, "amiga@email.com"); !errors.Is(err, errTicketNotFound) {
+		t.Errorf("transfer by former holder = %v, want errTicketNotFound", err)
+	}
+	if err := TransferTicket(sqlite, cfg, tickets[0].ID, friendID, "comprador@email.com"); !errors.Is(err, ErrTransferLimitReached) {
+		t.Errorf("second transfer = %v, want ErrTransferLimitReached", err)
+	}
+
+	if err := repository.SetEventTransferable(sqlite, tickets[1].EventID, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := TransferTicket(sqlite, cfg, tickets[1].ID, buyerID, "amiga@email.com"); !errors.Is(err, ErrTransferNotAllowed) {
+		t.Errorf("non-transferable event = %v, want ErrTransferNotAllowed", err)
+	}
+	if still, _ := repository.TicketByID(sqlite, tickets[1].ID); still.UserID != buyerID {
+		t.Errorf("refused transfer moved the ticket to %s", still.UserID)
+	}
+}
+
+func TestTransferTicketReissuesCodeAndQR(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	buyerID := f.BuyerID
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	keys := cfg.QRRing()
+	orderID := f.PaidOrder(t, sqlite, 1, keys)
+	issued, _ := repository.TicketsByOrderID(sqlite, orderID)
+	if len(issued) != 1 {
+		t.Fatalf("tickets = %d, want 1", len(issued))
+	}
+	before := issued[0]
+	if _, err := repository.CreateUser(sqlite, "Amiga", "amiga@email.com", "hash", "15350946056", "1990-01-01", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := TransferTicket(sqlite, cfg, before.ID, buyerID, "amiga@email.com"); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+
+	after, _ := repository.TicketByID(sqlite, before.ID)
+	if after.Code == before.Code || after.QRCode == before.QRCode {
+		t.Fatalf("transfer kept code %q / QR %q", after.Code, after.QRCode)
+	}
+	if got, _, _ := TicketByQRPayload(sqlite, keys, after.QRCode); got == nil || got.ID != before.ID {
+		t.Errorf("new QR doesn't resolve to the ticket: %+v", got)
+	}
+	if got, _ := repository.TicketByCode(sqlite, after.Code); got == nil || got.ID != before.ID {
+		t.Errorf("new code doesn't resolve to the ticket: %+v", got)
+	}
+
+	// What the seller kept no longer admits anyone, whether the stored QR,
+	// a V1 payload signed for the ticket or the old code.
+	for _, payload := range []string{before.QRCode, qrcode.GenerateSignedPayload(before.ID, []byte("test-secret"))} {
+		if got, signed, err := TicketByQRPayload(sqlite, keys, payload); err != nil || got != nil || !signed {
+			t.Errorf("old QR %s: ticket=%v signed=%v err=%v", payload, got, signed, err)
+		}
+	}
+	if got, _ := repository.TicketByCode(sqlite, before.Code); got != nil {
+		t.Errorf("old code still resolves to ticket %s", got.ID)
+	}
+}
@@ -0,0 +1,152 @@
+package tickets
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/logger"
+	"afterzin/api/internal/middleware"
+	"afterzin/api/internal/qrcode"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/rest"
+)
+
+// Outcomes of VerifyTicket.
+const (
+	VerifyValid       = "valid"
+	VerifyAlreadyUsed = "already_used"
+	VerifyWrongEvent  = "wrong_event"
+	VerifyInvalid     = "invalid"
+)
+
+// TicketByQRPayload finds the ticket a scanned QR payload points to, the way
+// validateTicket does: a stored qr_code first, then the ticket id of a V2
+// (ticketID:chargeID:eventID.sig) or V1 (ticketID.sig) signed payload.
+// signed reports whether the payload carries a valid signature by any key of
+// the ring. A ticket with rows in ticket_transfers is only found by its
+// current qr_code: the signature of the payloads it had before the transfer
+// no longer admits it.
+// Returns a nil ticket when nothing matches.
+func TicketByQRPayload(db *sql.DB, keys qrcode.KeyRing, payload string) (t *repository.TicketRow, signed bool, err error) {
+	var ticketID string
+	if id, _, _, ok := keys.VerifyV2(payload); ok {
+		ticketID, signed = id, true
+	} else if id, ok := keys.Verify(payload); ok {
+		ticketID, signed = id, true
+	}
+	t, err = repository.TicketByQRCode(db, payload)
+	if err != nil || t != nil || !signed {
+		return t, signed, err
+	}
+	t, err = repository.TicketByID(db, ticketID)
+	if err != nil || t == nil {
+		return t, signed, err
+	}
+	transfers, err := repository.CountTicketTransfers(db, t.ID)
+	if err != nil || transfers > 0 {
+		return nil, signed, err
+	}
+	return t, signed, nil
+}
+
+// VerifiedTicket is the ticket part of a VerifyTicket response.
+type VerifiedTicket struct {
+	ID              string `json:"id"`
+	Code            string `json:"code"`
+	EventID         string `json:"eventId"`
+	EventDateID     string `json:"eventDateId"`
+	TicketTypeID    string `json:"ticketTypeId"`
+	Used            bool   `json:"used"`
+	UsedAt          string `json:"usedAt,omitempty"`
+	Admits          int    `json:"admits"`
+	AdmitsRemaining int    `json:"admitsRemaining"`
+}
+
+// VerifyTicket handles POST /v1/ticket/verify
+// Checks a scanned QR payload ({"qrCode", "eventId"?}) without checking the
+// ticket in, so a pre-screening step (e.g. a turnstile) can run before the
+// real gate: the signature is verified with the QR key ring and the ticket
+// looked up like in validateTicket, and the response tells whether it would
+// be admitted ("status": valid, already_used, wrong_event or invalid) and
+// whether it was used. Nothing is written, not even a check-in attempt.
+// Producers verify tickets of their own events; admins any ticket.
+func (h *Handler) VerifyTicket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rest.Error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !rest.RequireFeature(w, h.cfg, config.FeatureTicketVerify) {
+		return
+	}
+
+	userID := middleware.UserID(r.Context())
+	if userID == "" {
+		rest.Error(w, http.StatusUnauthorized, "não autenticado")
+		return
+	}
+	producerID, _ := repository.ProducerIDByUser(h.db, userID)
+	admin := rest.IsAdmin(h.db, userID)
+	if producerID == "" && !admin {
+		rest.Error(w, http.StatusForbidden, "apenas produtores podem validar ingressos")
+		return
+	}
+
+	var req struct {
+		QRCode  string `json:"qrCode"`
+		EventID string `json:"eventId"`
+	}
+	if !rest.DecodeStrict(w, r, &req, h.cfg.StrictJSON) {
+		return
+	}
+	req.QRCode = strings.TrimSpace(req.QRCode)
+	if req.QRCode == "" {
+		rest.Error(w, http.StatusBadRequest, "qrCode é obrigatório")
+		return
+	}
+
+	t, signed, err := TicketByQRPayload(h.db, h.cfg.QRRing(), req.QRCode)
+	if err != nil {
+		logger.Errorf("erro ao verificar QR code: %v", err)
+		rest.Error(w, http.StatusInternalServerError, "erro ao validar")
+		return
+	}
+	response := map[string]interface{}{"valid": false, "status": VerifyInvalid, "signatureValid": signed}
+	if t == nil {
+		rest.JSON(w, http.StatusOK, response)
+		return
+	}
+	// Tickets of other producers' events are reported as invalid, as in
+	// the batch check-in, so their details don't leak
+	if !admin {
+		eventProducerID, err := repository.EventProducerID(h.db, t.EventID)
+		if err != nil || eventProducerID != producerID {
+			rest.JSON(w, http.StatusOK, response)
+			return
+		}
+	}
+
+	ticket := VerifiedTicket{
+		ID:              t.ID,
+		Code:            t.Code,
+		EventID:         t.EventID,
+		EventDateID:     t.EventDateID,
+		TicketTypeID:    t.TicketTypeID,
+		Used:            t.Used == 1,
+		UsedAt:          t.UsedAt.String,
+		Admits:          t.Admits,
+		AdmitsRemaining: t.AdmitsRemaining,
+	}
+	switch {
+	case req.EventID != "" && t.EventID != req.EventID:
+		response["status"] = VerifyWrongEvent
+	case ticket.Used:
+		response["status"] = VerifyAlreadyUsed
+	default:
+		response["status"] = VerifyValid
+		response["valid"] = true
+	}
+	response["ticket"] = ticket
+	rest.JSON(w, http.StatusOK, response)
+}
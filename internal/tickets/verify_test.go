@@ -0,0 +1,121 @@
+package tickets
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"afterzin/api/internal/config"
+	"afterzin/api/internal/mailer"
+	"afterzin/api/internal/qrcode"
+	"afterzin/api/internal/repository"
+	"afterzin/api/internal/testutil"
+)
+
+func TestVerifyTicket(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewHandler(sqlite, cfg, mailer.LogMailer{})
+	orderID := f.PaidOrder(t, sqlite, 1, cfg.QRRing())
+
+	tickets, _ := repository.TicketsByOrderID(sqlite, orderID)
+	if len(tickets) != 1 {
+		t.Fatalf("tickets = %d, want 1", len(tickets))
+	}
+	ticket := tickets[0]
+	producer, _ := repository.UserByEmail(sqlite, "produtor@email.com")
+
+	type verifyResponse struct {
+		Valid          bool            `json:"valid"`
+		Status         string          `json:"status"`
+		SignatureValid bool            `json:"signatureValid"`
+		Ticket         *VerifiedTicket `json:"ticket"`
+	}
+	verify := func(userID, body string) (*httptest.ResponseRecorder, verifyResponse) {
+		rec := httptest.NewRecorder()
+		h.VerifyTicket(rec, withUser(httptest.NewRequest(http.MethodPost, "/v1/ticket/verify", strings.NewReader(body)), userID))
+		var resp verifyResponse
+		json.NewDecoder(strings.NewReader(rec.Body.String())).Decode(&resp)
+		return rec, resp
+	}
+
+	if rec, _ := verify(f.BuyerID, `{"qrCode":"`+ticket.QRCode+`"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("buyer: status = %d, want 403", rec.Code)
+	}
+
+	// The V1 payload of the same ticket is accepted too
+	for _, payload := range []string{ticket.QRCode, qrcode.GenerateSignedPayload(ticket.ID, []byte("test-secret"))} {
+		rec, resp := verify(producer.ID, `{"qrCode":"`+payload+`","eventId":"`+ticket.EventID+`"}`)
+		if rec.Code != http.StatusOK || !resp.Valid || resp.Status != VerifyValid || !resp.SignatureValid || resp.Ticket == nil || resp.Ticket.ID != ticket.ID {
+			t.Errorf("verify %s: %d %+v", payload, rec.Code, resp)
+		}
+	}
+	if _, resp := verify(producer.ID, `{"qrCode":"`+ticket.QRCode+`","eventId":"other"}`); resp.Valid || resp.Status != VerifyWrongEvent {
+		t.Errorf("other event: %+v", resp)
+	}
+	forged := qrcode.GenerateSignedPayload(ticket.ID, []byte("wrong-secret"))
+	if _, resp := verify(producer.ID, `{"qrCode":"`+forged+`"}`); resp.Valid || resp.Status != VerifyInvalid || resp.SignatureValid || resp.Ticket != nil {
+		t.Errorf("forged signature: %+v", resp)
+	}
+
+	// Verifying never checks the ticket in
+	if fresh, _ := repository.TicketByID(sqlite, ticket.ID); fresh.Used != 0 {
+		t.Fatal("verify marked the ticket as used")
+	}
+	if got := countRows(t, sqlite, `SELECT COUNT(*) FROM checkin_attempts`); got != 0 {
+		t.Errorf("verify recorded %d check-in attempts", got)
+	}
+
+	if _, err := repository.MarkTicketUsedIfNotUsed(sqlite, ticket.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, resp := verify(producer.ID, `{"qrCode":"`+ticket.QRCode+`"}`); resp.Valid || resp.Status != VerifyAlreadyUsed || !resp.Ticket.Used {
+		t.Errorf("used ticket: %+v", resp)
+	}
+
+	// Another producer learns nothing about the ticket
+	other, _ := repository.CreateUser(sqlite, "Outro", "outro@email.com", "hash", "15350946056", "1990-01-01", nil, nil, nil)
+	if _, err := sqlite.Exec(`INSERT INTO producers (id, user_id) VALUES ('p2', ?)`, other); err != nil {
+		t.Fatal(err)
+	}
+	if _, resp := verify(other, `{"qrCode":"`+ticket.QRCode+`"}`); resp.Status != VerifyInvalid || resp.Ticket != nil {
+		t.Errorf("other producer: %+v", resp)
+	}
+}
+
+func TestTicketByQRPayloadKeyRotation(t *testing.T) {
+	sqlite := testutil.NewDB(t)
+	f := testutil.Seed(t, sqlite, 10, 50)
+	cfg := &config.Config{JWTSecret: "test-secret", QRSigningKeys: []string{"qr-new", "qr-old"}}
+	orderID := f.PaidOrder(t, sqlite, 1, cfg.QRRing())
+
+	tickets, _ := repository.TicketsByOrderID(sqlite, orderID)
+	if len(tickets) != 1 {
+		t.Fatalf("tickets = %d, want 1", len(tickets))
+	}
+	ticket := tickets[0]
+
+	ring := cfg.QRRing()
+	for _, key := range []string{"qr-new", "qr-old"} {
+		got, signed, err := TicketByQRPayload(sqlite, ring, qrcode.GenerateSignedPayload(ticket.ID, []byte(key)))
+		if err != nil || !signed || got == nil || got.ID != ticket.ID {
+			t.Errorf("payload signed with %s: ticket=%v signed=%v err=%v", key, got, signed, err)
+		}
+	}
+	// The JWT secret is no longer a QR key once the ring is configured
+	if got, signed, _ := TicketByQRPayload(sqlite, ring, qrcode.GenerateSignedPayload(ticket.ID, []byte("test-secret"))); signed || got != nil {
+		t.Errorf("payload signed with the JWT secret: ticket=%v signed=%v", got, signed)
+	}
+	// Dropping a key from the ring retires the payloads it signed that are
+	// only found by their signature, like this V1 one that was never stored.
+	// A stored qr_code is still found by lookup, just no longer as signed.
+	if got, signed, _ := TicketByQRPayload(sqlite, qrcode.NewKeyRing("qr-new"), qrcode.GenerateSignedPayload(ticket.ID, []byte("qr-old"))); signed || got != nil {
+		t.Errorf("unstored payload signed with a retired key: ticket=%v signed=%v", got, signed)
+	}
+	if got, signed, _ := TicketByQRPayload(sqlite, qrcode.NewKeyRing("qr-newer"), ticket.QRCode); signed || got == nil || got.ID != ticket.ID {
+		t.Errorf("stored payload signed with a retired key: ticket=%v signed=%v", got, signed)
+	}
+}
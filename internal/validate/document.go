@@ -0,0 +1,84 @@
+// Package validate checks and normalizes the personal data the API accepts
+// from buyers and producers: emails, names, CPF/CNPJ and phones. It is
+// shared by the GraphQL resolvers, checkout and ticket transfers.
+package validate
+
+import "regexp"
+
+// SanitizeDocument remove todos os caracteres não numéricos de um documento (CPF/CNPJ).
+func SanitizeDocument(doc string) string {
+	return regexp.MustCompile(`[^\d]`).ReplaceAllString(doc, "")
+}
+
+// IsValidCPF implements the standard CPF checksum validation.
+func IsValidCPF(cpf string) bool {
+	if len(cpf) != 11 {
+		return false
+	}
+	// reject repeated digits
+	repeat := true
+	for i := 1; i < 11; i++ {
+		if cpf[i] != cpf[0] {
+			repeat = false
+			break
+		}
+	}
+	if repeat {
+		return false
+	}
+	toInt := func(b byte) int { return int(b - '0') }
+	// first digit
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += toInt(cpf[i]) * (10 - i)
+	}
+	r := sum % 11
+	var d1 int
+	if r < 2 {
+		d1 = 0
+	} else {
+		d1 = 11 - r
+	}
+	// second digit
+	sum = 0
+	for i := 0; i < 10; i++ {
+		sum += toInt(cpf[i]) * (11 - i)
+	}
+	r = sum % 11
+	var d2 int
+	if r < 2 {
+		d2 = 0
+	} else {
+		d2 = 11 - r
+	}
+	return toInt(cpf[9]) == d1 && toInt(cpf[10]) == d2
+}
+
+// IsValidCNPJ implements the standard CNPJ checksum validation.
+func IsValidCNPJ(cnpj string) bool {
+	if len(cnpj) != 14 {
+		return false
+	}
+	repeat := true
+	for i := 1; i < 14; i++ {
+		if cnpj[i] != cnpj[0] {
+			repeat = false
+			break
+		}
+	}
+	if repeat {
+		return false
+	}
+	digit := func(n int) int {
+		weights := []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}[13-n:]
+		sum := 0
+		for i := 0; i < n; i++ {
+			sum += int(cnpj[i]-'0') * weights[i]
+		}
+		if r := sum % 11; r >= 2 {
+			return 11 - r
+		}
+		return 0
+	}
+	return int(cnpj[12]-'0') == digit(12) && int(cnpj[13]-'0') == digit(13)
+}
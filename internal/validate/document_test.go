@@ -0,0 +1,56 @@
+package validate
+
+import "testing"
+
+func TestSanitizeDocument(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "CPF com pontos e hífen",
+			input:    "123.456.789-00",
+			expected: "12345678900",
+		},
+		{
+			name:     "CPF já limpo",
+			input:    "12345678900",
+			expected: "12345678900",
+		},
+		{
+			name:     "CPF com hífens múltiplos",
+			input:    "123-456-789-00",
+			expected: "12345678900",
+		},
+		{
+			name:     "CPF vazio",
+			input:    "",
+			expected: "",
+		},
+		{
+			name:     "CPF com espaços",
+			input:    "123 456 789 00",
+			expected: "12345678900",
+		},
+		{
+			name:     "CNPJ com formatação",
+			input:    "12.345.678/0001-90",
+			expected: "12345678000190",
+		},
+		{
+			name:     "Apenas caracteres não numéricos",
+			input:    "abc-def.ghi/jkl",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SanitizeDocument(tt.input)
+			if result != tt.expected {
+				t.Errorf("SanitizeDocument() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// maxCustomerFieldLength é o tamanho máximo de nome e email aceito pelo Pagar.me
+const maxCustomerFieldLength = 64
+
+// NormalizeEmail remove espaços e converte o email para minúsculas
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidateEmail valida um email (já normalizado) antes de enviá-lo ao gateway
+func ValidateEmail(email string) error {
+	if email == "" {
+		return fmt.Errorf("email é obrigatório")
+	}
+	if len(email) > maxCustomerFieldLength {
+		return fmt.Errorf("email deve ter no máximo %d caracteres", maxCustomerFieldLength)
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return fmt.Errorf("formato de email inválido")
+	}
+	at := strings.LastIndex(email, "@")
+	if domain := email[at+1:]; !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return fmt.Errorf("domínio do email inválido")
+	}
+	return nil
+}
+
+// ValidateCustomerName valida o nome do comprador enviado ao gateway
+func ValidateCustomerName(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("nome é obrigatório")
+	}
+	if len([]rune(name)) > maxCustomerFieldLength {
+		return fmt.Errorf("nome deve ter no máximo %d caracteres", maxCustomerFieldLength)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateEmail(t *testing.T) {
+	tests := []struct {
+		name    string
+		email   string
+		wantErr bool
+	}{
+		{name: "email válido", email: "comprador@email.com", wantErr: false},
+		{name: "subdomínio", email: "a.b+c@mail.empresa.com.br", wantErr: false},
+		{name: "vazio", email: "", wantErr: true},
+		{name: "sem arroba", email: "comprador.email.com", wantErr: true},
+		{name: "sem domínio", email: "comprador@", wantErr: true},
+		{name: "domínio sem ponto", email: "comprador@localhost", wantErr: true},
+		{name: "com nome de exibição", email: "Fulano <fulano@email.com>", wantErr: true},
+		{name: "muito longo", email: strings.Repeat("a", 60) + "@email.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEmail(NormalizeEmail(tt.email))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEmail(%q) error = %v, wantErr %v", tt.email, err, tt.wantErr)
+			}
+		})
+	}
+}
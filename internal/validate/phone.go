@@ -0,0 +1,110 @@
+package validate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// PhoneData representa telefone estruturado para envio ao gateway de pagamento
+type PhoneData struct {
+	CountryCode string // "55" para Brasil
+	AreaCode    string // DDD (2 dígitos)
+	Number      string // Número (8 ou 9 dígitos)
+}
+
+// Campos do telefone reportados em PhoneValidationError.Field
+const (
+	PhoneFieldCountryCode = "countryCode"
+	PhoneFieldAreaCode    = "areaCode"
+	PhoneFieldNumber      = "number"
+)
+
+// PhoneValidationError indica qual parte do telefone é inválida.
+// Error() retorna apenas a mensagem legível (Reason).
+type PhoneValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *PhoneValidationError) Error() string { return e.Reason }
+
+// InputField retorna o nome do campo nas entradas da API (phoneCountryCode,
+// phoneAreaCode ou phoneNumber), usado para mensagens por campo.
+func (e *PhoneValidationError) InputField() string {
+	switch e.Field {
+	case PhoneFieldCountryCode:
+		return "phoneCountryCode"
+	case PhoneFieldAreaCode:
+		return "phoneAreaCode"
+	default:
+		return "phoneNumber"
+	}
+}
+
+func phoneError(field, format string, args ...interface{}) error {
+	return &PhoneValidationError{Field: field, Reason: fmt.Sprintf(format, args...)}
+}
+
+// sanitizePhone remove caracteres não numéricos de um telefone
+func sanitizePhone(phone string) string {
+	return regexp.MustCompile(`[^\d]`).ReplaceAllString(phone, "")
+}
+
+// ValidatePhone valida telefone completo com suporte a Brasil e internacional.
+// Erros são sempre *PhoneValidationError.
+func ValidatePhone(countryCode, areaCode, number string) error {
+	// Valida country code não vazio
+	if countryCode == "" {
+		return phoneError(PhoneFieldCountryCode, "country code é obrigatório")
+	}
+
+	// Sanitiza todos os campos
+	cc := sanitizePhone(countryCode)
+	ac := sanitizePhone(areaCode)
+	num := sanitizePhone(number)
+
+	// Validação específica para Brasil (55)
+	if cc == "55" {
+		// Area code (DDD): deve ter 2 dígitos
+		if len(ac) != 2 {
+			return phoneError(PhoneFieldAreaCode, "DDD deve ter 2 dígitos")
+		}
+
+		// Valida se DDD está na faixa válida (11-99)
+		ddd, err := strconv.Atoi(ac)
+		if err != nil || ddd < 11 || ddd > 99 {
+			return phoneError(PhoneFieldAreaCode, "DDD inválido: deve estar entre 11 e 99")
+		}
+
+		// Número: deve ter 8 ou 9 dígitos
+		numLen := len(num)
+		if numLen != 8 && numLen != 9 {
+			return phoneError(PhoneFieldNumber, "número deve ter 8 ou 9 dígitos (recebido %d)", numLen)
+		}
+	} else {
+		// Validação internacional: mais flexível
+		if len(cc) < 1 || len(cc) > 3 {
+			return phoneError(PhoneFieldCountryCode, "country code inválido")
+		}
+
+		if len(ac) == 0 {
+			return phoneError(PhoneFieldAreaCode, "area code é obrigatório")
+		}
+
+		if len(num) < 6 {
+			return phoneError(PhoneFieldNumber, "número de telefone muito curto")
+		}
+	}
+
+	return nil
+}
+
+// ParsePhone converte telefone para estrutura sanitizada do Pagar.me
+func ParsePhone(countryCode, areaCode, number string) PhoneData {
+	return PhoneData{
+		CountryCode: sanitizePhone(countryCode),
+		AreaCode:    sanitizePhone(areaCode),
+		Number:      sanitizePhone(number),
+	}
+}
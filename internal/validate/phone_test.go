@@ -0,0 +1,111 @@
+package validate
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidatePhone(t *testing.T) {
+	tests := []struct {
+		name        string
+		countryCode string
+		areaCode    string
+		number      string
+		wantErr     bool
+	}{
+		{name: "celular Brasil 9 dígitos", countryCode: "55", areaCode: "11", number: "987654321", wantErr: false},
+		{name: "fixo Brasil 8 dígitos", countryCode: "55", areaCode: "21", number: "32345678", wantErr: false},
+		{name: "DDD limite superior", countryCode: "55", areaCode: "99", number: "987654321", wantErr: false},
+		{name: "DDD 09 inválido", countryCode: "55", areaCode: "09", number: "987654321", wantErr: true},
+		{name: "DDD 100 inválido", countryCode: "55", areaCode: "100", number: "987654321", wantErr: true},
+		{name: "DDD com 1 dígito", countryCode: "55", areaCode: "1", number: "987654321", wantErr: true},
+		{name: "número Brasil com 7 dígitos", countryCode: "55", areaCode: "11", number: "9876543", wantErr: true},
+		{name: "número Brasil com 10 dígitos", countryCode: "55", areaCode: "11", number: "9876543210", wantErr: true},
+		{name: "sem country code", countryCode: "", areaCode: "11", number: "987654321", wantErr: true},
+		{name: "internacional 1 dígito (EUA)", countryCode: "1", areaCode: "212", number: "5550123", wantErr: false},
+		{name: "internacional 2 dígitos (Reino Unido)", countryCode: "44", areaCode: "20", number: "79460958", wantErr: false},
+		{name: "internacional 3 dígitos (Portugal)", countryCode: "351", areaCode: "2", number: "1234567", wantErr: false},
+		{name: "internacional country code com 4 dígitos", countryCode: "1234", areaCode: "21", number: "1234567", wantErr: true},
+		{name: "internacional sem area code", countryCode: "44", areaCode: "", number: "12345678", wantErr: true},
+		{name: "internacional número curto", countryCode: "44", areaCode: "20", number: "12345", wantErr: true},
+		{name: "country code só com formatação", countryCode: "+", areaCode: "11", number: "987654321", wantErr: true},
+		{name: "formatação removida (Brasil)", countryCode: "+55", areaCode: "(11)", number: "98765-4321", wantErr: false},
+		{name: "formatação removida (internacional)", countryCode: "+1", areaCode: "(212)", number: "555 0123", wantErr: false},
+		{name: "formatação não conta como dígito", countryCode: "55", areaCode: "11", number: "9876-543", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePhone(tt.countryCode, tt.areaCode, tt.number)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePhone(%q, %q, %q) error = %v, wantErr %v", tt.countryCode, tt.areaCode, tt.number, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParsePhone(t *testing.T) {
+	tests := []struct {
+		name        string
+		countryCode string
+		areaCode    string
+		number      string
+		want        PhoneData
+	}{
+		{
+			name:        "já sanitizado",
+			countryCode: "55", areaCode: "11", number: "987654321",
+			want: PhoneData{CountryCode: "55", AreaCode: "11", Number: "987654321"},
+		},
+		{
+			name:        "com formatação",
+			countryCode: "+55", areaCode: "(11)", number: "98765-4321",
+			want: PhoneData{CountryCode: "55", AreaCode: "11", Number: "987654321"},
+		},
+		{
+			name:        "espaços e pontos",
+			countryCode: " +1 ", areaCode: " 212 ", number: "555.01.23",
+			want: PhoneData{CountryCode: "1", AreaCode: "212", Number: "5550123"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParsePhone(tt.countryCode, tt.areaCode, tt.number)
+			if got != tt.want {
+				t.Errorf("ParsePhone() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePhoneReportsField(t *testing.T) {
+	tests := []struct {
+		name                          string
+		countryCode, areaCode, number string
+		wantField, wantInputField     string
+	}{
+		{"sem country code", "", "11", "987654321", PhoneFieldCountryCode, "phoneCountryCode"},
+		{"DDD inválido", "55", "09", "987654321", PhoneFieldAreaCode, "phoneAreaCode"},
+		{"número Brasil curto", "55", "11", "1234", PhoneFieldNumber, "phoneNumber"},
+		{"country code longo", "1234", "21", "1234567", PhoneFieldCountryCode, "phoneCountryCode"},
+		{"internacional sem area code", "44", "", "12345678", PhoneFieldAreaCode, "phoneAreaCode"},
+		{"internacional número curto", "44", "20", "12345", PhoneFieldNumber, "phoneNumber"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePhone(tt.countryCode, tt.areaCode, tt.number)
+			var phoneErr *PhoneValidationError
+			if !errors.As(err, &phoneErr) {
+				t.Fatalf("expected *PhoneValidationError, got %T (%v)", err, err)
+			}
+			if phoneErr.Field != tt.wantField || phoneErr.InputField() != tt.wantInputField {
+				t.Errorf("field = %q/%q, want %q/%q", phoneErr.Field, phoneErr.InputField(), tt.wantField, tt.wantInputField)
+			}
+			if err.Error() == "" || err.Error() != phoneErr.Reason {
+				t.Errorf("Error() = %q, want reason %q", err.Error(), phoneErr.Reason)
+			}
+		})
+	}
+}